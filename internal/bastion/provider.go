@@ -0,0 +1,110 @@
+// Package bastion provisions ephemeral jump hosts through provider plugins.
+//
+// A provider plugin is any executable speaking a tiny protocol:
+//
+//	<command> [args...] up <tunnel-name>   prints {"id","host","user","port"} as JSON
+//	<command> [args...] down <id>          tears the instance down
+//
+// Anything written to stderr is surfaced in error messages. A plugin that runs
+// out of time gets SIGTERM first, so it can tear down a half-made instance.
+package bastion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+// Tag marking tunnels whose bastion is provisioned on demand
+const EphemeralTag = "ephemeral-bastion"
+
+// Cloud instances can take a while to boot and accept SSH
+var (
+	upTimeout   = 5 * time.Minute
+	downTimeout = 2 * time.Minute
+	// A plugin out of time gets SIGTERM, then this long to tear down what it
+	// created before it is killed
+	cleanupTimeout = 30 * time.Second
+)
+
+// Instance is a provisioned jump host
+type Instance struct {
+	ID   string `json:"id"`
+	Host string `json:"host"`
+	User string `json:"user"`
+	Port int    `json:"port"`
+}
+
+type Provider struct {
+	command string
+	args    []string
+}
+
+// NewProvider returns the configured provider, or nil if none is configured
+func NewProvider(cfg config.BastionProviderConfig) *Provider {
+	if cfg.Command == "" {
+		return nil
+	}
+	return &Provider{
+		command: cfg.Command,
+		args:    cfg.Args,
+	}
+}
+
+// IsEphemeral reports whether a tunnel wants an on-demand bastion
+func IsEphemeral(tc config.TunnelConfig) bool {
+	return tc.Tag == EphemeralTag
+}
+
+func (p *Provider) run(timeout time.Duration, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, append(append([]string{}, p.args...), args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = cleanupTimeout
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %w: %s", p.command, args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", p.command, args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Up provisions a jump host for the named tunnel
+func (p *Provider) Up(name string) (*Instance, error) {
+	out, err := p.run(upTimeout, "up", name)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance Instance
+	if err := json.Unmarshal(out, &instance); err != nil {
+		return nil, fmt.Errorf("invalid provider output: %w", err)
+	}
+	if instance.Host == "" {
+		return nil, fmt.Errorf("provider returned no host")
+	}
+	if instance.Port == 0 {
+		instance.Port = 22
+	}
+	return &instance, nil
+}
+
+// Down tears down a previously provisioned jump host
+func (p *Provider) Down(instance *Instance) error {
+	_, err := p.run(downTimeout, "down", instance.ID)
+	return err
+}
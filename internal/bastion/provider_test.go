@@ -0,0 +1,79 @@
+package bastion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func TestProviderUpDown(t *testing.T) {
+	plugin := writePlugin(t, `#!/bin/sh
+case $2 in
+  up) echo '{"id": "i-123", "host": "203.0.113.7", "user": "'$1'"}' ;;
+  down) [ "$3" = "i-123" ] || exit 1 ;;
+esac
+`)
+
+	provider := NewProvider(config.BastionProviderConfig{Command: plugin, Args: []string{"jumper"}})
+	instance, err := provider.Up("prod-db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if instance.ID != "i-123" || instance.Host != "203.0.113.7" || instance.User != "jumper" {
+		t.Errorf("unexpected instance: %+v", instance)
+	}
+	if instance.Port != 22 {
+		t.Errorf("expected default port 22, got %d", instance.Port)
+	}
+
+	if err := provider.Down(instance); err != nil {
+		t.Errorf("unexpected error on down: %v", err)
+	}
+}
+
+func TestProviderFailure(t *testing.T) {
+	plugin := writePlugin(t, "#!/bin/sh\necho 'quota exceeded' 1>&2\nexit 1\n")
+
+	provider := NewProvider(config.BastionProviderConfig{Command: plugin})
+	if _, err := provider.Up("prod-db"); err == nil {
+		t.Error("expected error from failing plugin")
+	}
+}
+
+func TestProviderTimeoutLetsPluginCleanUp(t *testing.T) {
+	defer func(up time.Duration) { upTimeout = up }(upTimeout)
+	upTimeout = 200 * time.Millisecond
+
+	cleaned := filepath.Join(t.TempDir(), "cleaned")
+	plugin := writePlugin(t, `#!/bin/sh
+trap 'kill $!; touch `+cleaned+`; exit 1' TERM
+sleep 10 &
+wait $!
+`)
+
+	provider := NewProvider(config.BastionProviderConfig{Command: plugin})
+	if _, err := provider.Up("prod-db"); err == nil {
+		t.Error("expected error from a plugin out of time")
+	}
+	if _, err := os.Stat(cleaned); err != nil {
+		t.Errorf("expected the plugin to get SIGTERM and clean up, got %v", err)
+	}
+}
+
+func TestNewProviderUnconfigured(t *testing.T) {
+	if NewProvider(config.BastionProviderConfig{}) != nil {
+		t.Error("expected nil provider without a command")
+	}
+}
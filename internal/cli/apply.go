@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"tunnel9/internal/control"
+)
+
+// Applier is a running daemon that can switch configs, a control.Client in
+// practice
+type Applier interface {
+	Apply(config []byte, dryRun bool) ([]control.Change, error)
+}
+
+// Apply shows what switching the daemon to a config would change, then
+// applies it unless dryRun is set or confirm turns it down
+func Apply(w io.Writer, daemon Applier, data []byte, dryRun bool, confirm Confirmer) error {
+	plan, err := daemon.Apply(data, true)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "The daemon already runs this config, nothing to change")
+		return nil
+	}
+	printChanges(w, plan)
+
+	if dryRun {
+		fmt.Fprintf(w, "%d tunnel(s) would change (dry run, nothing applied)\n", len(plan))
+		return nil
+	}
+	if !confirm.ask("Apply %d change(s)?", len(plan)) {
+		return fmt.Errorf("aborted, nothing applied")
+	}
+
+	// The daemon plans again, in case its tunnels changed meanwhile
+	changes, err := daemon.Apply(data, false)
+	if err != nil {
+		if len(changes) > 0 {
+			fmt.Fprintf(w, "%d tunnel(s) changed, some with errors\n", len(changes))
+		}
+		return err
+	}
+	fmt.Fprintf(w, "%d tunnel(s) changed\n", len(changes))
+	return nil
+}
+
+// printChanges lists changes like a diff, with what happens to running
+// tunnels
+func printChanges(w io.Writer, changes []control.Change) {
+	symbols := map[string]string{"add": "+", "update": "~", "remove": "-"}
+	for _, change := range changes {
+		line := fmt.Sprintf("%s %s", symbols[change.Action], change.Name)
+		if len(change.Fields) > 0 {
+			line += ": " + strings.Join(change.Fields, ", ")
+		}
+		if change.Effect != "" {
+			line += fmt.Sprintf(" (%ss)", change.Effect)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
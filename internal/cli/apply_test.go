@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"tunnel9/internal/control"
+)
+
+type fakeApplier struct {
+	plan    []control.Change
+	applied int
+}
+
+func (a *fakeApplier) Apply(config []byte, dryRun bool) ([]control.Change, error) {
+	if !dryRun {
+		a.applied++
+	}
+	return a.plan, nil
+}
+
+func TestApply(t *testing.T) {
+	daemon := &fakeApplier{plan: []control.Change{
+		{Action: "remove", Name: "old", Effect: "stop"},
+		{Action: "update", Name: "db", Fields: []string{"remote_host", "local_port"}, Effect: "restart"},
+		{Action: "add", Name: "cache"},
+	}}
+
+	var out bytes.Buffer
+	if err := Apply(&out, daemon, nil, true, nil); err != nil || daemon.applied != 0 {
+		t.Fatalf("expected a dry run to apply nothing, got %d (%v)", daemon.applied, err)
+	}
+	want := "- old (stops)\n~ db: remote_host, local_port (restarts)\n+ cache\n3 tunnel(s) would change (dry run, nothing applied)\n"
+	if out.String() != want {
+		t.Errorf("unexpected plan:\n%s", out.String())
+	}
+
+	declined := Confirmer(func(string) bool { return false })
+	if err := Apply(&out, daemon, nil, false, declined); err == nil || daemon.applied != 0 {
+		t.Errorf("expected declining to apply nothing")
+	}
+
+	if err := Apply(&out, daemon, nil, false, nil); err != nil || daemon.applied != 1 {
+		t.Errorf("expected the config applied, got %d (%v)", daemon.applied, err)
+	}
+
+	out.Reset()
+	daemon.plan = nil
+	if err := Apply(&out, daemon, nil, false, nil); err != nil || daemon.applied != 1 || out.String() == "" {
+		t.Errorf("expected nothing to apply, got %q (%v)", out.String(), err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package cli implements tunnel9's non-interactive commands.
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+)
+
+// filterByTag keeps the tunnels matching any of the comma separated tags
+func filterByTag(tunnels []config.TunnelConfig, tag string) []config.TunnelConfig {
+	if tag == "" {
+		return tunnels
+	}
+
+	selectedTags := strings.Split(tag, ",")
+	filtered := make([]config.TunnelConfig, 0)
+	for _, t := range tunnels {
+		for _, tag := range selectedTags {
+			if t.Tag == tag {
+				filtered = append(filtered, t)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// describeKeys lists identity files, marking the ones that can't be read
+func describeKeys(paths []string) string {
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			keys = append(keys, path+" (missing)")
+		} else {
+			keys = append(keys, path)
+		}
+	}
+	return strings.Join(keys, ", ")
+}
+
+// localPortLabel is a tunnel's local_port, or auto for one that picks it
+// when it starts
+func localPortLabel(tc config.TunnelConfig) string {
+	if tc.PicksPort() {
+		return config.AutoPort
+	}
+	return strconv.Itoa(tc.LocalPort)
+}
+
+// Check prints the effective settings of every tunnel after ssh_config
+// overrides, without connecting, and returns the number of problems found
+func Check(w io.Writer, tunnels []config.TunnelConfig, tag string) int {
+	tunnels = filterByTag(tunnels, tag)
+	problems := 0
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTAG\tLOCAL\tSSH SERVER\tUSER\tREMOTE\tKEYS")
+
+	notes := make([]string, 0)
+	for _, tc := range tunnels {
+		name := tc.Name
+		if name == "" {
+			name = tc.RemoteHost
+		}
+
+		settings, err := ssh.ResolveSSHSettings(tc)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %v\t\t\t\n", name, tc.Tag, localPortLabel(tc), err)
+			problems++
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			tc.Tag,
+			localPortLabel(tc),
+			settings.SSHEndpoint().String(),
+			settings.User,
+			settings.RemoteEndpoint().String(),
+			describeKeys(settings.IdentityFiles))
+
+		for _, note := range settings.Notes {
+			notes = append(notes, fmt.Sprintf("  [%s] %s", name, note))
+		}
+		if settings.User == "" {
+			notes = append(notes, fmt.Sprintf("  [%s] No user configured", name))
+			problems++
+		}
+		if algorithms, err := ssh.ResolveAlgorithms(tc.SSHOptions); err != nil {
+			notes = append(notes, fmt.Sprintf("  [%s] Invalid ssh_options: %v", name, err))
+			problems++
+		} else if len(algorithms.Legacy) > 0 {
+			notes = append(notes, fmt.Sprintf("  [%s] Allowing legacy algorithms: %s", name, strings.Join(algorithms.Legacy, ", ")))
+		}
+	}
+	tw.Flush()
+
+	if len(notes) > 0 {
+		fmt.Fprintln(w, "\nssh_config overrides:")
+		for _, note := range notes {
+			fmt.Fprintln(w, note)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d tunnel(s) checked, %d problem(s)\n", len(tunnels), problems)
+	return problems
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestFilterByTag(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "a", Tag: "dev"},
+		{Name: "b", Tag: "prod"},
+		{Name: "c", Tag: "staging"},
+	}
+
+	if got := filterByTag(tunnels, ""); len(got) != 3 {
+		t.Errorf("expected all tunnels without a tag, got %d", len(got))
+	}
+
+	got := filterByTag(tunnels, "dev,staging")
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Errorf("unexpected filter result: %+v", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "db", Tag: "dev", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432},
+	}
+	tunnels[0].Bastion.Host = "jump.example.com"
+	tunnels[0].Bastion.User = "deploy"
+
+	var out bytes.Buffer
+	Check(&out, tunnels, "")
+
+	for _, want := range []string{"db", "deploy", "db.internal:5432", "1 tunnel(s) checked"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCheckAutoPort(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "scratch", AutoPort: true, RemoteHost: "db.internal", RemotePort: 5432},
+	}
+	tunnels[0].Bastion.Host = "jump.example.com"
+	tunnels[0].Bastion.User = "deploy"
+
+	var out bytes.Buffer
+	Check(&out, tunnels, "")
+
+	for _, want := range []string{"scratch", "auto"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Confirmer asks whether to go ahead with a destructive command. A nil
+// Confirmer goes ahead without asking, as with --yes.
+type Confirmer func(question string) bool
+
+// PromptConfirmer asks on out and reads the answer from in, taking only y
+// or yes as agreement
+func PromptConfirmer(in io.Reader, out io.Writer) Confirmer {
+	reader := bufio.NewReader(in)
+	return func(question string) bool {
+		fmt.Fprintf(out, "%s [y/N] ", question)
+		answer, _ := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		}
+		return false
+	}
+}
+
+// ask runs the confirmer, treating a nil one as yes
+func (c Confirmer) ask(format string, args ...interface{}) bool {
+	if c == nil {
+		return true
+	}
+	return c(fmt.Sprintf(format, args...))
+}
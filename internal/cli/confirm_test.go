@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestPromptConfirmer(t *testing.T) {
+	var out bytes.Buffer
+	confirm := PromptConfirmer(strings.NewReader("y\nno\n\n"), &out)
+
+	if !confirm("Go?") {
+		t.Error("expected y to confirm")
+	}
+	if confirm("Go?") {
+		t.Error("expected no to decline")
+	}
+	if confirm("Go?") {
+		t.Error("expected an empty answer to decline")
+	}
+	if !strings.Contains(out.String(), "Go? [y/N] ") {
+		t.Errorf("expected the question on out, got %q", out.String())
+	}
+}
+
+func TestTagRemoveConfirmation(t *testing.T) {
+	newLoader := func(cfg config.Config) *config.ConfigLoader {
+		loader := config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+		if err := loader.SaveConfig(cfg); err != nil {
+			t.Fatalf("failed to save config: %v", err)
+		}
+		return loader
+	}
+	decline := func(string) bool { return false }
+
+	// Declining leaves the config alone
+	loader := newLoader(tagTestConfig())
+	if err := Tag(io.Discard, loader, "rm", []string{"old"}, false, decline); err == nil {
+		t.Error("expected declining to abort")
+	}
+	if tunnels, _ := loader.Load(); tunnels[0].Tag != "old" {
+		t.Errorf("expected nothing written, got tag %q", tunnels[0].Tag)
+	}
+
+	// Without a confirmer, as with --yes, it goes ahead
+	if err := Tag(io.Discard, loader, "rm", []string{"old"}, false, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tunnels, _ := loader.Load(); tunnels[0].Tag != "" {
+		t.Errorf("expected tag removed, got %q", tunnels[0].Tag)
+	}
+
+	// A confirm: none policy never asks
+	cfg := tagTestConfig()
+	cfg.Confirm = config.ConfirmNone
+	if err := Tag(io.Discard, newLoader(cfg), "rm", []string{"old"}, false, decline); err != nil {
+		t.Errorf("expected no confirmation with confirm: none, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"tunnel9/internal/diagnostics"
+	"tunnel9/internal/metrics"
+)
+
+// How much of the daemon's log goes into a diagnostic archive
+const diagnosticLogLines = 200
+
+// Diagnostics writes a redacted diagnostic archive to output for attaching
+// to bug reports. It has the running daemon's tunnels and the end of its
+// log at logPath, or the config's tunnels, all stopped, when none runs.
+func Diagnostics(w io.Writer, bundle diagnostics.Bundle, daemon Daemon, logPath string, output string) error {
+	states, err := statusStates(bundle.Config.Tunnels, daemon)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		bundle.Samples = append(bundle.Samples, metrics.Sample{
+			Name:    state.Name,
+			Tag:     state.Tag,
+			State:   state.Status,
+			Latency: time.Duration(state.LatencyMs) * time.Millisecond,
+		})
+	}
+	if daemon != nil {
+		lines, err := diagnostics.TailLines(logPath, diagnosticLogLines)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read the daemon's log: %w", err)
+		}
+		bundle.Logs = append(bundle.Logs, lines...)
+	}
+
+	if output == "" {
+		output = diagnostics.Filename(bundle.Time)
+	}
+	if err := bundle.Save(output); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %s, hostnames, users and secrets are masked. Please look it over before attaching it.\n", output)
+	return nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+	"tunnel9/internal/diagnostics"
+)
+
+func TestDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "daemon.log")
+	os.WriteFile(logPath, []byte("Starting db\nFailed to reach db.corp.internal\n"), 0600)
+	bundle := diagnostics.Bundle{
+		Version: "1.0.3",
+		Config:  config.Config{Tunnels: []config.TunnelConfig{{Name: "db", LocalPort: 5432, RemoteHost: "db.corp.internal", RemotePort: 5432}}},
+		Time:    time.Now(),
+	}
+	daemon := &fakeDaemon{states: []control.TunnelState{{Name: "db", Status: "error"}}}
+
+	var out bytes.Buffer
+	output := filepath.Join(dir, "bundle.tar.gz")
+	if err := Diagnostics(&out, bundle, daemon, logPath, output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), output) {
+		t.Errorf("expected the archive's path, got %q", out.String())
+	}
+	if info, err := os.Stat(output); err != nil || info.Size() == 0 {
+		t.Errorf("expected an archive at %s (%v)", output, err)
+	}
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+)
+
+// sshDestination is the user@host ssh would be pointed at for a tunnel
+func sshDestination(tc config.TunnelConfig) (string, int) {
+	sshEndpoint, _ := ssh.TargetEndpoints(tc)
+	destination := sshEndpoint.Host
+	if tc.Bastion.User != "" {
+		destination = tc.Bastion.User + "@" + destination
+	}
+	return destination, sshEndpoint.Port
+}
+
+// forwardSpec is the -L argument for a tunnel
+func forwardSpec(tc config.TunnelConfig) string {
+	_, remoteEndpoint := ssh.TargetEndpoints(tc)
+	spec := fmt.Sprintf("%d:%s:%d", tc.LocalPort, remoteEndpoint.Host, remoteEndpoint.Port)
+	if tc.BindAddress != "" {
+		spec = tc.BindAddress + ":" + spec
+	}
+	return spec
+}
+
+// algorithmOptions are the -o arguments matching a tunnel's ssh_options
+func algorithmOptions(opts config.SSHOptions) []string {
+	var args []string
+	for _, option := range []struct {
+		name   string
+		values []string
+	}{
+		{"Ciphers", opts.Ciphers},
+		{"MACs", opts.MACs},
+		{"KexAlgorithms", opts.KeyExchanges},
+		{"HostKeyAlgorithms", opts.HostKeyAlgorithms},
+	} {
+		if len(option.values) == 0 {
+			continue
+		}
+		// ssh_config only takes the + in front of the whole list
+		values := make([]string, len(option.values))
+		for i, value := range option.values {
+			values[i] = strings.TrimPrefix(value, "+")
+		}
+		list := strings.Join(values, ",")
+		if strings.HasPrefix(option.values[0], "+") {
+			list = "+" + list
+		}
+		args = append(args, "-o", fmt.Sprintf(`"%s %s"`, option.name, list))
+	}
+	return args
+}
+
+// AutosshCommand renders a tunnel as an equivalent autossh invocation
+func AutosshCommand(tc config.TunnelConfig) string {
+	destination, port := sshDestination(tc)
+	interval, countMax := ssh.ServerAlive(tc)
+	args := []string{
+		"autossh", "-M", "0", "-N",
+		"-o", fmt.Sprintf(`"ServerAliveInterval %d"`, int(interval.Seconds())),
+		"-o", fmt.Sprintf(`"ServerAliveCountMax %d"`, countMax),
+		"-o", `"ExitOnForwardFailure yes"`,
+		"-L", forwardSpec(tc),
+	}
+	args = append(args, algorithmOptions(tc.SSHOptions)...)
+	if port != 22 {
+		args = append(args, "-p", fmt.Sprint(port))
+	}
+	args = append(args, destination)
+	return strings.Join(args, " ")
+}
+
+var unitNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// UnitName is the systemd unit name used for an exported tunnel
+func UnitName(tc config.TunnelConfig) string {
+	return "tunnel9-" + unitNameChars.ReplaceAllString(tc.Name, "-") + ".service"
+}
+
+// SystemdUnit renders a tunnel as a systemd user unit running autossh
+func SystemdUnit(tc config.TunnelConfig) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=tunnel9 %s (%s)\n", tc.Name, forwardSpec(tc))
+	fmt.Fprintf(&b, "After=network-online.target\n")
+	fmt.Fprintf(&b, "Wants=network-online.target\n\n")
+	fmt.Fprintf(&b, "[Service]\n")
+	fmt.Fprintf(&b, "Environment=AUTOSSH_GATETIME=0\n")
+	fmt.Fprintf(&b, "ExecStart=/usr/bin/%s\n", AutosshCommand(tc))
+	fmt.Fprintf(&b, "Restart=always\n")
+	fmt.Fprintf(&b, "RestartSec=5\n\n")
+	fmt.Fprintf(&b, "[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=default.target\n")
+	return b.String()
+}
+
+// Export writes the selected tunnels as autossh commands or systemd units
+func Export(w io.Writer, tunnels []config.TunnelConfig, format string, names []string, tag string) error {
+	tunnels = filterByTag(tunnels, tag)
+	if len(names) > 0 {
+		matched, err := matchTunnels(tunnels, names)
+		if err != nil {
+			return err
+		}
+		selected := make([]config.TunnelConfig, 0, len(matched))
+		for _, i := range matched {
+			selected = append(selected, tunnels[i])
+		}
+		tunnels = selected
+	}
+
+	for i, tc := range tunnels {
+		// autossh only speaks TCP forwards
+		if tc.IsUDP() {
+			fmt.Fprintf(w, "# %s: %s tunnels have no autossh equivalent, skipped\n", tc.Name, tc.Type)
+			continue
+		}
+		// ssh can't be told to forward from whatever port is free
+		if tc.PicksPort() {
+			fmt.Fprintf(w, "# %s: local_port %s has no autossh equivalent, skipped\n", tc.Name, config.AutoPort)
+			continue
+		}
+
+		switch format {
+		case "autossh":
+			fmt.Fprintf(w, "# %s\n%s\n", tc.Name, AutosshCommand(tc))
+		case "systemd":
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "# ~/.config/systemd/user/%s\n%s", UnitName(tc), SystemdUnit(tc))
+		default:
+			return fmt.Errorf("unknown export format %q", format)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestAutosshCommand(t *testing.T) {
+	tc := config.TunnelConfig{Name: "pg", LocalPort: 15432, RemoteHost: "db.internal", RemotePort: 5432}
+	tc.Bastion.Host = "jump.example.com"
+	tc.Bastion.User = "ops"
+	tc.Bastion.Port = 2222
+
+	cmd := AutosshCommand(tc)
+	for _, want := range []string{"-L 15432:db.internal:5432", "-p 2222", "ops@jump.example.com"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %s", want, cmd)
+		}
+	}
+
+	// Without a bastion the remote host is the SSH server
+	direct := config.TunnelConfig{Name: "redis", LocalPort: 16379, RemoteHost: "cache.internal", RemotePort: 6379, BindAddress: "0.0.0.0"}
+	cmd = AutosshCommand(direct)
+	if !strings.Contains(cmd, "-L 0.0.0.0:16379:localhost:6379") || !strings.HasSuffix(cmd, " cache.internal") {
+		t.Errorf("unexpected direct command: %s", cmd)
+	}
+}
+
+func TestAutosshCommandSSHOptions(t *testing.T) {
+	tc := config.TunnelConfig{Name: "switch", LocalPort: 8443, RemoteHost: "switch.lan", RemotePort: 443}
+	tc.SSHOptions.Ciphers = []string{"aes128-cbc", "aes128-ctr"}
+	tc.SSHOptions.KeyExchanges = []string{"+diffie-hellman-group1-sha1", "+diffie-hellman-group14-sha1"}
+
+	cmd := AutosshCommand(tc)
+	for _, want := range []string{`-o "Ciphers aes128-cbc,aes128-ctr"`, `-o "KexAlgorithms +diffie-hellman-group1-sha1,diffie-hellman-group14-sha1"`} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in %s", want, cmd)
+		}
+	}
+	if strings.Contains(cmd, "MACs") {
+		t.Errorf("expected no MACs option without macs set: %s", cmd)
+	}
+}
+
+func TestExportSkipsUDP(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "wg", Type: "wireguard", RemoteHost: "vpn", LocalPort: 51820, RemotePort: 51820},
+		{Name: "web", RemoteHost: "web.internal", LocalPort: 8080, RemotePort: 80},
+	}
+
+	var out bytes.Buffer
+	if err := Export(&out, tunnels, "systemd", nil, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "# wg: wireguard tunnels have no autossh equivalent") {
+		t.Errorf("expected wireguard tunnel to be skipped, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "tunnel9-web.service") {
+		t.Errorf("expected unit for web, got:\n%s", out.String())
+	}
+}
+
+func TestExportSkipsAutoPort(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "scratch", RemoteHost: "db.internal", AutoPort: true, RemotePort: 5432},
+		{Name: "web", RemoteHost: "web.internal", LocalPort: 8080, RemotePort: 80},
+	}
+
+	var out bytes.Buffer
+	if err := Export(&out, tunnels, "autossh", nil, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "# scratch: local_port auto has no autossh equivalent") {
+		t.Errorf("expected auto port tunnel to be skipped, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "-L 0:") {
+		t.Errorf("expected no forward from port 0, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "-L 8080:localhost:80 web.internal") {
+		t.Errorf("expected a command for web, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+)
+
+// csvColumns maps accepted header names, lowercased, to config fields so
+// spreadsheets don't need renaming before import
+var csvColumns = map[string]string{
+	"name":         "name",
+	"local_port":   "local_port",
+	"local":        "local_port",
+	"remote_host":  "remote_host",
+	"host":         "remote_host",
+	"remote_port":  "remote_port",
+	"port":         "remote_port",
+	"bastion":      "bastion",
+	"jump":         "bastion",
+	"jump_box":     "bastion",
+	"jump_host":    "bastion",
+	"tag":          "tag",
+	"bind_address": "bind_address",
+	"bind":         "bind_address",
+}
+
+// ImportResult describes what an import added and what it passed over
+type ImportResult struct {
+	Added    []config.TunnelConfig
+	Skipped  []string // Rows left out, with the reason
+	Warnings []string
+}
+
+// targetKey identifies what a tunnel reaches, for duplicate detection
+func targetKey(tc config.TunnelConfig) string {
+	if tc.Bastion.Host == "" {
+		return fmt.Sprintf("%s:%d", tc.RemoteHost, tc.RemotePort)
+	}
+	return fmt.Sprintf("%s:%d via %s", tc.RemoteHost, tc.RemotePort, tc.Bastion.Host)
+}
+
+func localKey(tc config.TunnelConfig) string {
+	return fmt.Sprintf("%s:%d", tc.BindAddress, tc.LocalPort)
+}
+
+// ParseCSV reads tunnels from a CSV file with a header row. Rows without a
+// tag get defaultTag.
+func ParseCSV(r io.Reader, defaultTag string) ([]config.TunnelConfig, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), " ", "_")
+		if field, ok := csvColumns[key]; ok {
+			columns[field] = i
+		}
+	}
+	for _, required := range []string{"local_port", "remote_host", "remote_port"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column %q", required)
+		}
+	}
+
+	tunnels := make([]config.TunnelConfig, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		tc := config.TunnelConfig{
+			Name:        get("name"),
+			RemoteHost:  get("remote_host"),
+			Tag:         get("tag"),
+			BindAddress: get("bind_address"),
+		}
+		if tc.LocalPort, err = strconv.Atoi(get("local_port")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid local port %q", line, get("local_port"))
+		}
+		if tc.RemotePort, err = strconv.Atoi(get("remote_port")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid remote port %q", line, get("remote_port"))
+		}
+		if tc.RemoteHost == "" {
+			return nil, fmt.Errorf("line %d: remote host cannot be empty", line)
+		}
+		if bastion := get("bastion"); bastion != "" {
+			endpoint := ssh.NewEndpointFromString(bastion)
+			tc.Bastion.Host = endpoint.Host
+			tc.Bastion.User = endpoint.User
+			tc.Bastion.Port = endpoint.Port
+			if tc.Bastion.Port == 0 {
+				tc.Bastion.Port = 22
+			}
+		}
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("%s-%d", tc.RemoteHost, tc.LocalPort)
+		}
+		if tc.Tag == "" {
+			tc.Tag = defaultTag
+		}
+		tunnels = append(tunnels, tc)
+	}
+	return tunnels, nil
+}
+
+// MergeImport adds imported tunnels to existing ones, skipping any that reach
+// a target already configured and warning about local port clashes
+func MergeImport(existing []config.TunnelConfig, imported []config.TunnelConfig) ImportResult {
+	result := ImportResult{}
+
+	targets := make(map[string]string)
+	locals := make(map[string]string)
+	for _, tc := range existing {
+		targets[targetKey(tc)] = tc.Name
+		locals[localKey(tc)] = tc.Name
+	}
+
+	for _, tc := range imported {
+		if other, ok := targets[targetKey(tc)]; ok {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: duplicate of %s (%s)", tc.Name, other, targetKey(tc)))
+			continue
+		}
+		if other, ok := locals[localKey(tc)]; ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: local port %d also used by %s", tc.Name, tc.LocalPort, other))
+		}
+		targets[targetKey(tc)] = tc.Name
+		locals[localKey(tc)] = tc.Name
+		result.Added = append(result.Added, tc)
+	}
+	return result
+}
+
+// ImportCSV imports tunnels from a CSV file into the loaded config, writing
+// it back unless dryRun is set
+func ImportCSV(w io.Writer, loader *config.ConfigLoader, r io.Reader, defaultTag string, dryRun bool) error {
+	imported, err := ParseCSV(r, defaultTag)
+	if err != nil {
+		return err
+	}
+	return applyImport(w, loader, imported, dryRun)
+}
+
+// applyImport merges imported tunnels into the loaded config, reports what
+// happened and saves unless dryRun is set
+func applyImport(w io.Writer, loader *config.ConfigLoader, imported []config.TunnelConfig, dryRun bool) error {
+	cfg := loader.Config()
+	result := MergeImport(cfg.Tunnels, imported)
+
+	for _, tc := range result.Added {
+		fmt.Fprintf(w, "add   %s (%d -> %s)\n", tc.Name, tc.LocalPort, targetKey(tc))
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(w, "skip  %s\n", skipped)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "warn  %s\n", warning)
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d tunnel(s) would be added, %d skipped (dry run, nothing written)\n", len(result.Added), len(result.Skipped))
+		return nil
+	}
+
+	cfg.Tunnels = append(append([]config.TunnelConfig(nil), cfg.Tunnels...), result.Added...)
+	if err := loader.SaveConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d tunnel(s) added, %d skipped\n", len(result.Added), len(result.Skipped))
+	return nil
+}
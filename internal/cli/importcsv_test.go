@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := `Name,Host,Port,Local,Jump Box,Tag
+pg,db.internal,5432,15432,ops@jump.example.com:2222,
+,cache.internal,6379,16379,,cache
+`
+	tunnels, err := ParseCSV(strings.NewReader(input), "legacy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels, got %d", len(tunnels))
+	}
+
+	pg := tunnels[0]
+	if pg.RemoteHost != "db.internal" || pg.RemotePort != 5432 || pg.LocalPort != 15432 {
+		t.Errorf("unexpected tunnel: %+v", pg)
+	}
+	if pg.Bastion.Host != "jump.example.com" || pg.Bastion.User != "ops" || pg.Bastion.Port != 2222 {
+		t.Errorf("unexpected bastion: %+v", pg.Bastion)
+	}
+	if pg.Tag != "legacy" {
+		t.Errorf("expected default tag, got %s", pg.Tag)
+	}
+
+	if tunnels[1].Name != "cache.internal-16379" || tunnels[1].Tag != "cache" {
+		t.Errorf("unexpected tunnel: %+v", tunnels[1])
+	}
+
+	if _, err := ParseCSV(strings.NewReader("name,host\nx,y\n"), ""); err == nil {
+		t.Error("expected error for missing port columns")
+	}
+	if _, err := ParseCSV(strings.NewReader("host,port,local\ny,abc,1\n"), ""); err == nil {
+		t.Error("expected error for invalid port")
+	}
+}
+
+func TestMergeImport(t *testing.T) {
+	existing := []config.TunnelConfig{
+		{Name: "pg", LocalPort: 15432, RemoteHost: "db.internal", RemotePort: 5432},
+	}
+	imported := []config.TunnelConfig{
+		{Name: "pg-copy", LocalPort: 25432, RemoteHost: "db.internal", RemotePort: 5432},
+		{Name: "redis", LocalPort: 15432, RemoteHost: "cache.internal", RemotePort: 6379},
+		{Name: "redis-again", LocalPort: 16379, RemoteHost: "cache.internal", RemotePort: 6379},
+	}
+
+	result := MergeImport(existing, imported)
+	if len(result.Added) != 1 || result.Added[0].Name != "redis" {
+		t.Errorf("expected only redis added, got %+v", result.Added)
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("expected 2 skipped, got %v", result.Skipped)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected a local port warning, got %v", result.Warnings)
+	}
+}
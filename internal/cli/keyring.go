@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"tunnel9/internal/config"
+)
+
+// KeyringSet stores a secret in the OS keyring for the config to refer to as
+// keyring:<item>. The secret is read from r, without its trailing newline.
+func KeyringSet(w io.Writer, r io.Reader, item string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read the secret: %w", err)
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return fmt.Errorf("no secret given for %q", item)
+	}
+	if err := config.StoreSecret(item, secret); err != nil {
+		return fmt.Errorf("failed to store %q in the keyring: %w", item, err)
+	}
+	fmt.Fprintf(w, "Stored %q, refer to it in the config as keyring:%s\n", item, item)
+	return nil
+}
+
+// KeyringRemove deletes a secret from the OS keyring
+func KeyringRemove(w io.Writer, item string) error {
+	if err := config.DeleteSecret(item); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Removed %q from the keyring\n", item)
+	return nil
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeyring(t *testing.T) {
+	keyring.MockInit()
+	var out bytes.Buffer
+	if err := KeyringSet(&out, strings.NewReader("hunter2\n"), "smtp"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "keyring:smtp") {
+		t.Errorf("expected the config reference, got %q", out.String())
+	}
+	if secret, err := config.ResolveSecret("keyring:smtp"); err != nil || secret != "hunter2" {
+		t.Errorf("expected the stored secret, got %q (%v)", secret, err)
+	}
+
+	if err := KeyringSet(&out, strings.NewReader("\n"), "empty"); err == nil {
+		t.Error("expected an empty secret to be refused")
+	}
+	if err := KeyringRemove(&out, "smtp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := KeyringRemove(&out, "smtp"); err == nil {
+		t.Error("expected removing a missing item to fail")
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+)
+
+// Daemon is the running tunnel9 instance the start, stop and list commands
+// talk to, a control.Client in practice
+type Daemon interface {
+	List() ([]control.TunnelState, error)
+	Start(name string) error
+	Stop(name string) error
+}
+
+// List prints the tunnels of the running daemon, or those of the config when
+// none is running, optionally only those with tag
+func List(w io.Writer, tunnels []config.TunnelConfig, daemon Daemon, tag string) error {
+	var states []control.TunnelState
+	if daemon != nil {
+		var err error
+		if states, err = daemon.List(); err != nil {
+			return err
+		}
+	} else {
+		for _, tc := range tunnels {
+			states = append(states, control.TunnelState{Name: tc.Name, Tag: tc.Tag, Status: "stopped"})
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tLOCAL\tTAG\tMESSAGE")
+	for _, state := range states {
+		if tag != "" && state.Tag != tag {
+			continue
+		}
+		local := state.Local
+		if local == "" {
+			local = localAddress(tunnels, state.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", state.Name, state.Status, local, state.Tag, state.Message)
+	}
+	return tw.Flush()
+}
+
+// localAddress is where a configured tunnel listens once started, with the
+// port as auto for one that picks it when it starts
+func localAddress(tunnels []config.TunnelConfig, name string) string {
+	for _, tc := range tunnels {
+		if tc.Name != name {
+			continue
+		}
+		host := tc.BindAddress
+		if host == "" {
+			host = "localhost"
+		}
+		if tc.PicksPort() {
+			return fmt.Sprintf("%s:%s", host, config.AutoPort)
+		}
+		return fmt.Sprintf("%s:%d", host, tc.LocalPort)
+	}
+	return ""
+}
+
+// daemonTunnels returns the daemon's tunnels in a form matchTunnels takes
+func daemonTunnels(daemon Daemon) ([]config.TunnelConfig, error) {
+	states, err := daemon.List()
+	if err != nil {
+		return nil, err
+	}
+	tunnels := make([]config.TunnelConfig, len(states))
+	for i, state := range states {
+		tunnels[i] = config.TunnelConfig{Name: state.Name}
+	}
+	return tunnels, nil
+}
+
+// Start asks the daemon to start the tunnels matching names, which may be
+// globs. Once it returns, their local ports accept connections.
+func Start(w io.Writer, daemon Daemon, names []string) error {
+	return each(w, daemon, names, "Started", daemon.Start)
+}
+
+// Stop asks the daemon to stop the tunnels matching names
+func Stop(w io.Writer, daemon Daemon, names []string) error {
+	return each(w, daemon, names, "Stopped", daemon.Stop)
+}
+
+func each(w io.Writer, daemon Daemon, names []string, verb string, action func(string) error) error {
+	tunnels, err := daemonTunnels(daemon)
+	if err != nil {
+		return err
+	}
+	matched, err := matchTunnels(tunnels, names)
+	if err != nil {
+		return err
+	}
+	for _, i := range matched {
+		if err := action(tunnels[i].Name); err != nil {
+			return fmt.Errorf("%s: %w", tunnels[i].Name, err)
+		}
+		fmt.Fprintf(w, "%s %s\n", verb, tunnels[i].Name)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+)
+
+type fakeDaemon struct {
+	states []control.TunnelState
+}
+
+func (d *fakeDaemon) List() ([]control.TunnelState, error) {
+	return d.states, nil
+}
+
+func (d *fakeDaemon) set(name string, status string) error {
+	for i := range d.states {
+		if d.states[i].Name == name {
+			d.states[i].Status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("no tunnel named %s", name)
+}
+
+func (d *fakeDaemon) Start(name string) error { return d.set(name, "active") }
+func (d *fakeDaemon) Stop(name string) error  { return d.set(name, "stopped") }
+
+func TestStartStop(t *testing.T) {
+	daemon := &fakeDaemon{states: []control.TunnelState{
+		{Name: "db-a", Status: "stopped"},
+		{Name: "db-b", Status: "stopped"},
+		{Name: "web", Status: "stopped"},
+	}}
+
+	var out bytes.Buffer
+	if err := Start(&out, daemon, []string{"db-*"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if daemon.states[0].Status != "active" || daemon.states[1].Status != "active" || daemon.states[2].Status != "stopped" {
+		t.Errorf("expected only the db tunnels started, got %+v", daemon.states)
+	}
+	if out.String() != "Started db-a\nStarted db-b\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := Stop(&out, daemon, []string{"db-a"}); err != nil || daemon.states[0].Status != "stopped" {
+		t.Errorf("expected db-a stopped, got %+v (%v)", daemon.states, err)
+	}
+	if err := Stop(&out, daemon, []string{"missing"}); err == nil {
+		t.Error("expected an error for a name matching nothing")
+	}
+}
+
+func TestList(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: 5432, Tag: "prod"},
+		{Name: "web", LocalPort: 8080, BindAddress: "0.0.0.0"},
+	}
+
+	// Without a daemon the config is listed, all stopped
+	var out bytes.Buffer
+	if err := List(&out, tunnels, nil, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "0.0.0.0:8080") || strings.Count(out.String(), "stopped") != 2 {
+		t.Errorf("unexpected listing:\n%s", out.String())
+	}
+
+	daemon := &fakeDaemon{states: []control.TunnelState{
+		{Name: "db", Status: "active", Local: "localhost:5432", Tag: "prod"},
+		{Name: "web", Status: "stopped", Local: "0.0.0.0:8080"},
+	}}
+	out.Reset()
+	List(&out, tunnels, daemon, "prod")
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "active") {
+		t.Errorf("expected only the active prod tunnel, got:\n%s", out.String())
+	}
+}
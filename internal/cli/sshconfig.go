@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/localforward"
+)
+
+// ImportSSHConfig imports the LocalForward directives of an ssh_config file
+// into the loaded config, writing it back unless dryRun is set. Each tunnel
+// keeps going through its Host alias, so the rest of the ssh_config still
+// applies.
+func ImportSSHConfig(w io.Writer, loader *config.ConfigLoader, path string, defaultTag string, dryRun bool) error {
+	forwards, skipped, err := localforward.Read(path)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		fmt.Fprintf(w, "skip  %s\n", s)
+	}
+
+	imported := make([]config.TunnelConfig, 0, len(forwards))
+	for _, f := range forwards {
+		tc := f.Config
+		tc.Tag = defaultTag
+		imported = append(imported, tc)
+	}
+	return applyImport(w, loader, imported, dryRun)
+}
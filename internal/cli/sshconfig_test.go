@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestImportSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	sshConfig := filepath.Join(dir, "ssh_config")
+	err := os.WriteFile(sshConfig, []byte(`Host db
+  HostName db.example.com
+  LocalForward 5432 localhost:5432
+  LocalForward 6379 cache.internal:6379
+`), 0600)
+	if err != nil {
+		t.Fatalf("failed to write ssh_config: %v", err)
+	}
+
+	configPath := filepath.Join(dir, "config.yaml")
+	loader := config.NewConfigLoader(configPath)
+	existing := config.TunnelConfig{Name: "existing", LocalPort: 15432, RemoteHost: "localhost", RemotePort: 5432}
+	existing.Bastion.Host = "db"
+	if err := loader.SaveConfig(config.Config{Tunnels: []config.TunnelConfig{existing}}); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	if _, err := loader.Load(); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	var out strings.Builder
+	if err := ImportSSHConfig(&out, loader, sshConfig, "ssh", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "add   db-6379") || !strings.Contains(out.String(), "skip  db-5432: duplicate of existing") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	tunnels, err := config.NewConfigLoader(configPath).Load()
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if len(tunnels) != 2 || tunnels[1].Name != "db-6379" || tunnels[1].Tag != "ssh" || tunnels[1].Bastion.Host != "db" {
+		t.Errorf("unexpected tunnels: %+v", tunnels)
+	}
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+)
+
+// sshuttle options that consume the following argument
+var sshuttleArgOptions = map[string]bool{
+	"-l": true, "--listen": true,
+	"-e": true, "--ssh-cmd": true,
+	"-x": true, "--exclude": true,
+	"-X": true, "--exclude-from": true,
+	"-s": true, "--subnets": true,
+	"--python": true, "--method": true, "--pidfile": true,
+	"--ns-hosts": true, "--to-ns": true, "--user": true,
+	"--seed-hosts": true, "--remote-shell": true,
+}
+
+// sshuttleLines joins backslash continuations and drops comments
+func sshuttleLines(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	var current strings.Builder
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\") + " ")
+			continue
+		}
+		current.WriteString(line)
+		lines = append(lines, current.String())
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		lines = append(lines, current.String())
+	}
+	return lines, scanner.Err()
+}
+
+// parseSshuttleTarget turns a subnet argument like 10.0.0.5:5432 or
+// db.internal/32:5432 into a host and port, when it names a single one
+func parseSshuttleTarget(arg string) (string, int, error) {
+	host, portSpec, err := net.SplitHostPort(arg)
+	if err != nil {
+		return "", 0, fmt.Errorf("no port, sshuttle routes whole subnets")
+	}
+	if strings.Contains(portSpec, "-") {
+		return "", 0, fmt.Errorf("port range %s", portSpec)
+	}
+	port, err := strconv.Atoi(portSpec)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %s", portSpec)
+	}
+
+	if base, bits, found := strings.Cut(host, "/"); found {
+		if bits != "32" && bits != "128" {
+			return "", 0, fmt.Errorf("subnet /%s", bits)
+		}
+		host = base
+	}
+	return host, port, nil
+}
+
+// ParseSshuttle reads sshuttle invocations from a shell script and turns each
+// host:port they route into a tunnel through the -r remote. Subnets without a
+// single host and port can't be port forwarded and are reported as skipped.
+func ParseSshuttle(r io.Reader, defaultTag string) ([]config.TunnelConfig, []string, error) {
+	lines, err := sshuttleLines(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tunnels := make([]config.TunnelConfig, 0)
+	skipped := make([]string, 0)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+
+		start := -1
+		for i, field := range fields {
+			if filepath.Base(field) == "sshuttle" {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			continue
+		}
+
+		var remote string
+		targets := make([]string, 0)
+		for i := start; i < len(fields); i++ {
+			field := strings.Trim(fields[i], `"'`)
+			switch {
+			case field == "-r" || field == "--remote":
+				if i+1 < len(fields) {
+					remote = strings.Trim(fields[i+1], `"'`)
+					i++
+				}
+			case strings.HasPrefix(field, "--remote="):
+				remote = strings.TrimPrefix(field, "--remote=")
+			case strings.HasPrefix(field, "-r") && !strings.HasPrefix(field, "--"):
+				remote = strings.TrimPrefix(field, "-r")
+			case sshuttleArgOptions[field]:
+				i++
+			case strings.HasPrefix(field, "-"):
+				// Flags without arguments, or --option=value
+			default:
+				targets = append(targets, field)
+			}
+		}
+
+		if remote == "" {
+			skipped = append(skipped, fmt.Sprintf("%s: no -r remote", line))
+			continue
+		}
+		bastion := ssh.NewEndpointFromString(remote)
+
+		for _, target := range targets {
+			host, port, err := parseSshuttleTarget(target)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v", target, err))
+				continue
+			}
+
+			tc := config.TunnelConfig{
+				Name:       fmt.Sprintf("%s-%d", host, port),
+				LocalPort:  port,
+				RemoteHost: host,
+				RemotePort: port,
+				Tag:        defaultTag,
+			}
+			tc.Bastion.Host = bastion.Host
+			tc.Bastion.User = bastion.User
+			tc.Bastion.Port = bastion.Port
+			if tc.Bastion.Port == 0 {
+				tc.Bastion.Port = 22
+			}
+			tunnels = append(tunnels, tc)
+		}
+	}
+	return tunnels, skipped, nil
+}
+
+// ImportSshuttle imports the host:port targets of sshuttle invocations into
+// the loaded config, writing it back unless dryRun is set
+func ImportSshuttle(w io.Writer, loader *config.ConfigLoader, r io.Reader, defaultTag string, dryRun bool) error {
+	imported, skipped, err := ParseSshuttle(r, defaultTag)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		fmt.Fprintf(w, "skip  %s\n", s)
+	}
+	return applyImport(w, loader, imported, dryRun)
+}
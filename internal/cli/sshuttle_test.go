@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSshuttle(t *testing.T) {
+	script := `#!/bin/sh
+# sshuttle -r ignored@comment 1.1.1.1:1
+exec sshuttle --dns -r ops@jump.example.com:2222 \
+  -x 10.0.0.1 10.0.0.0/8 10.1.2.3:5432 db.internal/32:6379 1.2.3.4:8000-8080
+`
+	tunnels, skipped, err := ParseSshuttle(strings.NewReader(script), "vpn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels, got %+v", tunnels)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected subnet and port range skipped, got %v", skipped)
+	}
+
+	pg := tunnels[0]
+	if pg.RemoteHost != "10.1.2.3" || pg.RemotePort != 5432 || pg.LocalPort != 5432 || pg.Tag != "vpn" {
+		t.Errorf("unexpected tunnel: %+v", pg)
+	}
+	if pg.Bastion.Host != "jump.example.com" || pg.Bastion.User != "ops" || pg.Bastion.Port != 2222 {
+		t.Errorf("unexpected bastion: %+v", pg.Bastion)
+	}
+	if tunnels[1].RemoteHost != "db.internal" {
+		t.Errorf("expected /32 host to be unwrapped, got %s", tunnels[1].RemoteHost)
+	}
+}
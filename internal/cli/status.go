@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+	"tunnel9/internal/ssh"
+)
+
+// StatusReport is what `tunnel9 status --json` prints
+type StatusReport struct {
+	Daemon  bool          `json:"daemon"` // Whether a daemon is running, without one every tunnel is stopped
+	Tunnels []StatusEntry `json:"tunnels"`
+}
+
+// StatusEntry is one tunnel in the status report
+type StatusEntry struct {
+	Name          string     `json:"name"`
+	Tag           string     `json:"tag,omitempty"`
+	Status        string     `json:"status"`
+	Message       string     `json:"message,omitempty"`
+	Local         string     `json:"local"`
+	Remote        string     `json:"remote"`
+	Via           string     `json:"via"`
+	RateIn        float64    `json:"rate_in"`  // bytes per second
+	RateOut       float64    `json:"rate_out"` // bytes per second
+	LatencyMs     int64      `json:"latency_ms,omitempty"`
+	ActiveSince   *time.Time `json:"active_since,omitempty"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+}
+
+// statusStates returns the daemon's tunnels, or those of the config, all
+// stopped, when none is running
+func statusStates(tunnels []config.TunnelConfig, daemon Daemon) ([]control.TunnelState, error) {
+	if daemon != nil {
+		return daemon.List()
+	}
+	states := make([]control.TunnelState, 0, len(tunnels))
+	for _, tc := range tunnels {
+		sshEndpoint, remoteEndpoint := ssh.TargetEndpoints(tc)
+		states = append(states, control.TunnelState{
+			Name:   tc.Name,
+			Tag:    tc.Tag,
+			Status: "stopped",
+			Local:  localAddress(tunnels, tc.Name),
+			Remote: remoteEndpoint.String(),
+			Via:    sshEndpoint.String(),
+		})
+	}
+	return states, nil
+}
+
+// BuildStatus collects the state of every tunnel, optionally only those with
+// tag, as of now
+func BuildStatus(tunnels []config.TunnelConfig, daemon Daemon, tag string, now time.Time) (StatusReport, error) {
+	states, err := statusStates(tunnels, daemon)
+	if err != nil {
+		return StatusReport{}, err
+	}
+
+	report := StatusReport{Daemon: daemon != nil, Tunnels: make([]StatusEntry, 0, len(states))}
+	for _, state := range states {
+		if tag != "" && state.Tag != tag {
+			continue
+		}
+		entry := StatusEntry{
+			Name:        state.Name,
+			Tag:         state.Tag,
+			Status:      state.Status,
+			Message:     state.Message,
+			Local:       state.Local,
+			Remote:      state.Remote,
+			Via:         state.Via,
+			RateIn:      state.RateIn,
+			RateOut:     state.RateOut,
+			LatencyMs:   state.LatencyMs,
+			ActiveSince: state.ActiveSince,
+		}
+		if entry.Local == "" {
+			entry.Local = localAddress(tunnels, state.Name)
+		}
+		if state.ActiveSince != nil {
+			entry.UptimeSeconds = int64(now.Sub(*state.ActiveSince).Seconds())
+		}
+		report.Tunnels = append(report.Tunnels, entry)
+	}
+	return report, nil
+}
+
+// Status prints the state of every tunnel as JSON, or as a one line summary
+// short enough for a shell prompt
+func Status(w io.Writer, tunnels []config.TunnelConfig, daemon Daemon, tag string, asJSON bool) error {
+	report, err := BuildStatus(tunnels, daemon, tag, time.Now())
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	active, failed := 0, 0
+	for _, entry := range report.Tunnels {
+		switch entry.Status {
+		case "active":
+			active++
+		case "error":
+			failed++
+		}
+	}
+	summary := fmt.Sprintf("%d/%d active", active, len(report.Tunnels))
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d error", failed)
+	}
+	_, err = fmt.Fprintln(w, summary)
+	return err
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+)
+
+func TestBuildStatus(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	since := now.Add(-90 * time.Second)
+	daemon := &fakeDaemon{states: []control.TunnelState{
+		{Name: "db", Tag: "prod", Status: "active", Local: "localhost:5432", Remote: "db.internal:5432", Via: "jump:22", RateIn: 2048, LatencyMs: 12, ActiveSince: &since},
+		{Name: "web", Status: "error", Message: "connection refused"},
+	}}
+	tunnels := []config.TunnelConfig{{Name: "web", LocalPort: 8080}}
+
+	report, err := BuildStatus(tunnels, daemon, "", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Daemon || len(report.Tunnels) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if db := report.Tunnels[0]; db.UptimeSeconds != 90 || db.RateIn != 2048 || db.LatencyMs != 12 {
+		t.Errorf("unexpected db entry %+v", db)
+	}
+	// Addresses the daemon doesn't know come from the config
+	if web := report.Tunnels[1]; web.Local != "localhost:8080" || web.UptimeSeconds != 0 || web.ActiveSince != nil {
+		t.Errorf("unexpected web entry %+v", web)
+	}
+
+	if report, _ := BuildStatus(tunnels, daemon, "prod", now); len(report.Tunnels) != 1 {
+		t.Errorf("expected only prod tunnels, got %+v", report.Tunnels)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432},
+		{Name: "web", LocalPort: 8080, RemoteHost: "web.internal", RemotePort: 80},
+	}
+
+	// Without a daemon everything is stopped
+	var out bytes.Buffer
+	if err := Status(&out, tunnels, nil, "", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report StatusReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("expected JSON, got %q: %v", out.String(), err)
+	}
+	if report.Daemon || len(report.Tunnels) != 2 || report.Tunnels[0].Status != "stopped" || report.Tunnels[0].Via != "db.internal:22" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	daemon := &fakeDaemon{states: []control.TunnelState{
+		{Name: "db", Status: "active"},
+		{Name: "web", Status: "error"},
+	}}
+	out.Reset()
+	Status(&out, tunnels, daemon, "", false)
+	if out.String() != "1/2 active, 1 error\n" {
+		t.Errorf("unexpected summary %q", out.String())
+	}
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path"
+
+	"tunnel9/internal/config"
+)
+
+// TagChange is one tunnel's tag moving from Old to New
+type TagChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// matchTunnels returns the indexes of tunnels whose name matches any of the
+// glob patterns, failing on patterns that match nothing so typos are loud
+func matchTunnels(tunnels []config.TunnelConfig, patterns []string) ([]int, error) {
+	seen := make(map[int]bool)
+	matched := make([]int, 0)
+	for _, pattern := range patterns {
+		found := false
+		for i, t := range tunnels {
+			ok, err := path.Match(pattern, t.Name)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+			}
+			if !ok {
+				continue
+			}
+			found = true
+			if !seen[i] {
+				seen[i] = true
+				matched = append(matched, i)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no tunnel matches %q", pattern)
+		}
+	}
+	return matched, nil
+}
+
+// AddTag tags the tunnels matching names with tag
+func AddTag(cfg *config.Config, tag string, names []string) ([]TagChange, error) {
+	if tag == "" {
+		return nil, fmt.Errorf("tag cannot be empty")
+	}
+	matched, err := matchTunnels(cfg.Tunnels, names)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]TagChange, 0)
+	for _, i := range matched {
+		t := &cfg.Tunnels[i]
+		if t.Tag == tag {
+			continue
+		}
+		changes = append(changes, TagChange{Name: t.Name, Old: t.Tag, New: tag})
+		t.Tag = tag
+	}
+	return changes, nil
+}
+
+// RemoveTag clears tag from the tunnels carrying it, limited to those
+// matching names when any are given
+func RemoveTag(cfg *config.Config, tag string, names []string) ([]TagChange, error) {
+	candidates := make([]int, 0)
+	if len(names) > 0 {
+		matched, err := matchTunnels(cfg.Tunnels, names)
+		if err != nil {
+			return nil, err
+		}
+		candidates = matched
+	} else {
+		for i := range cfg.Tunnels {
+			candidates = append(candidates, i)
+		}
+	}
+
+	changes := make([]TagChange, 0)
+	for _, i := range candidates {
+		t := &cfg.Tunnels[i]
+		if t.Tag != tag {
+			continue
+		}
+		changes = append(changes, TagChange{Name: t.Name, Old: t.Tag})
+		t.Tag = ""
+	}
+	return changes, nil
+}
+
+// RenameTag moves every tunnel and the tag_settings entry from one tag to another
+func RenameTag(cfg *config.Config, from string, to string) ([]TagChange, error) {
+	if to == "" {
+		return nil, fmt.Errorf("new tag cannot be empty")
+	}
+
+	changes := make([]TagChange, 0)
+	for i := range cfg.Tunnels {
+		t := &cfg.Tunnels[i]
+		if t.Tag != from {
+			continue
+		}
+		changes = append(changes, TagChange{Name: t.Name, Old: from, New: to})
+		t.Tag = to
+	}
+
+	if settings, ok := cfg.TagSettings[from]; ok {
+		if _, exists := cfg.TagSettings[to]; exists {
+			return nil, fmt.Errorf("tag_settings already has an entry for %q", to)
+		}
+		cfg.TagSettings[to] = settings
+		delete(cfg.TagSettings, from)
+	}
+	return changes, nil
+}
+
+// Tag runs one of the tag subcommands against the loaded config, writing it
+// back unless dryRun is set or confirm turns down removing a tag
+func Tag(w io.Writer, loader *config.ConfigLoader, action string, args []string, dryRun bool, confirm Confirmer) error {
+	cfg := loader.Config()
+
+	// Work on copies so a dry run can't leak into the loader
+	cfg.Tunnels = append([]config.TunnelConfig(nil), cfg.Tunnels...)
+	tagSettings := make(map[string]config.TagSettings, len(cfg.TagSettings))
+	for tag, settings := range cfg.TagSettings {
+		tagSettings[tag] = settings
+	}
+	cfg.TagSettings = tagSettings
+
+	var changes []TagChange
+	var err error
+	switch action {
+	case "add":
+		changes, err = AddTag(&cfg, args[0], args[1:])
+	case "rm":
+		changes, err = RemoveTag(&cfg, args[0], args[1:])
+	case "rename":
+		changes, err = RenameTag(&cfg, args[0], args[1])
+	default:
+		err = fmt.Errorf("unknown tag command %q", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s: %q -> %q\n", c.Name, c.Old, c.New)
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d tunnel(s) would change (dry run, nothing written)\n", len(changes))
+		return nil
+	}
+
+	// Removing a tag is destructive, so it follows the confirm setting
+	if action == "rm" && len(changes) > 0 && config.ConfirmsDelete(cfg.ConfirmPolicy(args[0])) {
+		if !confirm.ask("Remove tag %q from %d tunnel(s)?", args[0], len(changes)) {
+			return fmt.Errorf("aborted, nothing written")
+		}
+	}
+
+	if len(cfg.TagSettings) == 0 {
+		cfg.TagSettings = nil
+	}
+	if err := loader.SaveConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d tunnel(s) changed\n", len(changes))
+	return nil
+}
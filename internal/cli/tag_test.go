@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func tagTestConfig() config.Config {
+	return config.Config{
+		Tunnels: []config.TunnelConfig{
+			{Name: "db-a", Tag: "old"},
+			{Name: "db-b", Tag: "old"},
+			{Name: "web", Tag: "front"},
+		},
+		TagSettings: map[string]config.TagSettings{
+			"old": {Color: "203"},
+		},
+	}
+}
+
+func TestAddTag(t *testing.T) {
+	cfg := tagTestConfig()
+	changes, err := AddTag(&cfg, "db", []string{"db-*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 2 || cfg.Tunnels[0].Tag != "db" || cfg.Tunnels[1].Tag != "db" {
+		t.Errorf("expected both db tunnels retagged, got %+v", cfg.Tunnels)
+	}
+
+	if _, err := AddTag(&cfg, "db", []string{"missing"}); err == nil {
+		t.Error("expected error for a name matching nothing")
+	}
+}
+
+func TestRemoveTag(t *testing.T) {
+	cfg := tagTestConfig()
+	changes, err := RemoveTag(&cfg, "old", []string{"db-a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || cfg.Tunnels[0].Tag != "" || cfg.Tunnels[1].Tag != "old" {
+		t.Errorf("expected only db-a untagged, got %+v", cfg.Tunnels)
+	}
+
+	changes, _ = RemoveTag(&cfg, "old", nil)
+	if len(changes) != 1 || cfg.Tunnels[1].Tag != "" {
+		t.Errorf("expected remaining old tag removed, got %+v", cfg.Tunnels)
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	cfg := tagTestConfig()
+	changes, err := RenameTag(&cfg, "old", "new")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("expected 2 changes, got %d", len(changes))
+	}
+	if _, ok := cfg.TagSettings["new"]; !ok {
+		t.Error("expected tag_settings entry to follow the rename")
+	}
+	if _, ok := cfg.TagSettings["old"]; ok {
+		t.Error("expected old tag_settings entry to be gone")
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// APIConfig opens the daemon's control protocol on a TCP address, for local
+// automation that can't use the control socket. Every request has to carry
+// one of the tokens.
+type APIConfig struct {
+	Listen string     `yaml:"listen,omitempty"` // e.g. "127.0.0.1:7709", a bare ":7709" binds loopback only
+	Tokens []APIToken `yaml:"tokens,omitempty"`
+}
+
+// APIToken lets a client use the API within its scope
+type APIToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`           // May refer to a secret, like env:API_TOKEN or keyring:api-grafana
+	Scope string `yaml:"scope,omitempty"` // What the token may do, default read
+}
+
+// Token scopes
+const (
+	ScopeRead    = "read"    // List tunnels
+	ScopeControl = "control" // List, start and stop tunnels
+)
+
+// Address is where the API listens, on loopback unless a host is given
+func (a APIConfig) Address() string {
+	host, port, err := net.SplitHostPort(a.Listen)
+	if err != nil || host != "" {
+		return a.Listen
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// TokenScope is a token's scope, read when it doesn't say
+func (t APIToken) TokenScope() string {
+	if t.Scope == "" {
+		return ScopeRead
+	}
+	return t.Scope
+}
+
+func (a APIConfig) validate() []error {
+	var errs []error
+	if a.Listen != "" {
+		if _, _, err := net.SplitHostPort(a.Listen); err != nil {
+			errs = append(errs, fmt.Errorf("api: invalid listen %q, expected host:port", a.Listen))
+		}
+		if len(a.Tokens) == 0 {
+			errs = append(errs, fmt.Errorf("api: listen is set but there are no tokens"))
+		}
+	}
+	names := make(map[string]bool)
+	for i, token := range a.Tokens {
+		label := fmt.Sprintf("api token %d", i+1)
+		if token.Name != "" {
+			label = fmt.Sprintf("api token %q", token.Name)
+		}
+		switch {
+		case token.Name == "":
+			errs = append(errs, fmt.Errorf("%s: name is required", label))
+		case names[token.Name]:
+			errs = append(errs, fmt.Errorf("%s: duplicate name", label))
+		}
+		names[token.Name] = true
+		if token.Token == "" {
+			errs = append(errs, fmt.Errorf("%s: token is required", label))
+		}
+		if token.Scope != "" && !contains(schemaEnums["scope"], token.Scope) {
+			errs = append(errs, fmt.Errorf("%s: unknown scope %q", label, token.Scope))
+		}
+	}
+	return errs
+}
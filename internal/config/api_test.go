@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestAPIConfig(t *testing.T) {
+	if got := (APIConfig{Listen: ":7709"}).Address(); got != "127.0.0.1:7709" {
+		t.Errorf("expected a bare port on loopback, got %s", got)
+	}
+	if got := (APIConfig{Listen: "0.0.0.0:7709"}).Address(); got != "0.0.0.0:7709" {
+		t.Errorf("expected an explicit host kept, got %s", got)
+	}
+	if (APIToken{}).TokenScope() != ScopeRead {
+		t.Error("expected tokens read-only by default")
+	}
+}
+
+func TestConfig_ValidateAPI(t *testing.T) {
+	cfg := Config{API: APIConfig{Listen: ":7709", Tokens: []APIToken{{Name: "ci", Token: "env:CI_TOKEN", Scope: ScopeControl}}}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.API = APIConfig{Listen: "7709", Tokens: []APIToken{{Name: "ci", Scope: "admin"}, {Name: "ci", Token: "x"}, {Token: "y"}}}
+	// Bad listen, missing token, unknown scope, duplicate name, missing name
+	if errs := cfg.Validate(); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %v", errs)
+	}
+	if errs := (Config{API: APIConfig{Listen: ":7709"}}).Validate(); len(errs) != 1 {
+		t.Errorf("expected an API without tokens refused, got %v", errs)
+	}
+}
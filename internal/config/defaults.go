@@ -0,0 +1,73 @@
+package config
+
+import "reflect"
+
+// TunnelDefaults are settings every tunnel inherits unless it sets its own,
+// such as the jump host most tunnels share
+type TunnelDefaults struct {
+	Bastion struct {
+		Host string `yaml:"host,omitempty"`
+		User string `yaml:"user,omitempty"`
+		Port int    `yaml:"port,omitempty"`
+	} `yaml:"bastion,omitempty"`
+	BindAddress string     `yaml:"bind_address,omitempty"`
+	Tag         string     `yaml:"tag,omitempty"`
+	SSHOptions  SSHOptions `yaml:"ssh_options,omitempty"`
+}
+
+// Apply fills in what a tunnel leaves out from the defaults
+func (d TunnelDefaults) Apply(tc TunnelConfig) TunnelConfig {
+	inherit(&tc.Bastion.Host, d.Bastion.Host)
+	inherit(&tc.Bastion.User, d.Bastion.User)
+	inherit(&tc.Bastion.Port, d.Bastion.Port)
+	if tc.BindInterface == "" {
+		inherit(&tc.BindAddress, d.BindAddress)
+	}
+	inherit(&tc.Tag, d.Tag)
+	inherit(&tc.SSHOptions.Ciphers, d.SSHOptions.Ciphers)
+	inherit(&tc.SSHOptions.MACs, d.SSHOptions.MACs)
+	inherit(&tc.SSHOptions.KeyExchanges, d.SSHOptions.KeyExchanges)
+	inherit(&tc.SSHOptions.HostKeyAlgorithms, d.SSHOptions.HostKeyAlgorithms)
+	return tc
+}
+
+// Strip leaves out what a tunnel has in common with the defaults, so saving
+// doesn't copy them into every tunnel and later changes to the defaults
+// still reach it
+func (d TunnelDefaults) Strip(tc TunnelConfig) TunnelConfig {
+	omit(&tc.Bastion.Host, d.Bastion.Host)
+	omit(&tc.Bastion.User, d.Bastion.User)
+	omit(&tc.Bastion.Port, d.Bastion.Port)
+	omit(&tc.BindAddress, d.BindAddress)
+	omit(&tc.Tag, d.Tag)
+	omit(&tc.SSHOptions.Ciphers, d.SSHOptions.Ciphers)
+	omit(&tc.SSHOptions.MACs, d.SSHOptions.MACs)
+	omit(&tc.SSHOptions.KeyExchanges, d.SSHOptions.KeyExchanges)
+	omit(&tc.SSHOptions.HostKeyAlgorithms, d.SSHOptions.HostKeyAlgorithms)
+	return tc
+}
+
+// ApplyDefaults returns the tunnels with the defaults filled in
+func (c Config) ApplyDefaults(tunnels []TunnelConfig) []TunnelConfig {
+	applied := make([]TunnelConfig, len(tunnels))
+	for i, tc := range tunnels {
+		applied[i] = c.Defaults.Apply(tc)
+	}
+	return applied
+}
+
+// inherit sets an unset field to the default
+func inherit[T any](field *T, value T) {
+	var zero T
+	if reflect.DeepEqual(*field, zero) {
+		*field = value
+	}
+}
+
+// omit unsets a field that holds the default
+func omit[T any](field *T, value T) {
+	var zero T
+	if !reflect.DeepEqual(value, zero) && reflect.DeepEqual(*field, value) {
+		*field = zero
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testDefaults() TunnelDefaults {
+	var d TunnelDefaults
+	d.Bastion.Host = "jump.prod"
+	d.Bastion.User = "ops"
+	d.BindAddress = "127.0.0.1"
+	d.Tag = "production"
+	d.SSHOptions.Ciphers = []string{"aes256-gcm@openssh.com"}
+	return d
+}
+
+func TestDefaultsApply(t *testing.T) {
+	d := testDefaults()
+
+	inherited := d.Apply(TunnelConfig{Name: "db"})
+	if inherited.Bastion.Host != "jump.prod" || inherited.Bastion.User != "ops" || inherited.Tag != "production" {
+		t.Errorf("expected the defaults inherited, got %+v", inherited)
+	}
+	if inherited.BindAddress != "127.0.0.1" || !reflect.DeepEqual(inherited.SSHOptions.Ciphers, d.SSHOptions.Ciphers) {
+		t.Errorf("expected bind address and ciphers inherited, got %+v", inherited)
+	}
+
+	tc := TunnelConfig{Name: "db", Tag: "staging", BindInterface: "utun3"}
+	tc.Bastion.User = "me"
+	overridden := d.Apply(tc)
+	if overridden.Tag != "staging" || overridden.Bastion.User != "me" || overridden.Bastion.Host != "jump.prod" {
+		t.Errorf("expected own values kept and the rest inherited, got %+v", overridden)
+	}
+	if overridden.BindAddress != "" {
+		t.Errorf("expected no bind address with a bind interface, got %q", overridden.BindAddress)
+	}
+}
+
+func TestDefaultsStrip(t *testing.T) {
+	d := testDefaults()
+	tc := d.Apply(TunnelConfig{Name: "db"})
+	tc.Tag = "staging"
+
+	stripped := d.Strip(tc)
+	if stripped.Bastion.Host != "" || stripped.BindAddress != "" || stripped.SSHOptions.Ciphers != nil {
+		t.Errorf("expected inherited values stripped, got %+v", stripped)
+	}
+	if stripped.Tag != "staging" {
+		t.Errorf("expected the override kept, got %q", stripped.Tag)
+	}
+	if !reflect.DeepEqual(d.Apply(stripped), tc) {
+		t.Errorf("expected applying the defaults to give the tunnel back, got %+v", d.Apply(stripped))
+	}
+}
+
+func TestDefaultsStayOutOfSavedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`defaults:
+  bastion:
+    host: jump.prod
+  tag: production
+tunnels:
+  - name: db
+    remote_host: db.internal
+    remote_port: 5432
+    local_port: 5432
+  - name: cache
+    remote_host: cache.internal
+    remote_port: 6379
+    local_port: 6379
+    tag: staging
+`), 0644)
+
+	loader := NewConfigLoader(path)
+	tunnels, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tunnels[0].Bastion.Host != "jump.prod" || tunnels[0].Tag != "production" || tunnels[1].Tag != "staging" {
+		t.Fatalf("expected the defaults applied on load, got %+v", tunnels)
+	}
+	if err := loader.Save(tunnels); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "jump.prod") != 1 || strings.Count(string(data), "production") != 1 {
+		t.Errorf("expected the defaults only in the defaults block, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "tag: staging") {
+		t.Errorf("expected the override saved, got:\n%s", data)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// What a tunnel change does
+const (
+	ChangeAdd    = "add"
+	ChangeUpdate = "update"
+	ChangeRemove = "remove"
+)
+
+// TunnelChange is a tunnel added, updated or removed between two configs,
+// matched up by name
+type TunnelChange struct {
+	Action string
+	Name   string
+	Fields []string     // Settings an update changes, as named in the YAML
+	Config TunnelConfig // The tunnel as it will be, unset for a removal
+}
+
+// DiffTunnels lists what it takes to get from one set of tunnels to another:
+// removals in the old order first, then updates and additions in the new
+// order
+func DiffTunnels(from []TunnelConfig, to []TunnelConfig) []TunnelChange {
+	old := make(map[string]TunnelConfig, len(from))
+	for _, tc := range from {
+		old[tc.Name] = tc
+	}
+	kept := make(map[string]bool, len(to))
+	for _, tc := range to {
+		kept[tc.Name] = true
+	}
+
+	changes := make([]TunnelChange, 0)
+	for _, tc := range from {
+		if !kept[tc.Name] {
+			changes = append(changes, TunnelChange{Action: ChangeRemove, Name: tc.Name})
+		}
+	}
+	for _, tc := range to {
+		previous, ok := old[tc.Name]
+		if !ok {
+			changes = append(changes, TunnelChange{Action: ChangeAdd, Name: tc.Name, Config: tc})
+			continue
+		}
+		if fields := changedFields(previous, tc); len(fields) > 0 {
+			changes = append(changes, TunnelChange{Action: ChangeUpdate, Name: tc.Name, Fields: fields, Config: tc})
+		}
+	}
+	return changes
+}
+
+// changedFields names the top-level settings that differ between two
+// tunnels
+func changedFields(a TunnelConfig, b TunnelConfig) []string {
+	fields := make([]string, 0)
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		// AutoPort is written as local_port
+		if t.Field(i).Name == "AutoPort" {
+			name = "local_port"
+			if contains(fields, name) {
+				continue
+			}
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTunnels(t *testing.T) {
+	db := TunnelConfig{Name: "db", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432}
+	web := TunnelConfig{Name: "web", LocalPort: 8080, RemoteHost: "web.internal", RemotePort: 80}
+	cache := TunnelConfig{Name: "cache", LocalPort: 6379, RemoteHost: "cache.internal", RemotePort: 6379}
+
+	moved := db
+	moved.LocalPort = 15432
+	moved.Bastion.Host = "jump.prod"
+
+	changes := DiffTunnels([]TunnelConfig{db, web}, []TunnelConfig{cache, moved})
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if changes[0].Action != ChangeRemove || changes[0].Name != "web" {
+		t.Errorf("expected web removed first, got %+v", changes[0])
+	}
+	if changes[1].Action != ChangeAdd || changes[1].Name != "cache" || changes[1].Config.LocalPort != 6379 {
+		t.Errorf("expected cache added, got %+v", changes[1])
+	}
+	if changes[2].Action != ChangeUpdate || changes[2].Name != "db" {
+		t.Errorf("expected db updated, got %+v", changes[2])
+	}
+	if !reflect.DeepEqual(changes[2].Fields, []string{"local_port", "bastion"}) {
+		t.Errorf("expected the YAML names of what changed, got %v", changes[2].Fields)
+	}
+
+	if changes := DiffTunnels([]TunnelConfig{db, web}, []TunnelConfig{web, db}); len(changes) != 0 {
+		t.Errorf("expected reordering alone to change nothing, got %+v", changes)
+	}
+}
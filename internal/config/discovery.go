@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// Largest subnet discovery scans, as every address is dialed once per port
+const MaxDiscoverySubnetBits = 12
+
+// DiscoveryConfig says where to look for services behind a jump host, to
+// propose tunnels to them
+type DiscoveryConfig struct {
+	Sources []string `yaml:"sources,omitempty"` // Looked through in order, default scan and hosts
+	Subnets []string `yaml:"subnets,omitempty"` // e.g. "10.0.1.0/24", scanned by the scan source
+	Ports   []int    `yaml:"ports,omitempty"`   // Probed on every address scan and hosts find
+	Consul  string   `yaml:"consul,omitempty"`  // Consul's HTTP API as seen from the jump host, default 127.0.0.1:8500
+}
+
+// Discovery sources
+const (
+	DiscoveryScan   = "scan"   // Dial every address of the subnets on every port
+	DiscoveryHosts  = "hosts"  // Dial the addresses in the jump host's /etc/hosts on every port
+	DiscoveryConsul = "consul" // Services registered in Consul
+	DiscoveryKube   = "kube"   // Endpoints kubectl on the jump host lists
+)
+
+// DiscoverySources are the sources to look through, in order
+func (d DiscoveryConfig) DiscoverySources() []string {
+	if len(d.Sources) == 0 {
+		return []string{DiscoveryScan, DiscoveryHosts}
+	}
+	return d.Sources
+}
+
+// ConsulAddress is where Consul's HTTP API is, as seen from the jump host
+func (d DiscoveryConfig) ConsulAddress() string {
+	if d.Consul == "" {
+		return "127.0.0.1:8500"
+	}
+	return d.Consul
+}
+
+func (d DiscoveryConfig) validate() []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("discovery: %s", fmt.Sprintf(format, args...)))
+	}
+	for _, source := range d.Sources {
+		if !contains(schemaEnums["sources"], source) {
+			fail("unknown source %q", source)
+		}
+	}
+	for _, subnet := range d.Subnets {
+		_, network, err := net.ParseCIDR(subnet)
+		if err != nil {
+			fail("invalid subnet %q, expected e.g. 10.0.1.0/24", subnet)
+			continue
+		}
+		ones, bits := network.Mask.Size()
+		if bits-ones > MaxDiscoverySubnetBits {
+			fail("subnet %q is too large, at most /%d", subnet, bits-MaxDiscoverySubnetBits)
+		}
+	}
+	for _, port := range d.Ports {
+		if port < 1 || port > 65535 {
+			fail("invalid port %d", port)
+		}
+	}
+	if d.Consul != "" {
+		if _, _, err := net.SplitHostPort(d.Consul); err != nil {
+			fail("invalid consul %q, expected host:port", d.Consul)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseDSCP reads a dscp setting, a name like ef, af21, cs1 or le as used by
+// IPQoS in ssh_config, or a codepoint from 0 to 63
+func ParseDSCP(value string) (int, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case name == "ef":
+		return 46, nil
+	case name == "le":
+		return 1, nil
+	case len(name) == 3 && strings.HasPrefix(name, "cs") && name[2] >= '0' && name[2] <= '7':
+		return int(name[2]-'0') * 8, nil
+	case len(name) == 4 && strings.HasPrefix(name, "af") && name[2] >= '1' && name[2] <= '4' && name[3] >= '1' && name[3] <= '3':
+		// Class times 8 plus drop precedence times 2, af21 is 18
+		return int(name[2]-'0')*8 + int(name[3]-'0')*2, nil
+	}
+	codepoint, err := strconv.Atoi(name)
+	if err != nil || codepoint < 0 || codepoint > 63 {
+		return 0, fmt.Errorf("invalid dscp %q, expected e.g. ef, af21, cs1 or 0-63", value)
+	}
+	return codepoint, nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestParseDSCP(t *testing.T) {
+	for value, want := range map[string]int{
+		"ef":   46,
+		"EF":   46,
+		"af11": 10,
+		"af21": 18,
+		"af43": 38,
+		"cs0":  0,
+		"cs1":  8,
+		"cs6":  48,
+		"le":   1,
+		"34":   34,
+	} {
+		if got, err := ParseDSCP(value); err != nil || got != want {
+			t.Errorf("ParseDSCP(%q) = %d (%v), want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "af14", "af51", "cs8", "64", "-1", "lowdelay"} {
+		if _, err := ParseDSCP(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
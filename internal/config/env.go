@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that configure tunnel9 without a config file, for
+// running in a container
+const (
+	EnvConfigYAML    = "TUNNEL9_CONFIG_YAML"    // A whole config file, inline
+	EnvTunnelPrefix  = "TUNNEL9_TUNNEL_"        // One tunnel each, named after the rest of the variable
+	EnvIdentityFiles = "TUNNEL9_IDENTITY_FILES" // Comma separated, like ssh.identity_files
+	EnvMetricsListen = "TUNNEL9_METRICS_LISTEN"
+	EnvMetrics       = "TUNNEL9_METRICS"
+)
+
+// FromEnv builds a config from the environment, TUNNEL9_CONFIG_YAML first
+// and then the other variables on top. Tunnels given as TUNNEL9_TUNNEL_<NAME>
+// read [bind_address:]local_port:remote_host:remote_port@[user@]host[:port]
+// and autostart, as nobody is around to start them.
+func FromEnv(environ []string) (Config, error) {
+	vars := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if key, value, found := strings.Cut(entry, "="); found {
+			vars[key] = value
+		}
+	}
+
+	var config Config
+	if data := vars[EnvConfigYAML]; data != "" {
+		stripped, auto := StripAutoPorts([]byte(data))
+		if err := yaml.Unmarshal(stripped, &config); err != nil {
+			return config, fmt.Errorf("%s: %w", EnvConfigYAML, err)
+		}
+		config.MarkAutoPorts(auto)
+	}
+	if files := vars[EnvIdentityFiles]; files != "" {
+		config.SSH.IdentityFiles = strings.Split(files, ",")
+	}
+	if addr := vars[EnvMetricsListen]; addr != "" {
+		config.MetricsListen = addr
+	}
+	if level := vars[EnvMetrics]; level != "" {
+		config.Metrics = level
+	}
+
+	// Sorted, as the environment has no order of its own
+	keys := make([]string, 0)
+	for key := range vars {
+		if strings.HasPrefix(key, EnvTunnelPrefix) && len(key) > len(EnvTunnelPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(key, EnvTunnelPrefix)), "_", "-")
+		tc, err := ParseTunnelSpec(name, vars[key])
+		if err != nil {
+			return config, fmt.Errorf("%s: %w", key, err)
+		}
+		config.Tunnels = append(config.Tunnels, tc)
+	}
+	return config, nil
+}
+
+// ParseTunnelSpec reads a tunnel written on one line, as
+// [bind_address:]local_port:remote_host:remote_port@[user@]host[:port], where
+// local_port may be auto
+func ParseTunnelSpec(name string, spec string) (TunnelConfig, error) {
+	tc := TunnelConfig{Name: name, Autostart: true}
+	forward, via, found := strings.Cut(spec, "@")
+	if !found || via == "" {
+		return tc, fmt.Errorf("%q names no SSH server after @", spec)
+	}
+
+	parts := strings.Split(forward, ":")
+	if len(parts) == 4 {
+		tc.BindAddress = parts[0]
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return tc, fmt.Errorf("%q isn't [bind_address:]local_port:remote_host:remote_port", forward)
+	}
+	var err error
+	if tc.LocalPort, tc.AutoPort, err = ParseLocalPort(parts[0]); err != nil {
+		return tc, err
+	}
+	if tc.RemotePort, err = strconv.Atoi(parts[2]); err != nil {
+		return tc, fmt.Errorf("invalid remote port %q", parts[2])
+	}
+	tc.RemoteHost = parts[1]
+
+	if at := strings.LastIndex(via, "@"); at >= 0 {
+		tc.Bastion.User = via[:at]
+		via = via[at+1:]
+	}
+	tc.Bastion.Host = via
+	if host, port, found := strings.Cut(via, ":"); found {
+		tc.Bastion.Host = host
+		if tc.Bastion.Port, err = strconv.Atoi(port); err != nil {
+			return tc, fmt.Errorf("invalid SSH port %q", port)
+		}
+	}
+	return tc, nil
+}
+
+// LoadEnv reads the config from the environment instead of the loader's
+// file, refusing one that doesn't validate. It is never saved back.
+func (c *ConfigLoader) LoadEnv(environ []string) ([]TunnelConfig, error) {
+	config, err := FromEnv(environ)
+	if err != nil {
+		return nil, err
+	}
+	if errs := config.Validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	config.Tunnels, c.assigned = config.AssignPorts()
+	config.Tunnels = config.ApplyDefaults(config.Tunnels)
+	c.config = config
+	c.fromEnv = true
+	return config.Tunnels, nil
+}
+
+// ResolveSecret returns the value a setting like a password refers to:
+// "env:NAME" reads an environment variable, "file:/path" a file, such as
+// a mounted secret, without its trailing newline, "keyring:item" the OS
+// keyring and "pass://path" the first line of a pass or gopass entry.
+// Anything else is taken as it is.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s isn't set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, "keyring:"):
+		return readKeyring(strings.TrimPrefix(value, "keyring:"))
+	case strings.HasPrefix(value, PassPrefix):
+		lines, err := readPass(value)
+		if err != nil {
+			return "", err
+		}
+		return lines[0], nil
+	}
+	return value, nil
+}
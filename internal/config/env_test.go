@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestFromEnv(t *testing.T) {
+	config, err := FromEnv([]string{
+		"PATH=/usr/bin",
+		EnvConfigYAML + "=confirm: none\nmetrics: basic\n",
+		EnvTunnelPrefix + "WEB=8080:web.internal:80@jump",
+		EnvTunnelPrefix + "DB_PRIMARY=0.0.0.0:5432:db.internal:5432@ops@jump:2222",
+		EnvIdentityFiles + "=/run/secrets/id_ed25519,id_rsa",
+		EnvMetrics + "=off",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Confirm != ConfirmNone || config.Metrics != MetricsOff {
+		t.Errorf("expected the inline config with variables on top, got confirm %q metrics %q", config.Confirm, config.Metrics)
+	}
+	if strings.Join(config.SSH.IdentityFiles, " ") != "/run/secrets/id_ed25519 id_rsa" {
+		t.Errorf("unexpected identity files %v", config.SSH.IdentityFiles)
+	}
+	if len(config.Tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels, got %+v", config.Tunnels)
+	}
+
+	db := config.Tunnels[0]
+	if db.Name != "db-primary" || db.BindAddress != "0.0.0.0" || db.LocalPort != 5432 || db.RemoteHost != "db.internal" ||
+		db.RemotePort != 5432 || db.Bastion.User != "ops" || db.Bastion.Host != "jump" || db.Bastion.Port != 2222 || !db.Autostart {
+		t.Errorf("unexpected db tunnel %+v", db)
+	}
+	web := config.Tunnels[1]
+	if web.Name != "web" || web.LocalPort != 8080 || web.Bastion.Host != "jump" || web.Bastion.User != "" || web.Bastion.Port != 0 {
+		t.Errorf("unexpected web tunnel %+v", web)
+	}
+
+	if scratch, err := ParseTunnelSpec("scratch", "auto:web:80@jump"); err != nil || !scratch.AutoPort {
+		t.Errorf("expected auto to pick the port at start, got %+v (%v)", scratch, err)
+	}
+
+	for _, spec := range []string{"8080:web:80", "web:80@jump", "x:web:80@jump", "8080:web:80@jump:ssh"} {
+		if _, err := ParseTunnelSpec("web", spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	loader := NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	if _, err := loader.LoadEnv([]string{EnvTunnelPrefix + "WEB=99999:web:80@jump"}); err == nil {
+		t.Errorf("expected an invalid tunnel to be refused")
+	}
+
+	tunnels, err := loader.LoadEnv([]string{EnvTunnelPrefix + "WEB=8080:web:80@jump"})
+	if err != nil || len(tunnels) != 1 {
+		t.Fatalf("expected one tunnel, got %+v (%v)", tunnels, err)
+	}
+	if err := loader.Save(tunnels); err == nil {
+		t.Errorf("expected a config from the environment not to be saved")
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	keyring.MockInit()
+	if err := StoreSecret("smtp", "k3yring"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TUNNEL9_TEST_SECRET", "hunter2")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for value, want := range map[string]string{
+		"plain":                   "plain",
+		"env:TUNNEL9_TEST_SECRET": "hunter2",
+		"file:" + path:            "s3cret",
+		"keyring:smtp":            "k3yring",
+	} {
+		if got, err := ResolveSecret(value); err != nil || got != want {
+			t.Errorf("ResolveSecret(%q) = %q (%v), expected %q", value, got, err, want)
+		}
+	}
+	if _, err := ResolveSecret("env:TUNNEL9_TEST_UNSET"); err == nil {
+		t.Errorf("expected an error for an unset variable")
+	}
+	if _, err := ResolveSecret("keyring:missing"); err == nil || !strings.Contains(err.Error(), "tunnel9 keyring set missing") {
+		t.Errorf("expected a hint to add the missing item, got %v", err)
+	}
+}
+
+func TestResolvePassSecrets(t *testing.T) {
+	entries := map[string]string{
+		"work/ssh": "hunter2\nuser: alice\n",
+		"work/otp": "hunter2\notpauth://totp/work?secret=GEZDGNBV\n",
+	}
+	saved := runPass
+	defer func() { runPass = saved }()
+	runPass = func(entry string) ([]byte, error) {
+		out, ok := entries[entry]
+		if !ok {
+			return nil, fmt.Errorf("%s is not in the password store", entry)
+		}
+		return []byte(out), nil
+	}
+
+	if secret, err := ResolveSecret("pass://work/ssh"); err != nil || secret != "hunter2" {
+		t.Errorf("expected the first line, got %q (%v)", secret, err)
+	}
+	if seed, err := ResolveTOTPSeed("pass://work/otp"); err != nil || seed != "otpauth://totp/work?secret=GEZDGNBV" {
+		t.Errorf("expected the otpauth line, got %q (%v)", seed, err)
+	}
+	if seed, err := ResolveTOTPSeed("pass://work/ssh"); err != nil || seed != "hunter2" {
+		t.Errorf("expected the first line without an otpauth line, got %q (%v)", seed, err)
+	}
+	if _, err := ResolveSecret("pass://work/missing"); err == nil {
+		t.Error("expected a missing entry to fail")
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// DefaultStepTimeout is how long a startup group waits for a step's tunnels
+// to come up before giving up on the rest
+const DefaultStepTimeout = time.Minute
+
+// StartupGroup brings up a set of tunnels in order with one action, e.g. a
+// VPN-like jump tunnel before the databases behind it
+type StartupGroup struct {
+	Name      string      `yaml:"name"`
+	Autostart bool        `yaml:"autostart,omitempty"` // Run when tunnel9 starts
+	Steps     []GroupStep `yaml:"steps"`
+}
+
+// GroupStep is one stage of a startup group. It starts once every tunnel of
+// the step before is active.
+type GroupStep struct {
+	Tunnels []string `yaml:"tunnels,omitempty"` // Names, which may be globs like "db-*"
+	Tags    []string `yaml:"tags,omitempty"`
+	Delay   string   `yaml:"delay,omitempty"`   // Wait after the step before is up, e.g. "5s"
+	Timeout string   `yaml:"timeout,omitempty"` // How long its tunnels may take to come up, default 1m
+}
+
+// Matches reports whether a tunnel belongs to the step
+func (s GroupStep) Matches(tc TunnelConfig) bool {
+	if contains(s.Tags, tc.Tag) {
+		return true
+	}
+	for _, pattern := range s.Tunnels {
+		if ok, _ := path.Match(pattern, tc.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DelayDuration is how long the step waits before starting, zero when unset
+func (s GroupStep) DelayDuration() time.Duration {
+	delay, _ := time.ParseDuration(s.Delay)
+	return delay
+}
+
+// TimeoutDuration is how long the step's tunnels may take to come up
+func (s GroupStep) TimeoutDuration() time.Duration {
+	if timeout, err := time.ParseDuration(s.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return DefaultStepTimeout
+}
+
+// validateGroups checks the startup groups against the tunnels they start
+func validateGroups(groups []StartupGroup, tunnels []TunnelConfig) []error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, group := range groups {
+		label := fmt.Sprintf("startup group %d", i+1)
+		if group.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", label))
+		} else {
+			label = fmt.Sprintf("startup group %q", group.Name)
+			if names[group.Name] {
+				errs = append(errs, fmt.Errorf("%s: duplicate name", label))
+			}
+			names[group.Name] = true
+		}
+		if len(group.Steps) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no steps", label))
+		}
+		for j, step := range group.Steps {
+			fail := func(format string, args ...interface{}) {
+				errs = append(errs, fmt.Errorf("%s step %d: %s", label, j+1, fmt.Sprintf(format, args...)))
+			}
+			for _, pattern := range step.Tunnels {
+				if _, err := path.Match(pattern, ""); err != nil {
+					fail("bad pattern %q", pattern)
+				}
+			}
+			matched := false
+			for _, tc := range tunnels {
+				matched = matched || step.Matches(tc)
+			}
+			if !matched {
+				fail("matches no tunnel")
+			}
+			if step.Delay != "" {
+				if delay, err := time.ParseDuration(step.Delay); err != nil || delay < 0 {
+					fail("invalid delay %q", step.Delay)
+				}
+			}
+			if step.Timeout != "" {
+				if timeout, err := time.ParseDuration(step.Timeout); err != nil || timeout <= 0 {
+					fail("invalid timeout %q", step.Timeout)
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupStep(t *testing.T) {
+	step := GroupStep{Tunnels: []string{"db-*"}, Tags: []string{"vpn"}, Delay: "5s"}
+	for _, c := range []struct {
+		tc   TunnelConfig
+		want bool
+	}{
+		{TunnelConfig{Name: "db-primary"}, true},
+		{TunnelConfig{Name: "jump", Tag: "vpn"}, true},
+		{TunnelConfig{Name: "web", Tag: "stage"}, false},
+	} {
+		if step.Matches(c.tc) != c.want {
+			t.Errorf("Matches(%s) = %v, expected %v", c.tc.Name, !c.want, c.want)
+		}
+	}
+	if step.DelayDuration() != 5*time.Second || step.TimeoutDuration() != DefaultStepTimeout {
+		t.Errorf("unexpected delay %s or timeout %s", step.DelayDuration(), step.TimeoutDuration())
+	}
+}
+
+func TestConfig_ValidateStartupGroups(t *testing.T) {
+	cfg := Config{
+		Tunnels: []TunnelConfig{
+			{Name: "jump", LocalPort: 1, RemoteHost: "a", RemotePort: 1, Tag: "vpn"},
+			{Name: "db-primary", LocalPort: 2, RemoteHost: "a", RemotePort: 2},
+		},
+		StartupGroups: []StartupGroup{{
+			Name:  "standup",
+			Steps: []GroupStep{{Tags: []string{"vpn"}}, {Tunnels: []string{"db-*"}, Delay: "5s", Timeout: "2m"}},
+		}},
+	}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.StartupGroups = append(cfg.StartupGroups,
+		StartupGroup{Name: "standup"},
+		StartupGroup{Steps: []GroupStep{{Tunnels: []string{"[", "web"}, Delay: "soon", Timeout: "0s"}}},
+	)
+	// Duplicate name, no steps, no name, bad pattern, no match, bad delay and timeout
+	if errs := cfg.Validate(); len(errs) != 7 {
+		t.Errorf("expected 7 errors, got %v", errs)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringService is what tunnel9's secrets are filed under in the OS
+// keyring: the macOS Keychain, Secret Service or Windows Credential Manager
+const KeyringService = "tunnel9"
+
+// readKeyring fetches a secret stored with StoreSecret
+func readKeyring(item string) (string, error) {
+	secret, err := keyring.Get(KeyringService, item)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("no %s item %q in the keyring, add it with: tunnel9 keyring set %s", KeyringService, item, item)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %q from the keyring: %w", item, err)
+	}
+	return secret, nil
+}
+
+// StoreSecret saves a secret in the OS keyring, for the config to refer to
+// as keyring:item
+func StoreSecret(item string, secret string) error {
+	return keyring.Set(KeyringService, item, secret)
+}
+
+// DeleteSecret removes a secret from the OS keyring
+func DeleteSecret(item string) error {
+	err := keyring.Delete(KeyringService, item)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("no %s item %q in the keyring", KeyringService, item)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LogFileOff turns the log file off
+const LogFileOff = "off"
+
+// Size and number of rotated log files unless the config says otherwise
+const (
+	DefaultLogMaxSize = 10 // Megabytes
+	DefaultLogKeep    = 3
+)
+
+// LogConfig keeps everything the console shows in a file, rotated by size
+type LogConfig struct {
+	File    string `yaml:"file,omitempty"`     // Defaults to ~/.local/state/tunnel9/tunnel9.log, "off" keeps none
+	MaxSize int    `yaml:"max_size,omitempty"` // Megabytes before the file is rotated, default 10
+	Keep    int    `yaml:"keep,omitempty"`     // Rotated files kept, default 3
+}
+
+// Path is where the log file is kept, empty when it is off
+func (l LogConfig) Path() (string, error) {
+	if l.File == LogFileOff {
+		return "", nil
+	}
+	if l.File != "" && !strings.HasPrefix(l.File, "~/") {
+		return l.File, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if l.File != "" {
+		return filepath.Join(home, l.File[2:]), nil
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "tunnel9.log"), nil
+}
+
+// MaxBytes is the size the log file is rotated at
+func (l LogConfig) MaxBytes() int64 {
+	size := l.MaxSize
+	if size <= 0 {
+		size = DefaultLogMaxSize
+	}
+	return int64(size) << 20
+}
+
+// Backups is the number of rotated files kept
+func (l LogConfig) Backups() int {
+	if l.Keep <= 0 {
+		return DefaultLogKeep
+	}
+	return l.Keep
+}
+
+// Validate checks the log file's size and the number kept
+func (l LogConfig) Validate() []error {
+	var errs []error
+	if l.MaxSize < 0 {
+		errs = append(errs, fmt.Errorf("log: invalid max_size %d, expected megabytes", l.MaxSize))
+	}
+	if l.Keep < 0 {
+		errs = append(errs, fmt.Errorf("log: invalid keep %d", l.Keep))
+	}
+	return errs
+}
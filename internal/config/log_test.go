@@ -0,0 +1,35 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLogConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for file, want := range map[string]string{
+		"":                filepath.Join(home, ".local", "state", "tunnel9", "tunnel9.log"),
+		"~/logs/t9.log":   filepath.Join(home, "logs", "t9.log"),
+		"/var/log/t9.log": "/var/log/t9.log",
+		LogFileOff:        "",
+	} {
+		if got, err := (LogConfig{File: file}).Path(); err != nil || got != want {
+			t.Errorf("expected %q for %q, got %q (%v)", want, file, got, err)
+		}
+	}
+
+	var defaults LogConfig
+	if defaults.MaxBytes() != 10<<20 || defaults.Backups() != 3 {
+		t.Errorf("expected 10MB and 3 files by default, got %d and %d", defaults.MaxBytes(), defaults.Backups())
+	}
+	set := LogConfig{MaxSize: 1, Keep: 7}
+	if set.MaxBytes() != 1<<20 || set.Backups() != 7 {
+		t.Errorf("expected 1MB and 7 files, got %d and %d", set.MaxBytes(), set.Backups())
+	}
+
+	cfg := Config{Log: LogConfig{MaxSize: -1, Keep: -2}}
+	if errs := cfg.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NotificationsConfig decides which tunnel state changes are sent where
+type NotificationsConfig struct {
+	// Tried in order, the first that matches a state change decides where
+	// it goes. Without a match failures and recoveries are emailed when
+	// email is configured.
+	Routes []NotificationRoute `yaml:"routes,omitempty"`
+
+	// URL every state change is POSTed to as JSON, whatever the routes say
+	Webhook string `yaml:"webhook,omitempty"`
+}
+
+// NotificationRoute sends the state changes it matches to its sinks. A
+// route without sinks keeps what it matches quiet.
+type NotificationRoute struct {
+	Tags        []string `yaml:"tags,omitempty"`        // Any tag when empty
+	Severity    string   `yaml:"severity,omitempty"`    // The least severe change matched, default info
+	Transitions []string `yaml:"transitions,omitempty"` // e.g. "active->error" or "*->error", any when empty
+	Sinks       []string `yaml:"sinks,omitempty"`
+	Webhook     string   `yaml:"webhook,omitempty"` // URL the webhook sink POSTs to
+}
+
+// Severities of state changes, from least to most severe
+const (
+	SeverityInfo    = "info"    // Started, stopped or recovered
+	SeverityWarning = "warning" // Dropped and reconnecting
+	SeverityError   = "error"   // Failed
+)
+
+// Where notifications can go
+const (
+	SinkDesktop = "desktop"
+	SinkEmail   = "email"
+	SinkWebhook = "webhook"
+)
+
+// Tunnel states a transition may name
+var tunnelStates = []string{"stopped", "connecting", "active", "error"}
+
+// Severity rates a change from one tunnel state to another
+func Severity(from string, to string) string {
+	switch {
+	case to == "error":
+		return SeverityError
+	case from == "active" && to == "connecting":
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// severityRank orders severities, unknown ones as info
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	}
+	return 0
+}
+
+// Matches reports whether a tunnel with tag changing from one state to
+// another falls under the route
+func (r NotificationRoute) Matches(tag string, from string, to string) bool {
+	if len(r.Tags) > 0 && !contains(r.Tags, tag) {
+		return false
+	}
+	if severityRank(Severity(from, to)) < severityRank(r.Severity) {
+		return false
+	}
+	if len(r.Transitions) == 0 {
+		return true
+	}
+	for _, transition := range r.Transitions {
+		wantFrom, wantTo, _ := strings.Cut(transition, "->")
+		if (wantFrom == "*" || wantFrom == from) && (wantTo == "*" || wantTo == to) {
+			return true
+		}
+	}
+	return false
+}
+
+// Route returns the first route matching a state change
+func (n NotificationsConfig) Route(tag string, from string, to string) (NotificationRoute, bool) {
+	for _, route := range n.Routes {
+		if route.Matches(tag, from, to) {
+			return route, true
+		}
+	}
+	return NotificationRoute{}, false
+}
+
+// validate checks the webhook and each route, email being whether email is
+// configured
+func (n NotificationsConfig) validate(email bool) []error {
+	var errs []error
+	if n.Webhook != "" && !validWebhook(n.Webhook) {
+		errs = append(errs, fmt.Errorf("notifications: invalid webhook URL %q", n.Webhook))
+	}
+	for i, route := range n.Routes {
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("notifications route %d: %s", i+1, fmt.Sprintf(format, args...)))
+		}
+		if route.Severity != "" && !contains(schemaEnums["severity"], route.Severity) {
+			fail("unknown severity %q", route.Severity)
+		}
+		for _, transition := range route.Transitions {
+			from, to, found := strings.Cut(transition, "->")
+			valid := func(state string) bool { return state == "*" || contains(tunnelStates, state) }
+			if !found || !valid(from) || !valid(to) {
+				fail("invalid transition %q, expected e.g. active->error or *->error", transition)
+			}
+		}
+		for _, sink := range route.Sinks {
+			switch {
+			case !contains(schemaEnums["sinks"], sink):
+				fail("unknown sink %q", sink)
+			case sink == SinkEmail && !email:
+				fail("email sink without email settings")
+			case sink == SinkWebhook && route.Webhook == "":
+				fail("webhook sink without a webhook URL")
+			}
+		}
+		if route.Webhook != "" && !validWebhook(route.Webhook) {
+			fail("invalid webhook URL %q", route.Webhook)
+		}
+	}
+	return errs
+}
+
+// validWebhook reports whether a webhook URL is one that can be POSTed to
+func validWebhook(webhook string) bool {
+	u, err := url.Parse(webhook)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
@@ -0,0 +1,68 @@
+package config
+
+import "testing"
+
+func TestNotificationRoutes(t *testing.T) {
+	n := NotificationsConfig{Routes: []NotificationRoute{
+		{Tags: []string{"homelab"}},
+		{Tags: []string{"prod"}, Severity: SeverityError, Sinks: []string{SinkWebhook}, Webhook: "https://hooks.example.com/t9"},
+		{Transitions: []string{"error->active", "*->error"}, Sinks: []string{SinkDesktop}},
+	}}
+
+	cases := []struct {
+		tag, from, to string
+		route         int // -1 for no match
+	}{
+		{"homelab", "active", "error", 0},
+		{"prod", "active", "error", 1},
+		{"prod", "active", "connecting", -1},
+		{"prod", "error", "active", 2},
+		{"", "connecting", "error", 2},
+		{"", "stopped", "connecting", -1},
+	}
+	for _, c := range cases {
+		route, ok := n.Route(c.tag, c.from, c.to)
+		switch {
+		case c.route < 0 && ok:
+			t.Errorf("%s %s->%s: expected no route, got %+v", c.tag, c.from, c.to, route)
+		case c.route >= 0 && (!ok || route.Webhook != n.Routes[c.route].Webhook || len(route.Sinks) != len(n.Routes[c.route].Sinks)):
+			t.Errorf("%s %s->%s: expected route %d, got %+v", c.tag, c.from, c.to, c.route+1, route)
+		}
+	}
+
+	if Severity("active", "connecting") != SeverityWarning || Severity("stopped", "active") != SeverityInfo {
+		t.Error("unexpected severities")
+	}
+}
+
+func TestConfig_ValidateNotifications(t *testing.T) {
+	cfg := Config{Notifications: NotificationsConfig{Routes: []NotificationRoute{
+		{Tags: []string{"prod"}, Severity: SeverityWarning, Transitions: []string{"*->error"}, Sinks: []string{SinkDesktop}},
+	}}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	tests := []struct {
+		name          string
+		notifications NotificationsConfig
+		want          string
+	}{
+		{"webhook", NotificationsConfig{Webhook: "hooks.example.com/tunnel9"}, `notifications: invalid webhook URL "hooks.example.com/tunnel9"`},
+		{"severity", NotificationsConfig{Routes: []NotificationRoute{{Severity: "critical"}}}, `notifications route 1: unknown severity "critical"`},
+		{"transition", NotificationsConfig{Routes: []NotificationRoute{{Transitions: []string{"up->down"}}}}, `notifications route 1: invalid transition "up->down", expected e.g. active->error or *->error`},
+		{"sink", NotificationsConfig{Routes: []NotificationRoute{{Sinks: []string{"pager"}}}}, `notifications route 1: unknown sink "pager"`},
+		{"email sink", NotificationsConfig{Routes: []NotificationRoute{{Sinks: []string{SinkEmail}}}}, `notifications route 1: email sink without email settings`},
+		{"webhook sink", NotificationsConfig{Routes: []NotificationRoute{{Sinks: []string{SinkWebhook}}}}, `notifications route 1: webhook sink without a webhook URL`},
+		{"route webhook", NotificationsConfig{Routes: []NotificationRoute{{}, {Webhook: "ftp://example.com"}}}, `notifications route 2: invalid webhook URL "ftp://example.com"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Notifications: tt.notifications}
+			errs := cfg.Validate()
+			if len(errs) != 1 || errs[0].Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PassPrefix refers to an entry in pass or gopass, e.g. pass://work/ssh
+const PassPrefix = "pass://"
+
+// runPass runs the password store CLI, gopass when installed and pass
+// otherwise. Tests replace it.
+var runPass = func(entry string) ([]byte, error) {
+	name, args := "pass", []string{"show", entry}
+	if _, err := exec.LookPath("gopass"); err == nil {
+		name, args = "gopass", []string{"show", "--unsafe", entry}
+	} else if _, err := exec.LookPath("pass"); err != nil {
+		return nil, fmt.Errorf("neither pass nor gopass is installed")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s show %s: %v: %s", name, entry, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// readPass returns the lines of a password store entry
+func readPass(entry string) ([]string, error) {
+	out, err := runPass(strings.TrimPrefix(entry, PassPrefix))
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(out), "\r\n", "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return nil, fmt.Errorf("pass entry %s is empty", strings.TrimPrefix(entry, PassPrefix))
+	}
+	return lines, nil
+}
+
+// ResolveTOTPSeed returns the TOTP seed a setting refers to, like
+// ResolveSecret. From a pass entry it takes the otpauth:// line pass-otp and
+// gopass keep, falling back to the first line.
+func ResolveTOTPSeed(value string) (string, error) {
+	if !strings.HasPrefix(value, PassPrefix) {
+		return ResolveSecret(value)
+	}
+	lines, err := readPass(value)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "otpauth://") {
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return lines[0], nil
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParsePortRange reads a port_range like "20000-20999"
+func ParsePortRange(value string) (int, int, error) {
+	first, last, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid port_range %q, expected e.g. 20000-20999", value)
+	}
+	lo, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port_range %q, expected e.g. 20000-20999", value)
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port_range %q, expected e.g. 20000-20999", value)
+	}
+	if lo < 1 || hi > 65535 || lo > hi {
+		return 0, 0, fmt.Errorf("port_range %q out of range", value)
+	}
+	return lo, hi, nil
+}
+
+// AutoPort is the local_port of a tunnel that listens on any free port,
+// picked by the system each time it starts
+const AutoPort = "auto"
+
+// ParseLocalPort reads a local port as written in the config, the dialog or
+// a tunnel spec: a number, or auto
+func ParseLocalPort(value string) (port int, auto bool, err error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, AutoPort) {
+		return 0, true, nil
+	}
+	if port, err = strconv.Atoi(value); err != nil {
+		return 0, false, fmt.Errorf("invalid local port %q", value)
+	}
+	return port, false, nil
+}
+
+// PicksPort reports whether the tunnel listens on a port the system picks
+// when it starts, because its local_port is auto
+func (tc TunnelConfig) PicksPort() bool {
+	return tc.LocalPort == 0 && tc.AutoPort
+}
+
+// StripAutoPorts prepares a config file's contents for decoding, turning
+// local_port: auto, which the int field can't hold, into 0. It returns the
+// tunnels that had it by index, for MarkAutoPorts once decoded. Contents
+// that don't parse are left for the decoding to report.
+func StripAutoPorts(data []byte) ([]byte, []int) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return data, nil
+	}
+	tunnels := mappingValue(doc.Content[0], "tunnels")
+	if tunnels == nil || tunnels.Kind != yaml.SequenceNode {
+		return data, nil
+	}
+
+	var auto []int
+	for i, tunnel := range tunnels.Content {
+		if port := mappingValue(tunnel, "local_port"); port != nil && port.Kind == yaml.ScalarNode && strings.EqualFold(port.Value, AutoPort) {
+			port.Value, port.Tag, port.Style = "0", "!!int", 0
+			auto = append(auto, i)
+		}
+	}
+	if len(auto) == 0 {
+		return data, nil
+	}
+	stripped, err := yaml.Marshal(&doc)
+	if err != nil {
+		return data, nil
+	}
+	return stripped, auto
+}
+
+// MarkAutoPorts sets AutoPort on the tunnels StripAutoPorts found it on
+func (c *Config) MarkAutoPorts(auto []int) {
+	for _, i := range auto {
+		if i < len(c.Tunnels) {
+			c.Tunnels[i].AutoPort = true
+		}
+	}
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes local_port: auto back for tunnels that had it
+func (tc TunnelConfig) MarshalYAML() (interface{}, error) {
+	type plain TunnelConfig
+	var node yaml.Node
+	if err := node.Encode(plain(tc)); err != nil {
+		return nil, err
+	}
+	if tc.AutoPort && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "local_port" {
+				node.Content[i+1].Value, node.Content[i+1].Tag = AutoPort, "!!str"
+			}
+		}
+	}
+	return &node, nil
+}
+
+// AssignPort picks a port between lo and hi for a tunnel without one. It
+// starts from a hash of the name, so the same tunnel tends to get the same
+// port on every machine, and moves on past ports in used.
+func AssignPort(name string, lo int, hi int, used map[int]bool) (int, bool) {
+	size := hi - lo + 1
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	start := int(hash.Sum32() % uint32(size))
+
+	for i := 0; i < size; i++ {
+		port := lo + (start+i)%size
+		if !used[port] {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+// AssignPorts returns the tunnels with a port from port_range given to each
+// one that leaves local_port out, along with the ports handed out by tunnel
+// name. Wireguard tunnels keep their own default port, and those with
+// local_port: auto pick one when they start.
+func (c Config) AssignPorts() ([]TunnelConfig, map[string]int) {
+	if c.PortRange == "" {
+		return c.Tunnels, nil
+	}
+	lo, hi, err := ParsePortRange(c.PortRange)
+	if err != nil {
+		return c.Tunnels, nil
+	}
+
+	used := make(map[int]bool)
+	for _, tc := range c.Tunnels {
+		if tc.LocalPort != 0 {
+			used[tc.LocalPort] = true
+		}
+	}
+
+	tunnels := make([]TunnelConfig, len(c.Tunnels))
+	assigned := make(map[string]int)
+	for i, tc := range c.Tunnels {
+		if tc.LocalPort == 0 && !tc.AutoPort && tc.Type != "wireguard" {
+			if port, ok := AssignPort(tc.Name, lo, hi, used); ok {
+				tc.LocalPort = port
+				used[port] = true
+				assigned[tc.Name] = port
+			}
+		}
+		tunnels[i] = tc
+	}
+	return tunnels, assigned
+}
+
+// bindHost is what a listener binds to, loopback addresses all being
+// localhost and the unspecified ones every address, "*"
+func bindHost(address string) string {
+	if address == "" || strings.EqualFold(address, "localhost") {
+		return "localhost"
+	}
+	if ip := net.ParseIP(strings.Trim(address, "[]")); ip != nil {
+		switch {
+		case ip.IsLoopback():
+			return "localhost"
+		case ip.IsUnspecified():
+			return "*"
+		}
+		return ip.String()
+	}
+	return strings.ToLower(address)
+}
+
+// BindOverlaps reports whether listeners on two bind addresses would take
+// the same port, an empty one meaning localhost as it does for tunnels
+func BindOverlaps(a string, b string) bool {
+	a, b = bindHost(a), bindHost(b)
+	return a == b || a == "*" || b == "*"
+}
+
+// ClashesWith reports whether two tunnels would listen on the same local
+// port. Tunnels bound to an interface clash with those on the same one.
+func (tc TunnelConfig) ClashesWith(other TunnelConfig) bool {
+	if tc.LocalPort == 0 || tc.LocalPort != other.LocalPort || tc.IsUDP() != other.IsUDP() {
+		return false
+	}
+	bind := func(t TunnelConfig) string {
+		if t.BindInterface != "" {
+			return "interface " + t.BindInterface
+		}
+		return t.BindAddress
+	}
+	return BindOverlaps(bind(tc), bind(other))
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	if lo, hi, err := ParsePortRange("20000-20999"); err != nil || lo != 20000 || hi != 20999 {
+		t.Errorf("expected 20000-20999, got %d-%d (%v)", lo, hi, err)
+	}
+	for _, bad := range []string{"20000", "a-b", "30000-20000", "0-10", "60000-70000"} {
+		if _, _, err := ParsePortRange(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestAssignPort(t *testing.T) {
+	first, ok := AssignPort("prod-db", 20000, 20999, nil)
+	if !ok || first < 20000 || first > 20999 {
+		t.Fatalf("expected a port in range, got %d", first)
+	}
+	// The same name gets the same port every time
+	if again, _ := AssignPort("prod-db", 20000, 20999, nil); again != first {
+		t.Errorf("expected %d again, got %d", first, again)
+	}
+	// Taken ports are skipped
+	if next, _ := AssignPort("prod-db", 20000, 20999, map[int]bool{first: true}); next == first {
+		t.Errorf("expected a different port than the taken %d", first)
+	}
+	// A full range has nothing to give
+	if _, ok := AssignPort("prod-db", 20000, 20000, map[int]bool{20000: true}); ok {
+		t.Errorf("expected no port from a full range")
+	}
+}
+
+func TestConfigLoader_PortRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `port_range: "20000-20009"
+tunnels:
+  - name: web
+    local_port: 8080
+    remote_host: web.internal
+    remote_port: 80
+  - name: db
+    remote_host: db.internal
+    remote_port: 5432
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader(path)
+	tunnels, err := loader.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if port := tunnels[1].LocalPort; port < 20000 || port > 20009 {
+		t.Errorf("expected db to get a port from the range, got %d", port)
+	}
+	if errs := loader.Config().Validate(); len(errs) != 0 {
+		t.Errorf("expected the assigned port to validate, got %v", errs)
+	}
+
+	// Saving keeps the assigned port out of the file
+	if err := loader.Save(tunnels); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+	if !strings.Contains(string(saved), "local_port: 0") {
+		t.Errorf("expected db's assigned port to stay out of the file, got\n%s", saved)
+	}
+}
+
+func TestConfigLoader_AutoPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `port_range: "20000-20009"
+tunnels:
+  - name: scratch
+    local_port: auto
+    remote_host: scratch.internal
+    remote_port: 80
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewConfigLoader(path)
+	tunnels, err := loader.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	// Auto wins over port_range, the port is picked at start
+	if tc := tunnels[0]; !tc.AutoPort || tc.LocalPort != 0 || !tc.PicksPort() {
+		t.Errorf("expected scratch to pick its port at start, got %+v", tc)
+	}
+	if errs := loader.Config().Validate(); len(errs) != 0 {
+		t.Errorf("expected auto to validate, got %v", errs)
+	}
+
+	if err := loader.Save(tunnels); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+	if !strings.Contains(string(saved), "local_port: auto") {
+		t.Errorf("expected auto to be saved back, got\n%s", saved)
+	}
+}
+
+func TestParseLocalPort(t *testing.T) {
+	if port, auto, err := ParseLocalPort("5432"); port != 5432 || auto || err != nil {
+		t.Errorf("expected 5432, got %d %v %v", port, auto, err)
+	}
+	if port, auto, err := ParseLocalPort("Auto"); port != 0 || !auto || err != nil {
+		t.Errorf("expected auto, got %d %v %v", port, auto, err)
+	}
+	if _, _, err := ParseLocalPort("any"); err == nil {
+		t.Error("expected an invalid port to be rejected")
+	}
+
+	// Without port_range a missing port is an error, not picked at start
+	tc := TunnelConfig{Name: "db", RemoteHost: "db.internal", RemotePort: 5432}
+	if errs := tc.Validate(); len(errs) != 1 || tc.PicksPort() {
+		t.Errorf("expected a tunnel without local_port to be rejected, got %v", errs)
+	}
+	if wg := (TunnelConfig{Type: "wireguard"}); wg.PicksPort() {
+		t.Error("expected wireguard to keep its default port")
+	}
+}
+
+func TestTunnelConfig_ClashesWith(t *testing.T) {
+	db := TunnelConfig{Name: "db", LocalPort: 5432}
+	for _, tc := range []struct {
+		other TunnelConfig
+		want  bool
+	}{
+		{TunnelConfig{LocalPort: 5432}, true},
+		{TunnelConfig{LocalPort: 5432, BindAddress: "127.0.0.1"}, true},
+		{TunnelConfig{LocalPort: 5432, BindAddress: "0.0.0.0"}, true},
+		{TunnelConfig{LocalPort: 5432, BindAddress: "10.0.0.5"}, false},
+		{TunnelConfig{LocalPort: 5432, Type: "udp"}, false},
+		{TunnelConfig{LocalPort: 5433}, false},
+		{TunnelConfig{LocalPort: 5432, BindInterface: "tun0"}, false},
+	} {
+		if got := db.ClashesWith(tc.other); got != tc.want {
+			t.Errorf("expected %v for %+v, got %v", tc.want, tc.other, got)
+		}
+	}
+
+	vpn := TunnelConfig{LocalPort: 5432, BindInterface: "tun0"}
+	if !vpn.ClashesWith(TunnelConfig{LocalPort: 5432, BindInterface: "tun0"}) {
+		t.Errorf("expected tunnels on the same interface to clash")
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the config found without --profile
+const DefaultProfile = "default"
+
+// ProfilesDir holds a config file for each named profile, like work or
+// personal, each with its own tunnels and settings
+func ProfilesDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "profiles"), nil
+}
+
+// ProfilePath returns the config file of a named profile, which need not
+// exist yet
+func ProfilePath(name string) (string, error) {
+	if name == "" || name == DefaultProfile || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	dir, err := ProfilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".yaml"), nil
+}
+
+// ProfileName returns the profile a config file belongs to, or
+// DefaultProfile for one outside the profiles directory
+func ProfileName(path string) string {
+	dir, err := ProfilesDir()
+	if err != nil || filepath.Dir(path) != dir || filepath.Ext(path) != ".yaml" {
+		return DefaultProfile
+	}
+	return strings.TrimSuffix(filepath.Base(path), ".yaml")
+}
+
+// ListProfiles returns the default profile followed by every named one,
+// sorted
+func ListProfiles() ([]string, error) {
+	profiles := []string{DefaultProfile}
+	dir, err := ProfilesDir()
+	if err != nil {
+		return profiles, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return profiles, err
+	}
+	names := make([]string, 0, len(matches))
+	for _, path := range matches {
+		names = append(names, ProfileName(path))
+	}
+	sort.Strings(names)
+	return append(profiles, names...), nil
+}
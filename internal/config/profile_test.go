@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, name := range []string{"", DefaultProfile, "../work", ".hidden"} {
+		if _, err := ProfilePath(name); err == nil {
+			t.Errorf("expected profile name %q to be refused", name)
+		}
+	}
+
+	path, err := ProfilePath("work")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(home, ".local", "state", "tunnel9", "profiles", "work.yaml") {
+		t.Errorf("unexpected profile path %s", path)
+	}
+	if name := ProfileName(path); name != "work" {
+		t.Errorf("expected work, got %s", name)
+	}
+	if name := ProfileName(filepath.Join(home, ".tunnel9.yaml")); name != DefaultProfile {
+		t.Errorf("expected a config elsewhere to be the default profile, got %s", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"work", "personal"} {
+		if err := os.WriteFile(filepath.Join(filepath.Dir(path), name+".yaml"), []byte("tunnels: []\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profiles, err := ListProfiles()
+	if err != nil || strings.Join(profiles, " ") != "default personal work" {
+		t.Errorf("expected default, personal and work, got %v (%v)", profiles, err)
+	}
+}
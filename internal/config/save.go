@@ -0,0 +1,66 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tunnel9/internal/fsutil"
+)
+
+// ConfigBackups is how many earlier versions of the config file are kept
+// next to it, as config.yaml.bak.1 (the latest) to config.yaml.bak.5
+const ConfigBackups = 5
+
+// backupPath is the nth backup of the config file at path
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// writeConfigFile replaces the file at path with data so that it is never
+// seen half written: data goes to a temporary file beside it, which is
+// synced and renamed over the original. The version it replaces is kept
+// as the latest backup, unless nothing changed.
+func writeConfigFile(path string, data []byte) error {
+	// Write through a symlink, such as one into a dotfiles repo, rather
+	// than replacing it
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	mode := os.FileMode(0644)
+	previous, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if !bytes.Equal(previous, data) {
+			if err := backupConfigFile(path, previous, mode); err != nil {
+				return err
+			}
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	if err := fsutil.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return nil
+}
+
+// backupConfigFile shifts the backups along, dropping the oldest, and keeps
+// previous as the latest
+func backupConfigFile(path string, previous []byte, mode os.FileMode) error {
+	for n := ConfigBackups - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error rotating config backups: %w", err)
+		}
+	}
+	if err := os.WriteFile(backupPath(path, 1), previous, mode); err != nil {
+		return fmt.Errorf("error backing up config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveKeepsBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	loader := NewConfigLoader(path)
+
+	save := func(name string) {
+		t.Helper()
+		if err := loader.Save([]TunnelConfig{{Name: name, LocalPort: 1, RemoteHost: "a", RemotePort: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	save("first")
+	if _, err := os.Stat(backupPath(path, 1)); !os.IsNotExist(err) {
+		t.Error("expected no backup of a file that didn't exist")
+	}
+	save("first")
+	if _, err := os.Stat(backupPath(path, 1)); !os.IsNotExist(err) {
+		t.Error("expected no backup when nothing changed")
+	}
+
+	for i := 2; i <= ConfigBackups+2; i++ {
+		save(strings.Repeat("x", i))
+	}
+	if !strings.Contains(read(path), "name: "+strings.Repeat("x", ConfigBackups+2)) {
+		t.Errorf("expected the last save in place, got %q", read(path))
+	}
+	if !strings.Contains(read(backupPath(path, 1)), "name: "+strings.Repeat("x", ConfigBackups+1)) {
+		t.Errorf("expected the save before it as the latest backup, got %q", read(backupPath(path, 1)))
+	}
+	if !strings.Contains(read(backupPath(path, ConfigBackups)), "name: xx\n") {
+		t.Errorf("expected the oldest kept backup, got %q", read(backupPath(path, ConfigBackups)))
+	}
+	if _, err := os.Stat(backupPath(path, ConfigBackups+1)); !os.IsNotExist(err) {
+		t.Error("expected older backups dropped")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("expected no temporary files left behind, found %s", e.Name())
+		}
+	}
+}
+
+func TestSaveThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles.yaml")
+	if err := os.WriteFile(target, []byte("tunnels: []\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot symlink: %v", err)
+	}
+
+	if err := NewConfigLoader(link).Save([]TunnelConfig{{Name: "db"}}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("expected the symlink kept")
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the target's mode kept, got %v (%v)", info.Mode(), err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "name: db") {
+		t.Errorf("expected the target written, got %q", data)
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Allowed values for string fields that only take a fixed set
+var schemaEnums = map[string][]string{
+	"type":              {"", "udp", "wireguard"},
+	"keepalive":         {"aggressive", "balanced", "relaxed"},
+	"ssh_config_ignore": {"port", "user", "identity_file", "hostname", "proxy", "all"},
+	"confirm":           {ConfirmDelete, ConfirmDeleteStop, ConfirmNone},
+	"protocol":          {"tcp", "http", "https"},
+	"metrics":           {MetricsOff, MetricsBasic, MetricsFull},
+	"severity":          {SeverityInfo, SeverityWarning, SeverityError},
+	"sinks":             {SinkDesktop, SinkEmail, SinkWebhook},
+	"scope":             {ScopeRead, ScopeControl},
+	"sources":           {DiscoveryScan, DiscoveryHosts, DiscoveryConsul, DiscoveryKube},
+	"preset":            {ThemeDark, ThemeLight, ThemeHighContrast},
+}
+
+// Fields a tunnel must set to be usable
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(TunnelConfig{}): {"name", "remote_host"},
+}
+
+// JSONSchema describes the config file format as a JSON Schema document. It
+// is generated from the yaml tags so it can't drift from what Load accepts.
+func JSONSchema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "tunnel9 config"
+	return schema
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+
+			property := schemaFor(field.Type)
+			if values, ok := schemaEnums[name]; ok {
+				if property["type"] == "array" {
+					property["items"] = map[string]interface{}{"type": "string", "enum": values}
+				} else {
+					property["enum"] = values
+				}
+			}
+			if strings.HasSuffix(name, "port") && property["type"] == "integer" {
+				property["minimum"] = 0
+				property["maximum"] = 65535
+			}
+			if name == "local_port" {
+				property = map[string]interface{}{"anyOf": []interface{}{property, map[string]interface{}{"const": AutoPort}}}
+			}
+			properties[name] = property
+		}
+
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if required, ok := schemaRequired[t]; ok {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+var templateVariable = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Values returns the template's pre-filled values, in dialog order
+func (t TunnelTemplate) Values() []string {
+	return []string{
+		t.BindAddress, t.LocalPort, t.RemoteHost, t.RemotePort,
+		t.BastionHost, t.BastionPort, t.BastionUser, t.TunnelName, t.Tag,
+	}
+}
+
+// Variables returns the names of the {variables} the template uses, in the
+// order they first appear
+func (t TunnelTemplate) Variables() []string {
+	seen := make(map[string]bool)
+	var variables []string
+	for _, value := range t.Values() {
+		for _, match := range templateVariable.FindAllStringSubmatch(value, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				variables = append(variables, match[1])
+			}
+		}
+	}
+	return variables
+}
+
+// ExpandTemplate replaces the {variables} in value, leaving unknown ones
+func ExpandTemplate(value string, variables map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(value, func(match string) string {
+		if v, ok := variables[strings.Trim(match, "{}")]; ok {
+			return v
+		}
+		return match
+	})
+}
@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestTunnelTemplate(t *testing.T) {
+	template := TunnelTemplate{
+		Name:        "prod",
+		TunnelName:  "{service}-prod",
+		RemoteHost:  "{service}.{region}.internal",
+		RemotePort:  "{port}",
+		BastionHost: "jump.{region}.example.com",
+	}
+
+	variables := template.Variables()
+	if len(variables) != 3 || variables[0] != "service" || variables[1] != "region" || variables[2] != "port" {
+		t.Errorf("expected service, region and port, got %v", variables)
+	}
+
+	values := map[string]string{"service": "billing", "region": "eu"}
+	if got := ExpandTemplate(template.RemoteHost, values); got != "billing.eu.internal" {
+		t.Errorf("expected billing.eu.internal, got %s", got)
+	}
+	if got := ExpandTemplate(template.RemotePort, values); got != "{port}" {
+		t.Errorf("expected an unknown variable to stay, got %s", got)
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Built-in themes
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+)
+
+// ThemeConfig picks the colors of the interface: a built-in preset, with any
+// of its colors replaced. Colors are anything lipgloss takes, a hex code like
+// "#0f766e" or an ANSI color number like "203".
+type ThemeConfig struct {
+	Preset           string `yaml:"preset,omitempty"`            // dark, light or high-contrast, default dark
+	Accent           string `yaml:"accent,omitempty"`            // Title, borders, dialogs, key hints and tags without a color
+	AccentBackground string `yaml:"accent_background,omitempty"` // Behind the selected dialog item
+	AccentText       string `yaml:"accent_text,omitempty"`       // Text on the tag filter badge
+	Selected         string `yaml:"selected,omitempty"`          // Selected table row
+	Success          string `yaml:"success,omitempty"`           // Good latency, passed checks, active periods, INFO in the console
+	Warning          string `yaml:"warning,omitempty"`           // Slow latency, connecting periods, WARN in the console, errors in the log
+	Error            string `yaml:"error,omitempty"`             // Bad latency, failed checks, error messages and badge
+	Info             string `yaml:"info,omitempty"`              // Debug lines in the console
+	Highlight        string `yaml:"highlight,omitempty"`         // Tunnel names in the console
+	Muted            string `yaml:"muted,omitempty"`             // Timestamps and stopped periods
+	Faint            string `yaml:"faint,omitempty"`             // Expanded rows, previews and hosts in maintenance
+	Badge            string `yaml:"badge,omitempty"`             // Behind the marked tunnels badge
+	BadgeText        string `yaml:"badge_text,omitempty"`        // Text on the footer badges
+}
+
+// Colors lists the colors a theme sets, by their YAML names
+func (t ThemeConfig) Colors() map[string]string {
+	return map[string]string{
+		"accent":            t.Accent,
+		"accent_background": t.AccentBackground,
+		"accent_text":       t.AccentText,
+		"selected":          t.Selected,
+		"success":           t.Success,
+		"warning":           t.Warning,
+		"error":             t.Error,
+		"info":              t.Info,
+		"highlight":         t.Highlight,
+		"muted":             t.Muted,
+		"faint":             t.Faint,
+		"badge":             t.Badge,
+		"badge_text":        t.BadgeText,
+	}
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// ValidColor reports whether a color is a hex code or an ANSI color number
+func ValidColor(color string) bool {
+	if hexColor.MatchString(color) {
+		return true
+	}
+	n, err := strconv.Atoi(color)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Validate checks the preset and the colors of a theme
+func (t ThemeConfig) Validate() []error {
+	var errs []error
+	if t.Preset != "" && !contains(schemaEnums["preset"], t.Preset) {
+		errs = append(errs, fmt.Errorf("theme: unknown preset %q", t.Preset))
+	}
+	colors := t.Colors()
+	names := make([]string, 0, len(colors))
+	for name := range colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if color := colors[name]; color != "" && !ValidColor(color) {
+			errs = append(errs, fmt.Errorf("theme: invalid %s color %q, expected e.g. \"#0f766e\" or \"203\"", name, color))
+		}
+	}
+	return errs
+}
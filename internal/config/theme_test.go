@@ -0,0 +1,20 @@
+package config
+
+import "testing"
+
+func TestThemeConfig_Validate(t *testing.T) {
+	valid := ThemeConfig{Preset: ThemeLight, Accent: "#0f766e", Selected: "125", Faint: "#abc"}
+	if errs := valid.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	broken := ThemeConfig{Preset: "solarized", Accent: "teal", Error: "256", Muted: "#12345"}
+	if errs := broken.Validate(); len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %v", errs)
+	}
+
+	cfg := Config{Theme: ThemeConfig{Warning: "orange"}}
+	if errs := cfg.Validate(); len(errs) != 1 {
+		t.Errorf("expected the theme checked with the config, got %v", errs)
+	}
+}
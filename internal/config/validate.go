@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"tunnel9/internal/resolver"
+)
+
+// URL schemes as RFC 3986 allows them, for browse settings
+var browseSchemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*$`)
+
+func validPort(port int) bool {
+	return port >= 0 && port <= 65535
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks a tunnel for settings that Load accepts but that can't work
+func (tc TunnelConfig) Validate() []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("tunnel %q: "+format, append([]interface{}{tc.Name}, args...)...))
+	}
+
+	if tc.Name == "" {
+		fail("name is required")
+	}
+	if tc.RemoteHost == "" {
+		fail("remote_host is required")
+	}
+	if !validPort(tc.LocalPort) {
+		fail("local_port %d out of range", tc.LocalPort)
+	}
+	if !validPort(tc.RemotePort) {
+		fail("remote_port %d out of range", tc.RemotePort)
+	}
+	if tc.Type != "wireguard" {
+		// Only an explicit auto picks a free port, not a forgotten one
+		if tc.LocalPort == 0 && !tc.AutoPort {
+			fail("local_port is required")
+		}
+		if tc.RemotePort == 0 {
+			fail("remote_port is required")
+		}
+	}
+	if !validPort(tc.Bastion.Port) {
+		fail("bastion port %d out of range", tc.Bastion.Port)
+	}
+	if !contains(schemaEnums["type"], tc.Type) {
+		fail("unknown type %q", tc.Type)
+	}
+	if tc.Keepalive != "" && !contains(schemaEnums["keepalive"], tc.Keepalive) {
+		fail("unknown keepalive %q", tc.Keepalive)
+	}
+	if tc.Metrics != "" && !contains(schemaEnums["metrics"], tc.Metrics) {
+		fail("unknown metrics %q", tc.Metrics)
+	}
+	if tc.DSCP != "" {
+		if _, err := ParseDSCP(tc.DSCP); err != nil {
+			fail("%v", err)
+		}
+	}
+	if tc.IdleTimeout != "" {
+		if _, err := time.ParseDuration(tc.IdleTimeout); err != nil {
+			fail("invalid idle_timeout %q", tc.IdleTimeout)
+		}
+	}
+	if tc.ServerAliveInterval != "" {
+		if interval, err := time.ParseDuration(tc.ServerAliveInterval); err != nil || interval < 0 {
+			fail("invalid server_alive_interval %q", tc.ServerAliveInterval)
+		}
+	}
+	if tc.ServerAliveCountMax < 0 {
+		fail("server_alive_count_max %d can't be negative", tc.ServerAliveCountMax)
+	}
+	for _, delay := range []struct{ name, value string }{
+		{"initial_delay", tc.Reconnect.InitialDelay},
+		{"max_delay", tc.Reconnect.MaxDelay},
+	} {
+		if delay.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(delay.value); err != nil || d <= 0 {
+			fail("invalid reconnect %s %q", delay.name, delay.value)
+		}
+	}
+	if tc.Reconnect.MaxRetries < -1 {
+		fail("reconnect max_retries %d out of range", tc.Reconnect.MaxRetries)
+	}
+	if tc.HealthCheck.Protocol != "" && !contains(schemaEnums["protocol"], tc.HealthCheck.Protocol) {
+		fail("unknown health_check protocol %q", tc.HealthCheck.Protocol)
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"interval", tc.HealthCheck.Interval},
+		{"timeout", tc.HealthCheck.Timeout},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(setting.value); err != nil || d <= 0 {
+			fail("invalid health_check %s %q", setting.name, setting.value)
+		}
+	}
+	if tc.Browse.Scheme != "" && !browseSchemePattern.MatchString(tc.Browse.Scheme) {
+		fail("invalid browse scheme %q", tc.Browse.Scheme)
+	}
+	if tc.Browse.Path != "" && !strings.HasPrefix(tc.Browse.Path, "/") {
+		fail("browse path %q must start with /", tc.Browse.Path)
+	}
+	if tc.BindAddress != "" && tc.BindInterface != "" {
+		fail("bind_address and bind_interface are mutually exclusive")
+	}
+	for _, key := range tc.SSHConfigIgnore {
+		if !contains(schemaEnums["ssh_config_ignore"], key) {
+			fail("unknown ssh_config_ignore entry %q", key)
+		}
+	}
+	for i, standby := range tc.Standby {
+		if !validPort(standby.RemotePort) {
+			fail("standby %d remote_port %d out of range", i+1, standby.RemotePort)
+		}
+	}
+	return errs
+}
+
+// Validate checks the whole config, including that tunnel names are unique
+func (c Config) Validate() []error {
+	var errs []error
+	if c.PortRange != "" {
+		if _, _, err := ParsePortRange(c.PortRange); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Ports from port_range and settings from the defaults count as set
+	tunnels, _ := c.AssignPorts()
+	tunnels = c.ApplyDefaults(tunnels)
+	names := make(map[string]bool)
+	for _, tc := range tunnels {
+		errs = append(errs, tc.Validate()...)
+		if tc.Name != "" && names[tc.Name] {
+			errs = append(errs, fmt.Errorf("tunnel %q: duplicate name", tc.Name))
+		}
+		names[tc.Name] = true
+	}
+	if c.Confirm != "" && !contains(schemaEnums["confirm"], c.Confirm) {
+		errs = append(errs, fmt.Errorf("unknown confirm %q", c.Confirm))
+	}
+	if c.Metrics != "" && !contains(schemaEnums["metrics"], c.Metrics) {
+		errs = append(errs, fmt.Errorf("unknown metrics %q", c.Metrics))
+	}
+	if c.RefreshInterval != "" {
+		if interval, err := time.ParseDuration(c.RefreshInterval); err != nil || interval < MinRefreshInterval || interval > MaxRefreshInterval {
+			errs = append(errs, fmt.Errorf("invalid refresh_interval %q, expected %v to %v", c.RefreshInterval, MinRefreshInterval, MaxRefreshInterval))
+		}
+	}
+	if c.Heartbeat.Interval != "" {
+		if interval, err := time.ParseDuration(c.Heartbeat.Interval); err != nil || interval <= 0 {
+			errs = append(errs, fmt.Errorf("heartbeat: invalid interval %q", c.Heartbeat.Interval))
+		}
+	}
+	if c.MQTT.MetricsInterval != "" {
+		if interval, err := time.ParseDuration(c.MQTT.MetricsInterval); err != nil || interval <= 0 {
+			errs = append(errs, fmt.Errorf("mqtt: invalid metrics_interval %q", c.MQTT.MetricsInterval))
+		}
+	}
+	if c.DNS.Server != "" {
+		if _, err := resolver.New(c.DNS.Server); err != nil {
+			errs = append(errs, fmt.Errorf("dns: %w", err))
+		}
+	}
+	if c.MetricsListen != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsListen); err != nil {
+			errs = append(errs, fmt.Errorf("invalid metrics_listen %q, expected host:port", c.MetricsListen))
+		}
+	}
+	if c.Email.Cooldown != "" {
+		if cooldown, err := time.ParseDuration(c.Email.Cooldown); err != nil || cooldown < 0 {
+			errs = append(errs, fmt.Errorf("email: invalid cooldown %q", c.Email.Cooldown))
+		}
+	}
+	if c.Email.SMTPHost != "" && len(c.Email.To) == 0 {
+		errs = append(errs, fmt.Errorf("email: smtp_host is set but there is no one to send to"))
+	}
+	errs = append(errs, c.Notifications.validate(c.Email.SMTPHost != "")...)
+	errs = append(errs, validateGroups(c.StartupGroups, tunnels)...)
+	errs = append(errs, c.API.validate()...)
+	errs = append(errs, c.Discovery.validate()...)
+	templates := make(map[string]bool)
+	for _, template := range c.Templates {
+		if template.Name == "" {
+			errs = append(errs, fmt.Errorf("templates: name is required"))
+		} else if templates[template.Name] {
+			errs = append(errs, fmt.Errorf("template %q: duplicate name", template.Name))
+		}
+		templates[template.Name] = true
+		if template.PortRange != "" {
+			if _, _, err := ParsePortRange(template.PortRange); err != nil {
+				errs = append(errs, fmt.Errorf("template %q: %w", template.Name, err))
+			}
+		}
+	}
+	for tag, settings := range c.TagSettings {
+		if tag == "" {
+			errs = append(errs, fmt.Errorf("tag_settings: empty tag name"))
+		}
+		if settings.Confirm != "" && !contains(schemaEnums["confirm"], settings.Confirm) {
+			errs = append(errs, fmt.Errorf("tag_settings %q: unknown confirm %q", tag, settings.Confirm))
+		}
+	}
+	errs = append(errs, c.Theme.Validate()...)
+	errs = append(errs, c.Log.Validate()...)
+	return errs
+}
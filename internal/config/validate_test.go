@@ -0,0 +1,239 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTunnelConfig_Validate(t *testing.T) {
+	valid := TunnelConfig{Name: "db", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432}
+	if errs := valid.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	wireguard := TunnelConfig{Name: "wg", RemoteHost: "vpn", Type: "wireguard"}
+	if errs := wireguard.Validate(); len(errs) != 0 {
+		t.Errorf("expected wireguard defaults to be accepted, got %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		breaks func(tc *TunnelConfig)
+		want   string
+	}{
+		{"name", func(tc *TunnelConfig) { tc.Name = "" }, `tunnel "": name is required`},
+		{"remote_host", func(tc *TunnelConfig) { tc.RemoteHost = "" }, `tunnel "db": remote_host is required`},
+		{"local_port range", func(tc *TunnelConfig) { tc.LocalPort = 70000 }, `tunnel "db": local_port 70000 out of range`},
+		{"local_port missing", func(tc *TunnelConfig) { tc.LocalPort = 0 }, `tunnel "db": local_port is required`},
+		{"remote_port range", func(tc *TunnelConfig) { tc.RemotePort = -1 }, `tunnel "db": remote_port -1 out of range`},
+		{"remote_port missing", func(tc *TunnelConfig) { tc.RemotePort = 0 }, `tunnel "db": remote_port is required`},
+		{"bastion port", func(tc *TunnelConfig) { tc.Bastion.Port = 65536 }, `tunnel "db": bastion port 65536 out of range`},
+		{"type", func(tc *TunnelConfig) { tc.Type = "sctp" }, `tunnel "db": unknown type "sctp"`},
+		{"keepalive", func(tc *TunnelConfig) { tc.Keepalive = "sometimes" }, `tunnel "db": unknown keepalive "sometimes"`},
+		{"metrics", func(tc *TunnelConfig) { tc.Metrics = "some" }, `tunnel "db": unknown metrics "some"`},
+		{"dscp", func(tc *TunnelConfig) { tc.DSCP = "gold" }, `tunnel "db": invalid dscp "gold", expected e.g. ef, af21, cs1 or 0-63`},
+		{"idle_timeout", func(tc *TunnelConfig) { tc.IdleTimeout = "soon" }, `tunnel "db": invalid idle_timeout "soon"`},
+		{"server_alive_interval", func(tc *TunnelConfig) { tc.ServerAliveInterval = "often" }, `tunnel "db": invalid server_alive_interval "often"`},
+		{"server_alive_count_max", func(tc *TunnelConfig) { tc.ServerAliveCountMax = -1 }, `tunnel "db": server_alive_count_max -1 can't be negative`},
+		{"reconnect initial_delay", func(tc *TunnelConfig) { tc.Reconnect.InitialDelay = "0s" }, `tunnel "db": invalid reconnect initial_delay "0s"`},
+		{"reconnect max_delay", func(tc *TunnelConfig) { tc.Reconnect.MaxDelay = "never" }, `tunnel "db": invalid reconnect max_delay "never"`},
+		{"reconnect max_retries", func(tc *TunnelConfig) { tc.Reconnect.MaxRetries = -2 }, `tunnel "db": reconnect max_retries -2 out of range`},
+		{"health_check protocol", func(tc *TunnelConfig) { tc.HealthCheck.Protocol = "icmp" }, `tunnel "db": unknown health_check protocol "icmp"`},
+		{"health_check interval", func(tc *TunnelConfig) { tc.HealthCheck.Interval = "often" }, `tunnel "db": invalid health_check interval "often"`},
+		{"health_check timeout", func(tc *TunnelConfig) { tc.HealthCheck.Timeout = "0s" }, `tunnel "db": invalid health_check timeout "0s"`},
+		{"browse scheme", func(tc *TunnelConfig) { tc.Browse.Scheme = "https://" }, `tunnel "db": invalid browse scheme "https://"`},
+		{"browse path", func(tc *TunnelConfig) { tc.Browse.Path = "admin" }, `tunnel "db": browse path "admin" must start with /`},
+		{"bind", func(tc *TunnelConfig) { tc.BindAddress, tc.BindInterface = "0.0.0.0", "utun3" }, `tunnel "db": bind_address and bind_interface are mutually exclusive`},
+		{"ssh_config_ignore", func(tc *TunnelConfig) { tc.SSHConfigIgnore = []string{"forward_agent"} }, `tunnel "db": unknown ssh_config_ignore entry "forward_agent"`},
+		{"standby remote_port", func(tc *TunnelConfig) { tc.Standby = []StandbyTarget{{RemotePort: 70000}} }, `tunnel "db": standby 1 remote_port 70000 out of range`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := valid
+			tt.breaks(&tc)
+			errs := tc.Validate()
+			if len(errs) != 1 || errs[0].Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, errs)
+			}
+		})
+	}
+
+	auto := TunnelConfig{Name: "scratch", AutoPort: true, RemoteHost: "db.internal", RemotePort: 5432}
+	if errs := auto.Validate(); len(errs) != 0 {
+		t.Errorf("expected local_port auto to be accepted, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateDuplicateNames(t *testing.T) {
+	cfg := Config{Tunnels: []TunnelConfig{
+		{Name: "db", LocalPort: 1, RemoteHost: "a", RemotePort: 1},
+		{Name: "db", LocalPort: 2, RemoteHost: "b", RemotePort: 2},
+	}}
+	errs := cfg.Validate()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "duplicate name") {
+		t.Errorf("expected duplicate name error, got %v", errs)
+	}
+}
+
+func TestConfig_ConfirmPolicy(t *testing.T) {
+	cfg := Config{
+		TagSettings: map[string]TagSettings{
+			"prod": {Confirm: ConfirmDeleteStop},
+			"dev":  {Color: "42"},
+		},
+	}
+	if got := cfg.ConfirmPolicy("dev"); got != ConfirmDelete {
+		t.Errorf("expected delete by default, got %s", got)
+	}
+
+	cfg.Confirm = ConfirmNone
+	if got := cfg.ConfirmPolicy("dev"); got != ConfirmNone {
+		t.Errorf("expected top-level policy, got %s", got)
+	}
+	if got := cfg.ConfirmPolicy("prod"); got != ConfirmDeleteStop {
+		t.Errorf("expected tag policy to win, got %s", got)
+	}
+	if ConfirmsDelete(ConfirmNone) || !ConfirmsDelete(ConfirmDelete) || !ConfirmsStop(ConfirmDeleteStop) || ConfirmsStop(ConfirmDelete) {
+		t.Error("unexpected policy semantics")
+	}
+
+	cfg.Confirm = "always"
+	cfg.TagSettings["dev"] = TagSettings{Confirm: "stop"}
+	if errs := cfg.Validate(); len(errs) != 2 {
+		t.Errorf("expected two unknown confirm errors, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateHeartbeat(t *testing.T) {
+	cfg := Config{Heartbeat: HeartbeatConfig{URL: "https://hc-ping.com/abc", Interval: "30s"}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.Heartbeat.Interval = "often"
+	if errs := cfg.Validate(); len(errs) != 1 {
+		t.Errorf("expected an invalid interval error, got %v", errs)
+	}
+}
+
+func TestConfig_RefreshInterval(t *testing.T) {
+	cfg := Config{RefreshInterval: "5s"}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	if cfg.Refresh() != 5*time.Second {
+		t.Errorf("expected 5s, got %v", cfg.Refresh())
+	}
+
+	for _, interval := range []string{"100ms", "1m", "often"} {
+		cfg.RefreshInterval = interval
+		if errs := cfg.Validate(); len(errs) != 1 {
+			t.Errorf("expected an invalid refresh_interval error for %q, got %v", interval, errs)
+		}
+		if cfg.Refresh() != DefaultRefreshInterval {
+			t.Errorf("expected the default for %q, got %v", interval, cfg.Refresh())
+		}
+	}
+}
+
+func TestConfig_ValidateMQTT(t *testing.T) {
+	cfg := Config{MQTT: MQTTConfig{Broker: "tcp://broker:1883", MetricsInterval: "10s"}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.MQTT.MetricsInterval = "0s"
+	if errs := cfg.Validate(); len(errs) != 1 {
+		t.Errorf("expected an invalid metrics_interval error, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateMetricsListen(t *testing.T) {
+	cfg := Config{MetricsListen: "127.0.0.1:9109"}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.MetricsListen = "9109"
+	if errs := cfg.Validate(); len(errs) != 1 {
+		t.Errorf("expected an invalid metrics_listen error, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateDiscovery(t *testing.T) {
+	cfg := Config{Discovery: DiscoveryConfig{
+		Sources: []string{DiscoveryConsul, DiscoveryScan},
+		Subnets: []string{"10.0.1.0/24"},
+		Ports:   []int{5432},
+	}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.Discovery = DiscoveryConfig{
+		Sources: []string{"nmap"},
+		Subnets: []string{"10.0.0.0/8", "10.0.1.0"},
+		Ports:   []int{0},
+		Consul:  "consul",
+	}
+	if errs := cfg.Validate(); len(errs) != 5 {
+		t.Errorf("expected five discovery errors, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateEmail(t *testing.T) {
+	cfg := Config{Email: EmailConfig{SMTPHost: "smtp.example.com", To: []string{"ops@example.com"}, Cooldown: "5m"}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.Email = EmailConfig{SMTPHost: "smtp.example.com", Cooldown: "later"}
+	if errs := cfg.Validate(); len(errs) != 2 {
+		t.Errorf("expected cooldown and recipient errors, got %v", errs)
+	}
+}
+
+func TestConfig_ValidateTemplates(t *testing.T) {
+	cfg := Config{Templates: []TunnelTemplate{{Name: "prod", RemoteHost: "{service}.internal", PortRange: "20000-20099"}}}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.Templates = append(cfg.Templates, TunnelTemplate{Name: "prod", PortRange: "lots"}, TunnelTemplate{})
+	if errs := cfg.Validate(); len(errs) != 3 {
+		t.Errorf("expected duplicate, port_range and missing name errors, got %v", errs)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	tunnels := schema["properties"].(map[string]interface{})["tunnels"].(map[string]interface{})
+	tunnel := tunnels["items"].(map[string]interface{})
+	properties := tunnel["properties"].(map[string]interface{})
+
+	for _, key := range []string{"name", "local_port", "bastion", "standby", "ssh_config_ignore"} {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("expected %s in tunnel schema", key)
+		}
+	}
+	if _, ok := properties["keepalive"].(map[string]interface{})["enum"]; !ok {
+		t.Error("expected keepalive to list its allowed values")
+	}
+}
+
+func TestConfig_ValidateMetrics(t *testing.T) {
+	cfg := Config{
+		Metrics: MetricsBasic,
+		Tunnels: []TunnelConfig{{Name: "db", RemoteHost: "db", RemotePort: 5432, LocalPort: 5432, Metrics: MetricsOff}},
+	}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	cfg.Metrics = "verbose"
+	cfg.Tunnels[0].Metrics = "some"
+	if errs := cfg.Validate(); len(errs) != 2 {
+		t.Errorf("expected two unknown metrics errors, got %v", errs)
+	}
+}
@@ -4,30 +4,315 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type TunnelConfig struct {
-	Name        string `yaml:"name"`
-	LocalPort   int    `yaml:"local_port"`
-	RemotePort  int    `yaml:"remote_port"`
-	RemoteHost  string `yaml:"remote_host"`
-	Tag         string `yaml:"tag"`
-	BindAddress string `yaml:"bind_address,omitempty"`
-	Bastion     struct {
+	Name          string `yaml:"name"`
+	LocalPort     int    `yaml:"local_port"`
+	RemotePort    int    `yaml:"remote_port"`
+	RemoteHost    string `yaml:"remote_host"`
+	Tag           string `yaml:"tag"`
+	BindAddress   string `yaml:"bind_address,omitempty"`
+	BindInterface string `yaml:"bind_interface,omitempty"`
+	Type          string `yaml:"type,omitempty"`         // "" (tcp), "udp" or "wireguard"
+	Keepalive     string `yaml:"keepalive,omitempty"`    // aggressive, balanced or relaxed
+	IdleTimeout   string `yaml:"idle_timeout,omitempty"` // e.g. 15m, drop SSH when unused
+	Autostart     bool   `yaml:"autostart,omitempty"`    // Connect as soon as tunnel9 starts
+	Metrics       string `yaml:"metrics,omitempty"`      // off, basic or full, overrides the top-level metrics
+	DSCP          string `yaml:"dscp,omitempty"`         // e.g. ef or af21, marks the SSH connection's packets for QoS
+	Bastion       struct {
 		Host string `yaml:"host"`
 		User string `yaml:"user"`
 		Port int    `yaml:"port,omitempty"`
 	} `yaml:"bastion,omitempty"`
+	Standby         []StandbyTarget `yaml:"standby,omitempty"`
+	SSHConfigIgnore []string        `yaml:"ssh_config_ignore,omitempty"` // port, user, identity_file, hostname, proxy or all
+	SSHOptions      SSHOptions      `yaml:"ssh_options,omitempty"`
+
+	// Keepalives like ServerAliveInterval and ServerAliveCountMax in
+	// ssh_config, an interval of "0" turns them off
+	ServerAliveInterval string `yaml:"server_alive_interval,omitempty"`  // Default 30s, or the keepalive preset
+	ServerAliveCountMax int    `yaml:"server_alive_count_max,omitempty"` // Default 3
+
+	Reconnect ReconnectPolicy `yaml:"reconnect,omitempty"`
+
+	Auth AuthSecrets `yaml:"auth,omitempty"`
+
+	HealthCheck HealthCheck `yaml:"health_check,omitempty"`
+
+	Browse BrowseSettings `yaml:"browse,omitempty"`
+
+	// Written local_port: auto, the tunnel listens on whatever port is free
+	// when it starts, even with a port_range. LocalPort stays 0.
+	AutoPort bool `yaml:"-"`
+}
+
+// ReconnectPolicy controls how a tunnel redials an SSH connection that died,
+// doubling the delay between attempts up to max_delay
+type ReconnectPolicy struct {
+	MaxRetries   int    `yaml:"max_retries,omitempty"`   // Default 10, -1 waits for the next local connection instead
+	InitialDelay string `yaml:"initial_delay,omitempty"` // Default 1s
+	MaxDelay     string `yaml:"max_delay,omitempty"`     // Default 1m
+}
+
+// AuthSecrets log in with a password and answer one-time code challenges
+// without asking. Each refers to a secret, like pass://work/ssh or
+// keyring:ssh, looked up whenever the tunnel connects.
+type AuthSecrets struct {
+	Password string `yaml:"password,omitempty"`
+	TOTP     string `yaml:"totp,omitempty"` // Seed, base32 or an otpauth:// URI
+}
+
+// HealthCheck probes the service behind a running tunnel through its local
+// endpoint, so a dead target shows up even while the SSH session is fine.
+// An empty protocol turns it off.
+type HealthCheck struct {
+	Protocol string `yaml:"protocol,omitempty"` // "tcp", "http" or "https"
+	Path     string `yaml:"path,omitempty"`     // Requested by http and https checks, default /
+	Interval string `yaml:"interval,omitempty"` // Default 30s
+	Timeout  string `yaml:"timeout,omitempty"`  // Default 5s
+}
+
+// BrowseSettings change the URL opened in the browser for a tunnel, which
+// is http://<bind>:<local_port> by default
+type BrowseSettings struct {
+	Scheme string `yaml:"scheme,omitempty"` // e.g. https, default http
+	Path   string `yaml:"path,omitempty"`   // e.g. /admin/?tab=jobs
+}
+
+// SSHOptions changes the algorithms offered in the SSH handshake, for old
+// servers that only speak legacy ones. Empty lists keep the defaults, and
+// entries starting with + are added to them.
+type SSHOptions struct {
+	Ciphers           []string `yaml:"ciphers,omitempty"`
+	MACs              []string `yaml:"macs,omitempty"`
+	KeyExchanges      []string `yaml:"kex,omitempty"`
+	HostKeyAlgorithms []string `yaml:"host_key_algorithms,omitempty"`
+}
+
+// StandbyTarget is a fallback used when the primary target is unreachable.
+// Empty fields inherit from the primary.
+type StandbyTarget struct {
+	RemoteHost string `yaml:"remote_host,omitempty"`
+	RemotePort int    `yaml:"remote_port,omitempty"`
+	Bastion    string `yaml:"bastion,omitempty"` // user@host[:port]
+}
+
+// IsUDP reports whether the tunnel forwards datagrams rather than streams
+func (tc TunnelConfig) IsUDP() bool {
+	return tc.Type == "udp" || tc.Type == "wireguard"
+}
+
+// Autostarts reports whether the tunnel connects on startup, because it or
+// its tag says so
+func (tc TunnelConfig) Autostarts(tagSettings map[string]TagSettings) bool {
+	return tc.Autostart || tagSettings[tc.Tag].Autostart
+}
+
+// IgnoresSSHConfig reports whether the ssh_config override for key (port,
+// user, identity_file, hostname or proxy) has been switched off for this tunnel
+func (tc TunnelConfig) IgnoresSSHConfig(key string) bool {
+	for _, ignored := range tc.SSHConfigIgnore {
+		if ignored == key || ignored == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryConfig points at a shared location where active forwards are
+// announced so teammates can see who already exposes what
+type RegistryConfig struct {
+	Path    string `yaml:"path,omitempty"`
+	Machine string `yaml:"machine,omitempty"`
+}
+
+// BastionProviderConfig names the plugin command used to provision
+// ephemeral jump hosts for tunnels tagged ephemeral-bastion
+type BastionProviderConfig struct {
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+}
+
+// PublicShareConfig describes the public VPS used to expose local ports to
+// the internet through a remote forward
+type PublicShareConfig struct {
+	Host        string `yaml:"host,omitempty"`
+	User        string `yaml:"user,omitempty"`
+	Port        int    `yaml:"port,omitempty"`
+	BindAddress string `yaml:"bind_address,omitempty"` // Defaults to 0.0.0.0 on the VPS
+	BindPort    int    `yaml:"bind_port,omitempty"`    // 0 lets the server pick
+	Helper      string `yaml:"helper,omitempty"`       // Run on the VPS, may print the public URL
+	URL         string `yaml:"url,omitempty"`          // Template, e.g. https://{name}.example.com
+}
+
+// TagSettings customises how a tag is displayed and what it does on startup
+type TagSettings struct {
+	DisplayName string `yaml:"display_name,omitempty"`
+	Color       string `yaml:"color,omitempty"`    // Any lipgloss color, e.g. "#f97316" or "203"
+	Priority    int    `yaml:"priority,omitempty"` // Lower sorts first
+	Autostart   bool   `yaml:"autostart,omitempty"`
+	Confirm     string `yaml:"confirm,omitempty"` // Overrides the top-level confirm for this tag
+}
+
+// SSHDefaults holds settings shared by every tunnel's SSH connection
+type SSHDefaults struct {
+	// Tried in order when ~/.ssh/config names none, relative to ~/.ssh
+	IdentityFiles []string `yaml:"identity_files,omitempty"`
+	// Passphrases of encrypted identity files, by path like the above. Each
+	// refers to a secret, like keyring:id_work.
+	Passphrases map[string]string `yaml:"passphrases,omitempty"`
+}
+
+// DNSConfig resolves SSH server names through a chosen DNS server instead of
+// the system resolver, for split DNS where corporate names only resolve there
+type DNSConfig struct {
+	Server        string   `yaml:"server,omitempty"`         // host[:port], tls://host[:port] or an https:// DoH URL
+	Domains       []string `yaml:"domains,omitempty"`        // Only names under these, every name when empty
+	ResolveRemote bool     `yaml:"resolve_remote,omitempty"` // Resolve remote_host here too, rather than on the SSH server
+}
+
+// LatencyConfig sets where latency turns from green to yellow to red
+type LatencyConfig struct {
+	GoodMs int `yaml:"good_ms,omitempty"` // Green below this, default 50
+	WarnMs int `yaml:"warn_ms,omitempty"` // Yellow below this, red above, default 200
+}
+
+// HeartbeatConfig makes tunnel9 POST a summary of its tunnels to a monitoring
+// URL, so an alert fires when the machine running it goes away
+type HeartbeatConfig struct {
+	URL      string `yaml:"url,omitempty"`
+	Interval string `yaml:"interval,omitempty"` // e.g. "30s", default one minute
+}
+
+// MQTTConfig publishes tunnel state and metrics to a broker, one topic per
+// tunnel, for dashboards and home automation
+type MQTTConfig struct {
+	Broker          string `yaml:"broker,omitempty"` // e.g. tcp://broker:1883 or ssl://broker:8883
+	Username        string `yaml:"username,omitempty"`
+	Password        string `yaml:"password,omitempty"`         // May refer to a secret, like env:MQTT_PASSWORD, file:/run/secrets/mqtt or keyring:mqtt
+	ClientID        string `yaml:"client_id,omitempty"`        // Defaults to tunnel9-<machine>
+	TopicPrefix     string `yaml:"topic_prefix,omitempty"`     // Defaults to tunnel9/<machine>
+	MetricsInterval string `yaml:"metrics_interval,omitempty"` // e.g. "10s", the default
+}
+
+// EmailConfig emails tunnel failures and recoveries over SMTP, batched so a
+// flapping link doesn't flood the inbox
+type EmailConfig struct {
+	SMTPHost string   `yaml:"smtp_host,omitempty"`
+	SMTPPort int      `yaml:"smtp_port,omitempty"` // Defaults to 587, STARTTLS is used when offered
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"` // May refer to a secret, like env:SMTP_PASSWORD, file:/run/secrets/smtp or keyring:smtp
+	From     string   `yaml:"from,omitempty"`     // Defaults to tunnel9@<machine>
+	To       []string `yaml:"to,omitempty"`
+	Cooldown string   `yaml:"cooldown,omitempty"` // Least time between emails, default 5m
+}
+
+// TunnelTemplate pre-fills the new tunnel dialog for tunnels that only
+// differ in a few places. Values may use {variables}, which the dialog asks
+// for, e.g. remote_host: "{service}.prod.internal".
+type TunnelTemplate struct {
+	Name        string `yaml:"name"`
+	TunnelName  string `yaml:"tunnel_name,omitempty"` // e.g. "{service}-prod"
+	BindAddress string `yaml:"bind_address,omitempty"`
+	LocalPort   string `yaml:"local_port,omitempty"`
+	RemoteHost  string `yaml:"remote_host,omitempty"`
+	RemotePort  string `yaml:"remote_port,omitempty"`
+	BastionHost string `yaml:"bastion_host,omitempty"`
+	BastionPort string `yaml:"bastion_port,omitempty"`
+	BastionUser string `yaml:"bastion_user,omitempty"`
+	Tag         string `yaml:"tag,omitempty"`
+	PortRange   string `yaml:"port_range,omitempty"` // Local ports when local_port is left empty, instead of the top-level port_range
 }
 
 type Config struct {
-	Tunnels []TunnelConfig `yaml:"tunnels"`
+	Tunnels         []TunnelConfig         `yaml:"tunnels"`
+	Registry        RegistryConfig         `yaml:"registry,omitempty"`
+	BastionProvider BastionProviderConfig  `yaml:"bastion_provider,omitempty"`
+	PublicShare     PublicShareConfig      `yaml:"public_share,omitempty"`
+	TagSettings     map[string]TagSettings `yaml:"tag_settings,omitempty"`
+	Latency         LatencyConfig          `yaml:"latency,omitempty"`
+	Confirm         string                 `yaml:"confirm,omitempty"` // Which actions ask first, defaults to delete
+	SSH             SSHDefaults            `yaml:"ssh,omitempty"`
+	Heartbeat       HeartbeatConfig        `yaml:"heartbeat,omitempty"`
+	MQTT            MQTTConfig             `yaml:"mqtt,omitempty"`
+	Email           EmailConfig            `yaml:"email,omitempty"`
+	PortRange       string                 `yaml:"port_range,omitempty"` // e.g. "20000-20999", for tunnels without a local_port
+	Templates       []TunnelTemplate       `yaml:"templates,omitempty"`
+	MetricsListen   string                 `yaml:"metrics_listen,omitempty"`   // e.g. "127.0.0.1:9109", serves Prometheus metrics at /metrics
+	Metrics         string                 `yaml:"metrics,omitempty"`          // What tunnels measure unless they say otherwise, defaults to full
+	RefreshInterval string                 `yaml:"refresh_interval,omitempty"` // How often the table redraws, e.g. "5s", 500ms to 10s, default 1s
+	DNS             DNSConfig              `yaml:"dns,omitempty"`
+	Notifications   NotificationsConfig    `yaml:"notifications,omitempty"`
+	StartupGroups   []StartupGroup         `yaml:"startup_groups,omitempty"`
+	API             APIConfig              `yaml:"api,omitempty"`
+	Discovery       DiscoveryConfig        `yaml:"discovery,omitempty"`
+	Defaults        TunnelDefaults         `yaml:"defaults,omitempty"` // Inherited by tunnels that leave these settings out
+	Theme           ThemeConfig            `yaml:"theme,omitempty"`
+	Log             LogConfig              `yaml:"log,omitempty"`
+}
+
+// Metrics levels, from cheapest to most detailed
+const (
+	MetricsOff   = "off"   // Nothing is counted or sampled
+	MetricsBasic = "basic" // Bytes and rates, without latency probes
+	MetricsFull  = "full"  // Bytes, rates and latency
+)
+
+// Bounds of refresh_interval. Metrics are sampled every second whatever it is.
+const (
+	DefaultRefreshInterval = time.Second
+	MinRefreshInterval     = 500 * time.Millisecond
+	MaxRefreshInterval     = 10 * time.Second
+)
+
+// Refresh returns how often the table redraws, the default when
+// refresh_interval is unset or invalid
+func (c Config) Refresh() time.Duration {
+	interval, err := time.ParseDuration(c.RefreshInterval)
+	if err != nil || interval < MinRefreshInterval || interval > MaxRefreshInterval {
+		return DefaultRefreshInterval
+	}
+	return interval
+}
+
+// Confirmation policies, deciding which actions ask before going ahead
+const (
+	ConfirmDelete     = "delete"
+	ConfirmDeleteStop = "delete+stop"
+	ConfirmNone       = "none"
+)
+
+// ConfirmPolicy returns the confirmation policy for tunnels with the given
+// tag, preferring the tag's own setting
+func (c Config) ConfirmPolicy(tag string) string {
+	if settings, ok := c.TagSettings[tag]; ok && settings.Confirm != "" {
+		return settings.Confirm
+	}
+	if c.Confirm != "" {
+		return c.Confirm
+	}
+	return ConfirmDelete
+}
+
+// ConfirmsDelete reports whether a policy asks before deleting
+func ConfirmsDelete(policy string) bool {
+	return policy != ConfirmNone
+}
+
+// ConfirmsStop reports whether a policy asks before stopping
+func ConfirmsStop(policy string) bool {
+	return policy == ConfirmDeleteStop
 }
 
 type ConfigLoader struct {
-	path string
+	path     string
+	config   Config         // Last loaded config, so saves keep non-tunnel sections
+	assigned map[string]int // Local ports handed out from port_range, by tunnel name
+	fromEnv  bool           // Config read from the environment, with no file to save to
 }
 
 func NewConfigLoader(path string) *ConfigLoader {
@@ -76,20 +361,93 @@ func (c *ConfigLoader) Load() ([]TunnelConfig, error) {
 		return []TunnelConfig{}, err
 	}
 
+	config, assigned, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	c.config, c.assigned = config, assigned
+	return config.Tunnels, nil
+}
+
+// ParseConfig reads a config file's contents the way Load does, for configs
+// that arrive some other way than as the loader's file
+func ParseConfig(data []byte) (Config, error) {
+	config, _, err := parseConfig(data)
+	return config, err
+}
+
+// parseConfig also returns the local ports handed out from port_range
+func parseConfig(data []byte) (Config, map[string]int, error) {
 	var config Config
+	data, auto := StripAutoPorts(data)
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, err
+		return config, nil, err
 	}
+	config.MarkAutoPorts(auto)
 
-	return config.Tunnels, nil
+	// Tunnels that leave local_port out get one from port_range, and what
+	// else they leave out from the defaults
+	var assigned map[string]int
+	config.Tunnels, assigned = config.AssignPorts()
+	config.Tunnels = config.ApplyDefaults(config.Tunnels)
+	return config, assigned, nil
+}
+
+// AssignPort hands a tunnel without a local port one from port_range,
+// avoiding the ports in used. Like ports assigned on load, it isn't written
+// to the config file.
+func (c *ConfigLoader) AssignPort(name string, used map[int]bool) (int, bool) {
+	if c.config.PortRange == "" {
+		return 0, false
+	}
+	lo, hi, err := ParsePortRange(c.config.PortRange)
+	if err != nil {
+		return 0, false
+	}
+	port, ok := AssignPort(name, lo, hi, used)
+	if ok {
+		if c.assigned == nil {
+			c.assigned = make(map[string]int)
+		}
+		c.assigned[name] = port
+	}
+	return port, ok
+}
+
+// Path returns the config file the loader reads and writes
+func (c *ConfigLoader) Path() string {
+	return c.path
+}
+
+// Config returns the sections of the last loaded config file
+func (c *ConfigLoader) Config() Config {
+	return c.config
 }
 
 func (c *ConfigLoader) Save(tunnels []TunnelConfig) error {
-	config := Config{
-		Tunnels: tunnels,
+	config := c.config
+	config.Tunnels = tunnels
+	return c.SaveConfig(config)
+}
+
+// SaveConfig writes a whole config file, including the non-tunnel sections
+func (c *ConfigLoader) SaveConfig(config Config) error {
+	if c.fromEnv {
+		return fmt.Errorf("the config comes from the environment and can't be saved")
+	}
+
+	// Keep assigned ports out of the file, so they follow the name hash,
+	// and the defaults, so changing them still reaches every tunnel
+	saved := config
+	saved.Tunnels = make([]TunnelConfig, len(config.Tunnels))
+	for i, tc := range config.Tunnels {
+		if port, ok := c.assigned[tc.Name]; ok && port == tc.LocalPort {
+			tc.LocalPort = 0
+		}
+		saved.Tunnels[i] = config.Defaults.Strip(tc)
 	}
 
-	data, err := yaml.Marshal(config)
+	data, err := yaml.Marshal(saved)
 	if err != nil {
 		return fmt.Errorf("error marshaling config: %w", err)
 	}
@@ -100,10 +458,11 @@ func (c *ConfigLoader) Save(tunnels []TunnelConfig) error {
 		return fmt.Errorf("error creating config directory: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(c.path, data, 0644); err != nil {
-		return fmt.Errorf("error writing config file: %w", err)
+	// Never leave a half written file behind, and keep what it replaces
+	if err := writeConfigFile(c.path, data); err != nil {
+		return err
 	}
 
+	c.config = config
 	return nil
 }
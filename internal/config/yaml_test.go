@@ -261,3 +261,69 @@ func containsPathPart(path, part string) bool {
 	return filepath.Base(path) == part ||
 		filepath.Dir(path) != "." && containsPathPart(filepath.Dir(path), part)
 }
+
+func TestConfigLoader_SavePreservesSections(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tunnel9-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configYAML := `registry:
+  path: /mnt/team/tunnel9
+tunnels:
+  - name: "web-tunnel"
+    local_port: 8080
+    remote_port: 80
+    remote_host: "web.example.com"`
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	loader := NewConfigLoader(configPath)
+	tunnels, err := loader.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if err := loader.Save(tunnels[:0]); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	reloaded := NewConfigLoader(configPath)
+	if _, err := reloaded.Load(); err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if reloaded.Config().Registry.Path != "/mnt/team/tunnel9" {
+		t.Errorf("expected registry path to survive save, got %q", reloaded.Config().Registry.Path)
+	}
+}
+
+func TestTunnelConfig_IgnoresSSHConfig(t *testing.T) {
+	tc := TunnelConfig{SSHConfigIgnore: []string{"port", "identity_file"}}
+	if !tc.IgnoresSSHConfig("port") || !tc.IgnoresSSHConfig("identity_file") {
+		t.Error("expected listed overrides to be ignored")
+	}
+	if tc.IgnoresSSHConfig("user") {
+		t.Error("expected user override to still apply")
+	}
+
+	tc.SSHConfigIgnore = []string{"all"}
+	if !tc.IgnoresSSHConfig("hostname") {
+		t.Error("expected all to ignore every override")
+	}
+}
+
+func TestTunnelConfig_Autostarts(t *testing.T) {
+	tagSettings := map[string]TagSettings{"dev": {Autostart: true}}
+	if !(TunnelConfig{Autostart: true}).Autostarts(tagSettings) {
+		t.Error("expected a tunnel set to autostart to start")
+	}
+	if !(TunnelConfig{Tag: "dev"}).Autostarts(tagSettings) {
+		t.Error("expected a tunnel with an autostart tag to start")
+	}
+	if (TunnelConfig{Tag: "prod"}).Autostarts(tagSettings) {
+		t.Error("expected other tunnels to stay stopped")
+	}
+}
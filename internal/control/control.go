@@ -0,0 +1,297 @@
+// Package control lets one tunnel9 drive another. A headless daemon listens
+// on a unix socket and answers newline-delimited JSON requests, which a TUI
+// can send locally or, through an SSH connection, from another machine.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Operations a client can ask for
+const (
+	OpList  = "list"
+	OpStart = "start"
+	OpStop  = "stop"
+
+	OpUpgrade = "upgrade"
+	OpApply   = "apply"
+)
+
+// DefaultSocket is where the daemon listens, relative to the home directory
+var DefaultSocket = filepath.Join(".local", "state", "tunnel9", "control.sock")
+
+// Request is one line sent to the daemon
+type Request struct {
+	Op     string `json:"op"`
+	Name   string `json:"name,omitempty"`    // Tunnel to start or stop
+	Path   string `json:"path,omitempty"`    // Binary to upgrade to
+	Config string `json:"config,omitempty"`  // Config file contents to apply
+	DryRun bool   `json:"dry_run,omitempty"` // Only plan what applying would change
+	Token  string `json:"token,omitempty"`   // Required by the API, see ServeTokens
+}
+
+// Response answers a request, with Error set when it failed
+type Response struct {
+	Error   string        `json:"error,omitempty"`
+	Tunnels []TunnelState `json:"tunnels,omitempty"`
+	Changes []Change      `json:"changes,omitempty"`
+}
+
+// Change is one tunnel added, updated or removed by applying a config
+type Change struct {
+	Action string   `json:"action"` // "add", "update" or "remove"
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"` // Settings an update changes
+	Effect string   `json:"effect,omitempty"` // "start", "restart" or "stop", when it touches a running tunnel
+}
+
+// TunnelState is what the daemon reports about one of its tunnels
+type TunnelState struct {
+	Name    string `json:"name"`
+	Tag     string `json:"tag,omitempty"`
+	Status  string `json:"status"` // "stopped", "connecting", "active", "error"
+	Message string `json:"message,omitempty"`
+	Local   string `json:"local"`  // Address clients connect to on the daemon's machine
+	Remote  string `json:"remote"` // Target as seen from the SSH server
+	Via     string `json:"via"`    // SSH server the tunnel goes through
+
+	// While active
+	RateIn      float64    `json:"rate_in,omitempty"`  // bytes per second
+	RateOut     float64    `json:"rate_out,omitempty"` // bytes per second
+	LatencyMs   int64      `json:"latency_ms,omitempty"`
+	ActiveSince *time.Time `json:"active_since,omitempty"`
+}
+
+// Handler is what a daemon exposes over the socket
+type Handler interface {
+	List() []TunnelState
+	Start(name string) error
+	Stop(name string) error
+}
+
+// Upgrader is implemented by daemons that can hand their sockets to a new
+// binary without dropping connections
+type Upgrader interface {
+	Upgrade(path string) error
+}
+
+// Applier is implemented by daemons that can switch to another config,
+// changing only the tunnels that differ
+type Applier interface {
+	Apply(config []byte, dryRun bool) ([]Change, error)
+}
+
+// SocketPath returns the daemon socket path, resolving one relative to the
+// home directory
+func SocketPath(path string) (string, error) {
+	if path == "" {
+		path = DefaultSocket
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, path), nil
+}
+
+// Listen opens the daemon socket, replacing one left behind by a daemon that
+// didn't shut down cleanly. Only the owner may connect.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	// Refuse to steal the socket from a daemon that is still running
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another tunnel9 daemon is listening on %s", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Serve answers requests on every connection accepted from listener until
+// it is closed
+func Serve(listener net.Listener, handler Handler) error {
+	return serve(listener, handler, nil)
+}
+
+// serve answers requests that authorize lets through, all of them when it
+// is nil
+func serve(listener net.Listener, handler Handler, authorize func(req Request) error) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveConn(conn, handler, authorize)
+	}
+}
+
+func serveConn(conn net.Conn, handler Handler, authorize func(req Request) error) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		if authorize != nil {
+			if err := authorize(req); err != nil {
+				encoder.Encode(Response{Error: err.Error()})
+				continue
+			}
+		}
+		if err := encoder.Encode(handle(handler, req)); err != nil {
+			return
+		}
+	}
+}
+
+func handle(handler Handler, req Request) Response {
+	var err error
+	var changes []Change
+	switch req.Op {
+	case OpList:
+		return Response{Tunnels: handler.List()}
+	case OpStart:
+		err = handler.Start(req.Name)
+	case OpStop:
+		err = handler.Stop(req.Name)
+	case OpUpgrade:
+		upgrader, ok := handler.(Upgrader)
+		if !ok {
+			err = fmt.Errorf("this daemon can't upgrade in place")
+			break
+		}
+		err = upgrader.Upgrade(req.Path)
+	case OpApply:
+		applier, ok := handler.(Applier)
+		if !ok {
+			err = fmt.Errorf("this daemon can't apply a config")
+			break
+		}
+		changes, err = applier.Apply([]byte(req.Config), req.DryRun)
+	default:
+		err = fmt.Errorf("unknown op %q", req.Op)
+	}
+	if err != nil {
+		return Response{Error: err.Error(), Changes: changes}
+	}
+	return Response{Changes: changes}
+}
+
+// Client sends requests to a daemon, one at a time
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	mu      sync.Mutex
+	token   string // Sent with every request, see SetToken
+}
+
+// NewClient talks to the daemon on the other end of conn
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+// Dial connects to a daemon on this machine
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+func (c *Client) call(req Request) (Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req.Token = c.token
+	data, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return Response{}, err
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return Response{}, err
+		}
+		return Response{}, fmt.Errorf("daemon closed the connection")
+	}
+
+	var resp Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return Response{}, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// List returns the daemon's tunnels
+func (c *Client) List() ([]TunnelState, error) {
+	resp, err := c.call(Request{Op: OpList})
+	return resp.Tunnels, err
+}
+
+// Start asks the daemon to start a tunnel by name
+func (c *Client) Start(name string) error {
+	_, err := c.call(Request{Op: OpStart, Name: name})
+	return err
+}
+
+// Stop asks the daemon to stop a tunnel by name
+func (c *Client) Stop(name string) error {
+	_, err := c.call(Request{Op: OpStop, Name: name})
+	return err
+}
+
+// Close hangs up, and closes the SSH connection for remote daemons
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Upgrade asks the daemon to hand its tunnels over to the binary at path.
+// It returns once the new daemon accepts connections.
+func (c *Client) Upgrade(path string) error {
+	_, err := c.call(Request{Op: OpUpgrade, Path: path})
+	return err
+}
+
+// Apply has the daemon switch to a config, given as the contents of a config
+// file, returning the changes. A dry run only plans them. The changes made
+// are returned even when some of them failed.
+func (c *Client) Apply(config []byte, dryRun bool) ([]Change, error) {
+	resp, err := c.call(Request{Op: OpApply, Config: string(config), DryRun: dryRun})
+	return resp.Changes, err
+}
@@ -0,0 +1,95 @@
+package control
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type fakeHandler struct {
+	mu      sync.Mutex
+	tunnels map[string]string
+}
+
+func (h *fakeHandler) List() []TunnelState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	states := make([]TunnelState, 0, len(h.tunnels))
+	for name, status := range h.tunnels {
+		states = append(states, TunnelState{Name: name, Status: status})
+	}
+	return states
+}
+
+func (h *fakeHandler) set(name string, status string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.tunnels[name]; !ok {
+		return fmt.Errorf("no tunnel named %s", name)
+	}
+	h.tunnels[name] = status
+	return nil
+}
+
+func (h *fakeHandler) Start(name string) error { return h.set(name, "active") }
+func (h *fakeHandler) Stop(name string) error  { return h.set(name, "stopped") }
+
+func TestServeAndClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	listener, err := Listen(path)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	handler := &fakeHandler{tunnels: map[string]string{"db": "stopped"}}
+	go Serve(listener, handler)
+
+	// A second daemon must not take over the socket
+	if _, err := Listen(path); err == nil {
+		t.Errorf("expected a second daemon to be refused")
+	}
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Start("db"); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	tunnels, err := client.List()
+	if err != nil || len(tunnels) != 1 || tunnels[0].Status != "active" {
+		t.Errorf("expected db to be active, got %+v (%v)", tunnels, err)
+	}
+
+	if err := client.Stop("cache"); err == nil || err.Error() != "no tunnel named cache" {
+		t.Errorf("expected the daemon's error, got %v", err)
+	}
+}
+
+type fakeUpgrader struct {
+	fakeHandler
+	path string
+}
+
+func (h *fakeUpgrader) Upgrade(path string) error {
+	h.path = path
+	return nil
+}
+
+func TestUpgrade(t *testing.T) {
+	// Handlers that can't upgrade say so
+	response := handle(&fakeHandler{}, Request{Op: OpUpgrade, Path: "/usr/bin/tunnel9"})
+	if response.Error == "" {
+		t.Errorf("expected an error from a handler that can't upgrade")
+	}
+
+	upgrader := &fakeUpgrader{}
+	response = handle(upgrader, Request{Op: OpUpgrade, Path: "/usr/bin/tunnel9"})
+	if response.Error != "" || upgrader.path != "/usr/bin/tunnel9" {
+		t.Errorf("expected the upgrade to reach the handler, got %+v and %q", response, upgrader.path)
+	}
+}
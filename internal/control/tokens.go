@@ -0,0 +1,68 @@
+package control
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+)
+
+// Token scopes, matching the config's
+const (
+	ScopeRead    = "read"    // List tunnels
+	ScopeControl = "control" // List, start and stop tunnels
+)
+
+// Token is a credential the API accepts
+type Token struct {
+	Name   string
+	Secret string
+	Scope  string
+}
+
+// allows reports whether the scope covers an op. Upgrades and applying a
+// config are only ever asked for over the control socket.
+func (t Token) allows(op string) bool {
+	switch op {
+	case OpList:
+		return t.Scope == ScopeRead || t.Scope == ScopeControl
+	case OpStart, OpStop:
+		return t.Scope == ScopeControl
+	}
+	return false
+}
+
+// tokenAuthorizer checks each request's token against tokens
+func tokenAuthorizer(tokens []Token) func(req Request) error {
+	return func(req Request) error {
+		for _, token := range tokens {
+			// An empty secret would let in requests without a token
+			if token.Secret == "" {
+				continue
+			}
+			if subtle.ConstantTimeCompare([]byte(req.Token), []byte(token.Secret)) != 1 {
+				continue
+			}
+			if !token.allows(req.Op) {
+				return fmt.Errorf("token %q may not %s", token.Name, req.Op)
+			}
+			return nil
+		}
+		return fmt.Errorf("missing or unknown token")
+	}
+}
+
+// ServeTokens answers requests like Serve, but only those carrying one of
+// the tokens and within its scope. It is for listeners other processes can
+// reach, such as a TCP port, where the socket's file permissions don't
+// keep them out.
+func ServeTokens(listener net.Listener, handler Handler, tokens []Token) error {
+	return serve(listener, handler, tokenAuthorizer(tokens))
+}
+
+// SetToken has the client send token with every request, for daemons
+// reached through their API rather than the control socket
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
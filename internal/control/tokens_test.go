@@ -0,0 +1,56 @@
+package control
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServeTokens(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	defer listener.Close()
+
+	handler := &fakeHandler{tunnels: map[string]string{"db": "active"}}
+	go ServeTokens(listener, handler, []Token{
+		{Name: "grafana", Secret: "read-secret", Scope: ScopeRead},
+		{Name: "ci", Secret: "control-secret", Scope: ScopeControl},
+		{Name: "unset", Secret: "", Scope: ScopeControl},
+	})
+
+	dial := func(token string) *Client {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(conn)
+		client.SetToken(token)
+		return client
+	}
+
+	anonymous := dial("")
+	defer anonymous.Close()
+	if _, err := anonymous.List(); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Errorf("expected a request without a token refused, got %v", err)
+	}
+
+	reader := dial("read-secret")
+	defer reader.Close()
+	if tunnels, err := reader.List(); err != nil || len(tunnels) != 1 {
+		t.Errorf("expected a read token to list, got %+v (%v)", tunnels, err)
+	}
+	if err := reader.Stop("db"); err == nil || !strings.Contains(err.Error(), `"grafana" may not stop`) {
+		t.Errorf("expected a read token refused a stop, got %v", err)
+	}
+
+	controller := dial("control-secret")
+	defer controller.Close()
+	if err := controller.Stop("db"); err != nil {
+		t.Errorf("expected a control token to stop, got %v", err)
+	}
+	if err := controller.Upgrade("/usr/bin/tunnel9"); err == nil {
+		t.Error("expected upgrades refused over the API")
+	}
+}
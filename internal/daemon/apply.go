@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+)
+
+// Apply switches the daemon to another config, given as the contents of a
+// config file. Tunnels that didn't change keep running untouched, running
+// ones that did are restarted, removed ones stopped, and added ones started
+// when set to autostart. A dry run only plans the changes.
+func (d *Daemon) Apply(data []byte, dryRun bool) ([]control.Change, error) {
+	cfg, err := config.ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	d.mu.Lock()
+	current := append([]config.TunnelConfig(nil), d.tunnels...)
+	running := make(map[string]bool, len(d.states))
+	for name, state := range d.states {
+		running[name] = state.Status != "stopped"
+	}
+	d.mu.Unlock()
+
+	diff := config.DiffTunnels(current, cfg.Tunnels)
+	changes := make([]control.Change, len(diff))
+	for i, change := range diff {
+		changes[i] = control.Change{Action: change.Action, Name: change.Name, Fields: change.Fields}
+		switch {
+		case change.Action == config.ChangeRemove && running[change.Name]:
+			changes[i].Effect = "stop"
+		case change.Action == config.ChangeUpdate && running[change.Name]:
+			changes[i].Effect = "restart"
+		case change.Action == config.ChangeAdd && change.Config.Autostarts(cfg.TagSettings):
+			changes[i].Effect = "start"
+		}
+	}
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	// Stop first, so ports given up are free for the tunnels taking them
+	var errs []error
+	for _, change := range changes {
+		if change.Effect == "stop" || change.Effect == "restart" {
+			if err := d.Stop(change.Name); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", change.Name, err))
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.tunnels = cfg.Tunnels
+	for _, change := range diff {
+		if change.Action == config.ChangeRemove {
+			delete(d.states, change.Name)
+		} else {
+			d.states[change.Name] = stoppedState(change.Config)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, change := range changes {
+		fmt.Fprintf(d.out, "Applied %s of %s\n", change.Action, change.Name)
+		if change.Effect == "start" || change.Effect == "restart" {
+			if err := d.Start(change.Name); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", change.Name, err))
+			}
+		}
+	}
+	return changes, errors.Join(errs...)
+}
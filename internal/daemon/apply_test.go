@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestDaemonApply(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dbPort, webPort, cachePort := freePort(t), freePort(t), freePort(t)
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: dbPort, RemoteHost: "db.internal", RemotePort: 5432, BindAddress: "127.0.0.1"},
+		{Name: "web", LocalPort: webPort, RemoteHost: "web.internal", RemotePort: 80, BindAddress: "127.0.0.1"},
+		{Name: "old", LocalPort: freePort(t), RemoteHost: "old.internal", RemotePort: 22, BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+	if err := d.Start("db"); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	data := []byte(fmt.Sprintf(`tunnels:
+  - name: db
+    local_port: %d
+    remote_host: db2.internal
+    remote_port: 5432
+    bind_address: 127.0.0.1
+  - name: web
+    local_port: %d
+    remote_host: web.internal
+    remote_port: 80
+    bind_address: 127.0.0.1
+  - name: cache
+    local_port: %d
+    remote_host: cache.internal
+    remote_port: 6379
+    bind_address: 127.0.0.1
+    autostart: true
+`, dbPort, webPort, cachePort))
+
+	plan, err := d.Apply(data, true)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	want := map[string]string{"old": "remove/", "db": "update/restart", "cache": "add/start"}
+	if len(plan) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), plan)
+	}
+	for _, change := range plan {
+		if got := change.Action + "/" + change.Effect; got != want[change.Name] {
+			t.Errorf("expected %s to %s, got %s", change.Name, want[change.Name], got)
+		}
+	}
+	if states := d.List(); len(states) != 3 || states[2].Name != "old" {
+		t.Fatalf("expected a dry run to change nothing, got %+v", states)
+	}
+
+	if _, err := d.Apply(data, false); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	states := d.List()
+	if len(states) != 3 || states[0].Name != "db" || states[2].Name != "cache" {
+		t.Fatalf("expected the new tunnels in config order, got %+v", states)
+	}
+	if states[0].Status != "connecting" || states[0].Via != "db2.internal:22" {
+		t.Errorf("expected db restarted with its new host, got %+v", states[0])
+	}
+	if states[1].Status != "stopped" || states[2].Status != "connecting" {
+		t.Errorf("expected web untouched and cache started, got %+v", states)
+	}
+
+	if _, err := d.Apply([]byte("tunnels: [{name: db}, {name: db}]"), true); err == nil {
+		t.Error("expected an invalid config to be refused")
+	}
+}
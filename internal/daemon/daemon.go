@@ -0,0 +1,424 @@
+// Package daemon runs tunnels without the TUI, for always-on forwards on a
+// shared machine. It answers the control socket, so a TUI elsewhere can list,
+// start and stop its tunnels.
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+	"tunnel9/internal/events"
+	"tunnel9/internal/metrics"
+	"tunnel9/internal/notify"
+	"tunnel9/internal/ssh"
+)
+
+// Daemon runs the tunnels of a config headless
+type Daemon struct {
+	manager  *ssh.TunnelManager
+	tunnels  []config.TunnelConfig
+	states   map[string]*control.TunnelState // By tunnel name, which is also the manager ID
+	mu       sync.Mutex
+	out      io.Writer
+	logs     *events.Subscription
+	statuses *events.Subscription
+	done     chan struct{}
+
+	notifications config.NotificationsConfig // Where state changes go
+	emailer       *notify.Emailer            // Nil unless email alerts are configured
+	machine       string
+
+	listener   net.Listener // Control socket, handed over on upgrade
+	api        net.Listener // Control protocol over TCP, nil unless api.listen is set
+	metrics    net.Listener // Exported metrics, nil unless metrics_listen is set
+	upgrading  bool
+	handedOver bool
+
+	// Listeners an upgraded daemon handed over, until they are served
+	inheritedAPI     net.Listener
+	inheritedMetrics net.Listener
+}
+
+// New prepares a daemon for the given tunnels, all stopped, logging to out
+func New(tunnels []config.TunnelConfig, out io.Writer) *Daemon {
+	d := &Daemon{
+		manager: ssh.NewTunnelManager(),
+		tunnels: tunnels,
+		states:  make(map[string]*control.TunnelState, len(tunnels)),
+		out:     out,
+		done:    make(chan struct{}),
+	}
+	for _, tc := range tunnels {
+		d.states[tc.Name] = stoppedState(tc)
+	}
+
+	d.logs = d.manager.Events.Subscribe(100, events.DropOldest, events.KindLog)
+	d.statuses = d.manager.Events.Subscribe(100, events.DropOldest, events.KindStatus)
+	go d.followEvents()
+	return d
+}
+
+// stoppedState is what is reported about a tunnel before it first starts
+func stoppedState(tc config.TunnelConfig) *control.TunnelState {
+	sshEndpoint, remoteEndpoint := ssh.TargetEndpoints(tc)
+	return &control.TunnelState{
+		Name:   tc.Name,
+		Tag:    tc.Tag,
+		Status: "stopped",
+		Local:  localAddress(tc),
+		Remote: remoteEndpoint.String(),
+		Via:    sshEndpoint.String(),
+	}
+}
+
+// localAddress is where a tunnel accepts connections before it is running,
+// with the port as auto for one that picks it when it starts
+func localAddress(tc config.TunnelConfig) string {
+	host := tc.BindAddress
+	if host == "" {
+		host = "localhost"
+	}
+	port := strconv.Itoa(tc.LocalPort)
+	if tc.PicksPort() {
+		port = config.AutoPort
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// followEvents prints log lines and keeps track of every tunnel's state
+func (d *Daemon) followEvents() {
+	logs, statuses := d.logs.C, d.statuses.C
+	for logs != nil || statuses != nil {
+		select {
+		case event, ok := <-logs:
+			if !ok {
+				logs = nil
+				continue
+			}
+			fmt.Fprintln(d.out, event.Message)
+		case event, ok := <-statuses:
+			if !ok {
+				statuses = nil
+				continue
+			}
+			d.mu.Lock()
+			if state, exists := d.states[event.TunnelID]; exists && state.Status != "stopped" {
+				d.noteTransition(*state, event.State, event.Message)
+				state.Status = event.State
+				state.Message = event.Message
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// Autostart starts every tunnel set to autostart, by itself or its tag, or
+// every tunnel with the given tag
+func (d *Daemon) Autostart(tagSettings map[string]config.TagSettings, tag string) {
+	for _, tc := range d.tunnels {
+		if tag != "" && tc.Tag != tag {
+			continue
+		}
+		if tag == "" && !tc.Autostarts(tagSettings) {
+			continue
+		}
+		if err := d.Start(tc.Name); err != nil {
+			fmt.Fprintf(d.out, "Failed to start %s: %v\n", tc.Name, err)
+		}
+	}
+}
+
+func (d *Daemon) find(name string) (config.TunnelConfig, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, tc := range d.tunnels {
+		if tc.Name == name {
+			return tc, nil
+		}
+	}
+	return config.TunnelConfig{}, fmt.Errorf("no tunnel named %s", name)
+}
+
+// List reports every tunnel and its state
+func (d *Daemon) List() []control.TunnelState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := make([]control.TunnelState, 0, len(d.tunnels))
+	for _, tc := range d.tunnels {
+		state := *d.states[tc.Name]
+		if snapshot, ok := d.manager.Snapshot(tc.Name); ok {
+			if snapshot.Local != "" {
+				state.Local = snapshot.Local
+			}
+			if state.Status == "active" && !snapshot.ActiveSince.IsZero() {
+				since := snapshot.ActiveSince
+				state.ActiveSince = &since
+				state.RateIn = snapshot.Values.RateIn
+				state.RateOut = snapshot.Values.RateOut
+				if snapshot.Values.Latency > 0 {
+					state.LatencyMs = snapshot.Values.Latency.Milliseconds()
+				}
+			}
+		}
+		list = append(list, state)
+	}
+	return list
+}
+
+// Start brings a tunnel up, doing nothing if it already runs
+func (d *Daemon) Start(name string) error {
+	tc, err := d.find(name)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	previous := d.states[name].Status
+	d.mu.Unlock()
+	switch previous {
+	case "stopped":
+	case "error":
+		// Clear out what's left of the failed run first
+		d.manager.StopTunnel(name)
+	default:
+		return nil
+	}
+	d.setState(name, "connecting", "initializing")
+
+	fmt.Fprintf(d.out, "Starting %s\n", name)
+	tunnel := d.manager.CreateTunnel(name, tc)
+	if err := d.manager.StartTunnel(tunnel); err != nil {
+		d.manager.StopTunnel(name)
+		d.setState(name, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// Stop takes a tunnel down
+func (d *Daemon) Stop(name string) error {
+	if _, err := d.find(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(d.out, "Stopping %s\n", name)
+	err := d.manager.StopTunnel(name)
+	d.setState(name, "stopped", "stopped")
+	return err
+}
+
+func (d *Daemon) setState(name string, status string, message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.noteTransition(*d.states[name], status, message)
+	d.states[name].Status = status
+	d.states[name].Message = message
+}
+
+// SetNotifications sends state changes where the notifications config
+// routes them, emailing through the email config, naming machine as where
+// they happened
+func (d *Daemon) SetNotifications(notifications config.NotificationsConfig, email config.EmailConfig, machine string) {
+	d.mu.Lock()
+	d.notifications, d.machine = notifications, machine
+	d.emailer = notify.NewEmailer(email, machine)
+	emailer := d.emailer
+	d.mu.Unlock()
+	if emailer != nil {
+		go d.sendEmail(emailer)
+	}
+}
+
+// noteTransition sends a tunnel's change from its state to status where it
+// is routed, in the background. Called with d.mu held.
+func (d *Daemon) noteTransition(state control.TunnelState, status string, message string) {
+	if state.Status == status {
+		if d.emailer != nil {
+			d.emailer.Refresh(state.Name, status, message)
+		}
+		return
+	}
+	event := notify.Event{
+		Machine:  d.machine,
+		Tunnel:   state.Name,
+		Tag:      state.Tag,
+		From:     state.Status,
+		To:       status,
+		Severity: config.Severity(state.Status, status),
+		Message:  message,
+		Time:     time.Now(),
+	}
+	for _, send := range notify.Route(d.notifications, event) {
+		if send.Sink == config.SinkEmail {
+			if d.emailer != nil {
+				d.emailer.Add(notify.NewAlert(event))
+			}
+			continue
+		}
+		go func() {
+			if err := send.Deliver(event); err != nil {
+				fmt.Fprintf(d.out, "Failed to send %s notification for %s: %v\n", send.Sink, event.Tunnel, err)
+			}
+		}()
+	}
+}
+
+// How often queued email alerts are checked for being due
+const emailInterval = time.Second
+
+// sendEmail mails the queued alerts once they are due, until the daemon is
+// closed
+func (d *Daemon) sendEmail(emailer *notify.Emailer) {
+	ticker := time.NewTicker(emailInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			alerts := emailer.Take(now)
+			d.mu.Unlock()
+			if len(alerts) == 0 {
+				continue
+			}
+			if err := emailer.Send(alerts); err != nil {
+				fmt.Fprintf(d.out, "Failed to email %d alert(s) via %s: %v\n", len(alerts), emailer.Server(), err)
+			}
+		}
+	}
+}
+
+// Serve answers the control socket until listener is closed
+func (d *Daemon) Serve(listener net.Listener) error {
+	d.mu.Lock()
+	d.listener = listener
+	d.mu.Unlock()
+	return control.Serve(listener, d)
+}
+
+// Close stops every tunnel
+func (d *Daemon) Close() {
+	close(d.done)
+	d.manager.Cleanup()
+}
+
+// ServeAPI answers the control protocol on a TCP address until the daemon
+// is closed or hands over, for requests carrying one of the tokens
+func (d *Daemon) ServeAPI(addr string, tokens []control.Token) error {
+	listener, err := d.listenTCP(addr, &d.inheritedAPI)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.api = listener
+	d.mu.Unlock()
+	go control.ServeTokens(listener, d, tokens)
+	go func() {
+		<-d.done
+		listener.Close()
+	}()
+	return nil
+}
+
+// listenTCP listens on addr, taking over the listener an upgraded daemon
+// handed over in *inherited when it is on the same address
+func (d *Daemon) listenTCP(addr string, inherited *net.Listener) (net.Listener, error) {
+	d.mu.Lock()
+	listener := *inherited
+	*inherited = nil
+	d.mu.Unlock()
+	if listener != nil {
+		if sameAddress(listener.Addr().String(), addr) {
+			return listener, nil
+		}
+		listener.Close()
+	}
+	return net.Listen("tcp", addr)
+}
+
+// sameAddress reports whether a listener on bound serves addr, as written in
+// the config, e.g. ":9109" for one bound to [::]:9109
+func sameAddress(bound string, addr string) bool {
+	a, err := net.ResolveTCPAddr("tcp", bound)
+	if err != nil {
+		return false
+	}
+	b, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return false
+	}
+	unspecified := func(ip net.IP) bool { return ip == nil || ip.IsUnspecified() }
+	return a.Port == b.Port && (a.IP.Equal(b.IP) || unspecified(a.IP) && unspecified(b.IP))
+}
+
+// DropInherited closes the listeners handed over by an upgraded daemon that
+// this one's config no longer serves, once it has started what it does
+func (d *Daemon) DropInherited() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, inherited := range []*net.Listener{&d.inheritedAPI, &d.inheritedMetrics} {
+		if *inherited != nil {
+			(*inherited).Close()
+			*inherited = nil
+		}
+	}
+}
+
+// How often the exported metrics are brought up to date
+const metricsInterval = time.Second
+
+// ServeMetrics exports every tunnel's state and traffic for Prometheus on
+// addr until the daemon is closed or hands over
+func (d *Daemon) ServeMetrics(addr string) error {
+	listener, err := d.listenTCP(addr, &d.inheritedMetrics)
+	if err != nil {
+		return fmt.Errorf("metrics_listen: %w", err)
+	}
+	d.mu.Lock()
+	d.metrics = listener
+	d.mu.Unlock()
+	exporter := metrics.Serve(listener)
+	exporter.Update(d.Samples())
+	go func() {
+		ticker := time.NewTicker(metricsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				exporter.Update(d.Samples())
+			case <-d.done:
+				listener.Close()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Samples reports every tunnel as exported metrics
+func (d *Daemon) Samples() []metrics.Sample {
+	list := d.List()
+	samples := make([]metrics.Sample, 0, len(list))
+	for _, state := range list {
+		sample := metrics.Sample{Name: state.Name, Tag: state.Tag, State: state.Status}
+		if values, ok := d.manager.GetMetricValues(state.Name); ok {
+			sample.BytesIn = values.BytesIn
+			sample.BytesOut = values.BytesOut
+			sample.Connections = values.Connections
+			sample.ConnectionsTotal = values.ConnectionsTotal
+			sample.Latency = values.Latency
+			sample.Health = values.Health
+			sample.AuthFailures = values.Handshakes.Auth
+			sample.NetworkFailures = values.Handshakes.Network
+			sample.HostKeyFailures = values.Handshakes.HostKey
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
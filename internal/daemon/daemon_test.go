@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+	"tunnel9/internal/notify"
+)
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestDaemonStartStop(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432, Tag: "always-on", BindAddress: "127.0.0.1"},
+		{Name: "web", LocalPort: freePort(t), RemoteHost: "web.internal", RemotePort: 80, BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+
+	d.Autostart(map[string]config.TagSettings{"always-on": {Autostart: true}}, "")
+
+	states := d.List()
+	if len(states) != 2 || states[0].Status != "connecting" || states[1].Status != "stopped" {
+		t.Fatalf("expected only db to autostart, got %+v", states)
+	}
+	if states[0].Via != "db.internal:22" || states[0].Remote != "localhost:5432" {
+		t.Errorf("unexpected endpoints %+v", states[0])
+	}
+
+	if err := d.Stop("db"); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+	if status := d.List()[0].Status; status != "stopped" {
+		t.Errorf("expected db to be stopped, got %s", status)
+	}
+
+	if err := d.Start("cache"); err == nil {
+		t.Errorf("expected an error for an unknown tunnel")
+	}
+}
+
+func TestDaemonListsThePickedPort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", AutoPort: true, RemoteHost: "db.internal", RemotePort: 5432, BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+
+	if local := d.List()[0].Local; local != "127.0.0.1:auto" {
+		t.Errorf("expected the port as auto before it starts, got %q", local)
+	}
+	if err := d.Start("db"); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	local := d.List()[0].Local
+	if _, port, err := net.SplitHostPort(local); err != nil || port == "0" || port == config.AutoPort {
+		t.Fatalf("expected the port picked at start, got %q", local)
+	}
+	conn, err := net.Dial("tcp", local)
+	if err != nil {
+		t.Fatalf("expected %s to accept connections, got %v", local, err)
+	}
+	conn.Close()
+}
+
+func TestDaemonAutostartTunnel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432, BindAddress: "127.0.0.1", Autostart: true},
+		{Name: "web", LocalPort: freePort(t), RemoteHost: "web.internal", RemotePort: 80, BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+
+	d.Autostart(nil, "")
+	if states := d.List(); states[0].Status != "connecting" || states[1].Status != "stopped" {
+		t.Errorf("expected only db to autostart, got %+v", states)
+	}
+}
+
+func TestDaemonWebhook(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	received := make(chan notify.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer server.Close()
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432, Tag: "prod", BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+	d.SetNotifications(config.NotificationsConfig{Webhook: server.URL}, config.EmailConfig{}, "jump-vm")
+
+	if err := d.Start("db"); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	select {
+	case event := <-received:
+		if event.Tunnel != "db" || event.Tag != "prod" || event.From != "stopped" || event.To != "connecting" || event.Machine != "jump-vm" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the start to be posted")
+	}
+}
+
+func TestDaemonSamples(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432, Tag: "prod"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+
+	samples := d.Samples()
+	if len(samples) != 1 || samples[0].Name != "db" || samples[0].Tag != "prod" || samples[0].State != "stopped" {
+		t.Errorf("unexpected samples %+v", samples)
+	}
+}
+
+func TestDaemonKeepsTheLatestStatus(t *testing.T) {
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+
+	// Held up, the daemon is sent more changes than it queues
+	d.mu.Lock()
+	d.states["db"].Status = "connecting"
+	for i := 0; i < 150; i++ {
+		d.manager.Events.Publish(events.Event{Kind: events.KindStatus, TunnelID: "db", State: "error", Message: fmt.Sprintf("attempt %d failed", i)})
+	}
+	d.manager.Events.Publish(events.Event{Kind: events.KindStatus, TunnelID: "db", State: "active", Message: "connected"})
+	d.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		state := d.List()[0]
+		if state.Status == "active" && state.Message == "connected" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the last change to win, got %s (%s)", state.Status, state.Message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDaemonFollowsNotificationRoutes(t *testing.T) {
+	received := make(chan notify.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer server.Close()
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: freePort(t), RemoteHost: "db.internal", RemotePort: 5432, Tag: "prod"},
+		{Name: "nas", LocalPort: freePort(t), RemoteHost: "nas.internal", RemotePort: 445, Tag: "homelab"},
+		{Name: "web", LocalPort: freePort(t), RemoteHost: "web.internal", RemotePort: 80},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+	d.SetNotifications(config.NotificationsConfig{Routes: []config.NotificationRoute{
+		{Tags: []string{"homelab"}},
+		{Tags: []string{"prod"}, Sinks: []string{config.SinkWebhook}, Webhook: server.URL},
+	}}, config.EmailConfig{SMTPHost: "smtp.example.com", To: []string{"ops@example.com"}}, "jump-vm")
+
+	d.setState("db", "error", "connection refused")
+	d.setState("nas", "error", "connection refused")
+	d.setState("web", "error", "connection refused")
+
+	select {
+	case event := <-received:
+		if event.Tunnel != "db" || event.To != "error" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the prod route to post db's failure")
+	}
+
+	// Without a route the failure is emailed, as before routes existed
+	d.mu.Lock()
+	alerts := d.emailer.Take(time.Now().Add(time.Hour))
+	d.mu.Unlock()
+	if len(alerts) != 1 || alerts[0].Tunnel != "web" || !alerts[0].Failed() {
+		t.Errorf("expected only web's failure emailed, got %+v", alerts)
+	}
+}
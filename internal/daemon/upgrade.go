@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// HandoverEnv tells a daemon started by an upgrade which of its inherited
+// files are which
+const HandoverEnv = "TUNNEL9_HANDOVER"
+
+// How long the new daemon has to take over before the upgrade is given up
+const handoverTimeout = 15 * time.Second
+
+// handoverSpec is what HandoverEnv carries, indexes into the extra files the
+// new daemon was started with
+type handoverSpec struct {
+	Ready   int            `json:"ready"`             // Pipe to write "ready" to once taken over
+	Control int            `json:"control"`           // Control socket listener
+	API     int            `json:"api,omitempty"`     // API listener, 0 (the ready pipe) without one
+	Metrics int            `json:"metrics,omitempty"` // Metrics listener, likewise
+	Tunnels map[string]int `json:"tunnels"`           // Listening sockets by tunnel name
+}
+
+// Handover is what a daemon inherits from the one it upgrades
+type Handover struct {
+	Control net.Listener
+	API     net.Listener         // Nil unless the upgraded daemon served the API
+	Metrics net.Listener         // Nil unless it exported metrics
+	Tunnels map[string]io.Closer // A net.Listener, or net.PacketConn for UDP
+	ready   *os.File
+}
+
+// fileFor returns the file at an extra files index, which the child sees as
+// descriptor 3 onwards
+func fileFor(index int, name string) *os.File {
+	return os.NewFile(uintptr(3+index), name)
+}
+
+// InheritedHandover picks up the sockets passed by an upgrading daemon, if
+// this one was started by an upgrade
+func InheritedHandover() (*Handover, error) {
+	value := os.Getenv(HandoverEnv)
+	if value == "" {
+		return nil, nil
+	}
+	os.Unsetenv(HandoverEnv)
+
+	var spec handoverSpec
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", HandoverEnv, err)
+	}
+
+	h := &Handover{Tunnels: make(map[string]io.Closer, len(spec.Tunnels)), ready: fileFor(spec.Ready, "ready")}
+	controlFile := fileFor(spec.Control, "control")
+	listener, err := net.FileListener(controlFile)
+	controlFile.Close()
+	if err != nil {
+		h.ready.Close()
+		return nil, fmt.Errorf("failed to inherit the control socket: %w", err)
+	}
+	// The socket file is ours to remove now
+	if unix, ok := listener.(*net.UnixListener); ok {
+		unix.SetUnlinkOnClose(true)
+	}
+	h.Control = listener
+
+	h.API = inheritListener(spec.API, "api")
+	h.Metrics = inheritListener(spec.Metrics, "metrics")
+
+	for name, index := range spec.Tunnels {
+		f := fileFor(index, name)
+		if socket, err := net.FileListener(f); err == nil {
+			h.Tunnels[name] = socket
+		} else if socket, err := net.FilePacketConn(f); err == nil {
+			h.Tunnels[name] = socket
+		}
+		f.Close()
+	}
+	return h, nil
+}
+
+// inheritListener takes over the TCP listener at index, if one was handed
+// over. One that can't be is listened on afresh once the upgraded daemon lets
+// go.
+func inheritListener(index int, name string) net.Listener {
+	if index == 0 {
+		return nil
+	}
+	f := fileFor(index, name)
+	defer f.Close()
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil
+	}
+	return listener
+}
+
+// Resume starts the tunnels that were running in the upgraded daemon on the
+// sockets it handed over, then tells it to let go. The API and metrics
+// listeners wait for ServeAPI and ServeMetrics.
+func (d *Daemon) Resume(h *Handover) {
+	d.mu.Lock()
+	d.inheritedAPI = h.API
+	d.inheritedMetrics = h.Metrics
+	d.mu.Unlock()
+	for name, socket := range h.Tunnels {
+		if _, err := d.find(name); err != nil {
+			fmt.Fprintf(d.out, "Not resuming %s, it's no longer configured\n", name)
+			socket.Close()
+			continue
+		}
+		d.manager.Inherit(name, socket)
+		if err := d.Start(name); err != nil {
+			fmt.Fprintf(d.out, "Failed to resume %s: %v\n", name, err)
+		}
+	}
+	fmt.Fprintf(h.ready, "ready\n")
+	h.ready.Close()
+}
+
+// Upgrade hands the control socket, the API and metrics listeners and every
+// running tunnel's listening socket to a daemon started from the binary at
+// path. Once it has taken over, this one accepts nothing more and Serve
+// returns, while connections already forwarded carry on until they finish.
+func (d *Daemon) Upgrade(path string) error {
+	d.mu.Lock()
+	if d.upgrading || d.handedOver {
+		d.mu.Unlock()
+		return fmt.Errorf("an upgrade is already under way")
+	}
+	d.upgrading = true
+	listener := d.listener
+	api := d.api
+	exporter := d.metrics
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.upgrading = false
+		d.mu.Unlock()
+	}()
+
+	unix, ok := listener.(*net.UnixListener)
+	if !ok {
+		return fmt.Errorf("the control socket can't be handed over")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	files := []*os.File{readyWriter}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	controlFile, err := unix.File()
+	if err != nil {
+		return fmt.Errorf("failed to hand over the control socket: %w", err)
+	}
+	files = append(files, controlFile)
+
+	spec := handoverSpec{Ready: 0, Control: 1, Tunnels: make(map[string]int)}
+	if api != nil {
+		f, err := listenerFile(api)
+		if err != nil {
+			return fmt.Errorf("failed to hand over the API listener: %w", err)
+		}
+		spec.API = len(files)
+		files = append(files, f)
+	}
+	if exporter != nil {
+		f, err := listenerFile(exporter)
+		if err != nil {
+			return fmt.Errorf("failed to hand over the metrics listener: %w", err)
+		}
+		spec.Metrics = len(files)
+		files = append(files, f)
+	}
+	for _, state := range d.List() {
+		if state.Status != "connecting" && state.Status != "active" {
+			continue
+		}
+		f, err := d.manager.ListenerFile(state.Name)
+		if err != nil {
+			// Rebound by the new daemon, after a moment without it
+			fmt.Fprintf(d.out, "Can't hand over %s: %v\n", state.Name, err)
+			continue
+		}
+		spec.Tunnels[state.Name] = len(files)
+		files = append(files, f)
+	}
+	value, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Env = append(withoutHandover(os.Environ()), HandoverEnv+"="+string(value))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	fmt.Fprintf(d.out, "Upgrading to %s, handing over %d tunnel(s)\n", path, len(spec.Tunnels))
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", path, err)
+	}
+
+	// Our copy of the write end must go, so a child that dies reads as EOF
+	readyWriter.Close()
+	files = files[1:]
+	if err := waitReady(readyReader, handoverTimeout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("the new daemon didn't take over: %w", err)
+	}
+
+	// The new daemon accepts from here on, leave the socket file to it
+	d.mu.Lock()
+	d.handedOver = true
+	d.mu.Unlock()
+	unix.SetUnlinkOnClose(false)
+	unix.Close()
+	if api != nil {
+		api.Close()
+	}
+	if exporter != nil {
+		exporter.Close()
+	}
+	for name := range spec.Tunnels {
+		d.manager.Release(name)
+	}
+	fmt.Fprintf(d.out, "Handed over to process %d\n", cmd.Process.Pid)
+	cmd.Process.Release()
+	return nil
+}
+
+// listenerFile duplicates a TCP listener's socket, to be passed on
+func listenerFile(listener net.Listener) (*os.File, error) {
+	tcp, ok := listener.(*net.TCPListener)
+	if !ok {
+		return nil, fmt.Errorf("not a TCP listener")
+	}
+	return tcp.File()
+}
+
+// withoutHandover leaves out a HandoverEnv this daemon was itself started with
+func withoutHandover(env []string) []string {
+	kept := make([]string, 0, len(env))
+	for _, entry := range env {
+		if !strings.HasPrefix(entry, HandoverEnv+"=") {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// waitReady waits for the new daemon to say it has taken over
+func waitReady(r *os.File, timeout time.Duration) error {
+	r.SetReadDeadline(time.Now().Add(timeout))
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(line) != "ready" {
+		return fmt.Errorf("unexpected %q", line)
+	}
+	return nil
+}
+
+// HandedOver reports whether an upgrade took over from this daemon
+func (d *Daemon) HandedOver() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.handedOver
+}
+
+// How often a draining daemon checks for connections still open
+const drainInterval = time.Second
+
+// Drain waits for the connections forwarded before a handover to finish, up
+// to timeout
+func (d *Daemon) Drain(timeout time.Duration) {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	deadline := time.Now().Add(timeout)
+	for range ticker.C {
+		open := d.manager.ActiveConnections()
+		if open == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			fmt.Fprintf(d.out, "Closing %d connection(s) still open after %s\n", open, timeout)
+			return
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+)
+
+// Set for the test binary started as the new daemon by TestUpgrade, to the
+// addresses it serves the API and metrics on
+const (
+	upgradedAPIEnv     = "TUNNEL9_TEST_UPGRADED_API"
+	upgradedMetricsEnv = "TUNNEL9_TEST_UPGRADED_METRICS"
+)
+
+func TestMain(m *testing.M) {
+	if addr := os.Getenv(upgradedAPIEnv); addr != "" {
+		runUpgraded(addr, os.Getenv(upgradedMetricsEnv))
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runUpgraded stands in for the daemon an upgrade starts, serving what it
+// inherited for a few seconds
+func runUpgraded(addr string, metricsAddr string) {
+	h, err := InheritedHandover()
+	if err != nil || h == nil {
+		os.Exit(1)
+	}
+	d := New(nil, io.Discard)
+	d.Resume(h)
+	if err := d.ServeMetrics(metricsAddr); err != nil {
+		os.Exit(1)
+	}
+	if err := d.ServeAPI(addr, []control.Token{{Name: "test", Secret: "s3cret", Scope: config.ScopeRead}}); err != nil {
+		os.Exit(1)
+	}
+	d.DropInherited()
+	go d.Serve(h.Control)
+	time.Sleep(3 * time.Second)
+	os.Exit(0)
+}
+
+func TestInheritedHandoverWithoutUpgrade(t *testing.T) {
+	t.Setenv(HandoverEnv, "")
+	if h, err := InheritedHandover(); h != nil || err != nil {
+		t.Errorf("expected no handover, got %+v (%v)", h, err)
+	}
+}
+
+func TestWithoutHandover(t *testing.T) {
+	env := withoutHandover([]string{"HOME=/home/me", HandoverEnv + `={"ready":0}`, "TERM=xterm"})
+	if strings.Join(env, " ") != "HOME=/home/me TERM=xterm" {
+		t.Errorf("expected the previous handover left out, got %v", env)
+	}
+}
+
+func TestResume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	gone, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to make a pipe: %v", err)
+	}
+	defer readyReader.Close()
+
+	tunnels := []config.TunnelConfig{
+		{Name: "db", LocalPort: port, RemoteHost: "db.internal", RemotePort: 5432, BindAddress: "127.0.0.1"},
+	}
+	d := New(tunnels, io.Discard)
+	defer d.Close()
+	d.Resume(&Handover{
+		Tunnels: map[string]io.Closer{"db": listener, "removed": gone},
+		ready:   readyWriter,
+	})
+
+	line, err := bufio.NewReader(readyReader).ReadString('\n')
+	if err != nil || line != "ready\n" {
+		t.Errorf("expected the old daemon to be told, got %q (%v)", line, err)
+	}
+	if status := d.List()[0].Status; status != "connecting" {
+		t.Errorf("expected db to resume, got %s", status)
+	}
+
+	// The handed over socket keeps accepting, the one no longer configured
+	// is closed
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Errorf("expected db to accept on the inherited socket: %v", err)
+	} else {
+		conn.Close()
+	}
+	if _, err := gone.Accept(); err == nil {
+		t.Errorf("expected the socket of a removed tunnel to be closed")
+	}
+}
+
+func TestUpgradeHandsOverListeners(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket, err := control.Listen(filepath.Join(t.TempDir(), "control.sock"))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	d := New(nil, io.Discard)
+	defer d.Close()
+	go d.Serve(socket)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		d.mu.Lock()
+		serving := d.listener != nil
+		d.mu.Unlock()
+		if serving || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err := d.ServeAPI("127.0.0.1:0", []control.Token{{Name: "test", Secret: "s3cret", Scope: config.ScopeRead}}); err != nil {
+		t.Fatalf("failed to serve the API: %v", err)
+	}
+	if err := d.ServeMetrics("127.0.0.1:0"); err != nil {
+		t.Fatalf("failed to serve metrics: %v", err)
+	}
+	addr := d.api.Addr().String()
+	metricsAddr := d.metrics.Addr().String()
+	if err := listAPI(addr); err != nil {
+		t.Fatalf("expected the API to answer before the upgrade: %v", err)
+	}
+	if err := getMetrics(metricsAddr); err != nil {
+		t.Fatalf("expected metrics to be served before the upgrade: %v", err)
+	}
+
+	// Both daemons would want the same ports, only a handover gets them there
+	t.Setenv(upgradedAPIEnv, addr)
+	t.Setenv(upgradedMetricsEnv, metricsAddr)
+	if err := d.Upgrade(binary); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	if err := listAPI(addr); err != nil {
+		t.Errorf("expected the new daemon to answer the API: %v", err)
+	}
+	if err := getMetrics(metricsAddr); err != nil {
+		t.Errorf("expected the new daemon to serve metrics: %v", err)
+	}
+}
+
+// listAPI lists the tunnels through the API at addr
+func listAPI(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return err
+	}
+	client := control.NewClient(conn)
+	defer client.Close()
+	client.SetToken("s3cret")
+	_, err = client.List()
+	return err
+}
+
+// getMetrics scrapes the metrics served at addr, on a connection of its own
+func getMetrics(addr string) error {
+	client := http.Client{Timeout: time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr + "/metrics")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
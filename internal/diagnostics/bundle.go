@@ -0,0 +1,168 @@
+// Package diagnostics collects what a bug report needs into one archive,
+// with internal hostnames, users and secrets masked so it can be shared.
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/metrics"
+)
+
+// Bundle is what goes into a diagnostic archive
+type Bundle struct {
+	Version    string
+	ConfigPath string
+	Config     config.Config
+	Logs       []string         // Recent console or daemon log lines
+	Samples    []metrics.Sample // Each tunnel's state and counters when collected
+	Time       time.Time
+}
+
+// sample is a metrics.Sample as written to the archive
+type sample struct {
+	Name             string `json:"name"`
+	Tag              string `json:"tag,omitempty"`
+	State            string `json:"state"`
+	Health           string `json:"health,omitempty"`
+	BytesIn          int64  `json:"bytes_in"`
+	BytesOut         int64  `json:"bytes_out"`
+	Connections      int    `json:"connections"`
+	ConnectionsTotal int64  `json:"connections_total"`
+	LatencyMs        int64  `json:"latency_ms,omitempty"`
+	AuthFailures     int64  `json:"auth_failures,omitempty"`
+	NetworkFailures  int64  `json:"network_failures,omitempty"`
+	HostKeyFailures  int64  `json:"host_key_failures,omitempty"`
+}
+
+// Filename is what an archive made at t is called
+func Filename(t time.Time) string {
+	return fmt.Sprintf("tunnel9-diagnostics-%s.tar.gz", t.Format("20060102-150405"))
+}
+
+// Save writes the archive to path
+func (b Bundle) Save(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := b.Write(file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// Write writes the archive as a gzipped tarball of info.txt, config.yaml,
+// logs.txt and metrics.json, all redacted
+func (b Bundle) Write(w io.Writer) error {
+	redactor := NewRedactor()
+	configYAML, err := redactor.Config(b.Config)
+	if err != nil {
+		return fmt.Errorf("failed to redact config: %w", err)
+	}
+
+	logs := make([]string, len(b.Logs))
+	for i, line := range b.Logs {
+		logs[i] = redactor.Text(line)
+	}
+	samples := make([]sample, len(b.Samples))
+	for i, s := range b.Samples {
+		samples[i] = sample{
+			Name:             s.Name,
+			Tag:              s.Tag,
+			State:            s.State,
+			Health:           s.Health,
+			BytesIn:          s.BytesIn,
+			BytesOut:         s.BytesOut,
+			Connections:      s.Connections,
+			ConnectionsTotal: s.ConnectionsTotal,
+			LatencyMs:        s.Latency.Milliseconds(),
+			AuthFailures:     s.AuthFailures,
+			NetworkFailures:  s.NetworkFailures,
+			HostKeyFailures:  s.HostKeyFailures,
+		}
+	}
+	metricsJSON, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"info.txt", []byte(b.info())},
+		{"config.yaml", configYAML},
+		{"logs.txt", []byte(strings.Join(logs, "\n") + "\n")},
+		{"metrics.json", metricsJSON},
+	} {
+		header := &tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data)), ModTime: b.Time}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// info describes the version and system tunnel9 runs on
+func (b Bundle) info() string {
+	path := b.ConfigPath
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home) {
+		path = "~" + strings.TrimPrefix(path, home)
+	}
+	lines := []string{
+		"tunnel9 " + b.Version,
+		fmt.Sprintf("OS: %s/%s %s", runtime.GOOS, runtime.GOARCH, osRelease()),
+		"Go: " + runtime.Version(),
+		"Config: " + filepath.ToSlash(path),
+		fmt.Sprintf("Tunnels: %d", len(b.Config.Tunnels)),
+		"Collected: " + b.Time.Format(time.RFC3339),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// osRelease names the Linux distribution, where there is one to name
+func osRelease() string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, found := strings.CutPrefix(line, "PRETTY_NAME="); found {
+			return strings.Trim(name, `"`)
+		}
+	}
+	return ""
+}
+
+// TailLines returns the last n lines of a file, such as the daemon's log
+func TailLines(path string, n int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
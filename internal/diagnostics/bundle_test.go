@@ -0,0 +1,62 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/metrics"
+)
+
+func TestBundleWrite(t *testing.T) {
+	b := Bundle{
+		Version: "1.0.3",
+		Config:  config.Config{Tunnels: []config.TunnelConfig{{Name: "db", LocalPort: 5432, RemoteHost: "db.corp.internal", RemotePort: 5432}}},
+		Logs:    []string{"13:04:05 ERROR Failed to connect to db.corp.internal:5432"},
+		Samples: []metrics.Sample{{Name: "db", State: "error", NetworkFailures: 3}},
+		Time:    time.Date(2026, 10, 16, 13, 4, 5, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(archive)
+		files[header.Name] = string(data)
+	}
+
+	if !strings.HasPrefix(files["info.txt"], "tunnel9 1.0.3\n") {
+		t.Errorf("unexpected info.txt %q", files["info.txt"])
+	}
+	if !strings.Contains(files["config.yaml"], "remote_host: host-1") {
+		t.Errorf("expected the remote host masked, got %q", files["config.yaml"])
+	}
+	if files["logs.txt"] != "13:04:05 ERROR Failed to connect to host-1:5432\n" {
+		t.Errorf("expected the log masked like the config, got %q", files["logs.txt"])
+	}
+	if !strings.Contains(files["metrics.json"], `"network_failures": 3`) {
+		t.Errorf("unexpected metrics.json %q", files["metrics.json"])
+	}
+	if Filename(b.Time) != "tunnel9-diagnostics-20261016-130405.tar.gz" {
+		t.Errorf("unexpected file name %s", Filename(b.Time))
+	}
+}
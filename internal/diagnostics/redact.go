@@ -0,0 +1,163 @@
+package diagnostics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config keys whose values are masked, by what they hold
+var (
+	hostKeys   = []string{"remote_host", "host", "bastion_host", "smtp_host", "server", "broker", "machine", "domains"}
+	userKeys   = []string{"user", "username", "bastion_user", "from", "to", "client_id"}
+	secretKeys = []string{"password", "passphrases", "webhook", "url", "command", "args", "helper", "token", "totp"}
+)
+
+// Shown instead of secrets
+const redacted = "REDACTED"
+
+var (
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// Dotted names like db.corp.example.com, that aren't file names
+	hostnamePattern = regexp.MustCompile(`\b[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+\.[A-Za-z]{2,}\b`)
+	fileExtensions  = []string{"yaml", "yml", "json", "log", "sock", "gz", "txt", "pub"}
+)
+
+// Redactor swaps hostnames and users for stable stand-ins like host-1 and
+// user-1, so a bundle still shows which tunnels share a server without
+// naming it, and drops secrets altogether
+type Redactor struct {
+	masked map[string]string // Original to stand-in
+	counts map[string]int    // Stand-ins handed out, by kind
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{masked: make(map[string]string), counts: make(map[string]int)}
+}
+
+// mask returns the stand-in for a value, handing out the next one of its
+// kind the first time it is seen
+func (r *Redactor) mask(kind string, value string) string {
+	if value == "" || value == "localhost" || value == "127.0.0.1" || value == "0.0.0.0" {
+		return value
+	}
+	if stand, ok := r.masked[value]; ok {
+		return stand
+	}
+	r.counts[kind]++
+	stand := fmt.Sprintf("%s-%d", kind, r.counts[kind])
+	r.masked[value] = stand
+	return stand
+}
+
+// maskSpec masks a [user@]host[:port] string, keeping the port
+func (r *Redactor) maskSpec(spec string) string {
+	user, host, found := strings.Cut(spec, "@")
+	if !found {
+		user, host = "", spec
+	}
+	port := ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, port = host[:i], host[i:]
+	}
+	masked := r.mask("host", host) + port
+	if user != "" {
+		masked = r.mask("user", user) + "@" + masked
+	}
+	return masked
+}
+
+// Config returns the config as YAML with hostnames and users masked and
+// secrets removed. Hosts masked here are masked in Text too.
+func (r *Redactor) Config(cfg config.Config) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	r.walk(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// walk masks the values of the keys listed above, anywhere in the config
+func (r *Redactor) walk(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		for _, child := range node.Content {
+			r.walk(child)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case contains(secretKeys, key):
+			r.each(value, func(string) string { return redacted })
+		case contains(hostKeys, key):
+			r.each(value, func(s string) string { return r.mask("host", s) })
+		case contains(userKeys, key):
+			r.each(value, func(s string) string { return r.mask("user", s) })
+		case key == "bastion" && value.Kind == yaml.ScalarNode:
+			// Standby targets name their bastion as user@host:port
+			r.each(value, r.maskSpec)
+		default:
+			r.walk(value)
+		}
+	}
+}
+
+// each replaces every non-empty scalar under node
+func (r *Redactor) each(node *yaml.Node, replace func(string) string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value != "" {
+			node.Value = replace(node.Value)
+			node.Tag = "!!str"
+		}
+	case yaml.MappingNode:
+		// Keep the keys, e.g. which identity file has a passphrase
+		for i := 1; i < len(node.Content); i += 2 {
+			r.each(node.Content[i], replace)
+		}
+	default:
+		for _, child := range node.Content {
+			r.each(child, replace)
+		}
+	}
+}
+
+// Text masks the hosts and users seen in the config, then IP addresses and
+// dotted hostnames, in free text such as log lines
+func (r *Redactor) Text(s string) string {
+	originals := make([]string, 0, len(r.masked))
+	for original := range r.masked {
+		originals = append(originals, original)
+	}
+	// Longest first, so db.internal is replaced before a host named db
+	sort.Slice(originals, func(i, j int) bool { return len(originals[i]) > len(originals[j]) })
+	for _, original := range originals {
+		// Whole words only, a host named db leaves "debug" alone
+		word := regexp.MustCompile(`(^|[^\w.-])` + regexp.QuoteMeta(original) + `($|[^\w-])`)
+		s = word.ReplaceAllString(s, "${1}"+r.masked[original]+"${2}")
+	}
+
+	s = ipv4Pattern.ReplaceAllStringFunc(s, func(ip string) string { return r.mask("ip", ip) })
+	return hostnamePattern.ReplaceAllStringFunc(s, func(name string) string {
+		if contains(fileExtensions, strings.ToLower(name[strings.LastIndex(name, ".")+1:])) {
+			return name
+		}
+		return r.mask("host", name)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestRedactConfig(t *testing.T) {
+	cfg := config.Config{
+		Tunnels: []config.TunnelConfig{
+			{Name: "db", LocalPort: 5432, RemoteHost: "db.corp.internal", RemotePort: 5432},
+			{Name: "cache", LocalPort: 6379, RemoteHost: "localhost", RemotePort: 6379,
+				Standby: []config.StandbyTarget{{Bastion: "bob@jump2.corp.example.com:2222"}}},
+		},
+		Email: config.EmailConfig{SMTPHost: "smtp.corp.example.com", Password: "hunter2", To: []string{"ops@corp.example.com"}},
+	}
+
+	for i := range cfg.Tunnels {
+		cfg.Tunnels[i].Bastion.Host = "jump.corp.example.com"
+		cfg.Tunnels[i].Bastion.User = "alice"
+	}
+
+	r := NewRedactor()
+	data, err := r.Config(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, leaked := range []string{"corp", "alice", "bob", "hunter2"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q masked, got:\n%s", leaked, out)
+		}
+	}
+	for _, kept := range []string{"host-1", "host-2", "user-1", "@host-3:2222", "remote_host: localhost", "password: REDACTED", "local_port: 5432"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %q, got:\n%s", kept, out)
+		}
+	}
+	if strings.Count(out, "host: host-2") != 2 {
+		t.Errorf("expected the shared bastion masked the same way twice, got:\n%s", out)
+	}
+
+	line := r.Text("DEBUG [db] dialing jump.corp.example.com:22 as alice, resolved 10.0.4.17 via dns.example.net; debug on")
+	want := "DEBUG [db] dialing host-2:22 as user-1, resolved ip-1 via host-5; debug on"
+	if line != want {
+		t.Errorf("expected %q, got %q", want, line)
+	}
+	if got := r.Text("wrote ~/.local/state/tunnel9/config.yaml"); got != "wrote ~/.local/state/tunnel9/config.yaml" {
+		t.Errorf("expected file names left alone, got %q", got)
+	}
+}
+
+func TestRedactAPITokens(t *testing.T) {
+	cfg := config.Config{API: config.APIConfig{
+		Listen: "127.0.0.1:7709",
+		Tokens: []config.APIToken{
+			{Name: "grafana", Token: "s3cr3t-grafana", Scope: config.ScopeRead},
+			{Name: "ci", Token: "env:CI_TOKEN", Scope: config.ScopeControl},
+		},
+	}}
+
+	data, err := NewRedactor().Config(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, leaked := range []string{"s3cr3t-grafana", "CI_TOKEN"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q masked, got:\n%s", leaked, out)
+		}
+	}
+	if strings.Count(out, "token: REDACTED") != 2 {
+		t.Errorf("expected both tokens redacted, got:\n%s", out)
+	}
+	for _, kept := range []string{"name: grafana", "scope: control"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %q, got:\n%s", kept, out)
+		}
+	}
+}
+
+func TestRedactAuthSecrets(t *testing.T) {
+	cfg := config.Config{Tunnels: []config.TunnelConfig{{
+		Name: "db", LocalPort: 5432, RemoteHost: "localhost", RemotePort: 5432,
+		Auth: config.AuthSecrets{Password: "keyring:ssh", TOTP: "JBSWY3DPEHPK3PXP"},
+	}}}
+
+	data, err := NewRedactor().Config(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, leaked := range []string{"JBSWY3DPEHPK3PXP", "keyring:ssh"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q masked, got:\n%s", leaked, out)
+		}
+	}
+	for _, kept := range []string{"totp: REDACTED", "password: REDACTED"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %q, got:\n%s", kept, out)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package events carries what tunnels report to everything observing them,
+// so the TUI and any other front end see the same stream.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind tells events apart so subscribers only receive what they asked for
+type Kind int
+
+const (
+	KindLog    Kind = iota // A formatted log line
+	KindStatus             // A tunnel changed state
+)
+
+// Level is how much a log line matters, from chatter to failures
+type Level int
+
+const (
+	LevelDebug Level = iota // Chatter like health checks and connection steps
+	LevelInfo               // Tunnels starting, stopping and recovering
+	LevelWarn               // Trouble tunnel9 works around, like a dropped connection
+	LevelError              // Failures that need someone to look
+)
+
+// String names the level as it appears in log lines
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "DEBUG"
+}
+
+// Event is something a tunnel, or the manager, reported
+type Event struct {
+	Kind     Kind
+	TunnelID string // Empty for manager-wide log lines
+	State    string // Status events: "connecting", "active", "error", "stopped"
+	Level    Level  // Log events: how much the line matters
+	Message  string
+	Time     time.Time
+}
+
+// DropPolicy decides what a full subscription gives up
+type DropPolicy int
+
+const (
+	DropNewest DropPolicy = iota // Keep the backlog, discard the incoming event
+	DropOldest                   // Make room by discarding the oldest queued event
+)
+
+type subscription struct {
+	ch      chan Event
+	kinds   map[Kind]bool
+	policy  DropPolicy
+	dropped atomic.Int64
+}
+
+func (s *subscription) wants(kind Kind) bool {
+	return len(s.kinds) == 0 || s.kinds[kind]
+}
+
+// deliver never blocks, a slow subscriber must not stall the tunnels
+func (s *subscription) deliver(event Event) {
+	select {
+	case s.ch <- event:
+		return
+	default:
+	}
+
+	if s.policy == DropOldest {
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- event:
+			s.dropped.Add(1)
+			return
+		default:
+		}
+	}
+	s.dropped.Add(1)
+}
+
+// Bus fans events out to buffered subscriptions
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[int]*subscription
+	nextID int
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]*subscription),
+	}
+}
+
+// Subscription is a handle on a subscriber's channel
+type Subscription struct {
+	C      <-chan Event
+	bus    *Bus
+	id     int
+	sub    *subscription
+	closed sync.Once
+}
+
+// Dropped reports how many events this subscriber missed by falling behind
+func (s *Subscription) Dropped() int64 {
+	return s.sub.dropped.Load()
+}
+
+// Cancel unsubscribes and closes the channel
+func (s *Subscription) Cancel() {
+	s.closed.Do(func() {
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+		if _, ok := s.bus.subs[s.id]; ok {
+			delete(s.bus.subs, s.id)
+			close(s.sub.ch)
+		}
+	})
+}
+
+// Subscribe receives events of the given kinds, or all kinds if none are
+// given, buffered to size with policy deciding what is lost when full
+func (b *Bus) Subscribe(size int, policy DropPolicy, kinds ...Kind) *Subscription {
+	sub := &subscription{
+		ch:     make(chan Event, size),
+		kinds:  make(map[Kind]bool),
+		policy: policy,
+	}
+	for _, kind := range kinds {
+		sub.kinds[kind] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	if b.closed {
+		close(sub.ch)
+	} else {
+		b.subs[id] = sub
+	}
+	return &Subscription{C: sub.ch, bus: b, id: id, sub: sub}
+}
+
+// Publish hands an event to every interested subscriber
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if sub.wants(event.Kind) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// Close ends every subscription, later events are discarded
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for id, sub := range b.subs {
+		delete(b.subs, id)
+		close(sub.ch)
+	}
+}
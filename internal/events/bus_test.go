@@ -0,0 +1,63 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestBusFiltersByKind(t *testing.T) {
+	bus := NewBus()
+	logs := bus.Subscribe(10, DropNewest, KindLog)
+	all := bus.Subscribe(10, DropNewest)
+
+	bus.Publish(Event{Kind: KindLog, Message: "hello"})
+	bus.Publish(Event{Kind: KindStatus, TunnelID: "a", State: "active"})
+
+	if got := len(logs.C); got != 1 {
+		t.Errorf("expected 1 log event, got %d", got)
+	}
+	if got := len(all.C); got != 2 {
+		t.Errorf("expected 2 events, got %d", got)
+	}
+	if event := <-logs.C; event.Message != "hello" || event.Time.IsZero() {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestBusDropPolicies(t *testing.T) {
+	bus := NewBus()
+	newest := bus.Subscribe(2, DropNewest)
+	oldest := bus.Subscribe(2, DropOldest)
+
+	for _, msg := range []string{"1", "2", "3"} {
+		bus.Publish(Event{Kind: KindLog, Message: msg})
+	}
+
+	if (<-newest.C).Message != "1" || (<-newest.C).Message != "2" {
+		t.Error("expected DropNewest to keep the first events")
+	}
+	if (<-oldest.C).Message != "2" || (<-oldest.C).Message != "3" {
+		t.Error("expected DropOldest to keep the latest events")
+	}
+	if newest.Dropped() != 1 || oldest.Dropped() != 1 {
+		t.Errorf("expected one drop each, got %d and %d", newest.Dropped(), oldest.Dropped())
+	}
+}
+
+func TestBusCancelAndClose(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1, DropNewest)
+	sub.Cancel()
+	if _, ok := <-sub.C; ok {
+		t.Error("expected channel closed after cancel")
+	}
+
+	other := bus.Subscribe(1, DropNewest)
+	bus.Close()
+	other.Cancel() // Must not panic after Close
+	bus.Publish(Event{Kind: KindLog})
+
+	late := bus.Subscribe(1, DropNewest)
+	if _, ok := <-late.C; ok {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+}
@@ -0,0 +1,35 @@
+// Package fsutil holds file helpers shared by the packages that keep state
+// on disk.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces path with data through a uniquely named temporary file
+// in the same directory, synced before it is renamed over path with the
+// given permissions. Instances saving at once each rename a whole file of
+// their own, so the last one wins and no reader ever sees a torn one.
+func WriteFile(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ports.json")
+
+	// Writers racing each other leave one of their files whole
+	var wg sync.WaitGroup
+	for _, data := range []string{`["a"]`, `["bb"]`, `["ccc"]`, `["dddd"]`} {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			if err := WriteFile(path, []byte(data), 0644); err != nil {
+				t.Errorf("failed to write: %v", err)
+			}
+		}(data)
+	}
+	wg.Wait()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch string(got) {
+	case `["a"]`, `["bb"]`, `["ccc"]`, `["dddd"]`:
+	default:
+		t.Errorf("expected one whole write, got %q", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %v", entries)
+	}
+}
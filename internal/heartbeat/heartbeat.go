@@ -0,0 +1,116 @@
+package heartbeat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+// How often a heartbeat is sent when the config doesn't say
+const DefaultInterval = time.Minute
+
+// Tunnel is one tunnel's line in the heartbeat summary
+type Tunnel struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LocalPort int    `json:"local_port"`
+}
+
+// Payload is POSTed as JSON on every heartbeat
+type Payload struct {
+	Machine string    `json:"machine"`
+	Time    time.Time `json:"time"`
+	Total   int       `json:"total"`
+	Active  int       `json:"active"`
+	Errors  int       `json:"errors"`
+	Tunnels []Tunnel  `json:"tunnels"`
+}
+
+// NewPayload summarises the tunnels for a heartbeat
+func NewPayload(machine string, tunnels []Tunnel) Payload {
+	p := Payload{
+		Machine: machine,
+		Time:    time.Now(),
+		Total:   len(tunnels),
+		Tunnels: tunnels,
+	}
+	for _, t := range tunnels {
+		switch t.Status {
+		case "active":
+			p.Active++
+		case "error":
+			p.Errors++
+		}
+	}
+	return p
+}
+
+// Sender posts heartbeats to a monitoring URL, so that something outside
+// this machine notices when they stop coming
+type Sender struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	last     time.Time
+}
+
+// New returns the sender described by cfg, or nil if no URL is configured
+func New(cfg config.HeartbeatConfig) *Sender {
+	if cfg.URL == "" {
+		return nil
+	}
+	interval := DefaultInterval
+	if parsed, err := time.ParseDuration(cfg.Interval); err == nil && parsed > 0 {
+		interval = parsed
+	}
+	return &Sender{
+		url:      cfg.URL,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// URL is where heartbeats are sent
+func (s *Sender) URL() string {
+	return s.url
+}
+
+// Next is when the next heartbeat is due, zero before the first
+func (s *Sender) Next() time.Time {
+	if s.last.IsZero() {
+		return time.Time{}
+	}
+	return s.last.Add(s.interval)
+}
+
+// Due reports whether the next heartbeat should go out, marking it sent if so
+func (s *Sender) Due(now time.Time) bool {
+	if !s.last.IsZero() && now.Sub(s.last) < s.interval {
+		return false
+	}
+	s.last = now
+	return true
+}
+
+// Send posts the payload, failing on anything but a 2xx response
+func (s *Sender) Send(p Payload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("error marshaling heartbeat: %w", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
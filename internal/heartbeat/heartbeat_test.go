@@ -0,0 +1,63 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestSender(t *testing.T) {
+	if New(config.HeartbeatConfig{}) != nil {
+		t.Error("expected no sender without a URL")
+	}
+
+	var got Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s with %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode heartbeat: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	sender := New(config.HeartbeatConfig{URL: server.URL, Interval: "30s"})
+	payload := NewPayload("laptop", []Tunnel{
+		{Name: "db", Status: "active", LocalPort: 5432},
+		{Name: "web", Status: "error", LocalPort: 8080},
+		{Name: "cache", Status: "stopped", LocalPort: 6379},
+	})
+	if err := sender.Send(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Machine != "laptop" || got.Total != 3 || got.Active != 1 || got.Errors != 1 || len(got.Tunnels) != 3 {
+		t.Errorf("unexpected heartbeat %+v", got)
+	}
+
+	now := time.Now()
+	if !sender.Due(now) {
+		t.Error("expected the first heartbeat to be due right away")
+	}
+	if sender.Due(now.Add(10 * time.Second)) {
+		t.Error("expected no heartbeat before the interval")
+	}
+	if !sender.Due(now.Add(30 * time.Second)) {
+		t.Error("expected a heartbeat after the interval")
+	}
+}
+
+func TestSenderFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := New(config.HeartbeatConfig{URL: server.URL}).Send(NewPayload("laptop", nil)); err == nil {
+		t.Error("expected an error for a 503")
+	}
+}
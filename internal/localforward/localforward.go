@@ -0,0 +1,238 @@
+// Package localforward finds the LocalForward directives of an ssh_config
+// file, so forwards already written for plain ssh can become tunnels without
+// typing them in again.
+package localforward
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+)
+
+// How deep Include directives are followed, as in ssh
+const maxIncludeDepth = 5
+
+// Forward is a LocalForward found in an ssh_config file
+type Forward struct {
+	Config config.TunnelConfig
+	Source string // file:line it was found on
+}
+
+// DefaultFile is the user's ssh_config, ~/.ssh/config
+func DefaultFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// Read finds the forwards of an ssh_config file and the files it includes.
+// Each tunnel goes through the Host alias it was found under, so HostName,
+// User, Port, IdentityFile and ProxyJump keep coming from the ssh_config.
+// Forwards that can't be a tunnel, like those under wildcard hosts or to
+// Unix sockets, are returned as skipped with the reason.
+func Read(path string) ([]Forward, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	p := &parser{dir: filepath.Dir(path)}
+	if err := p.parse(f, path, 0); err != nil {
+		return nil, nil, err
+	}
+	return p.forwards, p.skipped, nil
+}
+
+// Parse finds the forwards of an ssh_config read from r, named source in
+// what it returns. Include directives are resolved relative to dir.
+func Parse(r io.Reader, source string, dir string) ([]Forward, []string, error) {
+	p := &parser{dir: dir}
+	if err := p.parse(r, source, 0); err != nil {
+		return nil, nil, err
+	}
+	return p.forwards, p.skipped, nil
+}
+
+type parser struct {
+	dir      string
+	forwards []Forward
+	skipped  []string
+}
+
+func (p *parser) parse(r io.Reader, source string, depth int) error {
+	// The forwards before any Host apply to every host, like Host *
+	host := "*"
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		keyword, value := splitDirective(scanner.Text())
+		where := fmt.Sprintf("%s:%d", source, line)
+		switch strings.ToLower(keyword) {
+		case "host":
+			host = alias(strings.Fields(value))
+		case "match":
+			host = ""
+		case "include":
+			p.include(strings.Fields(value), where, depth)
+		case "localforward":
+			switch {
+			case host == "":
+				p.skipped = append(p.skipped, fmt.Sprintf("%s: under a Match block", where))
+				continue
+			case host == "*":
+				p.skipped = append(p.skipped, fmt.Sprintf("%s: under a wildcard Host", where))
+				continue
+			}
+			tc, err := ParseDirective(value)
+			if err != nil {
+				p.skipped = append(p.skipped, fmt.Sprintf("%s: %v", where, err))
+				continue
+			}
+			tc.Name = fmt.Sprintf("%s-%d", host, tc.LocalPort)
+			tc.Bastion.Host = host
+			p.forwards = append(p.forwards, Forward{Config: tc, Source: where})
+		}
+	}
+	return scanner.Err()
+}
+
+// include reads the files an Include directive names, which ssh takes
+// relative to ~/.ssh when they aren't absolute
+func (p *parser) include(patterns []string, where string, depth int) {
+	if depth >= maxIncludeDepth {
+		p.skipped = append(p.skipped, fmt.Sprintf("%s: Include nested too deeply", where))
+		return
+	}
+	home, _ := os.UserHomeDir()
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "~/") {
+			pattern = filepath.Join(home, pattern[2:])
+		} else if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(p.dir, pattern)
+		}
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			f, err := os.Open(path)
+			if err != nil {
+				p.skipped = append(p.skipped, fmt.Sprintf("%s: %v", where, err))
+				continue
+			}
+			err = p.parse(f, path, depth+1)
+			f.Close()
+			if err != nil {
+				p.skipped = append(p.skipped, fmt.Sprintf("%s: %v", path, err))
+			}
+		}
+	}
+}
+
+// splitDirective splits an ssh_config line into its keyword and value,
+// which may be separated by whitespace or an equals sign
+func splitDirective(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return line, ""
+	}
+	value := strings.TrimSpace(line[end:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return line[:end], strings.Trim(value, `"`)
+}
+
+// alias picks the name a Host line can be connected to by, the first
+// pattern without wildcards, or "*" when all of them have one
+func alias(patterns []string) string {
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?!") {
+			return pattern
+		}
+	}
+	return "*"
+}
+
+// ParseDirective reads the value of a LocalForward directive,
+// [bind_address:]port host:hostport
+func ParseDirective(value string) (config.TunnelConfig, error) {
+	var tc config.TunnelConfig
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return tc, fmt.Errorf("unsupported LocalForward %q", value)
+	}
+	listen, target := fields[0], fields[1]
+	if strings.Contains(listen, "/") || strings.HasPrefix(target, "/") {
+		return tc, fmt.Errorf("forward %q uses a Unix socket", value)
+	}
+
+	port := listen
+	if strings.Contains(listen, ":") {
+		bind, p, err := net.SplitHostPort(listen)
+		if err != nil {
+			return tc, fmt.Errorf("invalid listen address %q", listen)
+		}
+		if bind == "*" {
+			bind = "0.0.0.0"
+		}
+		tc.BindAddress, port = bind, p
+	}
+	var err error
+	if tc.LocalPort, err = strconv.Atoi(port); err != nil {
+		return tc, fmt.Errorf("invalid local port %q", port)
+	}
+
+	// ssh also takes host/port, for IPv6 addresses without brackets
+	host, remotePort, err := net.SplitHostPort(target)
+	if err != nil {
+		var found bool
+		if host, remotePort, found = strings.Cut(target, "/"); !found {
+			return tc, fmt.Errorf("invalid target %q", target)
+		}
+	}
+	if tc.RemotePort, err = strconv.Atoi(remotePort); err != nil {
+		return tc, fmt.Errorf("invalid remote port %q", remotePort)
+	}
+	if host == "" {
+		return tc, fmt.Errorf("no remote host in %q", target)
+	}
+	tc.RemoteHost = host
+	return tc, nil
+}
+
+// Discover reads the forwards of an ssh_config file, leaving out those
+// already configured, going by where they reach through which host
+func Discover(path string, existing []config.TunnelConfig) ([]Forward, []string, error) {
+	forwards, skipped, err := Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]string, len(existing))
+	for _, tc := range existing {
+		known[targetKey(tc)] = tc.Name
+	}
+
+	fresh := make([]Forward, 0, len(forwards))
+	for _, f := range forwards {
+		if name, ok := known[targetKey(f.Config)]; ok {
+			skipped = append(skipped, fmt.Sprintf("%s: already configured as %s", f.Source, name))
+			continue
+		}
+		known[targetKey(f.Config)] = f.Config.Name
+		fresh = append(fresh, f)
+	}
+	return fresh, skipped, nil
+}
+
+func targetKey(tc config.TunnelConfig) string {
+	return fmt.Sprintf("%s:%d via %s", tc.RemoteHost, tc.RemotePort, tc.Bastion.Host)
+}
@@ -0,0 +1,98 @@
+package localforward
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		value string
+		bind  string
+		local int
+		host  string
+		port  int
+	}{
+		{"5432 localhost:5432", "", 5432, "localhost", 5432},
+		{"127.0.0.1:8080 web.internal:80", "127.0.0.1", 8080, "web.internal", 80},
+		{"*:9000 10.0.0.5:9000", "0.0.0.0", 9000, "10.0.0.5", 9000},
+		{"[::1]:3000 [2001:db8::5]:3000", "::1", 3000, "2001:db8::5", 3000},
+		{"3001 2001:db8::5/3001", "", 3001, "2001:db8::5", 3001},
+	}
+	for _, test := range tests {
+		tc, err := ParseDirective(test.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.value, err)
+			continue
+		}
+		if tc.BindAddress != test.bind || tc.LocalPort != test.local || tc.RemoteHost != test.host || tc.RemotePort != test.port {
+			t.Errorf("%q: unexpected tunnel %+v", test.value, tc)
+		}
+	}
+
+	for _, value := range []string{"5432", "/tmp/local.sock db:5432", "5432 /var/run/db.sock", "http db:80", "5432 db"} {
+		if _, err := ParseDirective(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "work"), []byte("Host bastion\n  LocalForward 6379 cache:6379\n"), 0600)
+
+	forwards, skipped, err := Parse(strings.NewReader(`# Forwards
+Host *
+  LocalForward 1111 everywhere:1
+Host db db.example.com
+  HostName 10.0.0.2
+  LocalForward=5432 localhost:5432
+  localforward 15432 replica:5432
+Host *.internal
+  LocalForward 2222 wild:2
+Match host foo
+  LocalForward 3333 matched:3
+Host web
+  LocalForward 8080 /run/web.sock
+Include work
+`), "config", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := make([]string, 0, len(forwards))
+	for _, f := range forwards {
+		names = append(names, f.Config.Name+" via "+f.Config.Bastion.Host)
+	}
+	if strings.Join(names, ", ") != "db-5432 via db, db-15432 via db, bastion-6379 via bastion" {
+		t.Errorf("unexpected forwards %v", names)
+	}
+	if forwards[0].Source != "config:6" {
+		t.Errorf("expected the line recorded, got %s", forwards[0].Source)
+	}
+	if len(skipped) != 4 {
+		t.Errorf("expected wildcards, Match and the socket skipped, got %v", skipped)
+	}
+}
+
+func TestDiscoverLeavesOutConfigured(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("Host db\n  LocalForward 5432 localhost:5432\n  LocalForward 5433 localhost:5433\n"), 0600)
+
+	existing := config.TunnelConfig{Name: "pg", LocalPort: 15432, RemoteHost: "localhost", RemotePort: 5432}
+	existing.Bastion.Host = "db"
+	forwards, skipped, err := Discover(path, []config.TunnelConfig{existing})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(forwards) != 1 || forwards[0].Config.RemotePort != 5433 {
+		t.Errorf("expected only 5433 left, got %+v", forwards)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0], "already configured as pg") {
+		t.Errorf("unexpected skipped %v", skipped)
+	}
+}
@@ -0,0 +1,57 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "tunnel9.log")
+	f, err := Open(path, 40, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Each dated line is about 30 bytes, so every file holds one
+	for _, line := range []string{"10:00:00 first line", "10:00:01 2nd line", "10:00:02 third line", "10:00:03 4th line"} {
+		if err := f.Println(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(name string) string {
+		data, _ := os.ReadFile(name)
+		return string(data)
+	}
+	if got := read(path); !strings.HasSuffix(got, " 10:00:03 4th line\n") || strings.Count(got, "\n") != 1 {
+		t.Errorf("expected the last line in the log, got %q", got)
+	}
+	if got := read(path + ".1"); !strings.Contains(got, "third line") {
+		t.Errorf("expected the third line in .1, got %q", got)
+	}
+	if got := read(path + ".2"); !strings.Contains(got, "2nd line") {
+		t.Errorf("expected the second line in .2, got %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only two rotated files, got %v", err)
+	}
+}
+
+func TestFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tunnel9.log")
+	for _, line := range []string{"10:00:00 before a restart", "10:05:00 after it"} {
+		f, err := Open(path, 1<<20, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Println(line)
+		f.Close()
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "\n") != 2 {
+		t.Errorf("expected both lines kept, got %q", data)
+	}
+}
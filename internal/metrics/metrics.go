@@ -0,0 +1,154 @@
+// Package metrics serves tunnel state and traffic in the Prometheus text
+// format, for dashboards of what goes through a machine's tunnels.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// States a tunnel is reported in, one series each
+var states = []string{"stopped", "connecting", "active", "error"}
+
+// Sample is one tunnel as exported
+type Sample struct {
+	Name             string
+	Tag              string
+	State            string
+	Health           string // Empty without a health check
+	BytesIn          int64
+	BytesOut         int64
+	Connections      int
+	ConnectionsTotal int64
+	Latency          time.Duration // Negative or zero when unknown
+
+	// Failed SSH logins since the tunnel started, by cause
+	AuthFailures    int64
+	NetworkFailures int64
+	HostKeyFailures int64
+}
+
+// Exporter serves the samples it was last given
+type Exporter struct {
+	mu      sync.Mutex
+	samples []Sample
+}
+
+// Listen serves metrics on addr, e.g. "127.0.0.1:9109", at /metrics until
+// the process exits
+func Listen(addr string) (*Exporter, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics_listen: %w", err)
+	}
+	return Serve(listener), nil
+}
+
+// Serve serves metrics at /metrics on a listener until it is closed
+func Serve(listener net.Listener) *Exporter {
+	e := &Exporter{}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	go http.Serve(listener, mux)
+	return e
+}
+
+// Update replaces what is served
+func (e *Exporter) Update(samples []Sample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples = samples
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	samples := e.samples
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Write(w, samples)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(s Sample, extra ...string) string {
+	pairs := []string{
+		fmt.Sprintf(`tunnel="%s"`, labelEscaper.Replace(s.Name)),
+		fmt.Sprintf(`tag="%s"`, labelEscaper.Replace(s.Tag)),
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], labelEscaper.Replace(extra[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Write renders samples in the Prometheus text format
+func Write(w io.Writer, samples []Sample) {
+	metric := func(name string, kind string, help string, value func(Sample) (string, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, s := range samples {
+			if v, ok := value(s); ok {
+				fmt.Fprintf(w, "%s%s %s\n", name, labels(s), v)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP tunnel9_tunnel_state Current state of the tunnel, 1 for the state it is in\n# TYPE tunnel9_tunnel_state gauge\n")
+	for _, s := range samples {
+		for _, state := range states {
+			value := 0
+			if s.State == state {
+				value = 1
+			}
+			fmt.Fprintf(w, "tunnel9_tunnel_state%s %d\n", labels(s, "state", state), value)
+		}
+	}
+
+	metric("tunnel9_tunnel_up", "gauge", "Whether the tunnel is active", func(s Sample) (string, bool) {
+		if s.State == "active" {
+			return "1", true
+		}
+		return "0", true
+	})
+	metric("tunnel9_tunnel_received_bytes_total", "counter", "Bytes received from the remote target since the tunnel started", func(s Sample) (string, bool) {
+		return fmt.Sprint(s.BytesIn), true
+	})
+	metric("tunnel9_tunnel_sent_bytes_total", "counter", "Bytes sent to the remote target since the tunnel started", func(s Sample) (string, bool) {
+		return fmt.Sprint(s.BytesOut), true
+	})
+	metric("tunnel9_tunnel_connections", "gauge", "Local connections being forwarded", func(s Sample) (string, bool) {
+		return fmt.Sprint(s.Connections), true
+	})
+	metric("tunnel9_tunnel_connections_total", "counter", "Local connections forwarded since the tunnel started", func(s Sample) (string, bool) {
+		return fmt.Sprint(s.ConnectionsTotal), true
+	})
+	fmt.Fprintf(w, "# HELP tunnel9_tunnel_handshake_failures_total Failed SSH logins since the tunnel started, by cause\n# TYPE tunnel9_tunnel_handshake_failures_total counter\n")
+	for _, s := range samples {
+		for _, failures := range []struct {
+			kind  string
+			count int64
+		}{{"auth", s.AuthFailures}, {"network", s.NetworkFailures}, {"host_key", s.HostKeyFailures}} {
+			fmt.Fprintf(w, "tunnel9_tunnel_handshake_failures_total%s %d\n", labels(s, "kind", failures.kind), failures.count)
+		}
+	}
+	metric("tunnel9_tunnel_latency_seconds", "gauge", "Round trip to the SSH server", func(s Sample) (string, bool) {
+		if s.State != "active" || s.Latency <= 0 {
+			return "", false
+		}
+		return fmt.Sprint(s.Latency.Seconds()), true
+	})
+	metric("tunnel9_tunnel_healthy", "gauge", "Whether the tunnel's health check passes, for tunnels with one", func(s Sample) (string, bool) {
+		switch {
+		case s.State != "active" || s.Health == "":
+			return "", false
+		case s.Health == "healthy":
+			return "1", true
+		}
+		return "0", true
+	})
+}
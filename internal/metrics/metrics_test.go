@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	var out strings.Builder
+	Write(&out, []Sample{
+		{Name: "prod-db", Tag: "prod", State: "active", BytesIn: 2048, BytesOut: 512, Connections: 2, ConnectionsTotal: 7, Latency: 42 * time.Millisecond, Health: "degraded", AuthFailures: 3},
+		{Name: `odd "name"`, State: "stopped"},
+	})
+	text := out.String()
+
+	for _, want := range []string{
+		`tunnel9_tunnel_state{tunnel="prod-db",tag="prod",state="active"} 1`,
+		`tunnel9_tunnel_state{tunnel="prod-db",tag="prod",state="error"} 0`,
+		`tunnel9_tunnel_received_bytes_total{tunnel="prod-db",tag="prod"} 2048`,
+		`tunnel9_tunnel_connections_total{tunnel="prod-db",tag="prod"} 7`,
+		`tunnel9_tunnel_latency_seconds{tunnel="prod-db",tag="prod"} 0.042`,
+		`tunnel9_tunnel_healthy{tunnel="prod-db",tag="prod"} 0`,
+		`tunnel9_tunnel_handshake_failures_total{tunnel="prod-db",tag="prod",kind="auth"} 3`,
+		`tunnel9_tunnel_handshake_failures_total{tunnel="prod-db",tag="prod",kind="host_key"} 0`,
+		`tunnel9_tunnel_up{tunnel="odd \"name\"",tag=""} 0`,
+		"# TYPE tunnel9_tunnel_sent_bytes_total counter",
+	} {
+		if !strings.Contains(text, want+"\n") {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	// No latency for stopped tunnels
+	if strings.Contains(text, `tunnel9_tunnel_latency_seconds{tunnel="odd`) {
+		t.Errorf("expected no latency for a stopped tunnel")
+	}
+}
+
+func TestListen(t *testing.T) {
+	e, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	e.Update([]Sample{{Name: "web", State: "active"}})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `tunnel9_tunnel_up{tunnel="web",tag=""} 1`) {
+		t.Errorf("unexpected response:\n%s", rec.Body.String())
+	}
+	if _, err := Listen("not an address"); err == nil {
+		t.Error("expected an invalid address to fail")
+	}
+}
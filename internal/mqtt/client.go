@@ -0,0 +1,288 @@
+// Package mqtt is a small MQTT 3.1.1 client, just enough to publish tunnel
+// state to a broker at QoS 0.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Control packet types, shifted into the high nibble of the fixed header
+const (
+	packetConnect    = 1 << 4
+	packetConnack    = 2 << 4
+	packetPublish    = 3 << 4
+	packetPingreq    = 12 << 4
+	packetDisconnect = 14 << 4
+)
+
+// Connect flags
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// Will is published by the broker if the client goes away without saying so
+type Will struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options describe how to log in to the broker
+type Options struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	Will      *Will
+}
+
+// Client is a connection to a broker
+type Client struct {
+	conn net.Conn
+	mu   sync.Mutex // Serialises writes
+	done chan struct{}
+	err  error
+}
+
+// brokerAddress turns tcp://host:1883, ssl://host:8883 or plain host:port
+// into an address and whether to use TLS
+func brokerAddress(broker string) (string, bool, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		// Plain host or host:port
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			return net.JoinHostPort(broker, "1883"), false, nil
+		}
+		return broker, false, nil
+	}
+
+	secure := false
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// Dial connects and logs in to the broker
+func Dial(broker string, opts Options) (*Client, error) {
+	address, secure, err := brokerAddress(broker)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, done: make(chan struct{})}
+	if err := c.login(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) login(opts Options) error {
+	var flags byte = flagCleanSession
+	payload := appendString(nil, opts.ClientID)
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= flagPassword
+			payload = appendString(payload, opts.Password)
+		}
+	}
+
+	keepAlive := int(opts.KeepAlive / time.Second)
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(keepAlive>>8), byte(keepAlive))
+	body = append(body, payload...)
+
+	if err := c.write(packetConnect, body); err != nil {
+		return err
+	}
+
+	// The broker must answer with a CONNACK before anything else
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+	kind, ack, err := readPacket(bufio.NewReader(c.conn))
+	if err != nil {
+		return fmt.Errorf("no reply from broker: %w", err)
+	}
+	if kind != packetConnack || len(ack) < 2 {
+		return fmt.Errorf("unexpected reply from broker")
+	}
+	if ack[1] != 0 {
+		return fmt.Errorf("broker refused connection: %s", connackReason(ack[1]))
+	}
+	return nil
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+// readLoop drains what the broker sends, which at QoS 0 is only ping
+// responses, and notices when the connection goes away
+func (c *Client) readLoop() {
+	r := bufio.NewReader(c.conn)
+	for {
+		if _, _, err := readPacket(r); err != nil {
+			c.err = err
+			close(c.done)
+			return
+		}
+	}
+}
+
+// Done is closed once the connection is lost
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err is why the connection was lost, once Done is closed
+func (c *Client) Err() error {
+	return c.err
+}
+
+// Publish sends a message at QoS 0
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = packetPublish
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(header, body)
+}
+
+// Ping keeps an idle connection alive
+func (c *Client) Ping() error {
+	return c.write(packetPingreq, nil)
+}
+
+// Close says goodbye, so the broker doesn't publish the will
+func (c *Client) Close() error {
+	c.write(packetDisconnect, nil)
+	return c.conn.Close()
+}
+
+func (c *Client) write(header byte, body []byte) error {
+	if len(body) > maxRemainingLength {
+		return errors.New("packet too large")
+	}
+	packet := append([]byte{header}, encodeLength(len(body))...)
+	packet = append(packet, body...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+// The remaining length is at most four 7-bit groups
+const maxRemainingLength = 268435455
+
+func encodeLength(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := 0
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = append(b, byte(len(data)>>8), byte(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,146 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+// fakeBroker accepts one client, acknowledges its CONNECT and hands every
+// packet it sends after that to the test
+func fakeBroker(t *testing.T) (string, <-chan []byte, <-chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	connects := make(chan []byte, 1)
+	packets := make(chan []byte, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		kind, body, err := readPacket(r)
+		if err != nil || kind != packetConnect {
+			return
+		}
+		connects <- body
+		conn.Write([]byte{packetConnack, 2, 0, 0})
+
+		for {
+			kind, body, err := readPacket(r)
+			if err != nil {
+				close(packets)
+				return
+			}
+			packets <- append([]byte{kind}, body...)
+		}
+	}()
+	return listener.Addr().String(), connects, packets
+}
+
+func TestEncodeLength(t *testing.T) {
+	for n, want := range map[int][]byte{
+		0:         {0x00},
+		127:       {0x7f},
+		128:       {0x80, 0x01},
+		16383:     {0xff, 0x7f},
+		268435455: {0xff, 0xff, 0xff, 0x7f},
+	} {
+		got := encodeLength(n)
+		if !bytes.Equal(got, want) {
+			t.Errorf("encodeLength(%d) = %x, want %x", n, got, want)
+		}
+		r := bufio.NewReader(bytes.NewReader(append(append([]byte{packetPublish}, got...), make([]byte, n)...)))
+		if _, body, err := readPacket(r); err != nil || len(body) != n {
+			t.Errorf("readPacket of length %d: got %d (%v)", n, len(body), err)
+		}
+	}
+}
+
+func TestBrokerAddress(t *testing.T) {
+	for broker, want := range map[string]string{
+		"broker":                 "broker:1883",
+		"broker:1884":            "broker:1884",
+		"tcp://broker":           "broker:1883",
+		"ssl://broker":           "broker:8883",
+		"mqtts://broker:9999":    "broker:9999",
+		"tcp://192.168.1.2:1883": "192.168.1.2:1883",
+	} {
+		got, _, err := brokerAddress(broker)
+		if err != nil || got != want {
+			t.Errorf("brokerAddress(%q) = %q (%v), want %q", broker, got, err, want)
+		}
+	}
+	if _, secure, _ := brokerAddress("ssl://broker"); !secure {
+		t.Error("expected ssl:// to use TLS")
+	}
+	if _, _, err := brokerAddress("ws://broker"); err == nil {
+		t.Error("expected an error for websockets")
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	address, connects, packets := fakeBroker(t)
+
+	logs := make(chan events.Level, 4)
+	p := NewPublisher(address, Options{ClientID: "tunnel9-test", Username: "alice", Password: "secret",
+		Will: &Will{Topic: "t9/status", Payload: []byte("offline"), Retain: true}},
+		&Will{Topic: "t9/status", Payload: []byte("online"), Retain: true},
+		func(level events.Level, format string, args ...interface{}) { logs <- level })
+
+	select {
+	case body := <-connects:
+		for _, want := range []string{"MQTT", "tunnel9-test", "t9/status", "offline", "alice", "secret"} {
+			if !bytes.Contains(body, []byte(want)) {
+				t.Errorf("expected CONNECT to carry %q", want)
+			}
+		}
+		if flags := body[7]; flags != flagCleanSession|flagWill|flagWillRetain|flagUsername|flagPassword {
+			t.Errorf("unexpected connect flags %08b", flags)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("publisher never connected")
+	}
+
+	p.Publish("t9/db/state", []byte(`{"status":"active"}`), true)
+	p.Close()
+
+	want := []struct {
+		header  byte
+		payload string
+	}{
+		{packetPublish | 0x01, "online"},
+		{packetPublish | 0x01, `{"status":"active"}`},
+		{packetDisconnect, ""},
+	}
+	for _, w := range want {
+		select {
+		case packet := <-packets:
+			if packet[0]&0xf0 != w.header&0xf0 || !bytes.HasSuffix(packet, []byte(w.payload)) {
+				t.Errorf("expected %x with %q, got %x", w.header, w.payload, packet)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing packet with %q", w.payload)
+		}
+	}
+	if len(logs) != 0 {
+		t.Errorf("expected no connection problems, got %d", len(logs))
+	}
+}
+
+func TestTopicName(t *testing.T) {
+	if got := TopicName("prod/db #1+"); got != "prod_db__1_" {
+		t.Errorf("unexpected topic name %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package mqtt
+
+import (
+	"strings"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+// How long to wait before trying a broker again
+var reconnectDelay = 5 * time.Second
+
+// LogFunc receives connection problems, at error level, and recoveries
+type LogFunc func(level events.Level, format string, args ...interface{})
+
+type message struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// Publisher queues messages and sends them from its own goroutine, holding
+// a connection to the broker and re-establishing it when it drops, so
+// callers never wait on the network
+type Publisher struct {
+	broker string
+	opts   Options
+	queue  chan message
+	stop   chan struct{}
+	done   chan struct{}
+	logf   LogFunc
+	online *message // Sent first on every connection, undoing the will
+}
+
+// NewPublisher starts publishing to broker, sending online first whenever it
+// connects. logf hears about problems once per outage rather than per retry.
+func NewPublisher(broker string, opts Options, online *Will, logf LogFunc) *Publisher {
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	p := &Publisher{
+		broker: broker,
+		opts:   opts,
+		queue:  make(chan message, 256),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		logf:   logf,
+	}
+	if online != nil {
+		p.online = &message{topic: online.Topic, payload: online.Payload, retain: online.Retain}
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues a message, dropping it if the broker has fallen far behind
+func (p *Publisher) Publish(topic string, payload []byte, retain bool) {
+	select {
+	case p.queue <- message{topic: topic, payload: payload, retain: retain}:
+	default:
+	}
+}
+
+// Close sends what is queued if connected, then disconnects cleanly. It
+// gives up waiting on a broker that is still being dialed.
+func (p *Publisher) Close() {
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-time.After(2 * time.Second):
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	var client *Client
+	failing := false
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+
+	for {
+		if client == nil {
+			c, err := Dial(p.broker, p.opts)
+			if err != nil {
+				if !failing {
+					p.logf(events.LevelError, "MQTT broker %s unavailable: %v", p.broker, err)
+				}
+				failing = true
+				select {
+				case <-p.stop:
+					return
+				case <-time.After(reconnectDelay):
+				}
+				continue
+			}
+			if failing {
+				p.logf(events.LevelInfo, "MQTT broker %s connected again", p.broker)
+			}
+			failing = false
+			client = c
+			if p.online != nil {
+				client.Publish(p.online.topic, p.online.payload, p.online.retain)
+			}
+		}
+
+		var err error
+		select {
+		case <-p.stop:
+			p.drain(client)
+			return
+		case msg := <-p.queue:
+			err = client.Publish(msg.topic, msg.payload, msg.retain)
+		case <-time.After(p.opts.KeepAlive / 2):
+			err = client.Ping()
+		case <-client.Done():
+			err = client.Err()
+		}
+		if err != nil {
+			if !failing {
+				p.logf(events.LevelError, "MQTT connection to %s lost: %v", p.broker, err)
+			}
+			failing = true
+			client.conn.Close()
+			client = nil
+		}
+	}
+}
+
+// drain sends whatever is still queued before disconnecting
+func (p *Publisher) drain(client *Client) {
+	for {
+		select {
+		case msg := <-p.queue:
+			if client.Publish(msg.topic, msg.payload, msg.retain) != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// TopicName makes a tunnel or machine name safe to use as one topic level
+func TopicName(name string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_").Replace(name)
+}
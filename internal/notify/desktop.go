@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// Swapped out in tests
+var runNotifier = func(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
+// Desktop shows a notification on this machine's desktop, through
+// osascript on macOS and notify-send elsewhere
+func Desktop(title string, body string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title))
+		return runNotifier("osascript", "-e", script)
+	case "windows":
+		return fmt.Errorf("desktop notifications aren't supported on Windows")
+	}
+	if _, err := exec.LookPath("notify-send"); err != nil {
+		return fmt.Errorf("notify-send not found")
+	}
+	return runNotifier("notify-send", "--app-name=tunnel9", title, body)
+}
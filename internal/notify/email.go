@@ -0,0 +1,191 @@
+// Package notify sends alerts about tunnel state changes to people who
+// aren't watching the TUI.
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+// Defaults for what the email config leaves out
+const (
+	DefaultSMTPPort = 587
+	DefaultCooldown = 5 * time.Minute
+)
+
+// How long alerts are collected before the first email goes out, so tunnels
+// failing together arrive together
+var batchWindow = 30 * time.Second
+
+// Swapped out in tests
+var sendMail = smtp.SendMail
+
+// Alert is a tunnel failing, recovering or otherwise changing state
+type Alert struct {
+	Time      time.Time
+	Tunnel    string
+	From      string // The state left, empty when not known
+	State     string
+	Message   string
+	Recovered bool
+}
+
+// NewAlert is the alert for a state change, a failure when the tunnel went
+// into error and a recovery when it came back from one
+func NewAlert(event Event) Alert {
+	return Alert{
+		Time:      event.Time,
+		Tunnel:    event.Tunnel,
+		From:      event.From,
+		State:     event.To,
+		Message:   event.Message,
+		Recovered: event.From == "error" && event.To == "active",
+	}
+}
+
+// Failed reports whether the alert is about a tunnel going into error
+func (a Alert) Failed() bool {
+	return a.State == "error"
+}
+
+// Emailer batches alerts into emails, sending at most one per cooldown
+type Emailer struct {
+	cfg      config.EmailConfig
+	machine  string
+	cooldown time.Duration
+	pending  []Alert
+	lastSent time.Time
+}
+
+// NewEmailer returns the emailer described by cfg, or nil if no SMTP host
+// and recipients are configured
+func NewEmailer(cfg config.EmailConfig, machine string) *Emailer {
+	if cfg.SMTPHost == "" || len(cfg.To) == 0 {
+		return nil
+	}
+	if cfg.SMTPPort == 0 {
+		cfg.SMTPPort = DefaultSMTPPort
+	}
+	if cfg.From == "" {
+		cfg.From = "tunnel9@" + machine
+	}
+	cooldown := DefaultCooldown
+	if parsed, err := time.ParseDuration(cfg.Cooldown); err == nil && parsed >= 0 {
+		cooldown = parsed
+	}
+	return &Emailer{
+		cfg:      cfg,
+		machine:  machine,
+		cooldown: cooldown,
+	}
+}
+
+// Server is the SMTP server emails go through
+func (e *Emailer) Server() string {
+	return net.JoinHostPort(e.cfg.SMTPHost, strconv.Itoa(e.cfg.SMTPPort))
+}
+
+// Add queues an alert for the next email
+func (e *Emailer) Add(alert Alert) {
+	e.pending = append(e.pending, alert)
+}
+
+// Refresh updates the message of a queued alert, as a failure is often
+// explained after it is first reported
+func (e *Emailer) Refresh(tunnel string, state string, message string) {
+	for i := len(e.pending) - 1; i >= 0; i-- {
+		if e.pending[i].Tunnel == tunnel {
+			if e.pending[i].State == state && message != "" {
+				e.pending[i].Message = message
+			}
+			return
+		}
+	}
+}
+
+// Pending reports whether alerts are queued for an email
+func (e *Emailer) Pending() bool {
+	return len(e.pending) > 0
+}
+
+// Take returns the alerts due to be emailed, if any, once the batch window
+// and cooldown have passed
+func (e *Emailer) Take(now time.Time) []Alert {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	if now.Sub(e.pending[0].Time) < batchWindow || now.Sub(e.lastSent) < e.cooldown {
+		return nil
+	}
+	alerts := e.pending
+	e.pending = nil
+	e.lastSent = now
+	return alerts
+}
+
+// Send emails a batch of alerts
+func (e *Emailer) Send(alerts []Alert) error {
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		password, err := config.ResolveSecret(e.cfg.Password)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", e.cfg.Username, password, e.cfg.SMTPHost)
+	}
+	return sendMail(e.Server(), auth, e.cfg.From, e.cfg.To, e.message(alerts))
+}
+
+func (e *Emailer) subject(alerts []Alert) string {
+	failed, recovered, changed := 0, 0, 0
+	for _, alert := range alerts {
+		switch {
+		case alert.Failed():
+			failed++
+		case alert.Recovered:
+			recovered++
+		default:
+			changed++
+		}
+	}
+
+	var parts []string
+	if failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d tunnel(s) failed", failed))
+	}
+	if recovered > 0 {
+		parts = append(parts, fmt.Sprintf("%d recovered", recovered))
+	}
+	if changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed state", changed))
+	}
+	return fmt.Sprintf("tunnel9 on %s: %s", e.machine, strings.Join(parts, ", "))
+}
+
+func (e *Emailer) message(alerts []Alert) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", e.subject(alerts))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, alert := range alerts {
+		line := fmt.Sprintf("%s  %s is %s", alert.Time.Format("Jan 2 15:04:05"), alert.Tunnel, alert.State)
+		if alert.Recovered {
+			line += " again"
+		} else if !alert.Failed() && alert.From != "" {
+			line += fmt.Sprintf(" (was %s)", alert.From)
+		}
+		if alert.Message != "" {
+			line += ": " + alert.Message
+		}
+		fmt.Fprintf(&b, "%s\r\n", line)
+	}
+	return []byte(b.String())
+}
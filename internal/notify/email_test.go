@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestEmailerBatching(t *testing.T) {
+	if NewEmailer(config.EmailConfig{SMTPHost: "smtp.example.com"}, "jump-vm") != nil {
+		t.Error("expected no emailer without recipients")
+	}
+
+	e := NewEmailer(config.EmailConfig{SMTPHost: "smtp.example.com", To: []string{"ops@example.com"}, Cooldown: "10m"}, "jump-vm")
+	if e.Server() != "smtp.example.com:587" {
+		t.Errorf("expected the submission port by default, got %s", e.Server())
+	}
+
+	start := time.Now()
+	e.Add(Alert{Time: start, Tunnel: "db", State: "error", Message: "failed, see logs"})
+	e.Refresh("db", "error", "SSH connection failed: timeout")
+	e.Add(Alert{Time: start.Add(5 * time.Second), Tunnel: "web", State: "error"})
+
+	if e.Take(start.Add(10*time.Second)) != nil {
+		t.Error("expected alerts to wait for the batch window")
+	}
+	alerts := e.Take(start.Add(batchWindow))
+	if len(alerts) != 2 || alerts[0].Message != "SSH connection failed: timeout" {
+		t.Fatalf("expected both failures in one batch with the refreshed message, got %+v", alerts)
+	}
+
+	e.Add(Alert{Time: start.Add(time.Minute), Tunnel: "db", State: "active", Recovered: true})
+	if e.Take(start.Add(5*time.Minute)) != nil {
+		t.Error("expected the cooldown to hold back the next email")
+	}
+	if alerts := e.Take(start.Add(batchWindow + 10*time.Minute)); len(alerts) != 1 {
+		t.Errorf("expected the recovery after the cooldown, got %+v", alerts)
+	}
+}
+
+func TestEmailerSend(t *testing.T) {
+	defer func(original func(string, smtp.Auth, string, []string, []byte) error) { sendMail = original }(sendMail)
+
+	var gotAddr, gotFrom string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+	sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotFrom, gotMsg = addr, auth, from, msg
+		return nil
+	}
+
+	e := NewEmailer(config.EmailConfig{SMTPHost: "smtp.example.com", SMTPPort: 25, Username: "alerts", Password: "secret", To: []string{"ops@example.com"}}, "jump-vm")
+	err := e.Send([]Alert{
+		{Time: time.Now(), Tunnel: "db", State: "error", Message: "remote connection failed"},
+		{Time: time.Now(), Tunnel: "web", State: "active", Recovered: true},
+		{Time: time.Now(), Tunnel: "cache", From: "stopped", State: "connecting"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAddr != "smtp.example.com:25" || gotFrom != "tunnel9@jump-vm" || gotAuth == nil {
+		t.Errorf("unexpected delivery to %s from %s (auth %v)", gotAddr, gotFrom, gotAuth)
+	}
+	for _, want := range []string{
+		"Subject: tunnel9 on jump-vm: 1 tunnel(s) failed, 1 recovered, 1 changed state",
+		"db is error: remote connection failed",
+		"web is active again",
+		"cache is connecting (was stopped)",
+	} {
+		if !strings.Contains(string(gotMsg), want) {
+			t.Errorf("expected %q in:\n%s", want, gotMsg)
+		}
+	}
+}
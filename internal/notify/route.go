@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"fmt"
+
+	"tunnel9/internal/config"
+)
+
+// Send is one place a state change goes
+type Send struct {
+	Sink string // config.SinkDesktop, SinkEmail or SinkWebhook
+	URL  string // Where a webhook sink POSTs
+}
+
+// Route lists where a state change goes: the webhook every change is POSTed
+// to, then the sinks of the first notification route matching it. Without a
+// match failures and recoveries are emailed, as before routes existed.
+func Route(n config.NotificationsConfig, event Event) []Send {
+	var sends []Send
+	if n.Webhook != "" {
+		sends = append(sends, Send{Sink: config.SinkWebhook, URL: n.Webhook})
+	}
+
+	route, ok := n.Route(event.Tag, event.From, event.To)
+	if !ok {
+		if alert := NewAlert(event); alert.Failed() || alert.Recovered {
+			sends = append(sends, Send{Sink: config.SinkEmail})
+		}
+		return sends
+	}
+	for _, sink := range route.Sinks {
+		send := Send{Sink: sink}
+		if sink == config.SinkWebhook {
+			send.URL = route.Webhook
+		}
+		sends = append(sends, send)
+	}
+	return sends
+}
+
+// Deliver sends an event to a desktop or webhook sink. Email goes through
+// an Emailer instead, batched with other alerts.
+func (s Send) Deliver(event Event) error {
+	switch s.Sink {
+	case config.SinkDesktop:
+		return Desktop("tunnel9", event.Summary())
+	case config.SinkWebhook:
+		return PostWebhook(s.URL, event)
+	}
+	return fmt.Errorf("%s notifications can't be delivered one at a time", s.Sink)
+}
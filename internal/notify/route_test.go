@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestRoute(t *testing.T) {
+	n := config.NotificationsConfig{
+		Webhook: "https://hooks.example.com/all",
+		Routes: []config.NotificationRoute{
+			{Tags: []string{"homelab"}},
+			{Tags: []string{"prod"}, Sinks: []string{config.SinkEmail, config.SinkWebhook}, Webhook: "https://hooks.example.com/prod"},
+		},
+	}
+
+	cases := []struct {
+		tag, from, to string
+		sinks         []Send
+	}{
+		{"homelab", "active", "error", []Send{{config.SinkWebhook, n.Webhook}}},
+		{"prod", "stopped", "connecting", []Send{{config.SinkWebhook, n.Webhook}, {Sink: config.SinkEmail}, {config.SinkWebhook, "https://hooks.example.com/prod"}}},
+		{"", "active", "error", []Send{{config.SinkWebhook, n.Webhook}, {Sink: config.SinkEmail}}},
+		{"", "error", "active", []Send{{config.SinkWebhook, n.Webhook}, {Sink: config.SinkEmail}}},
+		{"", "stopped", "connecting", []Send{{config.SinkWebhook, n.Webhook}}},
+	}
+	for _, c := range cases {
+		sends := Route(n, Event{Tunnel: "db", Tag: c.tag, From: c.from, To: c.to})
+		if len(sends) != len(c.sinks) {
+			t.Errorf("%s %s->%s: expected %+v, got %+v", c.tag, c.from, c.to, c.sinks, sends)
+			continue
+		}
+		for i := range sends {
+			if sends[i] != c.sinks[i] {
+				t.Errorf("%s %s->%s: expected %+v, got %+v", c.tag, c.from, c.to, c.sinks, sends)
+				break
+			}
+		}
+	}
+}
+
+func TestNewAlert(t *testing.T) {
+	cases := []struct {
+		from, to          string
+		failed, recovered bool
+	}{
+		{"active", "error", true, false},
+		{"error", "active", false, true},
+		{"stopped", "connecting", false, false},
+		{"active", "stopped", false, false},
+	}
+	for _, c := range cases {
+		alert := NewAlert(Event{Tunnel: "db", From: c.from, To: c.to})
+		if alert.Failed() != c.failed || alert.Recovered != c.recovered {
+			t.Errorf("%s->%s: expected failed %v and recovered %v, got %+v", c.from, c.to, c.failed, c.recovered, alert)
+		}
+	}
+}
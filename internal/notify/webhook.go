@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Event is a tunnel changing state, as sent to webhooks
+type Event struct {
+	Machine  string    `json:"machine"`
+	Tunnel   string    `json:"tunnel"`
+	Tag      string    `json:"tag,omitempty"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Summary describes the event in a line, for desktop notifications
+func (e Event) Summary() string {
+	return fmt.Sprintf("%s is %s (was %s)", e.Tunnel, e.To, e.From)
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// PostWebhook sends an event to url as JSON
+func PostWebhook(url string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostWebhook(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	event := Event{Machine: "jump-vm", Tunnel: "db", Tag: "prod", From: "active", To: "error", Severity: "error", Time: time.Now()}
+	if err := PostWebhook(server.URL, event); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tunnel != "db" || got.To != "error" || got.Machine != "jump-vm" {
+		t.Errorf("unexpected event %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := PostWebhook(failing.URL, event); err == nil {
+		t.Error("expected an error for a 502")
+	}
+}
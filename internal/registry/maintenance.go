@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/fsutil"
+)
+
+// MaintenanceHosts are the hosts under planned work, by when it began
+type MaintenanceHosts map[string]time.Time
+
+// Affecting returns the host in maintenance a tunnel goes to or through, if
+// any. The bastion is checked first.
+func (h MaintenanceHosts) Affecting(tc config.TunnelConfig) (string, bool) {
+	for _, host := range []string{tc.Bastion.Host, tc.RemoteHost} {
+		if _, ok := h[host]; ok && host != "" {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// Maintenance remembers which bastions and remote hosts are in maintenance,
+// across sessions, until it is cleared
+type Maintenance struct {
+	path string
+}
+
+// DefaultMaintenancePath is where hosts in maintenance are kept, next to the
+// default config file
+func DefaultMaintenancePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "maintenance.json"), nil
+}
+
+func NewMaintenance(path string) *Maintenance {
+	return &Maintenance{path: path}
+}
+
+// Load returns the hosts in maintenance, none if the file doesn't exist yet
+func (m *Maintenance) Load() (MaintenanceHosts, error) {
+	hosts := make(MaintenanceHosts)
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hosts, nil
+		}
+		return hosts, fmt.Errorf("error reading maintenance: %w", err)
+	}
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return hosts, fmt.Errorf("error parsing maintenance: %w", err)
+	}
+	return hosts, nil
+}
+
+// Set puts a host in maintenance from now on, or clears it, returning the
+// hosts in maintenance after
+func (m *Maintenance) Set(host string, on bool) (MaintenanceHosts, error) {
+	hosts, err := m.Load()
+	if err != nil {
+		return hosts, err
+	}
+	if on {
+		hosts[host] = time.Now()
+	} else {
+		delete(hosts, host)
+	}
+
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return hosts, fmt.Errorf("error marshaling maintenance: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return hosts, fmt.Errorf("error creating maintenance directory: %w", err)
+	}
+	if err := fsutil.WriteFile(m.path, data, 0644); err != nil {
+		return hosts, fmt.Errorf("error writing maintenance: %w", err)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestMaintenance(t *testing.T) {
+	maintenance := NewMaintenance(filepath.Join(t.TempDir(), "state", "maintenance.json"))
+	if hosts, err := maintenance.Load(); err != nil || len(hosts) != 0 {
+		t.Fatalf("expected no hosts in maintenance yet, got %v (%v)", hosts, err)
+	}
+
+	if _, err := maintenance.Set("bastion.example.com", true); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	hosts, err := maintenance.Set("db.internal", true)
+	if err != nil || len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts in maintenance, got %v (%v)", hosts, err)
+	}
+
+	db := config.TunnelConfig{RemoteHost: "db.internal"}
+	db.Bastion.Host = "bastion.example.com"
+	if host, ok := hosts.Affecting(db); !ok || host != "bastion.example.com" {
+		t.Errorf("expected the bastion to be blamed first, got %q", host)
+	}
+
+	if _, err := maintenance.Set("bastion.example.com", false); err != nil {
+		t.Fatalf("failed to clear: %v", err)
+	}
+	hosts, err = maintenance.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if host, ok := hosts.Affecting(db); !ok || host != "db.internal" {
+		t.Errorf("expected the remote host still in maintenance, got %q", host)
+	}
+	if _, ok := hosts.Affecting(config.TunnelConfig{RemoteHost: "web.internal"}); ok {
+		t.Error("expected other hosts unaffected")
+	}
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/fsutil"
+)
+
+// PortUse is a local port claimed by a tunnel in one config file
+type PortUse struct {
+	Config string `json:"config"` // Absolute path of the config file
+	Name   string `json:"name"`
+	Port   int    `json:"port"`
+}
+
+// PortRegistry remembers the local ports of every config file tunnel9 has
+// opened on this machine, so a tunnel in one profile doesn't quietly take a
+// port another profile already uses
+type PortRegistry struct {
+	path string
+}
+
+// DefaultPortsPath is where the port registry lives, next to the default
+// config file
+func DefaultPortsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "ports.json"), nil
+}
+
+func NewPortRegistry(path string) *PortRegistry {
+	return &PortRegistry{path: path}
+}
+
+func (r *PortRegistry) load() ([]PortUse, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading port registry: %w", err)
+	}
+	var uses []PortUse
+	if err := json.Unmarshal(data, &uses); err != nil {
+		return nil, fmt.Errorf("error parsing port registry: %w", err)
+	}
+	return uses, nil
+}
+
+// Record replaces the ports remembered for a config file with its current
+// tunnels, and forgets config files that no longer exist
+func (r *PortRegistry) Record(configPath string, tunnels []config.TunnelConfig) error {
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return err
+	}
+	uses, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]PortUse, 0, len(uses)+len(tunnels))
+	for _, use := range uses {
+		if use.Config == configPath {
+			continue
+		}
+		if _, err := os.Stat(use.Config); err != nil {
+			continue
+		}
+		kept = append(kept, use)
+	}
+	for _, tc := range tunnels {
+		if tc.LocalPort == 0 {
+			continue
+		}
+		kept = append(kept, PortUse{Config: configPath, Name: tc.Name, Port: tc.LocalPort})
+	}
+
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling port registry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("error creating port registry directory: %w", err)
+	}
+	if err := fsutil.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing port registry: %w", err)
+	}
+	return nil
+}
+
+// Collisions returns the tunnels of other config files using port
+func (r *PortRegistry) Collisions(configPath string, port int) ([]PortUse, error) {
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	uses, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	collisions := make([]PortUse, 0)
+	for _, use := range uses {
+		if use.Port == port && use.Config != configPath {
+			collisions = append(collisions, use)
+		}
+	}
+	return collisions, nil
+}
@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestPortRegistry(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work.yaml")
+	home := filepath.Join(dir, "home.yaml")
+	for _, path := range []string{work, home} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg := NewPortRegistry(filepath.Join(dir, "state", "ports.json"))
+	if err := reg.Record(work, []config.TunnelConfig{{Name: "prod-db", LocalPort: 5432}}); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+	if err := reg.Record(home, []config.TunnelConfig{{Name: "nas", LocalPort: 8080}}); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+
+	collisions, err := reg.Collisions(home, 5432)
+	if err != nil || len(collisions) != 1 || collisions[0].Name != "prod-db" || collisions[0].Config != work {
+		t.Errorf("expected prod-db from the work config, got %+v (%v)", collisions, err)
+	}
+	// A config never collides with itself
+	if collisions, _ := reg.Collisions(work, 5432); len(collisions) != 0 {
+		t.Errorf("expected no collisions within one config, got %+v", collisions)
+	}
+
+	// Recording again replaces the config's ports
+	reg.Record(work, []config.TunnelConfig{{Name: "prod-db", LocalPort: 15432}})
+	if collisions, _ := reg.Collisions(home, 5432); len(collisions) != 0 {
+		t.Errorf("expected the old port to be forgotten, got %+v", collisions)
+	}
+
+	// Config files that are gone are forgotten too
+	os.Remove(work)
+	reg.Record(home, []config.TunnelConfig{{Name: "nas", LocalPort: 8080}})
+	if collisions, _ := reg.Collisions(home, 15432); len(collisions) != 0 {
+		t.Errorf("expected the removed config to be forgotten, got %+v", collisions)
+	}
+}
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/fsutil"
+)
+
+// How many values of each kind are remembered
+const recentLimit = 20
+
+// RecentValues are hosts and users typed into tunnels lately, most recent
+// first
+type RecentValues struct {
+	RemoteHosts  []string `json:"remote_hosts,omitempty"`
+	BastionHosts []string `json:"bastion_hosts,omitempty"`
+	BastionUsers []string `json:"bastion_users,omitempty"`
+}
+
+// Recent remembers the hosts and users of tunnels created or edited in any
+// session, so the dialog can suggest them again
+type Recent struct {
+	path string
+}
+
+// DefaultRecentPath is where recent values live, next to the default config
+// file
+func DefaultRecentPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "recent.json"), nil
+}
+
+func NewRecent(path string) *Recent {
+	return &Recent{path: path}
+}
+
+// Load returns what was remembered, nothing if the file doesn't exist yet
+func (r *Recent) Load() (RecentValues, error) {
+	var values RecentValues
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return values, fmt.Errorf("error reading recent values: %w", err)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return values, fmt.Errorf("error parsing recent values: %w", err)
+	}
+	return values, nil
+}
+
+// Record moves a tunnel's hosts and user to the front of what is remembered
+func (r *Recent) Record(tc config.TunnelConfig) error {
+	values, err := r.Load()
+	if err != nil {
+		return err
+	}
+	values.RemoteHosts = remember(values.RemoteHosts, tc.RemoteHost)
+	values.BastionHosts = remember(values.BastionHosts, tc.Bastion.Host)
+	values.BastionUsers = remember(values.BastionUsers, tc.Bastion.User)
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling recent values: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("error creating recent values directory: %w", err)
+	}
+	if err := fsutil.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing recent values: %w", err)
+	}
+	return nil
+}
+
+// remember puts value first in list, dropping an older copy and whatever
+// falls past the limit
+func remember(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	updated := []string{value}
+	for _, existing := range list {
+		if existing != value && len(updated) < recentLimit {
+			updated = append(updated, existing)
+		}
+	}
+	return updated
+}
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestRecent(t *testing.T) {
+	recent := NewRecent(filepath.Join(t.TempDir(), "state", "recent.json"))
+	if values, err := recent.Load(); err != nil || len(values.RemoteHosts) != 0 {
+		t.Fatalf("expected nothing remembered yet, got %+v (%v)", values, err)
+	}
+
+	db := config.TunnelConfig{RemoteHost: "db.internal"}
+	db.Bastion.Host = "bastion.example.com"
+	db.Bastion.User = "deploy"
+	web := config.TunnelConfig{RemoteHost: "web.internal"}
+	for _, tc := range []config.TunnelConfig{db, web, db} {
+		if err := recent.Record(tc); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+	}
+
+	values, err := recent.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !reflect.DeepEqual(values.RemoteHosts, []string{"db.internal", "web.internal"}) {
+		t.Errorf("expected the most recent host first without duplicates, got %v", values.RemoteHosts)
+	}
+	if !reflect.DeepEqual(values.BastionHosts, []string{"bastion.example.com"}) || !reflect.DeepEqual(values.BastionUsers, []string{"deploy"}) {
+		t.Errorf("unexpected bastion values %+v", values)
+	}
+}
+
+func TestRememberLimit(t *testing.T) {
+	var list []string
+	for i := 0; i < recentLimit+5; i++ {
+		list = remember(list, fmt.Sprintf("host-%d", i))
+	}
+	if len(list) != recentLimit || list[0] != fmt.Sprintf("host-%d", recentLimit+4) {
+		t.Errorf("expected the %d most recent, got %v", recentLimit, list)
+	}
+}
@@ -0,0 +1,202 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/fsutil"
+)
+
+// Forward describes an active tunnel announced by one machine
+type Forward struct {
+	Machine     string    `json:"machine"`
+	User        string    `json:"user"`
+	Name        string    `json:"name"`
+	LocalPort   int       `json:"local_port"`
+	BindAddress string    `json:"bind_address"`
+	RemoteHost  string    `json:"remote_host"`
+	RemotePort  int       `json:"remote_port"`
+	Bastion     string    `json:"bastion,omitempty"` // SSH server the remote host is reached from
+	Since       time.Time `json:"since"`
+	Seen        time.Time `json:"seen,omitempty"` // Last announced, the owner re-announces while it runs
+}
+
+// Forwards not announced again for this long are left behind by a machine
+// that crashed or went away, and are dropped
+const StaleAfter = 2 * time.Minute
+
+// Target is the remote endpoint a forward exposes
+func (f Forward) Target() string {
+	return fmt.Sprintf("%s:%d", f.RemoteHost, f.RemotePort)
+}
+
+// Stale reports whether the forward's owner stopped announcing it
+func (f Forward) Stale(now time.Time) bool {
+	seen := f.Seen
+	if seen.IsZero() {
+		seen = f.Since
+	}
+	return now.Sub(seen) > StaleAfter
+}
+
+// IsShared reports whether the forward is reachable by other machines
+func (f Forward) IsShared() bool {
+	return f.BindAddress == "0.0.0.0" || f.BindAddress == "::"
+}
+
+// Registry is a shared backend where machines announce their active forwards
+type Registry interface {
+	Register(f Forward) error
+	Unregister(f Forward) error
+	List() ([]Forward, error)
+}
+
+// New returns the registry described by cfg, or nil if none is configured
+func New(cfg config.RegistryConfig) Registry {
+	if cfg.Path == "" {
+		return nil
+	}
+	return NewDirRegistry(cfg.Path, Machine(cfg))
+}
+
+// Machine returns the identity this machine announces itself as
+func Machine(cfg config.RegistryConfig) string {
+	if cfg.Machine != "" {
+		return cfg.Machine
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
+
+// NewForward builds the announcement for a tunnel on this machine
+func NewForward(machine string, tc config.TunnelConfig) Forward {
+	bastion := tc.Bastion.Host
+	if bastion != "" && tc.Bastion.Port != 0 && tc.Bastion.Port != 22 {
+		bastion = net.JoinHostPort(bastion, strconv.Itoa(tc.Bastion.Port))
+	}
+	return Forward{
+		Machine:     machine,
+		User:        os.Getenv("USER"),
+		Name:        tc.Name,
+		LocalPort:   tc.LocalPort,
+		BindAddress: tc.BindAddress,
+		RemoteHost:  tc.RemoteHost,
+		RemotePort:  tc.RemotePort,
+		Bastion:     bastion,
+		Since:       time.Now(),
+	}
+}
+
+// Duplicates returns forwards from other machines exposing the same target
+// through the same SSH server, a remote host like localhost being a
+// different one on every server
+func Duplicates(forwards []Forward, f Forward) []Forward {
+	dupes := make([]Forward, 0)
+	for _, other := range forwards {
+		if other.Machine != f.Machine && other.Target() == f.Target() && other.Bastion == f.Bastion {
+			dupes = append(dupes, other)
+		}
+	}
+	return dupes
+}
+
+// DirRegistry stores one JSON file per forward in a shared directory, which
+// works over any network filesystem or file sync tool the team already uses
+type DirRegistry struct {
+	path    string
+	machine string // This machine, the only one whose stale entries it removes
+}
+
+func NewDirRegistry(path string, machine string) *DirRegistry {
+	return &DirRegistry{
+		path:    path,
+		machine: machine,
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (r *DirRegistry) fileName(f Forward) string {
+	name := fmt.Sprintf("%s_%s_%d.json", f.Machine, f.Name, f.LocalPort)
+	return filepath.Join(r.path, unsafeFileChars.ReplaceAllString(name, "-"))
+}
+
+// Register announces a forward, or announces it again to keep it from going
+// stale, keeping when it was first announced
+func (r *DirRegistry) Register(f Forward) error {
+	if err := os.MkdirAll(r.path, 0755); err != nil {
+		return fmt.Errorf("error creating registry directory: %w", err)
+	}
+	if data, err := os.ReadFile(r.fileName(f)); err == nil {
+		var previous Forward
+		if json.Unmarshal(data, &previous) == nil && !previous.Since.IsZero() && !previous.Stale(time.Now()) {
+			f.Since = previous.Since
+		}
+	}
+	f.Seen = time.Now()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling forward: %w", err)
+	}
+
+	if err := fsutil.WriteFile(r.fileName(f), data, 0644); err != nil {
+		return fmt.Errorf("error writing registry entry: %w", err)
+	}
+	return nil
+}
+
+func (r *DirRegistry) Unregister(f Forward) error {
+	if err := os.Remove(r.fileName(f)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing registry entry: %w", err)
+	}
+	return nil
+}
+
+// List returns the forwards announced, leaving out those gone stale. Only
+// this machine's stale entries are removed, another's may just look stale
+// through clock skew and is left to its owner.
+func (r *DirRegistry) List() ([]Forward, error) {
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Forward{}, nil
+		}
+		return nil, fmt.Errorf("error reading registry directory: %w", err)
+	}
+
+	now := time.Now()
+	forwards := make([]Forward, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.path, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var f Forward
+		if err := json.Unmarshal(data, &f); err != nil {
+			continue
+		}
+		if f.Stale(now) {
+			if f.Machine == r.machine {
+				os.Remove(filepath.Join(r.path, entry.Name()))
+			}
+			continue
+		}
+		forwards = append(forwards, f)
+	}
+	return forwards, nil
+}
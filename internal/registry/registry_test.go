@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestDirRegistry(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tunnel9-registry-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	reg := NewDirRegistry(tempDir, "laptop")
+
+	mine := Forward{Machine: "laptop", Name: "prod-db", LocalPort: 5432, BindAddress: "0.0.0.0", RemoteHost: "db.internal", RemotePort: 5432}
+	theirs := Forward{Machine: "desktop", Name: "db", LocalPort: 15432, BindAddress: "0.0.0.0", RemoteHost: "db.internal", RemotePort: 5432}
+	other := Forward{Machine: "desktop", Name: "web", LocalPort: 8080, RemoteHost: "web.internal", RemotePort: 80}
+
+	for _, f := range []Forward{mine, theirs, other} {
+		if err := reg.Register(f); err != nil {
+			t.Fatalf("failed to register %s: %v", f.Name, err)
+		}
+	}
+
+	forwards, err := reg.List()
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(forwards) != 3 {
+		t.Fatalf("expected 3 forwards, got %d", len(forwards))
+	}
+
+	dupes := Duplicates(forwards, mine)
+	if len(dupes) != 1 || dupes[0].Machine != "desktop" {
+		t.Errorf("expected one duplicate from desktop, got %+v", dupes)
+	}
+
+	if err := reg.Unregister(theirs); err != nil {
+		t.Fatalf("failed to unregister: %v", err)
+	}
+	forwards, _ = reg.List()
+	if dupes := Duplicates(forwards, mine); len(dupes) != 0 {
+		t.Errorf("expected no duplicates after unregister, got %+v", dupes)
+	}
+
+	// Unregistering twice is not an error
+	if err := reg.Unregister(theirs); err != nil {
+		t.Errorf("unexpected error unregistering twice: %v", err)
+	}
+}
+
+func TestDuplicatesThroughOtherBastion(t *testing.T) {
+	mine := config.TunnelConfig{Name: "db", LocalPort: 5432, RemoteHost: "localhost", RemotePort: 5432}
+	mine.Bastion.Host = "db1.example.com"
+	theirs := mine
+	theirs.Bastion.Host = "db2.example.com"
+	same := mine
+	same.Bastion.Port = 22
+
+	forwards := []Forward{NewForward("desktop", theirs), NewForward("server", same)}
+	dupes := Duplicates(forwards, NewForward("laptop", mine))
+	if len(dupes) != 1 || dupes[0].Machine != "server" {
+		t.Errorf("expected only the forward through the same bastion, got %+v", dupes)
+	}
+}
+
+func TestDirRegistryStaleEntries(t *testing.T) {
+	dir := t.TempDir()
+	reg := NewDirRegistry(dir, "laptop")
+
+	// Machines that crashed an hour ago never unregistered
+	crashed := Forward{Machine: "desktop", Name: "db", LocalPort: 5432, RemoteHost: "db.internal", RemotePort: 5432}
+	mineCrashed := Forward{Machine: "laptop", Name: "cache", LocalPort: 6379, RemoteHost: "cache.internal", RemotePort: 6379}
+	for _, f := range []Forward{crashed, mineCrashed} {
+		f.Since = time.Now().Add(-time.Hour)
+		data, _ := json.Marshal(f)
+		if err := os.WriteFile(reg.fileName(f), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := time.Now().Add(-time.Hour)
+	running := Forward{Machine: "laptop", Name: "web", LocalPort: 8080, RemoteHost: "web.internal", RemotePort: 80, Since: since}
+	if err := reg.Register(running); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	// Announcing it again keeps when it started
+	running.Since = time.Now()
+	if err := reg.Register(running); err != nil {
+		t.Fatalf("failed to register again: %v", err)
+	}
+
+	forwards, err := reg.List()
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(forwards) != 1 || forwards[0].Name != "web" || !forwards[0].Since.Equal(since) {
+		t.Errorf("expected only the forward still announced, since it started, got %+v", forwards)
+	}
+	if _, err := os.Stat(reg.fileName(mineCrashed)); !os.IsNotExist(err) {
+		t.Errorf("expected our own stale entry to be removed, got %v", err)
+	}
+	// Another machine's clock may just be behind, its owner cleans up
+	if _, err := os.Stat(reg.fileName(crashed)); err != nil {
+		t.Errorf("expected another machine's stale entry to be left, got %v", err)
+	}
+}
+
+func TestListMissingDirectory(t *testing.T) {
+	forwards, err := NewDirRegistry("/non/existent/registry", "laptop").List()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(forwards) != 0 {
+		t.Errorf("expected no forwards, got %d", len(forwards))
+	}
+}
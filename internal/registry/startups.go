@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tunnel9/internal/fsutil"
+)
+
+// How many runs are remembered
+const startupLimit = 10
+
+// StartupWindow is how long a run has to stay up to count as having started,
+// a crash after that isn't blamed on the config
+const StartupWindow = 30 * time.Second
+
+// SafeModeAfter is how many startups in a row have to crash before the next
+// one starts in safe mode
+const SafeModeAfter = 3
+
+// StartupRecord is one run of tunnel9
+type StartupRecord struct {
+	Started  time.Time `json:"started"`
+	Settled  bool      `json:"settled,omitempty"`  // Stayed up for the StartupWindow
+	Clean    bool      `json:"clean,omitempty"`    // Exited normally
+	Activity string    `json:"activity,omitempty"` // What it was doing last while starting
+}
+
+// Crashed reports whether the run ended while still starting up
+func (r StartupRecord) Crashed() bool {
+	return !r.Settled && !r.Clean
+}
+
+// Startups keeps track of whether recent runs of tunnel9 made it through
+// startup, so one that keeps crashing on a bad config entry can come up in
+// safe mode instead
+type Startups struct {
+	path    string
+	mu      sync.Mutex
+	current StartupRecord
+	done    bool // Settled or ended, nothing more is written
+}
+
+// DefaultStartupsPath is where runs are tracked, next to the default config
+// file
+func DefaultStartupsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "tunnel9", "startups.json"), nil
+}
+
+func NewStartups(path string) *Startups {
+	return &Startups{path: path}
+}
+
+// Load returns the remembered runs, oldest first
+func (s *Startups) Load() ([]StartupRecord, error) {
+	var records []StartupRecord
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return records, fmt.Errorf("error reading startups: %w", err)
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return records, fmt.Errorf("error parsing startups: %w", err)
+	}
+	return records, nil
+}
+
+// Begin records that a run is starting. It returns the runs before it that
+// crashed while starting, most recent first, stopping at the first that
+// didn't.
+func (s *Startups) Begin() ([]StartupRecord, error) {
+	records, err := s.Load()
+	if err != nil {
+		// A torn file must not keep tunnel9 from starting, start over
+		records = nil
+	}
+
+	crashed := make([]StartupRecord, 0)
+	for i := len(records) - 1; i >= 0 && records[i].Crashed(); i-- {
+		crashed = append(crashed, records[i])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = StartupRecord{Started: time.Now()}
+	return crashed, s.write(append(records, s.current))
+}
+
+// Note records what the run is doing, so a crash can be put down to it.
+// Nothing is written once the run has settled. Like Settle and End it does
+// nothing on a nil Startups.
+func (s *Startups) Note(activity string) {
+	s.update(func(r *StartupRecord) { r.Activity = activity })
+}
+
+// Settle records that the run made it through startup
+func (s *Startups) Settle() {
+	s.update(func(r *StartupRecord) { r.Settled = true })
+}
+
+// End records that the run exited normally
+func (s *Startups) End() {
+	s.update(func(r *StartupRecord) { r.Clean = true })
+}
+
+func (s *Startups) update(change func(r *StartupRecord)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done || s.current.Started.IsZero() {
+		return
+	}
+	change(&s.current)
+	s.done = s.current.Settled || s.current.Clean
+
+	records, err := s.Load()
+	if err != nil || len(records) == 0 {
+		records = []StartupRecord{s.current}
+	}
+	// Ours is the last one, unless another instance started since
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Started.Equal(s.current.Started) {
+			records[i] = s.current
+			break
+		}
+	}
+	s.write(records)
+}
+
+func (s *Startups) write(records []StartupRecord) error {
+	if len(records) > startupLimit {
+		records = records[len(records)-startupLimit:]
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling startups: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("error creating startups directory: %w", err)
+	}
+	if err := fsutil.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing startups: %w", err)
+	}
+	return nil
+}
+
+// SafeModeReason explains why a run starts in safe mode, given the crashed
+// runs Begin returned, or is empty when it shouldn't
+func SafeModeReason(crashed []StartupRecord) string {
+	if len(crashed) < SafeModeAfter {
+		return ""
+	}
+	reason := fmt.Sprintf("tunnel9 crashed during its last %d startups", len(crashed))
+	if activity := crashed[0].Activity; activity != "" {
+		reason += ", last while " + activity
+	}
+	return reason
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartupsSafeMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "startups.json")
+
+	// Runs that end cleanly or settle don't count
+	run := NewStartups(path)
+	if crashed, err := run.Begin(); err != nil || len(crashed) != 0 {
+		t.Fatalf("expected no crashes yet, got %v (%v)", crashed, err)
+	}
+	run.End()
+	run = NewStartups(path)
+	run.Begin()
+	run.Settle()
+	run.Note("ignored once settled")
+
+	// Then three that crash while starting a tunnel
+	for i := 0; i < SafeModeAfter; i++ {
+		run = NewStartups(path)
+		crashed, _ := run.Begin()
+		if reason := SafeModeReason(crashed); reason != "" {
+			t.Fatalf("expected no safe mode after %d crashes, got %q", i, reason)
+		}
+		run.Note("starting tunnel db")
+	}
+
+	run = NewStartups(path)
+	crashed, err := run.Begin()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reason := SafeModeReason(crashed)
+	if !strings.Contains(reason, "last 3 startups") || !strings.Contains(reason, "starting tunnel db") {
+		t.Errorf("unexpected reason %q", reason)
+	}
+
+	// A clean exit from safe mode ends it
+	run.End()
+	crashed, _ = NewStartups(path).Begin()
+	if len(crashed) != 0 {
+		t.Errorf("expected a clean run to reset the count, got %v", crashed)
+	}
+
+	records, _ := NewStartups(path).Load()
+	if len(records) > startupLimit {
+		t.Errorf("expected at most %d runs remembered, got %d", startupLimit, len(records))
+	}
+}
+
+func TestStartupsNil(t *testing.T) {
+	var run *Startups
+	run.Note("nothing")
+	run.Settle()
+	run.End()
+}
@@ -0,0 +1,182 @@
+// Package resolver looks names up through a chosen DNS server rather than
+// the system's, for split DNS where corporate names only resolve there. The
+// server is spoken to over plain DNS, DNS over TLS or DNS over HTTPS.
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a DoH request may take when the lookup has no deadline of its own
+const dohTimeout = 10 * time.Second
+
+// New returns a resolver asking server, given as host[:port] for plain DNS,
+// tls://host[:port] for DNS over TLS or an https:// URL for DNS over HTTPS
+func New(server string) (*net.Resolver, error) {
+	dial, err := dialer(server)
+	if err != nil {
+		return nil, err
+	}
+	return &net.Resolver{PreferGo: true, Dial: dial}, nil
+}
+
+type dialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+func dialer(server string) (dialFunc, error) {
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid DNS over HTTPS URL %q", server)
+		}
+		client := &http.Client{}
+		return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			return &dohConn{client: client, url: server}, nil
+		}, nil
+	case strings.HasPrefix(server, "tls://"):
+		address, host, err := withPort(strings.TrimPrefix(server, "tls://"), "853")
+		if err != nil {
+			return nil, err
+		}
+		tlsDialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		// Not a PacketConn, so the resolver frames messages like over TCP
+		return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			return tlsDialer.DialContext(ctx, "tcp", address)
+		}, nil
+	case strings.Contains(server, "://"):
+		return nil, fmt.Errorf("unsupported DNS server %q, expected host:port, tls:// or https://", server)
+	}
+
+	address, _, err := withPort(server, "53")
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	return func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, network, address)
+	}, nil
+}
+
+// withPort adds the default port to an address without one
+func withPort(address string, port string) (string, string, error) {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return address, host, nil
+	}
+	host := strings.Trim(address, "[]")
+	if host == "" {
+		return "", "", fmt.Errorf("invalid DNS server %q", address)
+	}
+	return net.JoinHostPort(host, port), host, nil
+}
+
+// dohConn carries the resolver's TCP framed DNS messages as DNS over HTTPS
+// requests, one POST per query (RFC 8484)
+type dohConn struct {
+	client   *http.Client
+	url      string
+	mu       sync.Mutex
+	query    bytes.Buffer // Written by the resolver, until a whole message is in
+	answers  bytes.Buffer // Framed answers for the resolver to read
+	deadline time.Time
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.query.Write(b)
+	for c.query.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.query.Bytes()))
+		if c.query.Len() < 2+size {
+			break
+		}
+		c.query.Next(2)
+		answer, err := c.exchange(c.query.Next(size))
+		if err != nil {
+			return 0, err
+		}
+		binary.Write(&c.answers, binary.BigEndian, uint16(len(answer)))
+		c.answers.Write(answer)
+	}
+	return len(b), nil
+}
+
+// exchange sends one query and returns the answer
+func (c *dohConn) exchange(query []byte) ([]byte, error) {
+	deadline := c.deadline
+	if deadline.IsZero() {
+		deadline = time.Now().Add(dohTimeout)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS server answered %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.answers.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.answers.Read(b)
+}
+
+func (c *dohConn) Close() error         { return nil }
+func (c *dohConn) LocalAddr() net.Addr  { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr { return dohAddr{} }
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+type dohAddr struct{}
+
+func (dohAddr) Network() string { return "https" }
+func (dohAddr) String() string  { return "doh" }
+
+// Matches reports whether name falls under one of domains, or domains is
+// empty and every name does
+func Matches(name string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
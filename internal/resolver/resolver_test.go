@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// answer replies to an A query with ip, and to anything else with no records
+func answer(query []byte, ip net.IP) []byte {
+	end := 12
+	for query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5 // Root label, type and class
+	qtype := binary.BigEndian.Uint16(query[end-4:])
+
+	reply := append([]byte{}, query[:2]...)
+	reply = append(reply, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+	reply = append(reply, query[12:end]...)
+	if qtype == 1 {
+		reply[7] = 1
+		reply = append(reply, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+		reply = append(reply, ip.To4()...)
+	}
+	return reply
+}
+
+func lookup(t *testing.T, server string) {
+	t.Helper()
+	r, err := New(server)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addrs, err := r.LookupIPAddr(context.Background(), "db.corp.internal")
+	if err != nil || len(addrs) != 1 || addrs[0].IP.String() != "10.1.2.3" {
+		t.Errorf("expected 10.1.2.3 from %s, got %v (%v)", server, addrs, err)
+	}
+}
+
+func TestPlainDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on udp: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(answer(buf[:n], net.ParseIP("10.1.2.3")), addr)
+		}
+	}()
+
+	lookup(t, conn.LocalAddr().String())
+}
+
+func TestDNSOverHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(answer(query, net.ParseIP("10.1.2.3")))
+	}))
+	defer server.Close()
+
+	// Trust the test server's certificate
+	defer func(transport http.RoundTripper) { http.DefaultTransport = transport }(http.DefaultTransport)
+	http.DefaultTransport = server.Client().Transport
+
+	lookup(t, server.URL+"/dns-query")
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, server := range []string{"", "quic://dns.example", "https://"} {
+		if _, err := New(server); err == nil {
+			t.Errorf("expected an error for %q", server)
+		}
+	}
+	for _, server := range []string{"10.0.0.53", "10.0.0.53:5353", "[2001:db8::53]", "tls://dns.example", "https://dns.example/dns-query"} {
+		if _, err := New(server); err != nil {
+			t.Errorf("unexpected error for %q: %v", server, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	domains := []string{"corp.internal", ".example.com."}
+	for name, want := range map[string]bool{
+		"db.corp.internal":  true,
+		"corp.internal":     true,
+		"DB.Corp.Internal.": true,
+		"www.example.com":   true,
+		"notcorp.internal":  false,
+		"github.com":        false,
+	} {
+		if got := Matches(name, domains); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if !Matches("anything", nil) {
+		t.Errorf("expected every name to match without domains")
+	}
+}
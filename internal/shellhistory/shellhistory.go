@@ -0,0 +1,230 @@
+// Package shellhistory finds port forwards in past `ssh -L` commands, so
+// people moving over from plain ssh don't have to retype their tunnels.
+package shellhistory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+)
+
+// ssh options that take an argument, from ssh(1)
+const sshArgOptions = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// Discovered is a forward found in shell history
+type Discovered struct {
+	Config  config.TunnelConfig
+	Command string // Most recent command it was found in
+	Count   int    // How many times it was run
+}
+
+// DefaultFiles lists the history files of bash, zsh and fish that exist,
+// $HISTFILE first
+func DefaultFiles() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	candidates := []string{
+		os.Getenv("HISTFILE"),
+		filepath.Join(home, ".bash_history"),
+		filepath.Join(home, ".zsh_history"),
+		filepath.Join(home, ".local", "share", "fish", "fish_history"),
+	}
+
+	files := make([]string, 0, len(candidates))
+	seen := make(map[string]bool)
+	for _, path := range candidates {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Commands reads the commands of a history file in any of the supported
+// formats, oldest first
+func Commands(r io.Reader) ([]string, error) {
+	commands := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// zsh extended history, ": 1700000000:0;ssh -L ..."
+		if strings.HasPrefix(line, ": ") {
+			if _, command, found := strings.Cut(line, ";"); found {
+				line = command
+			}
+		}
+		// fish, "- cmd: ssh -L ..."
+		line = strings.TrimPrefix(line, "- cmd: ")
+		if line = strings.TrimSpace(line); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	return commands, scanner.Err()
+}
+
+// ParseCommand returns the forwards of an ssh command line, one for each
+// -L, through the host it logs in to
+func ParseCommand(command string) []config.TunnelConfig {
+	fields := strings.Fields(command)
+	start := -1
+	for i, field := range fields {
+		if filepath.Base(field) == "ssh" {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	var specs []string
+	var destination, user string
+	port := 0
+	for i := start; i < len(fields) && destination == ""; i++ {
+		field := strings.Trim(fields[i], `"'`)
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			destination = field
+			continue
+		}
+		// Flags may be combined, like -fNL 8080:web:80, up to one taking an argument
+		flags := field[1:]
+		j := strings.IndexAny(flags, sshArgOptions)
+		if j < 0 {
+			continue
+		}
+		option, value := flags[j], flags[j+1:]
+		if value == "" && i+1 < len(fields) {
+			value = strings.Trim(fields[i+1], `"'`)
+			i++
+		}
+		switch option {
+		case 'L':
+			specs = append(specs, value)
+		case 'p':
+			port, _ = strconv.Atoi(value)
+		case 'l':
+			user = value
+		}
+	}
+	if destination == "" || len(specs) == 0 {
+		return nil
+	}
+
+	if at := strings.LastIndex(destination, "@"); at >= 0 {
+		user = destination[:at]
+		destination = destination[at+1:]
+	}
+	if port == 0 {
+		port = 22
+	}
+
+	tunnels := make([]config.TunnelConfig, 0, len(specs))
+	for _, spec := range specs {
+		tc, err := parseForward(spec)
+		if err != nil {
+			continue
+		}
+		tc.Bastion.Host = destination
+		tc.Bastion.User = user
+		tc.Bastion.Port = port
+		tunnels = append(tunnels, tc)
+	}
+	return tunnels
+}
+
+// parseForward reads a -L spec, [bind_address:]port:host:hostport
+func parseForward(spec string) (config.TunnelConfig, error) {
+	var tc config.TunnelConfig
+	parts := strings.Split(spec, ":")
+	if len(parts) == 4 {
+		tc.BindAddress = parts[0]
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return tc, fmt.Errorf("unsupported forward %q", spec)
+	}
+
+	var err error
+	if tc.LocalPort, err = strconv.Atoi(parts[0]); err != nil {
+		return tc, fmt.Errorf("invalid local port in %q", spec)
+	}
+	if tc.RemotePort, err = strconv.Atoi(parts[2]); err != nil {
+		return tc, fmt.Errorf("invalid remote port in %q", spec)
+	}
+	tc.RemoteHost = strings.Trim(parts[1], "[]")
+	if tc.RemoteHost == "" {
+		return tc, fmt.Errorf("no remote host in %q", spec)
+	}
+	tc.Name = fmt.Sprintf("%s-%d", tc.RemoteHost, tc.LocalPort)
+	return tc, nil
+}
+
+// forwardKey identifies a forward regardless of what it is called
+func forwardKey(tc config.TunnelConfig) string {
+	port := tc.Bastion.Port
+	if port == 0 {
+		port = 22
+	}
+	return fmt.Sprintf("%s:%d>%s:%d@%s@%s:%d", tc.BindAddress, tc.LocalPort, tc.RemoteHost, tc.RemotePort,
+		tc.Bastion.User, tc.Bastion.Host, port)
+}
+
+// Discover collects the forwards run from the given history files, most
+// used first, leaving out those already in existing. Files that can't be
+// read are skipped.
+func Discover(files []string, existing []config.TunnelConfig) []Discovered {
+	known := make(map[string]bool, len(existing))
+	for _, tc := range existing {
+		known[forwardKey(tc)] = true
+	}
+
+	found := make(map[string]*Discovered)
+	order := make([]string, 0)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		commands, _ := Commands(f)
+		f.Close()
+
+		for _, command := range commands {
+			for _, tc := range ParseCommand(command) {
+				key := forwardKey(tc)
+				if known[key] {
+					continue
+				}
+				if d, exists := found[key]; exists {
+					d.Count++
+					d.Command = command
+					continue
+				}
+				found[key] = &Discovered{Config: tc, Command: command, Count: 1}
+				order = append(order, key)
+			}
+		}
+	}
+
+	discovered := make([]Discovered, 0, len(order))
+	for _, key := range order {
+		discovered = append(discovered, *found[key])
+	}
+	sort.SliceStable(discovered, func(i, j int) bool {
+		return discovered[i].Count > discovered[j].Count
+	})
+	return discovered
+}
@@ -0,0 +1,81 @@
+package shellhistory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestCommands(t *testing.T) {
+	history := strings.Join([]string{
+		": 1700000000:0;ssh -L 5432:db:5432 jump",
+		"- cmd: ssh -NL 8080:web:80 ops@jump",
+		"  when: 1700000001",
+		"ls -la",
+	}, "\n")
+	commands, err := Commands(strings.NewReader(history))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commands) != 4 || commands[0] != "ssh -L 5432:db:5432 jump" || commands[1] != "ssh -NL 8080:web:80 ops@jump" {
+		t.Errorf("unexpected commands %q", commands)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tunnels := ParseCommand("sudo ssh -f -N -p 2222 -L 127.0.0.1:5432:db.internal:5432 -L8080:web.internal:80 -i ~/.ssh/work deploy@jump.example.com uptime")
+	if len(tunnels) != 2 {
+		t.Fatalf("expected two forwards, got %+v", tunnels)
+	}
+	db := tunnels[0]
+	if db.BindAddress != "127.0.0.1" || db.LocalPort != 5432 || db.RemoteHost != "db.internal" || db.RemotePort != 5432 {
+		t.Errorf("unexpected forward %+v", db)
+	}
+	if db.Bastion.Host != "jump.example.com" || db.Bastion.User != "deploy" || db.Bastion.Port != 2222 || db.Name != "db.internal-5432" {
+		t.Errorf("unexpected server %+v", db.Bastion)
+	}
+	if tunnels[1].LocalPort != 8080 || tunnels[1].RemoteHost != "web.internal" {
+		t.Errorf("unexpected joined -L forward %+v", tunnels[1])
+	}
+
+	if tunnels := ParseCommand("ssh -fNL 9000:api:443 jump"); len(tunnels) != 1 || tunnels[0].RemotePort != 443 || tunnels[0].Bastion.Host != "jump" {
+		t.Errorf("expected the combined -fNL forward, got %+v", tunnels)
+	}
+
+	for _, command := range []string{
+		"ssh jump.example.com",
+		"ssh -L 8080 jump.example.com",
+		"sshfs jump:/srv /mnt",
+		"ssh -D 1080 jump",
+	} {
+		if tunnels := ParseCommand(command); len(tunnels) != 0 {
+			t.Errorf("expected no forwards in %q, got %+v", command, tunnels)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	bash := filepath.Join(dir, ".bash_history")
+	zsh := filepath.Join(dir, ".zsh_history")
+	os.WriteFile(bash, []byte("ssh -L 8080:web:80 jump\nssh -L 5432:db:5432 jump\n"), 0600)
+	os.WriteFile(zsh, []byte(": 1700000000:0;ssh -L 5432:db:5432 jump\n: 1700000001:0;ssh -L 6379:cache:6379 ops@jump\n"), 0600)
+
+	existing := config.TunnelConfig{Name: "cache", LocalPort: 6379, RemoteHost: "cache", RemotePort: 6379}
+	existing.Bastion.Host = "jump"
+	existing.Bastion.User = "ops"
+
+	discovered := Discover([]string{bash, zsh, filepath.Join(dir, "missing")}, []config.TunnelConfig{existing})
+	if len(discovered) != 2 {
+		t.Fatalf("expected web and db, got %+v", discovered)
+	}
+	if discovered[0].Config.RemoteHost != "db" || discovered[0].Count != 2 {
+		t.Errorf("expected the most used forward first, got %+v", discovered[0])
+	}
+	if discovered[1].Config.RemoteHost != "web" || discovered[1].Command != "ssh -L 8080:web:80 jump" {
+		t.Errorf("unexpected second forward %+v", discovered[1])
+	}
+}
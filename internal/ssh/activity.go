@@ -0,0 +1,26 @@
+package ssh
+
+import "sync"
+
+var (
+	activityMu sync.RWMutex
+	activity   func(string)
+)
+
+// SetActivityRecorder has what the ssh layer is doing, like starting a
+// tunnel, passed to record, so a crash can be put down to it. Nil stops
+// recording.
+func SetActivityRecorder(record func(string)) {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+	activity = record
+}
+
+func noteActivity(what string) {
+	activityMu.RLock()
+	record := activity
+	activityMu.RUnlock()
+	if record != nil {
+		record(what)
+	}
+}
@@ -0,0 +1,77 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// agentConn is one connection to ssh-agent shared by every tunnel, redialed
+// when the agent restarts or SSH_AUTH_SOCK changes
+type agentConn struct {
+	mu     sync.Mutex
+	socket string
+	conn   net.Conn
+	client agent.ExtendedAgent
+}
+
+var sharedAgent agentConn
+
+func (a *agentConn) dial(socket string) error {
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn, a.client = nil, nil
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to reach ssh-agent at %s: %w", socket, err)
+	}
+	a.socket, a.conn, a.client = socket, conn, agent.NewClient(conn)
+	return nil
+}
+
+// signers lists the agent's keys, redialing once if the connection has gone
+// stale. The agent must stay reachable while they sign.
+func (a *agentConn) signers() ([]ssh.Signer, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil || a.socket != socket {
+		if err := a.dial(socket); err != nil {
+			return nil, err
+		}
+	}
+	signers, err := a.client.Signers()
+	if err == nil {
+		return signers, nil
+	}
+	if err := a.dial(socket); err != nil {
+		return nil, err
+	}
+	return a.client.Signers()
+}
+
+// agentAuth offers the keys held by ssh-agent, which covers keys that never
+// touch the disk such as those on hardware tokens. Returns nil when no agent
+// is running.
+func agentAuth(t *Tunnel) ssh.AuthMethod {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil
+	}
+	if _, err := sharedAgent.signers(); err != nil {
+		t.logf("Skipping ssh-agent: %v", err)
+		return nil
+	}
+	t.logf("Using ssh-agent at %s", socket)
+	return ssh.PublicKeysCallback(sharedAgent.signers)
+}
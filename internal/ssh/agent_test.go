@@ -0,0 +1,81 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveAgent runs an in-memory ssh-agent holding one key on a temp socket
+func serveAgent(t *testing.T) (string, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return socket, signer.PublicKey()
+}
+
+func TestAgentAuth(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if agentAuth(&Tunnel{}) != nil {
+		t.Error("expected no agent auth without SSH_AUTH_SOCK")
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(t.TempDir(), "missing.sock"))
+	if agentAuth(&Tunnel{}) != nil {
+		t.Error("expected no agent auth when the agent is unreachable")
+	}
+
+	socket, key := serveAgent(t)
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	if agentAuth(&Tunnel{}) == nil {
+		t.Fatal("expected agent auth with a running agent")
+	}
+
+	signers, err := sharedAgent.signers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(signers) != 1 || ssh.FingerprintSHA256(signers[0].PublicKey()) != ssh.FingerprintSHA256(key) {
+		t.Errorf("expected the agent's key, got %d signer(s)", len(signers))
+	}
+
+	// A restarted agent on a new socket is picked up
+	socket, key = serveAgent(t)
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	signers, err = sharedAgent.signers()
+	if err != nil || len(signers) != 1 || ssh.FingerprintSHA256(signers[0].PublicKey()) != ssh.FingerprintSHA256(key) {
+		t.Errorf("expected the new agent's key, got %d signer(s) (%v)", len(signers), err)
+	}
+}
@@ -0,0 +1,80 @@
+package ssh
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithms is what the SSH handshake offers for a tunnel, nil lists
+// leaving the defaults alone
+type Algorithms struct {
+	Ciphers      []string
+	MACs         []string
+	KeyExchanges []string
+	HostKeys     []string
+	Legacy       []string // Insecure algorithms that had to be allowed
+}
+
+// ResolveAlgorithms checks a tunnel's ssh_options against what the SSH
+// library implements
+func ResolveAlgorithms(opts config.SSHOptions) (*Algorithms, error) {
+	supported := ssh.SupportedAlgorithms()
+	insecure := ssh.InsecureAlgorithms()
+	algorithms := &Algorithms{}
+
+	var err error
+	resolve := func(kind string, values []string, defaults []string, legacy []string) []string {
+		if err != nil || len(values) == 0 {
+			return nil
+		}
+		var resolved []string
+		if strings.HasPrefix(values[0], "+") {
+			resolved = append(resolved, defaults...)
+		}
+		for _, value := range values {
+			value = strings.TrimPrefix(value, "+")
+			switch {
+			case containsString(defaults, value):
+			case containsString(legacy, value):
+				algorithms.Legacy = append(algorithms.Legacy, value)
+			default:
+				err = fmt.Errorf("unsupported %s %q", kind, value)
+				return nil
+			}
+			if !containsString(resolved, value) {
+				resolved = append(resolved, value)
+			}
+		}
+		return resolved
+	}
+
+	algorithms.Ciphers = resolve("cipher", opts.Ciphers, supported.Ciphers, insecure.Ciphers)
+	algorithms.MACs = resolve("MAC", opts.MACs, supported.MACs, insecure.MACs)
+	algorithms.KeyExchanges = resolve("key exchange", opts.KeyExchanges, supported.KeyExchanges, insecure.KeyExchanges)
+	algorithms.HostKeys = resolve("host key algorithm", opts.HostKeyAlgorithms, supported.HostKeys, insecure.HostKeys)
+	if err != nil {
+		return nil, err
+	}
+	return algorithms, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the algorithms on a client config
+func (a *Algorithms) apply(c *ssh.ClientConfig) {
+	c.Ciphers = a.Ciphers
+	c.MACs = a.MACs
+	c.KeyExchanges = a.KeyExchanges
+	c.HostKeyAlgorithms = a.HostKeys
+}
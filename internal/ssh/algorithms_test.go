@@ -0,0 +1,38 @@
+package ssh
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestResolveAlgorithms(t *testing.T) {
+	algorithms, err := ResolveAlgorithms(config.SSHOptions{})
+	if err != nil || algorithms.Ciphers != nil || algorithms.KeyExchanges != nil {
+		t.Fatalf("expected defaults to be left alone, got %+v (%v)", algorithms, err)
+	}
+
+	algorithms, err = ResolveAlgorithms(config.SSHOptions{
+		Ciphers:      []string{"aes128-cbc"},
+		KeyExchanges: []string{"+diffie-hellman-group1-sha1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(algorithms.Ciphers) != 1 || algorithms.Ciphers[0] != "aes128-cbc" {
+		t.Errorf("expected only the legacy cipher, got %v", algorithms.Ciphers)
+	}
+	defaults := ssh.SupportedAlgorithms().KeyExchanges
+	if len(algorithms.KeyExchanges) != len(defaults)+1 || algorithms.KeyExchanges[len(defaults)] != "diffie-hellman-group1-sha1" {
+		t.Errorf("expected the legacy kex added after the defaults, got %v", algorithms.KeyExchanges)
+	}
+	if len(algorithms.Legacy) != 2 {
+		t.Errorf("expected both legacy algorithms to be reported, got %v", algorithms.Legacy)
+	}
+
+	if _, err := ResolveAlgorithms(config.SSHOptions{MACs: []string{"hmac-md5"}}); err == nil {
+		t.Error("expected an error for a MAC the library doesn't implement")
+	}
+}
@@ -0,0 +1,31 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+
+	"tunnel9/internal/config"
+)
+
+// BrowseURL is where a browser reaches the local end of a tunnel, with the
+// scheme and path of its browse settings. Tunnels listening on every address
+// are reached through localhost.
+func BrowseURL(tc config.TunnelConfig) (string, error) {
+	host := tc.BindAddress
+	if tc.BindInterface != "" {
+		addr, err := resolveInterfaceAddress(tc.BindInterface)
+		if err != nil {
+			return "", err
+		}
+		host = addr
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+
+	scheme := tc.Browse.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(tc.LocalPort)) + tc.Browse.Path, nil
+}
@@ -0,0 +1,32 @@
+package ssh
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestBrowseURL(t *testing.T) {
+	tests := []struct {
+		bind   string
+		browse config.BrowseSettings
+		want   string
+	}{
+		{"", config.BrowseSettings{}, "http://localhost:8080"},
+		{"0.0.0.0", config.BrowseSettings{}, "http://localhost:8080"},
+		{"::", config.BrowseSettings{}, "http://localhost:8080"},
+		{"127.0.0.2", config.BrowseSettings{Scheme: "https", Path: "/admin/?tab=jobs"}, "https://127.0.0.2:8080/admin/?tab=jobs"},
+		{"::1", config.BrowseSettings{Path: "/"}, "http://[::1]:8080/"},
+	}
+	for _, tt := range tests {
+		tc := config.TunnelConfig{LocalPort: 8080, BindAddress: tt.bind, Browse: tt.browse}
+		got, err := BrowseURL(tc)
+		if err != nil || got != tt.want {
+			t.Errorf("BrowseURL(%q, %+v) = %q, %v, want %q", tt.bind, tt.browse, got, err, tt.want)
+		}
+	}
+
+	if _, err := BrowseURL(config.TunnelConfig{LocalPort: 8080, BindInterface: "no-such-interface0"}); err == nil {
+		t.Error("expected an error for a missing interface")
+	}
+}
@@ -0,0 +1,76 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CheckConnection logs in to a tunnel's SSH server and dials its target
+// once, without listening locally, so a config can be tried before it is
+// saved. Host key and keyboard-interactive prompts reach the front end like
+// they do when a tunnel starts. The SSH connection isn't shared with running
+// tunnels, so the login itself is checked too.
+func (tm *TunnelManager) CheckConnection(tc config.TunnelConfig, timeout time.Duration) error {
+	t := &Tunnel{
+		ID:             "check",
+		Config:         tc,
+		stopChan:       make(chan struct{}),
+		hostKeyPrompts: tm.hostKeyPrompts,
+		authPrompts:    tm.authPrompts,
+	}
+	defer close(t.stopChan)
+	udpDefaults(t)
+
+	sshconfig, err := GetSSHConfig(t)
+	if err != nil {
+		return err
+	}
+	sshEndpoint, remoteEndpoint := figureOutRemoteVsBastion(t.Config)
+	client, err := t.dialSSH(sshEndpoint, sshconfig)
+	if err != nil {
+		return fmt.Errorf("ssh to %s failed: %w", sshEndpoint.String(), err)
+	}
+	defer client.Close()
+
+	// The relay speaks UDP on the far side, there is no TCP target to dial
+	if t.Config.IsUDP() {
+		return nil
+	}
+	return dialTarget(client, remoteEndpoint.String(), timeout)
+}
+
+// dialTarget opens a connection to target through client, giving up after
+// timeout since a firewalled target may never answer
+func dialTarget(client *ssh.Client, target string, timeout time.Duration) error {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial("tcp", target)
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return fmt.Errorf("%s unreachable from the SSH server: %w", target, r.err)
+		}
+		r.conn.Close()
+		return nil
+	case <-time.After(timeout):
+		// Close whatever the dial ends up with
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return fmt.Errorf("%s didn't answer within %v", target, timeout)
+	}
+}
@@ -0,0 +1,27 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDialTarget(t *testing.T) {
+	addr, _ := serveSSH(t)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "ops",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// The test server refuses every forward
+	err = dialTarget(client, "db.internal:5432", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "db.internal:5432 unreachable") {
+		t.Errorf("expected the target to be reported unreachable, got %v", err)
+	}
+}
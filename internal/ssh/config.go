@@ -1,116 +1,312 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"tunnel9/internal/config"
+
 	"github.com/sio2boss/ssh_config"
 	"golang.org/x/crypto/ssh"
 )
 
+// ResolvedSettings is what the ssh layer will actually use for a tunnel once
+// ~/.ssh/config overrides have been applied
+type ResolvedSettings struct {
+	Config        config.TunnelConfig // Tunnel config with overrides applied
+	User          string
+	IdentityFiles []string
+	ProxyJump     string   // Hosts to hop through first, from ssh_config
+	ProxyCommand  string   // Command that reaches the SSH server, from ssh_config
+	Notes         []string // What ssh_config changed, in the order it happened
+}
+
+// SSHEndpoint is the SSH server that will be dialed
+func (r *ResolvedSettings) SSHEndpoint() *Endpoint {
+	sshEndpoint, _ := figureOutRemoteVsBastion(r.Config)
+	return sshEndpoint
+}
+
+// RemoteEndpoint is the target as seen from the SSH server
+func (r *ResolvedSettings) RemoteEndpoint() *Endpoint {
+	_, remoteEndpoint := figureOutRemoteVsBastion(r.Config)
+	return remoteEndpoint
+}
+
+// Identity files tried when ~/.ssh/config names none, in order
+var defaultIdentityFiles = []string{"id_ed25519", "id_ed25519_sk", "id_ecdsa", "id_rsa"}
+
+var (
+	identityMu    sync.RWMutex
+	identityFiles = defaultIdentityFiles
+	passphrases   map[string]string // Secret references, by identity file as configured
+)
+
+// SetIdentityFiles changes which identity files are tried, and in what
+// order, when ~/.ssh/config doesn't name any. Bare names are looked up in
+// ~/.ssh and a leading ~/ is expanded. An empty list restores the default.
+func SetIdentityFiles(files []string) {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+	if len(files) == 0 {
+		identityFiles = defaultIdentityFiles
+		return
+	}
+	identityFiles = append([]string(nil), files...)
+}
+
+// SetPassphrases sets where the passphrases of encrypted identity files are
+// found, as secret references like keyring:id_work by identity file, named
+// like in SetIdentityFiles
+func SetPassphrases(secrets map[string]string) {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+	passphrases = secrets
+}
+
+// expandIdentityPath finds an identity file named as in SetIdentityFiles
+func expandIdentityPath(home string, file string) string {
+	switch {
+	case strings.HasPrefix(file, "~/"):
+		return filepath.Join(home, file[2:])
+	case filepath.IsAbs(file) || strings.ContainsRune(file, filepath.Separator):
+		return file
+	}
+	return filepath.Join(home, ".ssh", file)
+}
+
+func defaultIdentityPaths(home string) []string {
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+
+	paths := make([]string, len(identityFiles))
+	for i, file := range identityFiles {
+		paths[i] = expandIdentityPath(home, file)
+	}
+	return paths
+}
+
+// passphraseFor returns the secret reference for an identity file's
+// passphrase, if one is configured
+func passphraseFor(keyPath string) (string, bool) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	identityMu.RLock()
+	defer identityMu.RUnlock()
+	for file, secret := range passphrases {
+		if expandIdentityPath(home, file) == keyPath {
+			return secret, true
+		}
+	}
+	return "", false
+}
+
 func loadPrivateKey(t *Tunnel, keyPath string) (ssh.AuthMethod, error) {
 	key, err := os.ReadFile(keyPath)
 	if err != nil {
-		t.logf("Failed to find key at %s", keyPath)
+		t.warnf("Failed to find key at %s", keyPath)
 		return nil, err
 	}
 
 	signer, err := ssh.ParsePrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		secret, ok := passphraseFor(keyPath)
+		if !ok {
+			t.warnf("%s is encrypted, add its passphrase under ssh.passphrases", keyPath)
+			return nil, err
+		}
+		passphrase, resolveErr := config.ResolveSecret(secret)
+		if resolveErr != nil {
+			t.warnf("Failed to get the passphrase for %s: %v", keyPath, resolveErr)
+			return nil, resolveErr
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	}
 	if err != nil {
-		t.logf("failed to parse private key: %v", err)
+		t.warnf("failed to parse private key: %v", err)
+		if strings.HasSuffix(keyPath, "_sk") {
+			t.logf("Security key identities like %s are used through ssh-agent", keyPath)
+		}
 		return nil, err
 	}
 
 	return ssh.PublicKeys(signer), nil
 }
 
-func GetSSHConfig(t *Tunnel) (*ssh.ClientConfig, error) {
+// ResolveSSHSettings works out the effective host, port, user and identity
+// files for a tunnel without connecting anywhere
+func ResolveSSHSettings(tc config.TunnelConfig) (*ResolvedSettings, error) {
 	// Find home directory
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	// Try ECDSA first, then RSA
-	keyPaths := []string{
-		filepath.Join(home, ".ssh", "id_ecdsa"),
-		filepath.Join(home, ".ssh", "id_rsa"),
+	settings := &ResolvedSettings{
+		Config:        tc,
+		IdentityFiles: defaultIdentityPaths(home),
+	}
+	notef := func(format string, args ...interface{}) {
+		settings.Notes = append(settings.Notes, fmt.Sprintf(format, args...))
 	}
 
 	// Set User from config or environment variable
-	sshUser := t.Config.Bastion.User
-	if t.Config.Bastion.User == "" {
-		sshUser = os.Getenv("USER")
+	settings.User = tc.Bastion.User
+	if tc.Bastion.User == "" {
+		settings.User = os.Getenv("USER")
 	}
 
 	// We will resolve this host in the SSH config file
-	lookupHost := &t.Config.Bastion.Host
-	lookupPort := &t.Config.Bastion.Port
-	if t.Config.Bastion.Host == "" {
-		lookupHost = &t.Config.RemoteHost
-		lookupPort = &t.Config.RemotePort
+	lookupHost := &settings.Config.Bastion.Host
+	lookupPort := &settings.Config.Bastion.Port
+	if tc.Bastion.Host == "" {
+		lookupHost = &settings.Config.RemoteHost
+		lookupPort = &settings.Config.RemotePort
 	}
 
 	// Load SSH config file
 	configFile, err := os.Open(filepath.Join(home, ".ssh", "config"))
 	if err != nil {
-		t.logf("Failed to open SSH config: %v", err)
-	} else {
-		defer configFile.Close()
-		sshConfig, err := ssh_config.Decode(configFile)
-		if err != nil {
-			t.logf("Failed to parse SSH config: %v", err)
-		} else {
+		notef("Failed to open SSH config: %v", err)
+		return settings, nil
+	}
+	defer configFile.Close()
 
-			// override port with that in the SSH config
-			if port, _ := sshConfig.Get(*lookupHost, "Port"); port != "" {
-				if portNum, err := strconv.Atoi(port); err == nil {
-					t.logf("Overriding port %d with %d from SSH config", *lookupPort, portNum)
-					*lookupPort = portNum
-				}
-			}
+	sshConfig, err := ssh_config.Decode(configFile)
+	if err != nil {
+		notef("Failed to parse SSH config: %v", err)
+		return settings, nil
+	}
 
-			// Override Bastions User with User from SSH config
-			if user, _ := sshConfig.Get(*lookupHost, "User"); user != "" {
-				t.logf("Overriding user with %s from SSH config", user)
-				sshUser = user
+	// override port with that in the SSH config
+	if port, _ := sshConfig.Get(*lookupHost, "Port"); port != "" {
+		if portNum, err := strconv.Atoi(port); err == nil {
+			if tc.IgnoresSSHConfig("port") {
+				notef("Keeping port %d, ignoring %d from SSH config", *lookupPort, portNum)
+			} else {
+				notef("Overriding port %d with %d from SSH config", *lookupPort, portNum)
+				*lookupPort = portNum
 			}
+		}
+	}
 
-			// Add identity file to auths
-			if identityFiles, _ := sshConfig.GetAll(*lookupHost, "IdentityFile"); len(identityFiles) > 0 {
-				t.logf("Overriding identity with %d files from SSH config", len(identityFiles))
-				keyPaths = identityFiles
-			}
+	// Override Bastions User with User from SSH config
+	if user, _ := sshConfig.Get(*lookupHost, "User"); user != "" {
+		if tc.IgnoresSSHConfig("user") {
+			notef("Keeping user %s, ignoring %s from SSH config", settings.User, user)
+		} else {
+			notef("Overriding user with %s from SSH config", user)
+			settings.User = user
+		}
+	}
 
-			// override lookupHost with HostName from SSH config
-			if host, _ := sshConfig.Get(*lookupHost, "HostName"); host != "" {
-				t.logf("Overriding host %s with %s from SSH config", *lookupHost, host)
-				*lookupHost = host
-			}
+	// Add identity file to auths
+	if identityFiles, _ := sshConfig.GetAll(*lookupHost, "IdentityFile"); len(identityFiles) > 0 {
+		if tc.IgnoresSSHConfig("identity_file") {
+			notef("Keeping default identity, ignoring %d files from SSH config", len(identityFiles))
+		} else {
+			notef("Overriding identity with %d files from SSH config", len(identityFiles))
+			settings.IdentityFiles = identityFiles
 		}
 	}
 
-	// Load Keys
+	// Reach the host the way plain ssh would, looked up before HostName
+	// replaces the alias. When both are set ProxyJump is used.
+	if !tc.IgnoresSSHConfig("proxy") {
+		if jump, _ := sshConfig.Get(*lookupHost, "ProxyJump"); jump != "" && !strings.EqualFold(jump, "none") {
+			notef("Connecting through ProxyJump %s from SSH config", jump)
+			settings.ProxyJump = jump
+		} else if command, _ := sshConfig.Get(*lookupHost, "ProxyCommand"); command != "" && !strings.EqualFold(command, "none") {
+			notef("Connecting through ProxyCommand from SSH config: %s", command)
+			settings.ProxyCommand = command
+		}
+	}
+
+	// override lookupHost with HostName from SSH config
+	if host, _ := sshConfig.Get(*lookupHost, "HostName"); host != "" {
+		if tc.IgnoresSSHConfig("hostname") {
+			notef("Keeping host %s, ignoring %s from SSH config", *lookupHost, host)
+		} else {
+			notef("Overriding host %s with %s from SSH config", *lookupHost, host)
+			*lookupHost = host
+		}
+	}
+
+	return settings, nil
+}
+
+func GetSSHConfig(t *Tunnel) (*ssh.ClientConfig, error) {
+	settings, err := ResolveSSHSettings(t.Config)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range settings.Notes {
+		t.logf("%s", note)
+	}
+	t.Config = settings.Config
+	t.proxy = newProxySettings(settings)
+	t.identityFiles = settings.IdentityFiles
+
+	// Old appliances may only speak algorithms that are off by default
+	algorithms, err := ResolveAlgorithms(t.Config.SSHOptions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh_options: %w", err)
+	}
+	if len(algorithms.Legacy) > 0 {
+		t.logf("Allowing legacy algorithms: %s", strings.Join(algorithms.Legacy, ", "))
+	}
+
+	config := clientConfig(t, settings)
+	algorithms.apply(config)
+	return config, nil
+}
+
+// clientConfig builds the SSH client config for resolved settings, with the
+// agent, identity files and keyboard-interactive auth in that order
+func clientConfig(t *Tunnel, settings *ResolvedSettings) *ssh.ClientConfig {
+	// Keys in the agent come first, then the ones on disk
 	var auths []ssh.AuthMethod
-	for _, keyPath := range keyPaths {
+	if auth := agentAuth(t); auth != nil {
+		auths = append(auths, auth)
+	}
+	for _, keyPath := range settings.IdentityFiles {
 		if auth, err := loadPrivateKey(t, keyPath); err == nil {
 			t.logf("Loaded identity file: %s", keyPath)
 			auths = append(auths, auth)
 		}
 	}
 
+	// Then a password from the tunnel's auth secrets
+	if secret := t.Config.Auth.Password; secret != "" {
+		auths = append(auths, ssh.PasswordCallback(func() (string, error) {
+			return config.ResolveSecret(secret)
+		}))
+	}
+
+	// Fall back to answering the server's challenges, e.g. OTP or Duo,
+	// from the auth secrets or whoever drives the manager
+	if t.authPrompts != nil || t.Config.Auth.Password != "" || t.Config.Auth.TOTP != "" {
+		auths = append(auths, ssh.KeyboardInteractive(t.keyboardInteractive(settings.User)))
+	}
+
 	config := &ssh.ClientConfig{
-		User:            sshUser,
+		User:            settings.User,
 		Auth:            auths,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // TODO: Implement proper host key verification
+		HostKeyCallback: hostKeyCallback(t),
 		Timeout:         10 * time.Second,
 	}
 
 	// Add keep-alive configuration
 	config.Timeout = 10 * time.Second
 
-	return config, nil
+	return config
 }
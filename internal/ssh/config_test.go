@@ -0,0 +1,159 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDefaultIdentityPaths(t *testing.T) {
+	home := "/home/alice"
+	defer SetIdentityFiles(nil)
+
+	want := []string{
+		"/home/alice/.ssh/id_ed25519",
+		"/home/alice/.ssh/id_ed25519_sk",
+		"/home/alice/.ssh/id_ecdsa",
+		"/home/alice/.ssh/id_rsa",
+	}
+	if got := defaultIdentityPaths(home); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	SetIdentityFiles([]string{"id_rsa", "~/keys/work", "/etc/tunnel9/key"})
+	want = []string{
+		"/home/alice/.ssh/id_rsa",
+		filepath.Join(home, "keys", "work"),
+		"/etc/tunnel9/key",
+	}
+	if got := defaultIdentityPaths(home); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	SetIdentityFiles(nil)
+	if got := defaultIdentityPaths(home); len(got) != 4 {
+		t.Errorf("expected the default order back, got %v", got)
+	}
+}
+
+func TestResolveSSHSettingsIdentityFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	defer SetIdentityFiles(nil)
+
+	SetIdentityFiles([]string{"id_ed25519"})
+	settings, err := ResolveSSHSettings(config.TunnelConfig{RemoteHost: "db.example.com", RemotePort: 5432})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{filepath.Join(home, ".ssh", "id_ed25519")}; !reflect.DeepEqual(settings.IdentityFiles, want) {
+		t.Errorf("expected %v, got %v", want, settings.IdentityFiles)
+	}
+}
+
+func TestResolveSSHSettingsProxy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	sshConfig := `Host db
+  HostName db.internal
+  ProxyJump alice@jump.example.com:2222
+
+Host legacy
+  ProxyCommand nc -X connect -x proxy:3128 %h %p
+
+Host direct
+  ProxyJump none
+`
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "config"), []byte(sshConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := ResolveSSHSettings(config.TunnelConfig{RemoteHost: "db", RemotePort: 5432})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.ProxyJump != "alice@jump.example.com:2222" || settings.Config.RemoteHost != "db.internal" {
+		t.Errorf("expected a jump to db.internal, got %q to %s", settings.ProxyJump, settings.Config.RemoteHost)
+	}
+	if proxy := newProxySettings(settings); proxy == nil || proxy.endpoint != "db.internal:22" {
+		t.Errorf("expected the proxy to apply to db.internal:22, got %+v", proxy)
+	}
+
+	settings, _ = ResolveSSHSettings(config.TunnelConfig{RemoteHost: "legacy", RemotePort: 80})
+	if settings.ProxyCommand != "nc -X connect -x proxy:3128 %h %p" || settings.ProxyJump != "" {
+		t.Errorf("expected the ProxyCommand, got %q / %q", settings.ProxyCommand, settings.ProxyJump)
+	}
+
+	settings, _ = ResolveSSHSettings(config.TunnelConfig{RemoteHost: "direct", RemotePort: 80})
+	if newProxySettings(settings) != nil {
+		t.Errorf("expected ProxyJump none to dial directly, got %q", settings.ProxyJump)
+	}
+
+	settings, _ = ResolveSSHSettings(config.TunnelConfig{RemoteHost: "db", RemotePort: 5432, SSHConfigIgnore: []string{"proxy"}})
+	if settings.ProxyJump != "" {
+		t.Errorf("expected proxy to be ignored, got %q", settings.ProxyJump)
+	}
+}
+
+func TestExpandProxyCommand(t *testing.T) {
+	got := expandProxyCommand("ssh -W %h:%p %r@gw 100%%", "db.internal", 22, "alice")
+	if want := "ssh -W db.internal:22 alice@gw 100%"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestProxyCommandConn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs cat")
+	}
+	conn, err := startProxyCommand("cat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("SSH-2.0-test\r\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 14)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "SSH-2.0-test\r\n" {
+		t.Errorf("expected the command's output back, got %q (%v)", buf, err)
+	}
+}
+
+func TestLoadPrivateKeyWithPassphrase(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_work")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer SetPassphrases(nil)
+
+	if _, err := loadPrivateKey(nil, keyPath); err == nil {
+		t.Error("expected an encrypted key to fail without a passphrase")
+	}
+	t.Setenv("TUNNEL9_TEST_PASSPHRASE", "correct horse")
+	SetPassphrases(map[string]string{keyPath: "env:TUNNEL9_TEST_PASSPHRASE"})
+	if _, err := loadPrivateKey(nil, keyPath); err != nil {
+		t.Errorf("expected the passphrase to unlock the key, got %v", err)
+	}
+}
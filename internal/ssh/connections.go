@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ConnInfo describes one forwarded client connection
+type ConnInfo struct {
+	ID       int64
+	Client   string // Local client address, e.g. 127.0.0.1:53122
+	Target   string // Remote endpoint the connection was forwarded to
+	Via      string // SSH server carrying the connection
+	Since    time.Time
+	BytesIn  int64
+	BytesOut int64
+}
+
+// Duration is how long the connection has been open
+func (c ConnInfo) Duration() time.Duration {
+	return time.Since(c.Since)
+}
+
+// trackConn records a new forwarded connection
+func (t *Tunnel) trackConn(client string, target string, via string) *ConnInfo {
+	info := &ConnInfo{
+		ID:     atomic.AddInt64(&t.nextConnID, 1),
+		Client: client,
+		Target: target,
+		Via:    via,
+		Since:  time.Now(),
+	}
+
+	t.connsMu.Lock()
+	if t.conns == nil {
+		t.conns = make(map[int64]*ConnInfo)
+	}
+	t.conns[info.ID] = info
+	t.connsMu.Unlock()
+	return info
+}
+
+func (t *Tunnel) untrackConn(info *ConnInfo) {
+	t.connsMu.Lock()
+	delete(t.conns, info.ID)
+	t.connsMu.Unlock()
+}
+
+// Connections returns a snapshot of the tunnel's open connections, oldest first
+func (t *Tunnel) Connections() []ConnInfo {
+	t.connsMu.Lock()
+	conns := make([]ConnInfo, 0, len(t.conns))
+	for _, info := range t.conns {
+		snapshot := *info
+		snapshot.BytesIn = atomic.LoadInt64(&info.BytesIn)
+		snapshot.BytesOut = atomic.LoadInt64(&info.BytesOut)
+		conns = append(conns, snapshot)
+	}
+	t.connsMu.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].ID < conns[j].ID
+	})
+	return conns
+}
+
+// Connections returns the open connections of a running tunnel
+func (tm *TunnelManager) Connections(id string) []ConnInfo {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return nil
+	}
+	return tunnel.Connections()
+}
@@ -0,0 +1,202 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialClient logs in to the SSH server at address like ssh.Dial, racing its
+// addresses and marking the connection with the tunnel's DSCP
+func (t *Tunnel) dialClient(address string, clientConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	dialer := &net.Dialer{}
+	t.markDSCP(dialer)
+	conn, err := t.dialTCP(dialer, address, clientConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// How long an address gets before the next one joins the race, as RFC 8305
+// recommends
+var connectionAttemptDelay = 250 * time.Millisecond
+
+// How long the address that won a race is tried first
+const fastestAddressTTL = 30 * time.Minute
+
+// fastestAddresses remembers, by host:port, the address that connected
+// first last time, so a broken IPv6 path isn't raced on every connect
+var fastestAddresses = struct {
+	sync.Mutex
+	byHost map[string]fastestAddress
+}{byHost: make(map[string]fastestAddress)}
+
+type fastestAddress struct {
+	ip    string
+	since time.Time
+}
+
+func rememberFastest(address string, ip string) {
+	fastestAddresses.Lock()
+	defer fastestAddresses.Unlock()
+	fastestAddresses.byHost[address] = fastestAddress{ip: ip, since: time.Now()}
+}
+
+func rememberedFastest(address string) string {
+	fastestAddresses.Lock()
+	defer fastestAddresses.Unlock()
+	fastest, ok := fastestAddresses.byHost[address]
+	if !ok || time.Since(fastest.since) > fastestAddressTTL {
+		return ""
+	}
+	return fastest.ip
+}
+
+// raceOrder puts the addresses in the order they join the race: the one
+// remembered as fastest first, then alternating between IPv6 and IPv4 so one
+// broken family can't hold up the other
+func raceOrder(ips []net.IP, fastest string) []net.IP {
+	var first []net.IP
+	var v6, v4 []net.IP
+	for _, ip := range ips {
+		switch {
+		case ip.String() == fastest:
+			first = append(first, ip)
+		case ip.To4() == nil:
+			v6 = append(v6, ip)
+		default:
+			v4 = append(v4, ip)
+		}
+	}
+
+	// Start with the family the resolver listed first
+	if len(ips) > 0 && ips[0].To4() != nil {
+		v6, v4 = v4, v6
+	}
+	ordered := first
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
+		}
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+	return ordered
+}
+
+// dialTCP connects to a host:port. A host with several addresses is dialed
+// Happy Eyeballs style, starting on the next address whenever the last one
+// hasn't connected within connectionAttemptDelay, and the first to connect
+// wins and is tried first next time.
+func (t *Tunnel) dialTCP(dialer *net.Dialer, address string, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	addrs, err := resolverFor(host).LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	if len(ips) == 1 {
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(ips[0].String(), port))
+	}
+
+	conn, ip, err := raceDial(ctx, dialer, raceOrder(ips, rememberedFastest(address)), port)
+	if err != nil {
+		return nil, err
+	}
+	if ip != rememberedFastest(address) {
+		t.infof("Connected to %s through %s, the fastest of %d addresses", host, ip, len(ips))
+		rememberFastest(address, ip)
+	}
+	return conn, nil
+}
+
+type raceResult struct {
+	conn net.Conn
+	ip   string
+	err  error
+}
+
+// raceDial dials the addresses in order, staggered by connectionAttemptDelay
+// or sooner when an attempt fails, and returns the first connection made
+func raceDial(ctx context.Context, dialer *net.Dialer, ips []net.IP, port string) (net.Conn, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan raceResult, len(ips))
+	attempt := func(ip net.IP) {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		results <- raceResult{conn: conn, ip: ip.String(), err: err}
+	}
+
+	next, pending := 0, 0
+	var errs []error
+	for {
+		if next < len(ips) {
+			go attempt(ips[next])
+			next++
+			pending++
+		}
+		if pending == 0 {
+			return nil, "", fmt.Errorf("all %d addresses failed: %w", len(ips), errs[0])
+		}
+
+		var stagger <-chan time.Time
+		if next < len(ips) {
+			stagger = time.After(connectionAttemptDelay)
+		}
+		select {
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				errs = append(errs, result.err)
+				// Don't wait out the delay for a failure
+				continue
+			}
+			// Losers still dialing are cancelled, and closed if they connected anyway
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					if late := <-results; late.conn != nil {
+						late.conn.Close()
+					}
+				}
+			}(pending)
+			return result.conn, result.ip, nil
+		case <-stagger:
+		case <-ctx.Done():
+			// Wait for the attempts to give up, they see the same context
+			for ; pending > 0; pending-- {
+				if late := <-results; late.conn != nil {
+					late.conn.Close()
+				}
+			}
+			return nil, "", ctx.Err()
+		}
+	}
+}
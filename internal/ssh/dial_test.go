@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRaceOrder(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("2001:db8::2"),
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("192.0.2.2"),
+	}
+	order := func(ips []net.IP) string {
+		names := make([]string, len(ips))
+		for i, ip := range ips {
+			names[i] = ip.String()
+		}
+		return strings.Join(names, " ")
+	}
+
+	if got := order(raceOrder(ips, "")); got != "2001:db8::1 192.0.2.1 2001:db8::2 192.0.2.2" {
+		t.Errorf("expected the families interleaved, got %s", got)
+	}
+	if got := order(raceOrder(ips, "192.0.2.2")); got != "192.0.2.2 2001:db8::1 192.0.2.1 2001:db8::2" {
+		t.Errorf("expected the remembered address first, got %s", got)
+	}
+	if got := order(raceOrder([]net.IP{ips[2], ips[0]}, "")); got != "192.0.2.1 2001:db8::1" {
+		t.Errorf("expected the resolver's first family first, got %s", got)
+	}
+}
+
+func TestRaceDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	defer func(delay time.Duration) { connectionAttemptDelay = delay }(connectionAttemptDelay)
+	connectionAttemptDelay = 20 * time.Millisecond
+
+	// The first address hangs like a broken IPv6 path would
+	dialer := &net.Dialer{Control: func(_ string, address string, _ syscall.RawConn) error {
+		if strings.HasPrefix(address, "127.0.0.2:") {
+			time.Sleep(2 * time.Second)
+		}
+		return nil
+	}}
+	start := time.Now()
+	conn, ip, err := raceDial(context.Background(), dialer, []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}, port)
+	if err != nil {
+		t.Fatalf("expected the second address to connect: %v", err)
+	}
+	conn.Close()
+	if ip != "127.0.0.1" || time.Since(start) > time.Second {
+		t.Errorf("expected 127.0.0.1 to win without waiting for the first, got %s after %s", ip, time.Since(start))
+	}
+
+	listener.Close()
+	if _, _, err := raceDial(context.Background(), &net.Dialer{}, []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}, port); err == nil {
+		t.Errorf("expected an error when no address connects")
+	}
+}
@@ -0,0 +1,335 @@
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// How long a probe waits for a port to answer, and how many run at once
+const (
+	discoveryDialTimeout = 2 * time.Second
+	discoveryWorkers     = 32
+)
+
+// DiscoveredService is a service found behind a tunnel's SSH server, that a
+// tunnel through the same server could reach
+type DiscoveredService struct {
+	Name   string // From /etc/hosts, Consul or Kubernetes, the address when scanned
+	Host   string
+	Port   int
+	Source string // The discovery source that found it
+}
+
+// Address is the service's host:port
+func (s DiscoveredService) Address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// discoveryHost is what discovery needs from the SSH server it runs on
+type discoveryHost interface {
+	probe(address string) error
+	run(command string) ([]byte, error)
+	httpClient() *http.Client
+}
+
+// sshDiscoveryHost discovers over an established SSH connection
+type sshDiscoveryHost struct {
+	client *ssh.Client
+}
+
+// probe dials an address from the server, giving up after a while as the
+// server may take minutes to time out a filtered port
+func (h sshDiscoveryHost) probe(address string) error {
+	result := make(chan error, 1)
+	go func() {
+		conn, err := h.client.Dial("tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(discoveryDialTimeout):
+		return fmt.Errorf("%s did not answer", address)
+	}
+}
+
+func (h sshDiscoveryHost) run(command string) ([]byte, error) {
+	session, err := h.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	return session.Output(command)
+}
+
+func (h sshDiscoveryHost) httpClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				return h.client.Dial(network, addr)
+			},
+		},
+	}
+}
+
+// DiscoverServices looks for services from the SSH server a running tunnel
+// logs in to, through each configured source in turn. A source that fails
+// doesn't stop the others, its error is returned alongside what they found.
+func (tm *TunnelManager) DiscoverServices(id string, cfg config.DiscoveryConfig) ([]DiscoveredService, []error) {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return nil, []error{fmt.Errorf("tunnel is not running")}
+	}
+	tunnel.clientMu.RLock()
+	client := tunnel.Client
+	tunnel.clientMu.RUnlock()
+	if client == nil {
+		return nil, []error{fmt.Errorf("tunnel is not connected")}
+	}
+	return discoverServices(sshDiscoveryHost{client: client}, cfg)
+}
+
+func discoverServices(host discoveryHost, cfg config.DiscoveryConfig) ([]DiscoveredService, []error) {
+	var found []DiscoveredService
+	var errs []error
+	seen := make(map[string]bool)
+	for _, source := range cfg.DiscoverySources() {
+		var services []DiscoveredService
+		var err error
+		switch source {
+		case config.DiscoveryScan:
+			services = probeServices(host, subnetTargets(cfg.Subnets), cfg.Ports, source)
+		case config.DiscoveryHosts:
+			var out []byte
+			if out, err = host.run("cat /etc/hosts"); err == nil {
+				services = probeServices(host, parseHostsFile(string(out)), cfg.Ports, source)
+			}
+		case config.DiscoveryConsul:
+			services, err = consulServices(host.httpClient(), cfg.ConsulAddress())
+		case config.DiscoveryKube:
+			var out []byte
+			if out, err = host.run("kubectl get endpoints --all-namespaces -o json"); err == nil {
+				services, err = parseKubeEndpoints(out)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+
+		// The first source to find an address names it
+		for _, service := range services {
+			if !seen[service.Address()] {
+				seen[service.Address()] = true
+				found = append(found, service)
+			}
+		}
+	}
+	return found, errs
+}
+
+// probeServices dials every port of every target, returning those that
+// answered in the order given
+func probeServices(host discoveryHost, targets []DiscoveredService, ports []int, source string) []DiscoveredService {
+	candidates := make([]DiscoveredService, 0, len(targets)*len(ports))
+	for _, target := range targets {
+		for _, port := range ports {
+			candidates = append(candidates, DiscoveredService{Name: target.Name, Host: target.Host, Port: port, Source: source})
+		}
+	}
+
+	open := make([]bool, len(candidates))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < discoveryWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				open[i] = host.probe(candidates[i].Address()) == nil
+			}
+		}()
+	}
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	services := make([]DiscoveredService, 0)
+	for i, candidate := range candidates {
+		if open[i] {
+			services = append(services, candidate)
+		}
+	}
+	return services
+}
+
+// subnetTargets lists the host addresses of the subnets, named after
+// themselves
+func subnetTargets(subnets []string) []DiscoveredService {
+	targets := make([]DiscoveredService, 0)
+	for _, subnet := range subnets {
+		_, network, err := net.ParseCIDR(subnet)
+		if err != nil {
+			continue
+		}
+		ones, bits := network.Mask.Size()
+		if bits-ones > config.MaxDiscoverySubnetBits {
+			continue
+		}
+		count := 1 << (bits - ones)
+		for i := 0; i < count; i++ {
+			// Skip the network and broadcast addresses of IPv4 subnets
+			if bits == 32 && count > 2 && (i == 0 || i == count-1) {
+				continue
+			}
+			ip := make(net.IP, len(network.IP))
+			copy(ip, network.IP)
+			for b, carry := len(ip)-1, i; b >= 0 && carry > 0; b-- {
+				sum := int(ip[b]) + carry&0xff
+				ip[b] = byte(sum)
+				carry = carry>>8 + sum>>8
+			}
+			targets = append(targets, DiscoveredService{Name: ip.String(), Host: ip.String()})
+		}
+	}
+	return targets
+}
+
+// parseHostsFile lists the hosts of an /etc/hosts file, leaving out loopback
+// and other special addresses
+func parseHostsFile(data string) []DiscoveredService {
+	targets := make([]DiscoveredService, 0)
+	for _, line := range strings.Split(data, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		targets = append(targets, DiscoveredService{Name: fields[1], Host: ip.String()})
+	}
+	return targets
+}
+
+// consulServices lists every instance of every service registered in Consul
+func consulServices(client *http.Client, address string) ([]DiscoveredService, error) {
+	get := func(path string, v interface{}) error {
+		resp, err := client.Get("http://" + address + path)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s answered %s", path, resp.Status)
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	var catalog map[string][]string
+	if err := get("/v1/catalog/services", &catalog); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		if name != "consul" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	services := make([]DiscoveredService, 0, len(names))
+	for _, name := range names {
+		var instances []struct {
+			Node           string
+			Address        string
+			ServiceAddress string
+			ServicePort    int
+		}
+		if err := get("/v1/catalog/service/"+url.PathEscape(name), &instances); err != nil {
+			return services, err
+		}
+		for _, instance := range instances {
+			service := DiscoveredService{Name: name, Host: instance.ServiceAddress, Port: instance.ServicePort, Source: config.DiscoveryConsul}
+			if service.Host == "" {
+				service.Host = instance.Address
+			}
+			if len(instances) > 1 {
+				service.Name = name + "-" + instance.Node
+			}
+			services = append(services, service)
+		}
+	}
+	return services, nil
+}
+
+// parseKubeEndpoints lists the TCP endpoints in kubectl's JSON output, the
+// first ready address of each
+func parseKubeEndpoints(data []byte) ([]DiscoveredService, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Subsets []struct {
+				Addresses []struct {
+					IP string `json:"ip"`
+				} `json:"addresses"`
+				Ports []struct {
+					Name     string `json:"name"`
+					Port     int    `json:"port"`
+					Protocol string `json:"protocol"`
+				} `json:"ports"`
+			} `json:"subsets"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing kubectl output: %w", err)
+	}
+
+	services := make([]DiscoveredService, 0)
+	for _, item := range list.Items {
+		for _, subset := range item.Subsets {
+			if len(subset.Addresses) == 0 {
+				continue
+			}
+			for _, port := range subset.Ports {
+				if port.Protocol != "" && port.Protocol != "TCP" {
+					continue
+				}
+				name := item.Metadata.Namespace + "-" + item.Metadata.Name
+				if len(subset.Ports) > 1 && port.Name != "" {
+					name += "-" + port.Name
+				}
+				services = append(services, DiscoveredService{
+					Name:   name,
+					Host:   subset.Addresses[0].IP,
+					Port:   port.Port,
+					Source: config.DiscoveryKube,
+				})
+			}
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,129 @@
+package ssh
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+// fakeDiscoveryHost answers probes on the open addresses and runs commands
+// from a table
+type fakeDiscoveryHost struct {
+	open     map[string]bool
+	commands map[string]string
+	client   *http.Client
+}
+
+func (h fakeDiscoveryHost) probe(address string) error {
+	if h.open[address] {
+		return nil
+	}
+	return errors.New("connection refused")
+}
+
+func (h fakeDiscoveryHost) run(command string) ([]byte, error) {
+	out, ok := h.commands[command]
+	if !ok {
+		return nil, errors.New("command not found")
+	}
+	return []byte(out), nil
+}
+
+func (h fakeDiscoveryHost) httpClient() *http.Client {
+	return h.client
+}
+
+func TestSubnetTargets(t *testing.T) {
+	targets := subnetTargets([]string{"10.0.0.254/23"})
+	if len(targets) != 510 {
+		t.Fatalf("expected 510 host addresses, got %d", len(targets))
+	}
+	if targets[0].Host != "10.0.0.1" || targets[255].Host != "10.0.1.0" || targets[509].Host != "10.0.1.254" {
+		t.Errorf("unexpected addresses %v, %v, %v", targets[0], targets[255], targets[509])
+	}
+	if targets := subnetTargets([]string{"10.0.0.5/32"}); len(targets) != 1 || targets[0].Host != "10.0.0.5" {
+		t.Errorf("expected a single address, got %v", targets)
+	}
+}
+
+func TestParseHostsFile(t *testing.T) {
+	targets := parseHostsFile(`127.0.0.1 localhost
+::1 ip6-localhost
+10.0.0.5   db.internal db # primary
+# 10.0.0.6 old
+10.0.0.7 cache.internal
+`)
+	if len(targets) != 2 || targets[0].Name != "db.internal" || targets[1].Host != "10.0.0.7" {
+		t.Errorf("expected db and cache, got %v", targets)
+	}
+}
+
+func TestParseKubeEndpoints(t *testing.T) {
+	services, err := parseKubeEndpoints([]byte(`{"items": [
+		{"metadata": {"name": "api", "namespace": "prod"},
+		 "subsets": [{"addresses": [{"ip": "10.1.0.4"}, {"ip": "10.1.0.5"}],
+		              "ports": [{"name": "http", "port": 8080, "protocol": "TCP"}, {"name": "dns", "port": 53, "protocol": "UDP"}]}]},
+		{"metadata": {"name": "pending", "namespace": "prod"}, "subsets": [{"ports": [{"port": 80}]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Name != "prod-api-http" || services[0].Address() != "10.1.0.4:8080" {
+		t.Errorf("expected the api endpoint only, got %v", services)
+	}
+}
+
+func TestConsulServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/catalog/services":
+			json.NewEncoder(w).Encode(map[string][]string{"consul": nil, "postgres": {"primary"}})
+		case "/v1/catalog/service/postgres":
+			w.Write([]byte(`[{"Node": "db1", "Address": "10.0.0.5", "ServiceAddress": "", "ServicePort": 5432}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	services, err := consulServices(server.Client(), strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Name != "postgres" || services[0].Address() != "10.0.0.5:5432" {
+		t.Errorf("expected postgres on the node's address, got %v", services)
+	}
+}
+
+func TestDiscoverServices(t *testing.T) {
+	host := fakeDiscoveryHost{
+		open: map[string]bool{"10.0.0.5:5432": true, "10.0.0.9:6379": true},
+		commands: map[string]string{
+			"cat /etc/hosts": "10.0.0.5 db.internal\n",
+		},
+	}
+	cfg := config.DiscoveryConfig{
+		Sources: []string{config.DiscoveryHosts, config.DiscoveryScan, config.DiscoveryKube},
+		Subnets: []string{"10.0.0.0/28"},
+		Ports:   []int{5432, 6379},
+	}
+
+	services, errs := discoverServices(host, cfg)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "kube:") {
+		t.Errorf("expected kubectl to fail on its own, got %v", errs)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected two services, got %v", services)
+	}
+	if services[0].Name != "db.internal" || services[0].Source != config.DiscoveryHosts {
+		t.Errorf("expected the hosts file to name the database, got %v", services[0])
+	}
+	if services[1].Address() != "10.0.0.9:6379" || services[1].Source != config.DiscoveryScan {
+		t.Errorf("expected the scan to find the cache, got %v", services[1])
+	}
+}
@@ -0,0 +1,41 @@
+package ssh
+
+import (
+	"net"
+	"syscall"
+
+	"tunnel9/internal/config"
+)
+
+// dscp is the codepoint the tunnel's SSH connection is marked with, 0 for
+// unmarked
+func (t *Tunnel) dscp() int {
+	if t.Config.DSCP == "" {
+		return 0
+	}
+	codepoint, err := config.ParseDSCP(t.Config.DSCP)
+	if err != nil {
+		return 0
+	}
+	return codepoint
+}
+
+// markDSCP has the dialer mark the packets of its connections with the
+// tunnel's DSCP, so QoS policies can tell interactive tunnels from bulk ones
+func (t *Tunnel) markDSCP(dialer *net.Dialer) {
+	codepoint := t.dscp()
+	if codepoint == 0 {
+		return
+	}
+	dialer.Control = func(network string, _ string, conn syscall.RawConn) error {
+		var err error
+		conn.Control(func(fd uintptr) {
+			err = setTOS(fd, network, codepoint<<2)
+		})
+		if err != nil {
+			// Unmarked is better than not connected
+			t.warnf("Failed to set DSCP %s: %v", t.Config.DSCP, err)
+		}
+		return nil
+	}
+}
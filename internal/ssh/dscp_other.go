@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ssh
+
+import "fmt"
+
+// setTOS isn't supported here, Windows only marks packets through its own
+// QoS policies
+func setTOS(fd uintptr, network string, tos int) error {
+	return fmt.Errorf("not supported on this platform")
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package ssh
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestSetTOS(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	conn, err := net.Dial("tcp4", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tos int
+	raw.Control(func(fd uintptr) {
+		if err = setTOS(fd, "tcp4", 46<<2); err == nil {
+			tos, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+		}
+	})
+	if err != nil || tos != 0xb8 {
+		t.Errorf("expected EF (0xb8) to be set, got %#x (%v)", tos, err)
+	}
+}
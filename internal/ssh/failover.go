@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// How often the active and primary targets are probed
+var failoverCheckInterval = 30 * time.Second
+
+// targetCount is the primary plus any standby targets
+func (t *Tunnel) targetCount() int {
+	return 1 + len(t.Config.Standby)
+}
+
+// targetConfig returns the tunnel config as seen through target i, where 0 is
+// the primary and standby targets inherit anything they leave empty
+func (t *Tunnel) targetConfig(i int) config.TunnelConfig {
+	tc := t.Config
+	if i <= 0 || i > len(t.Config.Standby) {
+		return tc
+	}
+
+	standby := t.Config.Standby[i-1]
+	if standby.RemoteHost != "" {
+		tc.RemoteHost = standby.RemoteHost
+	}
+	if standby.RemotePort != 0 {
+		tc.RemotePort = standby.RemotePort
+	}
+	if standby.Bastion != "" {
+		bastion := NewEndpointFromString(standby.Bastion)
+		tc.Bastion.Host = bastion.Host
+		tc.Bastion.Port = bastion.Port
+		if bastion.User != "" {
+			tc.Bastion.User = bastion.User
+		}
+	}
+	return tc
+}
+
+// currentTarget resolves the endpoints and client config of the target in use
+func (t *Tunnel) currentTarget(sshconfig *ssh.ClientConfig) (*Endpoint, *Endpoint, *ssh.ClientConfig) {
+	index := t.targetIndex
+	tc := t.targetConfig(index)
+	sshEndpoint, remoteEndpoint := figureOutRemoteVsBastion(tc)
+
+	// A standby bastion may log in as someone else
+	if index > 0 && tc.Bastion.User != "" && tc.Bastion.User != sshconfig.User {
+		clientConfig := *sshconfig
+		clientConfig.User = tc.Bastion.User
+		return sshEndpoint, remoteEndpoint, &clientConfig
+	}
+	return sshEndpoint, remoteEndpoint, sshconfig
+}
+
+// advanceTarget switches to the next target, reporting false when there is
+// no standby to switch to
+func (t *Tunnel) advanceTarget(reason error) bool {
+	if t.targetCount() < 2 {
+		return false
+	}
+	t.targetIndex = (t.targetIndex + 1) % t.targetCount()
+	sshEndpoint, remoteEndpoint := figureOutRemoteVsBastion(t.targetConfig(t.targetIndex))
+	t.warnf("Failing over to target %d/%d (%s via %s) after: %v",
+		t.targetIndex+1, t.targetCount(), remoteEndpoint.String(), sshEndpoint.String(), reason)
+	return true
+}
+
+// OnStandby reports whether the tunnel is currently using a standby target
+func (t *Tunnel) OnStandby() bool {
+	t.clientMu.RLock()
+	defer t.clientMu.RUnlock()
+	return t.targetIndex > 0
+}
+
+// probe checks that a target's SSH server accepts TCP connections
+func probe(tc config.TunnelConfig) error {
+	sshEndpoint, _ := figureOutRemoteVsBastion(tc)
+	conn, err := net.DialTimeout("tcp", sshEndpoint.String(), 3*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// watchFailover health-checks the target in use, failing over when it goes
+// away and failing back as soon as the primary is reachable again
+func (t *Tunnel) watchFailover() {
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		t.clientMu.RLock()
+		index := t.targetIndex
+		t.clientMu.RUnlock()
+
+		if index > 0 && probe(t.targetConfig(0)) == nil {
+			t.infof("Primary target reachable again, failing back")
+			t.switchTarget(func() { t.targetIndex = 0 }, "primary restored")
+			continue
+		}
+
+		if err := probe(t.targetConfig(index)); err != nil {
+			t.switchTarget(func() { t.advanceTarget(err) }, fmt.Sprintf("failover: %v", err))
+		}
+	}
+}
+
+// switchTarget changes target and drops the current client so the next
+// connection is made to the new target
+func (t *Tunnel) switchTarget(change func(), message string) {
+	t.clientMu.Lock()
+	change()
+	t.closeClient(t.Client, false)
+	t.Client = nil
+	t.clientMu.Unlock()
+	t.updateStatus("active", message)
+}
@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestTargetConfig(t *testing.T) {
+	tunnel := &Tunnel{}
+	tunnel.Config.RemoteHost = "db-primary.internal"
+	tunnel.Config.RemotePort = 5432
+	tunnel.Config.Bastion.Host = "jump-a.example.com"
+	tunnel.Config.Bastion.User = "alice"
+	tunnel.Config.Standby = []config.StandbyTarget{
+		{RemoteHost: "db-replica.internal"},
+		{Bastion: "bob@jump-b.example.com:2222"},
+	}
+
+	if tunnel.targetCount() != 3 {
+		t.Fatalf("expected 3 targets, got %d", tunnel.targetCount())
+	}
+
+	primary := tunnel.targetConfig(0)
+	if primary.RemoteHost != "db-primary.internal" || primary.Bastion.Host != "jump-a.example.com" {
+		t.Errorf("unexpected primary: %+v", primary)
+	}
+
+	replica := tunnel.targetConfig(1)
+	if replica.RemoteHost != "db-replica.internal" || replica.RemotePort != 5432 || replica.Bastion.Host != "jump-a.example.com" {
+		t.Errorf("standby should inherit port and bastion: %+v", replica)
+	}
+
+	viaB := tunnel.targetConfig(2)
+	if viaB.RemoteHost != "db-primary.internal" || viaB.Bastion.Host != "jump-b.example.com" || viaB.Bastion.Port != 2222 || viaB.Bastion.User != "bob" {
+		t.Errorf("standby bastion not applied: %+v", viaB)
+	}
+
+	// Switching to the second standby logs in as its user
+	tunnel.targetIndex = 2
+	sshEndpoint, _, clientConfig := tunnel.currentTarget(&ssh.ClientConfig{User: "alice"})
+	if sshEndpoint.String() != "jump-b.example.com:2222" || clientConfig.User != "bob" {
+		t.Errorf("unexpected current target %s as %s", sshEndpoint.String(), clientConfig.User)
+	}
+
+	// Advancing wraps back round to the primary
+	if !tunnel.advanceTarget(nil) || tunnel.targetIndex != 0 {
+		t.Errorf("expected to wrap back to primary, got index %d", tunnel.targetIndex)
+	}
+}
@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HandshakeFailures counts a tunnel's failed SSH logins by cause, so
+// credentials that keep expiring stand apart from a flaky network
+type HandshakeFailures struct {
+	Auth    int64 // Rejected credentials
+	Network int64 // Server unreachable, or the connection dropped mid-handshake
+	HostKey int64 // Unknown key rejected, or a key that changed
+}
+
+// Total is every failure, whatever the cause
+func (f HandshakeFailures) Total() int64 {
+	return f.Auth + f.Network + f.HostKey
+}
+
+func (f HandshakeFailures) String() string {
+	if f.Total() == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, 3)
+	for _, count := range []struct {
+		n    int64
+		kind string
+	}{{f.Auth, "auth"}, {f.Network, "network"}, {f.HostKey, "host key"}} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.kind))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handshakeCounters is HandshakeFailures as the tunnel updates it
+type handshakeCounters struct {
+	auth    int64
+	network int64
+	hostKey int64
+}
+
+func (c *handshakeCounters) snapshot() HandshakeFailures {
+	return HandshakeFailures{
+		Auth:    atomic.LoadInt64(&c.auth),
+		Network: atomic.LoadInt64(&c.network),
+		HostKey: atomic.LoadInt64(&c.hostKey),
+	}
+}
+
+// hostKeyError is how a rejected server key comes out of the handshake
+type hostKeyError struct {
+	err error
+}
+
+func (e *hostKeyError) Error() string { return e.err.Error() }
+func (e *hostKeyError) Unwrap() error { return e.err }
+
+// rejectingHostKeys marks what a host key callback rejects, so it can be
+// told apart from other handshake failures
+func rejectingHostKeys(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := callback(hostname, remote, key); err != nil {
+			return &hostKeyError{err: err}
+		}
+		return nil
+	}
+}
+
+// countHandshakeFailure records why logging in to the SSH server failed
+func (t *Tunnel) countHandshakeFailure(err error) {
+	var hostKey *hostKeyError
+	switch {
+	case errors.As(err, &hostKey):
+		atomic.AddInt64(&t.handshakes.hostKey, 1)
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		atomic.AddInt64(&t.handshakes.auth, 1)
+	default:
+		atomic.AddInt64(&t.handshakes.network, 1)
+	}
+}
@@ -0,0 +1,42 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCountHandshakeFailure(t *testing.T) {
+	tunnel := &Tunnel{}
+	rejected := rejectingHostKeys(func(string, net.Addr, ssh.PublicKey) error {
+		return errors.New("host key mismatch")
+	})(
+		"example.com:22", &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}, nil)
+
+	tunnel.countHandshakeFailure(fmt.Errorf("ssh: handshake failed: %w", rejected))
+	tunnel.countHandshakeFailure(errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"))
+	tunnel.countHandshakeFailure(errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"))
+	tunnel.countHandshakeFailure(errors.New("dial tcp 192.0.2.10:22: connect: connection refused"))
+
+	got := tunnel.handshakes.snapshot()
+	want := HandshakeFailures{Auth: 2, Network: 1, HostKey: 1}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got.String() != "2 auth, 1 network, 1 host key" {
+		t.Errorf("unexpected summary %q", got.String())
+	}
+	if (HandshakeFailures{}).String() != "none" {
+		t.Errorf("expected none without failures, got %q", HandshakeFailures{}.String())
+	}
+}
+
+func TestRejectingHostKeysPassesAccepted(t *testing.T) {
+	accept := rejectingHostKeys(func(string, net.Addr, ssh.PublicKey) error { return nil })
+	if err := accept("example.com:22", nil, nil); err != nil {
+		t.Errorf("expected an accepted key to pass, got %v", err)
+	}
+}
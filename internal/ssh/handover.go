@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// A daemon upgrading in place hands its listening sockets to the new binary,
+// so clients never see the port closed. The old process stops accepting and
+// lets the connections it forwards finish; the new one accepts from then on.
+
+// fileSocket is a socket that can be duplicated into a file for another
+// process, like *net.TCPListener and *net.UDPConn
+type fileSocket interface {
+	File() (*os.File, error)
+}
+
+// ListenerFile duplicates a running tunnel's listening socket, for handing
+// it to another process
+func (tm *TunnelManager) ListenerFile(id string) (*os.File, error) {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return nil, fmt.Errorf("no tunnel %s", id)
+	}
+
+	tunnel.listenerMu.Lock()
+	defer tunnel.listenerMu.Unlock()
+	var socket interface{} = tunnel.Listener
+	if tunnel.Config.IsUDP() {
+		socket = tunnel.PacketConn
+	}
+	s, ok := socket.(fileSocket)
+	if !ok {
+		return nil, fmt.Errorf("tunnel %s has no socket to hand over", id)
+	}
+	return s.File()
+}
+
+// Inherit makes the next start of a tunnel use a socket handed over by a
+// previous process, a net.Listener or net.PacketConn, instead of binding one
+func (tm *TunnelManager) Inherit(id string, socket io.Closer) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if previous, exists := tm.inherited[id]; exists {
+		previous.Close()
+	}
+	tm.inherited[id] = socket
+}
+
+// takeInherited returns the socket handed over for a tunnel, if it still
+// fits its config. One that doesn't is closed so a fresh one can be bound.
+func (tm *TunnelManager) takeInherited(tunnel *Tunnel) io.Closer {
+	tm.mu.Lock()
+	socket, exists := tm.inherited[tunnel.ID]
+	delete(tm.inherited, tunnel.ID)
+	tm.mu.Unlock()
+	if !exists {
+		return nil
+	}
+
+	var addr net.Addr
+	switch s := socket.(type) {
+	case net.PacketConn:
+		if tunnel.Config.IsUDP() {
+			addr = s.LocalAddr()
+		}
+	case net.Listener:
+		if !tunnel.Config.IsUDP() {
+			addr = s.Addr()
+		}
+	}
+	// A tunnel that picks its port keeps the one it had
+	if addr != nil {
+		if _, port, err := net.SplitHostPort(addr.String()); err == nil && (port == strconv.Itoa(tunnel.Config.LocalPort) || tunnel.Config.PicksPort()) {
+			return socket
+		}
+	}
+	socket.Close()
+	return nil
+}
+
+// Release stops a tunnel from accepting connections while the ones it
+// forwards carry on. UDP tunnels have no connections to finish and stop.
+func (tm *TunnelManager) Release(id string) {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return
+	}
+	if tunnel.Config.IsUDP() {
+		tm.StopTunnel(id)
+		return
+	}
+
+	// The accept loop sees the listener closed and returns quietly
+	tunnel.listenerMu.Lock()
+	if tunnel.Listener != nil {
+		tunnel.Listener.Close()
+	}
+	tunnel.listenerMu.Unlock()
+}
+
+// ActiveConnections counts the local connections being forwarded by every
+// tunnel
+func (tm *TunnelManager) ActiveConnections() int {
+	total := 0
+	for _, tunnel := range tm.activeTunnels() {
+		total += int(atomic.LoadInt32(&tunnel.activeConns))
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package ssh
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestHandover(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+	tc := config.TunnelConfig{Name: "db", LocalPort: port, BindAddress: "127.0.0.1", RemoteHost: "db.internal", RemotePort: 5432}
+
+	old := NewTunnelManager()
+	defer old.Cleanup()
+	if err := old.StartTunnel(old.CreateTunnel("db", tc)); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	file, err := old.ListenerFile("db")
+	if err != nil {
+		t.Fatalf("failed to duplicate the listener: %v", err)
+	}
+	listener, err := net.FileListener(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new process starts on the same socket, the port can't be bound twice
+	successor := NewTunnelManager()
+	defer successor.Cleanup()
+	successor.Inherit("db", listener)
+	if err := successor.StartTunnel(successor.CreateTunnel("db", tc)); err != nil {
+		t.Fatalf("expected the inherited socket to be used, got %v", err)
+	}
+
+	old.Release("db")
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("expected the port to keep accepting, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestTakeInheritedMismatch(t *testing.T) {
+	tm := NewTunnelManager()
+	defer tm.Cleanup()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.Inherit("db", listener)
+
+	// The config moved to another port, so the old socket is closed
+	tunnel := &Tunnel{ID: "db", Config: config.TunnelConfig{LocalPort: 1}}
+	if socket := tm.takeInherited(tunnel); socket != nil {
+		t.Errorf("expected no socket for a different port, got %v", socket)
+	}
+	if _, err := listener.Accept(); err == nil {
+		t.Error("expected the stale socket to be closed")
+	}
+}
@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+// Health check results
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded" // A single failed probe, or the service answers with errors
+	HealthDown     = "down"     // Several probes in a row got no answer
+)
+
+// Health check defaults
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 5 * time.Second
+	healthFailuresDown    = 2 // Consecutive unanswered probes before a target is down
+)
+
+// healthCheckTiming returns how often a target is probed and how long a
+// probe may take
+func healthCheckTiming(hc config.HealthCheck) (time.Duration, time.Duration) {
+	interval, err := time.ParseDuration(hc.Interval)
+	if err != nil || interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	timeout, err := time.ParseDuration(hc.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	return interval, timeout
+}
+
+// probeHealth checks the service behind addr once. A TCP check only has to
+// connect, an HTTP check needs a response below 400 to be healthy and is
+// degraded on any other response.
+func probeHealth(hc config.HealthCheck, addr string, timeout time.Duration) (string, error) {
+	if hc.Protocol == "tcp" {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return HealthDown, err
+		}
+		conn.Close()
+		return HealthHealthy, nil
+	}
+
+	path := hc.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// The certificate is for the remote name, not the local endpoint
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(fmt.Sprintf("%s://%s%s", hc.Protocol, addr, path))
+	if err != nil {
+		return HealthDown, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return HealthDegraded, fmt.Errorf("%s %s", path, resp.Status)
+	}
+	return HealthHealthy, nil
+}
+
+// nextHealth turns a probe result into the state shown, so one lost probe
+// only degrades a target and it takes a few in a row to call it down
+func nextHealth(probe string, failures int) string {
+	if probe == HealthDown && failures < healthFailuresDown {
+		return HealthDegraded
+	}
+	return probe
+}
+
+// watchHealth probes the service behind the tunnel through the local
+// endpoint on an interval, which goes the whole way: listener, SSH
+// connection and remote target. Probes count as traffic, so they keep lazy
+// tunnels connected.
+func (t *Tunnel) watchHealth() {
+	interval, timeout := healthCheckTiming(t.Config.HealthCheck)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		addr, ok := t.localAddress()
+		if ok {
+			probe, err := probeHealth(t.Config.HealthCheck, addr, timeout)
+			if probe == HealthDown {
+				failures++
+			} else {
+				failures = 0
+			}
+			health := nextHealth(probe, failures)
+
+			t.Metrics.mu.Lock()
+			previous := t.Metrics.Health
+			t.Metrics.Health = health
+			t.Metrics.mu.Unlock()
+
+			if health != previous {
+				if err != nil {
+					t.warnf("Health check %s: %v", health, err)
+				} else {
+					t.infof("Health check %s", health)
+				}
+			}
+		}
+
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
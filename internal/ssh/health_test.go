@@ -0,0 +1,74 @@
+package ssh
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestProbeHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name  string
+		check config.HealthCheck
+		want  string
+	}{
+		{"tcp connects", config.HealthCheck{Protocol: "tcp"}, HealthHealthy},
+		{"http ok", config.HealthCheck{Protocol: "http", Path: "healthz"}, HealthHealthy},
+		{"http error status", config.HealthCheck{Protocol: "http", Path: "/missing"}, HealthDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := probeHealth(tt.check, addr, time.Second)
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// Nothing listening any more
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := listener.Addr().String()
+	listener.Close()
+	if got, err := probeHealth(config.HealthCheck{Protocol: "tcp"}, closed, time.Second); got != HealthDown || err == nil {
+		t.Errorf("expected down with an error, got %s (%v)", got, err)
+	}
+}
+
+func TestNextHealth(t *testing.T) {
+	if got := nextHealth(HealthDown, 1); got != HealthDegraded {
+		t.Errorf("expected a single failure to degrade, got %s", got)
+	}
+	if got := nextHealth(HealthDown, healthFailuresDown); got != HealthDown {
+		t.Errorf("expected repeated failures to be down, got %s", got)
+	}
+	if got := nextHealth(HealthHealthy, 0); got != HealthHealthy {
+		t.Errorf("expected healthy, got %s", got)
+	}
+}
+
+func TestHealthCheckTiming(t *testing.T) {
+	interval, timeout := healthCheckTiming(config.HealthCheck{Protocol: "tcp"})
+	if interval != defaultHealthInterval || timeout != defaultHealthTimeout {
+		t.Errorf("expected defaults, got %v and %v", interval, timeout)
+	}
+	interval, timeout = healthCheckTiming(config.HealthCheck{Interval: "10s", Timeout: "2s"})
+	if interval != 10*time.Second || timeout != 2*time.Second {
+		t.Errorf("expected 10s and 2s, got %v and %v", interval, timeout)
+	}
+}
@@ -0,0 +1,195 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Serialises writes to tunnel9's own known_hosts between tunnels
+var knownHostsMu sync.Mutex
+
+// Only one unknown key is put to the user at a time, so tunnels sharing a
+// host see the first answer instead of asking again
+var hostKeyPromptMu sync.Mutex
+
+// HostKeyPrompt asks whoever is driving the manager whether to trust a host
+// key seen for the first time. The connection waits until it is answered.
+type HostKeyPrompt struct {
+	TunnelID    string
+	Name        string
+	Hostname    string
+	KeyType     string
+	Fingerprint string
+	answer      chan bool
+}
+
+// Accept trusts the key and records it so later starts don't ask again
+func (p *HostKeyPrompt) Accept() {
+	p.respond(true)
+}
+
+// Reject refuses the key and fails the connection
+func (p *HostKeyPrompt) Reject() {
+	p.respond(false)
+}
+
+func (p *HostKeyPrompt) respond(trust bool) {
+	select {
+	case p.answer <- trust:
+	default:
+		// Already answered, or the tunnel gave up waiting
+	}
+}
+
+// knownHostsFiles returns the user's known_hosts and tunnel9's own, which
+// is where keys trusted on first use are recorded
+func knownHostsFiles() (string, string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"),
+		filepath.Join(home, ".local", "state", "tunnel9", "known_hosts"),
+		nil
+}
+
+// checkKnownHosts looks the key up in the given files, reporting whether the
+// host is known at all. A known host with a different key is an error.
+func checkKnownHosts(files []string, hostname string, remote net.Addr, key ssh.PublicKey) (bool, error) {
+	existing := make([]string, 0, len(files))
+	for _, path := range files {
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if len(existing) == 0 {
+		return false, nil
+	}
+
+	check, err := knownhosts.New(existing...)
+	if err != nil {
+		return false, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	err = check(hostname, remote, key)
+	if err == nil {
+		return true, nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return false, err
+	}
+	if len(keyErr.Want) > 0 {
+		known := keyErr.Want[0]
+		return true, fmt.Errorf("host key mismatch for %s: server sent %s %s, but %s:%d has %s, refusing to connect",
+			hostname,
+			key.Type(), ssh.FingerprintSHA256(key),
+			known.Filename, known.Line,
+			ssh.FingerprintSHA256(known.Key))
+	}
+	return false, nil
+}
+
+// recordHostKey appends a trusted key to tunnel9's known_hosts
+func recordHostKey(path string, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	return nil
+}
+
+// askHostKey hands the prompt to the front end and waits for its answer,
+// giving up if the tunnel is stopped in the meantime
+func (t *Tunnel) askHostKey(prompt *HostKeyPrompt) (bool, error) {
+	select {
+	case t.hostKeyPrompts <- prompt:
+	case <-t.stopChan:
+		return false, fmt.Errorf("tunnel stopped while verifying host key")
+	}
+
+	select {
+	case trust := <-prompt.answer:
+		return trust, nil
+	case <-t.stopChan:
+		return false, fmt.Errorf("tunnel stopped while verifying host key")
+	}
+}
+
+// hostKeyCallback checks server keys against ~/.ssh/known_hosts and
+// tunnel9's known_hosts. A changed key is rejected. An unknown host is put
+// to the user when the manager has prompting enabled, otherwise it is
+// trusted on first use, and remembered either way once trusted.
+func hostKeyCallback(t *Tunnel) ssh.HostKeyCallback {
+	return rejectingHostKeys(func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		userFile, ownFile, err := knownHostsFiles()
+		if err != nil {
+			return err
+		}
+		files := []string{userFile, ownFile}
+
+		knownHostsMu.Lock()
+		known, err := checkKnownHosts(files, hostname, remote, key)
+		knownHostsMu.Unlock()
+		if known || err != nil {
+			return err
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		if t.hostKeyPrompts != nil {
+			hostKeyPromptMu.Lock()
+			defer hostKeyPromptMu.Unlock()
+
+			// Another tunnel may have had this host accepted while we waited
+			knownHostsMu.Lock()
+			known, err := checkKnownHosts(files, hostname, remote, key)
+			knownHostsMu.Unlock()
+			if known || err != nil {
+				return err
+			}
+
+			t.logf("Waiting for confirmation of new host key for %s (%s %s)", hostname, key.Type(), fingerprint)
+			trust, err := t.askHostKey(&HostKeyPrompt{
+				TunnelID:    t.ID,
+				Name:        t.Config.Name,
+				Hostname:    hostname,
+				KeyType:     key.Type(),
+				Fingerprint: fingerprint,
+				answer:      make(chan bool, 1),
+			})
+			if err != nil {
+				return err
+			}
+			if !trust {
+				return fmt.Errorf("host key for %s rejected (%s %s)", hostname, key.Type(), fingerprint)
+			}
+		}
+
+		// Never seen this host, trust it and remember the key
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+		if err := recordHostKey(ownFile, hostname, key); err != nil {
+			return err
+		}
+		t.infof("Trusting new host key for %s (%s %s), saved to %s",
+			hostname, key.Type(), fingerprint, ownFile)
+		return nil
+	})
+}
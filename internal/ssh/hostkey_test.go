@@ -0,0 +1,97 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	callback := hostKeyCallback(&Tunnel{})
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 2222}
+	key := newHostKey(t)
+
+	// Unknown hosts are trusted on first use and recorded
+	if err := callback("jump.example.com:2222", remote, key); err != nil {
+		t.Fatalf("expected first connection to be trusted, got %v", err)
+	}
+	_, ownFile, _ := knownHostsFiles()
+	data, err := os.ReadFile(ownFile)
+	if err != nil || !strings.Contains(string(data), "[jump.example.com]:2222") {
+		t.Fatalf("expected key recorded in %s, got %q (%v)", ownFile, data, err)
+	}
+
+	// The same key is accepted afterwards
+	if err := callback("jump.example.com:2222", remote, key); err != nil {
+		t.Errorf("expected known key to be accepted, got %v", err)
+	}
+
+	// A different key for the same host is rejected
+	err = callback("jump.example.com:2222", remote, newHostKey(t))
+	if err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Errorf("expected host key mismatch, got %v", err)
+	}
+}
+
+func TestHostKeyCallbackPrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	prompts := make(chan *HostKeyPrompt)
+	tunnel := &Tunnel{ID: "t1", hostKeyPrompts: prompts, stopChan: make(chan struct{})}
+	tunnel.Config.Name = "db"
+	callback := hostKeyCallback(tunnel)
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+	key := newHostKey(t)
+
+	answer := func(accept bool) {
+		prompt := <-prompts
+		if prompt.Name != "db" || prompt.Fingerprint != ssh.FingerprintSHA256(key) {
+			t.Errorf("unexpected prompt %+v", prompt)
+		}
+		if accept {
+			prompt.Accept()
+		} else {
+			prompt.Reject()
+		}
+	}
+
+	// A rejected key fails the connection and is not remembered
+	go answer(false)
+	if err := callback("db.example.com:22", remote, key); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+
+	// An accepted key is recorded, so the next connection doesn't ask
+	go answer(true)
+	if err := callback("db.example.com:22", remote, key); err != nil {
+		t.Fatalf("expected accepted key to connect, got %v", err)
+	}
+	if err := callback("db.example.com:22", remote, key); err != nil {
+		t.Errorf("expected remembered key to be accepted without a prompt, got %v", err)
+	}
+
+	// Stopping the tunnel stops waiting for an answer
+	close(tunnel.stopChan)
+	if err := callback("other.example.com:22", remote, key); err == nil {
+		t.Error("expected stopped tunnel to give up on the prompt")
+	}
+}
@@ -0,0 +1,109 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// errScanned ends a handshake once the server's key is in hand
+var errScanned = errors.New("host key scanned")
+
+// HostKeyScan is the key a tunnel's SSH server presents, fetched without
+// logging in, so keys can be reviewed before any tunnel needs them
+type HostKeyScan struct {
+	Endpoint    string // SSH server as configured, host:port
+	Hostname    string // As recorded in known_hosts, after ~/.ssh/config
+	KeyType     string
+	Fingerprint string
+	Known       bool  // Already trusted in a known_hosts file
+	Err         error // Unreachable, or a key that doesn't match the one known
+	key         ssh.PublicKey
+	remote      net.Addr
+}
+
+// New reports whether the key was fetched and isn't trusted yet
+func (s *HostKeyScan) New() bool {
+	return s.key != nil && !s.Known && s.Err == nil
+}
+
+// ScanHostKey fetches the key of a tunnel's SSH server. Jump hosts on the
+// way are verified like they are when the tunnel starts.
+func (tm *TunnelManager) ScanHostKey(tc config.TunnelConfig) HostKeyScan {
+	t := &Tunnel{
+		ID:             "scan",
+		Config:         tc,
+		stopChan:       make(chan struct{}),
+		hostKeyPrompts: tm.hostKeyPrompts,
+		authPrompts:    tm.authPrompts,
+	}
+	defer close(t.stopChan)
+	udpDefaults(t)
+
+	sshEndpoint, _ := figureOutRemoteVsBastion(tc)
+	scan := HostKeyScan{Endpoint: sshEndpoint.String()}
+
+	clientConfig, err := GetSSHConfig(t)
+	if err != nil {
+		scan.Err = err
+		return scan
+	}
+	clientConfig.Auth = nil
+	clientConfig.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		scan.Hostname = hostname
+		scan.KeyType = key.Type()
+		scan.Fingerprint = ssh.FingerprintSHA256(key)
+		scan.key = key
+		scan.remote = remote
+
+		userFile, ownFile, err := knownHostsFiles()
+		if err != nil {
+			return err
+		}
+		knownHostsMu.Lock()
+		scan.Known, scan.Err = checkKnownHosts([]string{userFile, ownFile}, hostname, remote, key)
+		knownHostsMu.Unlock()
+		return errScanned
+	}
+
+	sshEndpoint, _ = figureOutRemoteVsBastion(t.Config)
+	client, err := t.dialSSH(sshEndpoint, clientConfig)
+	if client != nil {
+		client.Close()
+	}
+	if scan.key == nil {
+		if err == nil {
+			err = fmt.Errorf("%s presented no host key", sshEndpoint.String())
+		}
+		scan.Err = err
+	}
+	return scan
+}
+
+// Trust records a scanned key in tunnel9's known_hosts, unless it has been
+// trusted since the scan
+func (s *HostKeyScan) Trust() error {
+	if !s.New() {
+		return fmt.Errorf("no new host key to trust for %s", s.Endpoint)
+	}
+	userFile, ownFile, err := knownHostsFiles()
+	if err != nil {
+		return err
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	known, err := checkKnownHosts([]string{userFile, ownFile}, s.Hostname, s.remote, s.key)
+	if known || err != nil {
+		return err
+	}
+	if err := recordHostKey(ownFile, s.Hostname, s.key); err != nil {
+		return err
+	}
+	s.Known = true
+	return nil
+}
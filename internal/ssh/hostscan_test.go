@@ -0,0 +1,49 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestScanHostKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	addr, handshakes := serveSSH(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	var tc config.TunnelConfig
+	tc.Name = "db"
+	tc.RemoteHost = "db.internal"
+	tc.RemotePort = 5432
+	tc.Bastion.Host = host
+	tc.Bastion.Port, _ = strconv.Atoi(port)
+
+	tm := NewTunnelManager()
+	scan := tm.ScanHostKey(tc)
+	if scan.Err != nil || !scan.New() || scan.KeyType != "ssh-ed25519" || scan.Fingerprint == "" {
+		t.Fatalf("expected a new ed25519 key, got %+v", scan)
+	}
+	if *handshakes != 0 {
+		t.Errorf("expected the scan not to log in")
+	}
+
+	if err := scan.Trust(); err != nil {
+		t.Fatalf("failed to trust: %v", err)
+	}
+	if err := scan.Trust(); err == nil {
+		t.Errorf("expected nothing left to trust")
+	}
+
+	scan = tm.ScanHostKey(tc)
+	if scan.Err != nil || !scan.Known || scan.New() {
+		t.Errorf("expected the key known after trusting it, got %+v", scan)
+	}
+
+	// Unreachable servers are reported rather than hanging the review
+	tc.Bastion.Port = 1
+	if scan := tm.ScanHostKey(tc); scan.Err == nil {
+		t.Errorf("expected an unreachable server to fail")
+	}
+}
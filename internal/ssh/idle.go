@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// How often an idle timeout is checked
+var idleCheckInterval = 5 * time.Second
+
+func (t *Tunnel) markActivity() {
+	atomic.StoreInt64(&t.lastActivity, time.Now().UnixNano())
+}
+
+// idleFor returns how long the tunnel has gone without forwarding anything
+func (t *Tunnel) idleFor() time.Duration {
+	if atomic.LoadInt32(&t.activeConns) > 0 {
+		return 0
+	}
+	last := atomic.LoadInt64(&t.lastActivity)
+	if last == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, last))
+}
+
+// watchIdle closes the SSH connection once the tunnel has been unused for the
+// configured idle timeout. The local port stays bound, so the next client
+// brings the connection back on demand.
+func (t *Tunnel) watchIdle() {
+	timeout, err := time.ParseDuration(t.Config.IdleTimeout)
+	if err != nil || timeout <= 0 {
+		t.warnf("Ignoring invalid idle_timeout %q", t.Config.IdleTimeout)
+		return
+	}
+
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		if t.idleFor() < timeout {
+			continue
+		}
+
+		t.clientMu.Lock()
+		client := t.Client
+		t.Client = nil
+		t.clientMu.Unlock()
+
+		if client != nil {
+			t.closeClient(client, false)
+			t.infof("Idle for %v, disconnected until the next connection", timeout)
+			t.updateStatus("active", "idle, connects on demand")
+		}
+	}
+}
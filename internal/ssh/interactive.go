@@ -0,0 +1,136 @@
+package ssh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/totp"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Only one set of challenge questions is put to the user at a time
+var authPromptMu sync.Mutex
+
+// AuthPrompt carries keyboard-interactive challenges, such as an OTP or Duo
+// prompt, to whoever is driving the manager. The handshake waits until it
+// is answered or cancelled.
+type AuthPrompt struct {
+	TunnelID    string
+	Name        string // Tunnel name
+	User        string
+	Server      string // Challenge name sent by the server, often empty
+	Instruction string
+	Questions   []string
+	Echos       []bool // Whether each answer may be shown while typed
+	answers     chan []string
+}
+
+// Answer replies to the questions, one answer per question
+func (p *AuthPrompt) Answer(answers []string) {
+	select {
+	case p.answers <- answers:
+	default:
+		// Already answered, or the tunnel gave up waiting
+	}
+}
+
+// Cancel gives up on the challenge, failing the handshake
+func (p *AuthPrompt) Cancel() {
+	p.Answer(nil)
+}
+
+// keyboardInteractive hands the server's challenges to the front end and
+// passes its answers back into the handshake
+func (t *Tunnel) keyboardInteractive(user string) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		// Servers may send an empty round, e.g. to show only an instruction
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+		if answers, ok := t.autoAnswers(questions); ok {
+			t.logf("Answered %d authentication challenge(s) from the tunnel's auth secrets", len(questions))
+			return answers, nil
+		}
+		if t.authPrompts == nil {
+			return nil, fmt.Errorf("no one to answer the authentication challenges")
+		}
+
+		authPromptMu.Lock()
+		defer authPromptMu.Unlock()
+
+		prompt := &AuthPrompt{
+			TunnelID:    t.ID,
+			Name:        t.Config.Name,
+			User:        user,
+			Server:      name,
+			Instruction: instruction,
+			Questions:   questions,
+			Echos:       echos,
+			answers:     make(chan []string, 1),
+		}
+
+		t.logf("Waiting for answers to %d authentication challenge(s)", len(questions))
+		select {
+		case t.authPrompts <- prompt:
+		case <-t.stopChan:
+			return nil, fmt.Errorf("tunnel stopped during authentication")
+		}
+
+		select {
+		case answers := <-prompt.answers:
+			if answers == nil {
+				return nil, fmt.Errorf("authentication cancelled")
+			}
+			if len(answers) != len(questions) {
+				return nil, fmt.Errorf("expected %d answer(s), got %d", len(questions), len(answers))
+			}
+			return answers, nil
+		case <-t.stopChan:
+			return nil, fmt.Errorf("tunnel stopped during authentication")
+		}
+	}
+}
+
+// autoAnswers answers password and one-time code questions from the
+// tunnel's auth secrets, when it can answer every one of them
+func (t *Tunnel) autoAnswers(questions []string) ([]string, bool) {
+	secrets := t.Config.Auth
+	if secrets.Password == "" && secrets.TOTP == "" {
+		return nil, false
+	}
+	answers := make([]string, len(questions))
+	for i, question := range questions {
+		question = strings.ToLower(question)
+		var err error
+		switch {
+		case secrets.TOTP != "" && isCodeQuestion(question):
+			var seed string
+			if seed, err = config.ResolveTOTPSeed(secrets.TOTP); err == nil {
+				answers[i], err = totp.Code(seed, time.Now())
+			}
+		case secrets.Password != "" && strings.Contains(question, "password"):
+			answers[i], err = config.ResolveSecret(secrets.Password)
+		default:
+			return nil, false
+		}
+		if err != nil {
+			t.warnf("Failed to answer %q from the auth secrets: %v", questions[i], err)
+			return nil, false
+		}
+	}
+	return answers, true
+}
+
+// isCodeQuestion reports whether a challenge asks for a one-time code
+func isCodeQuestion(question string) bool {
+	for _, word := range []string{"code", "otp", "token", "one-time", "passcode", "verification"} {
+		if strings.Contains(question, word) {
+			return true
+		}
+	}
+	return false
+}
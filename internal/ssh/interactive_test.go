@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyboardInteractive(t *testing.T) {
+	prompts := make(chan *AuthPrompt)
+	tunnel := &Tunnel{ID: "t1", authPrompts: prompts, stopChan: make(chan struct{})}
+	tunnel.Config.Name = "prod"
+	challenge := tunnel.keyboardInteractive("alice")
+
+	// Empty rounds are answered without bothering anyone
+	answers, err := challenge("", "Welcome", nil, nil)
+	if err != nil || len(answers) != 0 {
+		t.Fatalf("expected empty answers, got %v (%v)", answers, err)
+	}
+
+	go func() {
+		prompt := <-prompts
+		if prompt.Name != "prod" || prompt.User != "alice" || prompt.Questions[0] != "Verification code: " || prompt.Echos[0] {
+			t.Errorf("unexpected prompt %+v", prompt)
+		}
+		prompt.Answer([]string{"123456"})
+	}()
+	answers, err = challenge("", "", []string{"Verification code: "}, []bool{false})
+	if err != nil || !reflect.DeepEqual(answers, []string{"123456"}) {
+		t.Errorf("expected the user's answer, got %v (%v)", answers, err)
+	}
+
+	go func() {
+		(<-prompts).Cancel()
+	}()
+	if _, err := challenge("", "", []string{"Passcode: "}, []bool{false}); err == nil {
+		t.Error("expected cancelling to fail the handshake")
+	}
+
+	close(tunnel.stopChan)
+	if _, err := challenge("", "", []string{"Passcode: "}, []bool{false}); err == nil {
+		t.Error("expected a stopped tunnel to give up")
+	}
+}
+
+func TestKeyboardInteractiveAuthSecrets(t *testing.T) {
+	t.Setenv("SSH_PASSWORD", "hunter2")
+	tunnel := &Tunnel{ID: "t1", stopChan: make(chan struct{})}
+	tunnel.Config.Auth.Password = "env:SSH_PASSWORD"
+	tunnel.Config.Auth.TOTP = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	challenge := tunnel.keyboardInteractive("alice")
+
+	answers, err := challenge("", "", []string{"Password: ", "Verification code: "}, []bool{false, false})
+	if err != nil || len(answers) != 2 || answers[0] != "hunter2" || len(answers[1]) != 6 {
+		t.Fatalf("expected the password and a code, got %v (%v)", answers, err)
+	}
+
+	// Without a front end, questions the secrets can't answer fail
+	if _, err := challenge("", "", []string{"Favourite colour: "}, []bool{true}); err == nil {
+		t.Error("expected an unanswerable challenge to fail")
+	}
+}
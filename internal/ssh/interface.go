@@ -0,0 +1,123 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// How often a tunnel bound to an interface checks whether its address moved
+var bindInterfacePollInterval = 5 * time.Second
+
+// resolveInterfaceAddress returns the current address of the named network
+// interface, preferring IPv4 over IPv6 and skipping link-local addresses
+func resolveInterfaceAddress(name string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", fmt.Errorf("interface %s not found: %w", name, err)
+	}
+
+	if iface.Flags&net.FlagUp == 0 {
+		return "", fmt.Errorf("interface %s is down", name)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to read addresses of %s: %w", name, err)
+	}
+
+	var fallback string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if fallback == "" {
+			fallback = ipNet.IP.String()
+		}
+	}
+
+	if fallback == "" {
+		return "", fmt.Errorf("interface %s has no usable address", name)
+	}
+	return fallback, nil
+}
+
+// listenAddress works out the host:port the local listener should bind to
+func listenAddress(t *Tunnel) (string, error) {
+	if t.Config.BindInterface != "" {
+		addr, err := resolveInterfaceAddress(t.Config.BindInterface)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(addr, strconv.Itoa(t.localPort())), nil
+	}
+
+	return NewEndpoint(t.Config.BindAddress, t.localPort(), "localhost").String(), nil
+}
+
+// localPort is the port the tunnel listens on: its local_port, or the one
+// the system picked for a tunnel without, which a re-bind keeps. It is 0
+// before such a tunnel first listens.
+func (t *Tunnel) localPort() int {
+	if t.Config.LocalPort != 0 {
+		return t.Config.LocalPort
+	}
+	t.listenerMu.Lock()
+	addr := t.listenAddr
+	t.listenerMu.Unlock()
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		if n, err := strconv.Atoi(port); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// watchBindInterface re-binds the local listener whenever the address of the
+// configured interface changes, e.g. when a VPN hands out a new IP
+func (t *Tunnel) watchBindInterface() {
+	ticker := time.NewTicker(bindInterfacePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		addr, err := listenAddress(t)
+		if err != nil {
+			t.warnf("Bind interface %s unavailable: %v", t.Config.BindInterface, err)
+			continue
+		}
+
+		t.listenerMu.Lock()
+		current := t.listenAddr
+		t.listenerMu.Unlock()
+		if addr == current {
+			continue
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.warnf("Failed to re-bind to %s: %v", addr, err)
+			continue
+		}
+
+		t.listenerMu.Lock()
+		old := t.Listener
+		t.Listener = listener
+		t.listenAddr = addr
+		t.listenerMu.Unlock()
+		if old != nil {
+			old.Close()
+		}
+		t.infof("Interface %s changed address, re-bound listener from %s to %s", t.Config.BindInterface, current, addr)
+	}
+}
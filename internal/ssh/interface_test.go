@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"net"
+	"testing"
+)
+
+func TestResolveInterfaceAddress(t *testing.T) {
+	t.Run("unknown interface", func(t *testing.T) {
+		if _, err := resolveInterfaceAddress("tunnel9-does-not-exist"); err == nil {
+			t.Error("expected error for unknown interface")
+		}
+	})
+
+	t.Run("loopback interface", func(t *testing.T) {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			t.Skipf("cannot list interfaces: %v", err)
+		}
+
+		var loopback string
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+				loopback = iface.Name
+				break
+			}
+		}
+		if loopback == "" {
+			t.Skip("no loopback interface available")
+		}
+
+		addr, err := resolveInterfaceAddress(loopback)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ip := net.ParseIP(addr); ip == nil || !ip.IsLoopback() {
+			t.Errorf("expected loopback address, got %s", addr)
+		}
+	})
+}
+
+func TestListenAddress(t *testing.T) {
+	tunnel := &Tunnel{}
+	tunnel.Config.LocalPort = 8080
+
+	addr, err := listenAddress(tunnel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr != "localhost:8080" {
+		t.Errorf("expected localhost:8080, got %s", addr)
+	}
+
+	tunnel.Config.BindAddress = "0.0.0.0"
+	addr, _ = listenAddress(tunnel)
+	if addr != "0.0.0.0:8080" {
+		t.Errorf("expected 0.0.0.0:8080, got %s", addr)
+	}
+
+	tunnel.Config.BindInterface = "tunnel9-does-not-exist"
+	if _, err := listenAddress(tunnel); err == nil {
+		t.Error("expected error for unknown bind interface")
+	}
+}
@@ -0,0 +1,116 @@
+package ssh
+
+import (
+	"fmt"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Keepalive defaults, matching what export hands to autossh
+const (
+	defaultServerAliveInterval = 30 * time.Second
+	defaultServerAliveCountMax = 3
+)
+
+// serverAlive returns how often keepalives are sent and how many may go
+// unanswered, with a zero interval when they are turned off
+func serverAlive(tc config.TunnelConfig) (time.Duration, int) {
+	interval := defaultServerAliveInterval
+	if tc.Keepalive != "" {
+		interval = keepaliveInterval(tc.Keepalive)
+	}
+	if tc.ServerAliveInterval != "" {
+		parsed, err := time.ParseDuration(tc.ServerAliveInterval)
+		if err != nil || parsed < 0 {
+			parsed = defaultServerAliveInterval
+		}
+		interval = parsed
+	}
+
+	countMax := tc.ServerAliveCountMax
+	if countMax <= 0 {
+		countMax = defaultServerAliveCountMax
+	}
+	return interval, countMax
+}
+
+// ServerAlive returns the keepalive interval and count a tunnel uses, for
+// tools that set up the same tunnel elsewhere
+func ServerAlive(tc config.TunnelConfig) (time.Duration, int) {
+	return serverAlive(tc)
+}
+
+// sendKeepalive asks the server for a reply, giving up after timeout
+func sendKeepalive(client *ssh.Client, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("no reply within %v", timeout)
+	}
+}
+
+// watchKeepalive checks that the server still answers, so a connection that
+// died quietly behind a NAT is noticed before the next local client needs it.
+// Once too many keepalives go unanswered the client is dropped and the
+// tunnel marked as failed, the next connection dials a fresh one.
+func (t *Tunnel) watchKeepalive() {
+	interval, countMax := serverAlive(t.Config)
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		t.clientMu.RLock()
+		client := t.Client
+		t.clientMu.RUnlock()
+		if client == nil {
+			// Idle or between connections, nothing to keep alive
+			missed = 0
+			continue
+		}
+
+		err := sendKeepalive(client, interval)
+		if err == nil {
+			missed = 0
+			continue
+		}
+
+		missed++
+		t.warnf("Keepalive %d/%d unanswered: %v", missed, countMax, err)
+		if missed < countMax {
+			continue
+		}
+		missed = 0
+
+		t.clientMu.Lock()
+		if t.Client == client {
+			t.Client = nil
+		}
+		t.clientMu.Unlock()
+		t.closeClient(client, true)
+
+		t.warnf("Server stopped answering keepalives, disconnected")
+		t.updateStatus("error", fmt.Sprintf("keepalive timed out after %d attempts", countMax))
+		go t.superviseReconnect("keepalive timed out")
+	}
+}
@@ -0,0 +1,31 @@
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestServerAlive(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       config.TunnelConfig
+		wantInterval time.Duration
+		wantCount    int
+	}{
+		{"defaults", config.TunnelConfig{}, 30 * time.Second, 3},
+		{"preset", config.TunnelConfig{Keepalive: "aggressive"}, 10 * time.Second, 3},
+		{"explicit wins over preset", config.TunnelConfig{Keepalive: "relaxed", ServerAliveInterval: "15s", ServerAliveCountMax: 5}, 15 * time.Second, 5},
+		{"turned off", config.TunnelConfig{ServerAliveInterval: "0"}, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, count := serverAlive(tt.config)
+			if interval != tt.wantInterval || count != tt.wantCount {
+				t.Errorf("expected %v/%d, got %v/%d", tt.wantInterval, tt.wantCount, interval, count)
+			}
+		})
+	}
+}
@@ -2,26 +2,76 @@ package ssh
 
 import (
 	"fmt"
+	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 	"tunnel9/internal/config"
+	"tunnel9/internal/events"
 )
 
 type TunnelManager struct {
-	tunnels    map[string]*Tunnel
-	LogChan    chan string
-	StatusChan chan TunnelStatus
+	tunnels        map[string]*Tunnel
+	shares         map[string]*Share
+	mu             sync.RWMutex      // Protect the tunnels map from background watchers
+	Events         *events.Bus       // Logs and status changes from every tunnel
+	logChan        chan events.Event // Manager-wide log lines, e.g. network changes and shares
+	hostKeyPrompts chan *HostKeyPrompt
+	authPrompts    chan *AuthPrompt
+	pool           *clientPool // SSH connections shared by tunnels to the same server
+	stopChan       chan struct{}
+	inherited      map[string]io.Closer // Sockets handed over by a previous process, by tunnel ID
+	networkWake    chan struct{}        // Wakes the network watcher once there are tunnels again
 }
 
 func NewTunnelManager() *TunnelManager {
-	return &TunnelManager{
-		tunnels:    make(map[string]*Tunnel),
-		LogChan:    make(chan string, 100),     // Buffered channel to prevent blocking
-		StatusChan: make(chan TunnelStatus, 5), // Small buffer for status updates
+	tm := &TunnelManager{
+		tunnels:     make(map[string]*Tunnel),
+		shares:      make(map[string]*Share),
+		Events:      events.NewBus(),
+		logChan:     make(chan events.Event, 100), // Buffered channel to prevent blocking
+		pool:        newClientPool(),
+		stopChan:    make(chan struct{}),
+		inherited:   make(map[string]io.Closer),
+		networkWake: make(chan struct{}, 1),
 	}
+
+	// Publish manager-wide log lines alongside the tunnels' own, ranging over
+	// the channel itself since Cleanup clears the field
+	go func(logs <-chan events.Event) {
+		for event := range logs {
+			tm.Events.Publish(event)
+		}
+	}(tm.logChan)
+
+	// Reconnect tunnels proactively when the network changes underneath us
+	go tm.watchNetwork(tm.stopChan)
+
+	return tm
+}
+
+// getTunnel looks up a tunnel by ID
+func (tm *TunnelManager) getTunnel(id string) (*Tunnel, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	tunnel, exists := tm.tunnels[id]
+	return tunnel, exists
+}
+
+// activeTunnels returns a snapshot of all tunnels currently managed
+func (tm *TunnelManager) activeTunnels() []*Tunnel {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	tunnels := make([]*Tunnel, 0, len(tm.tunnels))
+	for _, tunnel := range tm.tunnels {
+		tunnels = append(tunnels, tunnel)
+	}
+	return tunnels
 }
 
-func formatBytes(bytes float64) string {
+// FormatRate renders a transfer rate in bytes per second
+func FormatRate(bytes float64) string {
 	const unit = 1024
 	if bytes < unit {
 		return fmt.Sprintf("%.1f B/s", bytes)
@@ -34,56 +84,209 @@ func formatBytes(bytes float64) string {
 	return fmt.Sprintf("%.1f %cB/s", bytes/div, "KMGTPE"[exp])
 }
 
-func formatLatency(d time.Duration) string {
+// FormatLatency renders a latency, or n/a before the first measurement
+func FormatLatency(d time.Duration) string {
 	if d <= 0 {
 		return "n/a"
 	}
 	return fmt.Sprintf("%dms", d.Milliseconds())
 }
 
-func (tm *TunnelManager) GetMetrics(id string) string {
-	tunnel, exists := tm.tunnels[id]
+// MetricValues is a snapshot of a running tunnel's metrics
+type MetricValues struct {
+	RateIn  float64 // bytes per second
+	RateOut float64 // bytes per second
+	Latency time.Duration
+	Note    string // "failover" when on a standby, "idle, connects on demand" for idle lazy tunnels
+	Idle    bool
+	Health  string // healthy, degraded or down, empty without a health check
+	Level   string // What the tunnel measures: off, basic or full
+
+	// Totals since the tunnel started, for exporters
+	BytesIn          int64
+	BytesOut         int64
+	Connections      int   // Forwarded right now
+	ConnectionsTotal int64 // Forwarded since the tunnel started
+	Handshakes       HandshakeFailures
+}
+
+// GetMetricValues returns the current metrics of a tunnel
+func (tm *TunnelManager) GetMetricValues(id string) (MetricValues, bool) {
+	tunnel, exists := tm.getTunnel(id)
 	if !exists {
-		return "--"
+		return MetricValues{}, false
+	}
+
+	values := MetricValues{
+		Level:            tunnel.metricsLevel(),
+		Connections:      int(atomic.LoadInt32(&tunnel.activeConns)),
+		ConnectionsTotal: atomic.LoadInt64(&tunnel.nextConnID),
+		Handshakes:       tunnel.handshakes.snapshot(),
+	}
+
+	// Lazy tunnels sit disconnected between uses
+	if tunnel.Config.IdleTimeout != "" {
+		tunnel.clientMu.RLock()
+		idle := tunnel.Client == nil
+		tunnel.clientMu.RUnlock()
+		if idle {
+			values.Note = "idle, connects on demand"
+			values.Idle = true
+		}
+	}
+
+	// Make it obvious we're not on the primary target
+	if !values.Idle && tunnel.OnStandby() {
+		values.Note = "failover"
 	}
 
 	tunnel.Metrics.mu.Lock()
 	defer tunnel.Metrics.mu.Unlock()
+	values.BytesIn = tunnel.Metrics.BytesIn
+	values.BytesOut = tunnel.Metrics.BytesOut
+	values.Health = tunnel.Metrics.Health
+	if values.Idle {
+		return values, true
+	}
+	values.RateIn = tunnel.Metrics.CurrentRateIn
+	values.RateOut = tunnel.Metrics.CurrentRateOut
+	values.Latency = tunnel.Metrics.Latency
+	return values, true
+}
+
+func (tm *TunnelManager) GetMetrics(id string) string {
+	values, exists := tm.GetMetricValues(id)
+	if !exists {
+		return "--"
+	}
+	if values.Idle {
+		return values.Note
+	}
+
+	prefix := ""
+	if values.Note != "" {
+		prefix = values.Note + " "
+	}
+
+	switch values.Level {
+	case config.MetricsOff:
+		return prefix + "metrics off"
+	case config.MetricsBasic:
+		return fmt.Sprintf("%s↑%s ↓%s", prefix, FormatRate(values.RateOut), FormatRate(values.RateIn))
+	}
+	return fmt.Sprintf("%s↑%s ↓%s [%s]",
+		prefix,
+		FormatRate(values.RateOut),
+		FormatRate(values.RateIn),
+		FormatLatency(values.Latency))
+}
+
+// LocalAddress returns the address a running tunnel accepts connections on,
+// suitable for dialing from this machine
+func (tm *TunnelManager) LocalAddress(id string) (string, bool) {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return "", false
+	}
 
-	return fmt.Sprintf("↑%s ↓%s [%s]",
-		formatBytes(tunnel.Metrics.CurrentRateOut),
-		formatBytes(tunnel.Metrics.CurrentRateIn),
-		formatLatency(tunnel.Metrics.Latency))
+	return tunnel.localAddress()
+}
+
+// TunnelSnapshot is the state of a running tunnel, for front ends that
+// render it themselves
+type TunnelSnapshot struct {
+	ID          string
+	Local       string    // Address clients connect to, empty before the listener is up
+	ActiveSince time.Time // Zero while the tunnel isn't active
+	Values      MetricValues
+}
+
+// Snapshot returns the current state of a tunnel
+func (tm *TunnelManager) Snapshot(id string) (TunnelSnapshot, bool) {
+	tunnel, exists := tm.getTunnel(id)
+	if !exists {
+		return TunnelSnapshot{}, false
+	}
+
+	snapshot := TunnelSnapshot{ID: id}
+	snapshot.Local, _ = tunnel.localAddress()
+	if since := atomic.LoadInt64(&tunnel.activeSince); since != 0 {
+		snapshot.ActiveSince = time.Unix(0, since)
+	}
+	snapshot.Values, _ = tm.GetMetricValues(id)
+	return snapshot, true
+}
+
+// PromptHostKeys makes tunnels ask before trusting a host key they have
+// never seen, instead of trusting it on first use. Each connection waits
+// until its prompt is accepted or rejected. Enable this before creating
+// tunnels, only those created afterwards will ask.
+func (tm *TunnelManager) PromptHostKeys() <-chan *HostKeyPrompt {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.hostKeyPrompts == nil {
+		tm.hostKeyPrompts = make(chan *HostKeyPrompt)
+	}
+	return tm.hostKeyPrompts
+}
+
+// PromptAuth offers keyboard-interactive authentication, for servers that
+// ask for an OTP or a Duo push, with the challenges delivered here for the
+// user to answer. Like PromptHostKeys, only tunnels created afterwards use it.
+func (tm *TunnelManager) PromptAuth() <-chan *AuthPrompt {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.authPrompts == nil {
+		tm.authPrompts = make(chan *AuthPrompt)
+	}
+	return tm.authPrompts
 }
 
 func (tm *TunnelManager) CreateTunnel(id string, config config.TunnelConfig) *Tunnel {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	// Check if tunnel already exists
-	if _, exists := tm.tunnels[id]; exists {
-		return tm.tunnels[id]
+	if tunnel, exists := tm.tunnels[id]; exists {
+		return tunnel
 	}
 
 	// Create tunnel with log channel
 	tunnel := &Tunnel{
-		ID:         id,
-		Client:     nil,
-		Config:     config,
-		LogChan:    make(chan string, 50),      // Buffered channel for tunnel-specific logs
-		StatusChan: make(chan TunnelStatus, 2), // Small buffer for status updates
+		ID:             id,
+		Client:         nil,
+		Config:         config,
+		LogChan:        make(chan events.Event, 50), // Buffered channel for tunnel-specific logs
+		StatusChan:     make(chan TunnelStatus, 2),  // Small buffer for status updates
+		hostKeyPrompts: tm.hostKeyPrompts,
+		authPrompts:    tm.authPrompts,
+		pool:           tm.pool,
 	}
 
-	// Start goroutine to forward tunnel status to manager's status channel
-	go func() {
-		for status := range tunnel.StatusChan {
-			tm.StatusChan <- status
+	// Start goroutine to publish tunnel status changes
+	go func(statuses <-chan TunnelStatus) {
+		for status := range statuses {
+			tm.Events.Publish(events.Event{
+				Kind:     events.KindStatus,
+				TunnelID: status.ID,
+				State:    status.State,
+				Message:  status.Message,
+			})
 		}
-	}()
+	}(tunnel.StatusChan)
 
 	// Store the tunnel
 	tm.tunnels[id] = tunnel
+	select {
+	case tm.networkWake <- struct{}{}:
+	default:
+	}
 	return tunnel
 }
 
 func (tm *TunnelManager) StartTunnel(tunnel *Tunnel) error {
+	noteActivity("starting tunnel " + tunnel.Config.Name)
+
 	// Get SSH config
 	sshconfig, err := GetSSHConfig(tunnel)
 	if err != nil {
@@ -91,29 +294,104 @@ func (tm *TunnelManager) StartTunnel(tunnel *Tunnel) error {
 		return fmt.Errorf("failed to get SSH config")
 	}
 
-	// Start local listener
-	localEndpoint := NewEndpoint(tunnel.Config.BindAddress, tunnel.Config.LocalPort, "localhost")
-	tunnel.Listener, err = net.Listen("tcp", localEndpoint.String())
+	udpDefaults(tunnel)
+
+	// Only local_port: auto picks a free port, a port left at 0 is a mistake
+	if tunnel.Config.LocalPort == 0 && !tunnel.Config.AutoPort {
+		err := fmt.Errorf("local_port is required, set it to %s to pick a free port", config.AutoPort)
+		tunnel.logAt(events.LevelError, "%v", err)
+		tunnel.updateStatus("error", err.Error())
+		return err
+	}
+
+	// Resolve where to listen, following the bind interface if one is set
+	listenAddr, err := listenAddress(tunnel)
+	if err != nil {
+		tunnel.errorf("failed to resolve bind interface: %v", err)
+		return fmt.Errorf("failed to resolve bind interface: %w", err)
+	}
+
+	// Start local listener, unless a previous process handed one over
+	switch socket := tm.takeInherited(tunnel).(type) {
+	case net.PacketConn:
+		tunnel.PacketConn = socket
+		listenAddr = socket.LocalAddr().String()
+	case net.Listener:
+		tunnel.Listener = socket
+		listenAddr = socket.Addr().String()
+	default:
+		// Another tunnel on the port is named rather than left to the OS
+		if holder := tm.portHolder(tunnel, listenAddr); holder != nil {
+			err = fmt.Errorf("port %d in use by tunnel %s", tunnel.Config.LocalPort, holder.Config.Name)
+			break
+		}
+		if tunnel.Config.IsUDP() {
+			tunnel.PacketConn, err = net.ListenPacket("udp", listenAddr)
+		} else {
+			tunnel.Listener, err = net.Listen("tcp", listenAddr)
+		}
+		if err != nil {
+			err = listenError(tunnel, err)
+		} else if tunnel.Config.LocalPort == 0 {
+			// Note the port the system picked, for the table and the status
+			if tunnel.Listener != nil {
+				listenAddr = tunnel.Listener.Addr().String()
+			} else {
+				listenAddr = tunnel.PacketConn.LocalAddr().String()
+			}
+			tunnel.infof("Listening on %s, a port picked at start", listenAddr)
+		}
+	}
 	if err != nil {
-		tunnel.errorf("failed to listen on port %d", tunnel.Config.LocalPort)
-		return fmt.Errorf("failed to listen on port %d", tunnel.Config.LocalPort)
+		// Show why in the table, e.g. what holds the port
+		tunnel.logAt(events.LevelError, "%v", err)
+		tunnel.updateStatus("error", err.Error())
+		return err
 	}
+	tunnel.listenerMu.Lock()
+	tunnel.listenAddr = listenAddr
+	tunnel.listenerMu.Unlock()
 
-	// Start goroutine to forward tunnel logs to manager's log channel
+	// Start goroutine to publish tunnel logs
+	tunnel.chanMu.RLock()
+	logs := tunnel.LogChan
+	tunnel.chanMu.RUnlock()
 	go func() {
-		for msg := range tunnel.LogChan {
-			tm.LogChan <- msg
+		for event := range logs {
+			event.TunnelID = tunnel.ID
+			tm.Events.Publish(event)
 		}
 	}()
 
-	// Start the tunnel
-	go tunnel.connect(sshconfig)
+	// Start the tunnel, with its stop channel in place before anything can
+	// stop it
+	tunnel.stopChan = make(chan struct{})
+	if tunnel.Config.IsUDP() {
+		go tunnel.connectUDP(sshconfig)
+	} else {
+		go tunnel.connect(sshconfig)
+	}
 
 	return nil
 }
 
+// StartTunnels starts the tunnels side by side rather than one after the
+// other, as resolving each one's SSH settings takes a moment. It returns
+// once all are on their way.
+func (tm *TunnelManager) StartTunnels(tunnels []*Tunnel) {
+	var wg sync.WaitGroup
+	for _, tunnel := range tunnels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tm.StartTunnel(tunnel)
+		}()
+	}
+	wg.Wait()
+}
+
 func (tm *TunnelManager) StopTunnel(id string) error {
-	tunnel, exists := tm.tunnels[id]
+	tunnel, exists := tm.getTunnel(id)
 	if !exists {
 		return nil
 	}
@@ -124,12 +402,19 @@ func (tm *TunnelManager) StopTunnel(id string) error {
 	// Wait a moment for goroutines to clean up
 	time.Sleep(time.Second / 2)
 
+	tunnel.listenerMu.Lock()
 	if tunnel.Listener != nil {
 		tunnel.Listener.Close()
 		tunnel.Listener = nil
 	}
+	if tunnel.PacketConn != nil {
+		tunnel.PacketConn.Close()
+		tunnel.PacketConn = nil
+	}
+	tunnel.listenerMu.Unlock()
 
-	// Now close channels
+	// Now close channels, once nothing is sending on them
+	tunnel.chanMu.Lock()
 	if tunnel.LogChan != nil {
 		close(tunnel.LogChan)
 		tunnel.LogChan = nil
@@ -138,26 +423,43 @@ func (tm *TunnelManager) StopTunnel(id string) error {
 		close(tunnel.StatusChan)
 		tunnel.StatusChan = nil
 	}
+	tunnel.chanMu.Unlock()
 
 	// Remove from manager
+	tm.mu.Lock()
 	delete(tm.tunnels, id)
+	tm.mu.Unlock()
 	return nil
 }
 
 // Add cleanup method for the manager
 func (tm *TunnelManager) Cleanup() {
+	// Stop watching the network
+	if tm.stopChan != nil {
+		close(tm.stopChan)
+		tm.stopChan = nil
+	}
+
 	// Stop all tunnels
-	for id := range tm.tunnels {
-		tm.StopTunnel(id)
+	for _, tunnel := range tm.activeTunnels() {
+		tm.StopTunnel(tunnel.ID)
 	}
 
-	// Close manager channels
-	if tm.LogChan != nil {
-		close(tm.LogChan)
-		tm.LogChan = nil
+	// Stop all public shares
+	tm.mu.RLock()
+	shareIDs := make([]string, 0, len(tm.shares))
+	for id := range tm.shares {
+		shareIDs = append(shareIDs, id)
 	}
-	if tm.StatusChan != nil {
-		close(tm.StatusChan)
-		tm.StatusChan = nil
+	tm.mu.RUnlock()
+	for _, id := range shareIDs {
+		tm.StopShare(id)
+	}
+
+	// Close the manager log channel and end all subscriptions
+	if tm.logChan != nil {
+		close(tm.logChan)
+		tm.logChan = nil
 	}
+	tm.Events.Close()
 }
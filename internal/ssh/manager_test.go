@@ -0,0 +1,192 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+)
+
+func TestSnapshot(t *testing.T) {
+	tm := NewTunnelManager()
+	defer tm.Cleanup()
+
+	if _, ok := tm.Snapshot("db"); ok {
+		t.Fatal("expected no snapshot for an unknown tunnel")
+	}
+
+	tunnel := tm.CreateTunnel("db", config.TunnelConfig{Name: "db", LocalPort: 5432})
+	snapshot, ok := tm.Snapshot("db")
+	if !ok || snapshot.ID != "db" || !snapshot.ActiveSince.IsZero() || snapshot.Local != "" {
+		t.Fatalf("expected an idle snapshot, got %+v", snapshot)
+	}
+
+	before := time.Now()
+	tunnel.updateStatus("active", "tunnel established")
+	since := mustSnapshot(t, tm).ActiveSince
+	if since.Before(before) {
+		t.Fatalf("expected the tunnel active since now, got %v", since)
+	}
+	// Later active messages keep the uptime
+	tunnel.updateStatus("active", "reconnected")
+	if got := mustSnapshot(t, tm).ActiveSince; !got.Equal(since) {
+		t.Errorf("expected uptime kept from %v, got %v", since, got)
+	}
+	tunnel.updateStatus("error", "connection lost")
+	if got := mustSnapshot(t, tm).ActiveSince; !got.IsZero() {
+		t.Errorf("expected no uptime after an error, got %v", got)
+	}
+}
+
+func mustSnapshot(t *testing.T, tm *TunnelManager) TunnelSnapshot {
+	t.Helper()
+	snapshot, ok := tm.Snapshot("db")
+	if !ok {
+		t.Fatal("expected a snapshot")
+	}
+	return snapshot
+}
+
+func TestMetricsLevels(t *testing.T) {
+	tm := NewTunnelManager()
+	defer tm.Cleanup()
+	defer SetDefaultMetrics("")
+
+	tm.CreateTunnel("off", config.TunnelConfig{Name: "off", Metrics: config.MetricsOff})
+	tm.CreateTunnel("basic", config.TunnelConfig{Name: "basic", Metrics: config.MetricsBasic})
+	tm.CreateTunnel("default", config.TunnelConfig{Name: "default"})
+
+	if got := tm.GetMetrics("off"); got != "metrics off" {
+		t.Errorf("expected metrics off, got %q", got)
+	}
+	if got := tm.GetMetrics("basic"); got != "↑0.0 B/s ↓0.0 B/s" {
+		t.Errorf("expected rates without latency, got %q", got)
+	}
+	if got := tm.GetMetrics("default"); got != "↑0.0 B/s ↓0.0 B/s [n/a]" {
+		t.Errorf("expected full metrics by default, got %q", got)
+	}
+
+	// Tunnels without their own setting follow the top-level one
+	SetDefaultMetrics(config.MetricsOff)
+	if got := tm.GetMetrics("default"); got != "metrics off" {
+		t.Errorf("expected the default to apply, got %q", got)
+	}
+	if got := tm.GetMetrics("basic"); got != "↑0.0 B/s ↓0.0 B/s" {
+		t.Errorf("expected the tunnel's own setting to win, got %q", got)
+	}
+}
+
+func TestMetricsOffSkipsSampler(t *testing.T) {
+	tunnel := &Tunnel{Config: config.TunnelConfig{Metrics: config.MetricsOff}, stopChan: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		tunnel.runMetricsUpdater()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		close(tunnel.stopChan)
+		t.Fatal("expected no sampler with metrics off")
+	}
+}
+
+func TestTunnelLogLevels(t *testing.T) {
+	tunnel := &Tunnel{LogChan: make(chan events.Event, 4), StatusChan: make(chan TunnelStatus, 4)}
+	tunnel.Config.Name = "db"
+
+	tunnel.logf("Health check ok")
+	tunnel.warnf("SSH connection lost (%s), reconnecting", "EOF")
+	tunnel.errorf("failed to listen on port %d", 5432)
+
+	for _, want := range []struct {
+		level events.Level
+		text  string
+	}{
+		{events.LevelDebug, " DEBUG [db] Health check ok"},
+		{events.LevelWarn, " WARN [db] SSH connection lost (EOF), reconnecting"},
+		{events.LevelError, " ERROR [db] failed to listen on port 5432"},
+	} {
+		event := <-tunnel.LogChan
+		if event.Kind != events.KindLog || event.Level != want.level || event.Message[8:] != want.text {
+			t.Errorf("expected %s line %q, got %+v", want.level, want.text, event)
+		}
+	}
+}
+
+func TestStartTunnelNamesWhatHoldsThePort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tm := NewTunnelManager()
+	defer tm.Cleanup()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	tunnelOn := func(name string) config.TunnelConfig {
+		return config.TunnelConfig{Name: name, LocalPort: port, RemoteHost: "db.internal", RemotePort: 5432}
+	}
+
+	err = tm.StartTunnel(tm.CreateTunnel("db", tunnelOn("db")))
+	if want := fmt.Sprintf("port %d in use by ", port); err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("expected %q..., got %v", want, err)
+	}
+	if runtime.GOOS == "linux" && !strings.Contains(err.Error(), fmt.Sprintf("(pid %d)", os.Getpid())) {
+		t.Errorf("expected this process named, got %v", err)
+	}
+
+	listener.Close()
+	if err := tm.StartTunnel(tm.CreateTunnel("web", tunnelOn("web"))); err != nil {
+		t.Fatalf("expected web to start, got %v", err)
+	}
+	err = tm.StartTunnel(tm.CreateTunnel("api", tunnelOn("api")))
+	if want := fmt.Sprintf("port %d in use by tunnel web", port); err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestStartTunnelPicksAFreePort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tm := NewTunnelManager()
+	defer tm.Cleanup()
+
+	ports := make(map[string]bool)
+	for _, name := range []string{"db", "web"} {
+		tc := config.TunnelConfig{Name: name, AutoPort: true, RemoteHost: "db.internal", RemotePort: 5432}
+		if err := tm.StartTunnel(tm.CreateTunnel(name, tc)); err != nil {
+			t.Fatalf("expected %s to start, got %v", name, err)
+		}
+		addr, ok := tm.LocalAddress(name)
+		if !ok {
+			t.Fatalf("expected %s to report where it listens", name)
+		}
+		_, port, _ := net.SplitHostPort(addr)
+		if port == "0" || ports[port] {
+			t.Errorf("expected %s on a port of its own, got %s", name, addr)
+		}
+		ports[port] = true
+
+		if snapshot, _ := tm.Snapshot(name); snapshot.Local != addr {
+			t.Errorf("expected the snapshot to show %s, got %q", addr, snapshot.Local)
+		}
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("expected %s to accept connections, got %v", addr, err)
+		}
+		conn.Close()
+	}
+
+	// A port left at 0 isn't taken as auto
+	tc := config.TunnelConfig{Name: "cache", RemoteHost: "cache.internal", RemotePort: 6379}
+	err := tm.StartTunnel(tm.CreateTunnel("cache", tc))
+	if err == nil || !strings.Contains(err.Error(), "local_port is required") {
+		t.Errorf("expected a missing local port refused, got %v", err)
+	}
+}
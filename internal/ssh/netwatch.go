@@ -0,0 +1,110 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+// How often the manager looks for network changes
+var networkPollInterval = 3 * time.Second
+
+// networkFingerprint summarises the interfaces that are up and the addresses
+// assigned to them, so any change in routing-relevant state shows up as a diff
+func networkFingerprint() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	entries := make([]string, 0)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			entries = append(entries, iface.Name+"="+addr.String())
+		}
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, ",")
+}
+
+// watchNetwork detects interface changes and wake from sleep, then reconnects
+// every tunnel instead of waiting for traffic to discover dead connections,
+// until stop is closed
+func (tm *TunnelManager) watchNetwork(stop <-chan struct{}) {
+	ticker := time.NewTicker(networkPollInterval)
+	defer ticker.Stop()
+
+	last := networkFingerprint()
+	// Strip the monotonic reading so time spent asleep is counted
+	lastTick := time.Now().Round(0)
+
+	for {
+		// With nothing to reconnect, sleep until a tunnel is created instead
+		// of polling the interfaces while idle
+		if len(tm.activeTunnels()) == 0 {
+			ticker.Stop()
+			select {
+			case <-stop:
+				return
+			case <-tm.networkWake:
+			}
+			ticker.Reset(networkPollInterval)
+			last = networkFingerprint()
+			lastTick = time.Now().Round(0)
+			continue
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().Round(0)
+		reason := ""
+
+		// A tick arriving far later than scheduled means the machine was asleep
+		if now.Sub(lastTick) > 3*networkPollInterval {
+			reason = "wake from sleep"
+		}
+		lastTick = now
+
+		current := networkFingerprint()
+		if current != last && reason == "" {
+			reason = "network change"
+		}
+		last = current
+
+		if reason != "" {
+			tm.reconnectAll(reason)
+		}
+	}
+}
+
+func (tm *TunnelManager) reconnectAll(reason string) {
+	tunnels := tm.activeTunnels()
+	if len(tunnels) == 0 {
+		return
+	}
+
+	if tm.logChan != nil {
+		tm.logChan <- logEvent(events.LevelInfo, "network",
+			fmt.Sprintf("Detected %s, reconnecting %d tunnel(s)", reason, len(tunnels)))
+	}
+
+	for _, tunnel := range tunnels {
+		go tunnel.Reconnect(reason)
+	}
+}
@@ -0,0 +1,200 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// poolKey identifies an SSH login that tunnels can share
+type poolKey struct {
+	user  string
+	host  string
+	port  int
+	dscp  int    // Connections marked differently for QoS can't be shared
+	login string // Hash of how the connection is set up, see loginHash
+}
+
+// pooledClient is one shared SSH connection and how many tunnels use it
+type pooledClient struct {
+	client *ssh.Client
+	err    error
+	ready  chan struct{} // Closed once the dial has finished
+	refs   int
+}
+
+// errStopped is what a tunnel stopped while waiting for a pooled connection
+// gets instead
+var errStopped = errors.New("tunnel stopped while waiting for the SSH connection")
+
+// clientPool shares SSH connections between tunnels that log in to the same
+// server as the same user, so twenty tunnels through one bastion make one
+// handshake and hold one connection instead of twenty
+type clientPool struct {
+	mu      sync.Mutex
+	clients map[poolKey]*pooledClient
+}
+
+func newClientPool() *clientPool {
+	return &clientPool{clients: make(map[poolKey]*pooledClient)}
+}
+
+// acquire returns the connection for key, calling dial only when nobody has
+// one yet. Tunnels asking while a dial is in flight wait for it rather than
+// dialing again, until stop is closed. shared reports whether an existing
+// connection was reused.
+func (p *clientPool) acquire(key poolKey, stop <-chan struct{}, dial func() (*ssh.Client, error)) (client *ssh.Client, shared bool, err error) {
+	p.mu.Lock()
+	if entry, ok := p.clients[key]; ok {
+		entry.refs++
+		p.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+		case <-stop:
+			// The tunnel dialing carries on without this one
+			p.mu.Lock()
+			entry.refs--
+			p.mu.Unlock()
+			return nil, false, errStopped
+		}
+		if entry.err != nil {
+			return nil, false, entry.err
+		}
+		return entry.client, true, nil
+	}
+
+	entry := &pooledClient{ready: make(chan struct{}), refs: 1}
+	p.clients[key] = entry
+	p.mu.Unlock()
+
+	// Dial without holding the lock, a host key prompt may take a while
+	entry.client, entry.err = dial()
+
+	p.mu.Lock()
+	if entry.err != nil && p.clients[key] == entry {
+		delete(p.clients, key)
+	}
+	p.mu.Unlock()
+	close(entry.ready)
+
+	if entry.err != nil {
+		return nil, false, entry.err
+	}
+
+	// Forget connections that die, so the next tunnel dials a fresh one
+	go func() {
+		entry.client.Wait()
+		p.mu.Lock()
+		if p.clients[key] == entry {
+			delete(p.clients, key)
+		}
+		p.mu.Unlock()
+	}()
+
+	return entry.client, false, nil
+}
+
+// release gives back a connection, closing it once no tunnel uses it. A
+// broken connection is closed right away so nobody else picks it up.
+func (p *clientPool) release(client *ssh.Client, broken bool) {
+	// Connections no longer in the pool have no other users to wait for
+	unused := true
+
+	p.mu.Lock()
+	for key, entry := range p.clients {
+		if entry.client != client {
+			continue
+		}
+		entry.refs--
+		if entry.refs > 0 && !broken {
+			unused = false
+		} else {
+			delete(p.clients, key)
+		}
+		break
+	}
+	p.mu.Unlock()
+
+	if unused {
+		client.Close()
+	}
+}
+
+// users reports how many tunnels share the connection for key
+func (p *clientPool) users(key poolKey) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.clients[key]; ok {
+		return entry.refs
+	}
+	return 0
+}
+
+// openClient dials the SSH server at endpoint, reusing the connection
+// another tunnel already has to the same server as the same user
+func (t *Tunnel) openClient(endpoint *Endpoint, clientConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	if t.pool == nil {
+		client, err := t.dialSSH(endpoint, clientConfig)
+		if err != nil {
+			t.countHandshakeFailure(err)
+		}
+		return client, err
+	}
+
+	key := poolKey{
+		user:  clientConfig.User,
+		host:  endpoint.Host,
+		port:  endpoint.Port,
+		dscp:  t.dscp(),
+		login: t.loginHash(endpoint, clientConfig),
+	}
+	client, shared, err := t.pool.acquire(key, t.stopChan, func() (*ssh.Client, error) {
+		return t.dialSSH(endpoint, clientConfig)
+	})
+	if shared {
+		t.logf("Sharing the SSH connection to %s with %d other tunnel(s)", endpoint.String(), t.pool.users(key)-1)
+	}
+	if err != nil && err != errStopped {
+		t.countHandshakeFailure(err)
+	}
+	return client, err
+}
+
+// loginHash sums up everything besides the server and user that goes into
+// dialing endpoint: the algorithms allowed, the identities and secrets logged
+// in with, and the ProxyJump or ProxyCommand taken. Tunnels that differ in
+// any of them get connections of their own.
+func (t *Tunnel) loginHash(endpoint *Endpoint, clientConfig *ssh.ClientConfig) string {
+	hash := sha256.New()
+	write := func(name string, values ...string) {
+		fmt.Fprintf(hash, "%s=%q\n", name, values)
+	}
+	write("ciphers", clientConfig.Ciphers...)
+	write("macs", clientConfig.MACs...)
+	write("kex", clientConfig.KeyExchanges...)
+	write("host_key_algorithms", clientConfig.HostKeyAlgorithms...)
+	write("identity_files", t.identityFiles...)
+	write("auth", t.Config.Auth.Password, t.Config.Auth.TOTP)
+	if proxy := t.proxy; proxy != nil && proxy.endpoint == endpoint.String() {
+		write("proxy", proxy.user, proxy.jump, proxy.command)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// closeClient lets go of a client, which only closes it once no other tunnel
+// shares it, unless broken says it's no good to anyone anymore
+func (t *Tunnel) closeClient(client *ssh.Client, broken bool) {
+	if client == nil {
+		return
+	}
+	if t.pool == nil {
+		client.Close()
+		return
+	}
+	t.pool.release(client, broken)
+}
@@ -0,0 +1,191 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// serveSSH runs a throwaway SSH server that accepts anyone, counting the
+// handshakes it completes
+func serveSSH(t *testing.T) (string, *int32) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var handshakes int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				atomic.AddInt32(&handshakes, 1)
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "test server")
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), &handshakes
+}
+
+func TestClientPool(t *testing.T) {
+	addr, handshakes := serveSSH(t)
+	dial := func() (*ssh.Client, error) {
+		return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+			User:            "ops",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+	}
+
+	pool := newClientPool()
+	key := poolKey{user: "ops", host: "127.0.0.1", port: 22}
+
+	first, shared, err := pool.acquire(key, nil, dial)
+	if err != nil || shared {
+		t.Fatalf("expected a fresh connection, got shared=%v err=%v", shared, err)
+	}
+	second, shared, err := pool.acquire(key, nil, dial)
+	if err != nil || !shared || second != first {
+		t.Fatalf("expected the connection to be shared, got shared=%v err=%v", shared, err)
+	}
+	if got := atomic.LoadInt32(handshakes); got != 1 {
+		t.Errorf("expected 1 handshake, got %d", got)
+	}
+
+	// Still in use by the second tunnel
+	pool.release(first, false)
+	if pool.users(key) != 1 {
+		t.Errorf("expected 1 user left, got %d", pool.users(key))
+	}
+	if _, _, err := first.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		t.Errorf("expected shared connection to stay open, got %v", err)
+	}
+
+	// A broken connection is dropped for everyone
+	pool.release(second, true)
+	if pool.users(key) != 0 {
+		t.Errorf("expected broken connection to leave the pool")
+	}
+
+	third, shared, err := pool.acquire(key, nil, dial)
+	if err != nil || shared || third == first {
+		t.Fatalf("expected a new connection after the broken one, got shared=%v err=%v", shared, err)
+	}
+
+	// Connections closed underneath the pool are forgotten
+	third.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.users(key) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pool.users(key) != 0 {
+		t.Errorf("expected dead connection to be evicted")
+	}
+}
+
+func TestLoginHash(t *testing.T) {
+	endpoint := &Endpoint{Host: "jump.example.com", Port: 22}
+	tunnel := func() *Tunnel {
+		return &Tunnel{identityFiles: []string{"~/.ssh/id_ed25519"}}
+	}
+	base := tunnel()
+	same := tunnel()
+	if base.loginHash(endpoint, &ssh.ClientConfig{}) != same.loginHash(endpoint, &ssh.ClientConfig{}) {
+		t.Fatal("expected tunnels set up the same way to share a connection")
+	}
+
+	ciphers := &ssh.ClientConfig{}
+	ciphers.Ciphers = []string{"aes128-cbc"}
+	identity := tunnel()
+	identity.identityFiles = []string{"~/.ssh/legacy_rsa"}
+	password := tunnel()
+	password.Config.Auth.Password = "keyring:ssh"
+	jump := tunnel()
+	jump.proxy = &proxySettings{endpoint: endpoint.String(), jump: "edge.example.com"}
+	otherJump := tunnel()
+	otherJump.proxy = &proxySettings{endpoint: "other.example.com:22", jump: "edge.example.com"}
+
+	for name, hash := range map[string]string{
+		"ssh_options": base.loginHash(endpoint, ciphers),
+		"identity":    identity.loginHash(endpoint, &ssh.ClientConfig{}),
+		"password":    password.loginHash(endpoint, &ssh.ClientConfig{}),
+		"proxy jump":  jump.loginHash(endpoint, &ssh.ClientConfig{}),
+	} {
+		if hash == base.loginHash(endpoint, &ssh.ClientConfig{}) {
+			t.Errorf("expected a different %s to get its own connection", name)
+		}
+	}
+	// A ProxyJump for another server doesn't apply to this one
+	if otherJump.loginHash(endpoint, &ssh.ClientConfig{}) != base.loginHash(endpoint, &ssh.ClientConfig{}) {
+		t.Error("expected a proxy for another endpoint to be left out")
+	}
+}
+
+func TestClientPoolWaiterStops(t *testing.T) {
+	pool := newClientPool()
+	key := poolKey{user: "ops", host: "127.0.0.1", port: 22}
+
+	// The first tunnel's dial hangs, say on a host key prompt
+	dialing := make(chan struct{})
+	unblock := make(chan struct{})
+	go pool.acquire(key, nil, func() (*ssh.Client, error) {
+		close(dialing)
+		<-unblock
+		return nil, fmt.Errorf("host key rejected")
+	})
+	<-dialing
+	defer close(unblock)
+
+	stop := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := pool.acquire(key, stop, func() (*ssh.Client, error) {
+			t.Error("expected the waiting tunnel not to dial")
+			return nil, nil
+		})
+		result <- err
+	}()
+	for deadline := time.Now().Add(time.Second); pool.users(key) != 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(stop)
+	select {
+	case err := <-result:
+		if err != errStopped {
+			t.Errorf("expected the waiter to give up once stopped, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a stopped tunnel not to wait for the dial")
+	}
+	if pool.users(key) != 1 {
+		t.Errorf("expected only the dialing tunnel left, got %d", pool.users(key))
+	}
+}
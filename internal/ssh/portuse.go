@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"tunnel9/internal/config"
+)
+
+// portHolder returns the running tunnel other than t already listening
+// where t would, on listenAddr
+func (tm *TunnelManager) portHolder(t *Tunnel, listenAddr string) *Tunnel {
+	host, _, err := net.SplitHostPort(listenAddr)
+	if err != nil || t.Config.LocalPort == 0 {
+		return nil
+	}
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	for _, other := range tm.tunnels {
+		if other == t || other.Config.LocalPort != t.Config.LocalPort || other.Config.IsUDP() != t.Config.IsUDP() {
+			continue
+		}
+		other.listenerMu.Lock()
+		listening := other.Listener != nil || other.PacketConn != nil
+		otherAddr := other.listenAddr
+		other.listenerMu.Unlock()
+		if !listening {
+			continue
+		}
+		if otherHost, _, err := net.SplitHostPort(otherAddr); err == nil && config.BindOverlaps(host, otherHost) {
+			return other
+		}
+	}
+	return nil
+}
+
+// listenError explains a failed listen, naming the process holding the port
+// when that's what stopped it
+func listenError(t *Tunnel, err error) error {
+	port := t.Config.LocalPort
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	if holder := portProcess(t.Config.IsUDP(), port); holder != "" {
+		return fmt.Errorf("port %d in use by %s", port, holder)
+	}
+	return fmt.Errorf("port %d in use by another process", port)
+}
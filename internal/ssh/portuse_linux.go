@@ -0,0 +1,76 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// portProcess names the process listening on a local port, like
+// "postgres (pid 812)", from /proc. Sockets of other users' processes can't
+// be traced, leaving "".
+func portProcess(udp bool, port int) string {
+	tables, state := []string{"/proc/net/tcp", "/proc/net/tcp6"}, "0A" // LISTEN
+	if udp {
+		tables, state = []string{"/proc/net/udp", "/proc/net/udp6"}, "07" // Unconnected
+	}
+	inodes := make(map[string]bool)
+	for _, table := range tables {
+		for inode := range socketInodes(table, port, state) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+		pid := strings.Split(fd, "/")[2]
+		comm, err := os.ReadFile(filepath.Join("/proc", pid, "comm"))
+		if err != nil {
+			return fmt.Sprintf("pid %s", pid)
+		}
+		return fmt.Sprintf("%s (pid %s)", strings.TrimSpace(string(comm)), pid)
+	}
+	return ""
+}
+
+// socketInodes reads the inodes of the sockets on port in state from a
+// /proc/net table
+func socketInodes(table string, port int, state string) map[string]bool {
+	inodes := make(map[string]bool)
+	file, err := os.Open(table)
+	if err != nil {
+		return inodes
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != state {
+			continue
+		}
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+			inodes[fields[9]] = true
+		}
+	}
+	return inodes
+}
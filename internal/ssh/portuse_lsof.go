@@ -0,0 +1,36 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+
+package ssh
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// portProcess names the process listening on a local port, like
+// "postgres (pid 812)", asking lsof. Without lsof it's "".
+func portProcess(udp bool, port int) string {
+	args := []string{"-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc"}
+	if udp {
+		args = []string{"-nP", fmt.Sprintf("-iUDP:%d", port), "-Fpc"}
+	}
+	out, err := exec.Command("lsof", args...).Output()
+	if err != nil {
+		return ""
+	}
+	// One field per line: p<pid>, then c<command>
+	var pid string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == "":
+			pid = line[1:]
+		case strings.HasPrefix(line, "c") && pid != "":
+			return fmt.Sprintf("%s (pid %s)", line[1:], pid)
+		}
+	}
+	if pid != "" {
+		return "pid " + pid
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ssh
+
+// portProcess can't tell which process holds a port here
+func portProcess(udp bool, port int) string {
+	return ""
+}
@@ -0,0 +1,210 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// proxySettings is how ~/.ssh/config says to reach a tunnel's SSH server
+// when it can't be dialed directly
+type proxySettings struct {
+	endpoint string // SSH server these apply to, standbys are dialed directly
+	user     string
+	jump     string // Comma separated [user@]host[:port] hops
+	command  string // Command whose stdin and stdout reach the server
+}
+
+func newProxySettings(settings *ResolvedSettings) *proxySettings {
+	if settings.ProxyJump == "" && settings.ProxyCommand == "" {
+		return nil
+	}
+	return &proxySettings{
+		endpoint: settings.SSHEndpoint().String(),
+		user:     settings.User,
+		jump:     settings.ProxyJump,
+		command:  settings.ProxyCommand,
+	}
+}
+
+// dialSSH connects to the SSH server at endpoint the way plain ssh would,
+// through its ProxyCommand or ProxyJump hosts if it has any
+func (t *Tunnel) dialSSH(endpoint *Endpoint, clientConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	proxy := t.proxy
+	if proxy == nil || proxy.endpoint != endpoint.String() {
+		return t.dialClient(endpoint.String(), clientConfig)
+	}
+	if proxy.command != "" {
+		return t.dialProxyCommand(endpoint, clientConfig, proxy)
+	}
+	return t.dialProxyJump(endpoint, clientConfig, proxy)
+}
+
+// expandProxyCommand fills in the tokens ssh_config allows in ProxyCommand
+func expandProxyCommand(command string, host string, port int, user string) string {
+	return strings.NewReplacer(
+		"%%", "%",
+		"%h", host,
+		"%p", strconv.Itoa(port),
+		"%r", user,
+	).Replace(command)
+}
+
+func (t *Tunnel) dialProxyCommand(endpoint *Endpoint, clientConfig *ssh.ClientConfig, proxy *proxySettings) (*ssh.Client, error) {
+	command := expandProxyCommand(proxy.command, endpoint.Host, endpoint.Port, proxy.user)
+	t.logf("Connecting through ProxyCommand: %s", command)
+
+	conn, err := startProxyCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("ProxyCommand failed: %w", err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, endpoint.String(), clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func (t *Tunnel) dialProxyJump(endpoint *Endpoint, clientConfig *ssh.ClientConfig, proxy *proxySettings) (*ssh.Client, error) {
+	var hops []*ssh.Client
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			hops[i].Close()
+		}
+	}
+
+	// Each hop is dialed through the one before it, then the server itself
+	// through the last
+	dial := func(address string, hopConfig *ssh.ClientConfig) (*ssh.Client, error) {
+		if len(hops) == 0 {
+			return t.dialClient(address, hopConfig)
+		}
+		conn, err := hops[len(hops)-1].Dial("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, address, hopConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+
+	for _, hop := range strings.Split(proxy.jump, ",") {
+		hopEndpoint, hopConfig, err := t.jumpHostConfig(strings.TrimSpace(hop))
+		if err != nil {
+			closeHops()
+			return nil, err
+		}
+		t.logf("Jumping through %s", hopEndpoint.String())
+		client, err := dial(hopEndpoint.String(), hopConfig)
+		if err != nil {
+			closeHops()
+			return nil, fmt.Errorf("jump host %s: %w", hopEndpoint.String(), err)
+		}
+		hops = append(hops, client)
+	}
+
+	client, err := dial(endpoint.String(), clientConfig)
+	if err != nil {
+		closeHops()
+		return nil, err
+	}
+
+	// The hops only carry this client, so they go when it does
+	go func() {
+		client.Wait()
+		closeHops()
+	}()
+	return client, nil
+}
+
+// jumpHostConfig resolves a ProxyJump hop through ~/.ssh/config like any
+// other host, except for its own proxy settings
+func (t *Tunnel) jumpHostConfig(hop string) (*Endpoint, *ssh.ClientConfig, error) {
+	hopEndpoint := NewEndpointFromString(strings.TrimPrefix(hop, "ssh://"))
+
+	var tc config.TunnelConfig
+	tc.Bastion.Host = hopEndpoint.Host
+	tc.Bastion.Port = hopEndpoint.Port
+	tc.Bastion.User = hopEndpoint.User
+	tc.SSHConfigIgnore = []string{"proxy"}
+
+	settings, err := ResolveSSHSettings(tc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return settings.SSHEndpoint(), clientConfig(t, settings), nil
+}
+
+// proxyCommandConn talks to the SSH server through a ProxyCommand's stdin
+// and stdout
+type proxyCommandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+func startProxyCommand(command string) (*proxyCommandConn, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &proxyCommandConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (c *proxyCommandConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+func (c *proxyCommandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+func (c *proxyCommandConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+// Like the x/crypto/ssh channels, there is no real address to report
+func (c *proxyCommandConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
+
+func (c *proxyCommandConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4zero}
+}
+
+func (c *proxyCommandConn) SetDeadline(time.Time) error      { return nil }
+func (c *proxyCommandConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *proxyCommandConn) SetWriteDeadline(time.Time) error { return nil }
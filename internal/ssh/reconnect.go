@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Reconnect defaults when a tunnel's reconnect policy leaves them out
+const (
+	defaultReconnectRetries = 10
+	defaultReconnectDelay   = time.Second
+	defaultReconnectMax     = time.Minute
+)
+
+// reconnectPolicy returns how often and how patiently a tunnel redials,
+// with zero retries when it should wait for the next local connection instead
+func reconnectPolicy(policy config.ReconnectPolicy) (int, time.Duration, time.Duration) {
+	retries := policy.MaxRetries
+	switch {
+	case retries < 0:
+		retries = 0
+	case retries == 0:
+		retries = defaultReconnectRetries
+	}
+
+	initial := defaultReconnectDelay
+	if d, err := time.ParseDuration(policy.InitialDelay); err == nil && d > 0 {
+		initial = d
+	}
+	maxDelay := defaultReconnectMax
+	if d, err := time.ParseDuration(policy.MaxDelay); err == nil && d > 0 {
+		maxDelay = d
+	}
+	if maxDelay < initial {
+		maxDelay = initial
+	}
+	return retries, initial, maxDelay
+}
+
+// backoffDelay is the wait before the given attempt, doubling from initial
+// up to maxDelay. jitter in [0, 1) spreads it over the upper half so tunnels
+// sharing a dead bastion don't all redial at the same instant.
+func backoffDelay(attempt int, initial time.Duration, maxDelay time.Duration, jitter float64) time.Duration {
+	delay := initial
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay/2 + time.Duration(jitter*float64(delay/2))
+}
+
+// watchClient waits for a client to go away and starts reconnecting if it
+// died on its own rather than being closed by the tunnel
+func (t *Tunnel) watchClient(client *ssh.Client) {
+	err := client.Wait()
+
+	t.clientMu.Lock()
+	lost := t.Client == client
+	if lost {
+		t.Client = nil
+	}
+	t.clientMu.Unlock()
+	if !lost {
+		return
+	}
+
+	t.closeClient(client, true)
+	t.superviseReconnect(fmt.Sprintf("connection lost: %v", err))
+}
+
+// superviseReconnect redials the SSH server after the connection died, with
+// exponential backoff and jitter between attempts. The local port stays
+// bound throughout, and a local connection may bring the client back first.
+func (t *Tunnel) superviseReconnect(reason string) {
+	retries, initial, maxDelay := reconnectPolicy(t.Config.Reconnect)
+	// The UDP relay rebuilds its own connection
+	if retries == 0 || t.sshConfig == nil || t.Config.IsUDP() {
+		return
+	}
+
+	// One loop per tunnel is plenty
+	if !atomic.CompareAndSwapInt32(&t.reconnecting, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&t.reconnecting, 0)
+
+	t.warnf("SSH connection lost (%s), reconnecting", reason)
+	for attempt := 1; attempt <= retries; attempt++ {
+		delay := backoffDelay(attempt, initial, maxDelay, rand.Float64())
+		t.updateStatus("connecting", fmt.Sprintf("reconnecting %d/%d in %v", attempt, retries, delay.Round(time.Second)))
+
+		select {
+		case <-t.stopChan:
+			return
+		case <-time.After(delay):
+		}
+
+		t.clientMu.Lock()
+		if t.Client != nil {
+			// A local connection got there first
+			t.clientMu.Unlock()
+			return
+		}
+		sshEndpoint, _, clientConfig := t.currentTarget(t.sshConfig)
+		t.clientMu.Unlock()
+
+		t.updateStatus("connecting", fmt.Sprintf("reconnecting %d/%d", attempt, retries))
+		client, err := t.openClient(sshEndpoint, clientConfig)
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			t.warnf("Reconnect %d/%d to %s failed: %v", attempt, retries, sshEndpoint.String(), err)
+			continue
+		}
+
+		t.clientMu.Lock()
+		if t.Client != nil {
+			t.clientMu.Unlock()
+			t.closeClient(client, false)
+			return
+		}
+		t.Client = client
+		t.clientMu.Unlock()
+
+		go t.watchClient(client)
+		t.infof("Reconnected to %s after %d attempt(s)", sshEndpoint.String(), attempt)
+		t.updateStatus("active", "reconnected")
+		return
+	}
+
+	t.warnf("Giving up reconnecting after %d attempts, the next connection will try again", retries)
+	t.updateStatus("error", fmt.Sprintf("reconnect failed after %d attempts", retries))
+}
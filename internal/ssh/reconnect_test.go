@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		jitter  float64
+		want    time.Duration
+	}{
+		{1, 0.999999, time.Second},
+		{2, 0.999999, 2 * time.Second},
+		{4, 0.999999, 8 * time.Second},
+		{4, 0, 4 * time.Second}, // Jitter never goes below half
+		{20, 0.999999, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		got := backoffDelay(tt.attempt, time.Second, 30*time.Second, tt.jitter)
+		if got.Round(time.Millisecond) != tt.want {
+			t.Errorf("attempt %d jitter %v: expected %v, got %v", tt.attempt, tt.jitter, tt.want, got)
+		}
+	}
+}
+
+func TestReconnectPolicy(t *testing.T) {
+	retries, initial, maxDelay := reconnectPolicy(config.ReconnectPolicy{})
+	if retries != 10 || initial != time.Second || maxDelay != time.Minute {
+		t.Errorf("unexpected defaults %d/%v/%v", retries, initial, maxDelay)
+	}
+
+	retries, initial, maxDelay = reconnectPolicy(config.ReconnectPolicy{MaxRetries: 3, InitialDelay: "5s", MaxDelay: "2s"})
+	if retries != 3 || initial != 5*time.Second || maxDelay != 5*time.Second {
+		t.Errorf("expected max delay raised to the initial delay, got %d/%v/%v", retries, initial, maxDelay)
+	}
+
+	if retries, _, _ := reconnectPolicy(config.ReconnectPolicy{MaxRetries: -1}); retries != 0 {
+		t.Errorf("expected -1 to turn reconnecting off, got %d retries", retries)
+	}
+}
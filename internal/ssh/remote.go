@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// remoteConn is a connection to a unix socket on another machine, which
+// takes its SSH connection down with it
+type remoteConn struct {
+	net.Conn
+	client *ssh.Client
+}
+
+func (c *remoteConn) Close() error {
+	err := c.Conn.Close()
+	c.client.Close()
+	return err
+}
+
+// DialRemoteSocket connects to a unix socket on another machine, given as
+// [user@]host[:port], through SSH set up like any tunnel's: ~/.ssh/config,
+// the agent, identity files and known_hosts all apply. A relative socket
+// path is taken relative to the remote home directory.
+func DialRemoteSocket(destination string, socket string) (net.Conn, error) {
+	endpoint := NewEndpointFromString(destination)
+	if endpoint.Port == 0 {
+		endpoint.Port = 22
+	}
+
+	t := &Tunnel{}
+	t.Config.Name = destination
+	t.Config.RemoteHost = "localhost"
+	t.Config.Bastion.Host = endpoint.Host
+	t.Config.Bastion.User = endpoint.User
+	t.Config.Bastion.Port = endpoint.Port
+
+	sshconfig, err := GetSSHConfig(t)
+	if err != nil {
+		return nil, err
+	}
+	sshEndpoint, _ := figureOutRemoteVsBastion(t.Config)
+	client, err := t.dialSSH(sshEndpoint, sshconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", destination, err)
+	}
+
+	if !path.IsAbs(socket) {
+		home, err := remoteHome(client)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		socket = path.Join(home, socket)
+	}
+
+	conn, err := client.Dial("unix", socket)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("no tunnel9 daemon at %s:%s: %w", destination, socket, err)
+	}
+	return &remoteConn{Conn: conn, client: client}, nil
+}
+
+// remoteHome asks the server for the user's home directory
+func remoteHome(client *ssh.Client) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	out, err := session.Output(`printf %s "$HOME"`)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the remote home directory: %w", err)
+	}
+	home := strings.TrimSpace(string(out))
+	if !path.IsAbs(home) {
+		return "", fmt.Errorf("unexpected remote home directory %q", home)
+	}
+	return home, nil
+}
@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/resolver"
+)
+
+var (
+	resolverMu     sync.RWMutex
+	dnsConfig      config.DNSConfig
+	customResolver *net.Resolver // Nil to use the system resolver
+)
+
+// SetResolver makes SSH server names, and remote hosts if asked to, resolve
+// through the configured DNS server. Without a server the system resolver
+// is used.
+func SetResolver(cfg config.DNSConfig) error {
+	var r *net.Resolver
+	if cfg.Server != "" {
+		var err error
+		if r, err = resolver.New(cfg.Server); err != nil {
+			return err
+		}
+	}
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	dnsConfig = cfg
+	customResolver = r
+	return nil
+}
+
+// resolverFor returns the resolver to look host up with
+func resolverFor(host string) *net.Resolver {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	if customResolver != nil && resolver.Matches(host, dnsConfig.Domains) {
+		return customResolver
+	}
+	return net.DefaultResolver
+}
+
+// How long resolving a remote host may take before the SSH server is left
+// to do it
+const remoteResolveTimeout = 5 * time.Second
+
+// remoteAddress is what the SSH server is asked to connect to: the remote
+// host as named, or its address when dns.resolve_remote has it looked up
+// here because only our DNS server knows it
+func (t *Tunnel) remoteAddress(endpoint *Endpoint) string {
+	resolverMu.RLock()
+	resolveRemote := dnsConfig.ResolveRemote
+	resolverMu.RUnlock()
+	if !resolveRemote || net.ParseIP(endpoint.Host) != nil {
+		return endpoint.String()
+	}
+	r := resolverFor(endpoint.Host)
+	if r == net.DefaultResolver {
+		return endpoint.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteResolveTimeout)
+	defer cancel()
+	addrs, err := r.LookupIPAddr(ctx, endpoint.Host)
+	if err != nil || len(addrs) == 0 {
+		t.warnf("Failed to resolve %s, leaving it to the SSH server: %v", endpoint.Host, err)
+		return endpoint.String()
+	}
+	return net.JoinHostPort(addrs[0].IP.String(), strconv.Itoa(endpoint.Port))
+}
@@ -0,0 +1,32 @@
+package ssh
+
+import (
+	"net"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestSetResolver(t *testing.T) {
+	defer SetResolver(config.DNSConfig{})
+
+	if err := SetResolver(config.DNSConfig{Server: "quic://dns.example"}); err == nil {
+		t.Errorf("expected an unsupported server to be refused")
+	}
+
+	if err := SetResolver(config.DNSConfig{Server: "10.0.0.53", Domains: []string{"corp.internal"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolverFor("db.corp.internal") == net.DefaultResolver {
+		t.Errorf("expected corporate names to use the configured server")
+	}
+	if resolverFor("github.com") != net.DefaultResolver {
+		t.Errorf("expected other names to use the system resolver")
+	}
+
+	// Remote hosts are left to the SSH server unless asked otherwise
+	tunnel := &Tunnel{}
+	if addr := tunnel.remoteAddress(NewEndpoint("db.corp.internal", 5432)); addr != "db.corp.internal:5432" {
+		t.Errorf("expected the remote host as named, got %s", addr)
+	}
+}
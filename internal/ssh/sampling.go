@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"sync"
+
+	"tunnel9/internal/config"
+)
+
+var (
+	metricsMu       sync.RWMutex
+	defaultMetrics  = config.MetricsFull
+	metricsDisabled bool
+)
+
+// SetDefaultMetrics changes what tunnels without their own metrics setting
+// measure. An empty level restores full metrics.
+func SetDefaultMetrics(level string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if level == "" {
+		level = config.MetricsFull
+	}
+	defaultMetrics = level
+}
+
+// DisableMetrics turns metrics off for every tunnel, even those with a
+// metrics setting of their own, as safe mode does
+func DisableMetrics(disabled bool) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metricsDisabled = disabled
+}
+
+// metricsLevel is what the tunnel measures: off, basic or full
+func (t *Tunnel) metricsLevel() string {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	if metricsDisabled {
+		return config.MetricsOff
+	}
+	if t.Config.Metrics != "" {
+		return t.Config.Metrics
+	}
+	return defaultMetrics
+}
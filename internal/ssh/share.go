@@ -0,0 +1,210 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Share exposes a local port to the internet through a remote forward on a
+// public VPS
+type Share struct {
+	ID         string
+	URL        string
+	RemotePort int
+	client     *ssh.Client
+	listener   net.Listener
+}
+
+// expandShareTemplate fills the {name}, {host} and {port} placeholders used
+// by the helper command and URL template, passing each value through quote
+func expandShareTemplate(tmpl string, name string, host string, port int, quote func(string) string) string {
+	return strings.NewReplacer(
+		"{name}", quote(name),
+		"{host}", quote(host),
+		"{port}", quote(strconv.Itoa(port)),
+	).Replace(tmpl)
+}
+
+// shellQuote makes a value a single word to the remote shell, so a tunnel
+// named e.g. "db; rm -rf ~" can't run commands of its own
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// verbatim leaves a value as it is, for the URL template
+func verbatim(value string) string {
+	return value
+}
+
+// StartShare opens a remote forward on the configured VPS that leads back to
+// localAddr, runs the optional helper and works out the public URL
+func (tm *TunnelManager) StartShare(id string, name string, localAddr string, cfg config.PublicShareConfig) (*Share, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("no public_share host configured")
+	}
+
+	// A tunnel has one share at a time, the one still open is replaced, and
+	// closed first so its port on the VPS is free again
+	tm.StopShare(id)
+
+	// Reuse the regular SSH setup so ~/.ssh/config and keys apply to the VPS
+	t := &Tunnel{
+		ID:             id,
+		LogChan:        tm.logChan,
+		hostKeyPrompts: tm.hostKeyPrompts,
+		authPrompts:    tm.authPrompts,
+	}
+	t.Config.Name = name
+	t.Config.Bastion.Host = cfg.Host
+	t.Config.Bastion.User = cfg.User
+	t.Config.Bastion.Port = cfg.Port
+
+	sshconfig, err := GetSSHConfig(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH config: %w", err)
+	}
+
+	port := t.Config.Bastion.Port
+	if port == 0 {
+		port = 22
+	}
+	vpsEndpoint := NewEndpoint(t.Config.Bastion.Host, port)
+
+	t.logf("Connecting to public share host %s", vpsEndpoint.String())
+	client, err := t.dialSSH(vpsEndpoint, sshconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", vpsEndpoint.String(), err)
+	}
+
+	bindAddress := cfg.BindAddress
+	if bindAddress == "" {
+		bindAddress = "0.0.0.0"
+	}
+	listener, err := client.Listen("tcp", net.JoinHostPort(bindAddress, strconv.Itoa(cfg.BindPort)))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("remote forward refused: %w", err)
+	}
+
+	share := &Share{
+		ID:       id,
+		client:   client,
+		listener: listener,
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		share.RemotePort = addr.Port
+	}
+
+	// Give the helper a chance to wire up a hostname or path and report it
+	if cfg.Helper != "" {
+		helper := expandShareTemplate(cfg.Helper, name, cfg.Host, share.RemotePort, shellQuote)
+		if url, err := runShareHelper(client, helper); err != nil {
+			t.warnf("Share helper failed: %v", err)
+		} else {
+			share.URL = url
+		}
+	}
+
+	if share.URL == "" && cfg.URL != "" {
+		share.URL = expandShareTemplate(cfg.URL, name, cfg.Host, share.RemotePort, verbatim)
+	}
+	if share.URL == "" {
+		share.URL = fmt.Sprintf("http://%s:%d", cfg.Host, share.RemotePort)
+	}
+
+	go share.serve(t, localAddr)
+
+	// Close whatever share was started for the tunnel in the meantime
+	tm.mu.Lock()
+	previous := tm.shares[id]
+	tm.shares[id] = share
+	tm.mu.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+
+	t.infof("Sharing %s publicly at %s", localAddr, share.URL)
+	return share, nil
+}
+
+// runShareHelper runs a command on the VPS and returns the first URL it prints
+func runShareHelper(client *ssh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	out, err := session.CombinedOutput(command)
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			return line, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *Share) serve(t *Tunnel, localAddr string) {
+	for {
+		remote, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer remote.Close()
+
+			local, err := net.Dial("tcp", localAddr)
+			if err != nil {
+				t.warnf("Share could not reach %s: %v", localAddr, err)
+				return
+			}
+			defer local.Close()
+
+			// Once either side is done, closing both unblocks the other copy
+			done := make(chan struct{}, 2)
+			go func() {
+				io.Copy(local, remote)
+				done <- struct{}{}
+			}()
+			go func() {
+				io.Copy(remote, local)
+				done <- struct{}{}
+			}()
+			<-done
+		}()
+	}
+}
+
+func (s *Share) close() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.client != nil {
+		s.client.Close()
+	}
+}
+
+// StopShare tears down a public share
+func (tm *TunnelManager) StopShare(id string) {
+	tm.mu.Lock()
+	share, exists := tm.shares[id]
+	delete(tm.shares, id)
+	tm.mu.Unlock()
+
+	if exists {
+		share.close()
+	}
+}
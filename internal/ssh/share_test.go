@@ -0,0 +1,18 @@
+package ssh
+
+import "testing"
+
+func TestExpandShareTemplate(t *testing.T) {
+	helper := expandShareTemplate("sudo expose {name} {port}", "db; rm -rf ~", "vps.example.com", 41022, shellQuote)
+	if want := `sudo expose 'db; rm -rf ~' '41022'`; helper != want {
+		t.Errorf("expected %q, got %q", want, helper)
+	}
+	if quoted := shellQuote("it's"); quoted != `'it'\''s'` {
+		t.Errorf("expected a quote inside the name escaped, got %q", quoted)
+	}
+
+	url := expandShareTemplate("https://{name}.share.example.com:{port}", "db", "vps.example.com", 443, verbatim)
+	if want := "https://db.share.example.com:443"; url != want {
+		t.Errorf("expected %q, got %q", want, url)
+	}
+}
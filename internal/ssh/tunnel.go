@@ -6,9 +6,11 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"tunnel9/internal/config"
+	"tunnel9/internal/events"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -22,6 +24,7 @@ type TunnelMetrics struct {
 	CurrentRateIn  float64 // bytes per second
 	CurrentRateOut float64 // bytes per second
 	Latency        time.Duration
+	Health         string // Result of the last health check, empty without one
 	mu             sync.Mutex
 }
 
@@ -39,19 +42,49 @@ type TunnelOptions struct {
 }
 
 type Tunnel struct {
-	ID         string
-	Client     *ssh.Client
-	Config     config.TunnelConfig
-	LogChan    chan string
-	StatusChan chan TunnelStatus
-	Listener   net.Listener
-	Metrics    TunnelMetrics
-	stopChan   chan struct{} // Add stop channel for clean shutdown
-	clientMu   sync.RWMutex  // Protect SSH client access
+	ID             string
+	Client         *ssh.Client
+	Config         config.TunnelConfig
+	LogChan        chan events.Event
+	StatusChan     chan TunnelStatus
+	chanMu         sync.RWMutex // Guards sends on LogChan and StatusChan against StopTunnel closing them
+	Listener       net.Listener
+	PacketConn     net.PacketConn // Local socket for udp and wireguard tunnels
+	Metrics        TunnelMetrics
+	stopChan       chan struct{} // Add stop channel for clean shutdown
+	clientMu       sync.RWMutex  // Protect SSH client access
+	listenerMu     sync.Mutex    // Protect listener swaps when re-binding
+	listenAddr     string        // Address the listener is currently bound to
+	sshConfig      *ssh.ClientConfig
+	targetIndex    int   // 0 is the primary target, higher values are standbys
+	activeConns    int32 // Local connections currently being forwarded
+	lastActivity   int64 // Unix nanoseconds of the last forwarded connection
+	conns          map[int64]*ConnInfo
+	connsMu        sync.Mutex
+	nextConnID     int64
+	hostKeyPrompts chan<- *HostKeyPrompt // Nil when unknown hosts are trusted on first use
+	authPrompts    chan<- *AuthPrompt    // Nil when keyboard-interactive auth isn't offered
+	proxy          *proxySettings        // ProxyJump or ProxyCommand from ~/.ssh/config, if any
+	identityFiles  []string              // Keys offered when logging in, as resolved with the ssh_config
+	pool           *clientPool           // Nil when the tunnel always dials its own connection
+	reconnecting   int32                 // 1 while superviseReconnect is redialing
+	activeSince    int64                 // Unix nanoseconds since the tunnel has been active, 0 while it isn't
+	handshakes     handshakeCounters     // Failed SSH logins by cause, since the tunnel started
 }
 
 func (t *Tunnel) updateStatus(state string, message string) {
-	if t != nil && t.StatusChan != nil {
+	if t == nil {
+		return
+	}
+	// Keep counting uptime through messages that stay active
+	if state == "active" {
+		atomic.CompareAndSwapInt64(&t.activeSince, 0, time.Now().UnixNano())
+	} else {
+		atomic.StoreInt64(&t.activeSince, 0)
+	}
+	t.chanMu.RLock()
+	defer t.chanMu.RUnlock()
+	if t.StatusChan != nil {
 		t.StatusChan <- TunnelStatus{
 			ID:      t.ID,
 			State:   state,
@@ -60,14 +93,66 @@ func (t *Tunnel) updateStatus(state string, message string) {
 	}
 }
 
+// localAddress returns where the listener accepts connections, as dialable
+// from this machine
+func (t *Tunnel) localAddress() (string, bool) {
+	t.listenerMu.Lock()
+	addr := t.listenAddr
+	t.listenerMu.Unlock()
+	if addr == "" {
+		return "", false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// logEvent makes a log line from source, a tunnel's name or a part of the
+// manager, at level
+func logEvent(level events.Level, source string, msg string) events.Event {
+	now := time.Now()
+	return events.Event{
+		Kind:    events.KindLog,
+		Level:   level,
+		Message: fmt.Sprintf("%s %s [%s] %s", now.Format("15:04:05"), level, source, msg),
+		Time:    now,
+	}
+}
+
+// logf logs chatter, like connection steps and health checks
 func (t *Tunnel) logf(format string, args ...interface{}) {
+	t.logAt(events.LevelDebug, format, args...)
+}
+
+// infof logs the tunnel starting, stopping or recovering
+func (t *Tunnel) infof(format string, args ...interface{}) {
+	t.logAt(events.LevelInfo, format, args...)
+}
+
+// warnf logs trouble the tunnel works around, like a dropped connection
+func (t *Tunnel) warnf(format string, args ...interface{}) {
+	t.logAt(events.LevelWarn, format, args...)
+}
+
+func (t *Tunnel) logAt(level events.Level, format string, args ...interface{}) {
 	if t == nil || t.Config.Name == "" {
 		return
 	}
+	t.sendLog(logEvent(level, t.Config.Name, fmt.Sprintf(format, args...)))
+}
 
-	msg := fmt.Sprintf("DEBUG [%s] %s", t.Config.Name, fmt.Sprintf(format, args...))
+// sendLog hands a log line to the manager, unless the tunnel was stopped
+func (t *Tunnel) sendLog(event events.Event) {
+	t.chanMu.RLock()
+	defer t.chanMu.RUnlock()
 	if t.LogChan != nil {
-		t.LogChan <- fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), msg)
+		t.LogChan <- event
 	}
 }
 
@@ -76,10 +161,7 @@ func (t *Tunnel) errorf(format string, args ...interface{}) {
 		return
 	}
 
-	msg := fmt.Sprintf("ERROR [%s] %s", t.Config.Name, fmt.Sprintf(format, args...))
-	if t.LogChan != nil {
-		t.LogChan <- fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), msg)
-	}
+	t.sendLog(logEvent(events.LevelError, t.Config.Name, fmt.Sprintf(format, args...)))
 	t.updateStatus("error", "failed, see logs")
 }
 
@@ -175,71 +257,107 @@ func (t *Tunnel) isConnectionError(err error) bool {
 	return false
 }
 
-func (t *Tunnel) connect(sshconfig *ssh.ClientConfig) {
-	t.logf("Starting tunnel")
-
-	// Initialize stop channel
-	t.stopChan = make(chan struct{})
-
-	// Start combined metrics and latency updater
+// runMetricsUpdater refreshes throughput and latency once a second until the
+// tunnel stops. Without metrics it doesn't run at all, and basic metrics
+// skip the latency probe.
+func (t *Tunnel) runMetricsUpdater() {
+	level := t.metricsLevel()
+	if level == config.MetricsOff {
+		return
+	}
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				// Log the panic but don't crash
-				if t != nil && t.LogChan != nil {
-					t.logf("Metrics updater panic recovered: %v", r)
-				}
+	defer func() {
+		if r := recover(); r != nil {
+			// Log the panic but don't crash
+			if t != nil {
+				t.warnf("Metrics updater panic recovered: %v", r)
 			}
-		}()
-
-		for {
-			select {
-			case <-t.stopChan:
-				return
-			case <-ticker.C:
-				if t == nil || t.Client == nil {
-					continue
-				}
-				// Update metrics
-				t.updateMetrics()
+		}
+	}()
 
-				// Measure latency
-				t.clientMu.RLock()
-				client := t.Client
-				t.clientMu.RUnlock()
+	for {
+		select {
+		case <-t.stopChan:
+			return
+		case <-ticker.C:
+			t.clientMu.RLock()
+			connected := t.Client != nil
+			t.clientMu.RUnlock()
+			if !connected {
+				continue
+			}
+			// Update metrics
+			t.updateMetrics()
+			if level == config.MetricsBasic {
+				continue
+			}
 
-				if client == nil {
-					t.Metrics.mu.Lock()
-					t.Metrics.Latency = -1
-					t.Metrics.mu.Unlock()
-					continue
-				}
+			// Measure latency
+			t.clientMu.RLock()
+			client := t.Client
+			t.clientMu.RUnlock()
 
-				start := time.Now()
-				session, err := client.NewSession()
+			if client == nil {
 				t.Metrics.mu.Lock()
-				if err != nil {
-					t.Metrics.Latency = -1
-					t.Metrics.mu.Unlock()
-					t.logf("SSH client health check failed: %v", err)
-					// Close the client so the next connection attempt creates a new one
-					t.clientMu.Lock()
-					if t.Client != nil {
-						t.Client.Close()
-						t.Client = nil
-					}
-					t.clientMu.Unlock()
-					continue
-				}
-				t.Metrics.Latency = time.Since(start)
-				session.Close()
+				t.Metrics.Latency = -1
 				t.Metrics.mu.Unlock()
+				continue
 			}
+
+			start := time.Now()
+			session, err := client.NewSession()
+			t.Metrics.mu.Lock()
+			if err != nil {
+				t.Metrics.Latency = -1
+				t.Metrics.mu.Unlock()
+				t.warnf("SSH client health check failed: %v", err)
+				// Close the client so the next connection attempt creates a new one
+				t.clientMu.Lock()
+				t.closeClient(t.Client, true)
+				t.Client = nil
+				t.clientMu.Unlock()
+				go t.superviseReconnect(fmt.Sprintf("health check failed: %v", err))
+				continue
+			}
+			t.Metrics.Latency = time.Since(start)
+			session.Close()
+			t.Metrics.mu.Unlock()
 		}
-	}()
+	}
+}
+
+func (t *Tunnel) connect(sshconfig *ssh.ClientConfig) {
+	t.infof("Starting tunnel")
+
+	t.sshConfig = sshconfig
+
+	// Start combined metrics and latency updater
+	go t.runMetricsUpdater()
+
+	// Follow the bind interface if its address moves around
+	if t.Config.BindInterface != "" {
+		go t.watchBindInterface()
+	}
+
+	// Drop the SSH connection when nobody has used it for a while
+	if t.Config.IdleTimeout != "" {
+		go t.watchIdle()
+	}
+
+	// Notice connections that died quietly, e.g. behind a NAT
+	go t.watchKeepalive()
+
+	// Keep an eye on primary and standby targets
+	if len(t.Config.Standby) > 0 {
+		go t.watchFailover()
+	}
+
+	// Check the service behind the tunnel answers, not just the server
+	if t.Config.HealthCheck.Protocol != "" {
+		go t.watchHealth()
+	}
 
 	// Handle (re)connections in the background
 	t.updateStatus("connecting", "waiting for traffic")
@@ -247,26 +365,37 @@ func (t *Tunnel) connect(sshconfig *ssh.ClientConfig) {
 		// Check if we should stop
 		select {
 		case <-t.stopChan:
-			t.logf("Tunnel stopping")
+			t.infof("Tunnel stopping")
 			return
 		default:
 		}
 
+		t.listenerMu.Lock()
+		listener := t.Listener
+		t.listenerMu.Unlock()
+
 		// Signal that this is an error condition, not a normal stop
-		if t.Listener == nil {
+		if listener == nil {
 			t.errorf("Listener cannot accept connections")
 			t.updateStatus("error", "cannot accept connections")
 			return
 		}
 
-		t.Listener.(*net.TCPListener).SetDeadline(time.Now().Add(time.Second))
+		listener.(*net.TCPListener).SetDeadline(time.Now().Add(time.Second))
 
-		conn, err := t.Listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 				// This is just a timeout, continue to check stopChan
 				continue
 			}
+			// The listener was swapped out from under us by a re-bind
+			t.listenerMu.Lock()
+			swapped := t.Listener != nil && t.Listener != listener
+			t.listenerMu.Unlock()
+			if swapped {
+				continue
+			}
 			t.logf("Listener closed: %v", err)
 			return
 		}
@@ -280,13 +409,68 @@ func (t *Tunnel) Stop() {
 	}
 
 	t.clientMu.Lock()
-	if t.Client != nil {
-		t.Client.Close()
-		t.Client = nil
-	}
+	t.closeClient(t.Client, false)
+	t.Client = nil
 	t.clientMu.Unlock()
 }
 
+// Reconnect drops the current SSH client and dials a fresh one right away, so
+// the first connection after a network change doesn't hit a dead link
+func (t *Tunnel) Reconnect(reason string) {
+	if t == nil || t.stopChan == nil {
+		return
+	}
+
+	select {
+	case <-t.stopChan:
+		return
+	default:
+	}
+
+	t.clientMu.Lock()
+	defer t.clientMu.Unlock()
+
+	// Nothing to do if we were idle waiting for traffic
+	if t.Client == nil || t.sshConfig == nil {
+		return
+	}
+
+	t.closeClient(t.Client, true)
+	t.Client = nil
+
+	// The UDP relay notices the dead client and rebuilds itself
+	if t.Config.IsUDP() {
+		t.logf("Reconnecting after %s", reason)
+		return
+	}
+
+	sshEndpoint, _, clientConfig := t.currentTarget(t.sshConfig)
+	t.logf("Reconnecting to %s after %s", sshEndpoint.String(), reason)
+	t.updateStatus("connecting", fmt.Sprintf("reconnecting after %s", reason))
+
+	client, err := t.openClient(sshEndpoint, clientConfig)
+	if err == errStopped {
+		return
+	}
+	if err != nil {
+		// Leave the client nil so the next connection retries
+		t.warnf("Reconnect failed: %v", err)
+		t.updateStatus("connecting", "waiting for network")
+		go t.superviseReconnect(fmt.Sprintf("%s: %v", reason, err))
+		return
+	}
+
+	t.Client = client
+	go t.watchClient(client)
+	t.updateStatus("active", fmt.Sprintf("reconnected after %s", reason))
+}
+
+// TargetEndpoints returns the SSH server a tunnel dials and the target as seen
+// from it, as written in the config without ssh_config overrides
+func TargetEndpoints(tc config.TunnelConfig) (*Endpoint, *Endpoint) {
+	return figureOutRemoteVsBastion(tc)
+}
+
 func figureOutRemoteVsBastion(config config.TunnelConfig) (*Endpoint, *Endpoint) {
 
 	// Start with bastion mode
@@ -314,6 +498,13 @@ func figureOutRemoteVsBastion(config config.TunnelConfig) (*Endpoint, *Endpoint)
 func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig) {
 	defer localConnection.Close()
 
+	t.markActivity()
+	atomic.AddInt32(&t.activeConns, 1)
+	defer func() {
+		atomic.AddInt32(&t.activeConns, -1)
+		t.markActivity()
+	}()
+
 	// Check if tunnel is being shut down
 	if t == nil {
 		return
@@ -326,45 +517,50 @@ func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig)
 	default:
 	}
 
-	// Parse host and port
-	sshEndpoint, remoteEndpoint := figureOutRemoteVsBastion(t.Config)
-
 	// Check if SSH client is healthy and reconnect if necessary
 	t.clientMu.Lock()
 	needsHealthCheck := t.Client != nil
 	t.clientMu.Unlock()
 
 	if needsHealthCheck && !t.isSSHClientHealthy() {
-		t.logf("SSH client appears unhealthy, closing and reconnecting")
+		t.warnf("SSH client appears unhealthy, closing and reconnecting")
 		t.clientMu.Lock()
-		if t.Client != nil {
-			t.Client.Close()
-			t.Client = nil
-		}
+		t.closeClient(t.Client, true)
+		t.Client = nil
 		t.clientMu.Unlock()
 	}
 
 	// Only establish a new client if we don't have one or if it's closed
 	var isFirstConnect bool = false
 	t.clientMu.Lock()
+	sshEndpoint, remoteEndpoint, clientConfig := t.currentTarget(sshconfig)
 	if t.Client == nil {
 		isFirstConnect = true
-		t.logf("connecting to SSH server (1/2): %s", sshEndpoint.String())
-		t.updateStatus("connecting", "connecting to server")
-		client, err := ssh.Dial("tcp", sshEndpoint.String(), sshconfig)
-		if err != nil {
-			t.errorf("SSH connection failed: %v (user: %s, address: %s)", err, sshconfig.User, sshEndpoint)
-			t.updateStatus("error", fmt.Sprintf("SSH connection failed: %v", err))
-			if t.Client != nil {
-				t.Client.Close()
-				t.Client = nil
+		// Try each target once, failing over to standbys transparently
+		for tries := 1; ; tries++ {
+			t.logf("connecting to SSH server (1/2): %s", sshEndpoint.String())
+			t.updateStatus("connecting", "connecting to server")
+			client, err := t.openClient(sshEndpoint, clientConfig)
+			if err == nil {
+				t.Client = client
+				go t.watchClient(client)
+				break
 			}
-			t.clientMu.Unlock()
-			return
+			if err == errStopped {
+				t.clientMu.Unlock()
+				return
+			}
+			t.errorf("SSH connection failed: %v (user: %s, address: %s)", err, clientConfig.User, sshEndpoint)
+			if tries >= t.targetCount() || !t.advanceTarget(err) {
+				t.updateStatus("error", fmt.Sprintf("SSH connection failed: %v", err))
+				t.clientMu.Unlock()
+				return
+			}
+			sshEndpoint, remoteEndpoint, clientConfig = t.currentTarget(sshconfig)
 		}
-		t.Client = client
 	}
 	client := t.Client
+	onStandby := t.targetIndex > 0
 	t.clientMu.Unlock()
 
 	if isFirstConnect {
@@ -378,6 +574,7 @@ func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig)
 
 	var remoteConnection net.Conn
 	var err error
+	remoteAddress := t.remoteAddress(remoteEndpoint)
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		// Check if we should stop before each attempt
@@ -393,23 +590,23 @@ func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig)
 			return
 		}
 
-		remoteConnection, err = client.Dial("tcp", remoteEndpoint.String())
+		remoteConnection, err = client.Dial("tcp", remoteAddress)
 		if err == nil {
 			break
 		}
 
-		t.logf("connection failed to remote target (attempt %d/%d): %v", attempt+1, maxRetries, err)
+		t.warnf("connection failed to remote target (attempt %d/%d): %v", attempt+1, maxRetries, err)
 
 		// If this is the last attempt or SSH client seems broken, close it
 		if attempt == maxRetries-1 || t.isConnectionError(err) {
 			t.errorf("connection failed to remote target after %d attempts: %v", maxRetries, err)
 			t.updateStatus("error", fmt.Sprintf("remote connection failed: %v", err))
-			// Close and nil the client so next connection will create a fresh one
+			// Close and nil the client so next connection will create a fresh one,
+			// through the next standby target if there is one
 			t.clientMu.Lock()
-			if t.Client != nil {
-				t.Client.Close()
-				t.Client = nil
-			}
+			t.closeClient(t.Client, false)
+			t.Client = nil
+			t.advanceTarget(err)
 			t.clientMu.Unlock()
 			return
 		}
@@ -422,11 +619,20 @@ func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig)
 
 	defer remoteConnection.Close()
 
+	// Remember which local client maps to which remote connection
+	info := t.trackConn(localConnection.RemoteAddr().String(), remoteEndpoint.String(), sshEndpoint.String())
+	defer t.untrackConn(info)
+
 	if isFirstConnect {
-		t.updateStatus("active", "tunnel established")
+		if onStandby {
+			t.updateStatus("active", fmt.Sprintf("failover via %s", sshEndpoint.String()))
+		} else {
+			t.updateStatus("active", "tunnel established")
+		}
 	}
 
-	// Copy bidirectionally with metrics
+	// Copy bidirectionally with metrics, unless they are off
+	counting := t.metricsLevel() != config.MetricsOff
 	copyConn := func(writer, reader net.Conn, direction string) {
 		buf := make([]byte, 32*1024)
 		for {
@@ -445,13 +651,20 @@ func (t *Tunnel) forward(localConnection net.Conn, sshconfig *ssh.ClientConfig)
 					break
 				}
 
-				t.Metrics.mu.Lock()
 				if direction == "upload" {
-					t.Metrics.BytesOut += int64(n)
+					atomic.AddInt64(&info.BytesOut, int64(n))
 				} else {
-					t.Metrics.BytesIn += int64(n)
+					atomic.AddInt64(&info.BytesIn, int64(n))
+				}
+				if counting {
+					t.Metrics.mu.Lock()
+					if direction == "upload" {
+						t.Metrics.BytesOut += int64(n)
+					} else {
+						t.Metrics.BytesIn += int64(n)
+					}
+					t.Metrics.mu.Unlock()
 				}
-				t.Metrics.mu.Unlock()
 			}
 			if err != nil {
 				if err != io.EOF {
@@ -0,0 +1,292 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	"tunnel9/internal/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH only forwards streams, so datagrams are carried over an exec channel
+// as 2-byte length-prefixed frames, relayed to UDP by python3 on the far end.
+// A target that isn't listening yet answers with ICMP, raised as
+// ConnectionRefusedError by the next recv or send, and is treated like a
+// lost datagram. Any other failure ends the script, so the relay sees EOF
+// and reconnects rather than carrying on half dead.
+const udpRelayScript = `import os,socket,struct,sys,threading
+s=socket.socket(socket.AF_INET6 if ":" in "%[1]s" else socket.AF_INET,socket.SOCK_DGRAM)
+s.connect(("%[1]s",%[2]d))
+i=sys.stdin.buffer
+o=sys.stdout.buffer
+def down():
+    while True:
+        try:
+            d=s.recv(65535)
+            o.write(struct.pack(">H",len(d))+d)
+            o.flush()
+        except ConnectionRefusedError:
+            pass
+        except Exception:
+            os._exit(1)
+threading.Thread(target=down,daemon=True).start()
+while True:
+    h=i.read(2)
+    if len(h)<2:
+        break
+    n=struct.unpack(">H",h)[0]
+    try:
+        s.send(i.read(n))
+    except ConnectionRefusedError:
+        pass`
+
+// Remote hosts are interpolated into the relay script, so keep them boring
+var safeRelayHost = regexp.MustCompile(`^[A-Za-z0-9.:_-]+$`)
+
+// SSH-level keepalive intervals. Datagram tunnels carry no TCP connection
+// that would notice a dead link on its own, so they always use one.
+var keepalivePresets = map[string]time.Duration{
+	"aggressive": 10 * time.Second,
+	"balanced":   25 * time.Second, // Matches WireGuard's PersistentKeepalive advice
+	"relaxed":    60 * time.Second,
+}
+
+// WireGuard's default listen port
+const wireguardPort = 51820
+
+// udpDefaults fills in what a one-row wireguard config leaves out
+func udpDefaults(t *Tunnel) {
+	if t.Config.Type != "wireguard" {
+		return
+	}
+	if t.Config.LocalPort == 0 && !t.Config.AutoPort {
+		t.Config.LocalPort = wireguardPort
+	}
+	if t.Config.RemotePort == 0 {
+		t.Config.RemotePort = wireguardPort
+	}
+	if t.Config.Keepalive == "" {
+		t.Config.Keepalive = "balanced"
+	}
+}
+
+func keepaliveInterval(preset string) time.Duration {
+	if interval, ok := keepalivePresets[preset]; ok {
+		return interval
+	}
+	return keepalivePresets["balanced"]
+}
+
+func writeFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+func readFrame(r io.Reader, buf []byte) (int, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(header[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("frame of %d bytes exceeds buffer", n)
+	}
+	return io.ReadFull(r, buf[:n])
+}
+
+// connectUDP relays datagrams between the local packet listener and the
+// remote target, re-establishing the relay whenever the SSH link drops
+func (t *Tunnel) connectUDP(sshconfig *ssh.ClientConfig) {
+	t.infof("Starting UDP tunnel")
+
+	t.sshConfig = sshconfig
+
+	go t.runMetricsUpdater()
+
+	sshEndpoint, remoteEndpoint := figureOutRemoteVsBastion(t.Config)
+	if !safeRelayHost.MatchString(remoteEndpoint.Host) {
+		t.errorf("refusing to relay to suspicious host %q", remoteEndpoint.Host)
+		return
+	}
+
+	// Hold on to the socket, StopTunnel clears the field while we wind down
+	conn := t.PacketConn
+
+	// The most recent local client gets the replies, like a WireGuard peer
+	var peerMu sync.Mutex
+	var peer net.Addr
+
+	frames := make(chan []byte, 64)
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				close(frames)
+				return
+			}
+			peerMu.Lock()
+			peer = addr
+			peerMu.Unlock()
+
+			packet := make([]byte, n)
+			copy(packet, buf[:n])
+			select {
+			case frames <- packet:
+			default:
+				// Drop rather than stall, it's UDP after all
+			}
+		}
+	}()
+
+	t.updateStatus("connecting", "connecting to server")
+	for {
+		select {
+		case <-t.stopChan:
+			t.infof("Tunnel stopping")
+			return
+		default:
+		}
+
+		err := t.relayUDP(conn, sshEndpoint, remoteEndpoint, sshconfig, frames, func() net.Addr {
+			peerMu.Lock()
+			defer peerMu.Unlock()
+			return peer
+		})
+		if err == io.EOF {
+			t.infof("Tunnel stopping")
+			return
+		}
+
+		select {
+		case <-t.stopChan:
+			return
+		case <-time.After(3 * time.Second):
+			t.warnf("UDP relay lost (%v), reconnecting", err)
+			t.updateStatus("connecting", "relay lost, reconnecting")
+		}
+	}
+}
+
+// relayUDP runs a single relay session, returning io.EOF once the local side
+// is closed and any other error when the SSH side fails
+func (t *Tunnel) relayUDP(conn net.PacketConn, sshEndpoint *Endpoint, remoteEndpoint *Endpoint, sshconfig *ssh.ClientConfig, frames <-chan []byte, peer func() net.Addr) error {
+	t.logf("connecting to SSH server: %s", sshEndpoint.String())
+	client, err := t.openClient(sshEndpoint, sshconfig)
+	if err == errStopped {
+		return io.EOF
+	}
+	if err != nil {
+		t.errorf("SSH connection failed: %v (user: %s, address: %s)", err, sshconfig.User, sshEndpoint)
+		return err
+	}
+
+	t.clientMu.Lock()
+	t.Client = client
+	t.clientMu.Unlock()
+	defer func() {
+		t.clientMu.Lock()
+		if t.Client == client {
+			t.Client = nil
+		}
+		t.clientMu.Unlock()
+		t.closeClient(client, false)
+	}()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(udpRelayScript, remoteEndpoint.Host, remoteEndpoint.Port)
+	if err := session.Start(fmt.Sprintf("python3 -u -c '%s'", script)); err != nil {
+		return fmt.Errorf("failed to start remote relay: %w", err)
+	}
+	t.logf("relaying UDP to %s", remoteEndpoint.String())
+	t.updateStatus("active", "udp relay established")
+
+	failed := make(chan error, 2)
+	counting := t.metricsLevel() != config.MetricsOff
+
+	// Remote -> local
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, err := readFrame(stdout, buf)
+			if err != nil {
+				failed <- err
+				return
+			}
+			if addr := peer(); addr != nil {
+				conn.WriteTo(buf[:n], addr)
+				if counting {
+					t.Metrics.mu.Lock()
+					t.Metrics.BytesIn += int64(n)
+					t.Metrics.mu.Unlock()
+				}
+			}
+		}
+	}()
+
+	// Keepalives go out beside the relay, a server slow to answer them never
+	// holds up datagrams
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		interval := keepaliveInterval(t.Config.Keepalive)
+		keepalive := time.NewTicker(interval)
+		defer keepalive.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-keepalive.C:
+				if err := sendKeepalive(client, interval); err != nil {
+					failed <- fmt.Errorf("keepalive failed: %w", err)
+					return
+				}
+			}
+		}
+	}()
+
+	// Local -> remote
+	for {
+		select {
+		case <-t.stopChan:
+			return io.EOF
+		case err := <-failed:
+			return err
+		case packet, ok := <-frames:
+			if !ok {
+				return io.EOF
+			}
+			if err := writeFrame(stdin, packet); err != nil {
+				return err
+			}
+			if counting {
+				t.Metrics.mu.Lock()
+				t.Metrics.BytesOut += int64(len(packet))
+				t.Metrics.mu.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	payloads := [][]byte{[]byte("handshake"), {}, bytes.Repeat([]byte{0xAB}, 1420)}
+	for _, p := range payloads {
+		if err := writeFrame(&buf, p); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	out := make([]byte, 65535)
+	for _, p := range payloads {
+		n, err := readFrame(&buf, out)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if !bytes.Equal(out[:n], p) {
+			t.Errorf("expected %d byte frame, got %d bytes", len(p), n)
+		}
+	}
+}
+
+func TestUDPRelayScript(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	// UDP echo server standing in for the WireGuard endpoint
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			server.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	port := server.LocalAddr().(*net.UDPAddr).Port
+	cmd := exec.Command("python3", "-u", "-c", fmt.Sprintf(udpRelayScript, "127.0.0.1", port))
+	stdin, _ := cmd.StdinPipe()
+	stdout, _ := cmd.StdoutPipe()
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start relay: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	if err := writeFrame(stdin, []byte("ping")); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+
+	result := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 65535)
+		n, err := readFrame(stdout, buf)
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		result <- string(buf[:n])
+	}()
+
+	select {
+	case got := <-result:
+		if got != "ping" {
+			t.Errorf("expected ping echoed back, got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for relay")
+	}
+}
+
+func TestUDPRelayScriptOutlivesRefusedTarget(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	// Find a port nothing listens on yet
+	probe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	cmd := exec.Command("python3", "-u", "-c", fmt.Sprintf(udpRelayScript, "127.0.0.1", port))
+	stdin, _ := cmd.StdinPipe()
+	stdout, _ := cmd.StdoutPipe()
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start relay: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	// Refused while the target is down, twice so both directions see it
+	for i := 0; i < 2; i++ {
+		if err := writeFrame(stdin, []byte("lost")); err != nil {
+			t.Fatalf("failed to write frame: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	server, err := net.ListenPacket("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Skipf("port taken in the meantime: %v", err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			server.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	if err := writeFrame(stdin, []byte("ping")); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+	result := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 65535)
+		n, err := readFrame(stdout, buf)
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		result <- string(buf[:n])
+	}()
+
+	select {
+	case got := <-result:
+		if got != "ping" {
+			t.Errorf("expected ping echoed back once the target is up, got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for relay")
+	}
+}
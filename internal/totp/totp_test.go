@@ -0,0 +1,44 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238, appendix B
+func TestCode(t *testing.T) {
+	seed := func(secret string) string {
+		return base32.StdEncoding.EncodeToString([]byte(secret))
+	}
+	sha1Seed := seed("12345678901234567890")
+	sha256Seed := seed("12345678901234567890123456789012")
+
+	cases := []struct {
+		seed string
+		at   int64
+		want string
+	}{
+		{sha1Seed, 59, "287082"},
+		{sha1Seed, 1111111109, "081804"},
+		{"otpauth://totp/test?secret=" + sha1Seed + "&digits=8", 1234567890, "89005924"},
+		{"otpauth://totp/test?secret=" + sha256Seed + "&digits=8&algorithm=SHA256", 2000000000, "90698825"},
+	}
+	for _, c := range cases {
+		code, err := Code(c.seed, time.Unix(c.at, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != c.want {
+			t.Errorf("at %d expected %s, got %s", c.at, c.want, code)
+		}
+	}
+}
+
+func TestParseRejectsBadSeeds(t *testing.T) {
+	for _, seed := range []string{"", "not base32!", "otpauth://hotp/test?secret=GEZDGNBV", "otpauth://totp/test?secret=GEZDGNBV&digits=4"} {
+		if _, err := Parse(seed); err == nil {
+			t.Errorf("expected %q to be rejected", seed)
+		}
+	}
+}
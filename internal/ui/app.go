@@ -5,12 +5,20 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"tunnel9/internal/bastion"
 	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+	"tunnel9/internal/heartbeat"
+	"tunnel9/internal/localforward"
+	"tunnel9/internal/logfile"
+	"tunnel9/internal/metrics"
+	"tunnel9/internal/notify"
+	"tunnel9/internal/registry"
+	"tunnel9/internal/shellhistory"
 	"tunnel9/internal/ssh"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -20,20 +28,40 @@ import (
 	"github.com/google/uuid"
 )
 
-// Add a tick message type for periodic updates
-type tickMsg time.Time
+// tickMsg redraws the table, every refresh_interval
+type tickMsg struct {
+	round int // Which run of the loops it belongs to, see wake
+	at    time.Time
+}
+
+// sampleMsg reads the tunnels' metrics and runs periodic work, every second
+// however slowly the table redraws, so exporters and timers stay accurate
+type sampleMsg struct {
+	round int
+	at    time.Time
+}
+
+// sampleInterval is how often sampleMsg arrives
+const sampleInterval = time.Second
 
 // Add a log message type for the tea.Msg interface
-type logMsg string
+type logMsg logLine
 
 // Add a status message type for the tea.Msg interface
-type statusMsg ssh.TunnelStatus
+type statusMsg events.Event
 
 type TunnelRecord struct {
-	ID      string
-	Status  string // "stopped", "active", "error"
-	Config  config.TunnelConfig
-	Metrics string
+	ID        string
+	Status    string // "stopped", "active", "error"
+	Config    config.TunnelConfig
+	Metrics   string
+	Ephemeral *bastion.Instance // Bastion provisioned for this session only
+	PublicURL string            // Set while the local port is shared publicly
+	History   []statusChange    // Most recent status transitions, oldest first
+	Uptime    availability      // Up and down time while the tunnel was wanted
+	Values    ssh.MetricValues  // Rates and latency while active, for the wide columns
+	Transfer  transferTotals    // Bytes moved this run and since launch
+	Temporary bool              // Session only, never written to the config
 }
 
 type dialogField struct {
@@ -48,12 +76,14 @@ type dialogMode int
 const (
 	modeNew dialogMode = iota
 	modeEdit
+	modeConnect // A temporary tunnel from an ssh command, started right away
 )
 
 type App struct {
 	table             table.Model
 	tunnels           []TunnelRecord
 	currentTag        string
+	statusFilter      string // Only show tunnels in this status, see statusFilters
 	manager           *ssh.TunnelManager
 	height            int
 	width             int
@@ -61,8 +91,12 @@ type App struct {
 	showConsole       bool
 	sortColumn        int
 	sortReverse       bool
+	secondarySort     int      // -1 when rows are only sorted by sortColumn
+	manualIDs         []string // The tunnels' own order while sorted by a column
+	hScroll           int      // Wide columns scrolled off to the left of NAME
 	baseColumns       []string // Store original column titles
-	errorLog          []string
+	errorLog          []logLine
+	logLevel          events.Level // Least level the console shows
 	viewport          viewport.Model
 	filterLogs        bool // Whether to filter logs by selected tunnel
 	showDialog        bool
@@ -76,10 +110,103 @@ type App struct {
 	selectedTags      map[string]bool
 	showDeleteConfirm bool
 	deleteIndex       int
+	deleteIDs         []string        // Marked tunnels the delete dialog removes, when more than one
+	marked            map[string]bool // Tunnels marked for bulk actions, by ID
+	bulk              *bulkOp         // Bulk start or stop still on its way
+	groupByTag        bool            // Tunnels listed under their tag's header
+	collapsed         map[string]bool // Tag groups folded away, by tag
 	privacyMode       bool
 	logCursor         int  // Track position in logs for scrolling
 	autoScroll        bool // Whether to auto-scroll to bottom
 	isWideMode        bool // Whether to show wide or compact view
+	showDetail        bool // Whether to show the detail pane for the selected tunnel
+	detailSettings    bool // Whether the detail pane shows settings and metrics rather than activity
+	layout            screenLayout
+	lastClick         time.Time // When a row was last clicked, for double clicks
+	lastClickRow      int
+	expandRow         bool // Whether to expand the selected row inline with its full details
+	tagSettings       map[string]config.TagSettings
+	latency           config.LatencyConfig
+	logEvents         *events.Subscription
+	logFile           *logfile.File // Every console line, nil when off
+	statusEvents      *events.Subscription
+	dampener          *logDampener
+	registry          registry.Registry
+	machine           string
+	remoteForwards    []registry.Forward // Forwards announced by the whole team
+	registryTicks     int
+	ports             *registry.PortRegistry // Local ports of every config file on this machine
+	heartbeat         *heartbeat.Sender      // Nil unless a heartbeat URL is configured
+	heartbeatFailing  bool
+	mqtt              *mqttBridge       // Nil unless an MQTT broker is configured
+	emailer           *notify.Emailer   // Nil unless email alerts are configured
+	metrics           *metrics.Exporter // Nil unless metrics_listen is set
+	showShareConfirm  bool
+	shareConfirmID    string
+	shareConflictList []registry.Forward
+	hostKeyPrompts    <-chan *ssh.HostKeyPrompt
+	hostKeyPrompt     *ssh.HostKeyPrompt // Unknown host key awaiting an answer
+	authPrompts       <-chan *ssh.AuthPrompt
+	authPrompt        *ssh.AuthPrompt // Keyboard-interactive challenges awaiting answers
+	authAnswers       []string
+	authField         int
+	showMenu          bool
+	menuID            string // Tunnel the quick actions menu was opened for
+	menuItems         []menuItem
+	menuCursor        int
+	showDiscover      bool
+	discovered        []shellhistory.Discovered // Forwards from shell history not configured yet
+	discoverCursor    int
+	showMaintenance   bool
+	maintenance       *registry.Maintenance
+	maintenanceHosts  registry.MaintenanceHosts // Hosts whose tunnels stay stopped
+	maintenanceList   []string                  // Hosts listed in the maintenance overlay
+	maintenanceCursor int
+	showHostScan      bool
+	hostScans         []hostScan // SSH servers whose keys are under review
+	hostScanCursor    int
+	hostScanRound     int
+	showSSHImport     bool
+	sshForwards       []localforward.Forward // LocalForward entries of ~/.ssh/config to import
+	sshSelected       []bool
+	sshCursor         int
+	showProfiles      bool
+	profiles          []string // Profiles to switch to, the default one first
+	profileCursor     int
+	startupGroups     []config.StartupGroup
+	groupRuns         []*groupRun // Startup groups on their way up
+	showGroups        bool
+	showTimeline      bool
+	showServices      bool
+	services          []ssh.DiscoveredService // Found behind servicesVia's SSH server, not configured yet
+	servicesSelected  []bool
+	servicesCursor    int
+	servicesVia       config.TunnelConfig
+	servicesPending   bool
+	servicesRound     int
+	timelineOffset    int
+	sessionStart      time.Time // Where the timeline begins
+	groupCursor       int
+	confirm           string // Top-level confirmation policy, tags may override it
+	refreshInterval   time.Duration
+	suspended         bool   // Idle, the tick and sample loops have stopped
+	loopRound         int    // Bumped whenever the loops restart, so stale ticks are dropped
+	safeMode          string // Why autostart and metrics are off, after repeated crashes
+	version           string
+	showStopConfirm   bool
+	stopConfirmVerb   string
+	stopConfirmCount  int
+	stopConfirmNames  []string // Tunnels whose policy asks before stopping
+	stopConfirmAction func() tea.Cmd
+	bastionProvider   *bastion.Provider
+	publicShare       config.PublicShareConfig
+	templates         []config.TunnelTemplate
+	dialogTemplate    int    // Template the new tunnel dialog started from, -1 for none
+	dialogCheck       string // Outcome of the dialog's connection test
+	recent            *registry.Recent
+	recentValues      registry.RecentValues // Hosts and users suggested in the dialog
+	suggestIndex      int                   // Suggestion filled into the active field, -1 for none
+	suggestPrefix     string                // What was typed before cycling through suggestions
 }
 
 func convertConfigsToRecords(configs []config.TunnelConfig) []TunnelRecord {
@@ -106,33 +233,55 @@ var (
 
 	titleStyle = lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#2dd4bf")).
+			Foreground(activeTheme.accent).
 			Align(lipgloss.Center).
 			MarginBottom(1)
 
 	consoleStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#2dd4bf")).
+			BorderForeground(activeTheme.accent).
 			BorderRight(true).
 			Padding(0, 1)
 
 	dialogStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#2dd4bf")).
+			BorderForeground(activeTheme.accent).
 			Padding(1, 2)
 
 	dialogActiveStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#2dd4bf"))
+				Foreground(activeTheme.accent)
 
 	dialogSelectedStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#2d3436")).
-				Foreground(lipgloss.Color("#2dd4bf"))
+				Background(activeTheme.accentBackground).
+				Foreground(activeTheme.accent)
 
 	controlsStyle = lipgloss.NewStyle()
 )
 
-func NewApp(loader *config.ConfigLoader, configs []config.TunnelConfig, initialTag string) *App {
+// tableStyles are the default table styles, left aligned and without padding
+func tableStyles() table.Styles {
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true).
+		Bold(true).
+		Align(lipgloss.Left).
+		AlignHorizontal(lipgloss.Left).
+		MarginLeft(0).
+		PaddingLeft(0).
+		Width(0)
+	s.Selected = s.Selected.
+		Foreground(activeTheme.selected).
+		Bold(true)
+	s.Cell = s.Cell.
+		Align(lipgloss.Left).
+		AlignHorizontal(lipgloss.Left).
+		PaddingLeft(0).
+		PaddingRight(1)
+	return s
+}
 
+func NewApp(loader *config.ConfigLoader, configs []config.TunnelConfig, initialTag string) *App {
 	tunnels := convertConfigsToRecords(configs)
 
 	// Store base column titles for both wide and compact modes
@@ -145,16 +294,22 @@ func NewApp(loader *config.ConfigLoader, configs []config.TunnelConfig, initialT
 		"REMOTE",
 		"BASTION",
 		"TAG",
+		"RATE↑",
+		"RATE↓",
+		"TOTAL↑",
+		"TOTAL↓",
+		"LATENCY",
+		"HEALTH",
 		"MESSAGE",
 	}
 
 	// Create columns with initial widths for compact mode
 	columns := []table.Column{
-		{Title: baseColumns[0], Width: 8},  // STATUS
-		{Title: baseColumns[1], Width: 20}, // NAME
-		{Title: "TUNNEL", Width: 30},       // Combined LOCAL:HOST:REMOTE
-		{Title: baseColumns[7], Width: 12}, // TAG
-		{Title: baseColumns[8], Width: 40}, // MESSAGE
+		{Title: baseColumns[0], Width: 8},   // STATUS
+		{Title: baseColumns[1], Width: 20},  // NAME
+		{Title: "TUNNEL", Width: 30},        // Combined LOCAL:HOST:REMOTE
+		{Title: baseColumns[7], Width: 12},  // TAG
+		{Title: baseColumns[14], Width: 40}, // MESSAGE
 	}
 
 	t := table.New(
@@ -162,27 +317,7 @@ func NewApp(loader *config.ConfigLoader, configs []config.TunnelConfig, initialT
 		table.WithRows([]table.Row{}),
 		table.WithFocused(true),
 	)
-
-	// Use default table styles but customize them
-	s := table.DefaultStyles()
-	s.Header = s.Header.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderBottom(true).
-		Bold(true).
-		Align(lipgloss.Left).
-		AlignHorizontal(lipgloss.Left).
-		MarginLeft(0).
-		PaddingLeft(0).
-		Width(0)
-	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("212")).
-		Bold(true)
-	s.Cell = s.Cell.
-		Align(lipgloss.Left).
-		AlignHorizontal(lipgloss.Left).
-		PaddingLeft(0).
-		PaddingRight(1)
-	t.SetStyles(s)
+	t.SetStyles(tableStyles())
 
 	// Initialize viewport with a default size and scrollbar
 	vp := viewport.New(0, maxConsoleHeight)
@@ -193,28 +328,89 @@ func NewApp(loader *config.ConfigLoader, configs []config.TunnelConfig, initialT
 	vp.YPosition = 0
 
 	app := &App{
-		table:        t,
-		tunnels:      tunnels,
-		currentTag:   initialTag,
-		manager:      ssh.NewTunnelManager(),
-		baseColumns:  baseColumns,
-		viewport:     vp,
-		filterLogs:   false,
-		showDialog:   false,
-		dialogFields: make([]dialogField, 12),
-		activeField:  0,
-		loader:       loader,
-		selectedTags: make(map[string]bool),
-		autoScroll:   true,
-		isWideMode:   false,
+		table:         t,
+		tunnels:       tunnels,
+		currentTag:    initialTag,
+		manager:       ssh.NewTunnelManager(),
+		baseColumns:   baseColumns,
+		viewport:      vp,
+		filterLogs:    false,
+		showDialog:    false,
+		dialogFields:  make([]dialogField, 13),
+		activeField:   0,
+		loader:        loader,
+		selectedTags:  make(map[string]bool),
+		autoScroll:    true,
+		isWideMode:    false,
+		sortColumn:    unsorted,
+		secondarySort: -1,
+		sessionStart:  time.Now(),
+		dampener:      newLogDampener(),
+	}
+
+	// Subscribe before anything can start, so no early events are missed.
+	// Old log lines and superseded status changes matter least, the latest state
+	// must get through.
+	app.logEvents = app.manager.Events.Subscribe(100, events.DropOldest, events.KindLog)
+	app.statusEvents = app.manager.Events.Subscribe(100, events.DropOldest, events.KindStatus)
+	// Ask about unknown host keys rather than trusting them silently
+	app.hostKeyPrompts = app.manager.PromptHostKeys()
+	// Let servers that want an OTP or Duo push ask for it
+	app.authPrompts = app.manager.PromptAuth()
+
+	// Keep what the console shows past its last 100 lines
+	app.openLogFile(loader.Config().Log)
+
+	// Announce active forwards to the team if a registry is configured
+	registryConfig := loader.Config().Registry
+	app.registry = registry.New(registryConfig)
+	app.machine = registry.Machine(registryConfig)
+	app.refreshRegistry()
+	// Remember this config's ports so other profiles can avoid them
+	if path, err := registry.DefaultPortsPath(); err == nil {
+		app.ports = registry.NewPortRegistry(path)
+	}
+	app.recordPorts()
+	// And the hosts typed into the dialog so they can be suggested again
+	if path, err := registry.DefaultRecentPath(); err == nil {
+		app.recent = registry.NewRecent(path)
+	}
+	// Hosts put in maintenance stay there across sessions
+	if path, err := registry.DefaultMaintenancePath(); err == nil {
+		app.maintenance = registry.NewMaintenance(path)
+		if app.maintenanceHosts, err = app.maintenance.Load(); err != nil {
+			app.logError("Failed to load hosts in maintenance: %v", err)
+		}
 	}
 
+	// Let external monitoring notice if this machine goes quiet
+	app.heartbeat = heartbeat.New(loader.Config().Heartbeat)
+	// And dashboards that speak MQTT follow along
+	app.mqtt = newMQTTBridge(loader.Config().MQTT, app.machine, app.manager.Events)
+	// Headless machines can email failures instead
+	app.emailer = notify.NewEmailer(loader.Config().Email, app.machine)
+	app.applySettings()
+
 	// Set initial rows
 	app.updateTableRows()
 
 	return app
 }
 
+// applySettings takes the settings of the loaded config, other than the
+// tunnels
+func (a *App) applySettings() {
+	cfg := a.loader.Config()
+	a.bastionProvider = bastion.NewProvider(cfg.BastionProvider)
+	a.publicShare = cfg.PublicShare
+	a.tagSettings = cfg.TagSettings
+	a.latency = cfg.Latency
+	a.confirm = cfg.Confirm
+	a.templates = cfg.Templates
+	a.startupGroups = cfg.StartupGroups
+	a.refreshInterval = cfg.Refresh()
+}
+
 func (a *App) updateTableRows() {
 	// Update column headers to show sort indicators
 	columns := a.table.Columns()
@@ -233,7 +429,7 @@ func (a *App) updateTableRows() {
 			case 3:
 				title = a.baseColumns[7] // TAG
 			case 4:
-				title = a.baseColumns[8] // MESSAGE
+				title = a.baseColumns[14] // MESSAGE
 			}
 		}
 
@@ -244,48 +440,64 @@ func (a *App) updateTableRows() {
 			} else {
 				title += " ▲"
 			}
+		} else if i == a.secondarySort {
+			title += " △"
 		} else {
 			title += "  " // Add padding to maintain alignment
 		}
 		columns[i].Title = title
 	}
+	if a.isWideMode {
+		a.applyHScroll(columns)
+	}
 	a.table.SetColumns(columns)
 
-	// Filter tunnels based on selected tags
-	filteredTunnels := a.tunnels
-	if a.currentTag != "" {
-		selectedTags := strings.Split(a.currentTag, ",")
-		filteredTunnels = make([]TunnelRecord, 0)
-		for _, t := range a.tunnels {
-			for _, tag := range selectedTags {
-				if t.Config.Tag == tag {
-					filteredTunnels = append(filteredTunnels, t)
-					break
-				}
-			}
-		}
-	}
+	// Filter tunnels based on selected tags and status, grouped by tag when
+	// asked to
+	entries := a.tableEntries()
 
-	rows := make([]table.Row, len(filteredTunnels))
-	for i, t := range filteredTunnels {
-		// Format status without lipgloss styling
-		status := "[x]"
-		switch t.Status {
-		case "active":
-			status = "[✓]"
-		case "error":
-			status = "[!]"
-		case "connecting":
-			status = "[~]"
+	rows := make([]table.Row, len(entries))
+	for i, entry := range entries {
+		if entry.tunnel == nil {
+			rows[i] = a.groupRow(entry.tag, len(columns))
+			continue
 		}
+		t := *entry.tunnel
+		status := statusIcon(t.Status)
 
 		// Format message without lipgloss styling
 		message := t.Metrics
 
+		if t.PublicURL != "" {
+			message = fmt.Sprintf("public %s • %s", t.PublicURL, message)
+		}
+
+		// Let people know a teammate already exposes this target
+		if t.Status == "stopped" {
+			if dupes := a.remoteDuplicates(&t); len(dupes) > 0 {
+				message = fmt.Sprintf("shared by %s", describeForwards(dupes))
+			}
+		}
+
+		// Dimmed once rendered, see dimMaintenance
+		if host, ok := a.inMaintenance(&t); ok && t.Status == "stopped" {
+			status = maintenanceIcon
+			message = "maintenance on " + host
+		}
+		if a.marked[t.ID] {
+			status = "● " + status
+		}
+
 		// Mask sensitive information in privacy mode
 		remoteHost := t.Config.RemoteHost
 		bastionHost := t.Config.Bastion.Host
+		if t.Ephemeral != nil {
+			bastionHost = t.Ephemeral.Host
+		}
 		bindAddr := t.Config.BindAddress
+		if t.Config.BindInterface != "" {
+			bindAddr = t.Config.BindInterface
+		}
 		if bindAddr == "" {
 			bindAddr = "localhost"
 		}
@@ -302,15 +514,39 @@ func (a *App) updateTableRows() {
 		}
 
 		if a.isWideMode {
+			// Rates and latency get their own columns, leaving the message
+			// for anything else worth knowing
+			var rateOut, rateIn, totalOut, totalIn, latency, health string
+			if t.Status == "active" {
+				health = t.Values.Health
+				in, out := t.Transfer.run(t.Values)
+				totalOut, totalIn = formatSize(out), formatSize(in)
+			}
+			if t.Status == "active" && !t.Values.Idle {
+				rateOut = ssh.FormatRate(t.Values.RateOut)
+				rateIn = ssh.FormatRate(t.Values.RateIn)
+				latency = ssh.FormatLatency(t.Values.Latency)
+				message = t.Values.Note
+				if t.PublicURL != "" {
+					message = strings.TrimSuffix(fmt.Sprintf("public %s • %s", t.PublicURL, message), " • ")
+				}
+			}
+
 			rows[i] = table.Row{
 				status,
-				t.Config.Name,
-				fmt.Sprintf("%*d", 7, t.Config.LocalPort),
+				nameLabel(&t),
+				fmt.Sprintf("%*s", 7, a.localPortLabel(&t)),
 				bindAddr,
 				remoteHost,
 				fmt.Sprintf("%*d", 8, t.Config.RemotePort),
 				bastionHost,
-				t.Config.Tag,
+				a.tagLabel(t.Config.Tag),
+				fmt.Sprintf("%*s", 11, rateOut),
+				fmt.Sprintf("%*s", 11, rateIn),
+				fmt.Sprintf("%*s", 9, totalOut),
+				fmt.Sprintf("%*s", 9, totalIn),
+				fmt.Sprintf("%*s", 7, latency),
+				health,
 				message,
 			}
 		} else {
@@ -323,13 +559,21 @@ func (a *App) updateTableRows() {
 				shortRemoteHost = shortRemoteHost[:idx]
 			}
 
-			tunnel := fmt.Sprintf("%d:%s:%d", t.Config.LocalPort, shortRemoteHost, t.Config.RemotePort)
+			// No health column here, so only mention a target in trouble
+			if t.Status == "active" && (t.Values.Health == ssh.HealthDegraded || t.Values.Health == ssh.HealthDown) {
+				message = fmt.Sprintf("target %s • %s", t.Values.Health, message)
+			}
+
+			tunnel := fmt.Sprintf("%s:%s:%d", a.localPortLabel(&t), shortRemoteHost, t.Config.RemotePort)
+			if t.Config.IsUDP() {
+				tunnel += "/" + t.Config.Type
+			}
 
 			rows[i] = table.Row{
 				status,
-				t.Config.Name,
+				nameLabel(&t),
 				tunnel,
-				t.Config.Tag,
+				a.tagLabel(t.Config.Tag),
 				message,
 			}
 		}
@@ -337,88 +581,203 @@ func (a *App) updateTableRows() {
 	a.table.SetRows(rows)
 }
 
+// statusIcon is how a status shows in the STATUS column, without styling
+func statusIcon(status string) string {
+	switch status {
+	case "active":
+		return "[✓]"
+	case "error":
+		return "[!]"
+	case "connecting":
+		return "[~]"
+	}
+	return "[x]"
+}
+
+// SetSafeMode starts the app with autostart and metrics off, after
+// repeated crashes, saying why
+func (a *App) SetSafeMode(reason string) {
+	a.safeMode = reason
+	ssh.DisableMetrics(true)
+}
+
+// serveMetrics lets Prometheus scrape what runs here
+func (a *App) serveMetrics() {
+	addr := a.loader.Config().MetricsListen
+	if addr == "" {
+		return
+	}
+	exporter, err := metrics.Listen(addr)
+	if err != nil {
+		a.logError("%v", err)
+		return
+	}
+	a.metrics = exporter
+	a.metrics.Update(metricsSamples(a.tunnels))
+}
+
+// tick schedules the next redraw
+func (a *App) tick() tea.Cmd {
+	interval := a.refreshInterval
+	if interval <= 0 {
+		interval = config.DefaultRefreshInterval
+	}
+	round := a.loopRound
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return tickMsg{round: round, at: t}
+	})
+}
+
+// sample schedules the next metrics sample
+func (a *App) sample() tea.Cmd {
+	round := a.loopRound
+	return tea.Tick(sampleInterval, func(t time.Time) tea.Msg {
+		return sampleMsg{round: round, at: t}
+	})
+}
+
 func (a *App) Init() tea.Cmd {
+	// Bring up tunnels whose tag is set to autostart, unless a bad entry
+	// may have crashed the last startups
+	autostart := a.autostartTunnels
+	if a.safeMode != "" {
+		a.logError("Safe mode: %s", a.safeMode)
+		a.Logf("Autostart and metrics are off. Fix or remove the entry and restart tunnel9 to leave safe mode")
+		autostart = func() tea.Cmd { return nil }
+	} else {
+		a.serveMetrics()
+	}
+
 	// Return multiple commands using tea.Batch
 	return tea.Batch(
-		// Original tick command
-		tea.Tick(time.Second, func(t time.Time) tea.Msg {
-			return tickMsg(t)
-		}),
-		// New command to read from log channel
-		func() tea.Msg {
-			msg := <-a.manager.LogChan
-			return logMsg(msg)
-		},
-		// New command to read from status channel
-		func() tea.Msg {
-			status := <-a.manager.StatusChan
-			return statusMsg(status)
-		},
+		a.tick(),
+		a.sample(),
+		// Read tunnel logs and status changes from the event bus
+		a.waitForLog(),
+		a.waitForStatus(),
+		a.waitForHostKey(),
+		a.waitForAuth(),
+		autostart(),
+		// And the temporary ones given on the command line
+		a.startTemporaryTunnels(),
 	)
 }
 
+// waitForLog delivers the next log line from the event bus
+func (a *App) waitForLog() tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-a.logEvents.C
+		if !ok {
+			return nil
+		}
+		return logMsg{level: event.Level, text: event.Message}
+	}
+}
+
+// waitForStatus delivers the next tunnel status change from the event bus
+func (a *App) waitForStatus() tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-a.statusEvents.C
+		if !ok {
+			return nil
+		}
+		return statusMsg(event)
+	}
+}
+
 func (a *App) logError(format string, args ...interface{}) {
 	msg := fmt.Sprintf("%s ERROR %s", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
-	a.errorLog = append(a.errorLog, msg)
+	a.persistLog(msg)
+	a.errorLog = append(a.errorLog, logLine{level: events.LevelError, text: msg})
 	// Keep only last 10 messages
 	if len(a.errorLog) > 10 {
 		a.errorLog = a.errorLog[len(a.errorLog)-10:]
 	}
 }
 
-func (a *App) getAllFilteredLogs() []string {
-	if !a.filterLogs {
-		return a.errorLog
+// filteredTunnels returns the tunnels shown under the current tag and status
+// filters, in table order
+func (a *App) filteredTunnels() []TunnelRecord {
+	if a.currentTag == "" && a.statusFilter == "" {
+		return a.tunnels
 	}
 
-	cursor := a.table.Cursor()
-
-	// Get the filtered tunnels if there's a tag filter
-	filteredTunnels := a.tunnels
-	if a.currentTag != "" {
-		selectedTags := strings.Split(a.currentTag, ",")
-		filteredTunnels = make([]TunnelRecord, 0)
-		for _, t := range a.tunnels {
-			for _, tag := range selectedTags {
-				if t.Config.Tag == tag {
-					filteredTunnels = append(filteredTunnels, t)
-					break
-				}
+	selectedTags := strings.Split(a.currentTag, ",")
+	filtered := make([]TunnelRecord, 0)
+	for _, t := range a.tunnels {
+		if !a.matchesStatusFilter(&t) {
+			continue
+		}
+		if a.currentTag == "" {
+			filtered = append(filtered, t)
+			continue
+		}
+		for _, tag := range selectedTags {
+			if t.Config.Tag == tag {
+				filtered = append(filtered, t)
+				break
 			}
 		}
 	}
+	return filtered
+}
 
-	if cursor >= len(filteredTunnels) {
-		return a.errorLog
+// selectedRecord returns the tunnel under the table cursor, or nil, also
+// when the cursor is on a group header
+func (a *App) selectedRecord() *TunnelRecord {
+	entries := a.tableEntries()
+	cursor := a.table.Cursor()
+	if cursor < 0 || cursor >= len(entries) {
+		return nil
 	}
+	return entries[cursor].tunnel
+}
 
-	// Find the actual tunnel from the filtered list
-	selectedTunnel := filteredTunnels[cursor]
-	var selected *TunnelRecord
-	for i := range a.tunnels {
-		if a.tunnels[i].ID == selectedTunnel.ID {
-			selected = &a.tunnels[i]
-			break
+// selectedIndex returns where the tunnel under the table cursor is in
+// a.tunnels, or -1
+func (a *App) selectedIndex() int {
+	if selected := a.selectedRecord(); selected != nil {
+		for i := range a.tunnels {
+			if a.tunnels[i].ID == selected.ID {
+				return i
+			}
 		}
 	}
+	return -1
+}
 
-	if selected == nil {
+// getAllFilteredLogs returns the console lines at or above the least level
+// shown, only those of the selected tunnel when filtering by it
+func (a *App) getAllFilteredLogs() []logLine {
+	var prefix string
+	if a.filterLogs {
+		if selected := a.selectedRecord(); selected != nil {
+			prefix = fmt.Sprintf("[%s]", selected.Config.Name)
+		}
+	}
+	if prefix == "" && a.logLevel == events.LevelDebug {
 		return a.errorLog
 	}
 
-	prefix := fmt.Sprintf("[%s]", selected.Config.Name)
-
-	filtered := make([]string, 0)
+	filtered := make([]logLine, 0)
 	for _, log := range a.errorLog {
-		// Skip timestamp (first 8 chars) when looking for the tunnel name prefix
-		if len(log) > 9 && strings.Contains(log[9:], prefix) {
+		if log.pinned {
 			filtered = append(filtered, log)
+			continue
+		}
+		if log.level < a.logLevel {
+			continue
 		}
+		// Skip timestamp (first 8 chars) when looking for the tunnel name prefix
+		if prefix != "" && (len(log.text) <= 9 || !strings.Contains(log.text[9:], prefix)) {
+			continue
+		}
+		filtered = append(filtered, log)
 	}
 	return filtered
 }
 
-func (a *App) getVisibleLogs(logs []string) []string {
+func (a *App) getVisibleLogs(logs []logLine) []logLine {
 	if len(logs) == 0 {
 		return logs
 	}
@@ -456,31 +815,25 @@ func (a *App) getVisibleLogs(logs []string) []string {
 	return logs[start:end]
 }
 
-func (a *App) getFilteredLogs() []string {
+func (a *App) getFilteredLogs() []logLine {
 	return a.getVisibleLogs(a.getAllFilteredLogs())
 }
 
-func (a *App) colorizeLogLine(line string) string {
+func (a *App) colorizeLogLine(log logLine) string {
 	// Color styles for log levels
-	debugStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("14"))    // cyan
-	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))     // red
-	tunnelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("13"))   // pink/magenta
-	timestampStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8")) // grey
+	levelStyle := levelStyle(log.level)
+	tunnelStyle := lipgloss.NewStyle().Foreground(activeTheme.highlight)
+	timestampStyle := lipgloss.NewStyle().Foreground(activeTheme.muted)
 
 	// Parse the line and rebuild with colors
-	// Format: timestamp DEBUG [tunnel-name] message
-	timestampRegex := regexp.MustCompile(`^(\d{2}:\d{2}:\d{2})\s+(DEBUG|ERROR)\s+(\[[^\]]+\])\s+(.*)$`)
-	matches := timestampRegex.FindStringSubmatch(line)
+	// Format: timestamp LEVEL [tunnel-name] message
+	line := log.text
+	matches := tunnelLogLine.FindStringSubmatch(line)
 
 	if len(matches) == 5 {
 		// Format: timestamp LEVEL [tunnel] message
 		timestamp := timestampStyle.Render(matches[1])
-		var level string
-		if matches[2] == "DEBUG" {
-			level = debugStyle.Render(matches[2])
-		} else {
-			level = errorStyle.Render(matches[2])
-		}
+		level := levelStyle.Render(matches[2])
 		tunnel := tunnelStyle.Render(matches[3])
 		message := matches[4]
 
@@ -488,12 +841,11 @@ func (a *App) colorizeLogLine(line string) string {
 	}
 
 	// Fallback for lines without tunnel name or different format
-	// Try: timestamp ERROR message
-	simpleErrorRegex := regexp.MustCompile(`^(\d{2}:\d{2}:\d{2})\s+(ERROR)\s+(.*)$`)
-	simpleMatches := simpleErrorRegex.FindStringSubmatch(line)
+	// Try: timestamp LEVEL message
+	simpleMatches := simpleLogLine.FindStringSubmatch(line)
 	if len(simpleMatches) == 4 {
 		timestamp := timestampStyle.Render(simpleMatches[1])
-		level := errorStyle.Render(simpleMatches[2])
+		level := levelStyle.Render(simpleMatches[2])
 		message := simpleMatches[3]
 		return fmt.Sprintf("%s %s %s", timestamp, level, message)
 	}
@@ -511,12 +863,10 @@ func (a *App) colorizeLogLine(line string) string {
 		return tunnelStyle.Render(match)
 	})
 
-	// Colorize DEBUG/ERROR
-	if strings.Contains(line, " DEBUG ") {
-		line = strings.ReplaceAll(line, " DEBUG ", " "+debugStyle.Render("DEBUG")+" ")
-	}
-	if strings.Contains(line, " ERROR ") {
-		line = strings.ReplaceAll(line, " ERROR ", " "+errorStyle.Render("ERROR")+" ")
+	// Colorize the level if named
+	name := " " + log.level.String() + " "
+	if strings.Contains(line, name) {
+		line = strings.ReplaceAll(line, name, " "+levelStyle.Render(log.level.String())+" ")
 	}
 
 	return line
@@ -599,6 +949,7 @@ func parseSshString(sshStr string) (*config.TunnelConfig, error) {
 	config := config.TunnelConfig{
 		Name:        fmt.Sprintf("%s-%d", remoteHost, localPort),
 		LocalPort:   localPort,
+		AutoPort:    localPort == 0, // -L 0:... listens on any free port
 		RemotePort:  remotePort,
 		RemoteHost:  remoteHost,
 		BindAddress: bindAddr,
@@ -638,6 +989,9 @@ func parseSshString(sshStr string) (*config.TunnelConfig, error) {
 
 func (a *App) initDialog(mode dialogMode) {
 	a.dialogMode = mode
+	a.dialogTemplate = -1
+	a.dialogCheck = ""
+	a.loadRecent()
 	a.dialogFields = []dialogField{
 		{label: "Input Mode", value: "fields", cursor: 0, isHidden: true},
 		{label: "SSH Command", value: "", cursor: 0, isHidden: true},
@@ -650,40 +1004,12 @@ func (a *App) initDialog(mode dialogMode) {
 		{label: "Bastion User (optional)", value: "", cursor: 0},
 		{label: "Name", value: "", cursor: 0},
 		{label: "Tag", value: "", cursor: 0},
+		{label: "Ignore ssh_config (optional)", value: "", cursor: 0},
+		{label: "Temporary, not saved (y/N)", value: "", cursor: 0},
 	}
 
 	if mode == modeEdit {
-		cursor := a.table.Cursor()
-
-		// Get the filtered tunnels if there's a tag filter
-		filteredTunnels := a.tunnels
-		if a.currentTag != "" {
-			selectedTags := strings.Split(a.currentTag, ",")
-			filteredTunnels = make([]TunnelRecord, 0)
-			for _, t := range a.tunnels {
-				for _, tag := range selectedTags {
-					if t.Config.Tag == tag {
-						filteredTunnels = append(filteredTunnels, t)
-						break
-					}
-				}
-			}
-		}
-
-		if cursor >= len(filteredTunnels) {
-			return
-		}
-
-		// Find the actual tunnel index from the filtered tunnel
-		selectedTunnel := filteredTunnels[cursor]
-		actualIndex := -1
-		for i, t := range a.tunnels {
-			if t.ID == selectedTunnel.ID {
-				actualIndex = i
-				break
-			}
-		}
-
+		actualIndex := a.selectedIndex()
 		if actualIndex == -1 {
 			return
 		}
@@ -719,6 +1045,9 @@ func (a *App) initDialog(mode dialogMode) {
 		a.dialogFields[2].value = selected.Config.BindAddress
 		a.dialogFields[2].cursor = len(selected.Config.BindAddress)
 		a.dialogFields[3].value = fmt.Sprintf("%d", selected.Config.LocalPort)
+		if selected.Config.AutoPort {
+			a.dialogFields[3].value = config.AutoPort
+		}
 		a.dialogFields[3].cursor = len(a.dialogFields[3].value)
 		a.dialogFields[4].value = selected.Config.RemoteHost
 		a.dialogFields[4].cursor = len(selected.Config.RemoteHost)
@@ -734,11 +1063,17 @@ func (a *App) initDialog(mode dialogMode) {
 		a.dialogFields[9].cursor = len(selected.Config.Name)
 		a.dialogFields[10].value = selected.Config.Tag
 		a.dialogFields[10].cursor = len(selected.Config.Tag)
+		a.dialogFields[11].value = strings.Join(selected.Config.SSHConfigIgnore, ",")
+		a.dialogFields[11].cursor = len(a.dialogFields[11].value)
+		if selected.Temporary {
+			a.dialogFields[12].value = "yes"
+			a.dialogFields[12].cursor = len(a.dialogFields[12].value)
+		}
 
 	}
 
 	// Set active field to first visible field
-	if mode == modeNew || (mode == modeEdit && a.tunnels[a.editingIndex].Status != "active") {
+	if mode != modeEdit || a.tunnels[a.editingIndex].Status != "active" {
 		for i := range a.dialogFields {
 			if !a.dialogFields[i].isHidden {
 				a.activeField = i
@@ -767,23 +1102,31 @@ func (a *App) handleDialogSubmit() {
 		}
 	}
 
+	if err := a.expandTemplate(); err != nil {
+		a.logError("%v", err)
+		return
+	}
+
 	if a.dialogFields[0].value == "ssh" {
 		// Parse from SSH command
 		updatedConfig, err = parseSshString(a.dialogFields[1].value)
 		if err != nil {
-			a.errorLog = append(a.errorLog, fmt.Sprintf("Error parsing SSH string: %v", err))
+			a.errorLog = append(a.errorLog, logLine{level: events.LevelError, text: fmt.Sprintf("Error parsing SSH string: %v", err)})
 			return
 		}
 	} else {
-		// Parse from individual fields
-		localPort, err := strconv.Atoi(a.dialogFields[3].value)
-		if err != nil {
-			a.errorLog = append(a.errorLog, "Invalid local port")
-			return
+		// Parse from individual fields, an empty local port comes from port_range
+		localPort, autoPort := 0, false
+		if a.dialogFields[3].value != "" || a.dialogPortRange() == "" {
+			localPort, autoPort, err = config.ParseLocalPort(a.dialogFields[3].value)
+			if err != nil {
+				a.errorLog = append(a.errorLog, logLine{level: events.LevelError, text: "Invalid local port"})
+				return
+			}
 		}
 		remotePort, err := strconv.Atoi(a.dialogFields[5].value)
 		if err != nil {
-			a.errorLog = append(a.errorLog, "Invalid remote port")
+			a.errorLog = append(a.errorLog, logLine{level: events.LevelError, text: "Invalid remote port"})
 			return
 		}
 
@@ -809,6 +1152,7 @@ func (a *App) handleDialogSubmit() {
 
 		updatedConfig = &config.TunnelConfig{
 			LocalPort:   localPort,
+			AutoPort:    autoPort,
 			RemoteHost:  a.dialogFields[4].value,
 			RemotePort:  remotePort,
 			BindAddress: a.dialogFields[2].value,
@@ -826,29 +1170,100 @@ func (a *App) handleDialogSubmit() {
 		updatedConfig.Name = a.dialogFields[9].value
 	}
 	updatedConfig.Tag = a.dialogFields[10].value
+	updatedConfig.SSHConfigIgnore = parseIgnoreList(a.dialogFields[11].value)
+	temporary := parseYesNo(a.dialogFields[12].value)
+
+	if updatedConfig.LocalPort == 0 && !updatedConfig.AutoPort && a.dialogPortRange() != "" {
+		if !a.assignLocalPort(updatedConfig) {
+			a.logError("No free local port left in port_range %s", a.dialogPortRange())
+			return
+		}
+	}
 
 	if a.dialogMode == modeEdit {
-		// Update existing tunnel
+		// Update existing tunnel, keeping settings the dialog doesn't expose
 		selected := &a.tunnels[a.editingIndex]
-		selected.Config = *updatedConfig
+		selected.Config = mergeDialogConfig(selected.Config, *updatedConfig)
+		selected.Temporary = temporary
 		a.Logf("Updated tunnel: %s", updatedConfig.Name)
 	} else {
 		// Create new tunnel record
 		tunnel := TunnelRecord{
-			ID:      uuid.New().String(),
-			Status:  "stopped",
-			Config:  *updatedConfig,
-			Metrics: "--",
+			ID:        uuid.New().String(),
+			Status:    "stopped",
+			Config:    *updatedConfig,
+			Metrics:   "--",
+			Temporary: temporary,
 		}
 		a.tunnels = append(a.tunnels, tunnel)
-		a.Logf("Added new tunnel: %s", updatedConfig.Name)
+		if temporary {
+			a.Logf("Added temporary tunnel: %s, it won't be saved", updatedConfig.Name)
+		} else {
+			a.Logf("Added new tunnel: %s", updatedConfig.Name)
+		}
 	}
 
+	a.warnPortCollisions(updatedConfig)
+	a.recordRecent(*updatedConfig)
 	a.updateTableRows()
 	a.saveConfig()
 	a.showDialog = false
 }
 
+// assignLocalPort gives a tunnel created without a local port one from
+// port_range, avoiding the ports of every other tunnel
+func (a *App) assignLocalPort(tc *config.TunnelConfig) bool {
+	used := make(map[int]bool)
+	for i, t := range a.tunnels {
+		if a.dialogMode == modeEdit && i == a.editingIndex {
+			continue
+		}
+		used[t.Config.LocalPort] = true
+	}
+	var port int
+	var ok bool
+	if template, selected := a.selectedTemplate(); selected && template.PortRange != "" {
+		// Saved with the tunnel, since the template's range isn't known on load
+		if lo, hi, err := config.ParsePortRange(template.PortRange); err == nil {
+			port, ok = config.AssignPort(tc.Name, lo, hi, used)
+		}
+	} else {
+		port, ok = a.loader.AssignPort(tc.Name, used)
+	}
+	if ok {
+		tc.LocalPort = port
+		a.Logf("Assigned local port %d to %s from port_range", port, tc.Name)
+	}
+	return ok
+}
+
+// mergeDialogConfig applies the fields the edit dialog manages on top of an
+// existing config, so settings only available in YAML survive an edit
+func mergeDialogConfig(existing config.TunnelConfig, edited config.TunnelConfig) config.TunnelConfig {
+	merged := existing
+	merged.Name = edited.Name
+	merged.LocalPort = edited.LocalPort
+	merged.AutoPort = edited.AutoPort
+	merged.RemotePort = edited.RemotePort
+	merged.RemoteHost = edited.RemoteHost
+	merged.Tag = edited.Tag
+	merged.BindAddress = edited.BindAddress
+	merged.Bastion = edited.Bastion
+	merged.SSHConfigIgnore = edited.SSHConfigIgnore
+	return merged
+}
+
+// parseIgnoreList turns "port, identity_file" into the ssh_config_ignore list
+func parseIgnoreList(value string) []string {
+	var ignore []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			ignore = append(ignore, key)
+		}
+	}
+	return ignore
+}
+
 func (a *App) initTagDialog() {
 	// Collect unique tags
 	tagMap := make(map[string]bool)
@@ -863,7 +1278,7 @@ func (a *App) initTagDialog() {
 	for tag := range tagMap {
 		tags = append(tags, tag)
 	}
-	sort.Strings(tags)
+	a.sortTags(tags)
 
 	a.tagOptions = tags
 	a.selectedTags = tagMap
@@ -871,32 +1286,125 @@ func (a *App) initTagDialog() {
 }
 
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	wake := a.wake(msg)
+	model, cmd := a.update(msg)
+	if wake == nil {
+		return model, cmd
+	}
+	return model, tea.Batch(cmd, wake)
+}
+
+func (a *App) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// Handle unknown host key dialog, a connection is waiting on it
+	if a.hostKeyPrompt != nil {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleHostKeyKey(msg)
+		}
+	}
+
+	// Handle authentication challenges, a handshake is waiting on them
+	if a.authPrompt != nil {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleAuthKey(msg)
+		}
+	}
+
+	// Handle stop confirmation dialog
+	if a.showStopConfirm {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleStopConfirmKey(msg)
+		}
+	}
+
+	// Handle quick actions menu
+	if a.showMenu {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleMenuKey(msg)
+		}
+	}
+
+	// Handle the profile switcher
+	if a.showProfiles {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleProfilesKey(msg)
+		}
+	}
+
+	// Handle the startup group picker
+	if a.showGroups {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleGroupsKey(msg)
+		}
+	}
+
+	// Handle the discovered services list
+	if a.showServices {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleServicesKey(msg)
+		}
+	}
+
+	// Handle the session timeline
+	if a.showTimeline {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleTimelineKey(msg)
+		}
+	}
+
+	// Handle the host maintenance list
+	if a.showMaintenance {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleMaintenanceKey(msg)
+		}
+	}
+
+	// Handle the host key review
+	if a.showHostScan {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleHostScanKey(msg)
+		}
+	}
+
+	// Handle the ssh_config import
+	if a.showSSHImport {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleSSHImportKey(msg)
+		}
+	}
+
+	// Handle the discovered tunnels view
+	if a.showDiscover {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleDiscoverKey(msg)
+		}
+	}
+
+	// Handle duplicate share confirmation dialog
+	if a.showShareConfirm {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			return a.handleShareConfirmKey(msg)
+		}
+	}
+
 	// Handle delete confirmation dialog
 	if a.showDeleteConfirm {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			switch msg.Type {
 			case tea.KeyEnter:
-				if a.deleteIndex >= 0 && a.deleteIndex < len(a.tunnels) {
-					selected := &a.tunnels[a.deleteIndex]
-					// Don't allow deletion of active tunnels
-					if selected.Status == "active" || selected.Status == "connecting" {
-						a.logError("Cannot delete active tunnel. Stop it first.")
-						a.showDeleteConfirm = false
-						return a, nil
-					}
-					// Remove the tunnel
-					a.tunnels = append(a.tunnels[:a.deleteIndex], a.tunnels[a.deleteIndex+1:]...)
-					a.Logf("Deleted tunnel: %s", selected.Config.Name)
-					a.saveConfig()
-					a.updateTableRows()
+				if len(a.deleteIDs) > 0 {
+					a.deleteTunnels(a.deleteIDs)
+				} else {
+					a.deleteTunnel(a.deleteIndex)
 				}
 				a.showDeleteConfirm = false
+				a.deleteIDs = nil
 				return a, nil
 			case tea.KeyEsc, tea.KeyCtrlC:
 				a.showDeleteConfirm = false
+				a.deleteIDs = nil
 				return a, nil
 			}
 			return a, nil
@@ -907,6 +1415,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if a.showDialog {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
+			// Typing keeps the suggestion filled in, and suggests from it
+			if msg.Type != tea.KeyCtrlN {
+				a.suggestIndex = -1
+			}
 			switch msg.Type {
 			case tea.KeyRunes:
 				switch string(msg.Runes) {
@@ -978,9 +1490,28 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case tea.KeyEnter:
 				// Process the form on Enter key
+				if a.dialogMode == modeConnect {
+					return a, a.submitQuickConnect()
+				}
 				a.handleDialogSubmit()
 				return a, nil
 
+			case tea.KeyCtrlR:
+				// Try the settings before saving them
+				return a, a.checkConnection()
+
+			case tea.KeyCtrlN:
+				// Fill in a recently used host or user
+				a.nextSuggestion()
+				return a, nil
+
+			case tea.KeyCtrlT:
+				// Start over from the next template
+				if a.dialogMode == modeNew && len(a.templates) > 0 {
+					a.cycleTemplate()
+				}
+				return a, nil
+
 			case tea.KeyEsc, tea.KeyCtrlC:
 				// Cancel dialog
 				a.showDialog = false
@@ -1078,48 +1609,132 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case statusMsg:
 		// Find the tunnel and update its status
+		var notifications tea.Cmd
 		for i, t := range a.tunnels {
-			if t.ID == string(msg.ID) {
-				a.tunnels[i].Status = string(msg.State)
-				a.tunnels[i].Metrics = msg.Message
+			if t.ID == msg.TunnelID {
+				a.tunnels[i].setStatus(msg.State, msg.Message)
+				notifications = a.noteTransition(&a.tunnels[i], t.Status)
 				a.updateTableRows()
 				break
 			}
 		}
-		// Continue reading from the channel
-		return a, func() tea.Msg {
-			status := <-a.manager.StatusChan
-			return statusMsg(status)
-		}
+		// Continue reading from the event bus
+		return a, tea.Batch(a.waitForStatus(), notifications)
+
+	case hostKeyMsg:
+		a.hostKeyPrompt = msg.prompt
+		return a, nil
+
+	case authMsg:
+		a.openAuthPrompt(msg.prompt)
+		return a, nil
+
+	case bastionReadyMsg:
+		return a, a.handleBastionReady(msg)
+
+	case shareReadyMsg:
+		a.handleShareReady(msg)
+		return a, nil
 
 	case logMsg:
-		// Add the new log message to our log
-		a.errorLog = append(a.errorLog, string(msg))
+		// The log file gets every line, the console collapses repeats
+		a.persistLog(msg.text)
+		a.errorLog = append(a.errorLog, a.dampener.filter(logLine(msg), time.Now())...)
 		// Keep only last 100 messages for scrolling
 		if len(a.errorLog) > 100 {
 			a.errorLog = a.errorLog[len(a.errorLog)-100:]
 		}
 		// Update viewport content
 		a.updateViewport()
-		// Continue reading from the channel
-		return a, func() tea.Msg {
-			msg := <-a.manager.LogChan
-			return logMsg(msg)
-		}
+		// Continue reading from the event bus
+		return a, a.waitForLog()
 
 	case tickMsg:
+		if msg.round != a.loopRound {
+			return a, nil
+		}
+		// Redraw with the metrics sampled since the last time, one last
+		// time once idle
+		a.settleBulk()
+		a.updateTableRows()
+		if a.suspended {
+			return a, nil
+		}
+		return a, a.tick()
+
+	case sampleMsg:
+		if msg.round != a.loopRound {
+			return a, nil
+		}
 		// Update metrics for active tunnels
 		for i, t := range a.tunnels {
+			var values ssh.MetricValues
 			if t.Status == "active" {
 				a.tunnels[i].Metrics = a.manager.GetMetrics(t.ID)
+				values, _ = a.manager.GetMetricValues(t.ID)
 			}
+			a.tunnels[i].Transfer.observe(t.Values, values)
+			a.tunnels[i].Values = values
 		}
-		a.updateTableRows()
 
-		// Schedule next update
-		return a, tea.Tick(time.Second, func(t time.Time) tea.Msg {
-			return tickMsg(t)
-		})
+		// Surface errors that have been repeating quietly
+		if summaries := a.dampener.flush(time.Now()); len(summaries) > 0 {
+			a.errorLog = append(a.errorLog, summaries...)
+			if len(a.errorLog) > 100 {
+				a.errorLog = a.errorLog[len(a.errorLog)-100:]
+			}
+			a.updateViewport()
+		}
+
+		// Pick up changes in what the team is sharing
+		a.registryTicks++
+		if a.registryTicks >= registryRefreshTicks {
+			a.registryTicks = 0
+			a.refreshRegistry()
+		}
+		if a.mqtt != nil {
+			a.mqtt.update(a.tunnels, msg.at)
+		}
+		if a.metrics != nil {
+			a.metrics.Update(metricsSamples(a.tunnels))
+		}
+
+		// Schedule next update, unless there is nothing left to sample
+		next := a.sample()
+		if a.idle() {
+			a.suspended = true
+			next = a.sampleWhenDue(msg.at)
+		}
+		return a, tea.Batch(
+			a.advanceGroups(msg.at),
+			a.sendHeartbeat(msg.at),
+			a.sendEmail(msg.at),
+			next,
+		)
+
+	case heartbeatMsg:
+		a.handleHeartbeat(msg)
+		return a, nil
+
+	case emailMsg:
+		a.handleEmail(msg)
+		return a, nil
+
+	case notifyMsg:
+		a.handleNotify(msg)
+		return a, nil
+
+	case connectionCheckMsg:
+		a.showConnectionCheck(msg)
+		return a, nil
+
+	case hostScanMsg:
+		a.showHostScanResult(msg)
+		return a, nil
+
+	case servicesMsg:
+		a.showServicesResult(msg)
+		return a, nil
 
 	case tea.WindowSizeMsg:
 		// Save the window size
@@ -1133,7 +1748,11 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if a.showConsole {
 			consoleHeight = maxConsoleHeight + 1 // Console box height + spacing
 		}
-		availableHeight := a.height - headerHeight - footerHeight - consoleHeight
+		detailHeight := 0
+		if a.showDetail {
+			detailHeight = detailPaneHeight + 3 // Content + border + spacing
+		}
+		availableHeight := a.height - headerHeight - footerHeight - consoleHeight - detailHeight
 
 		// Ensure we show at least all tunnels if we have space
 		minHeight := len(a.tunnels)
@@ -1143,12 +1762,21 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		a.table.SetHeight(availableHeight)
 
+		// Keep wide mode from scrolling further than the new width needs
+		if a.isWideMode {
+			a.clampHScroll()
+			a.updateTableRows()
+		}
+
 		// Update viewport and console style width to match screen width
 		a.viewport.Width = a.width - 2
 		consoleStyle = consoleStyle.Width(a.width - 2)
 		a.viewport.Style = consoleStyle
 		return a, nil
 
+	case tea.MouseMsg:
+		return a.handleMouse(msg)
+
 	case tea.KeyMsg:
 		if a.showHelp {
 			if msg.String() == "esc" || msg.String() == "h" || msg.String() == "ctrl+c" {
@@ -1201,13 +1829,31 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.logCursor = len(allLogs) - 1
 				a.updateViewport()
 				return a, nil
+			case "L":
+				a.cycleLogLevel()
+				return a, nil
+			case "E":
+				a.exportLogs(time.Now())
+				return a, nil
 			}
 		}
 
 		switch msg.String() {
 		case "q", "ctrl+c":
 			// Cleanup all resources before quitting
+			for i := range a.tunnels {
+				if a.tunnels[i].Status != "stopped" {
+					a.unregisterForward(&a.tunnels[i])
+				}
+			}
+			a.teardownAllBastions()
+			if a.mqtt != nil {
+				a.mqtt.close(a.tunnels)
+			}
 			a.manager.Cleanup()
+			if a.logFile != nil {
+				a.logFile.Close()
+			}
 			return a, tea.Quit
 
 		case "h":
@@ -1223,24 +1869,56 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Trigger a window resize to adjust table height
 			return a.Update(tea.WindowSizeMsg{Width: a.width, Height: a.height})
 
-		case "<", ",":
+		case ",":
 			// Move to previous column
-			a.sortColumn--
-			if a.sortColumn < 0 {
-				a.sortColumn = len(a.table.Columns()) - 1
-			}
+			a.stepSortColumn(-1)
 			a.sortTunnels()
 			a.updateTableRows()
 
-		case ">", ".":
+		case ".":
 			// Move to next column
-			a.sortColumn++
-			if a.sortColumn >= len(a.table.Columns()) {
-				a.sortColumn = 0
+			a.stepSortColumn(1)
+			a.sortTunnels()
+			a.updateTableRows()
+
+		case "K", "shift+up":
+			// Move the selected tunnel up in its own order
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.moveSelected(-1)
+				return a, nil
+			}
+
+		case "J", "shift+down":
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.moveSelected(1)
+				return a, nil
 			}
+
+		case "<":
+			// Move the secondary sort key to the previous column
+			a.secondarySort = a.stepSecondarySort(-1)
 			a.sortTunnels()
 			a.updateTableRows()
 
+		case ">":
+			// Move the secondary sort key to the next column
+			a.secondarySort = a.stepSecondarySort(1)
+			a.sortTunnels()
+			a.updateTableRows()
+
+		case "left":
+			// Scroll wide mode columns, keeping STATUS and NAME in place
+			if a.isWideMode {
+				a.scrollWide(-1)
+				a.updateTableRows()
+			}
+
+		case "right":
+			if a.isWideMode {
+				a.scrollWide(1)
+				a.updateTableRows()
+			}
+
 		case "r":
 			// Reverse sort order
 			a.sortReverse = !a.sortReverse
@@ -1252,110 +1930,56 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, nil
 			}
 
-			cursor := a.table.Cursor()
-
-			// Get the filtered tunnels if there's a tag filter
-			filteredTunnels := a.tunnels
-			if a.currentTag != "" {
-				selectedTags := strings.Split(a.currentTag, ",")
-				filteredTunnels = make([]TunnelRecord, 0)
-				for _, t := range a.tunnels {
-					for _, tag := range selectedTags {
-						if t.Config.Tag == tag {
-							filteredTunnels = append(filteredTunnels, t)
-							break
-						}
-					}
-				}
-			}
-
-			if cursor >= len(filteredTunnels) {
+			// Group headers fold and unfold
+			if a.toggleSelectedGroup() {
 				return a, nil
 			}
 
-			// Find the actual tunnel from the filtered list
-			selectedTunnel := filteredTunnels[cursor]
-			var selected *TunnelRecord
-			for i := range a.tunnels {
-				if a.tunnels[i].ID == selectedTunnel.ID {
-					selected = &a.tunnels[i]
-					break
-				}
-			}
-
+			selected := a.selectedRecord()
 			if selected == nil {
 				return a, nil
 			}
 
 			switch selected.Status {
 			case "stopped", "error":
-				// Try to start the tunnel
-				tunnel := a.manager.CreateTunnel(
-					selected.ID,
-					selected.Config,
-				)
-				if tunnel == nil {
-					selected.Status = "error"
-					selected.Metrics = "failed to start"
-					a.logError("Failed to start tunnel to %s", selected.Config.RemoteHost)
-				} else {
-					selected.Status = "connecting"
-					selected.Metrics = "initializing"
-					a.manager.StartTunnel(tunnel)
+				// Ask first if a teammate already shares this target
+				if a.confirmShareConflict(selected) {
+					return a, nil
 				}
+				cmd := a.startTunnel(selected)
+				a.updateTableRows()
+				return a, cmd
 			case "active", "connecting":
-				// Try to stop the tunnel
-				err := a.manager.StopTunnel(selected.ID)
-				if err != nil {
-					selected.Status = "error"
-					selected.Metrics = fmt.Sprintf("stop: %v", err)
-					a.logError("Failed to stop tunnel %s: %v", selected.Config.RemoteHost, err)
-				} else {
-					selected.Status = "stopped"
-					selected.Metrics = "stopped"
-				}
+				cmd := a.confirmStop([]*TunnelRecord{selected}, "Stop", func() tea.Cmd {
+					a.stopTunnel(selected)
+					return nil
+				})
+				a.updateTableRows()
+				return a, cmd
 			}
 
 			a.updateTableRows()
 
 		case "delete", "backspace":
 			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
-				cursor := a.table.Cursor()
-				// Get the filtered tunnels if there's a tag filter
-				filteredTunnels := a.tunnels
-				if a.currentTag != "" {
-					selectedTags := strings.Split(a.currentTag, ",")
-					filteredTunnels = make([]TunnelRecord, 0)
-					for _, t := range a.tunnels {
-						for _, tag := range selectedTags {
-							if t.Config.Tag == tag {
-								filteredTunnels = append(filteredTunnels, t)
-								break
-							}
-						}
-					}
-				}
-
-				if cursor >= len(filteredTunnels) {
+				// Marked tunnels go all at once
+				if len(a.marked) > 0 {
+					a.confirmDeleteMarked()
 					return a, nil
 				}
 
-				// Find the actual tunnel index from the filtered tunnel
-				selectedTunnel := filteredTunnels[cursor]
-				if selectedTunnel.Status == "active" {
+				actualIndex := a.selectedIndex()
+				if actualIndex != -1 && a.tunnels[actualIndex].Status == "active" {
 					a.logError("Cannot delete active tunnel. Stop it first.")
 					return a, nil
 				}
 
-				actualIndex := -1
-				for i, t := range a.tunnels {
-					if t.ID == selectedTunnel.ID {
-						actualIndex = i
-						break
-					}
-				}
-
 				if actualIndex != -1 {
+					// Skip the dialog when the policy doesn't ask
+					if !config.ConfirmsDelete(a.confirmPolicy(&a.tunnels[actualIndex])) {
+						a.deleteTunnel(actualIndex)
+						return a, nil
+					}
 					a.deleteIndex = actualIndex
 					a.showDeleteConfirm = true
 				}
@@ -1379,13 +2003,17 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.initDialog(modeNew)
 				return a, nil
 			}
+		case "c":
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.initQuickConnect()
+				return a, nil
+			}
 		case "e":
 			if !a.showDialog && len(a.tunnels) > 0 {
-				cursor := a.table.Cursor()
-				if cursor >= len(a.tunnels) {
+				selected := a.selectedRecord()
+				if selected == nil {
 					return a, nil
 				}
-				selected := &a.tunnels[cursor]
 				// Don't allow editing of active or connecting tunnels
 				if selected.Status == "active" || selected.Status == "connecting" {
 					a.logError("Cannot edit tunnel while it is %s. Stop it first.", selected.Status)
@@ -1400,6 +2028,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.initTagDialog()
 				return a, nil
 			}
+		case "v":
+			a.cycleStatusFilter()
+			return a, nil
+		case "!":
+			a.jumpToNextError()
+			return a, nil
 		case "p":
 			a.privacyMode = !a.privacyMode
 			a.updateTableRows()
@@ -1407,43 +2041,23 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "o":
 			// Open browser to selected tunnel's local port
 			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm && len(a.tunnels) > 0 {
-				cursor := a.table.Cursor()
-
-				// Get the filtered tunnels if there's a tag filter
-				filteredTunnels := a.tunnels
-				if a.currentTag != "" {
-					selectedTags := strings.Split(a.currentTag, ",")
-					filteredTunnels = make([]TunnelRecord, 0)
-					for _, t := range a.tunnels {
-						for _, tag := range selectedTags {
-							if t.Config.Tag == tag {
-								filteredTunnels = append(filteredTunnels, t)
-								break
-							}
-						}
+				if selected := a.selectedRecord(); selected != nil {
+					tc := selected.Config
+					tc.LocalPort = a.localPort(selected)
+					if tc.LocalPort == 0 {
+						a.logError("%s picks its port when it starts, start it first", tc.Name)
+						return a, nil
 					}
-				}
-
-				if cursor >= len(filteredTunnels) {
-					return a, nil
-				}
-
-				// Find the actual tunnel from the filtered list
-				selectedTunnel := filteredTunnels[cursor]
-				var selected *TunnelRecord
-				for i := range a.tunnels {
-					if a.tunnels[i].ID == selectedTunnel.ID {
-						selected = &a.tunnels[i]
-						break
+					url, err := ssh.BrowseURL(tc)
+					if err != nil {
+						a.logError("Failed to open browser: %v", err)
+						return a, nil
 					}
-				}
-
-				if selected != nil {
-					url := fmt.Sprintf("http://localhost:%d", selected.Config.LocalPort)
 					var cmd *exec.Cmd
 					switch runtime.GOOS {
 					case "windows":
-						cmd = exec.Command("cmd", "/c", "start", url)
+						// start would cut the URL at its first &
+						cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
 					case "darwin":
 						cmd = exec.Command("open", url)
 					default:
@@ -1457,33 +2071,108 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return a, nil
 			}
+		case "i":
+			// Cycle the detail pane: activity, then settings, then closed
+			if a.showDetail && !a.detailSettings {
+				a.detailSettings = true
+				return a, nil
+			}
+			a.showDetail = !a.showDetail
+			a.detailSettings = false
+			// Trigger a window resize to adjust table height
+			return a.Update(tea.WindowSizeMsg{Width: a.width, Height: a.height})
+		case "m":
+			// Quick actions for the selected tunnel
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.openMenu()
+				return a, nil
+			}
+		case "H":
+			// Tunnels from past `ssh -L` commands, ready to import
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.openDiscover()
+				return a, nil
+			}
+		case "V":
+			// Review the host keys of the tunnels in view in one pass
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				return a, a.openHostScan()
+			}
+		case "I":
+			// LocalForward entries of ~/.ssh/config, picked before saving
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.openSSHImport()
+				return a, nil
+			}
+		case "P":
+			// Another set of tunnels, like work or personal
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.openProfiles()
+				return a, nil
+			}
+		case "D":
+			// Context for a bug report, with internal names masked
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.saveDiagnostics(time.Now())
+				return a, nil
+			}
+		case "S":
+			// Bring up a startup group, in order, with one action
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.groupCursor = 0
+				a.showGroups = true
+				return a, nil
+			}
+		case "M":
+			// Keep the tunnels of a host under planned work stopped
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.openMaintenance()
+				return a, nil
+			}
+		case "F":
+			// Find services behind the selected tunnel's SSH server
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				return a, a.openServices()
+			}
+		case "T":
+			// When each tunnel dropped and recovered this session
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.timelineOffset = 0
+				a.showTimeline = true
+				return a, nil
+			}
+		case "x":
+			// Expand the selected row inline, like a describe toggle
+			a.expandRow = !a.expandRow
+			return a, nil
+		case "s":
+			// Share selected tunnel's local port publicly
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				if selected := a.selectedRecord(); selected != nil {
+					return a, a.togglePublicShare(selected)
+				}
+				return a, nil
+			}
 		case "w":
 			a.isWideMode = !a.isWideMode
 			// Update columns based on mode
 			if a.isWideMode {
 				// First set empty rows to avoid index out of range errors
 				a.table.SetRows([]table.Row{})
-				columns := []table.Column{
-					{Title: a.baseColumns[0], Width: 8},
-					{Title: a.baseColumns[1], Width: 25},
-					{Title: a.baseColumns[2], Width: 7},
-					{Title: a.baseColumns[3], Width: 15},
-					{Title: a.baseColumns[4], Width: 30},
-					{Title: a.baseColumns[5], Width: 8},
-					{Title: a.baseColumns[6], Width: 30},
-					{Title: a.baseColumns[7], Width: 12},
-					{Title: a.baseColumns[8], Width: 40},
+				columns := make([]table.Column, len(wideColumnWidths))
+				for i, width := range wideColumnWidths {
+					columns[i] = table.Column{Title: a.baseColumns[i], Width: width}
 				}
 				a.table.SetColumns(columns)
 			} else {
 				// First set empty rows to avoid index out of range errors
 				a.table.SetRows([]table.Row{})
 				columns := []table.Column{
-					{Title: a.baseColumns[0], Width: 8},  // STATUS
-					{Title: a.baseColumns[1], Width: 25}, // NAME
-					{Title: "TUNNEL", Width: 40},         // Combined LOCAL:HOST:REMOTE
-					{Title: a.baseColumns[7], Width: 12}, // TAG
-					{Title: a.baseColumns[8], Width: 40}, // MESSAGE
+					{Title: a.baseColumns[0], Width: 8},   // STATUS
+					{Title: a.baseColumns[1], Width: 25},  // NAME
+					{Title: "TUNNEL", Width: 40},          // Combined LOCAL:HOST:REMOTE
+					{Title: a.baseColumns[7], Width: 12},  // TAG
+					{Title: a.baseColumns[14], Width: 40}, // MESSAGE
 				}
 				a.table.SetColumns(columns)
 			}
@@ -1491,66 +2180,53 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !a.isWideMode && a.sortColumn >= 5 {
 				a.sortColumn = 0
 			}
+			if !a.isWideMode && a.secondarySort >= 5 || a.secondarySort == a.sortColumn {
+				a.secondarySort = -1
+			}
+			a.hScroll = 0
 			a.updateTableRows()
 			return a, nil
 		case "A":
-			// Start all stopped tunnels
+			// Start the stopped tunnels in view, or just the marked ones
 			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
-				startedCount := 0
-				for i := range a.tunnels {
-					tunnel := &a.tunnels[i]
-					if tunnel.Status == "stopped" || tunnel.Status == "error" {
-						t := a.manager.CreateTunnel(tunnel.ID, tunnel.Config)
-						if t == nil {
-							tunnel.Status = "error"
-							tunnel.Metrics = "failed to start"
-							a.logError("Failed to start tunnel to %s", tunnel.Config.RemoteHost)
-						} else {
-							tunnel.Status = "connecting"
-							tunnel.Metrics = "initializing"
-							a.manager.StartTunnel(t)
-							startedCount++
-						}
-					}
-				}
-				if startedCount > 0 {
-					a.Logf("Started %d tunnel(s)", startedCount)
-				}
+				cmd := a.startTunnels(a.bulkTargets("stopped", "error"))
 				a.updateTableRows()
-				return a, nil
+				return a, cmd
 			}
 		case "C":
-			// Stop all active tunnels in background
+			// Stop the active tunnels in view in background, or just the marked ones
 			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
-				toStop := make([]*TunnelRecord, 0)
-				for i := range a.tunnels {
-					tunnel := &a.tunnels[i]
-					if tunnel.Status == "active" || tunnel.Status == "connecting" {
-						toStop = append(toStop, tunnel)
-						// Update status immediately for responsive UI
-						tunnel.Status = "stopping"
-						tunnel.Metrics = "stopping..."
-					}
-				}
-				if len(toStop) > 0 {
-					a.Logf("Stopping %d tunnel(s)...", len(toStop))
-					// Stop tunnels in background goroutines
-					for _, tunnel := range toStop {
-						go func(t *TunnelRecord) {
-							err := a.manager.StopTunnel(t.ID)
-							if err != nil {
-								t.Status = "error"
-								t.Metrics = fmt.Sprintf("stop: %v", err)
-							} else {
-								t.Status = "stopped"
-								t.Metrics = "stopped"
-							}
-							// Note: updateTableRows() is called by the tick handler,
-							// so the UI will update automatically on the next tick
-						}(tunnel)
-					}
-				}
+				cmd := a.stopTunnels(a.bulkTargets("active", "connecting"))
 				a.updateTableRows()
+				return a, cmd
+			}
+		case "0":
+			// Count transfers from zero again
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.resetTransfers()
+				return a, nil
+			}
+		case "z":
+			// Group the tunnels under their tags
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.toggleGroupByTag()
+				return a, nil
+			}
+		case "Z":
+			// Fold or unfold every tag group
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm && a.groupByTag {
+				a.toggleAllGroups()
+				return a, nil
+			}
+		case " ":
+			// Mark the row for bulk actions, before the table pages down
+			if !a.showDialog && !a.showTagDialog && !a.showDeleteConfirm {
+				a.toggleMark()
+				return a, nil
+			}
+		case "esc":
+			if len(a.marked) > 0 {
+				a.clearMarks()
 				return a, nil
 			}
 		}
@@ -1560,57 +2236,46 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, cmd
 }
 
-func (a *App) sortTunnels() {
-	col := a.sortColumn
-	rev := a.sortReverse
-
-	sort.SliceStable(a.tunnels, func(i, j int) bool {
-		var less bool
-		if a.isWideMode {
-			switch col {
-			case 0: // Status
-				less = a.tunnels[i].Status < a.tunnels[j].Status
-			case 1: // Name
-				less = a.tunnels[i].Config.Name < a.tunnels[j].Config.Name
-			case 2: // Local Port
-				less = a.tunnels[i].Config.LocalPort < a.tunnels[j].Config.LocalPort
-			case 3: // Bind
-				less = a.tunnels[i].Config.BindAddress < a.tunnels[j].Config.BindAddress
-			case 4: // Host
-				less = a.tunnels[i].Config.RemoteHost < a.tunnels[j].Config.RemoteHost
-			case 5: // Remote Port
-				less = a.tunnels[i].Config.RemotePort < a.tunnels[j].Config.RemotePort
-			case 6: // Bastion
-				less = a.tunnels[i].Config.Bastion.Host < a.tunnels[j].Config.Bastion.Host
-			case 7: // Tag
-				less = a.tunnels[i].Config.Tag < a.tunnels[j].Config.Tag
-			case 8: // Message
-				less = a.tunnels[i].Metrics < a.tunnels[j].Metrics
-			}
-		} else {
-			switch col {
-			case 0: // Status
-				less = a.tunnels[i].Status < a.tunnels[j].Status
-			case 1: // Name
-				less = a.tunnels[i].Config.Name < a.tunnels[j].Config.Name
-			case 2: // Combined tunnel
-				iTunnel := fmt.Sprintf("%d:%s:%d", a.tunnels[i].Config.LocalPort, a.tunnels[i].Config.RemoteHost, a.tunnels[i].Config.RemotePort)
-				jTunnel := fmt.Sprintf("%d:%s:%d", a.tunnels[j].Config.LocalPort, a.tunnels[j].Config.RemoteHost, a.tunnels[j].Config.RemotePort)
-				less = iTunnel < jTunnel
-			case 3: // Tag
-				less = a.tunnels[i].Config.Tag < a.tunnels[j].Config.Tag
-			case 4: // Message
-				less = a.tunnels[i].Metrics < a.tunnels[j].Metrics
-			}
-		}
-		if rev {
-			return !less
-		}
-		return less
-	})
+// stopInBackground stops tunnels without blocking the UI, their status
+// catches up on a later tick
+func (a *App) stopInBackground(toStop []*TunnelRecord) {
+	for _, tunnel := range toStop {
+		// Update status immediately for responsive UI
+		tunnel.setStatus("stopping", "stopping...")
+	}
+	if len(toStop) > 0 {
+		a.Logf("Stopping %d tunnel(s)...", len(toStop))
+		// Stop tunnels in background goroutines
+		for _, tunnel := range toStop {
+			a.teardownBastion(tunnel)
+			a.stopPublicShare(tunnel)
+			go func(t *TunnelRecord) {
+				a.unregisterForward(t)
+				err := a.manager.StopTunnel(t.ID)
+				if err != nil {
+					t.setStatus("error", fmt.Sprintf("stop: %v", err))
+				} else {
+					t.setStatus("stopped", "stopped")
+				}
+				// Note: updateTableRows() is called by the tick handler,
+				// so the UI will update automatically on the next tick
+			}(tunnel)
+		}
+	}
 }
 
 func (a *App) View() string {
+	// Until the table is drawn, clicks have nothing to land on
+	a.layout = screenLayout{}
+
+	if a.hostKeyPrompt != nil {
+		return a.hostKeyView()
+	}
+
+	if a.authPrompt != nil {
+		return a.authView()
+	}
+
 	if a.showHelp {
 		return a.helpView()
 	}
@@ -1626,10 +2291,11 @@ func (a *App) View() string {
 				content += "  "
 			}
 
+			label := lipgloss.NewStyle().Foreground(a.tagColor(tag)).Render(a.tagLabel(tag))
 			if a.selectedTags[tag] {
-				content += "[x] " + tag + "\n"
+				content += "[x] " + label + "\n"
 			} else {
-				content += "[ ] " + tag + "\n"
+				content += "[ ] " + label + "\n"
 			}
 		}
 
@@ -1645,13 +2311,61 @@ func (a *App) View() string {
 			dialog)
 	}
 
+	if a.showStopConfirm {
+		return a.stopConfirmView()
+	}
+
+	if a.showMenu {
+		return a.menuView()
+	}
+
+	if a.showDiscover {
+		return a.discoverView()
+	}
+
+	if a.showSSHImport {
+		return a.sshImportView()
+	}
+
+	if a.showMaintenance {
+		return a.maintenanceView()
+	}
+
+	if a.showHostScan {
+		return a.hostScanView()
+	}
+
+	if a.showProfiles {
+		return a.profilesView()
+	}
+
+	if a.showGroups {
+		return a.groupsView()
+	}
+
+	if a.showTimeline {
+		return a.timelineView()
+	}
+
+	if a.showServices {
+		return a.servicesView()
+	}
+
+	if a.showShareConfirm {
+		return a.shareConfirmView()
+	}
+
+	if a.showDeleteConfirm && len(a.deleteIDs) > 0 {
+		return a.deleteMarkedView()
+	}
+
 	if a.showDeleteConfirm {
 		if a.deleteIndex >= 0 && a.deleteIndex < len(a.tunnels) {
 			tunnel := a.tunnels[a.deleteIndex]
 			content := dialogActiveStyle.Render("Confirm Delete") + "\n\n"
 			content += fmt.Sprintf("Are you sure you want to delete tunnel '%s'?\n", tunnel.Config.Name)
-			content += fmt.Sprintf("Local: %d, Remote: %s:%d\n",
-				tunnel.Config.LocalPort,
+			content += fmt.Sprintf("Local: %s, Remote: %s:%d\n",
+				a.localPortLabel(&tunnel),
 				tunnel.Config.RemoteHost,
 				tunnel.Config.RemotePort)
 			if tunnel.Config.Bastion.Host != "" {
@@ -1672,8 +2386,14 @@ func (a *App) View() string {
 	if a.showDialog {
 		// Create the dialog content
 		title := "Add New Tunnel"
-		if a.dialogMode == modeEdit {
+		switch a.dialogMode {
+		case modeEdit:
 			title = "Edit Tunnel"
+		case modeConnect:
+			title = "Quick Connect, started now and never saved"
+		}
+		if template, ok := a.selectedTemplate(); ok {
+			title += " from template " + template.Name
 		}
 		content := dialogActiveStyle.Render(title) + "\n\n"
 
@@ -1725,8 +2445,11 @@ func (a *App) View() string {
 					content += field.value
 				}
 				content += "\n"
+				if i == a.activeField {
+					content += a.renderSuggestions(maxLabelWidth + 4)
+				}
 				// Add extra spacing between sections and after Remote Port field
-				if i == 1 || i == 5 || i == 8 {
+				if i == 1 || i == 5 || i == 8 || (i == firstVariableField-1 && len(a.dialogFields) > firstVariableField) {
 					content += "\n" // Add extra spacing between sections
 				}
 			}
@@ -1736,7 +2459,23 @@ func (a *App) View() string {
 			content += "\nFormat: ssh -N -L [bindAddress:]localPort:remoteHost:remotePort [user@host[:port]]\n"
 		}
 
-		content += "\n↑/↓: Change field • Enter: Save • Esc/Ctrl+C: Cancel • /: Toggle SSH mode"
+		if warning := a.portClashWarning(); warning != "" {
+			content += "\n" + warning
+		}
+		if preview := a.effectivePreview(); preview != "" {
+			content += "\n" + preview
+		}
+		if a.dialogCheck != "" {
+			content += "\n" + a.dialogCheck + "\n"
+		}
+
+		content += "\n↑/↓: Change field • Enter: Save • Esc/Ctrl+C: Cancel • /: Toggle SSH mode • Ctrl+R: Test"
+		if a.dialogMode == modeNew && len(a.templates) > 0 {
+			content += " • Ctrl+T: Template"
+		}
+		if len(a.dialogSuggestions()) > 0 {
+			content += " • Ctrl+N: Recent"
+		}
 
 		// Center the dialog on screen
 		dialog := dialogStyle.Width(80).Render(content)
@@ -1749,12 +2488,21 @@ func (a *App) View() string {
 
 	// Add title with optional right-aligned tags
 	titleText := "tunnel9 - SSH Tunnel Manager"
+	if profile := config.ProfileName(a.loader.Path()); profile != config.DefaultProfile {
+		titleText += " • " + profile
+	}
+	if a.safeMode != "" {
+		titleText += " • SAFE MODE"
+	}
+	if a.statusFilter != "" {
+		titleText += " • only " + a.statusFilter
+	}
 	if a.currentTag != "" {
 		tagStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color("#2dd4bf")). // same as titleStyle foreground
-			Foreground(lipgloss.Color("0")).       // black text
+			Background(a.tagColor(a.currentTag)). // titleStyle foreground unless the tag has a color
+			Foreground(activeTheme.accentText).
 			Padding(0, 2)
-		tagText := tagStyle.Render(a.currentTag)
+		tagText := tagStyle.Render(a.tagFilterLabel(a.currentTag))
 
 		// Align tag's right edge with log panel's right edge (log panel width is a.width - 2)
 		tagWidth := lipgloss.Width(tagText)
@@ -1768,7 +2516,7 @@ func (a *App) View() string {
 		// Use left-align style to position title on left and tag on right
 		titleLeftStyle := lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#2dd4bf")).
+			Foreground(activeTheme.accent).
 			Align(lipgloss.Left).
 			MarginBottom(1)
 		s += titleLeftStyle.Width(a.width).Render(titleText) + "\n"
@@ -1779,7 +2527,12 @@ func (a *App) View() string {
 	s += "\n"
 
 	// Table (no extra newlines)
-	s += a.table.View()
+	tableView := a.dimMaintenance(a.colorizeLatency(a.table.View()))
+	if a.expandRow {
+		tableView = a.expandSelected(tableView)
+	}
+	a.recordLayout(s, tableView)
+	s += tableView
 
 	// Status bar (with proper spacing)
 	s += "\n" // Single newline before status
@@ -1797,7 +2550,7 @@ func (a *App) View() string {
 
 	// Bottom status without sort info
 	// Color just the first letter of each word with selected style color
-	selectedColorStyle := controlsStyle.Foreground(lipgloss.Color("#2dd4bf"))
+	selectedColorStyle := controlsStyle.Foreground(activeTheme.accent)
 	upDownText := selectedColorStyle.Render("↑/↓") + ":select"
 	enterText := selectedColorStyle.Render("enter") + ":toggle"
 	sortText := selectedColorStyle.Render("</>") + ":sort"
@@ -1822,47 +2575,122 @@ func (a *App) View() string {
 	wideText := selectedColorStyle.Render("w") + "ide"
 	quitText := selectedColorStyle.Render("q") + "uit"
 	scrollText := selectedColorStyle.Render("[/]") + ":scroll"
+	levelText := selectedColorStyle.Render("L") + "evel:" + a.logLevel.String()
 
-	controls := controlsStyle.Render(upDownText + " • " + enterText + " • " + sortText + " • " + openText)
-	if strings.Count(strings.Join(a.errorLog, ""), "ERROR") > 0 {
-		controls += controlsStyle.Foreground(lipgloss.Color("227")).Render(" • " + logText)
+	controls := errorBadge(errorCount) + a.bulkBadge()
+	if errorCount > 0 {
+		controls += controlsStyle.Render(selectedColorStyle.Render("!") + ":next error • ")
+	}
+	controls += controlsStyle.Render(upDownText + " • " + enterText + " • " + sortText + " • " + openText)
+	if a.loggedErrors() {
+		controls += controlsStyle.Foreground(activeTheme.warning).Render(" • " + logText)
 	} else {
 		controls += controlsStyle.Render(" • " + logText)
 	}
 	if a.showConsole {
 		controls += controlsStyle.Render(" • " + filterText)
+		controls += controlsStyle.Render(" • " + levelText)
 		controls += controlsStyle.Render(" • " + scrollText)
 		controls += controlsStyle.Render(" • " + autoText)
 	}
 	controls += controlsStyle.Render(" • " + helpText + " • " + tagsText + " • " + wideText + " • " + quitText)
 	s += controls
 
+	// Add detail pane if enabled
+	if a.showDetail {
+		s += "\n" + a.detailView()
+	}
+
 	// Add console if enabled
 	if a.showConsole {
 		// Update viewport content
 		a.updateViewport()
-		s += "\n" + a.viewport.View()
+		s += "\n"
+		a.layout.consoleTop = strings.Count(s, "\n")
+		s += a.viewport.View()
 	}
 
+	a.fitLayout(s)
 	return s
 }
 
-func (a *App) saveConfig() {
-	configs := make([]config.TunnelConfig, len(a.tunnels))
-	for i, t := range a.tunnels {
-		configs[i] = t.Config
+// startTunnel creates and starts the manager tunnel for a record. Tunnels
+// that need an ephemeral bastion start once it is provisioned, via the
+// returned command.
+func (a *App) startTunnel(selected *TunnelRecord) tea.Cmd {
+	tunnel, cmd := a.createTunnel(selected)
+	if tunnel != nil {
+		a.manager.StartTunnel(tunnel)
+	}
+	return cmd
+}
+
+// createTunnel creates the manager tunnel for a record, for the caller to
+// start. There is none when it can't start or waits for an ephemeral
+// bastion, started by the returned command.
+func (a *App) createTunnel(selected *TunnelRecord) (*ssh.Tunnel, tea.Cmd) {
+	if host, ok := a.inMaintenance(selected); ok {
+		a.Logf("Not starting %s: %s is in maintenance", selected.Config.Name, host)
+		return nil, nil
+	}
+	if a.needsBastion(selected) {
+		return nil, a.provisionBastion(selected)
+	}
+
+	tunnel := a.manager.CreateTunnel(selected.ID, a.runtimeConfig(selected))
+	if tunnel == nil {
+		selected.setStatus("error", "failed to start")
+		a.logError("Failed to start tunnel to %s", selected.Config.RemoteHost)
+		return nil, nil
 	}
 
-	if err := a.loader.Save(configs); err != nil {
+	selected.setStatus("connecting", "initializing")
+	a.registerForward(selected)
+	return tunnel, nil
+}
+
+// runtimeConfig is the config a tunnel actually runs with this session
+func (a *App) runtimeConfig(selected *TunnelRecord) config.TunnelConfig {
+	tc := selected.Config
+	// Tunnels added since loading haven't inherited the defaults yet
+	if a.loader != nil {
+		tc = a.loader.Config().Defaults.Apply(tc)
+	}
+	if selected.Ephemeral != nil {
+		tc.Bastion.Host = selected.Ephemeral.Host
+		tc.Bastion.User = selected.Ephemeral.User
+		tc.Bastion.Port = selected.Ephemeral.Port
+	}
+	return tc
+}
+
+// stopTunnel stops the manager tunnel for a record
+func (a *App) stopTunnel(selected *TunnelRecord) {
+	a.stopPublicShare(selected)
+	a.teardownBastion(selected)
+	a.unregisterForward(selected)
+	err := a.manager.StopTunnel(selected.ID)
+	if err != nil {
+		selected.setStatus("error", fmt.Sprintf("stop: %v", err))
+		a.logError("Failed to stop tunnel %s: %v", selected.Config.RemoteHost, err)
+	} else {
+		selected.setStatus("stopped", "stopped")
+	}
+}
+
+func (a *App) saveConfig() {
+	if err := a.loader.Save(a.persistentConfigs()); err != nil {
 		a.logError("Failed to save config: %v", err)
 	} else {
 		a.Logf("Configuration saved successfully")
+		a.recordPorts()
 	}
 }
 
 func (a *App) Logf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	a.errorLog = append(a.errorLog, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), msg))
+	line := fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+	a.persistLog(line)
+	a.errorLog = append(a.errorLog, logLine{level: events.LevelInfo, text: line})
 	if len(a.errorLog) > 100 {
 		a.errorLog = a.errorLog[len(a.errorLog)-100:]
 	}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"strings"
+
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// authMsg carries keyboard-interactive challenges a handshake is waiting on
+type authMsg struct {
+	prompt *ssh.AuthPrompt
+}
+
+// waitForAuth delivers the next set of challenges from the manager, re-armed
+// once the current one is answered
+func (a *App) waitForAuth() tea.Cmd {
+	prompts := a.authPrompts
+	return func() tea.Msg {
+		prompt, ok := <-prompts
+		if !ok {
+			return nil
+		}
+		return authMsg{prompt: prompt}
+	}
+}
+
+func (a *App) openAuthPrompt(prompt *ssh.AuthPrompt) {
+	a.authPrompt = prompt
+	a.authAnswers = make([]string, len(prompt.Questions))
+	a.authField = 0
+}
+
+func (a *App) handleAuthKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	prompt := a.authPrompt
+	switch msg.Type {
+	case tea.KeyEnter:
+		// Move through the questions, answering after the last one
+		if a.authField < len(a.authAnswers)-1 {
+			a.authField++
+			return a, nil
+		}
+		a.Logf("Answered authentication challenge for %s", prompt.Name)
+		prompt.Answer(a.authAnswers)
+	case tea.KeyEsc, tea.KeyCtrlC:
+		a.Logf("Cancelled authentication for %s", prompt.Name)
+		prompt.Cancel()
+	case tea.KeyTab, tea.KeyDown:
+		a.authField = (a.authField + 1) % len(a.authAnswers)
+		return a, nil
+	case tea.KeyShiftTab, tea.KeyUp:
+		a.authField = (a.authField - 1 + len(a.authAnswers)) % len(a.authAnswers)
+		return a, nil
+	case tea.KeyBackspace:
+		if answer := a.authAnswers[a.authField]; len(answer) > 0 {
+			runes := []rune(answer)
+			a.authAnswers[a.authField] = string(runes[:len(runes)-1])
+		}
+		return a, nil
+	case tea.KeyRunes, tea.KeySpace:
+		a.authAnswers[a.authField] += string(msg.Runes)
+		return a, nil
+	default:
+		return a, nil
+	}
+	a.authPrompt = nil
+	a.authAnswers = nil
+	return a, a.waitForAuth()
+}
+
+func (a *App) authView() string {
+	prompt := a.authPrompt
+	title := "Authentication for " + prompt.Name
+	content := dialogActiveStyle.Render(title) + "\n\n"
+	if prompt.Server != "" {
+		content += prompt.Server + "\n"
+	}
+	if instruction := strings.TrimSpace(prompt.Instruction); instruction != "" {
+		content += instruction + "\n"
+	}
+	content += prompt.User + " is being asked:\n\n"
+
+	for i, question := range prompt.Questions {
+		answer := a.authAnswers[i]
+		if i >= len(prompt.Echos) || !prompt.Echos[i] {
+			answer = strings.Repeat("*", len([]rune(answer)))
+		}
+		label := strings.TrimSpace(question)
+		if i == a.authField {
+			content += dialogSelectedStyle.Render("> "+label+" ") + answer +
+				lipgloss.NewStyle().Underline(true).Render(" ") + "\n"
+		} else {
+			content += "  " + label + " " + answer + "\n"
+		}
+	}
+	content += "\nEnter: Next/Submit • Tab: Move • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(70).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestAuthPromptDialog(t *testing.T) {
+	a := &App{width: 100, height: 30}
+	a.openAuthPrompt(&ssh.AuthPrompt{
+		Name:      "prod",
+		User:      "alice",
+		Questions: []string{"Password: ", "Duo option: "},
+		Echos:     []bool{false, true},
+	})
+
+	typeText := func(text string) {
+		a.handleAuthKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	}
+
+	typeText("hunter22")
+	a.handleAuthKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	a.handleAuthKey(tea.KeyMsg{Type: tea.KeyEnter})
+	typeText("push")
+
+	if a.authAnswers[0] != "hunter2" || a.authAnswers[1] != "push" {
+		t.Errorf("unexpected answers %q", a.authAnswers)
+	}
+
+	view := ansi.Strip(a.authView())
+	if strings.Contains(view, "hunter2") || !strings.Contains(view, "*******") {
+		t.Error("expected hidden answers to be masked")
+	}
+	if !strings.Contains(view, "push") {
+		t.Error("expected echoed answers to be shown")
+	}
+
+	// Enter on the last question submits and closes the dialog
+	a.handleAuthKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if a.authPrompt != nil {
+		t.Error("expected the dialog to close once answered")
+	}
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+)
+
+// Drops shorter than this are reconnect blips, e.g. a lazy tunnel dialing
+// on demand, rather than outages
+const outageGrace = 5 * time.Second
+
+// availabilityWindow accumulates up and down time over a period
+type availabilityWindow struct {
+	start   time.Time
+	up      time.Duration
+	down    time.Duration
+	outages int
+	longest time.Duration
+}
+
+func (w *availabilityWindow) add(state string, elapsed time.Duration) {
+	switch state {
+	case "up":
+		w.up += elapsed
+	case "down":
+		if elapsed < outageGrace {
+			w.up += elapsed
+			return
+		}
+		w.down += elapsed
+		w.outages++
+		if elapsed > w.longest {
+			w.longest = elapsed
+		}
+	}
+}
+
+// percent is the share of wanted time the tunnel was up
+func (w availabilityWindow) percent() float64 {
+	total := w.up + w.down
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(w.up) / float64(total)
+}
+
+func (w availabilityWindow) String() string {
+	s := fmt.Sprintf("%.1f%% (%d outage", w.percent(), w.outages)
+	if w.outages != 1 {
+		s += "s"
+	}
+	if w.outages > 0 {
+		s += ", longest " + formatDuration(w.longest)
+	}
+	return s + ")"
+}
+
+// availability tracks how much of the time a tunnel was wanted it was
+// actually up, for the session and for the current day. Time spent
+// connecting after a start doesn't count against it, only losing the link
+// once established does.
+type availability struct {
+	state   string // "off", "pending", "up" or "down"
+	since   time.Time
+	session availabilityWindow
+	today   availabilityWindow
+}
+
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// observe feeds a tunnel status change into the tracker
+func (av *availability) observe(status string, now time.Time) {
+	next := av.state
+	switch status {
+	case "active":
+		next = "up"
+	case "stopped", "stopping":
+		next = "off"
+	default: // connecting or error
+		if av.state == "up" || av.state == "down" {
+			next = "down"
+		} else {
+			next = "pending"
+		}
+	}
+	if next == av.state {
+		return
+	}
+
+	av.settle(now)
+	av.state = next
+	av.since = now
+}
+
+// settle books the time spent in the current state up to now
+func (av *availability) settle(now time.Time) {
+	if av.session.start.IsZero() {
+		av.session.start = now
+	}
+
+	// Start a fresh day, only counting the part of the state after midnight
+	today := midnight(now)
+	if av.today.start.Before(today) {
+		av.today = availabilityWindow{start: today}
+	}
+
+	if av.state == "" || av.since.IsZero() {
+		return
+	}
+	av.session.add(av.state, now.Sub(av.since))
+	if av.since.Before(today) {
+		av.today.add(av.state, now.Sub(today))
+	} else {
+		av.today.add(av.state, now.Sub(av.since))
+	}
+}
+
+// snapshot returns the session and today windows including the state the
+// tunnel is in right now
+func (av availability) snapshot(now time.Time) (availabilityWindow, availabilityWindow) {
+	av.settle(now)
+	return av.session, av.today
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvailabilityCountsOutages(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	var av availability
+
+	av.observe("connecting", start)                     // Initial connect doesn't count
+	av.observe("active", start.Add(10*time.Second))     // Up for 50 minutes
+	av.observe("error", start.Add(50*time.Minute))      // Down for 10 minutes
+	av.observe("connecting", start.Add(55*time.Minute)) // Still down
+	av.observe("active", start.Add(60*time.Minute))
+	av.observe("connecting", start.Add(90*time.Minute)) // A blip, not an outage
+	av.observe("active", start.Add(90*time.Minute+2*time.Second))
+
+	session, today := av.snapshot(start.Add(120 * time.Minute))
+	if session.outages != 1 {
+		t.Errorf("expected 1 outage, got %d", session.outages)
+	}
+	if session.longest != 10*time.Minute {
+		t.Errorf("expected longest outage of 10m, got %s", session.longest)
+	}
+	if session.down != 10*time.Minute {
+		t.Errorf("expected 10m down, got %s", session.down)
+	}
+	if today.outages != 1 {
+		t.Errorf("expected today to include the outage, got %d", today.outages)
+	}
+}
+
+func TestAvailabilityIgnoresStoppedTime(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	var av availability
+
+	av.observe("active", start)
+	av.observe("stopped", start.Add(time.Hour))
+
+	session, _ := av.snapshot(start.Add(5 * time.Hour))
+	if session.up != time.Hour || session.down != 0 {
+		t.Errorf("expected only the hour up to count, got up %s down %s", session.up, session.down)
+	}
+	if session.percent() != 100 {
+		t.Errorf("expected 100%%, got %.1f", session.percent())
+	}
+}
+
+func TestAvailabilityStartsFreshEachDay(t *testing.T) {
+	start := time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local)
+	var av availability
+
+	av.observe("active", start)
+	session, today := av.snapshot(start.Add(2 * time.Hour))
+	if session.up != 2*time.Hour {
+		t.Errorf("expected 2h up for the session, got %s", session.up)
+	}
+	if today.up != time.Hour {
+		t.Errorf("expected only the hour after midnight today, got %s", today.up)
+	}
+}
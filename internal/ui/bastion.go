@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"tunnel9/internal/bastion"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bastionReadyMsg reports the outcome of provisioning an ephemeral bastion
+type bastionReadyMsg struct {
+	id       string
+	instance *bastion.Instance
+	err      error
+}
+
+// needsBastion reports whether a tunnel must wait for an ephemeral bastion
+func (a *App) needsBastion(t *TunnelRecord) bool {
+	return bastion.IsEphemeral(t.Config) && t.Ephemeral == nil
+}
+
+// provisionBastion asks the provider plugin for a jump host in the background
+func (a *App) provisionBastion(t *TunnelRecord) tea.Cmd {
+	if a.bastionProvider == nil {
+		t.setStatus("error", "no bastion provider configured")
+		a.logError("Tunnel %s is tagged %s but no bastion_provider is configured", t.Config.Name, bastion.EphemeralTag)
+		return nil
+	}
+
+	t.setStatus("connecting", "provisioning bastion")
+	a.Logf("Provisioning ephemeral bastion for %s", t.Config.Name)
+
+	provider := a.bastionProvider
+	id, name := t.ID, t.Config.Name
+	return func() tea.Msg {
+		instance, err := provider.Up(name)
+		return bastionReadyMsg{id: id, instance: instance, err: err}
+	}
+}
+
+// handleBastionReady starts the tunnel through its freshly provisioned
+// bastion, or releases the bastion if the tunnel is no longer wanted
+func (a *App) handleBastionReady(msg bastionReadyMsg) tea.Cmd {
+	var selected *TunnelRecord
+	for i := range a.tunnels {
+		if a.tunnels[i].ID == msg.id {
+			selected = &a.tunnels[i]
+			break
+		}
+	}
+
+	if msg.err != nil {
+		a.logError("Failed to provision bastion: %v", msg.err)
+		if selected != nil {
+			selected.setStatus("error", "bastion provisioning failed")
+			a.updateTableRows()
+		}
+		return nil
+	}
+
+	// Stopped or deleted while we were waiting on the provider
+	if selected == nil || selected.Status != "connecting" {
+		a.Logf("Tunnel no longer starting, releasing bastion %s", msg.instance.ID)
+		a.releaseBastion(msg.instance)
+		return nil
+	}
+
+	a.Logf("Bastion %s ready at %s for %s", msg.instance.ID, msg.instance.Host, selected.Config.Name)
+	selected.Ephemeral = msg.instance
+	cmd := a.startTunnel(selected)
+	a.updateTableRows()
+	return cmd
+}
+
+// teardownBastion releases a tunnel's ephemeral bastion, if it has one
+func (a *App) teardownBastion(t *TunnelRecord) {
+	if t.Ephemeral == nil {
+		return
+	}
+	a.Logf("Tearing down ephemeral bastion %s", t.Ephemeral.ID)
+	a.releaseBastion(t.Ephemeral)
+	t.Ephemeral = nil
+}
+
+func (a *App) releaseBastion(instance *bastion.Instance) {
+	provider := a.bastionProvider
+	if provider == nil || instance == nil {
+		return
+	}
+	go provider.Down(instance)
+}
+
+// teardownAllBastions synchronously releases every ephemeral bastion so
+// nothing is left running (and billing) after we quit
+func (a *App) teardownAllBastions() {
+	if a.bastionProvider == nil {
+		return
+	}
+	for i := range a.tunnels {
+		if instance := a.tunnels[i].Ephemeral; instance != nil {
+			a.bastionProvider.Down(instance)
+			a.tunnels[i].Ephemeral = nil
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How long the dialog's connection test may dial the target
+const connectionCheckTimeout = 5 * time.Second
+
+// connectionCheckMsg carries the outcome of trying the dialog's settings
+type connectionCheckMsg struct {
+	target  string
+	err     error
+	elapsed time.Duration
+}
+
+var (
+	checkOKStyle     = lipgloss.NewStyle().Foreground(activeTheme.success)
+	checkFailedStyle = lipgloss.NewStyle().Foreground(activeTheme.error)
+)
+
+// dialogCheckConfig is the tunnel the dialog describes so far, with template
+// variables filled in but the fields left as typed
+func (a *App) dialogCheckConfig() (config.TunnelConfig, error) {
+	typed := append([]dialogField(nil), a.dialogFields...)
+	defer func() { a.dialogFields = typed }()
+
+	if err := a.expandTemplate(); err != nil {
+		return config.TunnelConfig{}, err
+	}
+	tc, ok := a.dialogPreviewConfig()
+	if !ok || tc.RemoteHost == "" || (tc.RemotePort == 0 && tc.Type != "wireguard") {
+		return config.TunnelConfig{}, fmt.Errorf("fill in the remote host and port first")
+	}
+	tc.Name = a.dialogFields[9].value
+	if tc.Name == "" {
+		tc.Name = tc.RemoteHost
+	}
+	// Settings only available in YAML, e.g. ssh_options, matter as well
+	if a.dialogMode == modeEdit {
+		tc = mergeDialogConfig(a.tunnels[a.editingIndex].Config, tc)
+	}
+	return tc, nil
+}
+
+// checkConnection tries the dialog's settings in the background
+func (a *App) checkConnection() tea.Cmd {
+	tc, err := a.dialogCheckConfig()
+	if err != nil {
+		a.dialogCheck = checkFailedStyle.Render("✗ " + err.Error())
+		return nil
+	}
+	target := fmt.Sprintf("%s:%d", tc.RemoteHost, tc.RemotePort)
+	a.dialogCheck = fmt.Sprintf("Testing %s...", target)
+
+	manager := a.manager
+	return func() tea.Msg {
+		start := time.Now()
+		err := manager.CheckConnection(tc, connectionCheckTimeout)
+		return connectionCheckMsg{target: target, err: err, elapsed: time.Since(start)}
+	}
+}
+
+// showConnectionCheck puts the outcome under the dialog's fields, unless
+// the dialog was closed in the meantime
+func (a *App) showConnectionCheck(msg connectionCheckMsg) {
+	if !a.showDialog {
+		return
+	}
+	if msg.err != nil {
+		// Long SSH errors would stretch the dialog
+		reason := strings.ReplaceAll(msg.err.Error(), "\n", " ")
+		a.dialogCheck = checkFailedStyle.Render("✗ " + reason)
+		return
+	}
+	a.dialogCheck = checkOKStyle.Render(fmt.Sprintf("✓ Reached %s in %v", msg.target, msg.elapsed.Round(time.Millisecond)))
+}
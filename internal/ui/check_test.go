@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestDialogCheckConfig(t *testing.T) {
+	a := &App{
+		loader:    config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml")),
+		templates: []config.TunnelTemplate{{Name: "prod", RemoteHost: "{service}.internal", RemotePort: "5432"}},
+	}
+	a.initDialog(modeNew)
+	if _, err := a.dialogCheckConfig(); err == nil {
+		t.Error("expected an empty dialog to be refused")
+	}
+
+	a.cycleTemplate()
+	a.dialogFields[firstVariableField].value = "billing"
+	tc, err := a.dialogCheckConfig()
+	if err != nil || tc.RemoteHost != "billing.internal" || tc.RemotePort != 5432 || tc.Name != "billing.internal" {
+		t.Fatalf("expected the template filled in, got %+v (%v)", tc, err)
+	}
+	// Testing leaves the dialog as typed
+	if a.dialogFields[4].value != "{service}.internal" {
+		t.Errorf("expected the remote host field untouched, got %q", a.dialogFields[4].value)
+	}
+}
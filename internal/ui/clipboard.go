@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/x/term"
+)
+
+// clipboardCommand returns the first clipboard tool available on this system
+func clipboardCommand() (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("clip"), nil
+	case "darwin":
+		return exec.Command("pbcopy"), nil
+	}
+	candidates := [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return exec.Command(c[0], c[1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found (wl-copy, xclip or xsel)")
+}
+
+// overSSH reports whether we run in an SSH session, where a clipboard tool
+// would copy to the remote machine rather than the one in front of the user
+func overSSH() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// osc52Sequence asks the terminal itself to set its clipboard, wrapped so
+// tmux and screen pass it on to the outer terminal
+func osc52Sequence(text string, termName string, inTmux bool) osc52.Sequence {
+	seq := osc52.New(text)
+	switch {
+	case inTmux:
+		seq = seq.Tmux()
+	case strings.HasPrefix(termName, "screen"):
+		seq = seq.Screen()
+	}
+	return seq
+}
+
+// terminalOutput is where escape sequences for the terminal go. Stderr
+// keeps them out of the way of Bubble Tea's renderer on stdout.
+func terminalOutput() (io.Writer, error) {
+	if term.IsTerminal(os.Stderr.Fd()) {
+		return os.Stderr, nil
+	}
+	if term.IsTerminal(os.Stdout.Fd()) {
+		return os.Stdout, nil
+	}
+	return nil, fmt.Errorf("not attached to a terminal")
+}
+
+func copyOSC52(text string) error {
+	out, err := terminalOutput()
+	if err != nil {
+		return err
+	}
+	_, err = osc52Sequence(text, os.Getenv("TERM"), os.Getenv("TMUX") != "").WriteTo(out)
+	return err
+}
+
+// copyToClipboard puts text on the user's clipboard with the system tool,
+// or through the terminal with OSC 52 when there is none or we are running
+// over SSH. It returns a note on how it was copied for the log.
+func copyToClipboard(text string) (string, error) {
+	if overSSH() {
+		if err := copyOSC52(text); err != nil {
+			return "", err
+		}
+		return " via the terminal (OSC 52)", nil
+	}
+
+	cmd, err := clipboardCommand()
+	if err == nil {
+		cmd.Stdin = strings.NewReader(text)
+		if err = cmd.Run(); err == nil {
+			return "", nil
+		}
+	}
+
+	// Most terminals support OSC 52, try it before giving up
+	if oscErr := copyOSC52(text); oscErr != nil {
+		return "", fmt.Errorf("%v, and %v for OSC 52", err, oscErr)
+	}
+	return " via the terminal (OSC 52)", nil
+}
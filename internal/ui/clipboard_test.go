@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestOSC52Sequence(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("localhost:5432"))
+
+	plain := osc52Sequence("localhost:5432", "xterm-256color", false).String()
+	if plain != "\x1b]52;c;"+encoded+"\x07" {
+		t.Errorf("unexpected sequence %q", plain)
+	}
+
+	// tmux and screen only pass it on when wrapped
+	tmux := osc52Sequence("localhost:5432", "screen-256color", true).String()
+	if !strings.HasPrefix(tmux, "\x1bPtmux;") || !strings.Contains(tmux, encoded) {
+		t.Errorf("expected tmux passthrough, got %q", tmux)
+	}
+	screen := osc52Sequence("localhost:5432", "screen", false).String()
+	if !strings.HasPrefix(screen, "\x1bP") || strings.HasPrefix(screen, "\x1bPtmux;") {
+		t.Errorf("expected screen passthrough, got %q", screen)
+	}
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// confirmPolicy says which actions on a tunnel ask first, following its tag
+func (a *App) confirmPolicy(t *TunnelRecord) string {
+	cfg := config.Config{Confirm: a.confirm, TagSettings: a.tagSettings}
+	return cfg.ConfirmPolicy(t.Config.Tag)
+}
+
+// deleteTunnel removes a stopped tunnel and saves the config
+func (a *App) deleteTunnel(index int) {
+	if index < 0 || index >= len(a.tunnels) {
+		return
+	}
+	selected := a.tunnels[index]
+	// Don't allow deletion of active tunnels
+	if selected.Status == "active" || selected.Status == "connecting" {
+		a.logError("Cannot delete active tunnel. Stop it first.")
+		return
+	}
+	a.tunnels = append(a.tunnels[:index], a.tunnels[index+1:]...)
+	a.Logf("Deleted tunnel: %s", selected.Config.Name)
+	a.saveConfig()
+	a.updateTableRows()
+}
+
+// confirmStop runs action straight away, unless a tunnel it would stop has
+// a policy that asks first, in which case the stop dialog opens instead
+func (a *App) confirmStop(targets []*TunnelRecord, verb string, action func() tea.Cmd) tea.Cmd {
+	guarded := make([]string, 0)
+	for _, t := range targets {
+		if config.ConfirmsStop(a.confirmPolicy(t)) {
+			guarded = append(guarded, t.Config.Name)
+		}
+	}
+	if len(guarded) == 0 {
+		return action()
+	}
+	a.stopConfirmVerb = verb
+	a.stopConfirmCount = len(targets)
+	a.stopConfirmNames = guarded
+	a.stopConfirmAction = action
+	a.showStopConfirm = true
+	return nil
+}
+
+func (a *App) handleStopConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.Type {
+	case tea.KeyEnter:
+		a.showStopConfirm = false
+		cmd = a.stopConfirmAction()
+		a.updateTableRows()
+	case tea.KeyEsc, tea.KeyCtrlC:
+		a.showStopConfirm = false
+	}
+	return a, cmd
+}
+
+func (a *App) stopConfirmView() string {
+	content := dialogActiveStyle.Render("Confirm "+a.stopConfirmVerb) + "\n\n"
+	content += fmt.Sprintf("%s %d tunnel(s)? These ask before stopping:\n\n", a.stopConfirmVerb, a.stopConfirmCount)
+	content += "  " + strings.Join(a.stopConfirmNames, ", ") + "\n"
+	content += "\nEnter: Confirm • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(60).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
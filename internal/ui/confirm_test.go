@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestConfirmStop(t *testing.T) {
+	a := &App{
+		tagSettings: map[string]config.TagSettings{
+			"prod": {Confirm: config.ConfirmDeleteStop},
+		},
+	}
+	dev := &TunnelRecord{}
+	dev.Config.Name, dev.Config.Tag = "dev-db", "dev"
+	prod := &TunnelRecord{}
+	prod.Config.Name, prod.Config.Tag = "prod-db", "prod"
+
+	ran := 0
+	action := func() tea.Cmd {
+		ran++
+		return nil
+	}
+
+	a.confirmStop([]*TunnelRecord{dev}, "Stop", action)
+	if ran != 1 || a.showStopConfirm {
+		t.Fatalf("expected dev to stop without asking")
+	}
+
+	a.confirmStop([]*TunnelRecord{dev, prod}, "Stop", action)
+	if ran != 1 || !a.showStopConfirm {
+		t.Fatalf("expected prod to ask before stopping")
+	}
+	if len(a.stopConfirmNames) != 1 || a.stopConfirmNames[0] != "prod-db" || a.stopConfirmCount != 2 {
+		t.Errorf("unexpected dialog contents %v (%d)", a.stopConfirmNames, a.stopConfirmCount)
+	}
+
+	a.handleStopConfirmKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if ran != 1 || a.showStopConfirm {
+		t.Errorf("expected cancel to close the dialog without stopping")
+	}
+
+	a.confirmStop([]*TunnelRecord{prod}, "Stop", action)
+	a.handleStopConfirmKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if ran != 2 || a.showStopConfirm {
+		t.Errorf("expected confirming to stop")
+	}
+}
+
+func TestDeleteWithoutConfirmation(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.confirm = config.ConfirmNone
+
+	a.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	if a.showDeleteConfirm {
+		t.Error("expected no dialog with confirm: none")
+	}
+	if len(a.tunnels) != 1 || a.tunnels[0].Config.Name != "tunnel-1" {
+		t.Errorf("expected tunnel-0 deleted, got %d tunnel(s)", len(a.tunnels))
+	}
+
+	a.confirm = config.ConfirmDelete
+	a.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	if !a.showDeleteConfirm || len(a.tunnels) != 1 {
+		t.Error("expected the delete dialog by default")
+	}
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+// Per-tunnel log budget, beyond which lines are dropped and summarised
+const (
+	logRateWindow = 10 * time.Second
+	logRateLimit  = 20
+)
+
+// Format: timestamp LEVEL [tunnel-name] message, or without the tunnel
+var (
+	tunnelLogLine = regexp.MustCompile(`^(\S+)\s+(DEBUG|INFO|WARN|ERROR)\s+(\[[^\]]+\])\s+(.*)$`)
+	simpleLogLine = regexp.MustCompile(`^(\S+)\s+(DEBUG|INFO|WARN|ERROR)\s+(.*)$`)
+)
+
+type tunnelLogState struct {
+	lastLevel       events.Level
+	lastMessage     string
+	repeats         int
+	windowStart     time.Time
+	windowCount     int
+	suppressed      int
+	suppressedLevel events.Level // The highest level among the suppressed
+}
+
+// logDampener keeps one failing tunnel from flooding the console for everyone
+// else, collapsing identical messages and capping each tunnel's log rate
+type logDampener struct {
+	tunnels map[string]*tunnelLogState
+}
+
+func newLogDampener() *logDampener {
+	return &logDampener{
+		tunnels: make(map[string]*tunnelLogState),
+	}
+}
+
+func summaryLine(now time.Time, level events.Level, tunnel string, message string) logLine {
+	return logLine{level: level, text: fmt.Sprintf("%s %s %s %s", now.Format("15:04:05"), level, tunnel, message)}
+}
+
+// summariseRepeats emits the pending "repeated" note for a tunnel
+func (state *tunnelLogState) summariseRepeats(now time.Time, tunnel string) []logLine {
+	if state.repeats == 0 {
+		return nil
+	}
+	line := summaryLine(now, state.lastLevel, tunnel,
+		fmt.Sprintf("last message repeated %d times", state.repeats))
+	state.repeats = 0
+	return []logLine{line}
+}
+
+// endWindow emits pending notes and starts a new rate window
+func (state *tunnelLogState) endWindow(now time.Time, tunnel string) []logLine {
+	lines := state.summariseRepeats(now, tunnel)
+	state.windowStart = now
+	state.windowCount = 0
+	if state.suppressed > 0 {
+		lines = append(lines, summaryLine(now, state.suppressedLevel, tunnel,
+			fmt.Sprintf("suppressed %d messages (rate limited)", state.suppressed)))
+		state.suppressed = 0
+		state.suppressedLevel = events.LevelDebug
+	}
+	return lines
+}
+
+// filter returns the lines that should actually reach the console for line
+func (d *logDampener) filter(line logLine, now time.Time) []logLine {
+	matches := tunnelLogLine.FindStringSubmatch(line.text)
+	if len(matches) != 5 {
+		return []logLine{line}
+	}
+	level, tunnel, message := line.level, matches[3], matches[4]
+
+	state, exists := d.tunnels[tunnel]
+	if !exists {
+		state = &tunnelLogState{windowStart: now}
+		d.tunnels[tunnel] = state
+	}
+
+	// Collapse identical consecutive messages
+	if message == state.lastMessage && level == state.lastLevel {
+		state.repeats++
+		return nil
+	}
+
+	lines := state.summariseRepeats(now, tunnel)
+	state.lastLevel = level
+	state.lastMessage = message
+
+	// Start a fresh window once the old one has passed
+	if now.Sub(state.windowStart) >= logRateWindow {
+		lines = append(lines, state.endWindow(now, tunnel)...)
+	}
+
+	if state.windowCount >= logRateLimit {
+		state.suppressed++
+		state.suppressedLevel = max(state.suppressedLevel, level)
+		return lines
+	}
+	state.windowCount++
+	return append(lines, line)
+}
+
+// pending reports whether any summaries are still to be flushed
+func (d *logDampener) pending() bool {
+	for _, state := range d.tunnels {
+		if state.repeats > 0 || state.suppressed > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// flush emits summaries for tunnels whose rate window has passed, so a
+// repeating error still shows up periodically rather than going silent
+func (d *logDampener) flush(now time.Time) []logLine {
+	lines := make([]logLine, 0)
+	for tunnel, state := range d.tunnels {
+		if now.Sub(state.windowStart) < logRateWindow {
+			continue
+		}
+		lines = append(lines, state.endWindow(now, tunnel)...)
+	}
+	return lines
+}
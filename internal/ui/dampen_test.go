@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+func TestLogDampenerCollapsesRepeats(t *testing.T) {
+	d := newLogDampener()
+	now := time.Now()
+	line := logLine{level: events.LevelError, text: "10:00:00 ERROR [prod-db] SSH connection failed: connection refused"}
+
+	if got := d.filter(line, now); len(got) != 1 {
+		t.Fatalf("expected first line through, got %v", got)
+	}
+	for i := 0; i < 5; i++ {
+		if got := d.filter(line, now); len(got) != 0 {
+			t.Fatalf("expected repeat %d to be collapsed, got %v", i, got)
+		}
+	}
+
+	got := d.filter(logLine{level: events.LevelDebug, text: "10:00:01 DEBUG [prod-db] connecting to SSH server (1/2): jump:22"}, now)
+	if len(got) != 2 || !strings.Contains(got[0].text, "last message repeated 5 times") {
+		t.Errorf("expected repeat summary before new line, got %v", got)
+	}
+	if !strings.Contains(got[0].text, "ERROR [prod-db]") || got[0].level != events.LevelError {
+		t.Errorf("summary should keep the repeated level and tunnel, got %+v", got[0])
+	}
+}
+
+func TestLogDampenerRateLimitsPerTunnel(t *testing.T) {
+	d := newLogDampener()
+	now := time.Now()
+
+	passed := 0
+	for i := 0; i < logRateLimit+10; i++ {
+		level := events.LevelDebug
+		if i == logRateLimit+5 {
+			level = events.LevelWarn
+		}
+		passed += len(d.filter(logLine{level: level, text: "10:00:00 DEBUG [noisy] attempt " + strings.Repeat("x", i)}, now))
+	}
+	if passed != logRateLimit {
+		t.Errorf("expected %d lines through, got %d", logRateLimit, passed)
+	}
+
+	// Other tunnels are unaffected
+	if got := d.filter(logLine{level: events.LevelDebug, text: "10:00:00 DEBUG [quiet] hello"}, now); len(got) != 1 {
+		t.Errorf("expected quiet tunnel line through, got %v", got)
+	}
+
+	summaries := d.flush(now.Add(logRateWindow))
+	if len(summaries) != 1 || !strings.Contains(summaries[0].text, "suppressed 10 messages") {
+		t.Fatalf("expected suppression summary, got %v", summaries)
+	}
+	// At the level of the most important line it swallowed
+	if summaries[0].level != events.LevelWarn || !strings.Contains(summaries[0].text, "WARN [noisy]") {
+		t.Errorf("expected the summary at warn level, got %+v", summaries[0])
+	}
+}
+
+func TestLogDampenerPassesOtherLines(t *testing.T) {
+	d := newLogDampener()
+	line := logLine{level: events.LevelInfo, text: "10:00:00 Configuration saved successfully"}
+	for i := 0; i < 3; i++ {
+		if got := d.filter(line, time.Now()); len(got) != 1 {
+			t.Errorf("expected untagged line through, got %v", got)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"tunnel9/internal/ssh"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Lines of content in the detail pane, not counting its border
+const detailPaneHeight = 10
+
+// Lines given to the connections section, the status history gets the rest
+const detailSectionHeight = 5
+
+var detailStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(activeTheme.accent).
+	Padding(0, 1)
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return d.String()
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// detailView shows which local client ports map to which remote connections
+// for the selected tunnel, to line up application logs with tunnel activity,
+// how reliable it has been, why logging in failed and when it last changed
+// state. Its second page shows the settings the tunnel runs with instead.
+func (a *App) detailView() string {
+	selected := a.selectedRecord()
+	lines := make([]string, 0, detailPaneHeight)
+
+	if selected == nil {
+		lines = append(lines, "No tunnel selected")
+	} else if a.detailSettings {
+		lines = append(lines, a.settingsLines(selected)...)
+	} else {
+		lines = append(lines, a.connectionLines(selected, detailSectionHeight)...)
+		for len(lines) < detailSectionHeight {
+			lines = append(lines, "")
+		}
+
+		session, today := selected.Uptime.snapshot(time.Now())
+		lines = append(lines, fmt.Sprintf("Availability: session %s • today %s • Handshake failures: %s",
+			session, today, selected.Values.Handshakes))
+
+		lines = append(lines, dialogActiveStyle.Render("Recent status changes"))
+		history := historyLines(selected.History, detailPaneHeight-len(lines))
+		if len(history) == 0 {
+			lines = append(lines, "No status changes yet")
+		}
+		lines = append(lines, history...)
+	}
+
+	for len(lines) < detailPaneHeight {
+		lines = append(lines, "")
+	}
+	return detailStyle.Width(a.width - 2).Render(strings.Join(lines, "\n"))
+}
+
+// connectionLines lists the open connections of a tunnel in at most max lines
+func (a *App) connectionLines(selected *TunnelRecord, max int) []string {
+	conns := a.manager.Connections(selected.ID)
+	lines := make([]string, 0, max)
+	lines = append(lines, dialogActiveStyle.Render(fmt.Sprintf("Connections for %s (%d open)", selected.Config.Name, len(conns))))
+
+	if len(conns) == 0 {
+		lines = append(lines, "No open connections")
+	}
+	for i, c := range conns {
+		if len(lines) == max-1 && i < len(conns)-1 {
+			lines = append(lines, fmt.Sprintf("... %d more", len(conns)-i))
+			break
+		}
+		target := c.Target
+		via := c.Via
+		if a.privacyMode {
+			target = "********"
+			via = "********"
+		}
+		lines = append(lines, fmt.Sprintf("%-22s → %-28s via %-24s %8s  ↑%s ↓%s",
+			c.Client, target, via, formatDuration(c.Duration()), formatSize(c.BytesOut), formatSize(c.BytesIn)))
+	}
+	return lines
+}
+
+// settingsLines describes how a tunnel is configured once ~/.ssh/config had
+// its say, how it is doing and what last went wrong, in at most
+// detailPaneHeight lines
+func (a *App) settingsLines(t *TunnelRecord) []string {
+	mask := func(s string) string {
+		if a.privacyMode && s != "" {
+			return "********"
+		}
+		return s
+	}
+	lines := []string{dialogActiveStyle.Render(fmt.Sprintf("Settings of %s", t.Config.Name))}
+
+	bind := t.Config.BindAddress
+	if t.Config.BindInterface != "" {
+		bind = t.Config.BindInterface
+	}
+	if bind == "" {
+		bind = "localhost"
+	}
+	protocol := "tcp"
+	if t.Config.IsUDP() {
+		protocol = t.Config.Type
+	}
+	options := []string{fmt.Sprintf("%s:%s/%s", mask(bind), a.localPortLabel(t), protocol)}
+	if t.Config.Tag != "" {
+		options = append(options, "tag "+a.tagLabel(t.Config.Tag))
+	}
+	if t.Config.Autostart {
+		options = append(options, "autostart")
+	}
+	for _, option := range []struct{ name, value string }{
+		{"keepalive", t.Config.Keepalive},
+		{"idle timeout", t.Config.IdleTimeout},
+		{"metrics", t.Config.Metrics},
+		{"dscp", t.Config.DSCP},
+	} {
+		if option.value != "" {
+			options = append(options, option.name+" "+option.value)
+		}
+	}
+	if len(t.Config.Standby) > 0 {
+		options = append(options, fmt.Sprintf("%d standby", len(t.Config.Standby)))
+	}
+	lines = append(lines, "Local:      "+strings.Join(options, " • "))
+
+	settings, err := ssh.ResolveSSHSettings(t.Config)
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("SSH settings unavailable: %v", err))
+	} else {
+		server := fmt.Sprintf("%s@%s", settings.User, mask(settings.SSHEndpoint().String()))
+		if settings.ProxyJump != "" {
+			server += " via " + mask(settings.ProxyJump)
+		}
+		if settings.ProxyCommand != "" {
+			server += " through ProxyCommand"
+		}
+		lines = append(lines,
+			"SSH server: "+server,
+			"Remote:     "+mask(settings.RemoteEndpoint().String()),
+		)
+		if len(settings.IdentityFiles) > 0 {
+			files := make([]string, len(settings.IdentityFiles))
+			home, _ := os.UserHomeDir()
+			for i, file := range settings.IdentityFiles {
+				if home != "" && strings.HasPrefix(file, home+string(os.PathSeparator)) {
+					file = "~" + file[len(home):]
+				}
+				files[i] = file
+			}
+			lines = append(lines, "Identity:   "+strings.Join(files, ", "))
+		}
+		notes := "no overrides"
+		if len(settings.Notes) > 0 {
+			notes = strings.Join(settings.Notes, "; ")
+		}
+		if a.privacyMode {
+			notes = fmt.Sprintf("%d override(s)", len(settings.Notes))
+		}
+		lines = append(lines, "ssh_config: "+notes)
+	}
+
+	metrics := t.Status
+	if t.Status == "active" {
+		v := t.Values
+		metrics += fmt.Sprintf(" • ↑%s ↓%s • %d open, %d total",
+			ssh.FormatRate(v.RateOut), ssh.FormatRate(v.RateIn), v.Connections, v.ConnectionsTotal)
+		if v.Latency > 0 {
+			metrics += " • latency " + ssh.FormatLatency(v.Latency)
+		}
+		if v.Health != "" {
+			metrics += " • target " + v.Health
+		}
+		if v.Note != "" {
+			metrics += " • " + v.Note
+		}
+	}
+	lines = append(lines, "Metrics:    "+metrics)
+
+	runIn, runOut := t.Transfer.run(t.Values)
+	sessionIn, sessionOut := t.Transfer.session(t.Values)
+	lines = append(lines, fmt.Sprintf("Transfer:   this run ↑%s ↓%s • since launch ↑%s ↓%s",
+		formatSize(runOut), formatSize(runIn), formatSize(sessionOut), formatSize(sessionIn)))
+
+	if change, ok := lastError(t); ok {
+		lines = append(lines, fmt.Sprintf("Last error: %s %s", change.Time.Format("Jan 2 15:04:05"), change.Message))
+	} else {
+		lines = append(lines, "Last error: none")
+	}
+
+	if len(lines) > detailPaneHeight {
+		lines = lines[:detailPaneHeight]
+	}
+	return lines
+}
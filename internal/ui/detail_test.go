@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSettingsLines(t *testing.T) {
+	a := newExpandApp(t, 1, 10)
+	tunnel := &a.tunnels[0]
+	tunnel.Config.Tag = "prod"
+	tunnel.Config.Keepalive = "aggressive"
+	tunnel.Status = "active"
+	tunnel.Values.Connections = 2
+	tunnel.Values.ConnectionsTotal = 7
+	tunnel.Values.BytesIn = 2048
+	tunnel.Values.Health = "degraded"
+	tunnel.History = []statusChange{{Time: time.Now(), From: "connecting", To: "error", Message: "connection refused"}}
+
+	text := ansi.Strip(strings.Join(a.settingsLines(tunnel), "\n"))
+	for _, want := range []string{
+		"Local:      localhost:8000/tcp • tag prod • keepalive aggressive",
+		"SSH server: ",
+		"db.internal:22",
+		"Remote:     localhost:5432",
+		"Identity:   ~/.ssh/id_ed25519, ",
+		"ssh_config: ",
+		"2 open, 7 total",
+		"this run ↑0 B ↓2.0 KB",
+		"target degraded",
+		"Last error: ",
+		"connection refused",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the settings, got\n%s", want, text)
+		}
+	}
+
+	a.privacyMode = true
+	text = ansi.Strip(strings.Join(a.settingsLines(tunnel), "\n"))
+	if strings.Contains(text, "db.internal") {
+		t.Errorf("expected hosts masked in privacy mode, got\n%s", text)
+	}
+}
@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"path/filepath"
+	"time"
+
+	"tunnel9/internal/diagnostics"
+)
+
+// SetVersion is the version shown in diagnostic archives
+func (a *App) SetVersion(version string) {
+	a.version = version
+}
+
+// saveDiagnostics writes a redacted archive of the config, console and
+// tunnel metrics to the current directory, for attaching to bug reports
+func (a *App) saveDiagnostics(now time.Time) {
+	bundle := diagnostics.Bundle{
+		Version:    a.version,
+		ConfigPath: a.loader.Path(),
+		Config:     a.loader.Config(),
+		Logs:       logTexts(a.errorLog),
+		Samples:    metricsSamples(a.tunnels),
+		Time:       now,
+	}
+	path := diagnostics.Filename(now)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if err := bundle.Save(path); err != nil {
+		a.logError("Failed to save diagnostics: %v", err)
+		return
+	}
+	a.Logf("Saved diagnostics to %s, hostnames, users and secrets are masked", path)
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/shellhistory"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+	"github.com/mattn/go-runewidth"
+)
+
+// How many discovered tunnels are listed at once
+const discoverPageSize = 12
+
+// openDiscover scans shell history for `ssh -L` commands. History is only
+// read when asked for, never in the background.
+func (a *App) openDiscover() {
+	existing := make([]config.TunnelConfig, 0, len(a.tunnels))
+	for _, t := range a.tunnels {
+		existing = append(existing, t.Config)
+	}
+	files := shellhistory.DefaultFiles()
+	a.discovered = shellhistory.Discover(files, existing)
+	a.discoverCursor = 0
+	a.showDiscover = true
+	a.Logf("Found %d tunnel(s) in %d shell history file(s)", len(a.discovered), len(files))
+}
+
+func (a *App) handleDiscoverKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.discoverCursor > 0 {
+			a.discoverCursor--
+		}
+	case "down", "j":
+		if a.discoverCursor < len(a.discovered)-1 {
+			a.discoverCursor++
+		}
+	case "enter":
+		if a.discoverCursor < len(a.discovered) {
+			a.importDiscovered(a.discoverCursor)
+		}
+	case "esc", "ctrl+c", "H":
+		a.showDiscover = false
+	}
+	return a, nil
+}
+
+// importDiscovered adds a discovered tunnel, stopped, and takes it off the
+// list
+func (a *App) importDiscovered(index int) {
+	tc := a.discovered[index].Config
+	names := make(map[string]bool, len(a.tunnels))
+	for _, t := range a.tunnels {
+		names[t.Config.Name] = true
+		if t.Config.LocalPort == tc.LocalPort {
+			a.logError("%s uses local port %d like %s, only one can run at a time", tc.Name, tc.LocalPort, t.Config.Name)
+		}
+	}
+	original := tc.Name
+	for n := 2; names[tc.Name]; n++ {
+		tc.Name = fmt.Sprintf("%s-%d", original, n)
+	}
+
+	a.tunnels = append(a.tunnels, TunnelRecord{
+		ID:      uuid.New().String(),
+		Status:  "stopped",
+		Config:  tc,
+		Metrics: "--",
+	})
+	a.Logf("Imported %s from shell history", tc.Name)
+	a.updateTableRows()
+	a.saveConfig()
+
+	a.discovered = append(a.discovered[:index], a.discovered[index+1:]...)
+	if a.discoverCursor >= len(a.discovered) && a.discoverCursor > 0 {
+		a.discoverCursor--
+	}
+}
+
+func (a *App) discoverView() string {
+	content := dialogActiveStyle.Render("Discovered tunnels") + "\n\n"
+	if len(a.discovered) == 0 {
+		content += "No `ssh -L` commands in shell history that aren't configured already\n"
+	}
+
+	// Keep the cursor on the visible page
+	start := 0
+	if a.discoverCursor >= discoverPageSize {
+		start = a.discoverCursor - discoverPageSize + 1
+	}
+	for i := start; i < len(a.discovered) && i < start+discoverPageSize; i++ {
+		d := a.discovered[i]
+		line := fmt.Sprintf("%-28s %5d → %s:%d", d.Config.Name, d.Config.LocalPort, d.Config.RemoteHost, d.Config.RemotePort)
+		if d.Config.Bastion.Host != "" {
+			line += " via " + d.Config.Bastion.Host
+		}
+		line += previewStyle.Render(fmt.Sprintf("  %d×", d.Count))
+		if i == a.discoverCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+			content += "  " + previewStyle.Render(runewidth.Truncate(d.Command, 90, "…")) + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += "\n↑/↓: Move • Enter: Import • Esc/Ctrl+C: Close"
+
+	dialog := dialogStyle.Width(100).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestDiscoverAndImport(t *testing.T) {
+	dir := t.TempDir()
+	history := filepath.Join(dir, "history")
+	os.WriteFile(history, []byte("ssh -L 5432:db:5432 jump\nssh -L 8080:web:80 jump\nssh -L 5432:db:5432 jump\n"), 0600)
+	t.Setenv("HOME", dir)
+	t.Setenv("HISTFILE", history)
+
+	a := &App{loader: config.NewConfigLoader(filepath.Join(dir, "config.yaml"))}
+	a.tunnels = []TunnelRecord{{ID: "1", Status: "stopped", Config: config.TunnelConfig{Name: "db-5432", LocalPort: 15432}}}
+
+	a.openDiscover()
+	if !a.showDiscover || len(a.discovered) != 2 || a.discovered[0].Config.RemoteHost != "db" {
+		t.Fatalf("expected db then web discovered, got %+v", a.discovered)
+	}
+
+	a.importDiscovered(0)
+	if len(a.tunnels) != 2 || a.tunnels[1].Config.Name != "db-5432-2" || a.tunnels[1].Config.Bastion.Host != "jump" {
+		t.Errorf("expected db imported under a free name, got %+v", a.tunnels)
+	}
+	if len(a.discovered) != 1 || a.discovered[0].Config.RemoteHost != "web" {
+		t.Errorf("expected the import taken off the list, got %+v", a.discovered)
+	}
+
+	// Imported forwards aren't offered again
+	a.openDiscover()
+	if len(a.discovered) != 1 {
+		t.Errorf("expected only web left, got %+v", a.discovered)
+	}
+}
@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// emailMsg reports how sending a batch of alerts went
+type emailMsg struct {
+	count int
+	err   error
+}
+
+// sendEmail mails the queued alerts in the background once they are due
+func (a *App) sendEmail(now time.Time) tea.Cmd {
+	if a.emailer == nil {
+		return nil
+	}
+	alerts := a.emailer.Take(now)
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	emailer := a.emailer
+	return func() tea.Msg {
+		return emailMsg{count: len(alerts), err: emailer.Send(alerts)}
+	}
+}
+
+func (a *App) handleEmail(msg emailMsg) {
+	if msg.err != nil {
+		a.logError("Failed to email %d alert(s) via %s: %v", msg.count, a.emailer.Server(), msg.err)
+		return
+	}
+	a.Logf("Emailed %d alert(s) to %s", msg.count, strings.Join(a.loader.Config().Email.To, ", "))
+}
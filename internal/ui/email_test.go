@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/notify"
+)
+
+func TestNoteTransitionQueuesFailuresAndRecoveries(t *testing.T) {
+	a := &App{emailer: notify.NewEmailer(config.EmailConfig{SMTPHost: "smtp.example.com", To: []string{"ops@example.com"}, Cooldown: "0s"}, "jump-vm")}
+	record := TunnelRecord{ID: "1", Status: "stopped"}
+	record.Config.Name = "db"
+
+	change := func(state string, message string) {
+		from := record.Status
+		record.setStatus(state, message)
+		a.noteTransition(&record, from)
+	}
+	change("connecting", "connecting to server")
+	change("active", "ready")
+	change("error", "failed, see logs")
+	change("error", "SSH connection failed: timeout")
+	change("active", "ready")
+
+	alerts := a.emailer.Take(time.Now().Add(time.Hour))
+	if len(alerts) != 2 {
+		t.Fatalf("expected a failure and a recovery, got %+v", alerts)
+	}
+	if alerts[0].Message != "SSH connection failed: timeout" || alerts[0].Recovered {
+		t.Errorf("unexpected failure alert %+v", alerts[0])
+	}
+	if !alerts[1].Recovered {
+		t.Errorf("expected a recovery, got %+v", alerts[1])
+	}
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errorBadgeStyle makes errored tunnels hard to miss in the footer
+var errorBadgeStyle = lipgloss.NewStyle().
+	Background(activeTheme.error).
+	Foreground(activeTheme.badgeText).
+	Bold(true).
+	Padding(0, 1)
+
+// errorBadge counts the tunnels in error state, empty when there are none
+func errorBadge(count int) string {
+	if count == 0 {
+		return ""
+	}
+	label := "errors"
+	if count == 1 {
+		label = "error"
+	}
+	return errorBadgeStyle.Render(fmt.Sprintf("%d %s", count, label)) + " "
+}
+
+// nextErrorRow returns the next row after the cursor whose tunnel is in error
+// state, wrapping around, or -1 if no shown tunnel is
+func (a *App) nextErrorRow() int {
+	entries := a.tableEntries()
+	cursor := a.table.Cursor()
+	for step := 1; step <= len(entries); step++ {
+		row := (cursor + step) % len(entries)
+		if entries[row].tunnel != nil && entries[row].tunnel.Status == "error" {
+			return row
+		}
+	}
+	return -1
+}
+
+// jumpToNextError moves the cursor to the next errored tunnel
+func (a *App) jumpToNextError() {
+	row := a.nextErrorRow()
+	if row < 0 {
+		a.Logf("No tunnels in error state")
+		return
+	}
+	a.table.SetCursor(row)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJumpToNextError(t *testing.T) {
+	a := newExpandApp(t, 5, 10)
+	a.jumpToNextError()
+	if a.table.Cursor() != 0 {
+		t.Errorf("expected the cursor to stay put without errors, got %d", a.table.Cursor())
+	}
+
+	a.tunnels[1].Status = "error"
+	a.tunnels[3].Status = "error"
+	a.updateTableRows()
+
+	for _, want := range []int{1, 3, 1} {
+		a.jumpToNextError()
+		if got := a.table.Cursor(); got != want {
+			t.Fatalf("expected cursor on row %d, got %d", want, got)
+		}
+	}
+}
+
+func TestErrorBadge(t *testing.T) {
+	if errorBadge(0) != "" {
+		t.Error("expected no badge without errors")
+	}
+	if !strings.Contains(errorBadge(1), "1 error") || !strings.Contains(errorBadge(3), "3 errors") {
+		t.Errorf("unexpected badges %q and %q", errorBadge(1), errorBadge(3))
+	}
+}
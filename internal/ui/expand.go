@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/ssh"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+var expandStyle = lipgloss.NewStyle().
+	Foreground(activeTheme.faint)
+
+// Header and its bottom border come before the first row in the table view
+const tableHeaderLines = 2
+
+// lastError finds the most recent error a tunnel reported
+func lastError(t *TunnelRecord) (statusChange, bool) {
+	for i := len(t.History) - 1; i >= 0; i-- {
+		if t.History[i].To == "error" {
+			return t.History[i], true
+		}
+	}
+	return statusChange{}, false
+}
+
+// expandedLines describes a tunnel in full, for showing beneath its row
+func (a *App) expandedLines(t *TunnelRecord) []string {
+	mask := func(s string) string {
+		if a.privacyMode && s != "" {
+			return "********"
+		}
+		return s
+	}
+
+	bind := t.Config.BindAddress
+	if t.Config.BindInterface != "" {
+		bind = t.Config.BindInterface
+	}
+	if bind == "" {
+		bind = "localhost"
+	}
+	protocol := "tcp"
+	if t.Config.IsUDP() {
+		protocol = t.Config.Type
+	}
+
+	lines := []string{
+		fmt.Sprintf("Local:      %s:%s/%s", mask(bind), a.localPortLabel(t), protocol),
+	}
+	if settings, err := ssh.ResolveSSHSettings(t.Config); err == nil {
+		lines = append(lines,
+			fmt.Sprintf("SSH Server: %s@%s", settings.User, mask(settings.SSHEndpoint().String())),
+			fmt.Sprintf("Remote:     %s", mask(settings.RemoteEndpoint().String())),
+		)
+	} else {
+		lines = append(lines, fmt.Sprintf("Endpoints unavailable: %v", err))
+	}
+
+	if change, ok := lastError(t); ok {
+		lines = append(lines, fmt.Sprintf("Last error: %s %s", change.Time.Format("Jan 2 15:04:05"), change.Message))
+	} else {
+		lines = append(lines, "Last error: none")
+	}
+
+	for i, line := range lines {
+		lines[i] = expandStyle.Render("    " + line)
+	}
+	return lines
+}
+
+// collapseSpace ignores padding, which depends on the table styles
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// plainRow is the text of the selected row as the table lays it out, for
+// finding it among the rendered lines
+func (a *App) plainRow() string {
+	return a.rowText(a.table.SelectedRow())
+}
+
+// rowText is the text of a row as the table lays it out, padding ignored
+func (a *App) rowText(row table.Row) string {
+	var b strings.Builder
+	for i, col := range a.table.Columns() {
+		if col.Width <= 0 || i >= len(row) {
+			continue
+		}
+		cell := runewidth.Truncate(row[i], col.Width, "…")
+		b.WriteString(cell + " ")
+	}
+	return collapseSpace(b.String())
+}
+
+// expandSelected opens the selected row of the rendered table into a block
+// with its full details. Rows further from the selection make way, so the
+// table keeps its height.
+func (a *App) expandSelected(view string) string {
+	selected := a.selectedRecord()
+	if selected == nil {
+		return view
+	}
+
+	lines := strings.Split(view, "\n")
+	want := a.plainRow()
+	at := -1
+	for i := tableHeaderLines; i < len(lines); i++ {
+		if collapseSpace(ansi.Strip(lines[i])) == want {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return view
+	}
+
+	block := a.expandedLines(selected)
+	height := len(lines)
+	expanded := make([]string, 0, height+len(block))
+	expanded = append(expanded, lines[:at+1]...)
+	expanded = append(expanded, block...)
+	expanded = append(expanded, lines[at+1:]...)
+
+	// Drop rows below the block first, then rows above the selection
+	if len(expanded) > height {
+		below := len(expanded) - (at + 1 + len(block))
+		drop := len(expanded) - height
+		if drop <= below {
+			expanded = expanded[:height]
+		} else {
+			expanded = expanded[:len(expanded)-below]
+			drop -= below
+			if rows := at - tableHeaderLines; drop > rows {
+				drop = rows
+			}
+			expanded = append(expanded[:tableHeaderLines], expanded[tableHeaderLines+drop:]...)
+		}
+	}
+	return strings.Join(expanded, "\n")
+}
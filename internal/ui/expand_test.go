@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func newExpandApp(t *testing.T, count int, height int) *App {
+	t.Setenv("HOME", t.TempDir())
+
+	a := &App{
+		baseColumns: []string{"STATUS", "NAME", "LOCAL", "BIND", "HOST", "REMOTE", "BASTION", "TAG", "RATE↑", "RATE↓", "TOTAL↑", "TOTAL↓", "LATENCY", "HEALTH", "MESSAGE"},
+		table: table.New(
+			table.WithColumns([]table.Column{
+				{Title: "STATUS", Width: 8},
+				{Title: "NAME", Width: 20},
+				{Title: "TUNNEL", Width: 30},
+				{Title: "TAG", Width: 12},
+				{Title: "MESSAGE", Width: 40},
+			}),
+			table.WithHeight(height),
+			table.WithFocused(true),
+		),
+	}
+	for i := 0; i < count; i++ {
+		record := TunnelRecord{ID: fmt.Sprint(i), Status: "stopped"}
+		record.Config.Name = fmt.Sprintf("tunnel-%d", i)
+		record.Config.LocalPort = 8000 + i
+		record.Config.RemoteHost = "db.internal"
+		record.Config.RemotePort = 5432
+		a.tunnels = append(a.tunnels, record)
+	}
+	a.updateTableRows()
+	return a
+}
+
+func TestExpandSelected(t *testing.T) {
+	a := newExpandApp(t, 3, 10)
+	a.table.SetCursor(1)
+	a.tunnels[1].History = []statusChange{
+		{Time: time.Now(), From: "connecting", To: "error", Message: "connection refused"},
+		{Time: time.Now(), From: "error", To: "stopped"},
+	}
+
+	view := a.table.View()
+	expanded := strings.Split(ansi.Strip(a.expandSelected(view)), "\n")
+	if len(expanded) != len(strings.Split(view, "\n")) {
+		t.Errorf("expected the table to keep its height, got %d lines", len(expanded))
+	}
+
+	at := -1
+	for i, line := range expanded {
+		if strings.Contains(line, "tunnel-1") {
+			at = i
+		}
+	}
+	if at < 0 || at+4 >= len(expanded) {
+		t.Fatalf("expected the selected row followed by its details, got %q", expanded)
+	}
+	if !strings.Contains(expanded[at+1], "Local:      localhost:8001/tcp") {
+		t.Errorf("expected local endpoint beneath the row, got %q", expanded[at+1])
+	}
+	if !strings.Contains(expanded[at+2], "db.internal:22") {
+		t.Errorf("expected SSH server, got %q", expanded[at+2])
+	}
+	if !strings.Contains(expanded[at+3], "localhost:5432") {
+		t.Errorf("expected remote endpoint as seen from the server, got %q", expanded[at+3])
+	}
+	if !strings.Contains(expanded[at+4], "connection refused") {
+		t.Errorf("expected last error, got %q", expanded[at+4])
+	}
+}
+
+func TestExpandSelectedKeepsSelectionVisible(t *testing.T) {
+	a := newExpandApp(t, 8, 8)
+	a.table.SetCursor(5)
+
+	view := a.table.View()
+	expanded := strings.Split(ansi.Strip(a.expandSelected(view)), "\n")
+	if len(expanded) != len(strings.Split(view, "\n")) {
+		t.Errorf("expected the table to keep its height, got %d lines", len(expanded))
+	}
+	if !strings.Contains(strings.Join(expanded, "\n"), "tunnel-5") {
+		t.Errorf("expected the selected row to stay visible, got %q", expanded)
+	}
+	if !strings.Contains(expanded[len(expanded)-1], "Last error: none") {
+		t.Errorf("expected the details to end the table, got %q", expanded[len(expanded)-1])
+	}
+}
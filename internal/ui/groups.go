@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// groupRun is a startup group on its way up, moved along by the tick
+type groupRun struct {
+	group    config.StartupGroup
+	step     int       // Next step to start
+	waiting  []string  // IDs of the tunnels the started step waits on
+	deadline time.Time // When the waiting tunnels are given up on
+	startAt  time.Time // When the next step starts, once nothing is waited on
+}
+
+// runGroup starts a startup group's first step, the rest follow as the
+// tunnels before them come up
+func (a *App) runGroup(group config.StartupGroup, now time.Time) tea.Cmd {
+	for _, run := range a.groupRuns {
+		if run.group.Name == group.Name {
+			a.Logf("Startup group %s is already starting, at step %d of %d", group.Name, run.step, len(group.Steps))
+			return nil
+		}
+	}
+	a.Logf("Starting group %s", group.Name)
+	run := &groupRun{group: group}
+	if len(group.Steps) > 0 {
+		run.startAt = now.Add(group.Steps[0].DelayDuration())
+	}
+	a.groupRuns = append(a.groupRuns, run)
+	return a.advanceGroups(now)
+}
+
+// autostartGroups runs the startup groups set to start with tunnel9
+func (a *App) autostartGroups() tea.Cmd {
+	now := time.Now()
+	var cmds []tea.Cmd
+	for _, group := range a.startupGroups {
+		if group.Autostart {
+			cmds = append(cmds, a.runGroup(group, now))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// advanceGroups moves every running group along, dropping those that are
+// up or gave up
+func (a *App) advanceGroups(now time.Time) tea.Cmd {
+	var cmds []tea.Cmd
+	running := a.groupRuns[:0]
+	for _, run := range a.groupRuns {
+		cmd, done := a.advanceGroup(run, now)
+		cmds = append(cmds, cmd)
+		if !done {
+			running = append(running, run)
+		}
+	}
+	a.groupRuns = running
+	return tea.Batch(cmds...)
+}
+
+// advanceGroup starts the group's next step once the tunnels of the one
+// before are active and its delay has passed. It reports whether the group
+// is done, all up or stopped short by a tunnel that failed or took too long.
+func (a *App) advanceGroup(run *groupRun, now time.Time) (tea.Cmd, bool) {
+	if len(run.waiting) > 0 {
+		up := true
+		for _, id := range run.waiting {
+			t := a.findTunnel(id)
+			switch {
+			case t == nil:
+				// Deleted meanwhile, nothing to wait for
+			case t.Status == "error" || t.Status == "stopped":
+				a.logError("Startup group %s stopped at step %d: %s is %s", run.group.Name, run.step, t.Config.Name, t.Status)
+				return nil, true
+			case t.Status != "active":
+				up = false
+			}
+		}
+		if !up {
+			if now.After(run.deadline) {
+				a.logError("Startup group %s stopped at step %d: tunnels took longer than %s to come up", run.group.Name, run.step, run.group.Steps[run.step-1].TimeoutDuration())
+				return nil, true
+			}
+			return nil, false
+		}
+		run.waiting = nil
+		if run.step < len(run.group.Steps) {
+			run.startAt = now.Add(run.group.Steps[run.step].DelayDuration())
+		}
+	}
+
+	if run.step >= len(run.group.Steps) {
+		a.Logf("Startup group %s is up", run.group.Name)
+		return nil, true
+	}
+	if now.Before(run.startAt) {
+		return nil, false
+	}
+
+	step := run.group.Steps[run.step]
+	run.step++
+	run.deadline = now.Add(step.TimeoutDuration())
+	var cmds []tea.Cmd
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		if !step.Matches(t.Config) {
+			continue
+		}
+		run.waiting = append(run.waiting, t.ID)
+		if t.Status != "stopped" && t.Status != "error" {
+			continue
+		}
+		// Never silently create a duplicate share in bulk
+		if dupes := a.shareConflicts(t); len(dupes) > 0 {
+			a.logError("Skipping %s: already shared by %s", t.Config.Name, describeForwards(dupes))
+			continue
+		}
+		a.Logf("Starting %s (group %s, step %d)", t.Config.Name, run.group.Name, run.step)
+		cmds = append(cmds, a.startTunnel(t))
+	}
+	a.updateTableRows()
+	return tea.Batch(cmds...), false
+}
+
+// findTunnel returns the tunnel with the given ID, or nil
+func (a *App) findTunnel(id string) *TunnelRecord {
+	for i := range a.tunnels {
+		if a.tunnels[i].ID == id {
+			return &a.tunnels[i]
+		}
+	}
+	return nil
+}
+
+func (a *App) handleGroupsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.groupCursor > 0 {
+			a.groupCursor--
+		}
+	case "down", "j":
+		if a.groupCursor < len(a.startupGroups)-1 {
+			a.groupCursor++
+		}
+	case "enter":
+		a.showGroups = false
+		if a.groupCursor < len(a.startupGroups) {
+			return a, a.runGroup(a.startupGroups[a.groupCursor], time.Now())
+		}
+	case "esc", "ctrl+c", "S":
+		a.showGroups = false
+	}
+	return a, nil
+}
+
+// describeStep sums up what a step starts, for the group list
+func describeStep(step config.GroupStep) string {
+	parts := append([]string(nil), step.Tunnels...)
+	for _, tag := range step.Tags {
+		parts = append(parts, "tag "+tag)
+	}
+	description := strings.Join(parts, ", ")
+	if step.Delay != "" {
+		description = fmt.Sprintf("after %s: %s", step.Delay, description)
+	}
+	return description
+}
+
+func (a *App) groupsView() string {
+	content := dialogActiveStyle.Render("Startup groups") + "\n\n"
+	if len(a.startupGroups) == 0 {
+		content += "No startup_groups in the config\n"
+	}
+	for i, group := range a.startupGroups {
+		line := group.Name
+		for _, run := range a.groupRuns {
+			if run.group.Name == group.Name {
+				line += previewStyle.Render(fmt.Sprintf("  (starting, step %d of %d)", run.step, len(group.Steps)))
+			}
+		}
+		if i == a.groupCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+			for j, step := range group.Steps {
+				content += "    " + previewStyle.Render(fmt.Sprintf("%d. %s", j+1, describeStep(step))) + "\n"
+			}
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += "\n↑/↓: Move • Enter: Start group • Esc/Ctrl+C: Close"
+
+	dialog := dialogStyle.Width(80).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
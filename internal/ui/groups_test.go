@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+)
+
+func TestStartupGroupWaitsForEachStep(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	jump, db := &a.tunnels[0], &a.tunnels[1]
+	jump.Config.Tag = "vpn"
+	jump.Status = "active"
+	db.Config.Name = "db-primary"
+	db.Status = "connecting"
+	group := config.StartupGroup{Name: "standup", Steps: []config.GroupStep{
+		{Tags: []string{"vpn"}},
+		{Tunnels: []string{"db-*"}, Delay: "5s"},
+	}}
+
+	start := time.Now()
+	a.runGroup(group, start)
+	if len(a.groupRuns) != 1 || a.groupRuns[0].step != 1 {
+		t.Fatalf("expected the first step started, got %+v", a.groupRuns)
+	}
+	a.advanceGroups(start.Add(time.Second))
+	if a.groupRuns[0].step != 1 {
+		t.Error("expected the second step to wait for its delay")
+	}
+	a.advanceGroups(start.Add(7 * time.Second))
+	if run := a.groupRuns[0]; run.step != 2 || len(run.waiting) != 1 || run.waiting[0] != db.ID {
+		t.Fatalf("expected the second step waiting on db-primary, got %+v", run)
+	}
+
+	db.Status = "active"
+	a.advanceGroups(start.Add(8 * time.Second))
+	if len(a.groupRuns) != 0 {
+		t.Errorf("expected the group to be up, got %+v", a.groupRuns[0])
+	}
+}
+
+func TestStartupGroupStopsOnFailure(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.tunnels[0].Status = "connecting"
+	group := config.StartupGroup{Name: "standup", Steps: []config.GroupStep{
+		{Tunnels: []string{"tunnel-0"}, Timeout: "10s"},
+		{Tunnels: []string{"tunnel-1"}},
+	}}
+
+	start := time.Now()
+	a.runGroup(group, start)
+	a.advanceGroups(start.Add(11 * time.Second))
+	last := a.errorLog[len(a.errorLog)-1].text
+	if len(a.groupRuns) != 0 || !strings.Contains(last, "took longer than 10s") {
+		t.Errorf("expected the group to give up after its timeout, got %+v and %q", a.groupRuns, last)
+	}
+
+	a.runGroup(group, start)
+	a.tunnels[0].Status = "error"
+	a.advanceGroups(start.Add(time.Second))
+	last = a.errorLog[len(a.errorLog)-1].text
+	if len(a.groupRuns) != 0 || !strings.Contains(last, "tunnel-0 is error") || a.tunnels[1].Status != "stopped" {
+		t.Errorf("expected the group to stop at the failed tunnel, got %q", last)
+	}
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"time"
+
+	"tunnel9/internal/heartbeat"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// heartbeatMsg reports how the last heartbeat went
+type heartbeatMsg struct {
+	err error
+}
+
+// sendHeartbeat posts the tunnel summary in the background when one is due
+func (a *App) sendHeartbeat(now time.Time) tea.Cmd {
+	if a.heartbeat == nil || !a.heartbeat.Due(now) {
+		return nil
+	}
+
+	tunnels := make([]heartbeat.Tunnel, len(a.tunnels))
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		tunnels[i] = heartbeat.Tunnel{
+			Name:      t.Config.Name,
+			Status:    t.Status,
+			LocalPort: a.localPort(t),
+		}
+	}
+	payload := heartbeat.NewPayload(a.machine, tunnels)
+
+	sender := a.heartbeat
+	return func() tea.Msg {
+		return heartbeatMsg{err: sender.Send(payload)}
+	}
+}
+
+// handleHeartbeat logs when heartbeats start failing and when they recover,
+// rather than once a minute while the monitor is down
+func (a *App) handleHeartbeat(msg heartbeatMsg) {
+	if msg.err != nil {
+		if !a.heartbeatFailing {
+			a.logError("Heartbeat to %s failed: %v", a.heartbeat.URL(), msg.err)
+		}
+		a.heartbeatFailing = true
+		return
+	}
+	if a.heartbeatFailing {
+		a.Logf("Heartbeat to %s delivered again", a.heartbeat.URL())
+	}
+	a.heartbeatFailing = false
+}
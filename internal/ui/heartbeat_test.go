@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/heartbeat"
+)
+
+func TestHeartbeatLogsChangesOnly(t *testing.T) {
+	a := &App{heartbeat: heartbeat.New(config.HeartbeatConfig{URL: "http://monitor.invalid/ping"})}
+
+	if a.sendHeartbeat(time.Now()) == nil {
+		t.Fatal("expected the first heartbeat to be sent")
+	}
+	if a.sendHeartbeat(time.Now()) != nil {
+		t.Error("expected no second heartbeat within the interval")
+	}
+
+	a.handleHeartbeat(heartbeatMsg{err: errors.New("connection refused")})
+	a.handleHeartbeat(heartbeatMsg{err: errors.New("connection refused")})
+	if len(a.errorLog) != 1 || !strings.Contains(a.errorLog[0].text, "Heartbeat to http://monitor.invalid/ping failed") {
+		t.Errorf("expected a single failure line, got %v", a.errorLog)
+	}
+
+	a.handleHeartbeat(heartbeatMsg{})
+	a.handleHeartbeat(heartbeatMsg{})
+	if len(a.errorLog) != 2 || !strings.Contains(a.errorLog[1].text, "delivered again") {
+		t.Errorf("expected a single recovery line, got %v", a.errorLog)
+	}
+}
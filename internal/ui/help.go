@@ -5,7 +5,7 @@ import "github.com/charmbracelet/lipgloss"
 var helpStyle = lipgloss.NewStyle().
 	Align(lipgloss.Left).
 	Border(lipgloss.RoundedBorder()).
-	BorderForeground(lipgloss.Color("#2dd4bf")).
+	BorderForeground(activeTheme.accent).
 	Padding(1, 2)
 
 func (a *App) helpView() string {
@@ -13,9 +13,16 @@ func (a *App) helpView() string {
 
 Navigation
   ↑/↓: Select tunnel
+  mouse: Click to select, double-click to toggle, click header to sort
+  ←/→: Scroll wide view columns
   enter: Toggle selected tunnel
+  m: Quick actions for selected tunnel
   h: Toggle help
   l: Toggle error log
+  i: Cycle detail pane (activity, then settings and metrics)
+  x: Expand selected row (endpoints, last error)
+  0: Reset transfer totals of selected or marked tunnels
+  !: Jump to next tunnel in error state
   q/esc: Quit
 
 Console
@@ -23,9 +30,13 @@ Console
   home/end: Jump to top/bottom
   l: Toggle console view
   f: Toggle filtering by selected tunnel
+  SHIFT+l: Cycle least level shown (DEBUG, INFO, WARN, ERROR)
+  SHIFT+e: Export the lines shown to a file
 
 Sorting
-  </>: Change sort column
+  ,/.: Change sort column, or none for the config file's order
+  SHIFT+k/j: Move selected tunnel up/down in that order
+  </>: Change secondary sort column
   r: Reverse sort order
 
 Tunnel Status
@@ -33,17 +44,32 @@ Tunnel Status
   [~] Connecting tunnel
   [x] Stopped tunnel
   [!] Error state
+  [-] Host in maintenance
 
 Filtering
   t: Filter by tag
+  v: Cycle status filter (all, active, error, stopped)
+  z: Group tunnels by tag (enter folds a group, SHIFT+z all)
 
 Management
   n: Create new tunnel from SSH string
+  c: Quick connect an SSH command, started now and never saved
   e: Edit selected tunnel
-  ⌫: Delete selected tunnel
+  ⌫: Delete selected tunnel, or the marked ones
+  space: Mark tunnel for bulk actions (esc clears)
   o: Open browser to selected tunnel's local port
-  SHIFT+a: Start all stopped tunnels
-  SHIFT+c: Stop all active tunnels
+  s: Share selected tunnel's local port publicly
+  SHIFT+h: Import tunnels from shell history
+  SHIFT+i: Import LocalForward entries from ~/.ssh/config
+  SHIFT+p: Switch profile
+  SHIFT+s: Start a startup group
+  SHIFT+d: Save a redacted diagnostic archive
+  SHIFT+t: Session timeline of the tunnels in view
+  SHIFT+v: Verify host keys of the tunnels in view
+  SHIFT+f: Find services behind the selected tunnel's SSH server
+  SHIFT+m: Put a bastion or remote host in maintenance
+  SHIFT+a: Start stopped tunnels in view, or the marked
+  SHIFT+c: Stop active tunnels in view, or the marked
 
 Press h or esc to close help`
 
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+)
+
+// How many status transitions to remember per tunnel, enough for the
+// session timeline of a tunnel that keeps flapping
+const statusHistoryLimit = 500
+
+// statusChange is one status transition of a tunnel
+type statusChange struct {
+	Time    time.Time
+	From    string
+	To      string
+	Message string
+}
+
+// setStatus changes a tunnel's status and message, remembering the
+// transition so bounces can be reviewed later in the detail pane and
+// counting it towards availability
+func (t *TunnelRecord) setStatus(state string, message string) {
+	now := time.Now()
+	t.Uptime.observe(state, now)
+	if state != t.Status {
+		t.History = append(t.History, statusChange{
+			Time:    now,
+			From:    t.Status,
+			To:      state,
+			Message: message,
+		})
+		if len(t.History) > statusHistoryLimit {
+			t.History = t.History[len(t.History)-statusHistoryLimit:]
+		}
+	}
+	t.Status = state
+	t.Metrics = message
+}
+
+// historyLines renders the most recent transitions, newest first
+func historyLines(history []statusChange, max int) []string {
+	lines := make([]string, 0, max)
+	for i := len(history) - 1; i >= 0 && len(lines) < max; i-- {
+		change := history[i]
+		lines = append(lines, fmt.Sprintf("%s  %-10s → %-10s %s",
+			change.Time.Format("Jan 2 15:04:05"), change.From, change.To, change.Message))
+	}
+	return lines
+}
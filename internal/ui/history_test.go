@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetStatusRecordsTransitions(t *testing.T) {
+	record := &TunnelRecord{Status: "stopped"}
+
+	record.setStatus("connecting", "initializing")
+	record.setStatus("active", "ok")
+	record.setStatus("active", "1.2 KB/s") // Metrics updates are not transitions
+
+	if len(record.History) != 2 {
+		t.Fatalf("expected 2 transitions, got %d", len(record.History))
+	}
+	if record.History[1].From != "connecting" || record.History[1].To != "active" {
+		t.Errorf("unexpected transition: %+v", record.History[1])
+	}
+	if record.Metrics != "1.2 KB/s" {
+		t.Errorf("expected message to be updated, got %s", record.Metrics)
+	}
+
+	for i := 0; i < statusHistoryLimit; i++ {
+		record.setStatus("error", "down")
+		record.setStatus("active", "up")
+	}
+	if len(record.History) != statusHistoryLimit {
+		t.Errorf("expected history capped at %d, got %d", statusHistoryLimit, len(record.History))
+	}
+
+	lines := historyLines(record.History, 3)
+	if len(lines) != 3 || !strings.Contains(lines[0], "→ active") {
+		t.Errorf("expected newest transition first, got %v", lines)
+	}
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"fmt"
+
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// hostKeyMsg carries an unknown host key that a connection is waiting on
+type hostKeyMsg struct {
+	prompt *ssh.HostKeyPrompt
+}
+
+// waitForHostKey delivers the next unknown host key from the manager. Only
+// one is outstanding at a time, so this is re-armed once it is answered.
+func (a *App) waitForHostKey() tea.Cmd {
+	prompts := a.hostKeyPrompts
+	return func() tea.Msg {
+		prompt, ok := <-prompts
+		if !ok {
+			return nil
+		}
+		return hostKeyMsg{prompt: prompt}
+	}
+}
+
+func (a *App) handleHostKeyKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	prompt := a.hostKeyPrompt
+	switch msg.String() {
+	case "enter", "y":
+		a.Logf("Trusting host key for %s (%s)", prompt.Hostname, prompt.Fingerprint)
+		prompt.Accept()
+	case "esc", "ctrl+c", "n":
+		a.Logf("Rejected host key for %s (%s)", prompt.Hostname, prompt.Fingerprint)
+		prompt.Reject()
+	default:
+		return a, nil
+	}
+	a.hostKeyPrompt = nil
+	return a, a.waitForHostKey()
+}
+
+func (a *App) hostKeyView() string {
+	prompt := a.hostKeyPrompt
+	content := dialogActiveStyle.Render("Unknown Host Key") + "\n\n"
+	content += fmt.Sprintf("%s is connecting to %s for the first time.\n\n", prompt.Name, prompt.Hostname)
+	content += fmt.Sprintf("  %s key fingerprint is\n  %s\n", prompt.KeyType, prompt.Fingerprint)
+	content += "\nTrust this host? Accepted keys are remembered.\n"
+	content += "\nEnter/y: Trust • n/Esc: Reject"
+
+	dialog := dialogStyle.Width(70).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// hostScanMsg carries the key one SSH server presented to the review
+type hostScanMsg struct {
+	round int // Which opening of the review it belongs to
+	index int
+	scan  ssh.HostKeyScan
+}
+
+// hostScan is an SSH server under review and the tunnels going through it
+type hostScan struct {
+	endpoint string
+	tunnels  []string
+	scan     *ssh.HostKeyScan // Nil until the server has answered
+	selected bool
+}
+
+// openHostScan fetches the host keys of every SSH server the tunnels in view
+// go through, so a whole tag's keys can be reviewed and accepted in one pass
+// instead of a prompt per tunnel as each first connects
+func (a *App) openHostScan() tea.Cmd {
+	byEndpoint := make(map[string]int)
+	configs := make([]config.TunnelConfig, 0)
+	a.hostScans = nil
+	for _, t := range a.filteredTunnels() {
+		endpoint, _ := ssh.TargetEndpoints(t.Config)
+		if i, ok := byEndpoint[endpoint.String()]; ok {
+			a.hostScans[i].tunnels = append(a.hostScans[i].tunnels, t.Config.Name)
+			continue
+		}
+		byEndpoint[endpoint.String()] = len(a.hostScans)
+		a.hostScans = append(a.hostScans, hostScan{endpoint: endpoint.String(), tunnels: []string{t.Config.Name}})
+		configs = append(configs, t.Config)
+	}
+	a.hostScanCursor = 0
+	a.hostScanRound++
+	a.showHostScan = true
+
+	manager, round := a.manager, a.hostScanRound
+	cmds := make([]tea.Cmd, 0, len(configs))
+	for i, tc := range configs {
+		cmds = append(cmds, func() tea.Msg {
+			return hostScanMsg{round: round, index: i, scan: manager.ScanHostKey(tc)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// showHostScanResult fills in a server's key, selecting it when it's new
+func (a *App) showHostScanResult(msg hostScanMsg) {
+	if !a.showHostScan || msg.round != a.hostScanRound || msg.index >= len(a.hostScans) {
+		return
+	}
+	scan := msg.scan
+	a.hostScans[msg.index].scan = &scan
+	a.hostScans[msg.index].selected = scan.New()
+}
+
+func (a *App) handleHostScanKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.hostScanCursor > 0 {
+			a.hostScanCursor--
+		}
+	case "down", "j":
+		if a.hostScanCursor < len(a.hostScans)-1 {
+			a.hostScanCursor++
+		}
+	case " ":
+		if a.hostScanCursor < len(a.hostScans) {
+			h := &a.hostScans[a.hostScanCursor]
+			h.selected = !h.selected && h.scan != nil && h.scan.New()
+		}
+	case "a":
+		// Select every new key, or none when all are selected already
+		all := true
+		for _, h := range a.hostScans {
+			if h.scan != nil && h.scan.New() {
+				all = all && h.selected
+			}
+		}
+		for i := range a.hostScans {
+			h := &a.hostScans[i]
+			h.selected = !all && h.scan != nil && h.scan.New()
+		}
+	case "enter":
+		a.trustHostScans()
+		a.showHostScan = false
+		a.hostScans = nil
+	case "esc", "ctrl+c", "V":
+		a.showHostScan = false
+		a.hostScans = nil
+	}
+	return a, nil
+}
+
+// trustHostScans records the selected keys, so the tunnels connect without
+// asking
+func (a *App) trustHostScans() {
+	trusted := 0
+	for _, h := range a.hostScans {
+		if !h.selected || h.scan == nil {
+			continue
+		}
+		if err := h.scan.Trust(); err != nil {
+			a.logError("Failed to trust host key for %s: %v", h.endpoint, err)
+			continue
+		}
+		a.Logf("Trusting host key for %s (%s %s)", h.scan.Hostname, h.scan.KeyType, h.scan.Fingerprint)
+		trusted++
+	}
+	a.Logf("Trusted %d host key(s)", trusted)
+}
+
+// hostScanStatus describes where a server's review stands
+func hostScanStatus(h hostScan) string {
+	switch {
+	case h.scan == nil:
+		return previewStyle.Render("scanning...")
+	case h.scan.Err != nil:
+		reason := strings.ReplaceAll(h.scan.Err.Error(), "\n", " ")
+		return checkFailedStyle.Render("✗ " + reason)
+	case h.scan.Known:
+		return checkOKStyle.Render("✓ known")
+	}
+	return "new"
+}
+
+func (a *App) hostScanView() string {
+	title := "Verify host keys"
+	if a.currentTag != "" {
+		title += " for " + a.tagFilterLabel(a.currentTag)
+	}
+	content := dialogActiveStyle.Render(title) + "\n\n"
+	if len(a.hostScans) == 0 {
+		content += "No SSH servers in view\n"
+	}
+
+	// Keep the cursor on the visible page
+	start := 0
+	if a.hostScanCursor >= discoverPageSize {
+		start = a.hostScanCursor - discoverPageSize + 1
+	}
+	pending, fresh, selected := 0, 0, 0
+	for _, h := range a.hostScans {
+		switch {
+		case h.scan == nil:
+			pending++
+		case h.scan.New():
+			fresh++
+		}
+		if h.selected {
+			selected++
+		}
+	}
+	for i := start; i < len(a.hostScans) && i < start+discoverPageSize; i++ {
+		h := a.hostScans[i]
+		box := "   "
+		if h.scan != nil && h.scan.New() {
+			box = "[ ]"
+			if h.selected {
+				box = "[x]"
+			}
+		}
+		fingerprint := ""
+		if h.scan != nil && h.scan.Fingerprint != "" {
+			fingerprint = fmt.Sprintf("%s %s", h.scan.KeyType, h.scan.Fingerprint)
+		}
+		line := fmt.Sprintf("%s %-30s %s", box, h.endpoint, hostScanStatus(h))
+		if i == a.hostScanCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+			if fingerprint != "" {
+				content += "      " + fingerprint + "\n"
+			}
+			content += "      " + previewStyle.Render("for "+strings.Join(h.tunnels, ", ")) + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	summary := fmt.Sprintf("\n%d new, %d selected", fresh, selected)
+	if pending > 0 {
+		summary += fmt.Sprintf(", %d still scanning", pending)
+	}
+	content += previewStyle.Render(summary) + "\n"
+	content += "\n↑/↓: Move • Space: Select • a: All/none • Enter: Trust selected • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(100).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
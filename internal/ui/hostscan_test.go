@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHostScanGroupsServers(t *testing.T) {
+	tunnel := func(name string, bastion string, tag string) TunnelRecord {
+		tc := config.TunnelConfig{Name: name, LocalPort: 1, RemoteHost: "db", RemotePort: 5432, Tag: tag}
+		tc.Bastion.Host = bastion
+		return TunnelRecord{ID: name, Status: "stopped", Config: tc}
+	}
+	a := &App{manager: ssh.NewTunnelManager(), currentTag: "prod"}
+	a.tunnels = []TunnelRecord{
+		tunnel("db", "jump-a", "prod"),
+		tunnel("cache", "jump-a", "prod"),
+		tunnel("search", "jump-b", "prod"),
+		tunnel("dev", "jump-c", "dev"),
+	}
+
+	if cmd := a.openHostScan(); cmd == nil {
+		t.Fatal("expected the servers to be scanned")
+	}
+	if !a.showHostScan || len(a.hostScans) != 2 {
+		t.Fatalf("expected the 2 servers of the tag, got %+v", a.hostScans)
+	}
+	if strings.Join(a.hostScans[0].tunnels, ",") != "db,cache" {
+		t.Errorf("expected tunnels sharing a server grouped, got %v", a.hostScans[0].tunnels)
+	}
+
+	// Answers from an earlier review are ignored
+	a.showHostScanResult(hostScanMsg{round: a.hostScanRound - 1, index: 0, scan: ssh.HostKeyScan{Err: errors.New("stale")}})
+	if a.hostScans[0].scan != nil {
+		t.Errorf("expected a stale answer ignored")
+	}
+
+	a.showHostScanResult(hostScanMsg{round: a.hostScanRound, index: 0, scan: ssh.HostKeyScan{Err: errors.New("connection refused")}})
+	a.handleHostScanKey(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if a.hostScans[0].selected {
+		t.Errorf("expected a server without a new key not selectable")
+	}
+	if view := a.hostScanView(); !strings.Contains(view, "connection refused") || !strings.Contains(view, "1 still scanning") {
+		t.Errorf("unexpected view:\n%s", view)
+	}
+
+	a.handleHostScanKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if a.showHostScan || a.hostScans != nil {
+		t.Errorf("expected the review closed")
+	}
+}
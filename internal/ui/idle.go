@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// idle reports whether nothing needs sampling: no tunnel is up or on its way
+// up, no startup group is waiting and no summary or email is queued
+func (a *App) idle() bool {
+	for _, t := range a.tunnels {
+		if t.Status == "active" || t.Status == "connecting" {
+			return false
+		}
+	}
+	if len(a.groupRuns) > 0 || a.bulk != nil || a.dampener.pending() {
+		return false
+	}
+	return a.emailer == nil || !a.emailer.Pending()
+}
+
+// sampleWhenDue schedules the one sample an idle app still needs, the next
+// heartbeat, so monitoring doesn't mistake idle for gone
+func (a *App) sampleWhenDue(now time.Time) tea.Cmd {
+	if a.heartbeat == nil {
+		return nil
+	}
+	wait := sampleInterval
+	if next := a.heartbeat.Next(); !next.IsZero() && next.Sub(now) > wait {
+		wait = next.Sub(now)
+	}
+	round := a.loopRound
+	return tea.Tick(wait, func(t time.Time) tea.Msg {
+		return sampleMsg{round: round, at: t}
+	})
+}
+
+// wake restarts the tick and sample loops of an idle app on anything but
+// its own timers, e.g. a key press, a log line or a status change
+func (a *App) wake(msg tea.Msg) tea.Cmd {
+	if !a.suspended {
+		return nil
+	}
+	switch msg.(type) {
+	case tickMsg, sampleMsg, heartbeatMsg:
+		return nil
+	}
+	a.suspended = false
+	a.loopRound++
+	// The registry wasn't re-read while idle, catch up on the first sample
+	a.registryTicks = registryRefreshTicks - 1
+	return tea.Batch(a.tick(), a.sample())
+}
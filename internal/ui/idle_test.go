@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/heartbeat"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestIdleSuspendsLoops(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.dampener = newLogDampener()
+	now := time.Now()
+
+	a.tunnels[0].Status = "connecting"
+	if _, cmd := a.Update(sampleMsg{at: now}); a.suspended || cmd == nil {
+		t.Fatal("expected sampling to carry on while a tunnel connects")
+	}
+
+	a.tunnels[0].Status = "error"
+	if _, cmd := a.Update(sampleMsg{at: now}); !a.suspended || cmd != nil {
+		t.Fatalf("expected sampling to stop with every tunnel down, got %v", cmd)
+	}
+	if _, cmd := a.Update(tickMsg{at: now}); cmd != nil {
+		t.Error("expected the tick loop to stop once idle")
+	}
+
+	if _, cmd := a.Update(tea.KeyMsg{Type: tea.KeyDown}); a.suspended || cmd == nil {
+		t.Fatal("expected a key press to restart the loops")
+	}
+	if a.loopRound != 1 {
+		t.Errorf("expected a new round, got %d", a.loopRound)
+	}
+	if _, cmd := a.Update(tickMsg{round: 0, at: now}); cmd != nil {
+		t.Error("expected a tick from before the wake to be dropped")
+	}
+}
+
+func TestIdleStillSendsHeartbeats(t *testing.T) {
+	a := newExpandApp(t, 1, 10)
+	a.dampener = newLogDampener()
+	a.heartbeat = heartbeat.New(config.HeartbeatConfig{URL: "http://monitor.invalid/ping", Interval: "1m"})
+	now := time.Now()
+
+	a.heartbeat.Due(now)
+	if !a.idle() {
+		t.Fatal("expected an app with only stopped tunnels to be idle")
+	}
+	if a.sampleWhenDue(now) == nil {
+		t.Error("expected a sample scheduled for the next heartbeat")
+	}
+	a.heartbeat = nil
+	if a.sampleWhenDue(now) != nil {
+		t.Error("expected nothing scheduled without a heartbeat")
+	}
+}
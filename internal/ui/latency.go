@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"regexp"
+	"strconv"
+
+	"tunnel9/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Default latency thresholds in milliseconds
+const (
+	defaultLatencyGoodMs = 50
+	defaultLatencyWarnMs = 200
+)
+
+var (
+	latencyGoodStyle = lipgloss.NewStyle().Foreground(activeTheme.success)
+	latencyWarnStyle = lipgloss.NewStyle().Foreground(activeTheme.warning)
+	latencyBadStyle  = lipgloss.NewStyle().Foreground(activeTheme.error)
+
+	// The latency the manager appends to the metrics message, e.g. [42ms],
+	// or on its own in the wide LATENCY column
+	latencyPattern = regexp.MustCompile(`\[(\d+)ms\]|\b(\d+)ms\b`)
+)
+
+// latencyThresholds fills in defaults for thresholds left out of the config
+func latencyThresholds(cfg config.LatencyConfig) (int, int) {
+	good, warn := cfg.GoodMs, cfg.WarnMs
+	if good <= 0 {
+		good = defaultLatencyGoodMs
+	}
+	if warn <= 0 {
+		warn = defaultLatencyWarnMs
+	}
+	return good, warn
+}
+
+// colorizeLatency colors latencies in rendered table output so degraded
+// links stand out. It runs on the rendered table rather than the cells
+// because the table measures cell width without knowing about ANSI codes.
+func (a *App) colorizeLatency(rendered string) string {
+	good, warn := latencyThresholds(a.latency)
+	return latencyPattern.ReplaceAllStringFunc(rendered, func(match string) string {
+		groups := latencyPattern.FindStringSubmatch(match)
+		ms, err := strconv.Atoi(groups[1] + groups[2])
+		if err != nil {
+			return match
+		}
+		switch {
+		case ms < good:
+			return latencyGoodStyle.Render(match)
+		case ms < warn:
+			return latencyWarnStyle.Render(match)
+		default:
+			return latencyBadStyle.Render(match)
+		}
+	})
+}
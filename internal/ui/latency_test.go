@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestLatencyThresholds(t *testing.T) {
+	good, warn := latencyThresholds(config.LatencyConfig{})
+	if good != defaultLatencyGoodMs || warn != defaultLatencyWarnMs {
+		t.Errorf("expected defaults, got %d/%d", good, warn)
+	}
+
+	good, warn = latencyThresholds(config.LatencyConfig{GoodMs: 20, WarnMs: 80})
+	if good != 20 || warn != 80 {
+		t.Errorf("expected configured thresholds, got %d/%d", good, warn)
+	}
+}
+
+func TestColorizeLatency(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.ANSI)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	a := &App{}
+	rendered := a.colorizeLatency("db  ↑1.0 B/s ↓2.0 B/s [12ms]\nweb ↑1.0 B/s ↓2.0 B/s [450ms]\nidle [n/a]")
+
+	lines := strings.Split(rendered, "\n")
+	if !strings.Contains(lines[0], latencyGoodStyle.Render("[12ms]")) {
+		t.Errorf("expected fast link in green, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], latencyBadStyle.Render("[450ms]")) {
+		t.Errorf("expected slow link in red, got %q", lines[1])
+	}
+	if lines[2] != "idle [n/a]" {
+		t.Errorf("expected unknown latency untouched, got %q", lines[2])
+	}
+
+	// The wide LATENCY column has no brackets
+	if got := a.colorizeLatency("   120ms"); got != "   "+latencyWarnStyle.Render("120ms") {
+		t.Errorf("expected column latency in yellow, got %q", got)
+	}
+	if got := a.colorizeLatency("db-10msg"); got != "db-10msg" {
+		t.Errorf("expected unknown latency untouched, got %q", lines[2])
+	}
+}
@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"net"
+	"strconv"
+
+	"tunnel9/internal/config"
+)
+
+// localPort is the port a tunnel listens on: its local_port, or for one that
+// picks a free port when it starts, the port it got, 0 until it listens
+func (a *App) localPort(t *TunnelRecord) int {
+	if !t.Config.PicksPort() {
+		return t.Config.LocalPort
+	}
+	addr, ok := a.manager.LocalAddress(t.ID)
+	if !ok {
+		return 0
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// localPortLabel shows the port a tunnel listens on, or auto for one that
+// hasn't picked its port yet
+func (a *App) localPortLabel(t *TunnelRecord) string {
+	if port := a.localPort(t); port != 0 || !t.Config.PicksPort() {
+		return strconv.Itoa(port)
+	}
+	return config.AutoPort
+}
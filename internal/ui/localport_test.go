@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/ssh"
+)
+
+func TestLocalPortPickedAtStart(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.manager = ssh.NewTunnelManager()
+	defer a.manager.Cleanup()
+	a.tunnels[0].Config.LocalPort = 0
+	a.tunnels[0].Config.AutoPort = true
+	a.updateTableRows()
+
+	if tunnel := a.table.Rows()[0][2]; tunnel != "auto:db:5432" {
+		t.Errorf("expected the port as auto before it starts, got %q", tunnel)
+	}
+	if tunnel := a.table.Rows()[1][2]; tunnel != "8001:db:5432" {
+		t.Errorf("expected the configured port, got %q", tunnel)
+	}
+
+	if err := a.manager.StartTunnel(a.manager.CreateTunnel("0", a.tunnels[0].Config)); err != nil {
+		t.Fatalf("expected the tunnel to start, got %v", err)
+	}
+	addr, _ := a.manager.LocalAddress("0")
+	_, port, _ := net.SplitHostPort(addr)
+	a.updateTableRows()
+	if tunnel := a.table.Rows()[0][2]; tunnel != port+":db:5432" || port == "0" {
+		t.Errorf("expected the picked port %s, got %q", port, tunnel)
+	}
+	if lines := strings.Join(a.settingsLines(&a.tunnels[0]), "\n"); !strings.Contains(lines, "localhost:"+port+"/tcp") {
+		t.Errorf("expected the settings to show the picked port, got %q", lines)
+	}
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// logExportFilename names a console export after when it was made
+func logExportFilename(t time.Time) string {
+	return fmt.Sprintf("tunnel9-logs-%s.log", t.Format("20060102-150405"))
+}
+
+// exportLogs writes the console's lines, as filtered, to the current
+// directory, for attaching to a support ticket
+func (a *App) exportLogs(now time.Time) {
+	lines := logTexts(a.getAllFilteredLogs())
+	if len(lines) == 0 {
+		a.pinLog("No console lines to export")
+		return
+	}
+	path := logExportFilename(now)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		a.logError("Failed to export the console: %v", err)
+		return
+	}
+	a.pinLog("Exported %d console line(s) to %s", len(lines), path)
+}
+
+// pinLog logs a line the console shows whatever its filters
+func (a *App) pinLog(format string, args ...interface{}) {
+	a.Logf(format, args...)
+	if len(a.errorLog) > 0 {
+		a.errorLog[len(a.errorLog)-1].pinned = true
+	}
+	a.updateViewport()
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tunnel9/internal/events"
+)
+
+func TestExportLogs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	a := newExpandApp(t, 1, 40)
+	a.errorLog = []logLine{
+		{level: events.LevelDebug, text: "10:00:00 DEBUG [tunnel-0] Health check ok"},
+		{level: events.LevelError, text: "10:00:01 ERROR [tunnel-0] failed to listen on port 8080"},
+	}
+	a.logLevel = events.LevelError
+
+	now := time.Date(2026, 10, 16, 10, 15, 0, 0, time.Local)
+	a.exportLogs(now)
+
+	path := filepath.Join(dir, "tunnel9-logs-20261016-101500.log")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "10:00:01 ERROR [tunnel-0] failed to listen on port 8080\n" {
+		t.Errorf("expected only the lines shown exported, got %q", data)
+	}
+
+	// Where it went shows even though the console is down to errors
+	shown := logTexts(a.getAllFilteredLogs())
+	if last := shown[len(shown)-1]; !strings.Contains(last, "Exported 1 console line(s) to "+path) {
+		t.Errorf("expected the path in the console, got %v", shown)
+	}
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"tunnel9/internal/config"
+	"tunnel9/internal/logfile"
+)
+
+// openLogFile starts keeping every console line in the configured log file
+func (a *App) openLogFile(cfg config.LogConfig) {
+	path, err := cfg.Path()
+	if err != nil {
+		a.logError("Failed to open the log file: %v", err)
+		return
+	}
+	if path == "" {
+		return
+	}
+	if a.logFile, err = logfile.Open(path, cfg.MaxBytes(), cfg.Backups()); err != nil {
+		a.logError("Failed to open the log file: %v", err)
+	}
+}
+
+// persistLog writes a console line to the log file. After a failed write the
+// file is given up on, as saying so in the console can't be logged either.
+func (a *App) persistLog(line string) {
+	if a.logFile == nil {
+		return
+	}
+	if err := a.logFile.Println(line); err != nil {
+		a.logFile.Close()
+		a.logFile = nil
+		a.logError("Failed to write the log file, no longer writing it: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+)
+
+func TestLogFileKeepsEveryLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tunnel9.log")
+	a := newExpandApp(t, 1, 40)
+	a.dampener = newLogDampener()
+	a.openLogFile(config.LogConfig{File: path})
+	if a.logFile == nil {
+		t.Fatalf("expected the log file open, console: %v", a.errorLog)
+	}
+
+	// Repeats are collapsed in the console, not in the file
+	for i := 0; i < 3; i++ {
+		a.Update(logMsg{level: events.LevelDebug, text: "10:00:00 DEBUG [tunnel-0] health check ok"})
+	}
+	a.Logf("Configuration saved successfully")
+	a.logError("Failed to stop tunnel %s", "db.internal")
+	a.logFile.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	if strings.Count(log, "health check ok") != 3 || !strings.Contains(log, "Configuration saved") || !strings.Contains(log, "ERROR Failed to stop tunnel db.internal") {
+		t.Errorf("expected every line in the log file, got %q", log)
+	}
+}
+
+func TestLogFileOff(t *testing.T) {
+	a := newExpandApp(t, 1, 40)
+	a.openLogFile(config.LogConfig{File: config.LogFileOff})
+	if a.logFile != nil {
+		t.Errorf("expected no log file")
+	}
+}
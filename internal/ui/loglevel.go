@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"tunnel9/internal/events"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logLine is a console line and the level it was logged at
+type logLine struct {
+	level  events.Level
+	text   string
+	pinned bool // Shown whatever the console's filters, like where an export went
+}
+
+// logTexts returns the text of each line
+func logTexts(lines []logLine) []string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.text
+	}
+	return texts
+}
+
+// loggedErrors reports whether the console holds any errors, shown or not
+func (a *App) loggedErrors() bool {
+	for _, line := range a.errorLog {
+		if line.level == events.LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// cycleLogLevel raises the least level the console shows, from DEBUG up to
+// ERROR and back
+func (a *App) cycleLogLevel() {
+	a.logLevel = (a.logLevel + 1) % (events.LevelError + 1)
+	a.logCursor = len(a.getAllFilteredLogs()) - 1
+	a.updateViewport()
+}
+
+// levelStyle colors the name of a level in the console
+func levelStyle(level events.Level) lipgloss.Style {
+	switch level {
+	case events.LevelInfo:
+		return lipgloss.NewStyle().Foreground(activeTheme.success)
+	case events.LevelWarn:
+		return lipgloss.NewStyle().Foreground(activeTheme.warning)
+	case events.LevelError:
+		return lipgloss.NewStyle().Foreground(activeTheme.error)
+	}
+	return lipgloss.NewStyle().Foreground(activeTheme.info)
+}
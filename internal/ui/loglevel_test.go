@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	"tunnel9/internal/events"
+)
+
+func TestConsoleLevelFilter(t *testing.T) {
+	a := newExpandApp(t, 2, 40)
+	a.dampener = newLogDampener()
+	for _, line := range []logLine{
+		{level: events.LevelDebug, text: "10:00:00 DEBUG [tunnel-0] Health check ok"},
+		{level: events.LevelInfo, text: "10:00:01 INFO [tunnel-0] Starting tunnel"},
+		{level: events.LevelWarn, text: "10:00:02 WARN [tunnel-1] SSH connection lost (EOF), reconnecting"},
+		{level: events.LevelError, text: "10:00:03 ERROR [tunnel-0] failed to listen on port 8080"},
+	} {
+		a.Update(logMsg(line))
+	}
+	a.showConsole = true
+
+	shown := func() []string { return logTexts(a.getAllFilteredLogs()) }
+	if got := shown(); len(got) != 4 {
+		t.Fatalf("expected every line at DEBUG, got %v", got)
+	}
+
+	a.Update(runeKey("L"))
+	a.Update(runeKey("L"))
+	if got := shown(); a.logLevel != events.LevelWarn || len(got) != 2 {
+		t.Errorf("expected warnings and errors at %s, got %v", a.logLevel, got)
+	}
+
+	// Along with the tunnel filter
+	a.filterLogs = true
+	if got := shown(); len(got) != 1 || got[0] != "10:00:03 ERROR [tunnel-0] failed to listen on port 8080" {
+		t.Errorf("expected tunnel-0's error only, got %v", got)
+	}
+
+	a.Update(runeKey("L"))
+	a.Update(runeKey("L"))
+	if a.logLevel != events.LevelDebug {
+		t.Errorf("expected the level to wrap back to DEBUG, got %s", a.logLevel)
+	}
+	if !a.loggedErrors() {
+		t.Errorf("expected the error noticed")
+	}
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// maintenanceIcon replaces the status of a stopped tunnel whose host is in
+// maintenance
+const maintenanceIcon = "[-]"
+
+// maintenanceRowStyle dims the rows of tunnels whose host is in maintenance
+var maintenanceRowStyle = lipgloss.NewStyle().Foreground(activeTheme.faint)
+
+// inMaintenance returns the host in maintenance a tunnel goes to or through,
+// if any
+func (a *App) inMaintenance(t *TunnelRecord) (string, bool) {
+	return a.maintenanceHosts.Affecting(t.Config)
+}
+
+// maintenanceCandidates are the bastions and remote hosts the tunnels use,
+// and those still in maintenance that none do anymore, sorted
+func (a *App) maintenanceCandidates() []string {
+	seen := make(map[string]bool)
+	hosts := make([]string, 0)
+	add := func(host string) {
+		if host == "" || host == "localhost" || host == "127.0.0.1" || seen[host] {
+			return
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	for _, t := range a.tunnels {
+		add(t.Config.Bastion.Host)
+		add(t.Config.RemoteHost)
+	}
+	for host := range a.maintenanceHosts {
+		add(host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// openMaintenance lists the hosts that can be put in maintenance, starting
+// at the selected tunnel's SSH server
+func (a *App) openMaintenance() {
+	a.maintenanceList = a.maintenanceCandidates()
+	a.maintenanceCursor = 0
+	if selected := a.selectedRecord(); selected != nil {
+		want := selected.Config.Bastion.Host
+		if want == "" {
+			want = selected.Config.RemoteHost
+		}
+		for i, host := range a.maintenanceList {
+			if host == want {
+				a.maintenanceCursor = i
+				break
+			}
+		}
+	}
+	a.showMaintenance = true
+}
+
+func (a *App) handleMaintenanceKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "up", "k":
+		if a.maintenanceCursor > 0 {
+			a.maintenanceCursor--
+		}
+	case "down", "j":
+		if a.maintenanceCursor < len(a.maintenanceList)-1 {
+			a.maintenanceCursor++
+		}
+	case "enter", " ":
+		if a.maintenanceCursor < len(a.maintenanceList) {
+			cmd = a.toggleMaintenance(a.maintenanceList[a.maintenanceCursor])
+		}
+	case "esc", "ctrl+c", "M":
+		a.showMaintenance = false
+	}
+	return a, cmd
+}
+
+// toggleMaintenance puts a host in maintenance, stopping the tunnels using
+// it, or clears it so they can start again
+func (a *App) toggleMaintenance(host string) tea.Cmd {
+	if _, ok := a.maintenanceHosts[host]; ok {
+		a.setMaintenance(host, false)
+		a.updateTableRows()
+		return nil
+	}
+
+	toStop := make([]*TunnelRecord, 0)
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		if t.Config.Bastion.Host != host && t.Config.RemoteHost != host {
+			continue
+		}
+		// Errored ones too, so nothing keeps retrying
+		if t.Status == "active" || t.Status == "connecting" || t.Status == "error" {
+			toStop = append(toStop, t)
+		}
+	}
+	cmd := a.confirmStop(toStop, "Stop", func() tea.Cmd {
+		if a.setMaintenance(host, true) {
+			a.stopInBackground(toStop)
+		}
+		return nil
+	})
+	a.updateTableRows()
+	return cmd
+}
+
+// setMaintenance records a host's maintenance, reporting whether it stuck
+func (a *App) setMaintenance(host string, on bool) bool {
+	if a.maintenance == nil {
+		a.logError("Failed to change maintenance of %s: no state directory", host)
+		return false
+	}
+	hosts, err := a.maintenance.Set(host, on)
+	if err != nil {
+		a.logError("Failed to change maintenance of %s: %v", host, err)
+		return false
+	}
+	a.maintenanceHosts = hosts
+	if on {
+		a.Logf("%s is in maintenance, its tunnels stay stopped until it is cleared", host)
+	} else {
+		a.Logf("%s is out of maintenance", host)
+	}
+	return true
+}
+
+// dimMaintenance dims the rendered rows of tunnels whose host is in
+// maintenance, leaving the selected row highlighted. Like colorizeLatency it
+// works on the rendered table, as the cells can't carry ANSI codes.
+func (a *App) dimMaintenance(rendered string) string {
+	if len(a.maintenanceHosts) == 0 {
+		return rendered
+	}
+	selected := a.plainRow()
+	lines := strings.Split(rendered, "\n")
+	for i := tableHeaderLines; i < len(lines); i++ {
+		plain := ansi.Strip(lines[i])
+		status := strings.TrimLeft(plain, " ●")
+		if !strings.HasPrefix(status, maintenanceIcon) || collapseSpace(plain) == selected {
+			continue
+		}
+		lines[i] = maintenanceRowStyle.Render(plain)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (a *App) maintenanceView() string {
+	content := dialogActiveStyle.Render("Host maintenance") + "\n\n"
+	if len(a.maintenanceList) == 0 {
+		content += "No bastions or remote hosts configured\n"
+	}
+
+	// Keep the cursor on the visible page
+	start := 0
+	if a.maintenanceCursor >= discoverPageSize {
+		start = a.maintenanceCursor - discoverPageSize + 1
+	}
+	for i := start; i < len(a.maintenanceList) && i < start+discoverPageSize; i++ {
+		host := a.maintenanceList[i]
+		users := 0
+		for _, t := range a.tunnels {
+			if t.Config.Bastion.Host == host || t.Config.RemoteHost == host {
+				users++
+			}
+		}
+		line := fmt.Sprintf("%-40s %3d tunnel(s)", host, users)
+		if since, ok := a.maintenanceHosts[host]; ok {
+			line += "  " + checkFailedStyle.Render("in maintenance since "+since.Format("Jan 2 15:04"))
+		}
+		if i == a.maintenanceCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += "\n↑/↓: Move • Enter: Start/clear maintenance • Esc/Ctrl+C: Close"
+
+	dialog := dialogStyle.Width(100).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/registry"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+func TestMaintenance(t *testing.T) {
+	a := newExpandApp(t, 3, 10)
+	a.maintenance = registry.NewMaintenance(filepath.Join(t.TempDir(), "maintenance.json"))
+	a.tunnels[1].Config.Bastion.Host = "bastion.example.com"
+	a.tunnels[2].Config.RemoteHost = "web.internal"
+	a.updateTableRows()
+
+	a.table.SetCursor(1)
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'M'}})
+	if !a.showMaintenance || a.maintenanceList[a.maintenanceCursor] != "bastion.example.com" {
+		t.Fatalf("expected the selected tunnel's bastion under the cursor, got %v", a.maintenanceList)
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := a.maintenanceHosts["bastion.example.com"]; !ok {
+		t.Fatal("expected the bastion in maintenance")
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	if cmd := a.startTunnel(&a.tunnels[1]); cmd != nil || a.tunnels[1].Status != "stopped" {
+		t.Errorf("expected the tunnel through the bastion not to start")
+	}
+	if row := a.table.Rows()[1]; row[0] != maintenanceIcon || !strings.Contains(row[len(row)-1], "bastion.example.com") {
+		t.Errorf("expected the row to show the maintenance, got %v", row)
+	}
+
+	// Dimmed unless selected
+	lipgloss.SetColorProfile(termenv.ANSI)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+	a.table.SetCursor(0)
+	dimmed := a.dimMaintenance(a.table.View())
+	for _, line := range strings.Split(dimmed, "\n") {
+		styled := ansi.Strip(line) != line
+		if strings.Contains(line, "tunnel-1") && !styled || strings.Contains(line, "tunnel-2") && styled {
+			t.Errorf("expected only the row in maintenance dimmed, got %q", line)
+		}
+	}
+
+	// Survives a restart, until cleared
+	hosts, err := a.maintenance.Load()
+	if err != nil || len(hosts) != 1 {
+		t.Fatalf("expected the maintenance saved, got %v (%v)", hosts, err)
+	}
+	a.toggleMaintenance("bastion.example.com")
+	if len(a.maintenanceHosts) != 0 || a.table.Rows()[1][0] == maintenanceIcon {
+		t.Error("expected the maintenance cleared")
+	}
+}
@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// markBadgeStyle shows in the footer how many rows bulk actions apply to
+var markBadgeStyle = lipgloss.NewStyle().
+	Background(activeTheme.badge).
+	Foreground(activeTheme.badgeText).
+	Bold(true).
+	Padding(0, 1)
+
+// bulkOp is a bulk start or stop still on its way, for its progress in the
+// footer
+type bulkOp struct {
+	verb string   // "Starting" or "Stopping"
+	ids  []string // The tunnels it acts on
+	done func(status string) bool
+}
+
+// progress counts the tunnels that got where the operation takes them, and
+// those of them that failed. Tunnels deleted since count as done.
+func (b *bulkOp) progress(tunnels []TunnelRecord) (done int, failed int) {
+	status := make(map[string]string, len(tunnels))
+	for _, t := range tunnels {
+		status[t.ID] = t.Status
+	}
+	for _, id := range b.ids {
+		s, ok := status[id]
+		if !ok || b.done(s) {
+			done++
+		}
+		if s == "error" {
+			failed++
+		}
+	}
+	return done, failed
+}
+
+// toggleMark marks or unmarks the selected tunnel and moves on to the next
+// row, so a run of rows is marked by holding space
+func (a *App) toggleMark() {
+	selected := a.selectedRecord()
+	if selected == nil {
+		return
+	}
+	if a.marked == nil {
+		a.marked = make(map[string]bool)
+	}
+	if a.marked[selected.ID] {
+		delete(a.marked, selected.ID)
+	} else {
+		a.marked[selected.ID] = true
+	}
+	a.table.MoveDown(1)
+	a.updateTableRows()
+}
+
+// clearMarks unmarks every tunnel
+func (a *App) clearMarks() {
+	a.marked = nil
+	a.updateTableRows()
+}
+
+// markedTunnels returns the marked tunnels, in table order
+func (a *App) markedTunnels() []*TunnelRecord {
+	marked := make([]*TunnelRecord, 0, len(a.marked))
+	for i := range a.tunnels {
+		if a.marked[a.tunnels[i].ID] {
+			marked = append(marked, &a.tunnels[i])
+		}
+	}
+	return marked
+}
+
+// bulkTargets returns the marked tunnels in one of the statuses, or every
+// tunnel in view in them when none are marked
+func (a *App) bulkTargets(statuses ...string) []*TunnelRecord {
+	include := a.marked
+	if len(include) == 0 {
+		include = make(map[string]bool)
+		for _, t := range a.filteredTunnels() {
+			include[t.ID] = true
+		}
+	}
+	targets := make([]*TunnelRecord, 0)
+	for i := range a.tunnels {
+		tunnel := &a.tunnels[i]
+		if !include[tunnel.ID] {
+			continue
+		}
+		for _, status := range statuses {
+			if tunnel.Status == status {
+				targets = append(targets, tunnel)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// startTunnels starts the tunnels all at once in the background, following
+// their progress in the footer
+func (a *App) startTunnels(targets []*TunnelRecord) tea.Cmd {
+	startedCount := 0
+	cmds := make([]tea.Cmd, 0)
+	ids := make([]string, 0, len(targets))
+	created := make([]*ssh.Tunnel, 0, len(targets))
+	for _, tunnel := range targets {
+		// Never silently create a duplicate share in bulk
+		if dupes := a.shareConflicts(tunnel); len(dupes) > 0 {
+			a.logError("Skipping %s: already shared by %s", tunnel.Config.Name, describeForwards(dupes))
+			continue
+		}
+		started, cmd := a.createTunnel(tunnel)
+		if started != nil {
+			created = append(created, started)
+		}
+		cmds = append(cmds, cmd)
+		if tunnel.Status == "connecting" {
+			startedCount++
+			ids = append(ids, tunnel.ID)
+		}
+	}
+	if startedCount > 0 {
+		a.Logf("Started %d tunnel(s)", startedCount)
+	}
+	if len(created) > 0 {
+		manager := a.manager
+		cmds = append(cmds, func() tea.Msg {
+			manager.StartTunnels(created)
+			return nil
+		})
+	}
+	a.trackBulk("Starting", ids, func(status string) bool {
+		return status == "active" || status == "error"
+	})
+	return tea.Batch(cmds...)
+}
+
+// stopTunnels stops the tunnels in the background, following their progress
+// in the footer
+func (a *App) stopTunnels(targets []*TunnelRecord) tea.Cmd {
+	return a.confirmStop(targets, "Stop", func() tea.Cmd {
+		ids := make([]string, 0, len(targets))
+		for _, tunnel := range targets {
+			ids = append(ids, tunnel.ID)
+		}
+		a.stopInBackground(targets)
+		a.trackBulk("Stopping", ids, func(status string) bool {
+			return status == "stopped" || status == "error"
+		})
+		return nil
+	})
+}
+
+// trackBulk follows a bulk operation on more than one tunnel
+func (a *App) trackBulk(verb string, ids []string, done func(status string) bool) {
+	if len(ids) < 2 {
+		return
+	}
+	a.bulk = &bulkOp{verb: verb, ids: ids, done: done}
+}
+
+// settleBulk drops the bulk operation once all its tunnels got there,
+// logging how it went
+func (a *App) settleBulk() {
+	if a.bulk == nil {
+		return
+	}
+	done, failed := a.bulk.progress(a.tunnels)
+	if done < len(a.bulk.ids) {
+		return
+	}
+	verb := strings.ToLower(a.bulk.verb)
+	if failed > 0 {
+		a.logError("Finished %s %d tunnel(s), %d failed", verb, len(a.bulk.ids), failed)
+	} else {
+		a.Logf("Finished %s %d tunnel(s)", verb, len(a.bulk.ids))
+	}
+	a.bulk = nil
+}
+
+// bulkBadge shows the marked tunnels and how far a bulk operation got, empty
+// when there's neither
+func (a *App) bulkBadge() string {
+	parts := make([]string, 0, 2)
+	if len(a.marked) > 0 {
+		parts = append(parts, fmt.Sprintf("%d marked", len(a.marked)))
+	}
+	if a.bulk != nil {
+		done, failed := a.bulk.progress(a.tunnels)
+		progress := fmt.Sprintf("%s %d/%d", a.bulk.verb, done, len(a.bulk.ids))
+		if failed > 0 {
+			progress += fmt.Sprintf(", %d failed", failed)
+		}
+		parts = append(parts, progress)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return markBadgeStyle.Render(strings.Join(parts, " • ")) + " "
+}
+
+// confirmDeleteMarked deletes the marked tunnels that aren't running, asking
+// first when any of them has a policy that does
+func (a *App) confirmDeleteMarked() {
+	ids := make([]string, 0, len(a.marked))
+	ask := false
+	for _, tunnel := range a.markedTunnels() {
+		if tunnel.Status == "active" || tunnel.Status == "connecting" {
+			a.logError("Not deleting %s: stop it first", tunnel.Config.Name)
+			continue
+		}
+		ids = append(ids, tunnel.ID)
+		ask = ask || config.ConfirmsDelete(a.confirmPolicy(tunnel))
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if !ask {
+		a.deleteTunnels(ids)
+		return
+	}
+	a.deleteIDs = ids
+	a.showDeleteConfirm = true
+}
+
+// deleteTunnels removes the stopped tunnels among ids, saving once
+func (a *App) deleteTunnels(ids []string) {
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	kept := a.tunnels[:0]
+	deleted := 0
+	for _, t := range a.tunnels {
+		if remove[t.ID] && t.Status != "active" && t.Status != "connecting" {
+			a.Logf("Deleted tunnel: %s", t.Config.Name)
+			delete(a.marked, t.ID)
+			deleted++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	a.tunnels = kept
+	if deleted == 0 {
+		return
+	}
+	a.Logf("Deleted %d tunnel(s)", deleted)
+	a.saveConfig()
+	a.updateTableRows()
+}
+
+// deleteMarkedView lists the tunnels a bulk delete removes
+func (a *App) deleteMarkedView() string {
+	names := make([]string, 0, len(a.deleteIDs))
+	for _, id := range a.deleteIDs {
+		for _, t := range a.tunnels {
+			if t.ID == id {
+				names = append(names, t.Config.Name)
+				break
+			}
+		}
+	}
+	content := dialogActiveStyle.Render("Confirm Delete") + "\n\n"
+	content += fmt.Sprintf("Are you sure you want to delete %d tunnel(s)?\n\n", len(names))
+	content += "  " + strings.Join(names, ", ") + "\n"
+	content += "\nEnter: Confirm • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(60).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMarkRows(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.tunnels[2].Status = "active"
+
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	a.Update(space)
+	a.Update(tea.KeyMsg{Type: tea.KeyDown})
+	a.Update(space)
+	if !a.marked["0"] || a.marked["1"] || !a.marked["2"] || a.table.Cursor() != 3 {
+		t.Fatalf("expected rows 0 and 2 marked, cursor on 3, got %v at %d", a.marked, a.table.Cursor())
+	}
+
+	targets := a.bulkTargets("stopped", "error")
+	if len(targets) != 1 || targets[0].ID != "0" {
+		t.Errorf("expected only the marked stopped tunnel to start, got %d", len(targets))
+	}
+
+	a.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(a.marked) != 0 {
+		t.Fatal("expected esc to clear the marks")
+	}
+	if targets := a.bulkTargets("stopped", "error"); len(targets) != 3 {
+		t.Errorf("expected every stopped tunnel without marks, got %d", len(targets))
+	}
+}
+
+func TestDeleteMarked(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.tunnels[1].Status = "active"
+	a.marked = map[string]bool{"0": true, "1": true, "3": true}
+
+	a.Update(tea.KeyMsg{Type: tea.KeyDelete})
+	if !a.showDeleteConfirm || len(a.deleteIDs) != 2 {
+		t.Fatalf("expected a dialog for the 2 stopped marked tunnels, got %v", a.deleteIDs)
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if a.showDeleteConfirm || len(a.tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels left, got %d", len(a.tunnels))
+	}
+	if a.tunnels[0].ID != "1" || a.tunnels[1].ID != "2" {
+		t.Errorf("expected the active and unmarked tunnels kept, got %s and %s", a.tunnels[0].ID, a.tunnels[1].ID)
+	}
+	if len(a.marked) != 1 || !a.marked["1"] {
+		t.Errorf("expected the active tunnel still marked, got %v", a.marked)
+	}
+}
+
+func TestBulkProgress(t *testing.T) {
+	a := newExpandApp(t, 3, 10)
+	for i := range a.tunnels {
+		a.tunnels[i].Status = "connecting"
+	}
+	a.trackBulk("Starting", []string{"0", "1", "2"}, func(status string) bool {
+		return status == "active" || status == "error"
+	})
+
+	a.tunnels[0].Status = "active"
+	a.tunnels[1].Status = "error"
+	if done, failed := a.bulk.progress(a.tunnels); done != 2 || failed != 1 {
+		t.Errorf("expected 2 done and 1 failed, got %d and %d", done, failed)
+	}
+	a.settleBulk()
+	if a.bulk == nil {
+		t.Fatal("expected the operation to wait for the last tunnel")
+	}
+
+	a.tunnels[2].Status = "active"
+	a.settleBulk()
+	if a.bulk != nil {
+		t.Error("expected the operation settled")
+	}
+
+	a.trackBulk("Stopping", []string{"0"}, func(string) bool { return true })
+	if a.bulk != nil {
+		t.Error("expected a single tunnel not to be tracked")
+	}
+}
+
+func TestBulkTargetsFollowFilter(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.tunnels[0].Config.Tag = "prod"
+	a.tunnels[1].Config.Tag = "prod"
+	a.tunnels[1].Status = "active"
+	a.tunnels[2].Config.Tag = "dev"
+	a.currentTag = "prod"
+
+	starts := a.bulkTargets("stopped", "error")
+	if len(starts) != 1 || starts[0].ID != "0" {
+		t.Errorf("expected only the stopped prod tunnel to start, got %d", len(starts))
+	}
+	stops := a.bulkTargets("active", "connecting")
+	if len(stops) != 1 || stops[0].ID != "1" {
+		t.Errorf("expected only the active prod tunnel to stop, got %d", len(stops))
+	}
+
+	// Marks win over the filter
+	a.marked = map[string]bool{"2": true}
+	if starts := a.bulkTargets("stopped", "error"); len(starts) != 1 || starts[0].ID != "2" {
+		t.Errorf("expected the marked dev tunnel, got %d", len(starts))
+	}
+}
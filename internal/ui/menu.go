@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// menuItem is one action in the quick actions menu. Those with a key are
+// shortcuts for an existing binding, so the menu teaches it as well.
+type menuItem struct {
+	label string
+	key   string
+	run   func(a *App, t *TunnelRecord) (tea.Model, tea.Cmd)
+}
+
+// pressKey runs an action through its existing key binding
+func pressKey(msg tea.KeyMsg) func(a *App, t *TunnelRecord) (tea.Model, tea.Cmd) {
+	return func(a *App, t *TunnelRecord) (tea.Model, tea.Cmd) {
+		return a.Update(msg)
+	}
+}
+
+func runeKey(key string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// menuItems lists the actions that make sense for a tunnel in its state
+func menuItems(t *TunnelRecord) []menuItem {
+	items := make([]menuItem, 0, 8)
+	switch t.Status {
+	case "active", "connecting":
+		items = append(items,
+			menuItem{label: "Stop", key: "enter", run: pressKey(tea.KeyMsg{Type: tea.KeyEnter})},
+			menuItem{label: "Restart", run: (*App).restartTunnel},
+		)
+	default:
+		items = append(items,
+			menuItem{label: "Start", key: "enter", run: pressKey(tea.KeyMsg{Type: tea.KeyEnter})},
+		)
+	}
+
+	items = append(items,
+		menuItem{label: "Copy endpoint", run: (*App).copyEndpoint},
+		menuItem{label: "Open in browser", key: "o", run: pressKey(runeKey("o"))},
+		menuItem{label: "View logs", run: (*App).viewTunnelLogs},
+	)
+	if t.Status != "active" && t.Status != "connecting" {
+		items = append(items, menuItem{label: "Edit", key: "e", run: pressKey(runeKey("e"))})
+	}
+	items = append(items, menuItem{label: "Duplicate", run: (*App).duplicateTunnel})
+	if t.Status != "active" {
+		items = append(items, menuItem{label: "Delete", key: "delete", run: pressKey(tea.KeyMsg{Type: tea.KeyDelete})})
+	}
+	return items
+}
+
+func (a *App) openMenu() {
+	selected := a.selectedRecord()
+	if selected == nil {
+		return
+	}
+	a.menuID = selected.ID
+	a.menuItems = menuItems(selected)
+	a.menuCursor = 0
+	a.showMenu = true
+}
+
+func (a *App) handleMenuKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.menuCursor > 0 {
+			a.menuCursor--
+		}
+	case "down", "j":
+		if a.menuCursor < len(a.menuItems)-1 {
+			a.menuCursor++
+		}
+	case "enter":
+		a.showMenu = false
+		selected := a.selectedRecord()
+		if selected == nil || selected.ID != a.menuID {
+			return a, nil
+		}
+		return a.menuItems[a.menuCursor].run(a, selected)
+	case "esc", "ctrl+c", "m":
+		a.showMenu = false
+	}
+	return a, nil
+}
+
+func (a *App) menuView() string {
+	name := ""
+	if selected := a.selectedRecord(); selected != nil {
+		name = selected.Config.Name
+	}
+	content := dialogActiveStyle.Render("Actions for "+name) + "\n\n"
+	for i, item := range a.menuItems {
+		line := fmt.Sprintf("%-18s", item.label)
+		if item.key != "" {
+			line += controlsStyle.Render(item.key)
+		}
+		if i == a.menuCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += "\n↑/↓: Move • Enter: Run • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(50).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
+
+func (a *App) restartTunnel(t *TunnelRecord) (tea.Model, tea.Cmd) {
+	cmd := a.confirmStop([]*TunnelRecord{t}, "Restart", func() tea.Cmd {
+		a.Logf("Restarting %s", t.Config.Name)
+		a.stopTunnel(t)
+		return a.startTunnel(t)
+	})
+	a.updateTableRows()
+	return a, cmd
+}
+
+// localEndpoint is where clients reach a tunnel on this machine
+func (a *App) localEndpoint(t *TunnelRecord) string {
+	if addr, ok := a.manager.LocalAddress(t.ID); ok {
+		return addr
+	}
+	host := t.Config.BindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(t.Config.LocalPort))
+}
+
+func (a *App) copyEndpoint(t *TunnelRecord) (tea.Model, tea.Cmd) {
+	endpoint := a.localEndpoint(t)
+	via, err := copyToClipboard(endpoint)
+	if err != nil {
+		a.logError("Failed to copy %s: %v", endpoint, err)
+	} else {
+		a.Logf("Copied %s to the clipboard%s", endpoint, via)
+	}
+	return a, nil
+}
+
+// viewTunnelLogs opens the console filtered to the tunnel
+func (a *App) viewTunnelLogs(t *TunnelRecord) (tea.Model, tea.Cmd) {
+	a.filterLogs = true
+	a.autoScroll = true
+	a.logCursor = len(a.getAllFilteredLogs()) - 1
+	if a.showConsole {
+		a.updateViewport()
+		return a, nil
+	}
+	a.showConsole = true
+	a.updateViewport()
+	return a.Update(tea.WindowSizeMsg{Width: a.width, Height: a.height})
+}
+
+// duplicateTunnel adds a stopped copy of a tunnel under a new name, on the
+// next local port nothing else uses unless it picks its port at start
+func (a *App) duplicateTunnel(t *TunnelRecord) (tea.Model, tea.Cmd) {
+	names := make(map[string]bool, len(a.tunnels))
+	ports := make(map[int]bool, len(a.tunnels))
+	index := 0
+	for i, other := range a.tunnels {
+		names[other.Config.Name] = true
+		ports[other.Config.LocalPort] = true
+		if other.ID == t.ID {
+			index = i
+		}
+	}
+
+	original := t.Config.Name
+	tc := t.Config
+	tc.Name = original + "-copy"
+	for n := 2; names[tc.Name]; n++ {
+		tc.Name = fmt.Sprintf("%s-copy%d", original, n)
+	}
+	for tc.LocalPort != 0 && ports[tc.LocalPort] && tc.LocalPort < 65535 {
+		tc.LocalPort++
+	}
+
+	record := TunnelRecord{
+		ID:        uuid.New().String(),
+		Status:    "stopped",
+		Config:    tc,
+		Metrics:   "--",
+		Temporary: t.Temporary,
+	}
+	a.tunnels = append(a.tunnels[:index+1], append([]TunnelRecord{record}, a.tunnels[index+1:]...)...)
+	a.Logf("Duplicated %s as %s on port %s", original, tc.Name, a.localPortLabel(&record))
+	a.updateTableRows()
+	a.saveConfig()
+	return a, nil
+}
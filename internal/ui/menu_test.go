@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+)
+
+func menuLabels(items []menuItem) []string {
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.label
+	}
+	return labels
+}
+
+func TestMenuItems(t *testing.T) {
+	stopped := menuLabels(menuItems(&TunnelRecord{Status: "stopped"}))
+	want := []string{"Start", "Copy endpoint", "Open in browser", "View logs", "Edit", "Duplicate", "Delete"}
+	if len(stopped) != len(want) {
+		t.Fatalf("expected %v, got %v", want, stopped)
+	}
+	for i := range want {
+		if stopped[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, stopped)
+			break
+		}
+	}
+
+	active := menuLabels(menuItems(&TunnelRecord{Status: "active"}))
+	for _, label := range active {
+		if label == "Start" || label == "Edit" || label == "Delete" {
+			t.Errorf("unexpected %s for an active tunnel: %v", label, active)
+		}
+	}
+	if active[0] != "Stop" || active[1] != "Restart" {
+		t.Errorf("expected stop and restart first, got %v", active)
+	}
+}
+
+func TestDuplicateTunnel(t *testing.T) {
+	a := &App{
+		loader:  config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml")),
+		manager: ssh.NewTunnelManager(),
+	}
+	defer a.manager.Cleanup()
+	for _, tc := range []config.TunnelConfig{
+		{Name: "db", LocalPort: 5432},
+		{Name: "db-copy", LocalPort: 5433},
+		{Name: "web", LocalPort: 8080},
+	} {
+		a.tunnels = append(a.tunnels, TunnelRecord{ID: tc.Name, Status: "stopped", Config: tc})
+	}
+
+	a.duplicateTunnel(&a.tunnels[0])
+
+	if len(a.tunnels) != 4 {
+		t.Fatalf("expected 4 tunnels, got %d", len(a.tunnels))
+	}
+	dup := a.tunnels[1]
+	if dup.Config.Name != "db-copy2" || dup.Config.LocalPort != 5434 {
+		t.Errorf("expected db-copy2 on 5434 after the original, got %s on %d", dup.Config.Name, dup.Config.LocalPort)
+	}
+	if dup.ID == "db" || dup.Status != "stopped" {
+		t.Errorf("expected a new stopped tunnel, got %+v", dup)
+	}
+
+	saved, err := a.loader.Load()
+	if err != nil || len(saved) != 4 {
+		t.Errorf("expected the duplicate to be saved, got %d tunnels (%v)", len(saved), err)
+	}
+}
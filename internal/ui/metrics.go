@@ -0,0 +1,25 @@
+package ui
+
+import "tunnel9/internal/metrics"
+
+// metricsSamples reports the tunnels for the Prometheus exporter
+func metricsSamples(tunnels []TunnelRecord) []metrics.Sample {
+	samples := make([]metrics.Sample, 0, len(tunnels))
+	for _, t := range tunnels {
+		samples = append(samples, metrics.Sample{
+			Name:             t.Config.Name,
+			Tag:              t.Config.Tag,
+			State:            t.Status,
+			Health:           t.Values.Health,
+			BytesIn:          t.Values.BytesIn,
+			BytesOut:         t.Values.BytesOut,
+			Connections:      t.Values.Connections,
+			ConnectionsTotal: t.Values.ConnectionsTotal,
+			Latency:          t.Values.Latency,
+			AuthFailures:     t.Values.Handshakes.Auth,
+			NetworkFailures:  t.Values.Handshakes.Network,
+			HostKeyFailures:  t.Values.Handshakes.HostKey,
+		})
+	}
+	return samples
+}
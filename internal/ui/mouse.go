@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Two clicks on a row this close together toggle its tunnel, like enter
+const doubleClickInterval = 400 * time.Millisecond
+
+// screenLayout is where View last drew the table and the console, for
+// finding what a click landed on. There's no table while a dialog or another
+// view covers the screen.
+type screenLayout struct {
+	tableTop   int      // Screen line of the table's header
+	tableLines []string // The table as drawn, header included
+	consoleTop int      // Screen line of the console, 0 when hidden
+}
+
+// handleMouse selects the clicked row, toggles it on a double click, sorts by
+// a clicked column header and scrolls the table or console under the wheel
+func (a *App) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if a.layout.tableLines == nil || msg.Action != tea.MouseActionPress {
+		return a, nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		if a.showConsole && a.layout.consoleTop > 0 && msg.Y >= a.layout.consoleTop {
+			key := "["
+			if msg.Button == tea.MouseButtonWheelDown {
+				key = "]"
+			}
+			return a.Update(runeKey(key))
+		}
+		if msg.Button == tea.MouseButtonWheelUp {
+			a.table.MoveUp(1)
+		} else {
+			a.table.MoveDown(1)
+		}
+		return a, nil
+
+	case tea.MouseButtonLeft:
+		line := msg.Y - a.layout.tableTop
+		if line < 0 || line >= len(a.layout.tableLines) {
+			return a, nil
+		}
+		if line < tableHeaderLines {
+			if col := a.columnAt(msg.X); col >= 0 {
+				a.sortBy(col)
+			}
+			return a, nil
+		}
+
+		row := a.rowAt(a.layout.tableLines[line])
+		if row < 0 {
+			return a, nil
+		}
+		double := row == a.lastClickRow && time.Since(a.lastClick) < doubleClickInterval
+		a.table.SetCursor(row)
+		if double {
+			a.lastClick = time.Time{}
+			return a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+		a.lastClick, a.lastClickRow = time.Now(), row
+	}
+	return a, nil
+}
+
+// columnAt returns the table column drawn at screen column x, or -1. Every
+// visible column takes its width and one space of padding.
+func (a *App) columnAt(x int) int {
+	left := 0
+	for i, col := range a.table.Columns() {
+		if col.Width <= 0 {
+			continue
+		}
+		if x < left+col.Width+1 {
+			return i
+		}
+		left += col.Width + 1
+	}
+	return -1
+}
+
+// rowAt returns the table row drawn as line, or -1 for lines that aren't a
+// row, like those of an expanded row. Of identical rows it picks the one
+// closest to the cursor.
+func (a *App) rowAt(line string) int {
+	want := collapseSpace(ansi.Strip(line))
+	if want == "" {
+		return -1
+	}
+	cursor := a.table.Cursor()
+	found := -1
+	for i, row := range a.table.Rows() {
+		if a.rowText(row) != want {
+			continue
+		}
+		if found < 0 || abs(i-cursor) < abs(found-cursor) {
+			found = i
+		}
+	}
+	return found
+}
+
+// sortBy sorts by a column, or reverses the order when already sorted by it
+func (a *App) sortBy(col int) {
+	if col == a.sortColumn {
+		a.sortReverse = !a.sortReverse
+	} else {
+		a.sortColumn = col
+		if a.secondarySort == col {
+			a.secondarySort = -1
+		}
+	}
+	a.sortTunnels()
+	a.updateTableRows()
+}
+
+// recordLayout notes where the table is drawn, given the view drawn above it
+func (a *App) recordLayout(aboveTable string, tableView string) {
+	a.layout = screenLayout{
+		tableTop:   strings.Count(aboveTable, "\n"),
+		tableLines: strings.Split(tableView, "\n"),
+	}
+}
+
+// fitLayout accounts for the top of a view taller than the terminal, which
+// Bubble Tea leaves out
+func (a *App) fitLayout(view string) {
+	if cut := strings.Count(view, "\n") + 1 - a.height; a.height > 0 && cut > 0 {
+		a.layout.tableTop -= cut
+		if a.layout.consoleTop > 0 {
+			a.layout.consoleTop -= cut
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestMouseSelectsAndSorts(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.width, a.height = 120, 40
+	a.table.SetStyles(tableStyles())
+	a.sortColumn = unsorted
+
+	lineOf := func(text string) int {
+		for i, line := range strings.Split(ansi.Strip(a.View()), "\n") {
+			if strings.Contains(line, text) {
+				return i
+			}
+		}
+		t.Fatalf("%q not drawn", text)
+		return -1
+	}
+	click := func(x, y int) {
+		a.Update(tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	}
+
+	click(2, lineOf("tunnel-2"))
+	if a.table.Cursor() != 2 {
+		t.Fatalf("expected the clicked row selected, got %d", a.table.Cursor())
+	}
+
+	a.Update(tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonWheelUp, Y: lineOf("tunnel-0")})
+	if a.table.Cursor() != 1 {
+		t.Errorf("expected the wheel to move the selection up, got %d", a.table.Cursor())
+	}
+
+	// NAME starts after STATUS and its padding
+	header := lineOf("NAME")
+	click(9, header)
+	if a.sortColumn != 1 || a.sortReverse {
+		t.Fatalf("expected sorted by name, got column %d", a.sortColumn)
+	}
+	click(9, header)
+	if !a.sortReverse || a.tunnels[0].ID != "3" {
+		t.Errorf("expected a second click to reverse the order, got %s first", a.tunnels[0].ID)
+	}
+}
+
+func TestMouseDoubleClickToggles(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.width, a.height = 120, 40
+	a.table.SetStyles(tableStyles())
+	a.groupByTag = true
+	a.updateTableRows()
+
+	view := strings.Split(ansi.Strip(a.View()), "\n")
+	header := -1
+	for i, line := range view {
+		if strings.Contains(line, "untagged (2)") {
+			header = i
+		}
+	}
+	if header < 0 {
+		t.Fatal("group header not drawn")
+	}
+
+	press := tea.MouseMsg{X: 2, Y: header, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft}
+	a.Update(press)
+	if len(a.table.Rows()) != 3 {
+		t.Fatal("expected a single click only to select")
+	}
+	a.Update(press)
+	if len(a.table.Rows()) != 1 {
+		t.Errorf("expected a double click on the header to fold the group, got %d rows", len(a.table.Rows()))
+	}
+}
+
+func TestMouseIgnoredUnderDialogs(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.width, a.height = 120, 40
+	a.table.SetStyles(tableStyles())
+	a.View()
+	a.showHelp = true
+	a.View()
+	a.Update(tea.MouseMsg{X: 2, Y: 4, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	if a.table.Cursor() != 0 {
+		t.Errorf("expected clicks behind the help to do nothing, got %d", a.table.Cursor())
+	}
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+	"tunnel9/internal/mqtt"
+)
+
+// How often metrics are published when the config doesn't say
+const defaultMQTTMetricsInterval = 10 * time.Second
+
+// mqttState is what is published, retained, for each tunnel when it changes
+type mqttState struct {
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	LocalPort int       `json:"local_port"`
+	Time      time.Time `json:"time"`
+}
+
+// mqttMetrics is published for active tunnels every metrics interval
+type mqttMetrics struct {
+	RateIn    float64 `json:"rate_in"`  // bytes per second
+	RateOut   float64 `json:"rate_out"` // bytes per second
+	LatencyMs int64   `json:"latency_ms"`
+}
+
+// mqttBridge publishes tunnel state changes and metrics, one topic per tunnel
+type mqttBridge struct {
+	publisher   *mqtt.Publisher
+	prefix      string
+	interval    time.Duration
+	lastMetrics time.Time
+	published   map[string]string // Tunnel ID to the last status published
+}
+
+// newMQTTBridge connects to the configured broker, or returns nil if there
+// is none. Problems are logged through the event bus, as they are reported
+// from the publisher's goroutine.
+func newMQTTBridge(cfg config.MQTTConfig, machine string, bus *events.Bus) *mqttBridge {
+	if cfg.Broker == "" {
+		return nil
+	}
+
+	prefix := cfg.TopicPrefix
+	if prefix == "" {
+		prefix = "tunnel9/" + mqtt.TopicName(machine)
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "tunnel9-" + mqtt.TopicName(machine)
+	}
+	interval := defaultMQTTMetricsInterval
+	if parsed, err := time.ParseDuration(cfg.MetricsInterval); err == nil && parsed > 0 {
+		interval = parsed
+	}
+
+	logf := func(level events.Level, format string, args ...interface{}) {
+		bus.Publish(events.Event{
+			Kind:    events.KindLog,
+			Level:   level,
+			Message: fmt.Sprintf("%s %s [mqtt] %s", time.Now().Format("15:04:05"), level, fmt.Sprintf(format, args...)),
+		})
+	}
+	password, err := config.ResolveSecret(cfg.Password)
+	if err != nil {
+		logf(events.LevelError, "Not publishing, password: %v", err)
+		return nil
+	}
+
+	// The broker says offline for us if we vanish, and we say online on
+	// every connect
+	opts := mqtt.Options{
+		ClientID: clientID,
+		Username: cfg.Username,
+		Password: password,
+		Will:     &mqtt.Will{Topic: prefix + "/status", Payload: []byte("offline"), Retain: true},
+	}
+	online := &mqtt.Will{Topic: prefix + "/status", Payload: []byte("online"), Retain: true}
+
+	return &mqttBridge{
+		publisher: mqtt.NewPublisher(cfg.Broker, opts, online, logf),
+		prefix:    prefix,
+		interval:  interval,
+		published: make(map[string]string),
+	}
+}
+
+func (b *mqttBridge) topic(t *TunnelRecord, leaf string) string {
+	return fmt.Sprintf("%s/%s/%s", b.prefix, mqtt.TopicName(t.Config.Name), leaf)
+}
+
+func (b *mqttBridge) publishJSON(topic string, v interface{}, retain bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	b.publisher.Publish(topic, data, retain)
+}
+
+func (b *mqttBridge) publishState(t *TunnelRecord, now time.Time) {
+	b.published[t.ID] = t.Status
+	b.publishJSON(b.topic(t, "state"), mqttState{
+		Status:    t.Status,
+		Message:   t.Metrics,
+		LocalPort: t.Config.LocalPort,
+		Time:      now,
+	}, true)
+}
+
+// update publishes tunnels whose status changed since last time, and the
+// metrics of active ones when they are due
+func (b *mqttBridge) update(tunnels []TunnelRecord, now time.Time) {
+	for i := range tunnels {
+		t := &tunnels[i]
+		if last, ok := b.published[t.ID]; !ok || last != t.Status {
+			b.publishState(t, now)
+		}
+	}
+
+	if now.Sub(b.lastMetrics) < b.interval {
+		return
+	}
+	b.lastMetrics = now
+	for i := range tunnels {
+		t := &tunnels[i]
+		if t.Status != "active" {
+			continue
+		}
+		b.publishJSON(b.topic(t, "metrics"), mqttMetrics{
+			RateIn:    t.Values.RateIn,
+			RateOut:   t.Values.RateOut,
+			LatencyMs: t.Values.Latency.Milliseconds(),
+		}, false)
+	}
+}
+
+// close reports every tunnel stopped and the machine offline, then
+// disconnects
+func (b *mqttBridge) close(tunnels []TunnelRecord) {
+	now := time.Now()
+	for i := range tunnels {
+		t := tunnels[i]
+		t.Status = "stopped"
+		t.Metrics = ""
+		b.publishState(&t, now)
+	}
+	b.publisher.Publish(b.prefix+"/status", []byte("offline"), true)
+	b.publisher.Close()
+}
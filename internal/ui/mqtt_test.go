@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+)
+
+func TestMQTTBridgePublishesChanges(t *testing.T) {
+	if newMQTTBridge(config.MQTTConfig{}, "laptop", events.NewBus()) != nil {
+		t.Fatal("expected no bridge without a broker")
+	}
+
+	// Nothing listens here, messages are queued and dropped on close
+	bridge := newMQTTBridge(config.MQTTConfig{Broker: "127.0.0.1:1"}, "laptop", events.NewBus())
+	defer bridge.close(nil)
+
+	if bridge.prefix != "tunnel9/laptop" || bridge.interval != defaultMQTTMetricsInterval {
+		t.Errorf("unexpected defaults %q every %s", bridge.prefix, bridge.interval)
+	}
+
+	record := TunnelRecord{ID: "1", Status: "stopped"}
+	record.Config.Name = "prod/db"
+	if got := bridge.topic(&record, "state"); got != "tunnel9/laptop/prod_db/state" {
+		t.Errorf("unexpected topic %s", got)
+	}
+
+	tunnels := []TunnelRecord{record}
+	now := time.Now()
+	bridge.update(tunnels, now)
+	if bridge.published["1"] != "stopped" {
+		t.Errorf("expected the initial state to be published, got %v", bridge.published)
+	}
+
+	tunnels[0].Status = "active"
+	bridge.update(tunnels, now.Add(time.Second))
+	if bridge.published["1"] != "active" {
+		t.Errorf("expected the change to be published, got %v", bridge.published)
+	}
+}
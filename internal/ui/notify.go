@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/notify"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notifyMsg reports a desktop or webhook notification that couldn't be sent
+type notifyMsg struct {
+	sink   string
+	tunnel string
+	err    error
+}
+
+// noteTransition passes a tunnel's state change to wherever the
+// notifications config routes it
+func (a *App) noteTransition(t *TunnelRecord, from string) tea.Cmd {
+	if from == t.Status {
+		if a.emailer != nil {
+			a.emailer.Refresh(t.Config.Name, t.Status, t.Metrics)
+		}
+		return nil
+	}
+
+	var notifications config.NotificationsConfig
+	if a.loader != nil {
+		notifications = a.loader.Config().Notifications
+	}
+	event := notify.Event{
+		Machine:  a.machine,
+		Tunnel:   t.Config.Name,
+		Tag:      t.Config.Tag,
+		From:     from,
+		To:       t.Status,
+		Severity: config.Severity(from, t.Status),
+		Message:  t.Metrics,
+		Time:     time.Now(),
+	}
+	var cmds []tea.Cmd
+	for _, send := range notify.Route(notifications, event) {
+		if send.Sink == config.SinkEmail {
+			if a.emailer != nil {
+				a.emailer.Add(notify.NewAlert(event))
+			}
+			continue
+		}
+		cmds = append(cmds, func() tea.Msg {
+			return notifyMsg{sink: send.Sink, tunnel: event.Tunnel, err: send.Deliver(event)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+func (a *App) handleNotify(msg notifyMsg) {
+	if msg.err != nil {
+		a.logError("Failed to send %s notification for %s: %v", msg.sink, msg.tunnel, msg.err)
+	}
+}
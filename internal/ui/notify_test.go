@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/notify"
+)
+
+func TestNoteTransitionFollowsRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+email:
+  smtp_host: smtp.example.com
+  to: [ops@example.com]
+  cooldown: 0s
+notifications:
+  routes:
+    - tags: [homelab]
+    - tags: [prod]
+      sinks: [email]
+tunnels: []
+`), 0644)
+	loader := config.NewConfigLoader(path)
+	if _, err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{loader: loader, emailer: notify.NewEmailer(loader.Config().Email, "jump-vm")}
+
+	change := func(tag string, from string, to string) {
+		record := TunnelRecord{ID: tag, Status: to}
+		record.Config.Name = tag
+		record.Config.Tag = tag
+		if cmd := a.noteTransition(&record, from); cmd != nil {
+			t.Errorf("expected no background sends for %s", tag)
+		}
+	}
+	change("homelab", "active", "error")
+	change("prod", "stopped", "connecting")
+	change("other", "active", "error")
+
+	alerts := a.emailer.Take(time.Now().Add(time.Hour))
+	if len(alerts) != 2 || alerts[0].Tunnel != "prod" || alerts[1].Tunnel != "other" {
+		t.Errorf("expected prod's routed email and other's failure, got %+v", alerts)
+	}
+}
+
+func TestNoteTransitionPostsEveryChangeToTheWebhook(t *testing.T) {
+	received := make(chan notify.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+notifications:
+  webhook: `+server.URL+`
+  routes:
+    - tags: [homelab]
+tunnels: []
+`), 0644)
+	loader := config.NewConfigLoader(path)
+	if _, err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{loader: loader, machine: "jump-vm"}
+
+	record := TunnelRecord{ID: "nas", Status: "error", Metrics: "connection refused"}
+	record.Config.Name = "nas"
+	record.Config.Tag = "homelab"
+	cmd := a.noteTransition(&record, "active")
+	if cmd == nil {
+		t.Fatal("expected the webhook to be sent, though the route keeps it quiet")
+	}
+	if msg := cmd().(notifyMsg); msg.err != nil {
+		t.Fatalf("webhook failed: %v", msg.err)
+	}
+	event := <-received
+	if event.Tunnel != "nas" || event.From != "active" || event.To != "error" || event.Message != "connection refused" || event.Machine != "jump-vm" || event.Time.IsZero() {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	record.Status = "error"
+	if cmd := a.noteTransition(&record, "error"); cmd != nil {
+		t.Errorf("expected nothing sent without a change")
+	}
+}
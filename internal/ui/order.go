@@ -0,0 +1,106 @@
+package ui
+
+import "sort"
+
+// unsorted is the sort column that keeps the tunnels in the order of the
+// config file, which moving rows changes
+const unsorted = -1
+
+// manualOrder returns the tunnels in their own order, the one saved to the
+// config file. Tunnels added while sorted by a column come last.
+func (a *App) manualOrder() []TunnelRecord {
+	if a.manualIDs == nil {
+		return a.tunnels
+	}
+	rank := make(map[string]int, len(a.manualIDs))
+	for i, id := range a.manualIDs {
+		rank[id] = i
+	}
+	position := func(t *TunnelRecord) int {
+		if i, ok := rank[t.ID]; ok {
+			return i
+		}
+		return len(rank)
+	}
+	ordered := append([]TunnelRecord(nil), a.tunnels...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return position(&ordered[i]) < position(&ordered[j])
+	})
+	return ordered
+}
+
+// rememberManualOrder notes the tunnels' own order before sorting by a
+// column shuffles them
+func (a *App) rememberManualOrder() {
+	if a.manualIDs != nil {
+		return
+	}
+	a.manualIDs = make([]string, len(a.tunnels))
+	for i, t := range a.tunnels {
+		a.manualIDs[i] = t.ID
+	}
+}
+
+// stepSortColumn cycles the sort column by delta, passing through unsorted
+func (a *App) stepSortColumn(delta int) {
+	count := len(a.table.Columns())
+	a.sortColumn += delta
+	if a.sortColumn >= count {
+		a.sortColumn = unsorted
+	} else if a.sortColumn < unsorted {
+		a.sortColumn = count - 1
+	}
+	if a.sortColumn == a.secondarySort {
+		a.secondarySort = -1
+	}
+}
+
+// moveSelected moves the selected tunnel past its neighbour in view, up for
+// a negative delta, and saves the new order. Sorted by a column, it goes back
+// to the tunnels' own order first.
+func (a *App) moveSelected(delta int) {
+	selected := a.selectedRecord()
+	if selected == nil {
+		return
+	}
+	id := selected.ID
+	if a.sortColumn != unsorted {
+		a.sortColumn = unsorted
+		a.sortTunnels()
+		a.updateTableRows()
+		a.selectEntry(func(e tableEntry) bool { return e.tunnel != nil && e.tunnel.ID == id })
+		a.Logf("Showing tunnels in their own order")
+	}
+
+	entries := a.tableEntries()
+	cursor := a.table.Cursor()
+	neighbour := cursor + delta
+	// Group headers stop a tunnel from leaving its group
+	if neighbour < 0 || neighbour >= len(entries) || entries[neighbour].tunnel == nil {
+		return
+	}
+	from, to := -1, -1
+	for i := range a.tunnels {
+		switch a.tunnels[i].ID {
+		case id:
+			from = i
+		case entries[neighbour].tunnel.ID:
+			to = i
+		}
+	}
+	if from < 0 || to < 0 {
+		return
+	}
+
+	// Rotate rather than swap, so tunnels hidden in between keep their order
+	moved := a.tunnels[from]
+	if from < to {
+		copy(a.tunnels[from:to], a.tunnels[from+1:to+1])
+	} else {
+		copy(a.tunnels[to+1:from+1], a.tunnels[to:from])
+	}
+	a.tunnels[to] = moved
+	a.updateTableRows()
+	a.table.SetCursor(neighbour)
+	a.saveConfig()
+}
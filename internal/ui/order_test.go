@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMoveSelected(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.sortColumn = unsorted
+	a.table.SetCursor(2)
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'K'}})
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'K'}})
+	if got := tunnelIDs(a.tunnels); got != "2013" || a.table.Cursor() != 0 {
+		t.Fatalf("expected tunnel 2 moved to the top, got %s at %d", got, a.table.Cursor())
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'K'}})
+	if got := tunnelIDs(a.tunnels); got != "2013" {
+		t.Errorf("expected the top row to stay, got %s", got)
+	}
+
+	saved, err := a.loader.Load()
+	if err != nil || len(saved) != 4 || saved[0].Name != "tunnel-2" {
+		t.Fatalf("expected the order saved, got %v, %v", saved, err)
+	}
+
+	// Sorting by a column and back restores the own order
+	a.sortColumn = 1
+	a.sortReverse = true
+	a.sortTunnels()
+	if got := tunnelIDs(a.tunnels); got != "3210" {
+		t.Fatalf("expected sorted by name, got %s", got)
+	}
+	if configs := a.persistentConfigs(); configs[0].Name != "tunnel-2" || configs[3].Name != "tunnel-3" {
+		t.Errorf("expected saving to keep the own order while sorted, got %s first", configs[0].Name)
+	}
+	a.stepSortColumn(-1)
+	a.stepSortColumn(-1)
+	if a.sortColumn != unsorted {
+		t.Fatalf("expected the unsorted mode before the first column, got %d", a.sortColumn)
+	}
+	a.sortTunnels()
+	if got := tunnelIDs(a.tunnels); got != "2013" {
+		t.Errorf("expected the own order back, got %s", got)
+	}
+
+	// Moving while sorted goes back to the own order first
+	a.sortColumn = 1
+	a.sortTunnels()
+	a.updateTableRows()
+	a.table.SetCursor(0) // tunnel-3
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'J'}})
+	if got := tunnelIDs(a.tunnels); got != "2013" || a.sortColumn != unsorted {
+		t.Errorf("expected the last tunnel to stay last in the own order, got %s", got)
+	}
+}
+
+func tunnelIDs(tunnels []TunnelRecord) string {
+	ids := ""
+	for _, t := range tunnels {
+		ids += t.ID
+	}
+	return ids
+}
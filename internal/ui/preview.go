@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var previewStyle = lipgloss.NewStyle().
+	Foreground(activeTheme.faint)
+
+// dialogPreviewConfig builds a best-effort config from the dialog as typed so
+// far, ignoring anything that doesn't parse yet
+func (a *App) dialogPreviewConfig() (config.TunnelConfig, bool) {
+	if a.dialogFields[0].value == "ssh" {
+		parsed, err := parseSshString(a.dialogFields[1].value)
+		if err != nil {
+			return config.TunnelConfig{}, false
+		}
+		parsed.SSHConfigIgnore = parseIgnoreList(a.dialogFields[11].value)
+		return *parsed, true
+	}
+
+	var tc config.TunnelConfig
+	tc.LocalPort, _ = strconv.Atoi(a.dialogFields[3].value)
+	tc.RemoteHost = a.dialogFields[4].value
+	tc.RemotePort, _ = strconv.Atoi(a.dialogFields[5].value)
+	tc.Bastion.Host = a.dialogFields[6].value
+	tc.Bastion.Port, _ = strconv.Atoi(a.dialogFields[7].value)
+	tc.Bastion.User = a.dialogFields[8].value
+	tc.SSHConfigIgnore = parseIgnoreList(a.dialogFields[11].value)
+	if tc.Bastion.Host != "" && tc.Bastion.Port == 0 {
+		tc.Bastion.Port = 22
+	}
+
+	if tc.RemoteHost == "" && tc.Bastion.Host == "" {
+		return tc, false
+	}
+	return tc, true
+}
+
+// effectivePreview shows what the ssh layer will really connect to once
+// ~/.ssh/config has had its say, since those overrides are otherwise silent
+func (a *App) effectivePreview() string {
+	tc, ok := a.dialogPreviewConfig()
+	if !ok {
+		return ""
+	}
+
+	settings, err := ssh.ResolveSSHSettings(tc)
+	if err != nil {
+		return previewStyle.Render(fmt.Sprintf("Effective settings unavailable: %v", err)) + "\n"
+	}
+
+	lines := []string{
+		"Effective settings (after ~/.ssh/config):",
+		fmt.Sprintf("  SSH Server:    %s", settings.SSHEndpoint().String()),
+		fmt.Sprintf("  User:          %s", settings.User),
+		fmt.Sprintf("  Remote:        %s", settings.RemoteEndpoint().String()),
+		fmt.Sprintf("  Identity File: %s", strings.Join(settings.IdentityFiles, ", ")),
+	}
+	for _, note := range settings.Notes {
+		lines = append(lines, "  * "+note)
+	}
+	return previewStyle.Render(strings.Join(lines, "\n")) + "\n"
+}
+
+// portClashWarning warns when the local port typed in the dialog is one
+// another tunnel of the config listens on too
+func (a *App) portClashWarning() string {
+	var tc config.TunnelConfig
+	if a.dialogFields[0].value == "ssh" {
+		parsed, err := parseSshString(a.dialogFields[1].value)
+		if err != nil {
+			return ""
+		}
+		tc = *parsed
+	} else {
+		tc.BindAddress = a.dialogFields[2].value
+		tc.LocalPort, _ = strconv.Atoi(a.dialogFields[3].value)
+	}
+	if a.dialogMode == modeEdit && a.editingIndex < len(a.tunnels) {
+		// Settings the dialog doesn't show still decide what clashes
+		tc.Type = a.tunnels[a.editingIndex].Config.Type
+		tc.BindInterface = a.tunnels[a.editingIndex].Config.BindInterface
+	}
+
+	var names []string
+	for i, t := range a.tunnels {
+		if a.dialogMode == modeEdit && i == a.editingIndex {
+			continue
+		}
+		if tc.ClashesWith(t.Config) {
+			names = append(names, t.Config.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(activeTheme.warning).
+		Render(fmt.Sprintf("Local port %d is also used by %s, only one can run at a time", tc.LocalPort, strings.Join(names, ", "))) + "\n"
+}
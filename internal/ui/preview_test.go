@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestPortClashWarning(t *testing.T) {
+	a := newExpandApp(t, 3, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+
+	a.initDialog(modeNew)
+	a.dialogFields[3].value = "8001"
+	if warning := a.portClashWarning(); !strings.Contains(warning, "Local port 8001 is also used by tunnel-1") {
+		t.Errorf("expected a warning about tunnel-1, got %q", warning)
+	}
+	a.dialogFields[2].value = "10.0.0.5"
+	if warning := a.portClashWarning(); warning != "" {
+		t.Errorf("expected no warning on another address, got %q", warning)
+	}
+
+	a.dialogFields[0].value = "ssh"
+	a.dialogFields[1].value = "ssh -N -L 8002:db.internal:5432 ops@jump"
+	if warning := a.portClashWarning(); !strings.Contains(warning, "tunnel-2") {
+		t.Errorf("expected a warning from the SSH command, got %q", warning)
+	}
+
+	// A tunnel being edited doesn't clash with itself
+	a.table.SetCursor(1)
+	a.initDialog(modeEdit)
+	if warning := a.portClashWarning(); warning != "" {
+		t.Errorf("expected no warning for the tunnel's own port, got %q", warning)
+	}
+	a.dialogFields[3].value = "8000"
+	if warning := a.portClashWarning(); !strings.Contains(warning, "tunnel-0") {
+		t.Errorf("expected a warning about tunnel-0, got %q", warning)
+	}
+}
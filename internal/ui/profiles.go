@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"os"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openProfiles lists the profiles to switch to, starting at the current one
+func (a *App) openProfiles() {
+	profiles, err := config.ListProfiles()
+	if err != nil {
+		a.logError("Failed to list profiles: %v", err)
+	}
+	a.profiles = profiles
+	a.profileCursor = 0
+	current := config.ProfileName(a.loader.Path())
+	for i, name := range profiles {
+		if name == current {
+			a.profileCursor = i
+		}
+	}
+	a.showProfiles = true
+}
+
+func (a *App) handleProfilesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.profileCursor > 0 {
+			a.profileCursor--
+		}
+	case "down", "j":
+		if a.profileCursor < len(a.profiles)-1 {
+			a.profileCursor++
+		}
+	case "enter":
+		a.showProfiles = false
+		if a.profileCursor < len(a.profiles) {
+			return a, a.switchProfile(a.profiles[a.profileCursor])
+		}
+	case "esc", "ctrl+c", "P":
+		a.showProfiles = false
+	}
+	return a, nil
+}
+
+// profilePath is the config file of a profile
+func profilePath(name string) (string, error) {
+	if name == config.DefaultProfile {
+		return config.FindConfigFile(""), nil
+	}
+	return config.ProfilePath(name)
+}
+
+// switchProfile stops the current profile's tunnels and loads another's,
+// autostarting those that ask for it. Temporary tunnels belong to the
+// session rather than a profile and carry on.
+func (a *App) switchProfile(name string) tea.Cmd {
+	path, err := profilePath(name)
+	if err != nil {
+		a.logError("%v", err)
+		return nil
+	}
+	if path == a.loader.Path() {
+		return nil
+	}
+	loader := config.NewConfigLoader(path)
+	configs, err := loader.Load()
+	if err != nil && !os.IsNotExist(err) {
+		a.logError("Failed to load profile %s: %v", name, err)
+		return nil
+	}
+
+	toStop := make([]*TunnelRecord, 0)
+	kept := make([]TunnelRecord, 0)
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		if t.Temporary {
+			kept = append(kept, *t)
+		} else if t.Status != "stopped" {
+			toStop = append(toStop, t)
+		}
+	}
+	a.stopInBackground(toStop)
+
+	a.loader = loader
+	a.tunnels = append(convertConfigsToRecords(configs), kept...)
+	a.applySettings()
+	ssh.SetIdentityFiles(loader.Config().SSH.IdentityFiles)
+	ssh.SetPassphrases(loader.Config().SSH.Passphrases)
+	ssh.SetDefaultMetrics(loader.Config().Metrics)
+	if err := ssh.SetResolver(loader.Config().DNS); err != nil {
+		a.logError("%v", err)
+	}
+	a.currentTag = ""
+	a.recordPorts()
+
+	// Groups on their way up belonged to the other profile
+	a.groupRuns = nil
+
+	a.Logf("Switched to profile %s, %d tunnel(s) from %s", name, len(configs), path)
+	return a.autostartTunnels()
+}
+
+func (a *App) profilesView() string {
+	content := dialogActiveStyle.Render("Profiles") + "\n\n"
+	current := config.ProfileName(a.loader.Path())
+	for i, name := range a.profiles {
+		line := name
+		if name == current {
+			line += previewStyle.Render("  (current)")
+		}
+		if i == a.profileCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += previewStyle.Render("\nStart tunnel9 with --profile=<name> to create one") + "\n"
+	content += "\n↑/↓: Move • Enter: Switch • Esc/Ctrl+C: Close"
+
+	dialog := dialogStyle.Width(60).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
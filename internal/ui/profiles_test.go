@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestSwitchProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workPath, _ := config.ProfilePath("work")
+	if err := os.MkdirAll(filepath.Dir(workPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(workPath, []byte("tunnels:\n  - name: jira\n    local_port: 8080\n    remote_host: jira.internal\n    remote_port: 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defaultPath := filepath.Join(t.TempDir(), "config.yaml")
+	a := &App{loader: config.NewConfigLoader(defaultPath)}
+	a.tunnels = []TunnelRecord{
+		{ID: "db", Status: "stopped", Config: config.TunnelConfig{Name: "db", LocalPort: 5432}},
+		{ID: "temp", Status: "stopped", Config: config.TunnelConfig{Name: "web", LocalPort: 8081}, Temporary: true},
+	}
+	a.saveConfig()
+
+	a.switchProfile("work")
+	if a.loader.Path() != workPath {
+		t.Fatalf("expected the work profile to be loaded, got %s", a.loader.Path())
+	}
+	if len(a.tunnels) != 2 || a.tunnels[0].Config.Name != "jira" || !a.tunnels[1].Temporary {
+		t.Fatalf("expected jira and the temporary tunnel, got %+v", a.tunnels)
+	}
+
+	// Saving only touches the active profile
+	a.tunnels[0].Config.Tag = "tickets"
+	a.saveConfig()
+	other, err := config.NewConfigLoader(defaultPath).Load()
+	if err != nil || len(other) != 1 || other[0].Name != "db" {
+		t.Errorf("expected the default profile untouched, got %+v (%v)", other, err)
+	}
+	work, err := config.NewConfigLoader(workPath).Load()
+	if err != nil || len(work) != 1 || work[0].Tag != "tickets" {
+		t.Errorf("expected the work profile saved, got %+v (%v)", work, err)
+	}
+}
@@ -0,0 +1,36 @@
+package ui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// initQuickConnect opens the dialog for a quick connect: an ssh command
+// like "ssh -N -L 8080:localhost:80 user@host" that becomes a temporary
+// tunnel, started as soon as it's entered
+func (a *App) initQuickConnect() {
+	a.showDialog = true
+	a.initDialog(modeConnect)
+
+	// The ssh command and a name are all it asks for, it is always temporary
+	a.dialogFields[0].value = "ssh"
+	a.dialogFields[1].isHidden = false
+	for i := 2; i < len(a.dialogFields); i++ {
+		a.dialogFields[i].isHidden = i != 9
+	}
+	a.dialogFields[12].value = "yes"
+	a.activeField = 1
+}
+
+// submitQuickConnect adds the tunnel of the quick connect dialog and starts
+// it, selecting it so its progress is in view
+func (a *App) submitQuickConnect() tea.Cmd {
+	count := len(a.tunnels)
+	a.handleDialogSubmit()
+	if a.showDialog || len(a.tunnels) == count {
+		return nil
+	}
+
+	t := &a.tunnels[len(a.tunnels)-1]
+	cmd := a.startTunnel(t)
+	a.updateTableRows()
+	a.selectEntry(func(e tableEntry) bool { return e.tunnel != nil && e.tunnel.ID == t.ID })
+	return cmd
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestQuickConnect(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.loader = config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml"))
+	a.manager = ssh.NewTunnelManager()
+	defer a.manager.Cleanup()
+
+	a.Update(runeKey("c"))
+	if !a.showDialog || a.dialogMode != modeConnect || a.activeField != 1 {
+		t.Fatalf("expected the quick connect dialog on the ssh command, got mode %v field %d", a.dialogMode, a.activeField)
+	}
+	a.dialogFields[1].value = "ssh -N -L 0:localhost:80 deploy@web.internal"
+	a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if a.showDialog || len(a.tunnels) != 3 {
+		t.Fatalf("expected the tunnel to be added, got %d tunnels", len(a.tunnels))
+	}
+	quick := a.selectedRecord()
+	if quick == nil || quick.ID != a.tunnels[2].ID || !quick.Temporary {
+		t.Fatalf("expected the temporary tunnel to be selected, got %+v", quick)
+	}
+	if quick.Status != "connecting" {
+		t.Errorf("expected it to start right away, got %s", quick.Status)
+	}
+	if _, ok := a.manager.LocalAddress(quick.ID); !ok {
+		t.Error("expected it to listen")
+	}
+	if !quick.Config.AutoPort || a.localPort(quick) == 0 {
+		t.Errorf("expected -L 0 to pick any free port, got %+v", quick.Config)
+	}
+
+	saved, err := a.loader.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range saved {
+		if tc.Name == quick.Config.Name {
+			t.Errorf("expected the quick tunnel not to be saved, got %+v", saved)
+		}
+	}
+}
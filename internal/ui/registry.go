@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/registry"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How many metrics samples, a second apart, between re-reading the shared
+// registry
+const registryRefreshTicks = 15
+
+func (a *App) forwardFor(t *TunnelRecord) registry.Forward {
+	return registry.NewForward(a.machine, t.Config)
+}
+
+func (a *App) registerForward(t *TunnelRecord) {
+	if a.registry == nil {
+		return
+	}
+	if err := a.registry.Register(a.forwardFor(t)); err != nil {
+		a.logError("Failed to register %s: %v", t.Config.Name, err)
+	}
+}
+
+func (a *App) unregisterForward(t *TunnelRecord) {
+	if a.registry == nil {
+		return
+	}
+	if err := a.registry.Unregister(a.forwardFor(t)); err != nil {
+		a.logError("Failed to unregister %s: %v", t.Config.Name, err)
+	}
+}
+
+// recordPorts tells the port registry which local ports this config uses
+func (a *App) recordPorts() {
+	if a.ports == nil || a.loader.Path() == "" {
+		return
+	}
+	if err := a.ports.Record(a.loader.Path(), a.persistentConfigs()); err != nil {
+		a.logError("Failed to record local ports: %v", err)
+	}
+}
+
+// warnPortCollisions points out tunnels in other config files that use the
+// same local port as tc, since only one of them can run at a time
+func (a *App) warnPortCollisions(tc *config.TunnelConfig) {
+	if a.ports == nil || a.loader.Path() == "" {
+		return
+	}
+	collisions, err := a.ports.Collisions(a.loader.Path(), tc.LocalPort)
+	if err != nil {
+		a.logError("Failed to read the port registry: %v", err)
+		return
+	}
+	for _, use := range collisions {
+		a.logError("Local port %d of %s is also used by %s in %s", tc.LocalPort, tc.Name, use.Name, use.Config)
+	}
+}
+
+// refreshRegistry announces this machine's forwards again, so they don't go
+// stale, and re-reads what the rest of the team is exposing
+func (a *App) refreshRegistry() {
+	if a.registry == nil {
+		return
+	}
+	for i := range a.tunnels {
+		if a.tunnels[i].Status != "stopped" {
+			a.registerForward(&a.tunnels[i])
+		}
+	}
+	forwards, err := a.registry.List()
+	if err != nil {
+		a.logError("Failed to read registry: %v", err)
+		return
+	}
+	a.remoteForwards = forwards
+}
+
+// remoteDuplicates returns forwards on other machines exposing the same target
+func (a *App) remoteDuplicates(t *TunnelRecord) []registry.Forward {
+	if a.registry == nil {
+		return nil
+	}
+	return registry.Duplicates(a.remoteForwards, a.forwardFor(t))
+}
+
+// shareConflicts returns the remote forwards that starting t as a 0.0.0.0
+// share would duplicate
+func (a *App) shareConflicts(t *TunnelRecord) []registry.Forward {
+	if a.registry == nil || !a.forwardFor(t).IsShared() {
+		return nil
+	}
+	a.refreshRegistry()
+	return a.remoteDuplicates(t)
+}
+
+// confirmShareConflict opens the confirmation dialog if starting t would
+// duplicate a teammate's share, reporting whether it did
+func (a *App) confirmShareConflict(t *TunnelRecord) bool {
+	dupes := a.shareConflicts(t)
+	if len(dupes) == 0 {
+		return false
+	}
+	a.shareConfirmID = t.ID
+	a.shareConflictList = dupes
+	a.showShareConfirm = true
+	return true
+}
+
+func describeForwards(forwards []registry.Forward) string {
+	names := make([]string, len(forwards))
+	for i, f := range forwards {
+		names[i] = fmt.Sprintf("%s@%s", f.User, f.Machine)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (a *App) handleShareConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.Type {
+	case tea.KeyEnter:
+		for i := range a.tunnels {
+			if a.tunnels[i].ID == a.shareConfirmID {
+				a.Logf("Starting duplicate share of %s", a.tunnels[i].Config.Name)
+				cmd = a.startTunnel(&a.tunnels[i])
+				break
+			}
+		}
+		a.showShareConfirm = false
+		a.updateTableRows()
+	case tea.KeyEsc, tea.KeyCtrlC:
+		a.showShareConfirm = false
+	}
+	return a, cmd
+}
+
+func (a *App) shareConfirmView() string {
+	content := dialogActiveStyle.Render("Duplicate Share") + "\n\n"
+	content += "This target is already shared on the network by:\n\n"
+	for _, f := range a.shareConflictList {
+		content += fmt.Sprintf("  %s@%s  %s -> %s (since %s)\n",
+			f.User, f.Machine, f.Name, f.Target(), f.Since.Format("Jan 2 15:04"))
+	}
+	content += "\nStart another 0.0.0.0 share anyway?\n"
+	content += "\nEnter: Start • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(70).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
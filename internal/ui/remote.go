@@ -0,0 +1,251 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tunnel9/internal/control"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How often a remote daemon is asked for its tunnels
+const remoteRefreshInterval = 2 * time.Second
+
+// RemoteDaemon is a tunnel9 daemon on another machine
+type RemoteDaemon struct {
+	Name   string // Where the daemon runs, shown as the tunnel's origin
+	Client *control.Client
+}
+
+// remoteOrigin is what the TUI knows about one of the daemons it watches
+type remoteOrigin struct {
+	RemoteDaemon
+	tunnels []control.TunnelState
+	err     error // Last failure talking to the daemon
+}
+
+// remoteRow is a tunnel in the table and the daemon it runs on
+type remoteRow struct {
+	origin *remoteOrigin
+	state  control.TunnelState
+}
+
+// remoteListMsg carries a fresh tunnel list from a daemon
+type remoteListMsg struct {
+	origin  *remoteOrigin
+	tunnels []control.TunnelState
+	err     error
+}
+
+// remoteActionMsg reports how a start or stop request went
+type remoteActionMsg struct {
+	origin *remoteOrigin
+	verb   string
+	name   string
+	err    error
+}
+
+type remoteTickMsg time.Time
+
+// RemoteApp lists and controls the tunnels of tunnel9 daemons on other
+// machines, e.g. the team's always-on forwards on shared jump VMs. With
+// several daemons their tunnels share one table, with an ORIGIN column
+// telling them apart.
+type RemoteApp struct {
+	origins []*remoteOrigin
+	table   table.Model
+	rows    []remoteRow
+	notice  string // Outcome of the last action
+	width   int
+	height  int
+}
+
+// NewRemoteApp controls the given daemons
+func NewRemoteApp(daemons []RemoteDaemon) *RemoteApp {
+	r := &RemoteApp{}
+	for _, daemon := range daemons {
+		r.origins = append(r.origins, &remoteOrigin{RemoteDaemon: daemon})
+	}
+
+	columns := []table.Column{
+		{Title: "STATUS", Width: 8},
+		{Title: "NAME", Width: 20},
+	}
+	if r.multiple() {
+		columns = append(columns, table.Column{Title: "ORIGIN", Width: 20})
+	}
+	columns = append(columns,
+		table.Column{Title: "LOCAL", Width: 22},
+		table.Column{Title: "REMOTE", Width: 28},
+		table.Column{Title: "VIA", Width: 28},
+		table.Column{Title: "TAG", Width: 12},
+		table.Column{Title: "MESSAGE", Width: 40},
+	)
+	r.table = table.New(table.WithColumns(columns), table.WithFocused(true))
+	r.table.SetStyles(tableStyles())
+	return r
+}
+
+// multiple reports whether tunnels from more than one daemon are shown
+func (r *RemoteApp) multiple() bool {
+	return len(r.origins) > 1
+}
+
+func (r *RemoteApp) Init() tea.Cmd {
+	return tea.Batch(r.refreshAll(), r.tick())
+}
+
+func (r *RemoteApp) tick() tea.Cmd {
+	return tea.Tick(remoteRefreshInterval, func(t time.Time) tea.Msg {
+		return remoteTickMsg(t)
+	})
+}
+
+// refreshAll asks every daemon for its tunnels at once, so a slow one
+// doesn't hold up the others
+func (r *RemoteApp) refreshAll() tea.Cmd {
+	cmds := make([]tea.Cmd, len(r.origins))
+	for i, origin := range r.origins {
+		cmds[i] = r.refresh(origin)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (r *RemoteApp) refresh(origin *remoteOrigin) tea.Cmd {
+	return func() tea.Msg {
+		tunnels, err := origin.Client.List()
+		return remoteListMsg{origin: origin, tunnels: tunnels, err: err}
+	}
+}
+
+// toggle starts or stops a tunnel on its daemon, off the UI goroutine since
+// it goes over the network
+func (r *RemoteApp) toggle(row remoteRow) tea.Cmd {
+	return func() tea.Msg {
+		t := row.state
+		if t.Status == "active" || t.Status == "connecting" {
+			return remoteActionMsg{origin: row.origin, verb: "Stopped", name: t.Name, err: row.origin.Client.Stop(t.Name)}
+		}
+		return remoteActionMsg{origin: row.origin, verb: "Started", name: t.Name, err: row.origin.Client.Start(t.Name)}
+	}
+}
+
+// updateRows lists the tunnels of every daemon, in the order the daemons
+// were given. A daemon that stopped answering keeps its last known tunnels.
+func (r *RemoteApp) updateRows() {
+	r.rows = r.rows[:0]
+	rows := []table.Row{}
+	for _, origin := range r.origins {
+		for _, t := range origin.tunnels {
+			r.rows = append(r.rows, remoteRow{origin: origin, state: t})
+			row := table.Row{statusIcon(t.Status), t.Name}
+			if r.multiple() {
+				row = append(row, origin.Name)
+			}
+			rows = append(rows, append(row, t.Local, t.Remote, t.Via, t.Tag, t.Message))
+		}
+	}
+	r.table.SetRows(rows)
+}
+
+func (r *RemoteApp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case remoteListMsg:
+		msg.origin.err = msg.err
+		if msg.err == nil {
+			msg.origin.tunnels = msg.tunnels
+			r.updateRows()
+		}
+		return r, nil
+
+	case remoteTickMsg:
+		return r, tea.Batch(r.refreshAll(), r.tick())
+
+	case remoteActionMsg:
+		name := msg.name
+		if r.multiple() {
+			name = fmt.Sprintf("%s on %s", msg.name, msg.origin.Name)
+		}
+		if msg.err != nil {
+			r.notice = fmt.Sprintf("%s: %v", name, msg.err)
+		} else {
+			r.notice = fmt.Sprintf("%s %s", msg.verb, name)
+		}
+		return r, r.refresh(msg.origin)
+
+	case tea.WindowSizeMsg:
+		r.width = msg.Width
+		r.height = msg.Height
+		r.table.SetHeight(max(msg.Height-6, 1))
+		return r, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			for _, origin := range r.origins {
+				origin.Client.Close()
+			}
+			return r, tea.Quit
+		case "enter":
+			cursor := r.table.Cursor()
+			if cursor >= 0 && cursor < len(r.rows) {
+				return r, r.toggle(r.rows[cursor])
+			}
+			return r, nil
+		case "ctrl+r":
+			return r, r.refreshAll()
+		}
+	}
+
+	var cmd tea.Cmd
+	r.table, cmd = r.table.Update(msg)
+	return r, cmd
+}
+
+// unreachable describes the daemons that failed to answer last time
+func (r *RemoteApp) unreachable() string {
+	var failed []string
+	for _, origin := range r.origins {
+		if origin.err == nil {
+			continue
+		}
+		if r.multiple() {
+			failed = append(failed, fmt.Sprintf("%s (%v)", origin.Name, origin.err))
+		} else {
+			failed = append(failed, origin.err.Error())
+		}
+	}
+	return strings.Join(failed, ", ")
+}
+
+func (r *RemoteApp) View() string {
+	title := "tunnel9 - remote daemon on " + r.origins[0].Name
+	if r.multiple() {
+		title = fmt.Sprintf("tunnel9 - %d remote daemons", len(r.origins))
+	}
+	s := titleStyle.Render(title) + "\n\n"
+	s += r.table.View() + "\n"
+
+	unreachable := r.unreachable()
+	switch {
+	case unreachable != "" && r.multiple():
+		s += lipgloss.NewStyle().Foreground(activeTheme.error).Render("unreachable: "+unreachable) + "\n"
+	case unreachable != "":
+		s += lipgloss.NewStyle().Foreground(activeTheme.error).Render("daemon unreachable: "+unreachable) + "\n"
+	case r.notice != "":
+		s += r.notice + "\n"
+	default:
+		s += "\n"
+	}
+
+	selectedColorStyle := controlsStyle.Foreground(activeTheme.accent)
+	s += controlsStyle.Render(selectedColorStyle.Render("↑/↓") + ":select • " +
+		selectedColorStyle.Render("enter") + ":toggle • " +
+		selectedColorStyle.Render("ctrl+r") + ":refresh • " +
+		selectedColorStyle.Render("q") + "uit")
+	return s
+}
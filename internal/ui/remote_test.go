@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"tunnel9/internal/control"
+)
+
+type fakeDaemon struct {
+	mu      sync.Mutex
+	tunnels []control.TunnelState
+}
+
+func (d *fakeDaemon) List() []control.TunnelState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]control.TunnelState(nil), d.tunnels...)
+}
+
+func (d *fakeDaemon) set(name string, status string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.tunnels {
+		if d.tunnels[i].Name == name {
+			d.tunnels[i].Status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("no tunnel named %s", name)
+}
+
+func (d *fakeDaemon) Start(name string) error { return d.set(name, "active") }
+func (d *fakeDaemon) Stop(name string) error  { return d.set(name, "stopped") }
+
+// serveFakeDaemon answers a control socket for daemon and connects to it
+func serveFakeDaemon(t *testing.T, daemon *fakeDaemon) *control.Client {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	listener, err := control.Listen(path)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go control.Serve(listener, daemon)
+
+	client, err := control.Dial(path)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRemoteAppToggle(t *testing.T) {
+	daemon := &fakeDaemon{tunnels: []control.TunnelState{
+		{Name: "db", Status: "active", Local: "localhost:5432"},
+		{Name: "web", Status: "stopped", Local: "localhost:8080"},
+	}}
+	r := NewRemoteApp([]RemoteDaemon{{Name: "jump-vm", Client: serveFakeDaemon(t, daemon)}})
+
+	r.Update(r.refresh(r.origins[0])())
+	if rows := r.table.Rows(); len(rows) != 2 || rows[0][0] != "[✓]" || rows[1][1] != "web" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	// Enter on an active tunnel stops it on the daemon
+	msg := r.toggle(r.rows[0])()
+	if action := msg.(remoteActionMsg); action.err != nil || action.verb != "Stopped" {
+		t.Fatalf("unexpected result %+v", action)
+	}
+	if status := daemon.List()[0].Status; status != "stopped" {
+		t.Errorf("expected db to be stopped on the daemon, got %s", status)
+	}
+}
+
+func TestRemoteAppMultipleDaemons(t *testing.T) {
+	east := &fakeDaemon{tunnels: []control.TunnelState{{Name: "db", Status: "active"}}}
+	west := &fakeDaemon{tunnels: []control.TunnelState{{Name: "db", Status: "stopped"}}}
+	r := NewRemoteApp([]RemoteDaemon{
+		{Name: "jump-east", Client: serveFakeDaemon(t, east)},
+		{Name: "jump-west", Client: serveFakeDaemon(t, west)},
+	})
+
+	for _, origin := range r.origins {
+		r.Update(r.refresh(origin)())
+	}
+	rows := r.table.Rows()
+	if len(rows) != 2 || rows[0][2] != "jump-east" || rows[1][2] != "jump-west" {
+		t.Fatalf("expected a db row from each daemon with its origin, got %v", rows)
+	}
+
+	// Same tunnel name, but only the selected daemon's tunnel starts
+	r.toggle(r.rows[1])()
+	if east.List()[0].Status != "active" || west.List()[0].Status != "active" {
+		t.Errorf("expected only west's db to start, got east %v and west %v", east.List(), west.List())
+	}
+}
@@ -0,0 +1,79 @@
+package ui
+
+import "github.com/charmbracelet/bubbles/table"
+
+// STATUS and NAME stay put while wide mode scrolls sideways
+const frozenColumns = 2
+
+// Column widths in wide mode before any horizontal scrolling
+var wideColumnWidths = []int{8, 25, 7, 15, 30, 8, 30, 12, 12, 12, 10, 10, 8, 9, 40}
+
+// renderedWidth is what a column takes on screen, including cell padding
+func renderedWidth(width int) int {
+	return width + 1
+}
+
+// wideColumnsWidth sums the frozen columns and the scrollable ones from
+// first onwards
+func wideColumnsWidth(first int) int {
+	total := 0
+	for i, width := range wideColumnWidths {
+		if i < frozenColumns || i >= first {
+			total += renderedWidth(width)
+		}
+	}
+	return total
+}
+
+// maxHScroll is how many scrollable columns can be hidden before the rest
+// fits the terminal, always leaving MESSAGE in view
+func (a *App) maxHScroll() int {
+	scrollable := len(wideColumnWidths) - frozenColumns
+	for hidden := 0; hidden < scrollable; hidden++ {
+		if a.width <= 0 || wideColumnsWidth(frozenColumns+hidden) <= a.width {
+			return hidden
+		}
+	}
+	return scrollable - 1
+}
+
+// scrollWide moves the wide view sideways by delta columns
+func (a *App) scrollWide(delta int) {
+	a.hScroll += delta
+	a.clampHScroll()
+}
+
+func (a *App) clampHScroll() {
+	if limit := a.maxHScroll(); a.hScroll > limit {
+		a.hScroll = limit
+	}
+	if a.hScroll < 0 {
+		a.hScroll = 0
+	}
+}
+
+// applyHScroll hides the columns scrolled off to the left, which the table
+// skips entirely at zero width, and lets MESSAGE take whatever room is left
+func (a *App) applyHScroll(columns []table.Column) {
+	first := frozenColumns + a.hScroll
+	for i := range columns {
+		switch {
+		case i >= len(wideColumnWidths):
+		case i >= frozenColumns && i < first:
+			columns[i].Width = 0
+		default:
+			columns[i].Width = wideColumnWidths[i]
+		}
+	}
+
+	last := len(columns) - 1
+	if last < 0 || last >= len(wideColumnWidths) {
+		return
+	}
+	if spare := a.width - wideColumnsWidth(first); spare > 0 {
+		columns[last].Width += spare
+	}
+	if a.hScroll > 0 {
+		columns[first].Title = "◀ " + columns[first].Title
+	}
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+func TestHorizontalScroll(t *testing.T) {
+	a := &App{width: 120}
+
+	limit := a.maxHScroll()
+	if limit == 0 {
+		t.Fatal("expected a narrow terminal to allow scrolling")
+	}
+	if got := wideColumnsWidth(frozenColumns + limit); got > a.width {
+		t.Errorf("expected columns to fit after scrolling %d, got width %d", limit, got)
+	}
+
+	a.scrollWide(100)
+	if a.hScroll != limit {
+		t.Errorf("expected scroll clamped to %d, got %d", limit, a.hScroll)
+	}
+	a.scrollWide(-100)
+	if a.hScroll != 0 {
+		t.Errorf("expected scroll clamped to 0, got %d", a.hScroll)
+	}
+
+	a.hScroll = 3
+	columns := make([]table.Column, len(wideColumnWidths))
+	for i := range columns {
+		columns[i].Title = "COL"
+	}
+	a.applyHScroll(columns)
+	if columns[0].Width != wideColumnWidths[0] || columns[1].Width != wideColumnWidths[1] {
+		t.Error("expected STATUS and NAME to stay frozen")
+	}
+	for i := frozenColumns; i < frozenColumns+3; i++ {
+		if columns[i].Width != 0 {
+			t.Errorf("expected column %d hidden, got width %d", i, columns[i].Width)
+		}
+	}
+	if columns[5].Title != "◀ COL" {
+		t.Errorf("expected scroll marker on the first visible column, got %q", columns[5].Title)
+	}
+
+	// A wide enough terminal never scrolls, and MESSAGE takes the slack
+	a = &App{width: 400}
+	if a.maxHScroll() != 0 {
+		t.Errorf("expected no scrolling on a wide terminal")
+	}
+	a.applyHScroll(columns)
+	last := len(columns) - 1
+	if want := wideColumnWidths[last] + 400 - wideColumnsWidth(frozenColumns); columns[last].Width != want {
+		t.Errorf("expected MESSAGE width %d, got %d", want, columns[last].Width)
+	}
+}
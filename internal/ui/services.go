@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"fmt"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+)
+
+// servicesMsg carries what discovery found behind a tunnel's SSH server
+type servicesMsg struct {
+	round    int // Which opening of the list it belongs to
+	services []ssh.DiscoveredService
+	errs     []error
+}
+
+// openServices looks for services behind the selected tunnel's SSH server,
+// over its established connection, to propose tunnels to them
+func (a *App) openServices() tea.Cmd {
+	selected := a.selectedRecord()
+	if selected == nil || selected.Status != "active" {
+		a.logError("Discovery looks from a running tunnel's SSH server, start one first")
+		return nil
+	}
+	var cfg config.DiscoveryConfig
+	if a.loader != nil {
+		cfg = a.loader.Config().Discovery
+	}
+
+	a.servicesVia = selected.Config
+	a.services = nil
+	a.servicesSelected = nil
+	a.servicesCursor = 0
+	a.servicesPending = true
+	a.servicesRound++
+	a.showServices = true
+
+	manager, id, round := a.manager, selected.ID, a.servicesRound
+	return func() tea.Msg {
+		services, errs := manager.DiscoverServices(id, cfg)
+		return servicesMsg{round: round, services: services, errs: errs}
+	}
+}
+
+// showServicesResult lists the services found that no tunnel reaches yet
+func (a *App) showServicesResult(msg servicesMsg) {
+	if !a.showServices || msg.round != a.servicesRound {
+		return
+	}
+	a.servicesPending = false
+	for _, err := range msg.errs {
+		a.logError("Discovery via %s: %v", a.servicesVia.Name, err)
+	}
+
+	via, _ := ssh.TargetEndpoints(a.servicesVia)
+	configured := make(map[string]bool)
+	for _, t := range a.tunnels {
+		endpoint, remote := ssh.TargetEndpoints(t.Config)
+		if endpoint.String() == via.String() {
+			configured[remote.String()] = true
+		}
+	}
+	for _, service := range msg.services {
+		if !configured[ssh.NewEndpoint(service.Host, service.Port).String()] {
+			a.services = append(a.services, service)
+		}
+	}
+	a.servicesSelected = make([]bool, len(a.services))
+	a.Logf("Discovered %d service(s) via %s, %d not configured yet", len(msg.services), a.servicesVia.Name, len(a.services))
+}
+
+func (a *App) handleServicesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.servicesCursor > 0 {
+			a.servicesCursor--
+		}
+	case "down", "j":
+		if a.servicesCursor < len(a.services)-1 {
+			a.servicesCursor++
+		}
+	case " ":
+		if a.servicesCursor < len(a.servicesSelected) {
+			a.servicesSelected[a.servicesCursor] = !a.servicesSelected[a.servicesCursor]
+		}
+	case "a":
+		// Select all, or none when all are selected already
+		all := true
+		for _, selected := range a.servicesSelected {
+			all = all && selected
+		}
+		for i := range a.servicesSelected {
+			a.servicesSelected[i] = !all
+		}
+	case "enter":
+		a.showServices = false
+		a.importServices()
+	case "esc", "ctrl+c", "F":
+		a.showServices = false
+		a.services = nil
+		a.servicesSelected = nil
+	}
+	return a, nil
+}
+
+// serviceTunnel proposes a tunnel to a service through the same SSH server
+// as the tunnel it was discovered from
+func serviceTunnel(via config.TunnelConfig, service ssh.DiscoveredService) config.TunnelConfig {
+	tc := config.TunnelConfig{
+		Name:       service.Name,
+		RemoteHost: service.Host,
+		RemotePort: service.Port,
+		Tag:        via.Tag,
+	}
+	// Scans and hosts find several ports per host
+	if service.Source == config.DiscoveryScan || service.Source == config.DiscoveryHosts {
+		tc.Name = fmt.Sprintf("%s-%d", service.Name, service.Port)
+	}
+	tc.Bastion = via.Bastion
+	if tc.Bastion.Host == "" {
+		// Without a jump host, the tunnel logged in to its remote host
+		tc.Bastion.Host = via.RemoteHost
+	}
+	tc.SSHOptions = via.SSHOptions
+	tc.SSHConfigIgnore = via.SSHConfigIgnore
+	return tc
+}
+
+// importServices adds tunnels to the selected services, stopped, and saves
+// once
+func (a *App) importServices() {
+	names := make(map[string]bool, len(a.tunnels))
+	used := make(map[int]bool, len(a.tunnels))
+	for _, t := range a.tunnels {
+		names[t.Config.Name] = true
+		used[t.Config.LocalPort] = true
+	}
+
+	imported := 0
+	for i, service := range a.services {
+		if !a.servicesSelected[i] {
+			continue
+		}
+		tc := serviceTunnel(a.servicesVia, service)
+		original := tc.Name
+		for n := 2; names[tc.Name]; n++ {
+			tc.Name = fmt.Sprintf("%s-%d", original, n)
+		}
+		names[tc.Name] = true
+
+		// Prefer port_range, else the service's own port, above the
+		// privileged range
+		port, ok := 0, false
+		if a.loader != nil {
+			port, ok = a.loader.AssignPort(tc.Name, used)
+		}
+		if !ok {
+			port = service.Port
+			if port < 1024 {
+				port += 10000
+			}
+			for used[port] && port < 65535 {
+				port++
+			}
+		}
+		tc.LocalPort = port
+		used[port] = true
+
+		a.tunnels = append(a.tunnels, TunnelRecord{
+			ID:      uuid.New().String(),
+			Status:  "stopped",
+			Config:  tc,
+			Metrics: "--",
+		})
+		imported++
+	}
+	a.services = nil
+	a.servicesSelected = nil
+	if imported == 0 {
+		return
+	}
+
+	a.Logf("Imported %d discovered service(s) via %s", imported, a.servicesVia.Name)
+	a.updateTableRows()
+	a.saveConfig()
+}
+
+func (a *App) servicesView() string {
+	content := dialogActiveStyle.Render("Services via "+a.servicesVia.Name) + "\n\n"
+	switch {
+	case a.servicesPending:
+		content += previewStyle.Render("discovering...") + "\n"
+	case len(a.services) == 0:
+		content += "No services found that aren't configured already\n"
+	}
+
+	// Keep the cursor on the visible page
+	start := 0
+	if a.servicesCursor >= discoverPageSize {
+		start = a.servicesCursor - discoverPageSize + 1
+	}
+	selected := 0
+	for _, s := range a.servicesSelected {
+		if s {
+			selected++
+		}
+	}
+	for i := start; i < len(a.services) && i < start+discoverPageSize; i++ {
+		service := a.services[i]
+		box := "[ ]"
+		if a.servicesSelected[i] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s %-30s %-22s %s", box, serviceTunnel(a.servicesVia, service).Name,
+			service.Address(), previewStyle.Render(service.Source))
+		if i == a.servicesCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	if !a.servicesPending {
+		content += previewStyle.Render(fmt.Sprintf("\n%d of %d selected", selected, len(a.services))) + "\n"
+	}
+	content += "\n↑/↓: Move • Space: Select • a: All/none • Enter: Import selected • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(100).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
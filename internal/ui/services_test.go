@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestServiceTunnel(t *testing.T) {
+	via := config.TunnelConfig{Name: "jump-db", RemoteHost: "jump.internal", RemotePort: 22, Tag: "prod"}
+	via.Bastion.User = "ops"
+
+	tc := serviceTunnel(via, ssh.DiscoveredService{Name: "10.0.0.9", Host: "10.0.0.9", Port: 6379, Source: config.DiscoveryScan})
+	if tc.Name != "10.0.0.9-6379" || tc.RemoteHost != "10.0.0.9" || tc.RemotePort != 6379 || tc.Tag != "prod" {
+		t.Errorf("unexpected tunnel %+v", tc)
+	}
+	if tc.Bastion.Host != "jump.internal" || tc.Bastion.User != "ops" {
+		t.Errorf("expected the tunnel's own host as jump host, got %+v", tc.Bastion)
+	}
+
+	via.Bastion.Host = "bastion.prod"
+	tc = serviceTunnel(via, ssh.DiscoveredService{Name: "postgres", Host: "10.0.0.5", Port: 5432, Source: config.DiscoveryConsul})
+	if tc.Name != "postgres" || tc.Bastion.Host != "bastion.prod" {
+		t.Errorf("expected the same jump host, got %+v", tc)
+	}
+}
+
+func TestServicesImport(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	a := &App{loader: config.NewConfigLoader(filepath.Join(dir, "config.yaml"))}
+	via := TunnelRecord{ID: "via", Status: "active"}
+	via.Config.Name = "db"
+	via.Config.LocalPort = 5432
+	via.Config.RemoteHost = "10.0.0.5"
+	via.Config.RemotePort = 5432
+	via.Config.Bastion.Host = "jump"
+	a.tunnels = []TunnelRecord{via}
+
+	a.showServices = true
+	a.servicesVia = via.Config
+	a.showServicesResult(servicesMsg{services: []ssh.DiscoveredService{
+		{Name: "db.internal", Host: "10.0.0.5", Port: 5432, Source: config.DiscoveryHosts},
+		{Name: "db.internal", Host: "10.0.0.5", Port: 22, Source: config.DiscoveryHosts},
+		{Name: "postgres", Host: "10.0.0.6", Port: 5432, Source: config.DiscoveryConsul},
+	}})
+	if len(a.services) != 2 {
+		t.Fatalf("expected the configured service left out, got %+v", a.services)
+	}
+
+	a.handleServicesKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	a.handleServicesKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(a.tunnels) != 3 {
+		t.Fatalf("expected two tunnels imported, got %+v", a.tunnels)
+	}
+	ssh22, postgres := a.tunnels[1].Config, a.tunnels[2].Config
+	if ssh22.Name != "db.internal-22" || ssh22.LocalPort != 10022 {
+		t.Errorf("expected a privileged port moved up, got %+v", ssh22)
+	}
+	if postgres.Name != "postgres" || postgres.LocalPort != 5433 || postgres.Bastion.Host != "jump" {
+		t.Errorf("expected the next free port through the same jump host, got %+v", postgres)
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shareReadyMsg reports the outcome of exposing a tunnel publicly
+type shareReadyMsg struct {
+	id  string
+	url string
+	err error
+}
+
+// togglePublicShare exposes the tunnel's local port through the public VPS,
+// or stops sharing it if it already is
+func (a *App) togglePublicShare(t *TunnelRecord) tea.Cmd {
+	if t.PublicURL != "" {
+		a.stopPublicShare(t)
+		a.Logf("Stopped sharing %s publicly", t.Config.Name)
+		a.updateTableRows()
+		return nil
+	}
+
+	if a.publicShare.Host == "" {
+		a.logError("Cannot share %s: no public_share host configured", t.Config.Name)
+		return nil
+	}
+
+	localAddr, running := a.manager.LocalAddress(t.ID)
+	if !running {
+		a.logError("Start %s before sharing it publicly", t.Config.Name)
+		return nil
+	}
+
+	a.Logf("Sharing %s publicly via %s...", t.Config.Name, a.publicShare.Host)
+	manager, cfg := a.manager, a.publicShare
+	id, name := t.ID, t.Config.Name
+	return func() tea.Msg {
+		share, err := manager.StartShare(id, name, localAddr, cfg)
+		if err != nil {
+			return shareReadyMsg{id: id, err: err}
+		}
+		return shareReadyMsg{id: id, url: share.URL}
+	}
+}
+
+func (a *App) handleShareReady(msg shareReadyMsg) {
+	if msg.err != nil {
+		a.logError("Failed to share publicly: %v", msg.err)
+		return
+	}
+
+	for i := range a.tunnels {
+		if a.tunnels[i].ID == msg.id {
+			// Stopped while the share was coming up
+			if a.tunnels[i].Status == "stopped" {
+				break
+			}
+			a.tunnels[i].PublicURL = msg.url
+			a.Logf("%s is public at %s", a.tunnels[i].Config.Name, msg.url)
+			a.updateTableRows()
+			return
+		}
+	}
+
+	// Tunnel was stopped or deleted while the share was coming up
+	a.manager.StopShare(msg.id)
+}
+
+func (a *App) stopPublicShare(t *TunnelRecord) {
+	if t.PublicURL == "" {
+		return
+	}
+	a.manager.StopShare(t.ID)
+	t.PublicURL = ""
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+)
+
+// columnLess compares two tunnels by a single table column
+func (a *App) columnLess(col int, x *TunnelRecord, y *TunnelRecord) bool {
+	if a.isWideMode {
+		switch col {
+		case 0: // Status
+			return x.Status < y.Status
+		case 1: // Name
+			return x.Config.Name < y.Config.Name
+		case 2: // Local Port
+			return x.Config.LocalPort < y.Config.LocalPort
+		case 3: // Bind
+			return x.Config.BindAddress < y.Config.BindAddress
+		case 4: // Host
+			return x.Config.RemoteHost < y.Config.RemoteHost
+		case 5: // Remote Port
+			return x.Config.RemotePort < y.Config.RemotePort
+		case 6: // Bastion
+			return x.Config.Bastion.Host < y.Config.Bastion.Host
+		case 7: // Tag
+			return a.tagLess(x.Config.Tag, y.Config.Tag)
+		case 8: // Rate out
+			return x.Values.RateOut < y.Values.RateOut
+		case 9: // Rate in
+			return x.Values.RateIn < y.Values.RateIn
+		case 10: // Total out
+			_, xOut := x.Transfer.run(x.Values)
+			_, yOut := y.Transfer.run(y.Values)
+			return xOut < yOut
+		case 11: // Total in
+			xIn, _ := x.Transfer.run(x.Values)
+			yIn, _ := y.Transfer.run(y.Values)
+			return xIn < yIn
+		case 12: // Latency
+			return x.Values.Latency < y.Values.Latency
+		case 13: // Health
+			return x.Values.Health < y.Values.Health
+		case 14: // Message
+			return x.Metrics < y.Metrics
+		}
+		return false
+	}
+
+	switch col {
+	case 0: // Status
+		return x.Status < y.Status
+	case 1: // Name
+		return x.Config.Name < y.Config.Name
+	case 2: // Combined tunnel
+		xTunnel := fmt.Sprintf("%d:%s:%d", x.Config.LocalPort, x.Config.RemoteHost, x.Config.RemotePort)
+		yTunnel := fmt.Sprintf("%d:%s:%d", y.Config.LocalPort, y.Config.RemoteHost, y.Config.RemotePort)
+		return xTunnel < yTunnel
+	case 3: // Tag
+		return a.tagLess(x.Config.Tag, y.Config.Tag)
+	case 4: // Message
+		return x.Metrics < y.Metrics
+	}
+	return false
+}
+
+// compareColumn orders two tunnels by a column, returning 0 when they tie
+func (a *App) compareColumn(col int, x *TunnelRecord, y *TunnelRecord) int {
+	switch {
+	case a.columnLess(col, x, y):
+		return -1
+	case a.columnLess(col, y, x):
+		return 1
+	}
+	return 0
+}
+
+// sortTunnels orders rows by the sort column, breaking ties with the
+// secondary column so related rows stay grouped but sorted within the group.
+// Only the primary column is affected by reversing. Unsorted, the tunnels go
+// back to their own order.
+func (a *App) sortTunnels() {
+	if a.sortColumn == unsorted {
+		a.tunnels = a.manualOrder()
+		a.manualIDs = nil
+		return
+	}
+	a.rememberManualOrder()
+	sort.SliceStable(a.tunnels, func(i, j int) bool {
+		x, y := &a.tunnels[i], &a.tunnels[j]
+		if c := a.compareColumn(a.sortColumn, x, y); c != 0 {
+			if a.sortReverse {
+				return c > 0
+			}
+			return c < 0
+		}
+		if a.secondarySort >= 0 && a.secondarySort != a.sortColumn {
+			return a.compareColumn(a.secondarySort, x, y) < 0
+		}
+		return false
+	})
+}
+
+// stepSecondarySort cycles the secondary sort column by delta, passing
+// through "none" and skipping the primary column
+func (a *App) stepSecondarySort(delta int) int {
+	count := len(a.table.Columns())
+	col := a.secondarySort
+	for {
+		col += delta
+		if col >= count {
+			col = -1
+		} else if col < -1 {
+			col = count - 1
+		}
+		if col != a.sortColumn {
+			return col
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+func tunnelNames(tunnels []TunnelRecord) []string {
+	names := make([]string, len(tunnels))
+	for i, t := range tunnels {
+		names[i] = t.Config.Name
+	}
+	return names
+}
+
+func TestSortTunnelsSecondary(t *testing.T) {
+	record := func(name string, status string) TunnelRecord {
+		r := TunnelRecord{Status: status}
+		r.Config.Name = name
+		return r
+	}
+	a := &App{
+		tunnels: []TunnelRecord{
+			record("web", "stopped"),
+			record("db", "active"),
+			record("cache", "stopped"),
+			record("api", "active"),
+		},
+		sortColumn:    0,
+		secondarySort: 1,
+	}
+
+	a.sortTunnels()
+	want := []string{"api", "db", "cache", "web"}
+	if got := tunnelNames(a.tunnels); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Reversing flips the groups but keeps names ascending within them
+	a.sortReverse = true
+	a.sortTunnels()
+	want = []string{"cache", "web", "api", "db"}
+	if got := tunnelNames(a.tunnels); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStepSecondarySort(t *testing.T) {
+	a := &App{
+		table:         table.New(table.WithColumns(make([]table.Column, 5))),
+		sortColumn:    1,
+		secondarySort: -1,
+	}
+
+	var got []int
+	for i := 0; i < 6; i++ {
+		a.secondarySort = a.stepSecondarySort(1)
+		got = append(got, a.secondarySort)
+	}
+	want := []int{0, 2, 3, 4, -1, 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	a.secondarySort = -1
+	if col := a.stepSecondarySort(-1); col != 4 {
+		t.Errorf("expected to wrap to the last column, got %d", col)
+	}
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"fmt"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/localforward"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+)
+
+// openSSHImport lists the LocalForward entries of ~/.ssh/config that aren't
+// configured yet, all selected, to pick from before anything is saved
+func (a *App) openSSHImport() {
+	existing := make([]config.TunnelConfig, 0, len(a.tunnels))
+	for _, t := range a.tunnels {
+		existing = append(existing, t.Config)
+	}
+	path := localforward.DefaultFile()
+	forwards, skipped, err := localforward.Discover(path, existing)
+	if err != nil {
+		a.logError("Failed to read %s: %v", path, err)
+		return
+	}
+	for _, s := range skipped {
+		a.Logf("Not importing %s", s)
+	}
+
+	a.sshForwards = forwards
+	a.sshSelected = make([]bool, len(forwards))
+	for i := range a.sshSelected {
+		a.sshSelected[i] = true
+	}
+	a.sshCursor = 0
+	a.showSSHImport = true
+}
+
+func (a *App) handleSSHImportKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.sshCursor > 0 {
+			a.sshCursor--
+		}
+	case "down", "j":
+		if a.sshCursor < len(a.sshForwards)-1 {
+			a.sshCursor++
+		}
+	case " ":
+		if a.sshCursor < len(a.sshSelected) {
+			a.sshSelected[a.sshCursor] = !a.sshSelected[a.sshCursor]
+		}
+	case "a":
+		// Select all, or none when all are selected already
+		all := true
+		for _, selected := range a.sshSelected {
+			all = all && selected
+		}
+		for i := range a.sshSelected {
+			a.sshSelected[i] = !all
+		}
+	case "enter":
+		a.showSSHImport = false
+		a.importSSHForwards()
+	case "esc", "ctrl+c", "I":
+		a.showSSHImport = false
+	}
+	return a, nil
+}
+
+// importSSHForwards adds the selected forwards, stopped, and saves once
+func (a *App) importSSHForwards() {
+	names := make(map[string]bool, len(a.tunnels))
+	for _, t := range a.tunnels {
+		names[t.Config.Name] = true
+	}
+
+	imported := 0
+	for i, f := range a.sshForwards {
+		if !a.sshSelected[i] {
+			continue
+		}
+		tc := f.Config
+		original := tc.Name
+		for n := 2; names[tc.Name]; n++ {
+			tc.Name = fmt.Sprintf("%s-%d", original, n)
+		}
+		names[tc.Name] = true
+		a.tunnels = append(a.tunnels, TunnelRecord{
+			ID:      uuid.New().String(),
+			Status:  "stopped",
+			Config:  tc,
+			Metrics: "--",
+		})
+		imported++
+	}
+	a.sshForwards = nil
+	a.sshSelected = nil
+	if imported == 0 {
+		return
+	}
+
+	a.Logf("Imported %d tunnel(s) from SSH config", imported)
+	a.updateTableRows()
+	a.saveConfig()
+}
+
+func (a *App) sshImportView() string {
+	content := dialogActiveStyle.Render("Import from SSH config") + "\n\n"
+	if len(a.sshForwards) == 0 {
+		content += "No LocalForward entries in ~/.ssh/config that aren't configured already\n"
+	}
+
+	// Keep the cursor on the visible page
+	start := 0
+	if a.sshCursor >= discoverPageSize {
+		start = a.sshCursor - discoverPageSize + 1
+	}
+	selected := 0
+	for _, s := range a.sshSelected {
+		if s {
+			selected++
+		}
+	}
+	for i := start; i < len(a.sshForwards) && i < start+discoverPageSize; i++ {
+		f := a.sshForwards[i]
+		box := "[ ]"
+		if a.sshSelected[i] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s %-28s %5d → %s:%d via %s", box, f.Config.Name, f.Config.LocalPort,
+			f.Config.RemoteHost, f.Config.RemotePort, f.Config.Bastion.Host)
+		if i == a.sshCursor {
+			content += dialogActiveStyle.Render("> ") + line + "\n"
+			content += "      " + previewStyle.Render(f.Source) + "\n"
+		} else {
+			content += "  " + line + "\n"
+		}
+	}
+	content += previewStyle.Render(fmt.Sprintf("\n%d of %d selected", selected, len(a.sshForwards))) + "\n"
+	content += "\n↑/↓: Move • Space: Select • a: All/none • Enter: Import selected • Esc/Ctrl+C: Cancel"
+
+	dialog := dialogStyle.Width(100).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
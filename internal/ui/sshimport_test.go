@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSSHImportSelection(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".ssh"), 0700)
+	os.WriteFile(filepath.Join(dir, ".ssh", "config"), []byte(`Host jump
+  LocalForward 5432 db:5432
+  LocalForward 8080 web:80
+  LocalForward 9200 search:9200
+`), 0600)
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	a := &App{loader: config.NewConfigLoader(configPath)}
+	a.openSSHImport()
+	if !a.showSSHImport || len(a.sshForwards) != 3 {
+		t.Fatalf("expected 3 forwards offered, got %+v", a.sshForwards)
+	}
+
+	// Leave web out
+	a.handleSSHImportKey(tea.KeyMsg{Type: tea.KeyDown})
+	a.handleSSHImportKey(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	a.handleSSHImportKey(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if a.showSSHImport || len(a.tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels imported, got %+v", a.tunnels)
+	}
+	if a.tunnels[0].Config.Name != "jump-5432" || a.tunnels[1].Config.Name != "jump-9200" {
+		t.Errorf("unexpected tunnels %+v", a.tunnels)
+	}
+	saved, err := config.NewConfigLoader(configPath).Load()
+	if err != nil || len(saved) != 2 {
+		t.Errorf("expected the selection saved, got %+v (%v)", saved, err)
+	}
+
+	// Only what wasn't imported is offered again
+	a.openSSHImport()
+	if len(a.sshForwards) != 1 || a.sshForwards[0].Config.RemoteHost != "web" {
+		t.Errorf("expected only web offered, got %+v", a.sshForwards)
+	}
+}
@@ -0,0 +1,29 @@
+package ui
+
+// Status filters cycled with "v", the empty one showing every tunnel
+var statusFilters = []string{"", "active", "error", "stopped"}
+
+// matchesStatusFilter reports whether t is shown under the status filter
+func (a *App) matchesStatusFilter(t *TunnelRecord) bool {
+	return a.statusFilter == "" || t.Status == a.statusFilter
+}
+
+// cycleStatusFilter moves on to the next status filter
+func (a *App) cycleStatusFilter() {
+	next := 0
+	for i, filter := range statusFilters {
+		if filter == a.statusFilter {
+			next = (i + 1) % len(statusFilters)
+			break
+		}
+	}
+	a.statusFilter = statusFilters[next]
+
+	if a.statusFilter == "" {
+		a.Logf("Showing tunnels of any status")
+	} else {
+		a.Logf("Showing only %s tunnels", a.statusFilter)
+	}
+	a.table.SetCursor(0)
+	a.updateTableRows()
+}
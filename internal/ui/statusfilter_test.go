@@ -0,0 +1,49 @@
+package ui
+
+import "testing"
+
+func TestStatusFilter(t *testing.T) {
+	a := newExpandApp(t, 4, 10)
+	a.tunnels[0].Status = "active"
+	a.tunnels[1].Status = "error"
+	a.tunnels[2].Config.Tag = "prod"
+	a.tunnels[3].Status = "error"
+	a.tunnels[3].Config.Tag = "prod"
+
+	names := func() []string {
+		var names []string
+		for _, t := range a.filteredTunnels() {
+			names = append(names, t.Config.Name)
+		}
+		return names
+	}
+
+	a.cycleStatusFilter()
+	if a.statusFilter != "active" || len(names()) != 1 || names()[0] != "tunnel-0" {
+		t.Errorf("expected only tunnel-0 when showing active, got %v", names())
+	}
+
+	a.cycleStatusFilter()
+	if got := names(); len(got) != 2 || got[0] != "tunnel-1" || got[1] != "tunnel-3" {
+		t.Errorf("expected the errored tunnels, got %v", got)
+	}
+	if rows := len(a.table.Rows()); rows != 2 {
+		t.Errorf("expected the table to follow the filter, got %d rows", rows)
+	}
+
+	// Combines with the tag filter
+	a.currentTag = "prod"
+	if got := names(); len(got) != 1 || got[0] != "tunnel-3" {
+		t.Errorf("expected errored prod tunnels, got %v", got)
+	}
+
+	a.cycleStatusFilter()
+	if got := names(); len(got) != 1 || got[0] != "tunnel-2" {
+		t.Errorf("expected stopped prod tunnels, got %v", got)
+	}
+
+	a.cycleStatusFilter()
+	if a.statusFilter != "" || len(names()) != 2 {
+		t.Errorf("expected the filter to wrap around to all prod tunnels, got %q %v", a.statusFilter, names())
+	}
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"strings"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/registry"
+)
+
+// How many suggestions are listed under a field
+const maxShownSuggestions = 5
+
+// recentFor is what was typed lately into a dialog field, if it is one that
+// gets suggestions
+func recentFor(values registry.RecentValues, field int) []string {
+	switch field {
+	case 4:
+		return values.RemoteHosts
+	case 6:
+		return values.BastionHosts
+	case 8:
+		return values.BastionUsers
+	}
+	return nil
+}
+
+// dialogSuggestions are the recent values for the active field starting with
+// what was typed, before any suggestion was filled in
+func (a *App) dialogSuggestions() []string {
+	typed := a.dialogFields[a.activeField].value
+	if a.suggestIndex >= 0 {
+		typed = a.suggestPrefix
+	}
+	var matches []string
+	for _, value := range recentFor(a.recentValues, a.activeField) {
+		if value != typed && strings.HasPrefix(strings.ToLower(value), strings.ToLower(typed)) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
+
+// nextSuggestion fills the active field with the next matching recent value,
+// going back to what was typed after the last one
+func (a *App) nextSuggestion() {
+	field := &a.dialogFields[a.activeField]
+	if a.suggestIndex < 0 {
+		a.suggestPrefix = field.value
+	}
+	matches := a.dialogSuggestions()
+	if len(matches) == 0 {
+		return
+	}
+
+	a.suggestIndex++
+	if a.suggestIndex >= len(matches) {
+		field.value = a.suggestPrefix
+		a.suggestIndex = -1
+	} else {
+		field.value = matches[a.suggestIndex]
+	}
+	field.cursor = len(field.value)
+}
+
+// renderSuggestions lists the active field's suggestions, the one filled in
+// highlighted
+func (a *App) renderSuggestions(indent int) string {
+	matches := a.dialogSuggestions()
+	if len(matches) == 0 {
+		return ""
+	}
+	shown := make([]string, 0, maxShownSuggestions)
+	for i, value := range matches {
+		if i >= maxShownSuggestions {
+			shown = append(shown, "…")
+			break
+		}
+		if i == a.suggestIndex {
+			value = dialogSelectedStyle.Render(value)
+		}
+		shown = append(shown, value)
+	}
+	return strings.Repeat(" ", indent) + previewStyle.Render("recent: ") + strings.Join(shown, previewStyle.Render(", ")) + "\n"
+}
+
+// recordRecent remembers a saved tunnel's hosts and user for later dialogs
+func (a *App) recordRecent(tc config.TunnelConfig) {
+	if a.recent == nil {
+		return
+	}
+	if err := a.recent.Record(tc); err != nil {
+		a.logError("Failed to remember recent hosts: %v", err)
+	}
+}
+
+// loadRecent picks up values remembered by any session, including others
+// running at the same time
+func (a *App) loadRecent() {
+	a.suggestIndex = -1
+	if a.recent == nil {
+		return
+	}
+	values, err := a.recent.Load()
+	if err != nil {
+		a.logError("Failed to read recent hosts: %v", err)
+		return
+	}
+	a.recentValues = values
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/registry"
+)
+
+func TestRecentSuggestions(t *testing.T) {
+	dir := t.TempDir()
+	a := &App{
+		loader: config.NewConfigLoader(filepath.Join(dir, "config.yaml")),
+		recent: registry.NewRecent(filepath.Join(dir, "recent.json")),
+	}
+
+	// Saving a tunnel remembers its hosts and user
+	a.initDialog(modeNew)
+	a.dialogFields[3].value = "5432"
+	a.dialogFields[4].value = "db.internal"
+	a.dialogFields[5].value = "5432"
+	a.dialogFields[6].value = "jump.example.com"
+	a.dialogFields[8].value = "deploy"
+	a.handleDialogSubmit()
+	a.initDialog(modeNew)
+	a.dialogFields[3].value = "8080"
+	a.dialogFields[4].value = "dash.internal"
+	a.dialogFields[5].value = "80"
+	a.handleDialogSubmit()
+
+	// And the next dialog suggests them
+	a.initDialog(modeNew)
+	a.activeField = 4
+	a.dialogFields[4].value = "D"
+	if matches := a.dialogSuggestions(); len(matches) != 2 || matches[0] != "dash.internal" {
+		t.Fatalf("expected both hosts, most recent first, got %v", matches)
+	}
+	a.nextSuggestion()
+	a.nextSuggestion()
+	if a.dialogFields[4].value != "db.internal" || a.dialogFields[4].cursor != len("db.internal") {
+		t.Errorf("expected the second suggestion filled in, got %q", a.dialogFields[4].value)
+	}
+	// Past the last one the typed prefix comes back
+	a.nextSuggestion()
+	if a.dialogFields[4].value != "D" {
+		t.Errorf("expected the typed value back, got %q", a.dialogFields[4].value)
+	}
+
+	a.activeField = 8
+	a.dialogFields[8].value = ""
+	if matches := a.dialogSuggestions(); len(matches) != 1 || matches[0] != "deploy" {
+		t.Errorf("expected the bastion user suggested, got %v", matches)
+	}
+	// Fields without history get no suggestions
+	a.activeField = 9
+	if matches := a.dialogSuggestions(); len(matches) != 0 {
+		t.Errorf("expected no suggestions for the name, got %v", matches)
+	}
+}
@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// Markers of a tag group header, open or folded
+const (
+	groupOpenIcon   = "▾"
+	groupFoldedIcon = "▸"
+)
+
+// tableEntry is a row of the table: a tunnel, or the header of a tag group
+// when tunnel is nil
+type tableEntry struct {
+	tunnel *TunnelRecord
+	tag    string
+}
+
+// tableEntries returns the rows of the table in order. Normally those are the
+// tunnels passing the filters, grouped by tag they come under a header for
+// each tag, untagged ones last.
+func (a *App) tableEntries() []tableEntry {
+	filtered := a.filteredTunnels()
+	entries := make([]tableEntry, 0, len(filtered))
+	if !a.groupByTag {
+		for i := range filtered {
+			entries = append(entries, tableEntry{tunnel: a.record(filtered[i].ID)})
+		}
+		return entries
+	}
+
+	for _, tag := range groupTags(filtered) {
+		entries = append(entries, tableEntry{tag: tag})
+		if a.collapsed[tag] {
+			continue
+		}
+		for i := range filtered {
+			if filtered[i].Config.Tag == tag {
+				entries = append(entries, tableEntry{tunnel: a.record(filtered[i].ID), tag: tag})
+			}
+		}
+	}
+	return entries
+}
+
+// record returns the tunnel with an ID, where it lives in a.tunnels
+func (a *App) record(id string) *TunnelRecord {
+	for i := range a.tunnels {
+		if a.tunnels[i].ID == id {
+			return &a.tunnels[i]
+		}
+	}
+	return nil
+}
+
+// groupTags returns the tags of the tunnels, sorted, with "" for the
+// untagged ones last
+func groupTags(tunnels []TunnelRecord) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	untagged := false
+	for _, t := range tunnels {
+		if t.Config.Tag == "" {
+			untagged = true
+			continue
+		}
+		if !seen[t.Config.Tag] {
+			seen[t.Config.Tag] = true
+			tags = append(tags, t.Config.Tag)
+		}
+	}
+	sort.Strings(tags)
+	if untagged {
+		tags = append(tags, "")
+	}
+	return tags
+}
+
+// groupRow is the header row of a tag group, counting the active tunnels of
+// the group that pass the filters
+func (a *App) groupRow(tag string, columns int) table.Row {
+	total, active := 0, 0
+	for _, t := range a.filteredTunnels() {
+		if t.Config.Tag != tag {
+			continue
+		}
+		total++
+		if t.Status == "active" {
+			active++
+		}
+	}
+
+	icon := groupOpenIcon
+	if a.collapsed[tag] {
+		icon = groupFoldedIcon
+	}
+	label := a.tagLabel(tag)
+	if tag == "" {
+		label = "untagged"
+	}
+
+	row := make(table.Row, columns)
+	row[0] = icon
+	row[1] = fmt.Sprintf("%s (%d)", label, total)
+	row[columns-1] = fmt.Sprintf("%d/%d active", active, total)
+	return row
+}
+
+// toggleGroupByTag switches between the flat and the grouped table, keeping
+// the selected tunnel selected
+func (a *App) toggleGroupByTag() {
+	selected := a.selectedRecord()
+	a.groupByTag = !a.groupByTag
+	if a.groupByTag {
+		a.Logf("Grouping tunnels by tag")
+		// The selected tunnel's group has to be open to find it again
+		if selected != nil {
+			delete(a.collapsed, selected.Config.Tag)
+		}
+	} else {
+		a.Logf("Listing tunnels without groups")
+	}
+	a.updateTableRows()
+	a.selectEntry(func(e tableEntry) bool {
+		return selected != nil && e.tunnel != nil && e.tunnel.ID == selected.ID
+	})
+}
+
+// toggleSelectedGroup folds or unfolds the group whose header is selected,
+// reporting whether one was
+func (a *App) toggleSelectedGroup() bool {
+	entries := a.tableEntries()
+	cursor := a.table.Cursor()
+	if cursor < 0 || cursor >= len(entries) || entries[cursor].tunnel != nil {
+		return false
+	}
+	tag := entries[cursor].tag
+	if a.collapsed == nil {
+		a.collapsed = make(map[string]bool)
+	}
+	if a.collapsed[tag] {
+		delete(a.collapsed, tag)
+	} else {
+		a.collapsed[tag] = true
+	}
+	a.updateTableRows()
+	return true
+}
+
+// toggleAllGroups folds every group, or unfolds them all when they already
+// are, keeping the selected group's header selected
+func (a *App) toggleAllGroups() {
+	entries := a.tableEntries()
+	tag := ""
+	if cursor := a.table.Cursor(); cursor >= 0 && cursor < len(entries) {
+		tag = entries[cursor].tag
+	}
+
+	tags := groupTags(a.filteredTunnels())
+	folded := true
+	for _, t := range tags {
+		folded = folded && a.collapsed[t]
+	}
+	if folded {
+		a.collapsed = nil
+	} else {
+		a.collapsed = make(map[string]bool, len(tags))
+		for _, t := range tags {
+			a.collapsed[t] = true
+		}
+	}
+	a.updateTableRows()
+	a.selectEntry(func(e tableEntry) bool {
+		return e.tunnel == nil && e.tag == tag
+	})
+}
+
+// selectEntry moves the cursor to the first row matching, or the top when
+// none does
+func (a *App) selectEntry(match func(tableEntry) bool) {
+	for i, e := range a.tableEntries() {
+		if match(e) {
+			a.table.SetCursor(i)
+			return
+		}
+	}
+	a.table.SetCursor(0)
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestGroupByTag(t *testing.T) {
+	a := newExpandApp(t, 4, 20)
+	a.tunnels[0].Config.Tag = "prod"
+	a.tunnels[1].Config.Tag = "dev"
+	a.tunnels[2].Config.Tag = "prod"
+	a.tunnels[2].Status = "active"
+	a.table.SetCursor(2)
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	rows := a.table.Rows()
+	// dev, its tunnel, prod, its two tunnels, untagged and its tunnel
+	if len(rows) != 7 {
+		t.Fatalf("expected 7 rows, got %d", len(rows))
+	}
+	if rows[2][0] != groupOpenIcon || rows[2][1] != "prod (2)" || rows[2][len(rows[2])-1] != "1/2 active" {
+		t.Errorf("expected the prod header with its counts, got %v", rows[2])
+	}
+	if rows[5][1] != "untagged (1)" {
+		t.Errorf("expected untagged tunnels last, got %v", rows[5])
+	}
+	if selected := a.selectedRecord(); selected == nil || selected.ID != "2" {
+		t.Errorf("expected the selected tunnel kept, got %+v", selected)
+	}
+
+	// Enter on a header folds its group
+	a.table.SetCursor(2)
+	if a.selectedRecord() != nil {
+		t.Fatal("expected no tunnel selected on a header")
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	rows = a.table.Rows()
+	if len(rows) != 5 || rows[2][0] != groupFoldedIcon || a.tunnels[2].Status != "active" {
+		t.Fatalf("expected prod folded and nothing started or stopped, got %d rows", len(rows))
+	}
+	if selected := a.selectedRecord(); selected != nil {
+		t.Errorf("expected the header still selected, got %s", selected.ID)
+	}
+	a.table.SetCursor(4)
+	if selected := a.selectedRecord(); selected == nil || selected.ID != "3" {
+		t.Errorf("expected the untagged tunnel below the folded group, got %+v", selected)
+	}
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Z'}})
+	if rows := a.table.Rows(); len(rows) != 3 {
+		t.Errorf("expected only the 3 headers, got %d rows", len(rows))
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Z'}})
+	if rows := a.table.Rows(); len(rows) != 7 {
+		t.Errorf("expected every group unfolded, got %d rows", len(rows))
+	}
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if rows := a.table.Rows(); len(rows) != 4 {
+		t.Errorf("expected the flat table back, got %d rows", len(rows))
+	}
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tagLabel is how a tag is shown, honouring its display_name
+func (a *App) tagLabel(tag string) string {
+	if settings, ok := a.tagSettings[tag]; ok && settings.DisplayName != "" {
+		return settings.DisplayName
+	}
+	return tag
+}
+
+// tagFilterLabel shows a comma separated tag filter using display names
+func (a *App) tagFilterLabel(tags string) string {
+	labels := strings.Split(tags, ",")
+	for i, tag := range labels {
+		labels[i] = a.tagLabel(tag)
+	}
+	return strings.Join(labels, ",")
+}
+
+// tagColor returns the configured color for a tag, or the default accent
+func (a *App) tagColor(tag string) lipgloss.Color {
+	if settings, ok := a.tagSettings[tag]; ok && settings.Color != "" {
+		return lipgloss.Color(settings.Color)
+	}
+	return activeTheme.accent
+}
+
+// tagLess orders tags by their configured priority, then by name
+func (a *App) tagLess(i, j string) bool {
+	pi, pj := a.tagSettings[i].Priority, a.tagSettings[j].Priority
+	if pi != pj {
+		return pi < pj
+	}
+	return i < j
+}
+
+// sortTags orders tags for the tag dialog
+func (a *App) sortTags(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return a.tagLess(tags[i], tags[j])
+	})
+}
+
+// autostartTunnels starts every tunnel that, or whose tag, asks for it, and
+// the startup groups set to autostart
+func (a *App) autostartTunnels() tea.Cmd {
+	var cmds []tea.Cmd
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		if t.Status != "stopped" || !t.Config.Autostarts(a.tagSettings) {
+			continue
+		}
+		if host, ok := a.inMaintenance(t); ok {
+			a.Logf("Not autostarting %s: %s is in maintenance", t.Config.Name, host)
+			continue
+		}
+		if t.Config.Autostart {
+			a.Logf("Autostarting %s", t.Config.Name)
+		} else {
+			a.Logf("Autostarting %s (tag %s)", t.Config.Name, a.tagLabel(t.Config.Tag))
+		}
+		cmds = append(cmds, a.startTunnel(t))
+	}
+	a.updateTableRows()
+	return tea.Batch(append(cmds, a.autostartGroups())...)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestTagSettings(t *testing.T) {
+	a := &App{
+		tagSettings: map[string]config.TagSettings{
+			"prod": {DisplayName: "Production", Priority: -1},
+			"dev":  {Priority: 5},
+		},
+	}
+
+	if got := a.tagLabel("prod"); got != "Production" {
+		t.Errorf("expected display name, got %s", got)
+	}
+	if got := a.tagLabel("staging"); got != "staging" {
+		t.Errorf("expected tag itself without settings, got %s", got)
+	}
+	if got := a.tagFilterLabel("prod,staging"); got != "Production,staging" {
+		t.Errorf("unexpected filter label %s", got)
+	}
+
+	tags := []string{"dev", "staging", "prod", "alpha"}
+	a.sortTags(tags)
+	want := []string{"prod", "alpha", "staging", "dev"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("expected %v, got %v", want, tags)
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+)
+
+// Dialog fields after the fixed ones hold the selected template's variables
+const firstVariableField = 13
+
+// selectedTemplate is the template the new tunnel dialog started from, if any
+func (a *App) selectedTemplate() (config.TunnelTemplate, bool) {
+	if a.dialogMode != modeNew || a.dialogTemplate < 0 || a.dialogTemplate >= len(a.templates) {
+		return config.TunnelTemplate{}, false
+	}
+	return a.templates[a.dialogTemplate], true
+}
+
+// cycleTemplate restarts the new tunnel dialog from the next template, and
+// from scratch after the last one
+func (a *App) cycleTemplate() {
+	next := a.dialogTemplate + 1
+	if next >= len(a.templates) {
+		next = -1
+	}
+	a.initDialog(modeNew)
+	a.dialogTemplate = next
+
+	template, ok := a.selectedTemplate()
+	if !ok {
+		return
+	}
+	// Bind address through tag, in the order Values returns them
+	for i, value := range template.Values() {
+		a.dialogFields[2+i].value = value
+		a.dialogFields[2+i].cursor = len(value)
+	}
+	for _, variable := range template.Variables() {
+		a.dialogFields = append(a.dialogFields, dialogField{label: "{" + variable + "}"})
+	}
+	if len(a.dialogFields) > firstVariableField {
+		a.activeField = firstVariableField
+	}
+}
+
+// expandTemplate fills the template's variables into the dialog fields, so
+// the dialog can be submitted as if typed out in full
+func (a *App) expandTemplate() error {
+	template, ok := a.selectedTemplate()
+	if !ok {
+		return nil
+	}
+	variables := make(map[string]string)
+	for i, variable := range template.Variables() {
+		value := strings.TrimSpace(a.dialogFields[firstVariableField+i].value)
+		if value == "" {
+			return fmt.Errorf("fill in {%s} for template %s", variable, template.Name)
+		}
+		variables[variable] = value
+	}
+	for i := 1; i < firstVariableField; i++ {
+		a.dialogFields[i].value = config.ExpandTemplate(a.dialogFields[i].value, variables)
+	}
+	return nil
+}
+
+// dialogPortRange is where a tunnel created without a local port gets one,
+// the template's range taking precedence
+func (a *App) dialogPortRange() string {
+	if template, ok := a.selectedTemplate(); ok && template.PortRange != "" {
+		return template.PortRange
+	}
+	return a.loader.Config().PortRange
+}
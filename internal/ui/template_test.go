@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestNewTunnelFromTemplate(t *testing.T) {
+	a := &App{
+		loader: config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml")),
+		templates: []config.TunnelTemplate{{
+			Name:        "prod",
+			TunnelName:  "{service}-prod",
+			RemoteHost:  "{service}.prod.internal",
+			RemotePort:  "5432",
+			BastionHost: "jump.prod",
+			BastionUser: "ops",
+			Tag:         "prod",
+			PortRange:   "25000-25099",
+		}},
+	}
+	a.initDialog(modeNew)
+	a.cycleTemplate()
+	if len(a.dialogFields) != firstVariableField+1 || a.activeField != firstVariableField {
+		t.Fatalf("expected a {service} field to fill in, got %+v", a.dialogFields)
+	}
+
+	// Variables must be filled in
+	a.handleDialogSubmit()
+	if len(a.tunnels) != 0 {
+		t.Fatalf("expected nothing to be added without {service}")
+	}
+
+	a.dialogFields[firstVariableField].value = "billing"
+	a.handleDialogSubmit()
+	if len(a.tunnels) != 1 {
+		t.Fatalf("expected a tunnel to be added, log: %v", a.errorLog)
+	}
+	tc := a.tunnels[0].Config
+	if tc.Name != "billing-prod" || tc.RemoteHost != "billing.prod.internal" || tc.Bastion.Host != "jump.prod" || tc.Tag != "prod" {
+		t.Errorf("expected the template filled in, got %+v", tc)
+	}
+	if tc.LocalPort < 25000 || tc.LocalPort > 25099 {
+		t.Errorf("expected a local port from the template's range, got %d", tc.LocalPort)
+	}
+
+	// Cycling past the last template starts from scratch
+	a.initDialog(modeNew)
+	a.cycleTemplate()
+	a.cycleTemplate()
+	if _, ok := a.selectedTemplate(); ok || len(a.dialogFields) != firstVariableField {
+		t.Errorf("expected an empty dialog, got %+v", a.dialogFields)
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	"github.com/google/uuid"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// parseYesNo reads the yes/no answer typed into a dialog field
+func parseYesNo(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "y", "yes", "true":
+		return true
+	}
+	return false
+}
+
+// persistentConfigs are the tunnels that belong in config.yaml, leaving out
+// temporary ones
+func (a *App) persistentConfigs() []config.TunnelConfig {
+	configs := make([]config.TunnelConfig, 0, len(a.tunnels))
+	for _, t := range a.manualOrder() {
+		if !t.Temporary {
+			configs = append(configs, t.Config)
+		}
+	}
+	return configs
+}
+
+// AddTemporaryTunnel adds a tunnel for this session only, described as an
+// ssh command like "ssh -L 8080:localhost:80 user@host". It is started with
+// the app and never saved.
+func (a *App) AddTemporaryTunnel(spec string) error {
+	tc, err := parseSshString(spec)
+	if err != nil {
+		return fmt.Errorf("invalid temporary tunnel %q: %w", spec, err)
+	}
+	a.tunnels = append(a.tunnels, TunnelRecord{
+		ID:        uuid.New().String(),
+		Status:    "stopped",
+		Config:    *tc,
+		Metrics:   "--",
+		Temporary: true,
+	})
+	a.Logf("Added temporary tunnel %s, it won't be saved", tc.Name)
+	a.updateTableRows()
+	return nil
+}
+
+// startTemporaryTunnels brings up the temporary tunnels given on the command
+// line, which are only worth adding if they run
+func (a *App) startTemporaryTunnels() tea.Cmd {
+	var cmds []tea.Cmd
+	for i := range a.tunnels {
+		t := &a.tunnels[i]
+		if t.Temporary && t.Status == "stopped" {
+			cmds = append(cmds, a.startTunnel(t))
+		}
+	}
+	a.updateTableRows()
+	return tea.Batch(cmds...)
+}
+
+// nameLabel is the name shown in the table, flagging temporary tunnels so
+// nobody expects to find them in the config later
+func nameLabel(t *TunnelRecord) string {
+	if t.Temporary {
+		return t.Config.Name + " (temp)"
+	}
+	return t.Config.Name
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"tunnel9/internal/config"
+)
+
+func TestTemporaryTunnelsAreNotSaved(t *testing.T) {
+	a := &App{
+		loader: config.NewConfigLoader(filepath.Join(t.TempDir(), "config.yaml")),
+	}
+	a.tunnels = append(a.tunnels, TunnelRecord{ID: "db", Status: "stopped", Config: config.TunnelConfig{Name: "db", LocalPort: 5432}})
+
+	if err := a.AddTemporaryTunnel("ssh -L 8080:localhost:80 deploy@web.internal"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	temp := a.tunnels[1]
+	if !temp.Temporary || temp.Config.LocalPort != 8080 || temp.Config.Bastion.Host != "web.internal" {
+		t.Errorf("expected a temporary tunnel through web.internal, got %+v", temp)
+	}
+	if got := nameLabel(&temp); got != temp.Config.Name+" (temp)" {
+		t.Errorf("expected the name to be flagged, got %q", got)
+	}
+
+	a.saveConfig()
+	saved, err := a.loader.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved) != 1 || saved[0].Name != "db" {
+		t.Errorf("expected only db to be saved, got %+v", saved)
+	}
+
+	if err := a.AddTemporaryTunnel("ssh web.internal"); err == nil {
+		t.Error("expected an error for a spec without -L")
+	}
+}
+
+func TestParseYesNo(t *testing.T) {
+	for value, want := range map[string]bool{
+		"":     false,
+		"n":    false,
+		"no":   false,
+		"y":    true,
+		"Yes":  true,
+		"true": true,
+	} {
+		if got := parseYesNo(value); got != want {
+			t.Errorf("parseYesNo(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
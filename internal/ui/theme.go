@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"tunnel9/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme holds the colors the interface is drawn with
+type theme struct {
+	accent           lipgloss.Color
+	accentBackground lipgloss.Color
+	accentText       lipgloss.Color
+	selected         lipgloss.Color
+	success          lipgloss.Color
+	warning          lipgloss.Color
+	error            lipgloss.Color
+	info             lipgloss.Color
+	highlight        lipgloss.Color
+	muted            lipgloss.Color
+	faint            lipgloss.Color
+	badge            lipgloss.Color
+	badgeText        lipgloss.Color
+}
+
+// builtinThemes are the presets a theme starts from
+var builtinThemes = map[string]theme{
+	config.ThemeDark: {
+		accent:           "#2dd4bf",
+		accentBackground: "#2d3436",
+		accentText:       "0",
+		selected:         "212",
+		success:          "10",
+		warning:          "11",
+		error:            "9",
+		info:             "14",
+		highlight:        "13",
+		muted:            "8",
+		faint:            "245",
+		badge:            "12",
+		badgeText:        "15",
+	},
+	config.ThemeLight: {
+		accent:           "#0f766e",
+		accentBackground: "#e2e8f0",
+		accentText:       "15",
+		selected:         "125",
+		success:          "28",
+		warning:          "130",
+		error:            "160",
+		info:             "25",
+		highlight:        "90",
+		muted:            "244",
+		faint:            "242",
+		badge:            "25",
+		badgeText:        "15",
+	},
+	config.ThemeHighContrast: {
+		accent:           "14",
+		accentBackground: "4",
+		accentText:       "0",
+		selected:         "11",
+		success:          "10",
+		warning:          "11",
+		error:            "9",
+		info:             "14",
+		highlight:        "13",
+		muted:            "7",
+		faint:            "7",
+		badge:            "4",
+		badgeText:        "15",
+	},
+}
+
+// activeTheme is the theme in use, the styles are drawn from it
+var activeTheme = builtinThemes[config.ThemeDark]
+
+// resolveTheme starts from the configured preset and replaces the colors
+// the config sets
+func resolveTheme(cfg config.ThemeConfig) theme {
+	t, ok := builtinThemes[cfg.Preset]
+	if !ok {
+		t = builtinThemes[config.ThemeDark]
+	}
+	for _, color := range []struct {
+		field *lipgloss.Color
+		value string
+	}{
+		{&t.accent, cfg.Accent},
+		{&t.accentBackground, cfg.AccentBackground},
+		{&t.accentText, cfg.AccentText},
+		{&t.selected, cfg.Selected},
+		{&t.success, cfg.Success},
+		{&t.warning, cfg.Warning},
+		{&t.error, cfg.Error},
+		{&t.info, cfg.Info},
+		{&t.highlight, cfg.Highlight},
+		{&t.muted, cfg.Muted},
+		{&t.faint, cfg.Faint},
+		{&t.badge, cfg.Badge},
+		{&t.badgeText, cfg.BadgeText},
+	} {
+		if color.value != "" {
+			*color.field = lipgloss.Color(color.value)
+		}
+	}
+	return t
+}
+
+// SetTheme picks the colors of the interface from the config, before
+// anything is drawn
+func SetTheme(cfg config.ThemeConfig) {
+	useTheme(resolveTheme(cfg))
+}
+
+// useTheme makes a theme the active one, recoloring the shared styles.
+// Styles made while drawing pick it up from activeTheme.
+func useTheme(t theme) {
+	activeTheme = t
+
+	titleStyle = titleStyle.Foreground(t.accent)
+	consoleStyle = consoleStyle.BorderForeground(t.accent)
+	dialogStyle = dialogStyle.BorderForeground(t.accent)
+	dialogActiveStyle = dialogActiveStyle.Foreground(t.accent)
+	dialogSelectedStyle = dialogSelectedStyle.Background(t.accentBackground).Foreground(t.accent)
+	detailStyle = detailStyle.BorderForeground(t.accent)
+	helpStyle = helpStyle.BorderForeground(t.accent)
+
+	checkOKStyle = checkOKStyle.Foreground(t.success)
+	checkFailedStyle = checkFailedStyle.Foreground(t.error)
+	latencyGoodStyle = latencyGoodStyle.Foreground(t.success)
+	latencyWarnStyle = latencyWarnStyle.Foreground(t.warning)
+	latencyBadStyle = latencyBadStyle.Foreground(t.error)
+	timelineActiveStyle = timelineActiveStyle.Foreground(t.success)
+	timelineConnectingStyle = timelineConnectingStyle.Foreground(t.warning)
+	timelineErrorStyle = timelineErrorStyle.Foreground(t.error)
+	timelineStoppedStyle = timelineStoppedStyle.Foreground(t.muted)
+
+	expandStyle = expandStyle.Foreground(t.faint)
+	previewStyle = previewStyle.Foreground(t.faint)
+	maintenanceRowStyle = maintenanceRowStyle.Foreground(t.faint)
+
+	errorBadgeStyle = errorBadgeStyle.Background(t.error).Foreground(t.badgeText)
+	markBadgeStyle = markBadgeStyle.Background(t.badge).Foreground(t.badgeText)
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"testing"
+
+	"tunnel9/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestResolveTheme(t *testing.T) {
+	dark := resolveTheme(config.ThemeConfig{})
+	if dark != builtinThemes[config.ThemeDark] {
+		t.Errorf("expected the dark theme by default, got %+v", dark)
+	}
+
+	light := resolveTheme(config.ThemeConfig{Preset: config.ThemeLight, Accent: "#005f5f"})
+	if light.accent != "#005f5f" {
+		t.Errorf("expected the accent replaced, got %s", light.accent)
+	}
+	if light.error != builtinThemes[config.ThemeLight].error {
+		t.Errorf("expected the rest from the light preset, got %s", light.error)
+	}
+}
+
+func TestUseTheme(t *testing.T) {
+	defer useTheme(builtinThemes[config.ThemeDark])
+
+	SetTheme(config.ThemeConfig{Preset: config.ThemeHighContrast, Accent: "#ffffff"})
+	if got := dialogStyle.GetBorderTopForeground(); got != lipgloss.Color("#ffffff") {
+		t.Errorf("expected dialog borders in the accent, got %v", got)
+	}
+	if got := errorBadgeStyle.GetBackground(); got != builtinThemes[config.ThemeHighContrast].error {
+		t.Errorf("expected the error badge recolored, got %v", got)
+	}
+	if got := (&App{}).tagColor("prod"); got != "#ffffff" {
+		t.Errorf("expected tags without a color in the accent, got %s", got)
+	}
+}
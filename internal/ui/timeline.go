@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Width of the name column in the timeline
+const timelineNameWidth = 20
+
+var (
+	timelineActiveStyle     = lipgloss.NewStyle().Foreground(activeTheme.success)
+	timelineConnectingStyle = lipgloss.NewStyle().Foreground(activeTheme.warning)
+	timelineErrorStyle      = lipgloss.NewStyle().Foreground(activeTheme.error)
+	timelineStoppedStyle    = lipgloss.NewStyle().Foreground(activeTheme.muted)
+)
+
+// statusRank orders statuses from fine to worst, so a drop shorter than a
+// timeline cell still shows in it
+func statusRank(status string) int {
+	switch status {
+	case "error":
+		return 3
+	case "connecting":
+		return 2
+	case "active":
+		return 1
+	}
+	return 0
+}
+
+// timelineCells splits the time from start to end into width equal cells
+// and returns the worst status the tunnel was in during each, given its
+// transitions and its status now
+func timelineCells(history []statusChange, current string, start time.Time, end time.Time, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	cells := make([]string, width)
+	state := current
+	if len(history) > 0 {
+		state = history[0].From
+	}
+
+	// Changes before the start only decide the status it starts in
+	next := 0
+	for next < len(history) && history[next].Time.Before(start) {
+		state = history[next].To
+		next++
+	}
+
+	span := end.Sub(start)
+	for i := range cells {
+		cellEnd := start.Add(span * time.Duration(i+1) / time.Duration(width))
+		worst := state
+		for next < len(history) && !history[next].Time.After(cellEnd) {
+			state = history[next].To
+			if statusRank(state) > statusRank(worst) {
+				worst = state
+			}
+			next++
+		}
+		cells[i] = worst
+	}
+	return cells
+}
+
+// timelineBar renders cells as a colored bar
+func timelineBar(cells []string) string {
+	var b strings.Builder
+	for _, status := range cells {
+		switch status {
+		case "active":
+			b.WriteString(timelineActiveStyle.Render("█"))
+		case "connecting":
+			b.WriteString(timelineConnectingStyle.Render("▒"))
+		case "error":
+			b.WriteString(timelineErrorStyle.Render("█"))
+		default:
+			b.WriteString(timelineStoppedStyle.Render("·"))
+		}
+	}
+	return b.String()
+}
+
+// timelineRows is how many tunnels fit in the timeline at once
+func (a *App) timelineRows() int {
+	return max(a.height-14, 5)
+}
+
+func (a *App) handleTimelineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if a.timelineOffset > 0 {
+			a.timelineOffset--
+		}
+	case "down", "j":
+		if a.timelineOffset < len(a.filteredTunnels())-a.timelineRows() {
+			a.timelineOffset++
+		}
+	case "esc", "ctrl+c", "T":
+		a.showTimeline = false
+	}
+	return a, nil
+}
+
+// timelineView shows every tunnel in view as a row over the session, so
+// drops and recoveries while away stand out at a glance
+func (a *App) timelineView() string {
+	now := time.Now()
+	start := a.sessionStart
+	if start.IsZero() || !start.Before(now) {
+		start = now.Add(-time.Minute)
+	}
+
+	dialogWidth := max(a.width-4, 60)
+	barWidth := max(dialogWidth-4-timelineNameWidth-1, 10)
+
+	title := "Session timeline"
+	if a.currentTag != "" {
+		title += " for " + a.tagFilterLabel(a.currentTag)
+	}
+	content := dialogActiveStyle.Render(title) + "\n\n"
+
+	tunnels := a.filteredTunnels()
+	if len(tunnels) == 0 {
+		content += "No tunnels in view\n"
+	}
+	offset := min(a.timelineOffset, max(len(tunnels)-a.timelineRows(), 0))
+	for i := offset; i < len(tunnels) && i < offset+a.timelineRows(); i++ {
+		t := tunnels[i]
+		name := t.Config.Name
+		if len(name) > timelineNameWidth {
+			name = name[:timelineNameWidth-1] + "…"
+		}
+		cells := timelineCells(t.History, t.Status, start, now, barWidth)
+		content += fmt.Sprintf("%-*s %s\n", timelineNameWidth, name, timelineBar(cells))
+	}
+
+	// Time axis under the bars
+	from := start.Format("15:04:05")
+	axis := fmt.Sprintf("%-*s %s%s%s", timelineNameWidth, "", from,
+		strings.Repeat(" ", max(barWidth-len(from)-len("now"), 1)), "now")
+	content += previewStyle.Render(axis) + "\n\n"
+
+	content += timelineActiveStyle.Render("█") + " active  " +
+		timelineConnectingStyle.Render("▒") + " connecting  " +
+		timelineErrorStyle.Render("█") + " error  " +
+		timelineStoppedStyle.Render("·") + " stopped"
+	if len(tunnels) > a.timelineRows() {
+		content += previewStyle.Render(fmt.Sprintf("  (%d-%d of %d)", offset+1,
+			min(offset+a.timelineRows(), len(tunnels)), len(tunnels)))
+	}
+	content += "\n\n↑/↓: Scroll • Esc/Ctrl+C: Close"
+
+	dialog := dialogStyle.Width(dialogWidth).Render(content)
+	return lipgloss.Place(a.width, a.height,
+		lipgloss.Center, lipgloss.Center,
+		dialog)
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimelineCells(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Minute)
+	at := func(minutes float64) time.Time {
+		return start.Add(time.Duration(minutes * float64(time.Minute)))
+	}
+	history := []statusChange{
+		{Time: at(-5), From: "stopped", To: "connecting"},
+		{Time: at(-4), From: "connecting", To: "active"},
+		{Time: at(3.2), From: "active", To: "error"},
+		{Time: at(3.4), From: "error", To: "connecting"},
+		{Time: at(3.6), From: "connecting", To: "active"},
+		{Time: at(8.5), From: "active", To: "stopped"},
+	}
+
+	got := timelineCells(history, "stopped", start, end, 10)
+	want := []string{"active", "active", "active", "error", "active", "active", "active", "active", "active", "stopped"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := timelineCells(nil, "active", start, end, 3); !reflect.DeepEqual(got, []string{"active", "active", "active"}) {
+		t.Errorf("expected the current status throughout without history, got %v", got)
+	}
+}
+
+func TestTimelineView(t *testing.T) {
+	a := newExpandApp(t, 2, 30)
+	a.width = 100
+	a.sessionStart = time.Now().Add(-time.Hour)
+	a.tunnels[1].setStatus("error", "failed")
+
+	view := a.timelineView()
+	for _, want := range []string{"Session timeline", "tunnel-0", "tunnel-1", "now", "connecting"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the timeline, got:\n%s", want, view)
+		}
+	}
+}
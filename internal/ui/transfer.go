@@ -0,0 +1,62 @@
+package ui
+
+import "tunnel9/internal/ssh"
+
+// transferTotals counts what a tunnel moved, both in its current run and
+// since tunnel9 launched, on top of the manager's counters that start over
+// with every run
+type transferTotals struct {
+	earlierIn  int64 // Received in earlier runs since launch or the last reset
+	earlierOut int64
+	baseIn     int64 // Received in the current run before the last reset
+	baseOut    int64
+}
+
+// observe folds a run's bytes into the earlier runs once its counters start
+// over, because the tunnel stopped or restarted
+func (t *transferTotals) observe(previous ssh.MetricValues, current ssh.MetricValues) {
+	if current.BytesIn >= previous.BytesIn && current.BytesOut >= previous.BytesOut {
+		return
+	}
+	t.earlierIn += max(previous.BytesIn-t.baseIn, 0)
+	t.earlierOut += max(previous.BytesOut-t.baseOut, 0)
+	t.baseIn, t.baseOut = 0, 0
+}
+
+// run returns the bytes received and sent since the tunnel started, or the
+// last reset
+func (t *transferTotals) run(v ssh.MetricValues) (in int64, out int64) {
+	return max(v.BytesIn-t.baseIn, 0), max(v.BytesOut-t.baseOut, 0)
+}
+
+// session returns the bytes received and sent since tunnel9 launched, or
+// the last reset
+func (t *transferTotals) session(v ssh.MetricValues) (in int64, out int64) {
+	in, out = t.run(v)
+	return t.earlierIn + in, t.earlierOut + out
+}
+
+// reset starts counting again from the current values
+func (t *transferTotals) reset(v ssh.MetricValues) {
+	*t = transferTotals{baseIn: v.BytesIn, baseOut: v.BytesOut}
+}
+
+// resetTransfers zeroes the transfer totals of the marked tunnels, or the
+// selected one
+func (a *App) resetTransfers() {
+	targets := a.markedTunnels()
+	if len(targets) == 0 {
+		if selected := a.selectedRecord(); selected != nil {
+			targets = append(targets, selected)
+		}
+	}
+	for _, t := range targets {
+		t.Transfer.reset(t.Values)
+	}
+	if len(targets) == 1 {
+		a.Logf("Reset transfer totals of %s", targets[0].Config.Name)
+	} else if len(targets) > 1 {
+		a.Logf("Reset transfer totals of %d tunnel(s)", len(targets))
+	}
+	a.updateTableRows()
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"testing"
+
+	"tunnel9/internal/ssh"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTransferTotals(t *testing.T) {
+	var totals transferTotals
+	first := ssh.MetricValues{BytesIn: 1000, BytesOut: 200}
+	totals.observe(ssh.MetricValues{}, first)
+	if in, out := totals.run(first); in != 1000 || out != 200 {
+		t.Errorf("expected the first run counted, got %d and %d", in, out)
+	}
+
+	// Stopping zeroes the counters, the run moves to the session totals
+	totals.observe(first, ssh.MetricValues{})
+	second := ssh.MetricValues{BytesIn: 50, BytesOut: 10}
+	totals.observe(ssh.MetricValues{}, second)
+	if in, out := totals.run(second); in != 50 || out != 10 {
+		t.Errorf("expected only the second run, got %d and %d", in, out)
+	}
+	if in, out := totals.session(second); in != 1050 || out != 210 {
+		t.Errorf("expected both runs since launch, got %d and %d", in, out)
+	}
+
+	totals.reset(second)
+	third := ssh.MetricValues{BytesIn: 80, BytesOut: 30}
+	totals.observe(second, third)
+	if in, out := totals.session(third); in != 30 || out != 20 {
+		t.Errorf("expected only what moved since the reset, got %d and %d", in, out)
+	}
+
+	// A restart after the reset doesn't count the bytes before it twice
+	totals.observe(third, ssh.MetricValues{})
+	if in, out := totals.session(ssh.MetricValues{}); in != 30 || out != 20 {
+		t.Errorf("expected the reset run kept, got %d and %d", in, out)
+	}
+}
+
+func TestResetTransfersKey(t *testing.T) {
+	a := newExpandApp(t, 2, 10)
+	a.tunnels[0].Status = "active"
+	a.tunnels[0].Values = ssh.MetricValues{BytesIn: 4096, BytesOut: 512}
+	a.tunnels[0].Transfer.earlierIn = 100
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'0'}})
+	if in, out := a.tunnels[0].Transfer.session(a.tunnels[0].Values); in != 0 || out != 0 {
+		t.Errorf("expected the selected tunnel's totals zeroed, got %d and %d", in, out)
+	}
+}
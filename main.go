@@ -1,15 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"net"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
+	"tunnel9/internal/cli"
 	"tunnel9/internal/config"
+	"tunnel9/internal/control"
+	"tunnel9/internal/daemon"
+	"tunnel9/internal/diagnostics"
+	"tunnel9/internal/localforward"
+	"tunnel9/internal/registry"
+	"tunnel9/internal/ssh"
 	"tunnel9/internal/ui"
+	pkgconfig "tunnel9/pkg/config"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/docopt/docopt-go"
 )
 
@@ -19,13 +34,65 @@ const USAGE_CONTENT string = `tunnel9 - SSH Tunnel Manager
 Version: %s
 
 Usage:
-  tunnel9 [--config=<path>] [--tag=<tag>]
+  tunnel9 [--config=<path> | --profile=<name>] [--tag=<tag>] [--temp=<ssh>...]
+  tunnel9 connect <ssh>... [--config=<path> | --profile=<name>]
+  tunnel9 --remote=<host>... [--socket=<path>]
+  tunnel9 daemon [--config=<path> | --profile=<name>] [--tag=<tag>] [--socket=<path>] [--no-autostart] [--drain=<duration>]
+  tunnel9 headless [--env-config] [--config=<path> | --profile=<name>] [--tag=<tag>] [--socket=<path>] [--no-autostart] [--drain=<duration>]
+  tunnel9 upgrade [--socket=<path>]
+  tunnel9 apply <file> [--socket=<path>] [--dry-run] [--yes]
+  tunnel9 start <name>... [--config=<path> | --profile=<name>] [--socket=<path>]
+  tunnel9 stop <name>... [--config=<path> | --profile=<name>] [--socket=<path>]
+  tunnel9 list [--config=<path> | --profile=<name>] [--tag=<tag>] [--socket=<path>]
+  tunnel9 status [--json] [--config=<path> | --profile=<name>] [--tag=<tag>] [--socket=<path>]
+  tunnel9 --check [--config=<path> | --profile=<name>] [--tag=<tag>]
+  tunnel9 tag add <tag> <name>... [--config=<path>] [--dry-run]
+  tunnel9 tag rm <tag> [<name>...] [--config=<path>] [--dry-run] [--yes]
+  tunnel9 tag rename <old> <new> [--config=<path>] [--dry-run]
+  tunnel9 import csv <file> [--config=<path>] [--tag=<tag>] [--dry-run]
+  tunnel9 import sshuttle <file> [--config=<path>] [--tag=<tag>] [--dry-run]
+  tunnel9 import sshconfig [<file>] [--config=<path>] [--tag=<tag>] [--dry-run]
+  tunnel9 export (autossh|systemd) [<name>...] [--config=<path> | --profile=<name>] [--tag=<tag>]
+  tunnel9 diagnostics [--config=<path> | --profile=<name>] [--socket=<path>] [--output=<file>]
+  tunnel9 keyring set <item>
+  tunnel9 keyring rm <item>
+  tunnel9 schema
   tunnel9 -h | --help
 
 Options:
   -h --help        Show this screen.
   --config=<path>  Path to config file (optional)
-  -t, --tag=<tag>  Tag to filter tunnels by on startup (optional)`
+  --profile=<name> Use a named profile's config, like work or personal, kept in
+                   ~/.local/state/tunnel9/profiles/<name>.yaml
+  -t, --tag=<tag>  Tag to filter tunnels by on startup or export, or to give
+                   imported tunnels without one (optional)
+  --check          Print effective settings after ssh_config overrides and exit
+  --dry-run        Show what a tag, import or apply command would change without
+                   saving or applying
+  -y, --yes        Don't ask before destructive commands like tag rm or apply
+  --temp=<ssh>     Start a temporary tunnel for this session only, never saved
+                   to the config, e.g. "ssh -L 8080:localhost:80 user@host"
+  --remote=<host>  Control the daemon on another machine over SSH, given as
+                   [user@]host[:port]; repeat it to see several in one table
+  --socket=<path>  Daemon control socket, relative to the home directory
+                   unless absolute [default: .local/state/tunnel9/control.sock]
+  --no-autostart   Start no tunnels until asked to over the control socket
+  --drain=<duration>  How long a daemon that was upgraded keeps forwarding
+                   connections opened before the upgrade [default: 1h]
+  --env-config     Read the config from TUNNEL9_* environment variables instead
+                   of a file, see the README
+  --json           Print status as JSON, with endpoints, rates and uptime
+  --output=<file>  Where diagnostics writes its archive, by default
+                   tunnel9-diagnostics-<time>.tar.gz in the current directory
+
+Start and stop go through the daemon, starting one in the background if none
+is running. List and status show the daemon's tunnels, or the config's when
+none runs.
+
+Tag, export, start and stop commands match tunnel names, which may be globs like "db-*".
+
+Connect opens the TUI with a temporary tunnel for each ssh command, like
+"ssh -N -L 8080:localhost:80 user@host", started right away and never saved.`
 
 func main() {
 	usage := fmt.Sprintf(USAGE_CONTENT, VERSION)
@@ -35,13 +102,40 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Publish the config format for editors and other tools
+	if schema, _ := opts.Bool("schema"); schema {
+		data, err := pkgconfig.Schema()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	// Keep secrets the config refers to as keyring:<item> out of the YAML
+	if isKeyring, _ := opts.Bool("keyring"); isKeyring {
+		runKeyringCommand(opts)
+		return
+	}
+
 	var configPath string
 	if opts["--config"] != nil {
 		configPath = opts["--config"].(string)
 	}
 
-	// Find the appropriate config file using fallback logic
-	configPath = config.FindConfigFile(configPath)
+	// Find the appropriate config file using fallback logic, except when
+	// importing into a new file or using a profile, which is a config file
+	// of its own created on first save
+	isImport, _ := opts.Bool("import")
+	if profile, _ := opts.String("--profile"); profile != "" {
+		if configPath, err = config.ProfilePath(profile); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	} else if !isImport || configPath == "" {
+		configPath = config.FindConfigFile(configPath)
+	}
 
 	// Ensure config directory exists
 	configDir := filepath.Dir(configPath)
@@ -53,6 +147,85 @@ func main() {
 	// Load configuration
 	loader := config.NewConfigLoader(configPath)
 	tunnels, err := loader.Load()
+	// Containers configure tunnel9 through their environment
+	if envConfig, _ := opts.Bool("--env-config"); envConfig {
+		tunnels, err = loader.LoadEnv(os.Environ())
+	}
+
+	// Identity files to try when ~/.ssh/config names none
+	ssh.SetIdentityFiles(loader.Config().SSH.IdentityFiles)
+	ssh.SetPassphrases(loader.Config().SSH.Passphrases)
+	// And how much tunnels measure, many tunnels on a small VM may want less
+	ssh.SetDefaultMetrics(loader.Config().Metrics)
+	// Split DNS may need a resolver of its own for corporate names
+	if err := ssh.SetResolver(loader.Config().DNS); err != nil {
+		fmt.Println("Error:", err)
+	}
+	// Colors readable on the terminal's background, for the TUI
+	ui.SetTheme(loader.Config().Theme)
+
+	// Drive a daemon elsewhere instead of running tunnels here
+	if remotes, _ := opts["--remote"].([]string); len(remotes) > 0 {
+		runRemote(remotes, opts)
+		return
+	}
+
+	// Scripted retagging must never write back a config it failed to read
+	if isTag, _ := opts.Bool("tag"); isTag {
+		if err != nil {
+			fmt.Println("Unable to load configuration:", err)
+			os.Exit(1)
+		}
+		runTagCommand(opts, loader)
+		return
+	}
+
+	// Importing may create the config, but must not clobber one it can't read
+	if isImport {
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Println("Unable to load configuration:", err)
+			os.Exit(1)
+		}
+		runImportCommand(opts, loader)
+		return
+	}
+
+	// Swap the running daemon for this binary without dropping its tunnels
+	if isUpgrade, _ := opts.Bool("upgrade"); isUpgrade {
+		runUpgrade(opts)
+		return
+	}
+
+	// Bring the daemon's tunnels in line with a config file, like from git
+	if isApply, _ := opts.Bool("apply"); isApply {
+		runApply(opts)
+		return
+	}
+
+	// Script tunnels through the daemon rather than opening the TUI
+	if opts["start"] == true || opts["stop"] == true || opts["list"] == true || opts["status"] == true {
+		runLifecycleCommand(opts, configPath, tunnels, err)
+		return
+	}
+
+	// Context for a bug report, safe to attach
+	if opts["diagnostics"] == true {
+		runDiagnostics(opts, loader, configPath, err)
+		return
+	}
+
+	// Running as a container's entrypoint, a broken config must stop it
+	// rather than run nothing
+	if isHeadless, _ := opts.Bool("headless"); isHeadless {
+		if err != nil {
+			fmt.Println("Unable to load configuration:", err)
+			os.Exit(1)
+		}
+		tag, _ := opts.String("--tag")
+		runDaemon(opts, loader, tunnels, tag)
+		return
+	}
+
 	if err != nil {
 		fmt.Println("Unable to load configuration")
 		fmt.Println("  - ", err)
@@ -66,18 +239,507 @@ func main() {
 		initialTag = opts["--tag"].(string)
 	}
 
+	if isExport, _ := opts.Bool("export"); isExport {
+		format := "autossh"
+		if systemd, _ := opts.Bool("systemd"); systemd {
+			format = "systemd"
+		}
+		names, _ := opts["<name>"].([]string)
+		if err := cli.Export(os.Stdout, tunnels, format, names, initialTag); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if isDaemon, _ := opts.Bool("daemon"); isDaemon {
+		runDaemon(opts, loader, tunnels, initialTag)
+		return
+	}
+
+	// Show what we would do without starting the TUI
+	if check, _ := opts.Bool("--check"); check {
+		fmt.Printf("Config file: %s\n\n", configPath)
+		if problems := cli.Check(os.Stdout, tunnels, initialTag); problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	startups, safeMode := trackStartup()
+	startups.Note("opening the TUI")
 	app := ui.NewApp(loader, tunnels, initialTag)
+	app.SetVersion(VERSION)
+	if safeMode != "" {
+		app.SetSafeMode(safeMode)
+	}
+
+	// One-off forwards that shouldn't end up in the shared config
+	temps, _ := opts["--temp"].([]string)
+	connects, _ := opts["<ssh>"].([]string)
+	for _, spec := range append(temps, connects...) {
+		if err := app.AddTemporaryTunnel(spec); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
 
 	// Log which config file is being used
 	app.Logf("Using config file: %s", configPath)
+	app.Logf("Loaded %d tunnel(s) across %d tag(s)", len(tunnels), countTags(tunnels))
 
 	p := tea.NewProgram(
 		app,
-		tea.WithAltScreen(), // Use alternate screen buffer
+		tea.WithAltScreen(),       // Use alternate screen buffer
+		tea.WithMouseCellMotion(), // Clicks and the wheel
 	)
 
 	if _, err := p.Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
+	startups.End()
+}
+
+// trackStartup records this run, so that startups crashing again and again,
+// like on a bad config entry, bring the next one up in safe mode. It returns
+// why this run is in safe mode, if it is.
+func trackStartup() (*registry.Startups, string) {
+	path, err := registry.DefaultStartupsPath()
+	if err != nil {
+		return nil, ""
+	}
+	startups := registry.NewStartups(path)
+	crashed, err := startups.Begin()
+	if err != nil {
+		fmt.Println("Warning:", err)
+	}
+	// What the tunnels are doing is noted until the run has settled
+	ssh.SetActivityRecorder(startups.Note)
+	time.AfterFunc(registry.StartupWindow, func() {
+		ssh.SetActivityRecorder(nil)
+		startups.Settle()
+	})
+	return startups, registry.SafeModeReason(crashed)
+}
+
+func countTags(tunnels []config.TunnelConfig) int {
+	tags := make(map[string]bool)
+	for _, t := range tunnels {
+		if t.Tag != "" {
+			tags[t.Tag] = true
+		}
+	}
+	return len(tags)
+}
+
+func runTagCommand(opts docopt.Opts, loader *config.ConfigLoader) {
+	var action string
+	var args []string
+	names, _ := opts["<name>"].([]string)
+	switch {
+	case opts["add"] == true:
+		action = "add"
+		args = append([]string{opts["<tag>"].(string)}, names...)
+	case opts["rm"] == true:
+		action = "rm"
+		args = append([]string{opts["<tag>"].(string)}, names...)
+	case opts["rename"] == true:
+		action = "rename"
+		args = []string{opts["<old>"].(string), opts["<new>"].(string)}
+	}
+
+	dryRun, _ := opts.Bool("--dry-run")
+
+	// Only ask when someone is there to answer, so scripts keep working
+	var confirm cli.Confirmer
+	if yes, _ := opts.Bool("--yes"); !yes && term.IsTerminal(os.Stdin.Fd()) {
+		confirm = cli.PromptConfirmer(os.Stdin, os.Stdout)
+	}
+
+	if err := cli.Tag(os.Stdout, loader, action, args, dryRun, confirm); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runDiagnostics writes a redacted diagnostic archive, with the daemon's
+// tunnels and log when one is running
+func runDiagnostics(opts docopt.Opts, loader *config.ConfigLoader, configPath string, configErr error) {
+	bundle := diagnostics.Bundle{
+		Version:    VERSION,
+		ConfigPath: configPath,
+		Config:     loader.Config(),
+		Time:       time.Now(),
+	}
+	if configErr != nil {
+		bundle.Logs = append(bundle.Logs, fmt.Sprintf("Unable to load configuration: %v", configErr))
+	}
+
+	socketOpt, _ := opts.String("--socket")
+	socket, err := control.SocketPath(socketOpt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	var daemon cli.Daemon
+	if client, err := control.Dial(socket); err == nil {
+		defer client.Close()
+		daemon = client
+	}
+	output, _ := opts.String("--output")
+	logPath := filepath.Join(filepath.Dir(socket), "daemon.log")
+	if err := cli.Diagnostics(os.Stdout, bundle, daemon, logPath, output); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runKeyringCommand stores or removes a secret in the OS keyring. The
+// secret is typed without echo, or piped in by scripts.
+func runKeyringCommand(opts docopt.Opts) {
+	item, _ := opts.String("<item>")
+	var err error
+	if opts["rm"] == true {
+		err = cli.KeyringRemove(os.Stdout, item)
+	} else if term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Printf("Secret for %s: ", item)
+		secret, readErr := term.ReadPassword(os.Stdin.Fd())
+		fmt.Println()
+		if readErr != nil {
+			fmt.Println("Error:", readErr)
+			os.Exit(1)
+		}
+		err = cli.KeyringSet(os.Stdout, bytes.NewReader(secret), item)
+	} else {
+		err = cli.KeyringSet(os.Stdout, os.Stdin, item)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runDaemon runs the tunnels headless until interrupted, answering the
+// control socket so a TUI can drive them
+func runDaemon(opts docopt.Opts, loader *config.ConfigLoader, tunnels []config.TunnelConfig, tag string) {
+	socketOpt, _ := opts.String("--socket")
+	socket, err := control.SocketPath(socketOpt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	drainOpt, _ := opts.String("--drain")
+	drain, err := time.ParseDuration(drainOpt)
+	if err != nil {
+		fmt.Println("Error: invalid --drain:", err)
+		os.Exit(1)
+	}
+
+	// An upgrading daemon passes its sockets on rather than letting go of them
+	handover, err := daemon.InheritedHandover()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	var listener net.Listener
+	if handover != nil {
+		listener = handover.Control
+	} else if listener, err = control.Listen(socket); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	startups, safeMode := trackStartup()
+	defer startups.End()
+	if safeMode != "" {
+		fmt.Printf("Safe mode: %s\nAutostart and metrics are off. Fix or remove the entry and restart tunnel9 to leave safe mode\n", safeMode)
+		ssh.DisableMetrics(true)
+	}
+
+	startups.Note("starting the daemon")
+	d := daemon.New(tunnels, os.Stdout)
+	defer d.Close()
+	d.SetNotifications(loader.Config().Notifications, loader.Config().Email, registry.Machine(loader.Config().Registry))
+	if handover != nil {
+		d.Resume(handover)
+	} else if noAutostart, _ := opts.Bool("--no-autostart"); !noAutostart && safeMode == "" {
+		d.Autostart(loader.Config().TagSettings, tag)
+	}
+	if addr := loader.Config().MetricsListen; addr != "" && safeMode == "" {
+		startups.Note("serving metrics on " + addr)
+		if err := d.ServeMetrics(addr); err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			fmt.Printf("Serving metrics on http://%s/metrics\n", addr)
+		}
+	}
+	if api := loader.Config().API; api.Listen != "" {
+		if err := serveAPI(d, api); err != nil {
+			fmt.Println("Error:", err)
+		} else {
+			fmt.Printf("Serving the API on %s\n", api.Address())
+		}
+	}
+	d.DropInherited()
+	fmt.Printf("tunnel9 daemon managing %d tunnel(s), listening on %s\n", len(tunnels), socket)
+
+	// Closing the listener removes the socket and ends Serve
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	if err := d.Serve(listener); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// After an upgrade, let the transfers still running here finish
+	if d.HandedOver() {
+		d.Drain(drain)
+	}
+}
+
+// serveAPI opens the control protocol on the API address, for the
+// configured tokens only
+func serveAPI(d *daemon.Daemon, api config.APIConfig) error {
+	tokens := make([]control.Token, 0, len(api.Tokens))
+	for _, t := range api.Tokens {
+		secret, err := config.ResolveSecret(t.Token)
+		if err != nil {
+			return fmt.Errorf("api token %s: %w", t.Name, err)
+		}
+		tokens = append(tokens, control.Token{Name: t.Name, Secret: secret, Scope: t.TokenScope()})
+	}
+	return d.ServeAPI(api.Address(), tokens)
+}
+
+// runUpgrade has the running daemon hand its tunnels over to this binary
+func runUpgrade(opts docopt.Opts) {
+	socketOpt, _ := opts.String("--socket")
+	socket, err := control.SocketPath(socketOpt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	client, err := control.Dial(socket)
+	if err != nil {
+		fmt.Println("No tunnel9 daemon is running, nothing to upgrade")
+		return
+	}
+	defer client.Close()
+	if err := client.Upgrade(executable); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("The daemon now runs %s\n", executable)
+}
+
+// runApply shows how the running daemon's tunnels differ from a config file
+// and applies the changes once confirmed
+func runApply(opts docopt.Opts) {
+	path, _ := opts.String("<file>")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	socketOpt, _ := opts.String("--socket")
+	socket, err := control.SocketPath(socketOpt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	client, err := control.Dial(socket)
+	if err != nil {
+		fmt.Println("No tunnel9 daemon is running, nothing to apply to")
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	// Only ask when someone is there to answer, so pipelines keep working
+	var confirm cli.Confirmer
+	if yes, _ := opts.Bool("--yes"); !yes && term.IsTerminal(os.Stdin.Fd()) {
+		confirm = cli.PromptConfirmer(os.Stdin, os.Stdout)
+	}
+	dryRun, _ := opts.Bool("--dry-run")
+	if err := cli.Apply(os.Stdout, client, data, dryRun, confirm); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runLifecycleCommand starts, stops, lists or reports on tunnels through the
+// daemon. The config only matters when no daemon is running yet.
+func runLifecycleCommand(opts docopt.Opts, configPath string, tunnels []config.TunnelConfig, configErr error) {
+	socketOpt, _ := opts.String("--socket")
+	socket, err := control.SocketPath(socketOpt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	names, _ := opts["<name>"].([]string)
+	tag, _ := opts.String("--tag")
+
+	client, err := control.Dial(socket)
+	if err != nil {
+		if configErr != nil && opts["stop"] != true {
+			fmt.Println("Unable to load configuration:", configErr)
+			os.Exit(1)
+		}
+		switch {
+		case opts["list"] == true:
+			// Nothing runs, so show what could
+			if err := cli.List(os.Stdout, tunnels, nil, tag); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		case opts["status"] == true:
+			asJSON, _ := opts.Bool("--json")
+			if err := cli.Status(os.Stdout, tunnels, nil, tag, asJSON); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		case opts["stop"] == true:
+			fmt.Println("No tunnel9 daemon is running, nothing to stop")
+			return
+		}
+		if client, err = startDaemon(configPath, socket); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	defer client.Close()
+
+	switch {
+	case opts["list"] == true:
+		err = cli.List(os.Stdout, tunnels, client, tag)
+	case opts["status"] == true:
+		asJSON, _ := opts.Bool("--json")
+		err = cli.Status(os.Stdout, tunnels, client, tag, asJSON)
+	case opts["stop"] == true:
+		err = cli.Stop(os.Stdout, client, names)
+	default:
+		err = cli.Start(os.Stdout, client, names)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// startDaemon runs a daemon for configPath in the background, logging next
+// to its socket, and waits for it to answer
+func startDaemon(configPath string, socket string) (*control.Client, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	logPath := filepath.Join(filepath.Dir(socket), "daemon.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, "daemon", "--config="+configPath, "--socket="+socket, "--no-autostart")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start a daemon: %w", err)
+	}
+	fmt.Printf("Started a tunnel9 daemon, logging to %s\n", logPath)
+	cmd.Process.Release()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if client, err := control.Dial(socket); err == nil {
+			return client, nil
+		}
+	}
+	return nil, fmt.Errorf("the daemon didn't come up, see %s", logPath)
+}
+
+// runRemote shows the TUI for daemons on other machines. Daemons that can't
+// be reached are left out, as long as one of them answers.
+func runRemote(remotes []string, opts docopt.Opts) {
+	socket, _ := opts.String("--socket")
+
+	conns := make([]net.Conn, len(remotes))
+	errs := make([]error, len(remotes))
+	var wg sync.WaitGroup
+	for i, remote := range remotes {
+		fmt.Printf("Connecting to %s...\n", remote)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conns[i], errs[i] = ssh.DialRemoteSocket(remote, socket)
+		}()
+	}
+	wg.Wait()
+
+	var daemons []ui.RemoteDaemon
+	for i, remote := range remotes {
+		if errs[i] != nil {
+			fmt.Println("Error:", errs[i])
+			continue
+		}
+		daemons = append(daemons, ui.RemoteDaemon{Name: remote, Client: control.NewClient(conns[i])})
+	}
+	if len(daemons) == 0 {
+		os.Exit(1)
+	}
+
+	p := tea.NewProgram(ui.NewRemoteApp(daemons), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+func runImportCommand(opts docopt.Opts, loader *config.ConfigLoader) {
+	path, _ := opts.String("<file>")
+	tag, _ := opts["--tag"].(string)
+	dryRun, _ := opts.Bool("--dry-run")
+
+	// ssh_config follows its Include directives, so it is read by path
+	if sshConfig, _ := opts.Bool("sshconfig"); sshConfig {
+		if path == "" {
+			path = localforward.DefaultFile()
+		}
+		if err := cli.ImportSSHConfig(os.Stdout, loader, path, tag, dryRun); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	importer := cli.ImportCSV
+	if sshuttle, _ := opts.Bool("sshuttle"); sshuttle {
+		importer = cli.ImportSshuttle
+	}
+	if err := importer(os.Stdout, loader, file, tag, dryRun); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,101 @@
+// Package config lets other tools generate and validate tunnel9 config files
+// without depending on tunnel9's internals. The types are the ones tunnel9
+// itself loads, so anything built here is read back exactly as written.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"tunnel9/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	Config                = config.Config
+	TunnelConfig          = config.TunnelConfig
+	StandbyTarget         = config.StandbyTarget
+	SSHOptions            = config.SSHOptions
+	ReconnectPolicy       = config.ReconnectPolicy
+	HealthCheck           = config.HealthCheck
+	RegistryConfig        = config.RegistryConfig
+	BastionProviderConfig = config.BastionProviderConfig
+	PublicShareConfig     = config.PublicShareConfig
+	TagSettings           = config.TagSettings
+)
+
+// NewTunnel returns a tunnel forwarding localPort to remoteHost:remotePort,
+// optionally through bastion given as user@host[:port]
+func NewTunnel(name string, localPort int, remoteHost string, remotePort int, bastion string) TunnelConfig {
+	tc := TunnelConfig{
+		Name:       name,
+		LocalPort:  localPort,
+		RemoteHost: remoteHost,
+		RemotePort: remotePort,
+	}
+	if bastion == "" {
+		return tc
+	}
+
+	if user, host, found := strings.Cut(bastion, "@"); found {
+		tc.Bastion.User = user
+		bastion = host
+	}
+	tc.Bastion.Host = bastion
+	tc.Bastion.Port = 22
+	if host, port, found := strings.Cut(bastion, ":"); found {
+		if portNum, err := strconv.Atoi(port); err == nil {
+			tc.Bastion.Host = host
+			tc.Bastion.Port = portNum
+		}
+	}
+	return tc
+}
+
+// Parse reads a config file, rejecting unknown keys so typos fail in CI
+// rather than being silently ignored
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	data, auto := config.StripAutoPorts(data)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return Config{}, err
+	}
+	cfg.MarkAutoPorts(auto)
+	return cfg, nil
+}
+
+// Marshal renders a config the way tunnel9 writes it
+func Marshal(cfg Config) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
+
+// Validate returns every problem found in the config, joined
+func Validate(cfg Config) error {
+	return errors.Join(cfg.Validate()...)
+}
+
+// ValidateFile parses and validates a config file
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return Validate(cfg)
+}
+
+// Schema returns the config format as an indented JSON Schema document
+func Schema() ([]byte, error) {
+	return json.MarshalIndent(config.JSONSchema(), "", "  ")
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNewTunnel(t *testing.T) {
+	tc := NewTunnel("db", 15432, "db.internal", 5432, "ops@jump.example.com:2222")
+	if tc.Bastion.User != "ops" || tc.Bastion.Host != "jump.example.com" || tc.Bastion.Port != 2222 {
+		t.Errorf("unexpected bastion: %+v", tc.Bastion)
+	}
+
+	direct := NewTunnel("web", 8080, "web.internal", 80, "")
+	if direct.Bastion.Host != "" {
+		t.Errorf("expected no bastion, got %+v", direct.Bastion)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cfg := Config{Tunnels: []TunnelConfig{
+		NewTunnel("db", 15432, "db.internal", 5432, "jump.example.com"),
+	}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	data, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Tunnels) != 1 || parsed.Tunnels[0].Bastion.Port != 22 {
+		t.Errorf("unexpected round trip result: %+v", parsed.Tunnels)
+	}
+}
+
+func TestParseRejectsUnknownKeys(t *testing.T) {
+	data := []byte("tunnels:\n  - name: db\n    remote_hots: db.internal\n")
+	if _, err := Parse(data); err == nil {
+		t.Error("expected error for misspelled key")
+	}
+}
+
+func TestParseAutoPort(t *testing.T) {
+	data := []byte("tunnels:\n  - name: db\n    local_port: auto\n    remote_host: db.internal\n    remote_port: 5432\n")
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Tunnels) != 1 || !cfg.Tunnels[0].AutoPort {
+		t.Errorf("expected db to pick its port at start, got %+v", cfg.Tunnels)
+	}
+	if _, err := Parse(append(data, "    remote_hots: db\n"...)); err == nil {
+		t.Error("expected error for misspelled key next to auto")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil || len(data) == 0 {
+		t.Fatalf("expected schema, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Package tunnel embeds tunnel9's forwarding engine in other Go programs,
+// without the TUI. Tunnels are described with the same config the tunnel9
+// config file uses, so ~/.ssh/config, bastions, standby targets and UDP
+// relays all behave exactly as they do in tunnel9.
+//
+//	engine := tunnel.New()
+//	defer engine.Close()
+//
+//	events, cancel := engine.Subscribe(32)
+//	defer cancel()
+//
+//	engine.Create("db", config.NewTunnel("db", 15432, "db.internal", 5432, "ops@jump"))
+//	engine.Start("db")
+package tunnel
+
+import (
+	"fmt"
+	"sync"
+
+	"tunnel9/internal/config"
+	"tunnel9/internal/events"
+	"tunnel9/internal/ssh"
+)
+
+// Config describes a single tunnel, see tunnel9/pkg/config
+type Config = config.TunnelConfig
+
+// EventKind tells log lines and status changes apart
+type EventKind = events.Kind
+
+const (
+	EventLog    = events.KindLog
+	EventStatus = events.KindStatus
+)
+
+// Event is something a tunnel reported. Status events carry the tunnel ID and
+// its new State ("connecting", "active", "error", "stopped"); log events
+// carry the formatted log line in Message and its Level.
+type Event = events.Event
+
+// Level is how much a log event matters
+type Level = events.Level
+
+const (
+	LevelDebug = events.LevelDebug
+	LevelInfo  = events.LevelInfo
+	LevelWarn  = events.LevelWarn
+	LevelError = events.LevelError
+)
+
+// Engine manages a set of tunnels
+type Engine struct {
+	manager *ssh.TunnelManager
+	configs map[string]Config
+	mu      sync.Mutex
+}
+
+// New starts an engine with no tunnels
+func New() *Engine {
+	return &Engine{
+		manager: ssh.NewTunnelManager(),
+		configs: make(map[string]Config),
+	}
+}
+
+// Subscribe returns a channel receiving events of the given kinds, or all of
+// them, from now on, buffered to size. Events are dropped for subscribers
+// that fall behind. Call cancel to unsubscribe, which closes the channel.
+func (e *Engine) Subscribe(size int, kinds ...EventKind) (<-chan Event, func()) {
+	sub := e.manager.Events.Subscribe(size, events.DropNewest, kinds...)
+	return sub.C, sub.Cancel
+}
+
+// Create registers a tunnel under id without starting it
+func (e *Engine) Create(id string, cfg Config) error {
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return errs[0]
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.configs[id]; exists {
+		return fmt.Errorf("tunnel %s already exists", id)
+	}
+	e.configs[id] = cfg
+	return nil
+}
+
+// Start opens the local listener and connects the tunnel in the background.
+// Progress is reported through Subscribe.
+func (e *Engine) Start(id string) error {
+	e.mu.Lock()
+	cfg, exists := e.configs[id]
+	e.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("no tunnel %s", id)
+	}
+
+	t := e.manager.CreateTunnel(id, cfg)
+	if err := e.manager.StartTunnel(t); err != nil {
+		e.manager.StopTunnel(id)
+		return err
+	}
+	return nil
+}
+
+// Stop disconnects a tunnel and closes its listener. It can be started again.
+func (e *Engine) Stop(id string) error {
+	return e.manager.StopTunnel(id)
+}
+
+// Remove stops a tunnel and forgets it
+func (e *Engine) Remove(id string) error {
+	if err := e.Stop(id); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	delete(e.configs, id)
+	e.mu.Unlock()
+	return nil
+}
+
+// Metrics returns a short human readable summary of a running tunnel
+func (e *Engine) Metrics(id string) string {
+	return e.manager.GetMetrics(id)
+}
+
+// LocalAddress returns the address clients should connect to for a running
+// tunnel
+func (e *Engine) LocalAddress(id string) (string, bool) {
+	return e.manager.LocalAddress(id)
+}
+
+// Close stops every tunnel and ends all subscriptions
+func (e *Engine) Close() {
+	e.manager.Cleanup()
+}
@@ -0,0 +1,74 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("cannot find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	engine := New()
+	defer engine.Close()
+
+	if err := engine.Create("bad", Config{Name: "bad"}); err == nil {
+		t.Error("expected invalid config to be rejected")
+	}
+	if err := engine.Start("missing"); err == nil {
+		t.Error("expected error starting an unknown tunnel")
+	}
+
+	port := freePort(t)
+	cfg := Config{Name: "web", LocalPort: port, RemoteHost: "web.invalid", RemotePort: 80}
+	if err := engine.Create("web", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := engine.Create("web", cfg); err == nil {
+		t.Error("expected duplicate id to be rejected")
+	}
+
+	events, cancel := engine.Subscribe(100)
+	defer cancel()
+
+	if err := engine.Start("web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr, ok := engine.LocalAddress("web"); !ok || addr == "" {
+		t.Error("expected a local address once started")
+	}
+
+	select {
+	case <-events:
+	case <-time.After(2 * time.Second):
+		t.Error("expected an event after starting")
+	}
+
+	if err := engine.Remove("web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := engine.Start("web"); err == nil {
+		t.Error("expected removed tunnel to be gone")
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	engine := New()
+	events, cancel := engine.Subscribe(1)
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("expected channel closed after cancel")
+	}
+
+	// Cancelling after Close must not panic
+	_, cancel = engine.Subscribe(1)
+	engine.Close()
+	cancel()
+}
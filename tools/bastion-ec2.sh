@@ -0,0 +1,48 @@
+#!/bin/sh
+#
+# tunnel9 bastion provider for AWS EC2, requires the aws cli.
+#
+#   bastion_provider:
+#     command: /path/to/bastion-ec2.sh
+#     args: ["ami-0123456789abcdef0", "t3.nano", "my-keypair", "sg-0123456789abcdef0"]
+#
+# Usage: bastion-ec2.sh <ami> <type> <key-name> <security-group> up <tunnel-name>
+#        bastion-ec2.sh <ami> <type> <key-name> <security-group> down <instance-id>
+
+set -e
+
+AMI=$1
+TYPE=$2
+KEY=$3
+SG=$4
+ACTION=$5
+TARGET=$6
+USER=${BASTION_USER:-ec2-user}
+
+case $ACTION in
+  'up')
+    ID=`aws ec2 run-instances --image-id $AMI --instance-type $TYPE --key-name $KEY \
+      --security-group-ids $SG --instance-initiated-shutdown-behavior terminate \
+      --tag-specifications "ResourceType=instance,Tags=[{Key=Name,Value=tunnel9-$TARGET}]" \
+      --query 'Instances[0].InstanceId' --output text`
+    echo "created $ID" 1>&2
+    # Terminate it again unless it's handed over, when a step below fails or
+    # tunnel9 gives up waiting and sends SIGTERM
+    trap 'aws ec2 terminate-instances --instance-ids $ID 1>&2' EXIT
+    trap 'kill $! 2>/dev/null; exit 1' INT TERM
+    # Wait in the background, a signal is only handled once it returns
+    aws ec2 wait instance-status-ok --instance-ids $ID 1>&2 &
+    wait $!
+    HOST=`aws ec2 describe-instances --instance-ids $ID \
+      --query 'Reservations[0].Instances[0].PublicIpAddress' --output text`
+    trap - EXIT INT TERM
+    echo "{\"id\": \"$ID\", \"host\": \"$HOST\", \"user\": \"$USER\", \"port\": 22}"
+    ;;
+  'down')
+    aws ec2 terminate-instances --instance-ids $TARGET 1>&2
+    ;;
+  *)
+    echo "unknown action: $ACTION" 1>&2
+    exit 1
+    ;;
+esac
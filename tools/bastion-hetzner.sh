@@ -0,0 +1,50 @@
+#!/bin/sh
+#
+# tunnel9 bastion provider for Hetzner Cloud, requires the hcloud cli.
+#
+#   bastion_provider:
+#     command: /path/to/bastion-hetzner.sh
+#     args: ["debian-12", "cx22", "my-ssh-key", "fsn1"]
+#
+# Usage: bastion-hetzner.sh <image> <type> <ssh-key> <location> up <tunnel-name>
+#        bastion-hetzner.sh <image> <type> <ssh-key> <location> down <server-name>
+
+set -e
+
+IMAGE=$1
+TYPE=$2
+KEY=$3
+LOCATION=$4
+ACTION=$5
+TARGET=$6
+USER=${BASTION_USER:-root}
+
+case $ACTION in
+  'up')
+    NAME="tunnel9-$TARGET-`date +%s`"
+    echo "creating $NAME" 1>&2
+    # Delete it again unless it's handed over, when a step below fails or
+    # tunnel9 gives up waiting and sends SIGTERM
+    trap 'hcloud server delete $NAME 1>&2' EXIT
+    trap 'kill $! 2>/dev/null; exit 1' INT TERM
+    # Create in the background, a signal is only handled once it returns
+    hcloud server create --name $NAME --image $IMAGE --type $TYPE \
+      --ssh-key $KEY --location $LOCATION 1>&2 &
+    wait $!
+    HOST=`hcloud server ip $NAME`
+    # Give sshd a moment to come up
+    for i in 1 2 3 4 5 6 7 8 9 10; do
+      nc -z -w 2 $HOST 22 2>/dev/null && break
+      sleep 3
+    done
+    trap - EXIT INT TERM
+    echo "{\"id\": \"$NAME\", \"host\": \"$HOST\", \"user\": \"$USER\", \"port\": 22}"
+    ;;
+  'down')
+    hcloud server delete $TARGET 1>&2
+    ;;
+  *)
+    echo "unknown action: $ACTION" 1>&2
+    exit 1
+    ;;
+esac